@@ -0,0 +1,502 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v4.23.3
+// source: admin_service.proto
+
+package v0
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminServiceClient interface {
+	ListCategoryPolicies(ctx context.Context, in *ListCategoryPoliciesRequest, opts ...grpc.CallOption) (*ListCategoryPoliciesResponse, error)
+	EnableCategory(ctx context.Context, in *EnableCategoryRequest, opts ...grpc.CallOption) (*CategoryPolicy, error)
+	DisableCategory(ctx context.Context, in *DisableCategoryRequest, opts ...grpc.CallOption) (*CategoryPolicy, error)
+	SetCategoryMaxTTL(ctx context.Context, in *SetCategoryMaxTTLRequest, opts ...grpc.CallOption) (*CategoryPolicy, error)
+	GetAudienceAllowlist(ctx context.Context, in *GetAudienceAllowlistRequest, opts ...grpc.CallOption) (*AudienceAllowlist, error)
+	UpdateAudienceAllowlist(ctx context.Context, in *UpdateAudienceAllowlistRequest, opts ...grpc.CallOption) (*AudienceAllowlist, error)
+	// DeprovisionUser adds the principal to the deny list, so it can no longer
+	// mint tokens or be their subject.
+	DeprovisionUser(ctx context.Context, in *DeprovisionUserRequest, opts ...grpc.CallOption) (*DeprovisionUserResponse, error)
+	// GrantQuota allows a principal more tokens of a category than its daily
+	// quota, e.g. during an incident.
+	GrantQuota(ctx context.Context, in *GrantQuotaRequest, opts ...grpc.CallOption) (*QuotaUsage, error)
+	// ListValidators returns the versions and health of the validators of the
+	// JVS instance that serves the request, including disabled categories.
+	ListValidators(ctx context.Context, in *ListValidatorsRequest, opts ...grpc.CallOption) (*ListValidatorsResponse, error)
+	// GetAPIDescriptors returns the compiled descriptors of the JVS APIs and
+	// their dependencies, so clients can generate stubs without server
+	// reflection.
+	GetAPIDescriptors(ctx context.Context, in *GetAPIDescriptorsRequest, opts ...grpc.CallOption) (*descriptorpb.FileDescriptorSet, error)
+	// GetDecisionTrace returns the trace of a denied token request, by the
+	// request ID returned with its error, to tell why it was denied.
+	GetDecisionTrace(ctx context.Context, in *GetDecisionTraceRequest, opts ...grpc.CallOption) (*DecisionTrace, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) ListCategoryPolicies(ctx context.Context, in *ListCategoryPoliciesRequest, opts ...grpc.CallOption) (*ListCategoryPoliciesResponse, error) {
+	out := new(ListCategoryPoliciesResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/ListCategoryPolicies", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) EnableCategory(ctx context.Context, in *EnableCategoryRequest, opts ...grpc.CallOption) (*CategoryPolicy, error) {
+	out := new(CategoryPolicy)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/EnableCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DisableCategory(ctx context.Context, in *DisableCategoryRequest, opts ...grpc.CallOption) (*CategoryPolicy, error) {
+	out := new(CategoryPolicy)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/DisableCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetCategoryMaxTTL(ctx context.Context, in *SetCategoryMaxTTLRequest, opts ...grpc.CallOption) (*CategoryPolicy, error) {
+	out := new(CategoryPolicy)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/SetCategoryMaxTTL", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetAudienceAllowlist(ctx context.Context, in *GetAudienceAllowlistRequest, opts ...grpc.CallOption) (*AudienceAllowlist, error) {
+	out := new(AudienceAllowlist)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/GetAudienceAllowlist", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) UpdateAudienceAllowlist(ctx context.Context, in *UpdateAudienceAllowlistRequest, opts ...grpc.CallOption) (*AudienceAllowlist, error) {
+	out := new(AudienceAllowlist)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/UpdateAudienceAllowlist", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DeprovisionUser(ctx context.Context, in *DeprovisionUserRequest, opts ...grpc.CallOption) (*DeprovisionUserResponse, error) {
+	out := new(DeprovisionUserResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/DeprovisionUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GrantQuota(ctx context.Context, in *GrantQuotaRequest, opts ...grpc.CallOption) (*QuotaUsage, error) {
+	out := new(QuotaUsage)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/GrantQuota", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListValidators(ctx context.Context, in *ListValidatorsRequest, opts ...grpc.CallOption) (*ListValidatorsResponse, error) {
+	out := new(ListValidatorsResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/ListValidators", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetAPIDescriptors(ctx context.Context, in *GetAPIDescriptorsRequest, opts ...grpc.CallOption) (*descriptorpb.FileDescriptorSet, error) {
+	out := new(descriptorpb.FileDescriptorSet)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/GetAPIDescriptors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetDecisionTrace(ctx context.Context, in *GetDecisionTraceRequest, opts ...grpc.CallOption) (*DecisionTrace, error) {
+	out := new(DecisionTrace)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.AdminService/GetDecisionTrace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility
+type AdminServiceServer interface {
+	ListCategoryPolicies(context.Context, *ListCategoryPoliciesRequest) (*ListCategoryPoliciesResponse, error)
+	EnableCategory(context.Context, *EnableCategoryRequest) (*CategoryPolicy, error)
+	DisableCategory(context.Context, *DisableCategoryRequest) (*CategoryPolicy, error)
+	SetCategoryMaxTTL(context.Context, *SetCategoryMaxTTLRequest) (*CategoryPolicy, error)
+	GetAudienceAllowlist(context.Context, *GetAudienceAllowlistRequest) (*AudienceAllowlist, error)
+	UpdateAudienceAllowlist(context.Context, *UpdateAudienceAllowlistRequest) (*AudienceAllowlist, error)
+	// DeprovisionUser adds the principal to the deny list, so it can no longer
+	// mint tokens or be their subject.
+	DeprovisionUser(context.Context, *DeprovisionUserRequest) (*DeprovisionUserResponse, error)
+	// GrantQuota allows a principal more tokens of a category than its daily
+	// quota, e.g. during an incident.
+	GrantQuota(context.Context, *GrantQuotaRequest) (*QuotaUsage, error)
+	// ListValidators returns the versions and health of the validators of the
+	// JVS instance that serves the request, including disabled categories.
+	ListValidators(context.Context, *ListValidatorsRequest) (*ListValidatorsResponse, error)
+	// GetAPIDescriptors returns the compiled descriptors of the JVS APIs and
+	// their dependencies, so clients can generate stubs without server
+	// reflection.
+	GetAPIDescriptors(context.Context, *GetAPIDescriptorsRequest) (*descriptorpb.FileDescriptorSet, error)
+	// GetDecisionTrace returns the trace of a denied token request, by the
+	// request ID returned with its error, to tell why it was denied.
+	GetDecisionTrace(context.Context, *GetDecisionTraceRequest) (*DecisionTrace, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminServiceServer struct {
+}
+
+func (UnimplementedAdminServiceServer) ListCategoryPolicies(context.Context, *ListCategoryPoliciesRequest) (*ListCategoryPoliciesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategoryPolicies not implemented")
+}
+func (UnimplementedAdminServiceServer) EnableCategory(context.Context, *EnableCategoryRequest) (*CategoryPolicy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableCategory not implemented")
+}
+func (UnimplementedAdminServiceServer) DisableCategory(context.Context, *DisableCategoryRequest) (*CategoryPolicy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableCategory not implemented")
+}
+func (UnimplementedAdminServiceServer) SetCategoryMaxTTL(context.Context, *SetCategoryMaxTTLRequest) (*CategoryPolicy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCategoryMaxTTL not implemented")
+}
+func (UnimplementedAdminServiceServer) GetAudienceAllowlist(context.Context, *GetAudienceAllowlistRequest) (*AudienceAllowlist, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAudienceAllowlist not implemented")
+}
+func (UnimplementedAdminServiceServer) UpdateAudienceAllowlist(context.Context, *UpdateAudienceAllowlistRequest) (*AudienceAllowlist, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAudienceAllowlist not implemented")
+}
+func (UnimplementedAdminServiceServer) DeprovisionUser(context.Context, *DeprovisionUserRequest) (*DeprovisionUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeprovisionUser not implemented")
+}
+func (UnimplementedAdminServiceServer) GrantQuota(context.Context, *GrantQuotaRequest) (*QuotaUsage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantQuota not implemented")
+}
+func (UnimplementedAdminServiceServer) ListValidators(context.Context, *ListValidatorsRequest) (*ListValidatorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListValidators not implemented")
+}
+func (UnimplementedAdminServiceServer) GetAPIDescriptors(context.Context, *GetAPIDescriptorsRequest) (*descriptorpb.FileDescriptorSet, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAPIDescriptors not implemented")
+}
+func (UnimplementedAdminServiceServer) GetDecisionTrace(context.Context, *GetDecisionTraceRequest) (*DecisionTrace, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDecisionTrace not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_ListCategoryPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCategoryPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListCategoryPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/ListCategoryPolicies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListCategoryPolicies(ctx, req.(*ListCategoryPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_EnableCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).EnableCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/EnableCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).EnableCategory(ctx, req.(*EnableCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DisableCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DisableCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/DisableCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DisableCategory(ctx, req.(*DisableCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetCategoryMaxTTL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCategoryMaxTTLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetCategoryMaxTTL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/SetCategoryMaxTTL",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetCategoryMaxTTL(ctx, req.(*SetCategoryMaxTTLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetAudienceAllowlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAudienceAllowlistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetAudienceAllowlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/GetAudienceAllowlist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetAudienceAllowlist(ctx, req.(*GetAudienceAllowlistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_UpdateAudienceAllowlist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAudienceAllowlistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).UpdateAudienceAllowlist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/UpdateAudienceAllowlist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).UpdateAudienceAllowlist(ctx, req.(*UpdateAudienceAllowlistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DeprovisionUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeprovisionUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DeprovisionUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/DeprovisionUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DeprovisionUser(ctx, req.(*DeprovisionUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GrantQuota_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantQuotaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GrantQuota(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/GrantQuota",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GrantQuota(ctx, req.(*GrantQuotaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListValidators_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListValidatorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListValidators(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/ListValidators",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListValidators(ctx, req.(*ListValidatorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetAPIDescriptors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAPIDescriptorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetAPIDescriptors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/GetAPIDescriptors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetAPIDescriptors(ctx, req.(*GetAPIDescriptorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetDecisionTrace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDecisionTraceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetDecisionTrace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.AdminService/GetDecisionTrace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetDecisionTrace(ctx, req.(*GetDecisionTraceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "abcxyz.jvs.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListCategoryPolicies",
+			Handler:    _AdminService_ListCategoryPolicies_Handler,
+		},
+		{
+			MethodName: "EnableCategory",
+			Handler:    _AdminService_EnableCategory_Handler,
+		},
+		{
+			MethodName: "DisableCategory",
+			Handler:    _AdminService_DisableCategory_Handler,
+		},
+		{
+			MethodName: "SetCategoryMaxTTL",
+			Handler:    _AdminService_SetCategoryMaxTTL_Handler,
+		},
+		{
+			MethodName: "GetAudienceAllowlist",
+			Handler:    _AdminService_GetAudienceAllowlist_Handler,
+		},
+		{
+			MethodName: "UpdateAudienceAllowlist",
+			Handler:    _AdminService_UpdateAudienceAllowlist_Handler,
+		},
+		{
+			MethodName: "DeprovisionUser",
+			Handler:    _AdminService_DeprovisionUser_Handler,
+		},
+		{
+			MethodName: "GrantQuota",
+			Handler:    _AdminService_GrantQuota_Handler,
+		},
+		{
+			MethodName: "ListValidators",
+			Handler:    _AdminService_ListValidators_Handler,
+		},
+		{
+			MethodName: "GetAPIDescriptors",
+			Handler:    _AdminService_GetAPIDescriptors_Handler,
+		},
+		{
+			MethodName: "GetDecisionTrace",
+			Handler:    _AdminService_GetDecisionTrace_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin_service.proto",
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// dnsLabelRegexp matches a DNS label, which the wildcard of a wildcard
+// subdomain audience pattern stands for.
+var dnsLabelRegexp = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// ValidateAudiencePattern returns an error if the audience pattern is invalid.
+// An audience pattern is one of:
+//
+//   - An exact audience, e.g. "https://api.example.com".
+//   - A prefix, ending with "*", e.g. "https://api.example.com/*".
+//   - A wildcard subdomain, with "*" as the first label of the host, e.g.
+//     "*.example.com" or "https://*.example.com". The wildcard matches exactly
+//     one label, like in TLS certificates, so it matches
+//     "https://eu.example.com" but not "https://example.com" or
+//     "https://a.eu.example.com".
+func ValidateAudiencePattern(pattern string) error {
+	i := strings.Index(pattern, "*")
+	switch {
+	case pattern == "":
+		return fmt.Errorf("audience pattern must not be empty")
+	case pattern == "*":
+		return fmt.Errorf("audience pattern %q must not match every audience", pattern)
+	case i < 0:
+		return nil
+	case strings.Count(pattern, "*") > 1:
+		return fmt.Errorf("audience pattern %q must have at most one wildcard", pattern)
+	case i == len(pattern)-1:
+		return nil
+	case strings.HasPrefix(pattern[i:], "*.") && (i == 0 || strings.HasSuffix(pattern[:i], "://")):
+		return nil
+	default:
+		return fmt.Errorf("wildcard of audience pattern %q must be at the end, or the first label of the host", pattern)
+	}
+}
+
+// MatchAudience reports whether the audience matches the audience pattern. See
+// [ValidateAudiencePattern] for the patterns. Invalid patterns only match
+// themselves.
+func MatchAudience(pattern, audience string) bool {
+	if ValidateAudiencePattern(pattern) != nil {
+		return pattern == audience
+	}
+
+	if i := strings.Index(pattern, "*."); i >= 0 {
+		prefix, suffix := pattern[:i], pattern[i+1:]
+		if len(audience) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(audience, prefix) || !strings.HasSuffix(audience, suffix) {
+			return false
+		}
+		return dnsLabelRegexp.MatchString(audience[len(prefix) : len(audience)-len(suffix)])
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(audience, prefix)
+	}
+	return pattern == audience
+}
+
+// MatchAudiences reports whether any of the audiences, e.g. the "aud" claim of
+// a token, matches any of the audience patterns.
+func MatchAudiences(patterns, audiences []string) bool {
+	return slices.ContainsFunc(audiences, func(aud string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			return MatchAudience(pattern, aud)
+		})
+	})
+}
@@ -0,0 +1,191 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"testing"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestValidateAudiencePattern(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		pattern string
+		wantErr string
+	}{
+		{
+			name:    "exact",
+			pattern: "https://api.example.com",
+		},
+		{
+			name:    "prefix",
+			pattern: "https://api.example.com/*",
+		},
+		{
+			name:    "wildcard_subdomain",
+			pattern: "*.example.com",
+		},
+		{
+			name:    "wildcard_subdomain_url",
+			pattern: "https://*.example.com/v1",
+		},
+		{
+			name:    "empty",
+			pattern: "",
+			wantErr: "audience pattern must not be empty",
+		},
+		{
+			name:    "match_all",
+			pattern: "*",
+			wantErr: `audience pattern "*" must not match every audience`,
+		},
+		{
+			name:    "many_wildcards",
+			pattern: "https://*.example.com/*",
+			wantErr: `audience pattern "https://*.example.com/*" must have at most one wildcard`,
+		},
+		{
+			name:    "wildcard_in_label",
+			pattern: "api-*.example.com",
+			wantErr: `wildcard of audience pattern "api-*.example.com" must be at the end, or the first label of the host`,
+		},
+		{
+			name:    "wildcard_inner_label",
+			pattern: "https://api.*.example.com",
+			wantErr: `wildcard of audience pattern "https://api.*.example.com" must be at the end, or the first label of the host`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateAudiencePattern(tc.pattern)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMatchAudience(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		pattern  string
+		audience string
+		want     bool
+	}{
+		{
+			name:     "exact",
+			pattern:  "https://api.example.com",
+			audience: "https://api.example.com",
+			want:     true,
+		},
+		{
+			name:     "exact_mismatch",
+			pattern:  "https://api.example.com",
+			audience: "https://api.example.com/v1",
+		},
+		{
+			name:     "prefix",
+			pattern:  "https://api.example.com/*",
+			audience: "https://api.example.com/v1/users",
+			want:     true,
+		},
+		{
+			name:     "prefix_mismatch",
+			pattern:  "https://api.example.com/*",
+			audience: "https://api.example.com",
+		},
+		{
+			name:     "wildcard_subdomain",
+			pattern:  "*.example.com",
+			audience: "eu.example.com",
+			want:     true,
+		},
+		{
+			name:     "wildcard_subdomain_url",
+			pattern:  "https://*.example.com",
+			audience: "https://eu.example.com",
+			want:     true,
+		},
+		{
+			name:     "wildcard_subdomain_apex",
+			pattern:  "https://*.example.com",
+			audience: "https://example.com",
+		},
+		{
+			name:     "wildcard_subdomain_empty_label",
+			pattern:  "https://*.example.com",
+			audience: "https://.example.com",
+		},
+		{
+			name:     "wildcard_subdomain_many_labels",
+			pattern:  "https://*.example.com",
+			audience: "https://a.eu.example.com",
+		},
+		{
+			name:     "wildcard_subdomain_other_domain",
+			pattern:  "https://*.example.com",
+			audience: "https://eu.example.com.evil.com",
+		},
+		{
+			name:     "wildcard_subdomain_path",
+			pattern:  "https://*.example.com",
+			audience: "https://evil.com/.example.com",
+		},
+		{
+			name:     "wildcard_subdomain_scheme",
+			pattern:  "https://*.example.com",
+			audience: "http://eu.example.com",
+		},
+		{
+			name:     "invalid_pattern",
+			pattern:  "api-*.example.com",
+			audience: "api-eu.example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := MatchAudience(tc.pattern, tc.audience), tc.want; got != want {
+				t.Errorf("MatchAudience(%q, %q) = %t, want %t", tc.pattern, tc.audience, got, want)
+			}
+		})
+	}
+}
+
+func TestMatchAudiences(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{"https://*.example.com", "payments"}
+
+	if !MatchAudiences(patterns, []string{"other", "https://eu.example.com"}) {
+		t.Errorf("expected audiences to match")
+	}
+	if MatchAudiences(patterns, []string{"other", "https://example.com"}) {
+		t.Errorf("expected audiences not to match")
+	}
+	if MatchAudiences(patterns, nil) {
+		t.Errorf("expected no audiences not to match")
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	// AuditRouteKey is the key in the JWT that holds where services should
+	// write the data access audit logs of requests made with the token, e.g.
+	// with the abcxyz/lumberjack client SDK. It is only set if the JVS is
+	// configured with an audit route.
+	AuditRouteKey string = "audit_route"
+
+	// AuditRouteProjectKey and AuditRouteLogIDKey are the members of the audit
+	// route that hold the Cloud Logging project and log ID.
+	AuditRouteProjectKey string = "project"
+	AuditRouteLogIDKey   string = "log_id"
+)
+
+// AuditRoute is where the data access audit logs of requests made with a
+// token should be written.
+type AuditRoute struct {
+	// Project is the Cloud Logging project, and LogID the ID of the log in it,
+	// e.g. "audit.abcxyz/data_access".
+	Project string
+	LogID   string
+}
+
+// GetAuditRoute retrieves the audit route of the token. It returns nil if the
+// token has no audit route.
+func GetAuditRoute(t jwt.Token) (*AuditRoute, error) {
+	if t == nil {
+		return nil, fmt.Errorf("token cannot be nil")
+	}
+
+	raw, ok := t.Get(AuditRouteKey)
+	if !ok {
+		return nil, nil
+	}
+
+	var members map[string]any
+	switch route := raw.(type) {
+	case map[string]string:
+		// Token was built, not parsed.
+		members = make(map[string]any, len(route))
+		for k, v := range route {
+			members[k] = v
+		}
+	case map[string]any:
+		members = route
+	default:
+		return nil, fmt.Errorf("found audit route, but was of unknown type %T", raw)
+	}
+
+	var out AuditRoute
+	for k, dst := range map[string]*string{
+		AuditRouteProjectKey: &out.Project,
+		AuditRouteLogIDKey:   &out.LogID,
+	} {
+		v, ok := members[k]
+		if !ok {
+			continue
+		}
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("found audit route, but %s was of unknown type %T", k, v)
+		}
+		*dst = str
+	}
+	return &out, nil
+}
+
+// SetAuditRoute sets the audit route of the token. It overwrites any existing
+// audit route.
+func SetAuditRoute(t jwt.Token, route *AuditRoute) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+	if route == nil {
+		return fmt.Errorf("audit route cannot be nil")
+	}
+
+	if err := t.Set(AuditRouteKey, map[string]string{
+		AuditRouteProjectKey: route.Project,
+		AuditRouteLogIDKey:   route.LogID,
+	}); err != nil {
+		return fmt.Errorf("failed to set audit route: %w", err)
+	}
+	return nil
+}
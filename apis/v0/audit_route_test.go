@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestGetAuditRoute(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	route := &AuditRoute{Project: "my-audit-project", LogID: "audit.abcxyz/data_access"}
+
+	cases := []struct {
+		name   string
+		token  jwt.Token
+		exp    *AuditRoute
+		expErr string
+	}{
+		{
+			name:   "nil_token",
+			token:  nil,
+			expErr: "token cannot be nil",
+		},
+		{
+			name:  "no_route",
+			token: testTokenBuilder(t, jwt.NewBuilder()),
+			exp:   nil,
+		},
+		{
+			name: "built_route",
+			token: func() jwt.Token {
+				token := testTokenBuilder(t, jwt.NewBuilder())
+				if err := SetAuditRoute(token, route); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			}(),
+			exp: route,
+		},
+		{
+			name: "parsed_route",
+			token: func() jwt.Token {
+				token := testTokenBuilder(t, jwt.NewBuilder())
+				if err := SetAuditRoute(token, route); err != nil {
+					t.Fatal(err)
+				}
+				b, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("KEY")))
+				if err != nil {
+					t.Fatal(err)
+				}
+				parsed, err := jwt.ParseInsecure(b, jwt.WithContext(ctx))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return parsed
+			}(),
+			exp: route,
+		},
+		{
+			name:   "wrong_type",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(AuditRouteKey, "my-audit-project")),
+			expErr: "found audit route, but was of unknown type string",
+		},
+		{
+			name:   "wrong_member_type",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(AuditRouteKey, map[string]any{"project": 1})),
+			expErr: "found audit route, but project was of unknown type int",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := GetAuditRoute(tc.token)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("audit route: diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
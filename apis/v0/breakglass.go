@@ -30,6 +30,11 @@ const (
 	// does not introduce additional risk, and breakglass is disabled by default.
 	BreakglassHMACSecret = "BHzwNUbxcgpNoDfzwzt4Dr2nVXByUCWl1m8Eq2Jh26CGqu8IQ0VdiyjxnCtNahh9" //nolint:gosec
 
+	// BreakglassIssuer is the issuer (iss) of breakglass tokens created by
+	// jvsctl. Verifiers that only accept some issuers must accept it to accept
+	// breakglass tokens.
+	BreakglassIssuer = "jvsctl"
+
 	// breakglassJustificationCategory is the category name for a breakglass
 	// justification.
 	breakglassJustificationCategory = "breakglass"
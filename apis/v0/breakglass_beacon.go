@@ -0,0 +1,148 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// breakglassBeaconTimeout is how long sending a single breakglass event may
+// take.
+const breakglassBeaconTimeout = 5 * time.Second
+
+// BreakglassUsedEvent is the event a verifier emits when it accepts a
+// breakglass token, so breakglass use is visible to security even though the
+// JVS never sees the token.
+type BreakglassUsedEvent struct {
+	// Subject is the "sub" claim of the breakglass token.
+	Subject string `json:"subject"`
+
+	// Audience is the "aud" claim of the breakglass token, the resources the
+	// token was created for.
+	Audience []string `json:"audience,omitempty"`
+
+	// TokenID is the "jti" claim of the breakglass token.
+	TokenID string `json:"jti,omitempty"`
+
+	// Explanation is the reason given for breakglass.
+	Explanation string `json:"explanation"`
+
+	// Resource is the resource the verifier protects, from
+	// [Config.BreakglassBeaconResource].
+	Resource string `json:"resource,omitempty"`
+
+	// UsedAt is when the verifier accepted the token.
+	UsedAt time.Time `json:"used_at"`
+}
+
+// breakglassBeacon logs, and optionally sends, an event for each accepted
+// breakglass token.
+type breakglassBeacon struct {
+	client   *http.Client
+	endpoint string
+	resource string
+}
+
+// emit logs a "breakglass used" event for the token, and sends it to the
+// endpoint if one is configured. The event is sent in the background and
+// failures are only logged, so the beacon never affects verification.
+func (b *breakglassBeacon) emit(ctx context.Context, token jwt.Token) {
+	if b == nil {
+		return
+	}
+
+	event := &BreakglassUsedEvent{
+		Subject:     token.Subject(),
+		Audience:    token.Audience(),
+		TokenID:     token.JwtID(),
+		Explanation: breakglassExplanation(token),
+		Resource:    b.resource,
+		UsedAt:      time.Now().UTC(),
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.WarnContext(ctx, "breakglass used",
+		"subject", event.Subject,
+		"audience", event.Audience,
+		"jti", event.TokenID,
+		"explanation", event.Explanation,
+		"resource", event.Resource)
+
+	if b.endpoint == "" {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := b.send(ctx, event); err != nil {
+			logger.WarnContext(ctx, "failed to send breakglass event",
+				"subject", event.Subject,
+				"error", err)
+		}
+	}()
+}
+
+func (b *breakglassBeacon) send(ctx context.Context, event *BreakglassUsedEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, breakglassBeaconTimeout)
+	defer cancel()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// breakglassExplanation returns the value of the token's breakglass
+// justification.
+func breakglassExplanation(token jwt.Token) string {
+	justifications, err := GetJustifications(token)
+	if err != nil {
+		return ""
+	}
+	for _, j := range justifications {
+		if j.GetCategory() == breakglassJustificationCategory {
+			return j.GetValue()
+		}
+	}
+	return ""
+}
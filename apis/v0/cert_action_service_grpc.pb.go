@@ -37,6 +37,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CertificateActionServiceClient interface {
 	CertificateAction(ctx context.Context, in *CertificateActionRequest, opts ...grpc.CallOption) (*CertificateActionResponse, error)
+	KeyStatus(ctx context.Context, in *KeyStatusRequest, opts ...grpc.CallOption) (*KeyStatusResponse, error)
 }
 
 type certificateActionServiceClient struct {
@@ -56,11 +57,21 @@ func (c *certificateActionServiceClient) CertificateAction(ctx context.Context,
 	return out, nil
 }
 
+func (c *certificateActionServiceClient) KeyStatus(ctx context.Context, in *KeyStatusRequest, opts ...grpc.CallOption) (*KeyStatusResponse, error) {
+	out := new(KeyStatusResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.CertificateActionService/KeyStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CertificateActionServiceServer is the server API for CertificateActionService service.
 // All implementations must embed UnimplementedCertificateActionServiceServer
 // for forward compatibility
 type CertificateActionServiceServer interface {
 	CertificateAction(context.Context, *CertificateActionRequest) (*CertificateActionResponse, error)
+	KeyStatus(context.Context, *KeyStatusRequest) (*KeyStatusResponse, error)
 	mustEmbedUnimplementedCertificateActionServiceServer()
 }
 
@@ -71,6 +82,9 @@ type UnimplementedCertificateActionServiceServer struct {
 func (UnimplementedCertificateActionServiceServer) CertificateAction(context.Context, *CertificateActionRequest) (*CertificateActionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CertificateAction not implemented")
 }
+func (UnimplementedCertificateActionServiceServer) KeyStatus(context.Context, *KeyStatusRequest) (*KeyStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeyStatus not implemented")
+}
 func (UnimplementedCertificateActionServiceServer) mustEmbedUnimplementedCertificateActionServiceServer() {
 }
 
@@ -103,6 +117,24 @@ func _CertificateActionService_CertificateAction_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CertificateActionService_KeyStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateActionServiceServer).KeyStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.CertificateActionService/KeyStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateActionServiceServer).KeyStatus(ctx, req.(*KeyStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CertificateActionService_ServiceDesc is the grpc.ServiceDesc for CertificateActionService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -114,6 +146,10 @@ var CertificateActionService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CertificateAction",
 			Handler:    _CertificateActionService_CertificateAction_Handler,
 		},
+		{
+			MethodName: "KeyStatus",
+			Handler:    _CertificateActionService_KeyStatus_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "cert_action_service.proto",
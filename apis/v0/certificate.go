@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc"
+)
+
+// ClaimsExtensionOID is the OID of the X.509 extension of certificates issued
+// by the JVS that holds the same claims as a justification token, e.g. the
+// requestor and justifications. It's a private OID, and the extension is not
+// critical.
+var ClaimsExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 9, 1}
+
+// NewClaimsExtension returns the certificate extension that holds the claims
+// of the token, as a UTF8String of their JSON.
+func NewClaimsExtension(t jwt.Token) (pkix.Extension, error) {
+	if t == nil {
+		return pkix.Extension{}, fmt.Errorf("token cannot be nil")
+	}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	value, err := asn1.MarshalWithParams(string(b), "utf8")
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("failed to encode claims: %w", err)
+	}
+	return pkix.Extension{
+		Id:    ClaimsExtensionOID,
+		Value: value,
+	}, nil
+}
+
+// CertificateClaims returns the claims embedded in a certificate issued by the
+// JVS as a token, so they can be read with the same functions as token claims,
+// e.g. [GetRequestor] and [GetJustifications]. The certificate must already be
+// verified, e.g. by the TLS handshake.
+func CertificateClaims(cert *x509.Certificate) (jwt.Token, error) {
+	if cert == nil {
+		return nil, fmt.Errorf("certificate cannot be nil")
+	}
+
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(ClaimsExtensionOID) {
+			continue
+		}
+
+		var claims string
+		if rest, err := asn1.Unmarshal(ext.Value, &claims); err != nil {
+			return nil, fmt.Errorf("failed to decode claims extension: %w", err)
+		} else if len(rest) > 0 {
+			return nil, fmt.Errorf("failed to decode claims extension: trailing data")
+		}
+
+		t, err := jwt.Parse([]byte(claims),
+			jwt.WithVerify(false),
+			jwt.WithValidate(false),
+			WithTypedJustifications())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse claims: %w", err)
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("certificate has no claims extension")
+}
+
+// CreateCertificate requests a short-lived client certificate for the key
+// using the JVS. It returns the certificate, followed by the certificate of
+// the CA that issued it.
+func CreateCertificate(ctx context.Context, client JVSServiceClient, key crypto.Signer, req *CreateJustificationRequest, opts ...grpc.CallOption) ([]*x509.Certificate, error) {
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate signing request: %w", err)
+	}
+
+	resp, err := client.CreateCertificate(ctx, &CreateCertificateRequest{
+		Request: req,
+		Csr:     csr,
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	return ParseCertificateChain([]byte(resp.GetCertificateChain()))
+}
+
+// ParseCertificateChain parses the PEM-encoded certificates.
+func ParseCertificateChain(b []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return chain, nil
+}
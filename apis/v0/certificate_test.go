@@ -0,0 +1,189 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCertificateClaims(t *testing.T) {
+	t.Parallel()
+
+	justs := []*Justification{
+		{Category: "explanation", Value: "debugging"},
+	}
+	token, err := jwt.NewBuilder().
+		JwtID("test-jwt").
+		Subject("me@example.com").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRequestor(token, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetJustifications(token, justs); err != nil {
+		t.Fatal(err)
+	}
+	ext, err := NewClaimsExtension(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name          string
+		extensions    []pkix.Extension
+		wantRequestor string
+		wantJusts     []*Justification
+		expErr        string
+	}{
+		{
+			name:          "claims",
+			extensions:    []pkix.Extension{ext},
+			wantRequestor: "me@example.com",
+			wantJusts:     justs,
+		},
+		{
+			name:   "no_claims",
+			expErr: "certificate has no claims extension",
+		},
+		{
+			name: "malformed_claims",
+			extensions: []pkix.Extension{
+				{Id: ClaimsExtensionOID, Value: []byte("not asn1")},
+			},
+			expErr: "failed to decode claims extension",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cert := testCertificate(t, tc.extensions)
+
+			got, err := CertificateClaims(cert)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			requestor, err := GetRequestor(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requestor != tc.wantRequestor {
+				t.Errorf("requestor: expected %q to be %q", requestor, tc.wantRequestor)
+			}
+			gotJusts, err := GetJustifications(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantJusts, gotJusts, protocmp.Transform()); diff != "" {
+				t.Errorf("justifications (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseCertificateChain(t *testing.T) {
+	t.Parallel()
+
+	cert := testCertificate(t, nil)
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+	cases := []struct {
+		name    string
+		pem     []byte
+		wantLen int
+		expErr  string
+	}{
+		{
+			name:    "chain",
+			pem:     append(append([]byte{}, block...), block...),
+			wantLen: 2,
+		},
+		{
+			name: "skips_other_blocks",
+			pem: append(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("key")}),
+				block...),
+			wantLen: 1,
+		},
+		{
+			name:   "empty",
+			expErr: "no certificates found",
+		},
+		{
+			name:   "malformed",
+			pem:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}),
+			expErr: "failed to parse certificate",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseCertificateChain(tc.pem)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := len(got), tc.wantLen; got != want {
+				t.Errorf("expected %d certificates to be %d", got, want)
+			}
+		})
+	}
+}
+
+// testCertificate creates a self-signed certificate with the extensions.
+func testCertificate(tb testing.TB, extensions []pkix.Extension) *x509.Certificate {
+	tb.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{CommonName: "me@example.com"},
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtraExtensions: extensions,
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, key.Public(), key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return cert
+}
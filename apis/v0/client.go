@@ -17,11 +17,15 @@ package v0
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	googleidtoken "google.golang.org/api/idtoken"
 )
 
 // Client allows for getting JWK keys from the JVS and validating JWTs with
@@ -29,34 +33,128 @@ import (
 type Client struct {
 	config *Config
 	keys   jwk.Set
+	skew   time.Duration
+	usage  *usageReporter
+
+	// beacon is nil if the client doesn't emit breakglass events.
+	beacon *breakglassBeacon
+
+	// revocations is nil if the client doesn't check the revocation list.
+	revocations *revocationChecker
+
+	// requiredAnnotations are the annotations justifications must carry, keyed
+	// by category.
+	requiredAnnotations map[string][]string
+
+	// issuerKeys are the keys of each trusted issuer, if the client trusts
+	// multiple issuers. Then keys is nil.
+	issuerKeys map[string]jwk.Set
 }
 
 // NewClient returns a JVSClient with the cache initialized.
 func NewClient(ctx context.Context, config *Config) (*Client, error) {
-	c := jwk.NewCache(ctx)
-	if err := c.Register(config.JWKSEndpoint, jwk.WithMinRefreshInterval(config.CacheTimeout)); err != nil {
-		return nil, fmt.Errorf("failed to register: %w", err)
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to validate configuration: %w", err)
 	}
 
-	// check that cache is correctly set up and certs are available
-	if _, err := c.Refresh(ctx, config.JWKSEndpoint); err != nil {
-		return nil, fmt.Errorf("failed to retrieve JVS public keys: %w", err)
+	var keys jwk.Set
+	var issuerKeys map[string]jwk.Set
+	if config.JWKSEndpoint != "" {
+		set, err := newIssuerKeys(ctx, config.JWKSEndpoint, config)
+		if err != nil {
+			return nil, err
+		}
+		keys = set
+	} else {
+		issuerKeys = make(map[string]jwk.Set, len(config.Issuers))
+		for _, iss := range slices.Sorted(maps.Keys(config.Issuers)) {
+			set, err := newIssuerKeys(ctx, config.Issuers[iss], config)
+			if err != nil {
+				return nil, fmt.Errorf("issuer %q: %w", iss, err)
+			}
+			issuerKeys[iss] = set
+		}
 	}
 
-	cached := jwk.NewCachedSet(c, config.JWKSEndpoint)
+	skew := DefaultAllowedClockSkew
+	if config.AllowedClockSkew != nil {
+		skew = *config.AllowedClockSkew
+	}
 
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("failed to validate configuration: %w", err)
+	var usage *usageReporter
+	if config.UsageReportEndpoint != "" {
+		ts, err := googleidtoken.NewTokenSource(ctx, config.UsageReportAudience)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create usage report id token source: %w", err)
+		}
+		usage = &usageReporter{
+			client:     &http.Client{Timeout: usageReportTimeout},
+			tokens:     ts,
+			endpoint:   config.UsageReportEndpoint,
+			sampleRate: config.UsageReportSampleRate,
+		}
+	}
+
+	var beacon *breakglassBeacon
+	if config.BreakglassBeacon || config.BreakglassBeaconEndpoint != "" {
+		beacon = &breakglassBeacon{
+			client:   &http.Client{Timeout: breakglassBeaconTimeout},
+			endpoint: config.BreakglassBeaconEndpoint,
+			resource: config.BreakglassBeaconResource,
+		}
+	}
+
+	requiredAnnotations, err := config.RequiredAnnotationKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var revocations *revocationChecker
+	if config.RevocationEndpoint != "" {
+		revocations, err = newRevocationChecker(ctx, config.RevocationEndpoint, config.CacheTimeout)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &Client{
-		config: config,
-		keys:   cached,
+		config:      config,
+		keys:        keys,
+		skew:        skew,
+		usage:       usage,
+		beacon:      beacon,
+		revocations: revocations,
+		issuerKeys:  issuerKeys,
+
+		requiredAnnotations: requiredAnnotations,
 	}, nil
 }
 
+// newIssuerKeys returns the keys served at the JWKS endpoint, after checking
+// they can be fetched.
+func newIssuerKeys(ctx context.Context, endpoint string, config *Config) (jwk.Set, error) {
+	opts := []JWKSProviderOption{WithJWKSRefreshInterval(config.CacheTimeout)}
+	if len(config.JWKSRootKeys) > 0 {
+		opts = append(opts, WithJWKSRootKeys(config.JWKSRootKeys...))
+	}
+	keys, err := NewJWKSProvider(ctx, endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwks provider: %w", err)
+	}
+
+	// check that the endpoint is correctly set up and certs are available
+	if _, err := keys.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to retrieve JVS public keys: %w", err)
+	}
+	return keys, nil
+}
+
 // ValidateJWT takes a jwt string, converts it to a JWT, and validates the
-// signature against the keys in the JWKs endpoint.
+// signature against the keys in the JWKs endpoint. The token must have an
+// expiry and justifications, so other objects signed by the JVS keys, such as
+// receipts, are rejected. If accepted issuers are configured, tokens of other
+// issuers, including breakglass tokens, are rejected. Accepted breakglass
+// tokens are reported by the breakglass beacon, if configured.
 func (j *Client) ValidateJWT(ctx context.Context, jwtStr, expectedSubject string) (jwt.Token, error) {
 	// Handle breakglass tokens
 	token, err := ParseBreakglassToken(ctx, jwtStr)
@@ -67,19 +165,67 @@ func (j *Client) ValidateJWT(ctx context.Context, jwtStr, expectedSubject string
 		if !j.config.AllowBreakglass {
 			return nil, fmt.Errorf("breakglass is forbidden, denying")
 		}
+		if err := j.checkIssuer(token); err != nil {
+			return nil, fmt.Errorf("breakglass token: %w", err)
+		}
+		if err := j.checkAudiences(token); err != nil {
+			return nil, fmt.Errorf("breakglass token: %w", err)
+		}
+		j.beacon.emit(ctx, token)
 		return token, nil
 	}
 
 	// If we got this far, the token was not breakglass, so parse as normal.
-	token, err = jwt.Parse([]byte(jwtStr),
+	if err := CheckTokenType(jwtStr, j.config.AcceptedTokenTypes); err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.ParseOption{
 		jwt.WithContext(ctx),
-		jwt.WithKeySet(j.keys, jws.WithInferAlgorithmFromKey(true)),
-		jwt.WithAcceptableSkew(5*time.Second),
+		jwt.WithAcceptableSkew(j.skew),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
 		WithTypedJustifications(),
-	)
+	}
+	if ns := j.config.ClaimsNamespace; ns != "" {
+		opts = append(opts, jwt.WithTypedClaim(ns+JustificationsKey, []*Justification{}))
+	}
+	if j.issuerKeys == nil {
+		opts = append(opts, jwt.WithKeySet(j.keys, jws.WithInferAlgorithmFromKey(true)))
+	} else {
+		iss, keys, err := j.lookupIssuer(jwtStr)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts,
+			jwt.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+			jwt.WithIssuer(iss))
+	}
+
+	token, err = jwt.Parse([]byte(jwtStr), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify jwt: %w", err)
 	}
+	if ns := j.config.ClaimsNamespace; ns != "" {
+		if err := UnnamespaceClaims(token, ns); err != nil {
+			return nil, fmt.Errorf("failed to read namespaced claims: %w", err)
+		}
+	}
+	if !hasJustifications(token) {
+		return nil, fmt.Errorf("jwt has no %s or %s claim", JustificationsKey, EncryptedJustificationsKey)
+	}
+	if err := j.checkIssuer(token); err != nil {
+		return nil, err
+	}
+	if err := j.checkAudiences(token); err != nil {
+		return nil, err
+	}
+	if err := j.checkAnnotations(token); err != nil {
+		return nil, err
+	}
+	if err := j.revocations.check(ctx, token); err != nil {
+		return nil, err
+	}
+	j.usage.maybeReport(ctx, []byte(jwtStr))
 
 	if got, want := token.Subject(), expectedSubject; got != want && expectedSubject != "" {
 		return nil, fmt.Errorf("subject %q does not match expected subject %q", got, want)
@@ -87,3 +233,99 @@ func (j *Client) ValidateJWT(ctx context.Context, jwtStr, expectedSubject string
 
 	return token, nil
 }
+
+// CheckTokenType returns an error if the token's "typ" header isn't one of the
+// accepted types. If none are, it must be unset or [DefaultTokenType].
+// Receipts, policy attestations and detached signatures are signed by the same
+// keys, but aren't tokens.
+func CheckTokenType(jwtStr string, accepted []string) error {
+	msg, err := jws.Parse([]byte(jwtStr))
+	if err != nil {
+		return fmt.Errorf("failed to parse jwt: %w", err)
+	}
+	for _, sig := range msg.Signatures() {
+		typ := sig.ProtectedHeaders().Type()
+		if len(accepted) == 0 {
+			if typ != "" && !TokenTypesEqual(typ, DefaultTokenType) {
+				return fmt.Errorf("token type %q is not a jwt", typ)
+			}
+			continue
+		}
+
+		// Untyped tokens are read as JWTs.
+		if typ == "" {
+			typ = DefaultTokenType
+		}
+		if !slices.ContainsFunc(accepted, func(want string) bool { return TokenTypesEqual(typ, want) }) {
+			return fmt.Errorf("token type %q is not one of the accepted token types %q", typ, accepted)
+		}
+	}
+	return nil
+}
+
+// checkIssuer returns an error if accepted issuers are configured, and the
+// token's issuer isn't one of them.
+func (j *Client) checkIssuer(token jwt.Token) error {
+	if len(j.config.AcceptedIssuers) == 0 || slices.Contains(j.config.AcceptedIssuers, token.Issuer()) {
+		return nil
+	}
+	return fmt.Errorf("issuer %q is not one of the accepted issuers %q", token.Issuer(), j.config.AcceptedIssuers)
+}
+
+// checkAudiences returns an error if audience patterns are configured, and
+// none of the token's audiences match them.
+func (j *Client) checkAudiences(token jwt.Token) error {
+	if len(j.config.Audiences) == 0 || MatchAudiences(j.config.Audiences, token.Audience()) {
+		return nil
+	}
+	return fmt.Errorf("audiences %q do not match any of the accepted audiences %q", token.Audience(), j.config.Audiences)
+}
+
+// checkAnnotations returns an error if a justification lacks an annotation
+// required for its category. Encrypted justifications can't be checked, so
+// they are rejected when annotations are required.
+func (j *Client) checkAnnotations(token jwt.Token) error {
+	if len(j.requiredAnnotations) == 0 {
+		return nil
+	}
+	if HasEncryptedJustifications(token) {
+		return fmt.Errorf("justifications are encrypted, so required annotations can't be checked")
+	}
+
+	justifications, err := GetJustifications(token)
+	if err != nil {
+		return fmt.Errorf("failed to get justifications: %w", err)
+	}
+	for _, just := range justifications {
+		if missing := MissingAnnotations(just, j.requiredAnnotations); len(missing) > 0 {
+			return fmt.Errorf("justification of category %q is missing required annotations %q", just.GetCategory(), missing)
+		}
+	}
+	return nil
+}
+
+// hasJustifications reports whether the token carries justifications, in the
+// clear or encrypted. Every token the JVS issues does.
+func hasJustifications(token jwt.Token) bool {
+	if _, ok := token.Get(JustificationsKey); ok {
+		return true
+	}
+	_, ok := token.Get(EncryptedJustificationsKey)
+	return ok
+}
+
+// lookupIssuer returns the issuer of the unverified token and the keys to
+// verify it with. It returns an error if the issuer isn't trusted.
+func (j *Client) lookupIssuer(jwtStr string) (string, jwk.Set, error) {
+	unverified, err := jwt.ParseInsecure([]byte(jwtStr))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse jwt: %w", err)
+	}
+
+	iss := unverified.Issuer()
+	keys, ok := j.issuerKeys[iss]
+	if !ok {
+		return "", nil, fmt.Errorf("issuer %q is not trusted", iss)
+	}
+	return iss, keys, nil
+}
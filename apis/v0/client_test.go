@@ -19,10 +19,12 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,6 +33,7 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"golang.org/x/oauth2"
 
 	"github.com/abcxyz/pkg/testutil"
 )
@@ -98,6 +101,17 @@ func TestValidateJWT(t *testing.T) {
 	tok2 := testCreateToken(t, "test_id_2")
 	breakglassToken := testCreateBreakglassToken(t)
 
+	// Receipts have neither an expiry nor justifications, and must not be
+	// accepted as tokens even without their type.
+	noExpToken := testCreateToken(t, "no_exp")
+	if err := noExpToken.Remove(jwt.ExpirationKey); err != nil {
+		t.Fatal(err)
+	}
+	noJustsToken := testCreateToken(t, "no_justs")
+	if err := noJustsToken.Remove(JustificationsKey); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		name            string
 		jwt             string
@@ -144,6 +158,29 @@ func TestValidateJWT(t *testing.T) {
 			sub:     "bad_sub",
 			wantErr: "does not match expected subject",
 		},
+		{
+			name:    "no_expiration",
+			jwt:     testSignTokenPrivateKey(t, noExpToken, privateKey, keyID),
+			sub:     "test_sub",
+			wantErr: `"exp" not satisfied: required claim not found`,
+		},
+		{
+			name:    "no_justifications",
+			jwt:     testSignTokenPrivateKey(t, noJustsToken, privateKey, keyID),
+			sub:     "test_sub",
+			wantErr: "jwt has no justs or justs_enc claim",
+		},
+		{
+			name:    "not_a_jwt",
+			jwt:     testSignTokenType(t, tok, privateKey, keyID, ReceiptType),
+			sub:     "test_sub",
+			wantErr: `token type "jvs-receipt+json" is not a jwt`,
+		},
+		{
+			name:    "forged_from_signature",
+			jwt:     testForgeTokenFromSignature(t, privateKey, keyID),
+			wantErr: `token type "jvs-signature+jws" is not a jwt`,
+		},
 	}
 
 	for _, tc := range tests {
@@ -239,6 +276,56 @@ func testSignTokenPrivateKey(tb testing.TB, tok jwt.Token, privateKey *ecdsa.Pri
 	return string(valid)
 }
 
+func testSignTokenType(tb testing.TB, tok jwt.Token, privateKey *ecdsa.PrivateKey, keyID, typ string) string {
+	tb.Helper()
+
+	hdrs := jws.NewHeaders()
+	if err := hdrs.Set(jws.KeyIDKey, keyID); err != nil {
+		tb.Fatal(err)
+	}
+	if err := hdrs.Set(jws.TypeKey, typ); err != nil {
+		tb.Fatal(err)
+	}
+
+	b, err := jwt.Sign(tok, jwt.WithKey(jwa.ES256, privateKey, jws.WithProtectedHeaders(hdrs)))
+	if err != nil {
+		tb.Fatalf("failed to sign token: %s\n", err)
+	}
+	return string(b)
+}
+
+// testForgeTokenFromSignature signs a "digest" that is really a set of claims,
+// the way the JVS signs payloads, and splices it into a compact JWT.
+func testForgeTokenFromSignature(tb testing.TB, privateKey *ecdsa.PrivateKey, keyID string) string {
+	tb.Helper()
+
+	claims := []byte(`{"aud":"prod","exp":99999999999}`)
+	if len(claims) != 32 {
+		tb.Fatalf("claims must look like a digest, got %d bytes", len(claims))
+	}
+
+	hdrs := jws.NewHeaders()
+	for k, v := range map[string]any{
+		jws.KeyIDKey:    keyID,
+		jws.TypeKey:     SignatureType,
+		jws.CriticalKey: []string{"b64"},
+		"b64":           false,
+	} {
+		if err := hdrs.Set(k, v); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	sig, err := jws.Sign(nil,
+		jws.WithKey(jwa.ES256, privateKey, jws.WithProtectedHeaders(hdrs)),
+		jws.WithDetachedPayload(SignatureEnvelope(claims)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	parts := strings.Split(string(sig), ".")
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + parts[2]
+}
+
 func testSignBreakglassToken(tb testing.TB, token jwt.Token) string {
 	tb.Helper()
 
@@ -248,3 +335,895 @@ func testSignBreakglassToken(tb testing.TB, token jwt.Token) string {
 	}
 	return str
 }
+
+func TestValidateJWT_usageReport(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports := make(chan *KeyUsageReport, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	})
+	mux.HandleFunc("/key-usage", func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer test-id-token"; got != want {
+			t.Errorf("expected authorization %q to be %q", got, want)
+		}
+		var report KeyUsageReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Error(err)
+		}
+		reports <- &report
+		w.WriteHeader(http.StatusAccepted)
+	})
+	svr := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		svr.Close()
+	})
+
+	client, err := NewClient(ctx, &Config{
+		JWKSEndpoint: svr.URL + "/.well-known/jwks",
+		CacheTimeout: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ID tokens are minted from the application default credentials, which
+	// tests don't have.
+	client.usage = &usageReporter{
+		client:     svr.Client(),
+		tokens:     oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-id-token", TokenType: "Bearer"}),
+		endpoint:   svr.URL + "/key-usage",
+		sampleRate: 1,
+	}
+
+	tok := testCreateToken(t, "test_id")
+	if _, err := client.ValidateJWT(ctx, testSignTokenPrivateKey(t, tok, privateKey, keyID), "test_sub"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-reports:
+		if diff := cmp.Diff(&KeyUsageReport{KeyID: keyID, SampleRate: 1}, got); diff != "" {
+			t.Errorf("report (-want, +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a key usage report")
+	}
+}
+
+func TestValidateJWT_breakglassBeacon(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	events := make(chan *BreakglassUsedEvent, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"keys":[]}`)
+	})
+	mux.HandleFunc("/breakglass", func(w http.ResponseWriter, r *http.Request) {
+		var event BreakglassUsedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events <- &event
+		w.WriteHeader(http.StatusAccepted)
+	})
+	svr := httptest.NewServer(mux)
+	t.Cleanup(svr.Close)
+
+	client, err := NewClient(ctx, &Config{
+		JWKSEndpoint:             svr.URL + "/.well-known/jwks",
+		CacheTimeout:             5 * time.Minute,
+		AllowBreakglass:          true,
+		BreakglassBeaconEndpoint: svr.URL + "/breakglass",
+		BreakglassBeaconResource: "prod-database",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok := testSignBreakglassToken(t, testCreateToken(t, "test_id"))
+	if _, err := client.ValidateJWT(ctx, tok, "test_sub"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-events:
+		want := &BreakglassUsedEvent{
+			Subject:     "test_sub",
+			Audience:    []string{"test_aud"},
+			TokenID:     "test_id",
+			Explanation: "testing",
+			Resource:    "prod-database",
+		}
+		if got.UsedAt.IsZero() {
+			t.Error("expected the event to have a time")
+		}
+		got.UsedAt = time.Time{}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("event (-want, +got):\n%s", diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a breakglass event")
+	}
+}
+
+func TestValidateJWT_multipleIssuers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	prodKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stagingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both deployments use the same key ID, to show the keys of the token's
+	// issuer are used.
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+	testJWKS := func(privateKey *ecdsa.PrivateKey) string {
+		key, err := jwk.FromRaw(privateKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := key.Set(jwk.KeyIDKey, keyID); err != nil {
+			t.Fatal(err)
+		}
+		j, err := json.Marshal(map[string][]jwk.Key{"keys": {key}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s", j)
+		}))
+		t.Cleanup(svr.Close)
+		return svr.URL + "/.well-known/jwks"
+	}
+
+	client, err := NewClient(ctx, &Config{
+		Issuers: map[string]string{
+			"jvs.corp":         testJWKS(prodKey),
+			"jvs-staging.corp": testJWKS(stagingKey),
+		},
+		CacheTimeout: 5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testToken := func(iss string, privateKey *ecdsa.PrivateKey) string {
+		tok := testCreateToken(t, "test_id")
+		if err := tok.Set(jwt.IssuerKey, iss); err != nil {
+			t.Fatal(err)
+		}
+		return testSignTokenPrivateKey(t, tok, privateKey, keyID)
+	}
+
+	// Policy attestations name a trusted issuer, but never expire.
+	testAttestation := func(typ string) string {
+		payload, err := json.Marshal(&PolicyAttestation{
+			Issuer:   "jvs.corp",
+			Version:  "abc",
+			IssuedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdrs := jws.NewHeaders()
+		if err := hdrs.Set(jws.KeyIDKey, keyID); err != nil {
+			t.Fatal(err)
+		}
+		if typ != "" {
+			if err := hdrs.Set(jws.TypeKey, typ); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, prodKey, jws.WithProtectedHeaders(hdrs)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name    string
+		jwt     string
+		wantErr string
+	}{
+		{
+			name: "prod",
+			jwt:  testToken("jvs.corp", prodKey),
+		},
+		{
+			name: "staging",
+			jwt:  testToken("jvs-staging.corp", stagingKey),
+		},
+		{
+			name:    "other_issuers_key",
+			jwt:     testToken("jvs.corp", stagingKey),
+			wantErr: "failed to verify jwt",
+		},
+		{
+			name:    "untrusted_issuer",
+			jwt:     testToken("jvs.example.com", prodKey),
+			wantErr: `issuer "jvs.example.com" is not trusted`,
+		},
+		{
+			name:    "policy_attestation",
+			jwt:     testAttestation(PolicyAttestationType),
+			wantErr: `token type "jvs-policy+json" is not a jwt`,
+		},
+		{
+			name:    "untyped_policy_attestation",
+			jwt:     testAttestation(""),
+			wantErr: `"exp" not satisfied`,
+		},
+		{
+			name:    "malformed",
+			jwt:     "not-a-jwt",
+			wantErr: "failed to parse",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := client.ValidateJWT(ctx, tc.jwt, "test_sub")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateJWT_revoked(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revokedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	list, err := json.Marshal(&RevocationList{Revocations: []*Revocation{
+		{PrincipalSHA256: RevocationPrincipalDigest("Left@example.com"), RevokedAt: revokedAt},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	})
+	mux.HandleFunc("/revocations", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", list)
+	})
+	svr := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		svr.Close()
+	})
+
+	client, err := NewClient(ctx, &Config{
+		JWKSEndpoint:       svr.URL + "/.well-known/jwks",
+		CacheTimeout:       5 * time.Minute,
+		RevocationEndpoint: svr.URL + "/revocations",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testToken := func(sub, requestor string, issuedAt time.Time) string {
+		tok := testCreateToken(t, "test_id")
+		if err := tok.Set(jwt.SubjectKey, sub); err != nil {
+			t.Fatal(err)
+		}
+		if err := tok.Set(jwt.IssuedAtKey, issuedAt); err != nil {
+			t.Fatal(err)
+		}
+		if requestor != "" {
+			if err := tok.Set(RequestorKey, requestor); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return testSignTokenPrivateKey(t, tok, privateKey, keyID)
+	}
+
+	cases := []struct {
+		name    string
+		token   string
+		subject string
+		wantErr string
+	}{
+		{
+			name:    "not_revoked",
+			token:   testToken("me@example.com", "", revokedAt.Add(-time.Minute)),
+			subject: "me@example.com",
+		},
+		{
+			name:    "revoked_subject",
+			token:   testToken("left@example.com", "", revokedAt.Add(-time.Minute)),
+			subject: "left@example.com",
+			wantErr: `token of "left@example.com" was revoked at`,
+		},
+		{
+			name:    "revoked_requestor",
+			token:   testToken("me@example.com", "left@example.com", revokedAt),
+			subject: "me@example.com",
+			wantErr: `token of "left@example.com" was revoked at`,
+		},
+		{
+			name:    "issued_after_revocation",
+			token:   testToken("left@example.com", "", revokedAt.Add(time.Minute)),
+			subject: "left@example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := client.ValidateJWT(ctx, tc.token, tc.subject)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	// The revocation list must be reachable when the client is created.
+	if _, err := NewClient(ctx, &Config{
+		JWKSEndpoint:       svr.URL + "/.well-known/jwks",
+		CacheTimeout:       5 * time.Minute,
+		RevocationEndpoint: svr.URL + "/missing",
+	}); err == nil {
+		t.Error("expected error for missing revocation list")
+	}
+}
+
+func TestValidateJWT_clockSkew(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	}))
+	t.Cleanup(svr.Close)
+
+	// The token is valid from a few seconds in the future, as if the issuer's
+	// clock were ahead.
+	tok := testCreateToken(t, "test_id")
+	if err := tok.Set(jwt.NotBeforeKey, time.Now().UTC().Add(3*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	token := testSignTokenPrivateKey(t, tok, privateKey, keyID)
+
+	zero := time.Duration(0)
+	cases := []struct {
+		name    string
+		skew    *time.Duration
+		wantErr string
+	}{
+		{
+			name: "default_skew",
+		},
+		{
+			name:    "zero_skew",
+			skew:    &zero,
+			wantErr: `"nbf" not satisfied`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(ctx, &Config{
+				JWKSEndpoint:     svr.URL,
+				CacheTimeout:     5 * time.Minute,
+				AllowedClockSkew: tc.skew,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.ValidateJWT(ctx, token, "")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateJWT_acceptedIssuers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	}))
+	t.Cleanup(svr.Close)
+
+	token := testSignTokenPrivateKey(t, testCreateToken(t, "test_id"), privateKey, keyID)
+
+	breakglassToken := testCreateBreakglassToken(t)
+	if err := breakglassToken.Set(jwt.IssuerKey, BreakglassIssuer); err != nil {
+		t.Fatal(err)
+	}
+	breakglass := testSignBreakglassToken(t, breakglassToken)
+
+	cases := []struct {
+		name     string
+		accepted []string
+		token    string
+		wantErr  string
+	}{
+		{
+			name:  "any_issuer",
+			token: token,
+		},
+		{
+			name:     "accepted",
+			accepted: []string{"test_iss"},
+			token:    token,
+		},
+		{
+			name:     "not_accepted",
+			accepted: []string{"jvs.corp"},
+			token:    token,
+			wantErr:  `issuer "test_iss" is not one of the accepted issuers ["jvs.corp"]`,
+		},
+		{
+			name:     "breakglass_not_accepted",
+			accepted: []string{"test_iss"},
+			token:    breakglass,
+			wantErr:  `breakglass token: issuer "jvsctl" is not one of the accepted issuers ["test_iss"]`,
+		},
+		{
+			name:     "breakglass_accepted",
+			accepted: []string{"test_iss", BreakglassIssuer},
+			token:    breakglass,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(ctx, &Config{
+				JWKSEndpoint:    svr.URL,
+				CacheTimeout:    5 * time.Minute,
+				AllowBreakglass: true,
+				AcceptedIssuers: tc.accepted,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.ValidateJWT(ctx, tc.token, "")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateJWT_audiences(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	}))
+	t.Cleanup(svr.Close)
+
+	tok := testCreateToken(t, "test_id")
+	if err := tok.Set(jwt.AudienceKey, []string{"https://eu.example.com", "test_aud"}); err != nil {
+		t.Fatal(err)
+	}
+	token := testSignTokenPrivateKey(t, tok, privateKey, keyID)
+	breakglass := testSignBreakglassToken(t, testCreateBreakglassToken(t))
+
+	cases := []struct {
+		name      string
+		audiences []string
+		token     string
+		wantErr   string
+	}{
+		{
+			name:  "any_audience",
+			token: token,
+		},
+		{
+			name:      "exact",
+			audiences: []string{"test_aud"},
+			token:     token,
+		},
+		{
+			name:      "prefix",
+			audiences: []string{"test_*"},
+			token:     token,
+		},
+		{
+			name:      "wildcard_subdomain",
+			audiences: []string{"https://*.example.com"},
+			token:     token,
+		},
+		{
+			name:      "not_accepted",
+			audiences: []string{"https://*.example.org", "other_aud"},
+			token:     token,
+			wantErr:   `audiences ["https://eu.example.com" "test_aud"] do not match any of the accepted audiences ["https://*.example.org" "other_aud"]`,
+		},
+		{
+			name:      "breakglass_not_accepted",
+			audiences: []string{"other_aud"},
+			token:     breakglass,
+			wantErr:   `breakglass token: audiences ["test_aud"] do not match any of the accepted audiences ["other_aud"]`,
+		},
+		{
+			name:      "breakglass_accepted",
+			audiences: []string{"test_aud"},
+			token:     breakglass,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(ctx, &Config{
+				JWKSEndpoint:    svr.URL,
+				CacheTimeout:    5 * time.Minute,
+				AllowBreakglass: true,
+				Audiences:       tc.audiences,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.ValidateJWT(ctx, tc.token, "")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateJWT_requiredAnnotations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	}))
+	t.Cleanup(svr.Close)
+
+	sign := func(tb testing.TB, justs ...*Justification) string {
+		tb.Helper()
+
+		tok := testCreateToken(tb, "test_id")
+		if err := SetJustifications(tok, justs); err != nil {
+			tb.Fatal(err)
+		}
+		return testSignTokenPrivateKey(tb, tok, privateKey, keyID)
+	}
+
+	encrypted := testCreateToken(t, "encrypted")
+	if err := encrypted.Remove(JustificationsKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := encrypted.Set(EncryptedJustificationsKey, "ciphertext"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		required []string
+		token    string
+		wantErr  string
+	}{
+		{
+			name:  "none_required",
+			token: sign(t, &Justification{Category: "jira", Value: "ABC-123"}),
+		},
+		{
+			name:     "present",
+			required: []string{"jira=jira_issue_url"},
+			token: sign(t, &Justification{
+				Category:   "jira",
+				Value:      "ABC-123",
+				Annotation: map[string]string{"jira_issue_url": "https://jira.corp/browse/ABC-123"},
+			}),
+		},
+		{
+			name:     "other_category",
+			required: []string{"jira=jira_issue_url"},
+			token:    sign(t, &Justification{Category: "explanation", Value: "testing"}),
+		},
+		{
+			name:     "missing",
+			required: []string{"jira=jira_issue_url", "jira=jira_status"},
+			token: sign(t, &Justification{
+				Category:   "jira",
+				Value:      "ABC-123",
+				Annotation: map[string]string{"jira_status": "open"},
+			}),
+			wantErr: `justification of category "jira" is missing required annotations ["jira_issue_url"]`,
+		},
+		{
+			name:     "empty",
+			required: []string{"jira=jira_issue_url"},
+			token: sign(t, &Justification{
+				Category:   "jira",
+				Value:      "ABC-123",
+				Annotation: map[string]string{"jira_issue_url": ""},
+			}),
+			wantErr: `justification of category "jira" is missing required annotations ["jira_issue_url"]`,
+		},
+		{
+			name:     "encrypted",
+			required: []string{"jira=jira_issue_url"},
+			token:    testSignTokenPrivateKey(t, encrypted, privateKey, keyID),
+			wantErr:  "justifications are encrypted, so required annotations can't be checked",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(ctx, &Config{
+				JWKSEndpoint:        svr.URL,
+				CacheTimeout:        5 * time.Minute,
+				RequiredAnnotations: tc.required,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.ValidateJWT(ctx, tc.token, "")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateJWT_tokenTypesAndNamespace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]/cryptoKeyVersions/1"
+
+	ecdsaKey, err := jwk.FromRaw(privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ecdsaKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(map[string][]jwk.Key{"keys": {ecdsaKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s", j)
+	}))
+	t.Cleanup(svr.Close)
+
+	namespace := "https://jvs.example.com/"
+	namespaced := testCreateToken(t, "test_id")
+	if err := SetRequestor(namespaced, "me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := NamespaceClaims(namespaced, namespace); err != nil {
+		t.Fatal(err)
+	}
+
+	untyped := testSignTokenPrivateKey(t, testCreateToken(t, "test_id"), privateKey, keyID)
+	typed := testSignTokenType(t, testCreateToken(t, "test_id"), privateKey, keyID, "jvs+jwt")
+
+	cases := []struct {
+		name          string
+		acceptedTypes []string
+		namespace     string
+		token         string
+		wantRequestor string
+		wantErr       string
+	}{
+		{
+			name:  "default_type",
+			token: untyped,
+		},
+		{
+			name:    "custom_type_not_accepted_by_default",
+			token:   typed,
+			wantErr: `token type "jvs+jwt" is not a jwt`,
+		},
+		{
+			name:          "custom_type",
+			acceptedTypes: []string{"jvs+jwt"},
+			token:         typed,
+		},
+		{
+			name:          "custom_type_with_prefix",
+			acceptedTypes: []string{"application/JVS+JWT"},
+			token:         typed,
+		},
+		{
+			name:          "default_type_not_accepted",
+			acceptedTypes: []string{"jvs+jwt"},
+			token:         untyped,
+			wantErr:       `token type "JWT" is not one of the accepted token types ["jvs+jwt"]`,
+		},
+		{
+			name:          "migration",
+			acceptedTypes: []string{"JWT", "jvs+jwt"},
+			token:         untyped,
+		},
+		{
+			name:          "namespaced",
+			namespace:     namespace,
+			token:         testSignTokenPrivateKey(t, namespaced, privateKey, keyID),
+			wantRequestor: "me@example.com",
+		},
+		{
+			name:    "namespace_not_configured",
+			token:   testSignTokenPrivateKey(t, namespaced, privateKey, keyID),
+			wantErr: "jwt has no justs or justs_enc claim",
+		},
+		{
+			name:      "not_namespaced",
+			namespace: namespace,
+			token:     untyped,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewClient(ctx, &Config{
+				JWKSEndpoint:       svr.URL,
+				CacheTimeout:       5 * time.Minute,
+				AcceptedTokenTypes: tc.acceptedTypes,
+				ClaimsNamespace:    tc.namespace,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := client.ValidateJWT(ctx, tc.token, "")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			justs, err := GetJustifications(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(justs), 1; got != want {
+				t.Errorf("expected %d justifications to be %d", got, want)
+			}
+			requestor, err := GetRequestor(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := requestor, tc.wantRequestor; got != want {
+				t.Errorf("expected requestor %q to be %q", got, want)
+			}
+		})
+	}
+}
@@ -18,37 +18,226 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/sethvargo/go-envconfig"
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultAllowedClockSkew is the default clock skew tolerated when validating
+// tokens.
+const DefaultAllowedClockSkew = 5 * time.Second
+
 // Config is the jvs client configuration.
 type Config struct {
 	// JWKSEndpoint is the full path (including protocol and port) to the JWKS
 	// endpoint on a JVS server (e.g. https://jvs.corp:8080/.well-known/jwks).
 	JWKSEndpoint string `yaml:"endpoint,omitempty" env:"ENDPOINT,overwrite"`
 
+	// Issuers maps each trusted token issuer to the JWKS endpoint of its JVS
+	// deployment, for verifiers that trust more than one deployment (e.g.
+	// "jvs.corp" and "jvs-staging.corp"). Tokens are verified with the keys of
+	// their "iss" claim, and tokens of other issuers are rejected. It can't be
+	// used together with JWKSEndpoint. In the environment, pairs are given as
+	// "ISSUER=ENDPOINT,ISSUER=ENDPOINT".
+	Issuers map[string]string `yaml:"issuers,omitempty" env:"ISSUERS,overwrite,separator=="`
+
+	// JWKSRootKeys, if set, are the JWK thumbprints (RFC 7638, SHA-256) of the
+	// root keys the JWKS must be signed by, for JVS deployments with a key set
+	// signing key. The signed key set is fetched from the JWKS endpoints, and
+	// unsigned or otherwise signed key sets are rejected, so a compromised
+	// layer serving the JWKS can't substitute keys.
+	JWKSRootKeys []string `yaml:"jwks_root_keys,omitempty" env:"JWKS_ROOT_KEYS,overwrite"`
+
+	// AcceptedIssuers, if set, are the only issuers (iss) of tokens that are
+	// accepted, e.g. "jvs.corp". Breakglass tokens have the
+	// [BreakglassIssuer], which must be listed to accept them. With Issuers,
+	// every accepted issuer but the breakglass one must be trusted there.
+	AcceptedIssuers []string `yaml:"accepted_issuers,omitempty" env:"ACCEPTED_ISSUERS,overwrite"`
+
+	// Audiences, if set, are the audience patterns the "aud" claim of tokens
+	// must match, so one verifier can accept tokens for many hostnames, e.g.
+	// "https://*.example.com". A token is accepted if any of its audiences
+	// matches any of the patterns. See [ValidateAudiencePattern] for the
+	// patterns.
+	Audiences []string `yaml:"audiences,omitempty" env:"AUDIENCES,overwrite"`
+
+	// AcceptedTokenTypes, if set, are the only "typ" headers of tokens that are
+	// accepted, e.g. "jvs+jwt" if the JVS is configured with that token type.
+	// List [DefaultTokenType] too while the JVS is switched to a new type, so
+	// tokens minted before are accepted. If empty, only tokens of
+	// [DefaultTokenType] or without a type are accepted.
+	AcceptedTokenTypes []string `yaml:"accepted_token_types,omitempty" env:"ACCEPTED_TOKEN_TYPES,overwrite"`
+
+	// ClaimsNamespace is the prefix of the JVS claims of tokens, if the JVS is
+	// configured with a claims namespace, e.g. "https://jvs.example.com/".
+	// Claims in the namespace are moved out of it before validation, so the
+	// returned tokens are read like those of a JVS without a namespace. Tokens
+	// without namespaced claims are still accepted.
+	ClaimsNamespace string `yaml:"claims_namespace,omitempty" env:"CLAIMS_NAMESPACE,overwrite"`
+
+	// RequiredAnnotations are annotations that justifications of a category
+	// must carry, in the format "category=annotation", e.g.
+	// "jira=jira_issue_url". Tokens with a justification of the category that
+	// lacks the annotation are rejected, so policy that depends on it can't be
+	// bypassed by a plugin silently dropping it. Repeat the category to require
+	// more than one annotation.
+	RequiredAnnotations []string `yaml:"required_annotations,omitempty" env:"REQUIRED_ANNOTATIONS,overwrite"`
+
 	// CacheTimeout is the duration that keys stay in cache before being revoked.
 	CacheTimeout time.Duration `yaml:"cache_timeout" env:"CACHE_TIMEOUT,overwrite,default=5m"`
 
+	// AllowedClockSkew is the clock skew tolerated when validating the time
+	// based claims of tokens, "exp", "iat" and "nbf". If nil,
+	// [DefaultAllowedClockSkew] is used. Set it to zero to tolerate no skew.
+	AllowedClockSkew *time.Duration `yaml:"allowed_clock_skew" env:"ALLOWED_CLOCK_SKEW,overwrite,default=5s"`
+
 	// AllowBreakglass represents whether the jvs client allows breakglass.
 	AllowBreakglass bool `yaml:"allow_breakglass" env:"ALLOW_BREAKGLASS,overwrite,default=false"`
+
+	// BreakglassBeacon, if true, logs a "breakglass used" event with the
+	// subject and explanation of each accepted breakglass token.
+	BreakglassBeacon bool `yaml:"breakglass_beacon" env:"BREAKGLASS_BEACON,overwrite,default=false"`
+
+	// BreakglassBeaconEndpoint is the full path to a webhook that receives a
+	// [BreakglassUsedEvent] for each accepted breakglass token (e.g.
+	// https://security.corp/breakglass). Setting it enables BreakglassBeacon.
+	BreakglassBeaconEndpoint string `yaml:"breakglass_beacon_endpoint,omitempty" env:"BREAKGLASS_BEACON_ENDPOINT,overwrite"`
+
+	// BreakglassBeaconResource names the resource the verifier protects in
+	// breakglass events (e.g. "prod-database").
+	BreakglassBeaconResource string `yaml:"breakglass_beacon_resource,omitempty" env:"BREAKGLASS_BEACON_RESOURCE,overwrite"`
+
+	// UsageReportEndpoint is the full path to the key usage endpoint on a JVS
+	// public key server (e.g. https://jvs.corp:8080/key-usage). If set, a
+	// sample of verified tokens' key IDs are reported to it, so operators know
+	// which key versions are still in use.
+	UsageReportEndpoint string `yaml:"usage_report_endpoint,omitempty" env:"USAGE_REPORT_ENDPOINT,overwrite"`
+
+	// UsageReportAudience is the audience of the Google ID tokens that
+	// authenticate usage reports, minted from the application default
+	// credentials. It must be set with UsageReportEndpoint, and match the
+	// public key server's key usage audience.
+	UsageReportAudience string `yaml:"usage_report_audience,omitempty" env:"USAGE_REPORT_AUDIENCE,overwrite"`
+
+	// RevocationEndpoint is the full path to the revocation list on a JVS
+	// public key server (e.g. https://jvs.corp:8080/revocations). If set,
+	// tokens whose subject or requestor was deprovisioned after they were
+	// issued are rejected. The list is fetched again after CacheTimeout.
+	RevocationEndpoint string `yaml:"revocation_endpoint,omitempty" env:"REVOCATION_ENDPOINT,overwrite"`
+
+	// UsageReportSampleRate is the fraction of verified tokens that are
+	// reported, between [MinUsageReportSampleRate] and 1.
+	UsageReportSampleRate float64 `yaml:"usage_report_sample_rate" env:"USAGE_REPORT_SAMPLE_RATE,overwrite,default=0.01"`
 }
 
 // Validate checks if the config is valid.
 func (cfg *Config) Validate() error {
 	var merr error
-	if cfg.JWKSEndpoint == "" {
-		merr = errors.Join(merr, fmt.Errorf("endpoint must be set"))
+	switch {
+	case cfg.JWKSEndpoint == "" && len(cfg.Issuers) == 0:
+		merr = errors.Join(merr, fmt.Errorf("endpoint or issuers must be set"))
+	case cfg.JWKSEndpoint != "" && len(cfg.Issuers) > 0:
+		merr = errors.Join(merr, fmt.Errorf("endpoint and issuers are mutually exclusive"))
+	}
+	for _, iss := range slices.Sorted(maps.Keys(cfg.Issuers)) {
+		if iss == "" {
+			merr = errors.Join(merr, fmt.Errorf("issuer must not be empty"))
+		}
+		if cfg.Issuers[iss] == "" {
+			merr = errors.Join(merr, fmt.Errorf("endpoint of issuer %q must be set", iss))
+		}
+	}
+	for _, iss := range cfg.AcceptedIssuers {
+		switch {
+		case iss == "":
+			merr = errors.Join(merr, fmt.Errorf("accepted issuer must not be empty"))
+		case len(cfg.Issuers) > 0 && iss != BreakglassIssuer && cfg.Issuers[iss] == "":
+			merr = errors.Join(merr, fmt.Errorf("accepted issuer %q must be one of the issuers", iss))
+		}
+	}
+	for _, thumbprint := range cfg.JWKSRootKeys {
+		if err := ValidateJWKThumbprint(thumbprint); err != nil {
+			merr = errors.Join(merr, fmt.Errorf("invalid jwks root key %q: %w", thumbprint, err))
+		}
+	}
+	for _, pattern := range cfg.Audiences {
+		if err := ValidateAudiencePattern(pattern); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+	for _, typ := range cfg.AcceptedTokenTypes {
+		if !TokenTypesEqual(typ, DefaultTokenType) {
+			if err := ValidateTokenType(typ); err != nil {
+				merr = errors.Join(merr, fmt.Errorf("invalid accepted token type: %w", err))
+			}
+		}
+	}
+	if cfg.ClaimsNamespace != "" {
+		if err := ValidateClaimsNamespace(cfg.ClaimsNamespace); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+	if _, err := cfg.RequiredAnnotationKeys(); err != nil {
+		merr = errors.Join(merr, err)
 	}
 	if cfg.CacheTimeout <= 0 {
 		merr = errors.Join(merr, fmt.Errorf("cache timeout must be a positive duration, got %q", cfg.CacheTimeout))
 	}
+	if cfg.AllowedClockSkew != nil && *cfg.AllowedClockSkew < 0 {
+		merr = errors.Join(merr, fmt.Errorf("allowed clock skew cannot be negative, got %q", *cfg.AllowedClockSkew))
+	}
+	if (cfg.BreakglassBeacon || cfg.BreakglassBeaconEndpoint != "") && !cfg.AllowBreakglass {
+		merr = errors.Join(merr, fmt.Errorf("breakglass beacon requires allow breakglass"))
+	}
+	if cfg.UsageReportEndpoint != "" && cfg.UsageReportAudience == "" {
+		merr = errors.Join(merr, fmt.Errorf("usage report audience must be set with usage report endpoint"))
+	}
+	if cfg.UsageReportEndpoint != "" && (cfg.UsageReportSampleRate < MinUsageReportSampleRate || cfg.UsageReportSampleRate > 1) {
+		merr = errors.Join(merr, fmt.Errorf("usage report sample rate must be in [%v, 1], got %v", MinUsageReportSampleRate, cfg.UsageReportSampleRate))
+	}
 	return merr
 }
 
+// RequiredAnnotationKeys returns the annotations justifications must carry,
+// keyed by category.
+func (cfg *Config) RequiredAnnotationKeys() (map[string][]string, error) {
+	return ParseRequiredAnnotations(cfg.RequiredAnnotations)
+}
+
+// ParseRequiredAnnotations parses required annotations in the format
+// "category=annotation", and returns the annotations keyed by category.
+func ParseRequiredAnnotations(values []string) (map[string][]string, error) {
+	required := make(map[string][]string, len(values))
+	for _, v := range values {
+		category, key, ok := strings.Cut(v, "=")
+		category, key = strings.TrimSpace(category), strings.TrimSpace(key)
+		if !ok || category == "" || key == "" {
+			return nil, fmt.Errorf("required annotation %q must be in the format category=annotation", v)
+		}
+		if slices.Contains(required[category], key) {
+			return nil, fmt.Errorf("required annotation %q of category %q is specified more than once", key, category)
+		}
+		required[category] = append(required[category], key)
+	}
+	return required, nil
+}
+
+// MissingAnnotations returns the annotations required for the justification's
+// category that it doesn't carry, or carries empty.
+func MissingAnnotations(j *Justification, required map[string][]string) []string {
+	var missing []string
+	for _, key := range required[j.GetCategory()] {
+		if j.GetAnnotation()[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
 // LoadConfig calls the necessary methods to load in config using the OsLookuper
 // which finds env variables specified on the host.
 func LoadConfig(ctx context.Context, b []byte) (*Config, error) {
@@ -44,11 +44,19 @@ version: 1
 endpoint: https://jvs.corp:8080/.well-known/jwks
 cache_timeout: 1m
 allow_breakglass: true
+usage_report_endpoint: https://jvs.corp:8080/key-usage
+usage_report_audience: https://jvs.corp
+usage_report_sample_rate: 0.5
+allowed_clock_skew: 30s
 `,
 			wantConfig: &Config{
-				JWKSEndpoint:    "https://jvs.corp:8080/.well-known/jwks",
-				CacheTimeout:    time.Minute,
-				AllowBreakglass: true,
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				CacheTimeout:          time.Minute,
+				AllowBreakglass:       true,
+				UsageReportEndpoint:   "https://jvs.corp:8080/key-usage",
+				UsageReportAudience:   "https://jvs.corp",
+				UsageReportSampleRate: 0.5,
+				AllowedClockSkew:      testDuration(30 * time.Second),
 			},
 		},
 		{
@@ -57,9 +65,11 @@ allow_breakglass: true
 endpoint: https://jvs.corp:8080/.well-known/jwks
 `,
 			wantConfig: &Config{
-				JWKSEndpoint:    "https://jvs.corp:8080/.well-known/jwks",
-				CacheTimeout:    5 * time.Minute,
-				AllowBreakglass: false,
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				CacheTimeout:          5 * time.Minute,
+				AllowBreakglass:       false,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
 			},
 		},
 		{
@@ -88,11 +98,333 @@ allow_breakglass: false
 				"ALLOW_BREAKGLASS": "true",
 			},
 			wantConfig: &Config{
-				JWKSEndpoint:    "other.net:443",
-				CacheTimeout:    2 * time.Minute,
-				AllowBreakglass: true,
+				JWKSEndpoint:          "other.net:443",
+				CacheTimeout:          2 * time.Minute,
+				AllowBreakglass:       true,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
 			},
 		},
+		{
+			name: "issuers",
+			cfg: `
+issuers:
+  jvs.corp: https://jvs.corp:8080/.well-known/jwks
+  jvs-staging.corp: https://jvs-staging.corp:8080/.well-known/jwks
+`,
+			wantConfig: &Config{
+				Issuers: map[string]string{
+					"jvs.corp":         "https://jvs.corp:8080/.well-known/jwks",
+					"jvs-staging.corp": "https://jvs-staging.corp:8080/.well-known/jwks",
+				},
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "issuers_env_override",
+			cfg: `
+issuers:
+  jvs.corp: https://jvs.corp:8080/.well-known/jwks
+`,
+			envs: map[string]string{
+				"ISSUERS": "jvs.corp=https://jvs.corp:8443/.well-known/jwks,jvs-staging.corp=https://jvs-staging.corp/.well-known/jwks",
+			},
+			wantConfig: &Config{
+				Issuers: map[string]string{
+					"jvs.corp":         "https://jvs.corp:8443/.well-known/jwks",
+					"jvs-staging.corp": "https://jvs-staging.corp/.well-known/jwks",
+				},
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "zero_clock_skew",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+allowed_clock_skew: 0s
+`,
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(0),
+			},
+		},
+		{
+			name: "zero_clock_skew_env_override",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+allowed_clock_skew: 30s
+`,
+			envs: map[string]string{
+				"ALLOWED_CLOCK_SKEW": "0s",
+			},
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(0),
+			},
+		},
+		{
+			name: "accepted_issuers",
+			cfg: `
+issuers:
+  jvs.corp: https://jvs.corp:8080/.well-known/jwks
+accepted_issuers:
+- jvs.corp
+- jvsctl
+`,
+			wantConfig: &Config{
+				Issuers: map[string]string{
+					"jvs.corp": "https://jvs.corp:8080/.well-known/jwks",
+				},
+				AcceptedIssuers:       []string{"jvs.corp", "jvsctl"},
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "accepted_issuers_env_override",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+`,
+			envs: map[string]string{
+				"ACCEPTED_ISSUERS": "jvs.corp,jvsctl",
+			},
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				AcceptedIssuers:       []string{"jvs.corp", "jvsctl"},
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "audiences",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+audiences:
+- https://*.example.com
+`,
+			envs: map[string]string{
+				"AUDIENCES": "https://*.example.com,payments-*",
+			},
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				Audiences:             []string{"https://*.example.com", "payments-*"},
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "token_types_and_namespace",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+accepted_token_types:
+- JWT
+- jvs+jwt
+claims_namespace: https://jvs.corp/
+`,
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				AcceptedTokenTypes:    []string{"JWT", "jvs+jwt"},
+				ClaimsNamespace:       "https://jvs.corp/",
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "token_types_and_namespace_invalid",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+accepted_token_types:
+- jvs-receipt+json
+claims_namespace: "jvs "
+`,
+			wantConfig: nil,
+			wantErr:    `token type "jvs-receipt+json" is the type of another jvs object`,
+		},
+		{
+			name: "jwks_root_keys",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+jwks_root_keys:
+- NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs
+`,
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				JWKSRootKeys:          []string{"NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"},
+				CacheTimeout:          5 * time.Minute,
+				UsageReportSampleRate: 0.01,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+			},
+		},
+		{
+			name: "jwks_root_keys_invalid",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+jwks_root_keys:
+- not-a-thumbprint
+`,
+			wantConfig: nil,
+			wantErr:    `invalid jwks root key "not-a-thumbprint": thumbprint must be a 32 byte sha256 digest`,
+		},
+		{
+			name: "audience_invalid",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+audiences:
+- "*"
+`,
+			wantConfig: nil,
+			wantErr:    `audience pattern "*" must not match every audience`,
+		},
+		{
+			name: "accepted_issuer_not_trusted",
+			cfg: `
+issuers:
+  jvs.corp: https://jvs.corp:8080/.well-known/jwks
+accepted_issuers:
+- jvs-staging.corp
+- ""
+`,
+			wantConfig: nil,
+			wantErr:    "accepted issuer \"jvs-staging.corp\" must be one of the issuers\naccepted issuer must not be empty",
+		},
+		{
+			name:       "missing_endpoint",
+			cfg:        `cache_timeout: 1m`,
+			wantConfig: nil,
+			wantErr:    "endpoint or issuers must be set",
+		},
+		{
+			name: "endpoint_and_issuers",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+issuers:
+  jvs.corp: https://jvs.corp:8080/.well-known/jwks
+`,
+			wantConfig: nil,
+			wantErr:    "endpoint and issuers are mutually exclusive",
+		},
+		{
+			name: "issuer_without_endpoint",
+			cfg: `
+issuers:
+  jvs.corp: ""
+`,
+			wantConfig: nil,
+			wantErr:    `endpoint of issuer "jvs.corp" must be set`,
+		},
+		{
+			name: "test_usage_report_without_audience",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+usage_report_endpoint: https://jvs.corp:8080/key-usage
+`,
+			wantConfig: nil,
+			wantErr:    "usage report audience must be set with usage report endpoint",
+		},
+		{
+			name: "test_invalid_sample_rate",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+usage_report_endpoint: https://jvs.corp:8080/key-usage
+usage_report_audience: https://jvs.corp
+usage_report_sample_rate: 2
+`,
+			wantConfig: nil,
+			wantErr:    "usage report sample rate must be in [1e-06, 1], got 2",
+		},
+		{
+			name: "test_tiny_sample_rate",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+usage_report_endpoint: https://jvs.corp:8080/key-usage
+usage_report_audience: https://jvs.corp
+usage_report_sample_rate: 1e-300
+`,
+			wantConfig: nil,
+			wantErr:    "usage report sample rate must be in [1e-06, 1], got 1e-300",
+		},
+		{
+			name: "breakglass_beacon",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+allow_breakglass: true
+breakglass_beacon_endpoint: https://security.corp/breakglass
+breakglass_beacon_resource: prod-database
+`,
+			envs: map[string]string{
+				"BREAKGLASS_BEACON": "true",
+			},
+			wantConfig: &Config{
+				JWKSEndpoint:             "https://jvs.corp:8080/.well-known/jwks",
+				CacheTimeout:             5 * time.Minute,
+				AllowedClockSkew:         testDuration(5 * time.Second),
+				AllowBreakglass:          true,
+				BreakglassBeacon:         true,
+				BreakglassBeaconEndpoint: "https://security.corp/breakglass",
+				BreakglassBeaconResource: "prod-database",
+				UsageReportSampleRate:    0.01,
+			},
+		},
+		{
+			name: "required_annotations",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+required_annotations:
+- jira=jira_issue_url
+`,
+			envs: map[string]string{
+				"REQUIRED_ANNOTATIONS": "jira=jira_issue_url,servicenow=state",
+			},
+			wantConfig: &Config{
+				JWKSEndpoint:          "https://jvs.corp:8080/.well-known/jwks",
+				RequiredAnnotations:   []string{"jira=jira_issue_url", "servicenow=state"},
+				CacheTimeout:          5 * time.Minute,
+				AllowedClockSkew:      testDuration(5 * time.Second),
+				UsageReportSampleRate: 0.01,
+			},
+		},
+		{
+			name: "required_annotation_invalid",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+required_annotations:
+- jira
+`,
+			wantConfig: nil,
+			wantErr:    `required annotation "jira" must be in the format category=annotation`,
+		},
+		{
+			name: "required_annotation_duplicate",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+required_annotations:
+- jira=jira_issue_url
+- jira = jira_issue_url
+`,
+			wantConfig: nil,
+			wantErr:    `required annotation "jira_issue_url" of category "jira" is specified more than once`,
+		},
+		{
+			name: "breakglass_beacon_without_breakglass",
+			cfg: `
+endpoint: https://jvs.corp:8080/.well-known/jwks
+breakglass_beacon_endpoint: https://security.corp/breakglass
+`,
+			wantConfig: nil,
+			wantErr:    "breakglass beacon requires allow breakglass",
+		},
 	}
 
 	for _, tc := range tests {
@@ -110,3 +442,7 @@ allow_breakglass: false
 		})
 	}
 }
+
+func testDuration(d time.Duration) *time.Duration {
+	return &d
+}
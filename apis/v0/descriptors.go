@@ -0,0 +1,58 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FileDescriptorSet returns the compiled descriptors of the JVS APIs and the
+// files they import, with every file after its imports, like
+// "protoc --include_imports --descriptor_set_out". Clients can generate stubs
+// or call the APIs dynamically with it, without server reflection.
+func FileDescriptorSet() *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]struct{})
+
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if _, ok := seen[fd.Path()]; ok {
+			return
+		}
+		seen[fd.Path()] = struct{}{}
+
+		imports := fd.Imports()
+		for i := range imports.Len() {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	// The files are only registered once the package is initialized, so they
+	// can't be listed in a package variable.
+	for _, fd := range []protoreflect.FileDescriptor{
+		File_admin_request_proto,
+		File_admin_service_proto,
+		File_cert_action_request_proto,
+		File_cert_action_service_proto,
+		File_jvs_plugin_service_proto,
+		File_jvs_request_proto,
+		File_jvs_service_proto,
+	} {
+		add(fd)
+	}
+	return set
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestFileDescriptorSet(t *testing.T) {
+	t.Parallel()
+
+	// Every file must come after its imports to be resolved.
+	files, err := protodesc.NewFiles(FileDescriptorSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"abcxyz.jvs.JVSService",
+		"abcxyz.jvs.AdminService",
+		"abcxyz.jvs.CertificateActionService",
+		"abcxyz.jvs.JVSPlugin",
+	} {
+		if _, err := files.FindDescriptorByName(protoreflect.FullName(name)); err != nil {
+			t.Errorf("service %s: %v", name, err)
+		}
+	}
+	if _, err := files.FindFileByPath("google/protobuf/duration.proto"); err != nil {
+		t.Errorf("expected imports to be included: %v", err)
+	}
+}
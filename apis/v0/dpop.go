@@ -0,0 +1,266 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	// ConfirmationKey is the key in the JWT that holds the confirmation of the
+	// key the token is bound to (RFC 7800).
+	ConfirmationKey string = "cnf"
+
+	// JWKThumbprintKey is the member of the confirmation that holds the SHA-256
+	// JWK thumbprint of the key the token is bound to (RFC 9449).
+	JWKThumbprintKey string = "jkt"
+
+	// DPoPProofType is the "typ" header of DPoP proofs.
+	DPoPProofType string = "dpop+jwt"
+)
+
+// JWKThumbprint returns the base64url-encoded SHA-256 thumbprint of the key,
+// as set in [CreateJustificationRequest.JwkThumbprint]. Private keys have the
+// same thumbprint as their public key.
+func JWKThumbprint(key jwk.Key) (string, error) {
+	if key == nil {
+		return "", fmt.Errorf("key cannot be nil")
+	}
+
+	pub, err := key.PublicKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get public key: %w", err)
+	}
+	b, err := pub.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute thumbprint: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ValidateJWKThumbprint returns an error if the thumbprint is not a
+// base64url-encoded SHA-256 digest.
+func ValidateJWKThumbprint(thumbprint string) error {
+	b, err := base64.RawURLEncoding.DecodeString(thumbprint)
+	if err != nil {
+		return fmt.Errorf("thumbprint must be base64url encoded without padding: %w", err)
+	}
+	if got, want := len(b), sha256.Size; got != want {
+		return fmt.Errorf("thumbprint must be a %d byte sha256 digest, got %d bytes", want, got)
+	}
+	return nil
+}
+
+// GetConfirmation retrieves the JWK thumbprint of the key the token is bound
+// to. If the token is not bound to a key, it returns the empty string.
+func GetConfirmation(t jwt.Token) (string, error) {
+	if t == nil {
+		return "", fmt.Errorf("token cannot be nil")
+	}
+
+	raw, ok := t.Get(ConfirmationKey)
+	if !ok {
+		return "", nil
+	}
+
+	var jkt any
+	switch cnf := raw.(type) {
+	case map[string]string:
+		// Token was built, not parsed.
+		jkt = cnf[JWKThumbprintKey]
+	case map[string]any:
+		jkt = cnf[JWKThumbprintKey]
+	default:
+		return "", fmt.Errorf("found confirmation, but was of unknown type %T", raw)
+	}
+
+	str, ok := jkt.(string)
+	if !ok {
+		return "", fmt.Errorf("found confirmation, but %s was of unknown type %T", JWKThumbprintKey, jkt)
+	}
+	return str, nil
+}
+
+// SetConfirmation binds the token to the key with the JWK thumbprint. It
+// overwrites any existing confirmation.
+func SetConfirmation(t jwt.Token, thumbprint string) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+
+	if err := t.Set(ConfirmationKey, map[string]string{JWKThumbprintKey: thumbprint}); err != nil {
+		return fmt.Errorf("failed to set confirmation: %w", err)
+	}
+	return nil
+}
+
+// NewDPoPProof creates a DPoP proof that the caller holds the private key, for
+// a request with the HTTP method and URI that presents the token. A new proof
+// must be created for every request.
+func NewDPoPProof(key jwk.Key, alg jwa.SignatureAlgorithm, method, uri, token string) (string, error) {
+	return newDPoPProof(key, alg, method, uri, token, time.Now().UTC())
+}
+
+// newDPoPProof is an internal helper for testing that creates a DPoP proof
+// issued at the given time.
+func newDPoPProof(key jwk.Key, alg jwa.SignatureAlgorithm, method, uri, token string, now time.Time) (string, error) {
+	if key == nil {
+		return "", fmt.Errorf("key cannot be nil")
+	}
+
+	pub, err := key.PublicKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get public key: %w", err)
+	}
+
+	proof, err := jwt.NewBuilder().
+		JwtID(uuid.New().String()).
+		IssuedAt(now).
+		Claim("htm", method).
+		Claim("htu", uri).
+		Claim("ath", tokenHash(token)).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build proof: %w", err)
+	}
+
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.TypeKey, DPoPProofType); err != nil {
+		return "", fmt.Errorf("failed to set typ header: %w", err)
+	}
+	if err := headers.Set(jws.JWKKey, pub); err != nil {
+		return "", fmt.Errorf("failed to set jwk header: %w", err)
+	}
+
+	b, err := jwt.Sign(proof, jwt.WithKey(alg, key, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign proof: %w", err)
+	}
+	return string(b), nil
+}
+
+// VerifyDPoPProof verifies the DPoP proof was created for a request with the
+// HTTP method and URI, presenting the token, by the holder of the key the
+// token is bound to. The token must already be verified, e.g. with
+// [Client.ValidateJWT], and the raw token is what the request presented.
+// Proofs older than maxAge are rejected.
+//
+// It does not check the proof was not used before. Verifiers that need to
+// prevent replay within maxAge must track the "jti" of the proofs they
+// accepted.
+func VerifyDPoPProof(proof string, token jwt.Token, rawToken, method, uri string, maxAge time.Duration) error {
+	jkt, err := GetConfirmation(token)
+	if err != nil {
+		return err
+	}
+	if jkt == "" {
+		return fmt.Errorf("token is not bound to a key")
+	}
+
+	msg, err := jws.Parse([]byte(proof))
+	if err != nil {
+		return fmt.Errorf("failed to parse proof: %w", err)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return fmt.Errorf("expected 1 signature, got %d", len(sigs))
+	}
+	headers := sigs[0].ProtectedHeaders()
+	if got, want := headers.Type(), DPoPProofType; got != want {
+		return fmt.Errorf("proof typ %q must be %q", got, want)
+	}
+	key := headers.JWK()
+	if key == nil {
+		return fmt.Errorf("proof is missing the jwk header")
+	}
+	if key.KeyType() == jwa.OctetSeq {
+		return fmt.Errorf("proof must be signed by an asymmetric key")
+	}
+
+	got, err := JWKThumbprint(key)
+	if err != nil {
+		return err
+	}
+	if got != jkt {
+		return fmt.Errorf("proof key %s does not match the key the token is bound to", got)
+	}
+
+	claims, err := jwt.Parse([]byte(proof),
+		jwt.WithKey(headers.Algorithm(), key),
+		jwt.WithRequiredClaim(jwt.JwtIDKey),
+		jwt.WithRequiredClaim(jwt.IssuedAtKey))
+	if err != nil {
+		return fmt.Errorf("failed to verify proof: %w", err)
+	}
+	if iat := claims.IssuedAt(); time.Since(iat) > maxAge {
+		return fmt.Errorf("proof was issued at %s, more than %s ago", iat.UTC().Format(time.RFC3339), maxAge)
+	}
+
+	if got, want := stringClaim(claims, "htm"), method; got != want {
+		return fmt.Errorf("proof htm %q does not match the request method %q", got, want)
+	}
+	gotURI, err := withoutQuery(stringClaim(claims, "htu"))
+	if err != nil {
+		return fmt.Errorf("invalid proof htu: %w", err)
+	}
+	wantURI, err := withoutQuery(uri)
+	if err != nil {
+		return fmt.Errorf("invalid request uri: %w", err)
+	}
+	if gotURI != wantURI {
+		return fmt.Errorf("proof htu %q does not match the request uri %q", gotURI, wantURI)
+	}
+	if stringClaim(claims, "ath") != tokenHash(rawToken) {
+		return fmt.Errorf("proof ath does not match the token")
+	}
+	return nil
+}
+
+// tokenHash returns the "ath" of a DPoP proof for the token.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// stringClaim returns the private claim if it is a string, or the empty string.
+func stringClaim(t jwt.Token, key string) string {
+	v, _ := t.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// withoutQuery strips the query and fragment of the URI, which are not part of
+// the DPoP "htu".
+func withoutQuery(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse uri: %w", err)
+	}
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	t.Parallel()
+
+	// Example from RFC 7638, section 3.1.
+	key, err := jwk.ParseKey([]byte(`{
+		"kty": "RSA",
+		"n": "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e": "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := JWKThumbprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("expected thumbprint %q to be %q", got, want)
+	}
+	if err := ValidateJWKThumbprint(got); err != nil {
+		t.Errorf("expected thumbprint %q to be valid: %s", got, err)
+	}
+}
+
+func TestValidateJWKThumbprint(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		thumbprint string
+		expErr     string
+	}{
+		{
+			name:       "valid",
+			thumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			name:       "padded",
+			thumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs=",
+			expErr:     "thumbprint must be base64url encoded without padding",
+		},
+		{
+			name:       "wrong_length",
+			thumbprint: "c2hvcnQ",
+			expErr:     "thumbprint must be a 32 byte sha256 digest, got 5 bytes",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateJWKThumbprint(tc.thumbprint)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestVerifyDPoPProof(t *testing.T) {
+	t.Parallel()
+
+	holderKey := testEncryptionKey(t, "ec")
+	otherKey := testEncryptionKey(t, "ec")
+	jkt, err := JWKThumbprint(holderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawToken := "header.claims.signature"
+	uri := "https://app.example.com/resource"
+
+	boundToken := testTokenBuilder(t, jwt.NewBuilder())
+	if err := SetConfirmation(boundToken, jkt); err != nil {
+		t.Fatal(err)
+	}
+	// Round trip through JSON, like a token from [Client.ValidateJWT].
+	b, err := jwt.NewSerializer().Serialize(boundToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	boundToken, err = jwt.Parse(b, jwt.WithVerify(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof := func(tb testing.TB, key jwk.Key, method, uri, token string, iat time.Time) string {
+		tb.Helper()
+
+		p, err := newDPoPProof(key, jwa.ES256, method, uri, token, iat)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return p
+	}
+
+	now := time.Now().UTC()
+
+	cases := []struct {
+		name   string
+		proof  string
+		token  jwt.Token
+		method string
+		uri    string
+		expErr string
+	}{
+		{
+			name:   "valid",
+			proof:  proof(t, holderKey, "GET", uri, rawToken, now),
+			token:  boundToken,
+			method: "GET",
+			uri:    uri,
+		},
+		{
+			name:   "query_ignored",
+			proof:  proof(t, holderKey, "GET", uri, rawToken, now),
+			token:  boundToken,
+			method: "GET",
+			uri:    uri + "?page=2",
+		},
+		{
+			name:   "unbound_token",
+			proof:  proof(t, holderKey, "GET", uri, rawToken, now),
+			token:  testTokenBuilder(t, jwt.NewBuilder()),
+			method: "GET",
+			uri:    uri,
+			expErr: "token is not bound to a key",
+		},
+		{
+			name:   "other_key",
+			proof:  proof(t, otherKey, "GET", uri, rawToken, now),
+			token:  boundToken,
+			method: "GET",
+			uri:    uri,
+			expErr: "does not match the key the token is bound to",
+		},
+		{
+			name:   "wrong_method",
+			proof:  proof(t, holderKey, "GET", uri, rawToken, now),
+			token:  boundToken,
+			method: "POST",
+			uri:    uri,
+			expErr: `proof htm "GET" does not match the request method "POST"`,
+		},
+		{
+			name:   "wrong_uri",
+			proof:  proof(t, holderKey, "GET", uri, rawToken, now),
+			token:  boundToken,
+			method: "GET",
+			uri:    "https://other.example.com/resource",
+			expErr: "does not match the request uri",
+		},
+		{
+			name:   "other_token",
+			proof:  proof(t, holderKey, "GET", uri, "other.token.signature", now),
+			token:  boundToken,
+			method: "GET",
+			uri:    uri,
+			expErr: "proof ath does not match the token",
+		},
+		{
+			name:   "expired",
+			proof:  proof(t, holderKey, "GET", uri, rawToken, now.Add(-10*time.Minute)),
+			token:  boundToken,
+			method: "GET",
+			uri:    uri,
+			expErr: "more than 1m0s ago",
+		},
+		{
+			name:   "not_a_proof",
+			proof:  "not-a-jws",
+			token:  boundToken,
+			method: "GET",
+			uri:    uri,
+			expErr: "failed to parse proof",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := VerifyDPoPProof(tc.proof, tc.token, rawToken, tc.method, tc.uri, time.Minute)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// EncryptedJustificationsKey is the key in the JWT where justifications are
+// stored when they are encrypted. The value is a JWE, in compact serialization
+// for a single recipient and JSON serialization otherwise. Tokens with
+// encrypted justifications do not have a [JustificationsKey] claim.
+const EncryptedJustificationsKey string = "justs_enc"
+
+// HasEncryptedJustifications returns true if the token's justifications are
+// encrypted and must be read with [DecryptJustifications].
+func HasEncryptedJustifications(t jwt.Token) bool {
+	if t == nil {
+		return false
+	}
+	_, ok := t.Get(EncryptedJustificationsKey)
+	return ok
+}
+
+// EncryptJustifications encrypts the justifications to every encryption key in
+// recipients and stores them on the token, removing any plaintext
+// justifications. Standard claims remain visible.
+//
+// The key management algorithm is taken from each key's "alg", and defaults to
+// ECDH-ES+A256KW for EC and OKP keys and RSA-OAEP-256 for RSA keys. Keys with a
+// "use" other than "enc" are skipped.
+func EncryptJustifications(t jwt.Token, justifications []*Justification, recipients jwk.Set) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+	if recipients == nil {
+		return fmt.Errorf("recipients cannot be nil")
+	}
+
+	opts := []jwe.EncryptOption{jwe.WithContentEncryption(jwa.A256GCM)}
+	var count int
+	for i := 0; i < recipients.Len(); i++ {
+		key, ok := recipients.Key(i)
+		if !ok {
+			continue
+		}
+		if use := key.KeyUsage(); use != "" && use != string(jwk.ForEncryption) {
+			continue
+		}
+
+		alg, err := encryptionAlgorithm(key)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, jwe.WithKey(alg, key))
+		count++
+	}
+	if count == 0 {
+		return fmt.Errorf("no encryption keys found in recipients")
+	}
+	if count > 1 {
+		opts = append(opts, jwe.WithJSON())
+	}
+
+	if justifications == nil {
+		justifications = []*Justification{}
+	}
+	payload, err := json.Marshal(justifications)
+	if err != nil {
+		return fmt.Errorf("failed to marshal justifications: %w", err)
+	}
+
+	b, err := jwe.Encrypt(payload, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt justifications: %w", err)
+	}
+
+	if err := t.Set(EncryptedJustificationsKey, string(b)); err != nil {
+		return fmt.Errorf("failed to set encrypted justifications: %w", err)
+	}
+	if err := ClearJustifications(t); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DecryptJustifications decrypts the token's encrypted justifications with any
+// of the given private keys. If the justifications are not encrypted, it
+// returns the plaintext justifications like [GetJustifications].
+func DecryptJustifications(t jwt.Token, keys jwk.Set) ([]*Justification, error) {
+	if t == nil {
+		return nil, fmt.Errorf("token cannot be nil")
+	}
+
+	raw, ok := t.Get(EncryptedJustificationsKey)
+	if !ok {
+		return GetJustifications(t)
+	}
+
+	if err := checkClaimVersion(t); err != nil {
+		return nil, err
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("found encrypted justifications, but was of unknown type %T", raw)
+	}
+
+	payload, err := jwe.Decrypt([]byte(str), jwe.WithKeyProvider(decryptionKeyProvider(keys)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt justifications: %w", err)
+	}
+
+	var justifications []*Justification
+	if err := json.Unmarshal(payload, &justifications); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decrypted justifications: %w", err)
+	}
+	return justifications, nil
+}
+
+// decryptionKeyProvider offers every encryption key in the set that matches
+// the recipient's "kid" (if both have one). Unlike [jwe.WithKeySet], keys
+// without an "alg" are used with the recipient's algorithm, since keys held by
+// users frequently omit it.
+func decryptionKeyProvider(keys jwk.Set) jwe.KeyProvider {
+	return jwe.KeyProviderFunc(func(_ context.Context, sink jwe.KeySink, r jwe.Recipient, _ *jwe.Message) error {
+		alg := r.Headers().Algorithm()
+		kid := r.Headers().KeyID()
+
+		for i := 0; i < keys.Len(); i++ {
+			key, ok := keys.Key(i)
+			if !ok {
+				continue
+			}
+			if use := key.KeyUsage(); use != "" && use != string(jwk.ForEncryption) {
+				continue
+			}
+			if kid != "" && key.KeyID() != "" && key.KeyID() != kid {
+				continue
+			}
+			if v := key.Algorithm(); v != nil && v.String() != "" && v.String() != alg.String() {
+				continue
+			}
+			sink.Key(alg, key)
+		}
+		return nil
+	})
+}
+
+// encryptionAlgorithm returns the key management algorithm for the key.
+func encryptionAlgorithm(key jwk.Key) (jwa.KeyEncryptionAlgorithm, error) {
+	if alg := key.Algorithm(); alg != nil && alg.String() != "" {
+		var kea jwa.KeyEncryptionAlgorithm
+		if err := kea.Accept(alg.String()); err != nil {
+			return "", fmt.Errorf("key %q has unsupported encryption algorithm %q: %w", key.KeyID(), alg, err)
+		}
+		return kea, nil
+	}
+
+	switch key.KeyType() {
+	case jwa.EC, jwa.OKP:
+		return jwa.ECDH_ES_A256KW, nil
+	case jwa.RSA:
+		return jwa.RSA_OAEP_256, nil
+	default:
+		return "", fmt.Errorf("key %q has unsupported key type %q", key.KeyID(), key.KeyType())
+	}
+}
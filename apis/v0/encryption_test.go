@@ -0,0 +1,203 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestEncryptDecryptJustifications(t *testing.T) {
+	t.Parallel()
+
+	ecKey := testEncryptionKey(t, "ec")
+	rsaKey := testEncryptionKey(t, "rsa")
+	otherKey := testEncryptionKey(t, "ec")
+
+	justifications := []*Justification{
+		{Category: "explanation", Value: "incident #123: customer data in logs"},
+	}
+
+	cases := []struct {
+		name       string
+		recipients []jwk.Key
+		decrypt    []jwk.Key
+		expErr     string
+		expDecErr  string
+	}{
+		{
+			name:       "single_recipient",
+			recipients: []jwk.Key{ecKey},
+			decrypt:    []jwk.Key{ecKey},
+		},
+		{
+			name:       "multiple_recipients_first",
+			recipients: []jwk.Key{ecKey, rsaKey},
+			decrypt:    []jwk.Key{ecKey},
+		},
+		{
+			name:       "multiple_recipients_second",
+			recipients: []jwk.Key{ecKey, rsaKey},
+			decrypt:    []jwk.Key{rsaKey},
+		},
+		{
+			name:       "wrong_key",
+			recipients: []jwk.Key{ecKey},
+			decrypt:    []jwk.Key{otherKey},
+			expDecErr:  "failed to decrypt justifications",
+		},
+		{
+			name:   "no_recipients",
+			expErr: "no encryption keys found in recipients",
+		},
+		{
+			name: "signing_key_skipped",
+			recipients: func() []jwk.Key {
+				k := testEncryptionKey(t, "ec")
+				if err := k.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+					t.Fatal(err)
+				}
+				return []jwk.Key{k}
+			}(),
+			expErr: "no encryption keys found in recipients",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			token := testTokenBuilder(t, jwt.NewBuilder().Subject("you@example.com"))
+
+			err := EncryptJustifications(token, justifications, testPublicSet(t, tc.recipients...))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			if !HasEncryptedJustifications(token) {
+				t.Errorf("expected token to have encrypted justifications")
+			}
+			if _, ok := token.Get(JustificationsKey); ok {
+				t.Errorf("expected token to not have plaintext justifications")
+			}
+			if got, want := token.Subject(), "you@example.com"; got != want {
+				t.Errorf("expected standard claim %q to be %q", got, want)
+			}
+
+			// Round-trip through a signed token, like a client would see it.
+			b, err := jwt.NewSerializer().Serialize(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := jwt.ParseInsecure(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			privateSet := jwk.NewSet()
+			for _, k := range tc.decrypt {
+				if err := privateSet.AddKey(k); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := DecryptJustifications(parsed, privateSet)
+			if diff := testutil.DiffErrString(err, tc.expDecErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(justifications, got, cmpopts.IgnoreUnexported(Justification{})); diff != "" {
+				t.Errorf("justifications (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecryptJustifications_plaintext(t *testing.T) {
+	t.Parallel()
+
+	token := testTokenBuilder(t, jwt.NewBuilder())
+	if err := SetJustifications(token, []*Justification{{Category: "explanation", Value: "testing"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if HasEncryptedJustifications(token) {
+		t.Errorf("expected token to not have encrypted justifications")
+	}
+
+	got, err := DecryptJustifications(token, jwk.NewSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(got), 1; got != want {
+		t.Errorf("expected %d justifications to be %d", got, want)
+	}
+}
+
+func testEncryptionKey(tb testing.TB, kty string) jwk.Key {
+	tb.Helper()
+
+	var raw any
+	var err error
+	switch kty {
+	case "ec":
+		raw, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		raw, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := jwk.AssignKeyID(key); err != nil {
+		tb.Fatal(err)
+	}
+	return key
+}
+
+func testPublicSet(tb testing.TB, keys ...jwk.Key) jwk.Set {
+	tb.Helper()
+
+	set := jwk.NewSet()
+	for _, k := range keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err := set.AddKey(pub); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return set
+}
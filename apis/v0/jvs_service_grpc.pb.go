@@ -37,6 +37,20 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type JVSServiceClient interface {
 	CreateJustification(ctx context.Context, in *CreateJustificationRequest, opts ...grpc.CallOption) (*CreateJustificationResponse, error)
+	// ExchangeToken verifies a third-party OIDC token and mints a justification
+	// token for its subject, so callers need no long-lived credentials.
+	ExchangeToken(ctx context.Context, in *ExchangeTokenRequest, opts ...grpc.CallOption) (*CreateJustificationResponse, error)
+	// SignPayload signs a payload digest with the JVS signing key and returns a
+	// detached JWS, verifiable with the public keys served by the JVS.
+	SignPayload(ctx context.Context, in *SignPayloadRequest, opts ...grpc.CallOption) (*SignPayloadResponse, error)
+	// CreateCertificate issues a short-lived X.509 client certificate embedding
+	// the justifications, signed by the JVS certificate authority.
+	CreateCertificate(ctx context.Context, in *CreateCertificateRequest, opts ...grpc.CallOption) (*CreateCertificateResponse, error)
+	// ListCategories lists the justification categories the JVS accepts.
+	ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error)
+	// GetPolicyAttestation returns the issuance policy in force, e.g. the max
+	// TTLs, categories and audience rules, signed with the JVS signing key.
+	GetPolicyAttestation(ctx context.Context, in *GetPolicyAttestationRequest, opts ...grpc.CallOption) (*GetPolicyAttestationResponse, error)
 }
 
 type jVSServiceClient struct {
@@ -56,11 +70,70 @@ func (c *jVSServiceClient) CreateJustification(ctx context.Context, in *CreateJu
 	return out, nil
 }
 
+func (c *jVSServiceClient) ExchangeToken(ctx context.Context, in *ExchangeTokenRequest, opts ...grpc.CallOption) (*CreateJustificationResponse, error) {
+	out := new(CreateJustificationResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.JVSService/ExchangeToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jVSServiceClient) SignPayload(ctx context.Context, in *SignPayloadRequest, opts ...grpc.CallOption) (*SignPayloadResponse, error) {
+	out := new(SignPayloadResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.JVSService/SignPayload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jVSServiceClient) CreateCertificate(ctx context.Context, in *CreateCertificateRequest, opts ...grpc.CallOption) (*CreateCertificateResponse, error) {
+	out := new(CreateCertificateResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.JVSService/CreateCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jVSServiceClient) ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error) {
+	out := new(ListCategoriesResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.JVSService/ListCategories", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jVSServiceClient) GetPolicyAttestation(ctx context.Context, in *GetPolicyAttestationRequest, opts ...grpc.CallOption) (*GetPolicyAttestationResponse, error) {
+	out := new(GetPolicyAttestationResponse)
+	err := c.cc.Invoke(ctx, "/abcxyz.jvs.JVSService/GetPolicyAttestation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // JVSServiceServer is the server API for JVSService service.
 // All implementations must embed UnimplementedJVSServiceServer
 // for forward compatibility
 type JVSServiceServer interface {
 	CreateJustification(context.Context, *CreateJustificationRequest) (*CreateJustificationResponse, error)
+	// ExchangeToken verifies a third-party OIDC token and mints a justification
+	// token for its subject, so callers need no long-lived credentials.
+	ExchangeToken(context.Context, *ExchangeTokenRequest) (*CreateJustificationResponse, error)
+	// SignPayload signs a payload digest with the JVS signing key and returns a
+	// detached JWS, verifiable with the public keys served by the JVS.
+	SignPayload(context.Context, *SignPayloadRequest) (*SignPayloadResponse, error)
+	// CreateCertificate issues a short-lived X.509 client certificate embedding
+	// the justifications, signed by the JVS certificate authority.
+	CreateCertificate(context.Context, *CreateCertificateRequest) (*CreateCertificateResponse, error)
+	// ListCategories lists the justification categories the JVS accepts.
+	ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error)
+	// GetPolicyAttestation returns the issuance policy in force, e.g. the max
+	// TTLs, categories and audience rules, signed with the JVS signing key.
+	GetPolicyAttestation(context.Context, *GetPolicyAttestationRequest) (*GetPolicyAttestationResponse, error)
 	mustEmbedUnimplementedJVSServiceServer()
 }
 
@@ -71,6 +144,21 @@ type UnimplementedJVSServiceServer struct {
 func (UnimplementedJVSServiceServer) CreateJustification(context.Context, *CreateJustificationRequest) (*CreateJustificationResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateJustification not implemented")
 }
+func (UnimplementedJVSServiceServer) ExchangeToken(context.Context, *ExchangeTokenRequest) (*CreateJustificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExchangeToken not implemented")
+}
+func (UnimplementedJVSServiceServer) SignPayload(context.Context, *SignPayloadRequest) (*SignPayloadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignPayload not implemented")
+}
+func (UnimplementedJVSServiceServer) CreateCertificate(context.Context, *CreateCertificateRequest) (*CreateCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCertificate not implemented")
+}
+func (UnimplementedJVSServiceServer) ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedJVSServiceServer) GetPolicyAttestation(context.Context, *GetPolicyAttestationRequest) (*GetPolicyAttestationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPolicyAttestation not implemented")
+}
 func (UnimplementedJVSServiceServer) mustEmbedUnimplementedJVSServiceServer() {}
 
 // UnsafeJVSServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -102,6 +190,96 @@ func _JVSService_CreateJustification_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _JVSService_ExchangeToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExchangeTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JVSServiceServer).ExchangeToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.JVSService/ExchangeToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JVSServiceServer).ExchangeToken(ctx, req.(*ExchangeTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JVSService_SignPayload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignPayloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JVSServiceServer).SignPayload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.JVSService/SignPayload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JVSServiceServer).SignPayload(ctx, req.(*SignPayloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JVSService_CreateCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JVSServiceServer).CreateCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.JVSService/CreateCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JVSServiceServer).CreateCertificate(ctx, req.(*CreateCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JVSService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JVSServiceServer).ListCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.JVSService/ListCategories",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JVSServiceServer).ListCategories(ctx, req.(*ListCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _JVSService_GetPolicyAttestation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPolicyAttestationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JVSServiceServer).GetPolicyAttestation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/abcxyz.jvs.JVSService/GetPolicyAttestation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JVSServiceServer).GetPolicyAttestation(ctx, req.(*GetPolicyAttestationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // JVSService_ServiceDesc is the grpc.ServiceDesc for JVSService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -113,6 +291,26 @@ var JVSService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateJustification",
 			Handler:    _JVSService_CreateJustification_Handler,
 		},
+		{
+			MethodName: "ExchangeToken",
+			Handler:    _JVSService_ExchangeToken_Handler,
+		},
+		{
+			MethodName: "SignPayload",
+			Handler:    _JVSService_SignPayload_Handler,
+		},
+		{
+			MethodName: "CreateCertificate",
+			Handler:    _JVSService_CreateCertificate_Handler,
+		},
+		{
+			MethodName: "ListCategories",
+			Handler:    _JVSService_ListCategories_Handler,
+		},
+		{
+			MethodName: "GetPolicyAttestation",
+			Handler:    _JVSService_GetPolicyAttestation_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "jvs_service.proto",
@@ -0,0 +1,300 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jvsplugintest provides a conformance test suite for JVS validator
+// plugins. Plugin authors run it from their own tests, to check their
+// [jvspb.Validator] behaves the way the JVS expects:
+//
+//	func TestValidator_conformance(t *testing.T) {
+//		t.Parallel()
+//
+//		jvsplugintest.RunConformanceTests(t, NewValidator(cfg),
+//			jvsplugintest.WithValidValue("JVS-123"))
+//	}
+package jvsplugintest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+const (
+	// maxAnnotationSize is the default maximum total size, in bytes, of the
+	// annotations of a justification the JVS accepts.
+	maxAnnotationSize = 2000
+
+	// hugeValueSize is the size of the value of the huge value test, larger
+	// than the JVS accepts, so plugins served outside of the JVS handle it too.
+	hugeValueSize = 64 << 10
+
+	// cancelTimeout is how long a validator may take to return once its context
+	// is canceled.
+	cancelTimeout = 5 * time.Second
+
+	// concurrency is the number of concurrent calls of the concurrency test.
+	concurrency = 16
+)
+
+// Option is an option for [RunConformanceTests].
+type Option func(c *conformance)
+
+// WithValidValue sets a justification value the validator accepts, so the
+// tests of valid justifications are run. Without it, only the handling of
+// invalid justifications is tested.
+func WithValidValue(value string) Option {
+	return func(c *conformance) {
+		c.validValue = value
+	}
+}
+
+// WithCategory sets the category of the justifications passed to the
+// validator. The default is "test".
+func WithCategory(category string) Option {
+	return func(c *conformance) {
+		c.category = category
+	}
+}
+
+// WithRequestor sets the requestor of the justifications passed to the
+// validator. The default is "jvsplugintest@example.com".
+func WithRequestor(requestor string) Option {
+	return func(c *conformance) {
+		c.requestor = requestor
+	}
+}
+
+type conformance struct {
+	v          jvspb.Validator
+	validValue string
+	category   string
+	requestor  string
+}
+
+// RunConformanceTests runs the conformance test suite against the validator,
+// as subtests of t. It checks that:
+//
+//   - GetUIData returns a display name, and a value pattern that compiles and
+//     matches the valid value, if any.
+//   - Invalid justifications, including empty and huge values, are rejected
+//     with a response that explains why, rather than with an error, which the
+//     JVS treats as an internal failure.
+//   - Valid justifications are accepted, with annotations small enough for the
+//     JVS to accept.
+//   - Validate returns promptly once its context is canceled.
+//   - Concurrent calls are safe and consistent. Run the tests with -race to
+//     detect data races.
+//   - Requests aren't modified.
+func RunConformanceTests(t *testing.T, v jvspb.Validator, opts ...Option) {
+	t.Helper()
+
+	c := &conformance{
+		v:         v,
+		category:  "test",
+		requestor: "jvsplugintest@example.com",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	t.Run("get_ui_data", c.testGetUIData)
+	t.Run("empty_value", func(t *testing.T) {
+		c.testInvalid(t, "")
+	})
+	t.Run("huge_value", c.testHugeValue)
+	if c.validValue != "" {
+		t.Run("valid_value", c.testValid)
+	}
+	t.Run("canceled_context", c.testCanceled)
+	t.Run("concurrent_calls", c.testConcurrent)
+	t.Run("request_not_modified", c.testRequestNotModified)
+}
+
+func (c *conformance) request(value string) *jvspb.ValidateJustificationRequest {
+	return &jvspb.ValidateJustificationRequest{
+		Justification: &jvspb.Justification{
+			Category: c.category,
+			Value:    value,
+		},
+		Requestor: c.requestor,
+	}
+}
+
+// value returns the valid value if set, or an arbitrary one.
+func (c *conformance) value() string {
+	if c.validValue != "" {
+		return c.validValue
+	}
+	return "jvsplugintest"
+}
+
+func (c *conformance) testGetUIData(t *testing.T) {
+	uiData, err := c.v.GetUIData(context.Background(), &jvspb.GetUIDataRequest{})
+	if err != nil {
+		t.Fatalf("GetUIData returned an error: %v", err)
+	}
+	if uiData == nil {
+		t.Fatal("GetUIData returned no ui data")
+	}
+	if uiData.GetDisplayName() == "" {
+		t.Error("GetUIData returned no display name, which the web UI lists the category as")
+	}
+
+	pattern := uiData.GetValuePattern()
+	if pattern == "" {
+		return
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatalf("GetUIData returned a value pattern that isn't a valid RE2 regular expression: %v", err)
+	}
+	if c.validValue != "" && !re.MatchString(c.validValue) {
+		t.Errorf("value pattern %q doesn't match the valid value %q, so the JVS would reject it", pattern, c.validValue)
+	}
+}
+
+// testInvalid checks the value is rejected with a response that explains why.
+func (c *conformance) testInvalid(t *testing.T, value string) {
+	resp, err := c.v.Validate(context.Background(), c.request(value))
+	if err != nil {
+		t.Fatalf("Validate returned an error for value %q, it must return an invalid response instead: %v", value, err)
+	}
+	if resp == nil {
+		t.Fatalf("Validate returned no response for value %q", value)
+	}
+	if resp.GetValid() {
+		t.Errorf("Validate accepted value %q", value)
+	}
+	if len(resp.GetError()) == 0 {
+		t.Errorf("Validate rejected value %q without an error message", value)
+	}
+}
+
+func (c *conformance) testHugeValue(t *testing.T) {
+	value := c.value() + strings.Repeat("a", hugeValueSize)
+	resp, err := c.v.Validate(context.Background(), c.request(value))
+	if err != nil {
+		t.Fatalf("Validate returned an error for a huge value, it must return an invalid response instead: %v", err)
+	}
+	if resp == nil {
+		t.Fatal("Validate returned no response for a huge value")
+	}
+	if !resp.GetValid() && len(resp.GetError()) == 0 {
+		t.Error("Validate rejected a huge value without an error message")
+	}
+	checkAnnotations(t, resp)
+}
+
+func (c *conformance) testValid(t *testing.T) {
+	resp, err := c.v.Validate(context.Background(), c.request(c.validValue))
+	if err != nil {
+		t.Fatalf("Validate returned an error for the valid value %q: %v", c.validValue, err)
+	}
+	if resp == nil {
+		t.Fatalf("Validate returned no response for the valid value %q", c.validValue)
+	}
+	if !resp.GetValid() {
+		t.Errorf("Validate rejected the valid value %q: %v", c.validValue, resp.GetError())
+	}
+	if len(resp.GetError()) > 0 {
+		t.Errorf("Validate accepted the valid value %q with errors: %v", c.validValue, resp.GetError())
+	}
+	checkAnnotations(t, resp)
+}
+
+func (c *conformance) testCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Either an error or a response is fine, as long as it returns.
+		_, _ = c.v.Validate(ctx, c.request(c.value()))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(cancelTimeout):
+		t.Fatalf("Validate didn't return within %s of its context being canceled", cancelTimeout)
+	}
+}
+
+func (c *conformance) testConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	values := []string{"", c.value()}
+	want := make(map[string]bool, len(values))
+	for _, value := range values {
+		resp, err := c.v.Validate(ctx, c.request(value))
+		if err != nil {
+			t.Fatalf("Validate returned an error for value %q: %v", value, err)
+		}
+		want[value] = resp.GetValid()
+	}
+
+	var wg sync.WaitGroup
+	for i := range concurrency {
+		value := values[i%len(values)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := c.v.GetUIData(ctx, &jvspb.GetUIDataRequest{}); err != nil {
+				t.Errorf("concurrent GetUIData returned an error: %v", err)
+			}
+			resp, err := c.v.Validate(ctx, c.request(value))
+			if err != nil {
+				t.Errorf("concurrent Validate returned an error for value %q: %v", value, err)
+				return
+			}
+			if got := resp.GetValid(); got != want[value] {
+				t.Errorf("concurrent Validate returned valid %t for value %q, expected %t", got, value, want[value])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *conformance) testRequestNotModified(t *testing.T) {
+	req := c.request(c.value())
+	orig := proto.Clone(req)
+	if _, err := c.v.Validate(context.Background(), req); err != nil {
+		t.Fatalf("Validate returned an error: %v", err)
+	}
+	if !proto.Equal(orig, req) {
+		t.Errorf("Validate modified the request, expected %v, got %v", orig, req)
+	}
+}
+
+// checkAnnotations checks the annotations of the response fit in the default
+// annotation size limit of the JVS.
+func checkAnnotations(tb testing.TB, resp *jvspb.ValidateJustificationResponse) {
+	tb.Helper()
+
+	var size int
+	for k, v := range resp.GetAnnotation() {
+		size += len(k) + len(v)
+	}
+	if size > maxAnnotationSize {
+		tb.Errorf("annotations size (%d bytes) must be less than %d bytes", size, maxAnnotationSize)
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// DefaultJWKSRefreshInterval is the default interval between refreshes of
+	// a [JWKSProvider].
+	DefaultJWKSRefreshInterval = 5 * time.Minute
+
+	// jwksFetchTimeout is how long fetching the JWKS once may take.
+	jwksFetchTimeout = 10 * time.Second
+
+	// jwksMinRetryInterval is the first delay before retrying a failed refresh.
+	// It doubles on each consecutive failure, up to the refresh interval.
+	jwksMinRetryInterval = time.Second
+
+	// jwksMaxSize is the maximum size of a JWKS response.
+	jwksMaxSize = 1 << 20
+)
+
+// JWKSProvider is a [jwk.Set] of the keys served at a JWKS endpoint, which it
+// refreshes in the background. Refreshes are conditional requests using the
+// ETag and Last-Modified headers of the previous response, so an unchanged key
+// set costs the endpoint little more than a 304.
+//
+// Reads never wait for a refresh and keep returning the last fetched keys if a
+// refresh fails. Only the first read waits for the keys, if they weren't
+// fetched yet. Signed key sets are the exception: once one expires, reads fail
+// until a refresh returns a signed key set that hasn't expired, so an endpoint
+// that is blocked or replays a 304 can't keep revoked keys alive.
+//
+// The set is read-only, its mutating methods return an error.
+type JWKSProvider struct {
+	endpoint string
+	client   *http.Client
+	interval time.Duration
+	onChange func(jwk.Set)
+
+	// rootKeys are the JWK thumbprints of the root keys, if the key set must
+	// be signed.
+	rootKeys []string
+
+	// now is overridden in tests.
+	now func() time.Time
+
+	// fetchMu serializes fetches, so concurrent lazy reads fetch once.
+	fetchMu sync.Mutex
+
+	mu           sync.RWMutex
+	set          jwk.Set
+	raw          []byte
+	etag         string
+	lastModified string
+
+	// expiresAt is the expiry of the signed key set, zero if it isn't signed.
+	expiresAt time.Time
+}
+
+var _ jwk.Set = (*JWKSProvider)(nil)
+
+// JWKSProviderOption is an option for [NewJWKSProvider].
+type JWKSProviderOption func(p *JWKSProvider)
+
+// WithJWKSRefreshInterval sets the interval between refreshes. Each wait is
+// jittered by up to 10% so many clients don't refresh in lockstep. The default
+// is [DefaultJWKSRefreshInterval].
+func WithJWKSRefreshInterval(d time.Duration) JWKSProviderOption {
+	return func(p *JWKSProvider) {
+		p.interval = d
+	}
+}
+
+// WithJWKSHTTPClient sets the HTTP client used to fetch the JWKS.
+func WithJWKSHTTPClient(c *http.Client) JWKSProviderOption {
+	return func(p *JWKSProvider) {
+		p.client = c
+	}
+}
+
+// WithJWKSOnChange sets a function that is called with the new key set each
+// time a refresh returns different keys. It isn't called for the first fetch.
+func WithJWKSOnChange(fn func(jwk.Set)) JWKSProviderOption {
+	return func(p *JWKSProvider) {
+		p.onChange = fn
+	}
+}
+
+// WithJWKSRootKeys requires the key set to be signed by one of the root keys,
+// given as their JWK thumbprints, so keys substituted by the layer serving the
+// JWKS are rejected. The signed key set is requested, and unsigned responses
+// fail the fetch. See [VerifySignedJWKS].
+func WithJWKSRootKeys(thumbprints ...string) JWKSProviderOption {
+	return func(p *JWKSProvider) {
+		p.rootKeys = thumbprints
+	}
+}
+
+// NewJWKSProvider returns a provider of the keys served at the JWKS endpoint.
+// Keys are fetched on first use, or by calling [JWKSProvider.Refresh], and
+// refreshed in the background until the context is done.
+func NewJWKSProvider(ctx context.Context, endpoint string, opts ...JWKSProviderOption) (*JWKSProvider, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwks endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("jwks endpoint %q must be an http or https url", endpoint)
+	}
+
+	p := &JWKSProvider{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: jwksFetchTimeout},
+		interval: DefaultJWKSRefreshInterval,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.interval <= 0 {
+		return nil, fmt.Errorf("jwks refresh interval must be a positive duration, got %s", p.interval)
+	}
+	for _, thumbprint := range p.rootKeys {
+		if err := ValidateJWKThumbprint(thumbprint); err != nil {
+			return nil, fmt.Errorf("invalid jwks root key %q: %w", thumbprint, err)
+		}
+	}
+
+	go p.run(ctx)
+	return p, nil
+}
+
+// run refreshes the keys every interval until the context is done. Failed
+// refreshes are retried sooner, with exponential backoff.
+func (p *JWKSProvider) run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	retry := jwksMinRetryInterval
+	wait := jitter(p.interval)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := p.Refresh(ctx); err != nil {
+			logger.WarnContext(ctx, "failed to refresh jwks, serving previous keys",
+				"endpoint", p.endpoint,
+				"retry_in", retry,
+				"error", err)
+			wait = retry
+			retry = min(2*retry, p.interval)
+			continue
+		}
+		retry = jwksMinRetryInterval
+		wait = jitter(p.interval)
+	}
+}
+
+// jitter returns d randomly adjusted by up to 10%.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration((rand.Float64()-0.5)*0.2*float64(d)) //nolint:gosec // Jitter doesn't need to be secure.
+}
+
+// Refresh fetches the keys now and returns them. If the endpoint responds that
+// the keys didn't change, the current keys are returned.
+func (p *JWKSProvider) Refresh(ctx context.Context) (jwk.Set, error) {
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+	return p.fetch(ctx)
+}
+
+func (p *JWKSProvider) fetch(ctx context.Context) (jwk.Set, error) {
+	p.mu.RLock()
+	etag, lastModified := p.etag, p.lastModified
+	expired := p.expired()
+	p.mu.RUnlock()
+	// An expired key set must be replaced, so don't let the endpoint answer
+	// that it didn't change.
+	if expired {
+		etag, lastModified = "", ""
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if len(p.rootKeys) > 0 {
+		req.Header.Set("Accept", SignedJWKSContentType)
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		if p.set == nil {
+			return nil, fmt.Errorf("jwks endpoint returned 304 for the first fetch")
+		}
+		set, err := p.unexpired()
+		if err != nil {
+			return nil, fmt.Errorf("jwks endpoint returned 304: %w", err)
+		}
+		return set, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, jwksMaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks: %w", err)
+	}
+	set, expiresAt, err := p.parse(resp.Header.Get("Content-Type"), b)
+	if err != nil {
+		return nil, err
+	}
+	// Compare the keys in a canonical form, so formatting changes of the
+	// response aren't reported as key changes.
+	raw, err := json.Marshal(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal jwks: %w", err)
+	}
+
+	p.mu.Lock()
+	changed := p.set != nil && !bytes.Equal(p.raw, raw)
+	p.set, p.raw, p.expiresAt = set, raw, expiresAt
+	p.etag, p.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	p.mu.Unlock()
+
+	if changed && p.onChange != nil {
+		p.onChange(set)
+	}
+	return set, nil
+}
+
+// parse parses the fetched key set, verifying its signature if it must be
+// signed. It returns the expiry of signed key sets.
+func (p *JWKSProvider) parse(contentType string, b []byte) (jwk.Set, time.Time, error) {
+	if len(p.rootKeys) == 0 {
+		set, err := jwk.Parse(b)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("failed to parse jwks: %w", err)
+		}
+		return set, time.Time{}, nil
+	}
+
+	// An unsigned key set is rejected even if the endpoint never served a
+	// signed one, as it is what a substituted key set would look like.
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != SignedJWKSContentType {
+		return nil, time.Time{}, fmt.Errorf("jwks endpoint returned content type %q, expected a signed jwks (%s)",
+			contentType, SignedJWKSContentType)
+	}
+	signed, err := VerifySignedJWKS(b, p.rootKeys, p.now(), DefaultAllowedClockSkew)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return signed.Keys, signed.ExpiresAt, nil
+}
+
+// unexpired returns the current keys, or an error if they expired. The caller
+// must hold mu.
+func (p *JWKSProvider) unexpired() (jwk.Set, error) {
+	if p.expired() {
+		return nil, fmt.Errorf("signed jwks expired at %s", p.expiresAt.Format(time.RFC3339))
+	}
+	return p.set, nil
+}
+
+// expired reports whether the current key set is signed and expired, allowing
+// for clock skew. The caller must hold mu.
+func (p *JWKSProvider) expired() bool {
+	return !p.expiresAt.IsZero() && p.now().After(p.expiresAt.Add(DefaultAllowedClockSkew))
+}
+
+// current returns the last fetched keys, fetching them if there are none yet.
+// It returns an error if they are a signed key set that expired, until a
+// refresh replaces them.
+func (p *JWKSProvider) current() (jwk.Set, error) {
+	p.mu.RLock()
+	set, err := p.unexpired()
+	p.mu.RUnlock()
+	if set != nil || err != nil {
+		return set, err
+	}
+
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+
+	// Another read may have fetched the keys while this one waited.
+	p.mu.RLock()
+	set, err = p.unexpired()
+	p.mu.RUnlock()
+	if set != nil || err != nil {
+		return set, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	return p.fetch(ctx)
+}
+
+// AddKey returns an error, as the set is read-only.
+func (p *JWKSProvider) AddKey(_ jwk.Key) error {
+	return fmt.Errorf("jwks provider is read-only")
+}
+
+// Clear returns an error, as the set is read-only.
+func (p *JWKSProvider) Clear() error {
+	return fmt.Errorf("jwks provider is read-only")
+}
+
+// Set returns an error, as the set is read-only.
+func (p *JWKSProvider) Set(_ string, _ any) error {
+	return fmt.Errorf("jwks provider is read-only")
+}
+
+// Remove returns an error, as the set is read-only.
+func (p *JWKSProvider) Remove(_ string) error {
+	return fmt.Errorf("jwks provider is read-only")
+}
+
+// RemoveKey returns an error, as the set is read-only.
+func (p *JWKSProvider) RemoveKey(_ jwk.Key) error {
+	return fmt.Errorf("jwks provider is read-only")
+}
+
+// Clone returns a copy of the current keys.
+func (p *JWKSProvider) Clone() (jwk.Set, error) {
+	set, err := p.current()
+	if err != nil {
+		return nil, err
+	}
+	return set.Clone() //nolint:wrapcheck // Want passthrough
+}
+
+// Get returns the value of a non-key field of the current set.
+func (p *JWKSProvider) Get(name string) (any, bool) {
+	set, err := p.current()
+	if err != nil {
+		return nil, false
+	}
+	return set.Get(name)
+}
+
+// Key returns the current key at the index.
+func (p *JWKSProvider) Key(idx int) (jwk.Key, bool) {
+	set, err := p.current()
+	if err != nil {
+		return nil, false
+	}
+	return set.Key(idx)
+}
+
+// Index returns the index of the key in the current set, or -1.
+func (p *JWKSProvider) Index(key jwk.Key) int {
+	set, err := p.current()
+	if err != nil {
+		return -1
+	}
+	return set.Index(key)
+}
+
+// Keys returns an iterator over the current keys.
+func (p *JWKSProvider) Keys(ctx context.Context) jwk.KeyIterator {
+	set, err := p.current()
+	if err != nil {
+		return jwk.NewSet().Keys(ctx)
+	}
+	return set.Keys(ctx)
+}
+
+// Iterate returns an iterator over the non-key fields of the current set.
+func (p *JWKSProvider) Iterate(ctx context.Context) jwk.HeaderIterator {
+	set, err := p.current()
+	if err != nil {
+		return jwk.NewSet().Iterate(ctx)
+	}
+	return set.Iterate(ctx)
+}
+
+// Len returns the number of current keys, or -1 if they can't be fetched.
+func (p *JWKSProvider) Len() int {
+	set, err := p.current()
+	if err != nil {
+		return -1
+	}
+	return set.Len()
+}
+
+// LookupKeyID returns the current key with the key ID.
+func (p *JWKSProvider) LookupKeyID(kid string) (jwk.Key, bool) {
+	set, err := p.current()
+	if err != nil {
+		return nil, false
+	}
+	return set.LookupKeyID(kid)
+}
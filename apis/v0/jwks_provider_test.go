@@ -0,0 +1,306 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestNewJWKSProvider(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		endpoint string
+		opts     []JWKSProviderOption
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			endpoint: "https://jvs.example.com/.well-known/jwks",
+		},
+		{
+			name:     "not_http",
+			endpoint: "file:///jwks.json",
+			wantErr:  `jwks endpoint "file:///jwks.json" must be an http or https url`,
+		},
+		{
+			name:     "invalid_url",
+			endpoint: "https://jvs.example.com/%",
+			wantErr:  "failed to parse jwks endpoint",
+		},
+		{
+			name:     "zero_interval",
+			endpoint: "https://jvs.example.com/.well-known/jwks",
+			opts:     []JWKSProviderOption{WithJWKSRefreshInterval(0)},
+			wantErr:  "jwks refresh interval must be a positive duration, got 0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			_, err := NewJWKSProvider(ctx, tc.endpoint, tc.opts...)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestJWKSProvider_Refresh(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	srv := newTestJWKSServer(t, "key-1")
+
+	var changes []jwk.Set
+	p, err := NewJWKSProvider(ctx, srv.URL(),
+		WithJWKSRefreshInterval(time.Hour),
+		WithJWKSOnChange(func(set jwk.Set) {
+			changes = append(changes, set)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first read fetches the keys.
+	if _, ok := p.LookupKeyID("key-1"); !ok {
+		t.Fatal("expected key-1 to be found")
+	}
+	if got, want := srv.requests(), 1; got != want {
+		t.Errorf("expected %d requests to be %d", got, want)
+	}
+
+	// Unchanged keys are revalidated with the ETag.
+	set, err := p.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := set.Len(), 1; got != want {
+		t.Errorf("expected %d keys to be %d", got, want)
+	}
+	if got, want := srv.notModified(), 1; got != want {
+		t.Errorf("expected %d not modified responses to be %d", got, want)
+	}
+	if got := len(changes); got != 0 {
+		t.Errorf("expected no changes, got %d", got)
+	}
+
+	// Changed keys are fetched and reported.
+	srv.setKeys(t, "key-1", "key-2")
+	if _, err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.LookupKeyID("key-2"); !ok {
+		t.Error("expected key-2 to be found")
+	}
+	if got, want := len(changes), 1; got != want {
+		t.Fatalf("expected %d changes to be %d", got, want)
+	}
+	if got, want := changes[0].Len(), 2; got != want {
+		t.Errorf("expected %d changed keys to be %d", got, want)
+	}
+
+	// Failed refreshes keep serving the previous keys.
+	srv.setFailing(true)
+	if _, err := p.Refresh(ctx); err == nil {
+		t.Error("expected refresh to fail")
+	}
+	if _, ok := p.LookupKeyID("key-2"); !ok {
+		t.Error("expected key-2 to still be found")
+	}
+	if got, want := p.Len(), 2; got != want {
+		t.Errorf("expected %d keys to be %d", got, want)
+	}
+}
+
+func TestJWKSProvider_FirstFetchFails(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	srv := newTestJWKSServer(t, "key-1")
+	srv.setFailing(true)
+
+	p, err := NewJWKSProvider(ctx, srv.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Refresh(ctx); err == nil {
+		t.Error("expected refresh to fail")
+	}
+	if got, want := p.Len(), -1; got != want {
+		t.Errorf("expected %d keys to be %d", got, want)
+	}
+	if err := p.AddKey(nil); err == nil {
+		t.Error("expected AddKey to fail")
+	}
+}
+
+func TestJWKSProvider_Background(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	srv := newTestJWKSServer(t, "key-1")
+
+	changed := make(chan jwk.Set, 1)
+	p, err := NewJWKSProvider(ctx, srv.URL(),
+		WithJWKSRefreshInterval(10*time.Millisecond),
+		WithJWKSOnChange(func(set jwk.Set) {
+			select {
+			case changed <- set:
+			default:
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.setKeys(t, "key-2")
+
+	select {
+	case set := <-changed:
+		if _, ok := set.LookupKeyID("key-2"); !ok {
+			t.Error("expected key-2 in the changed keys")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the keys to change")
+	}
+	if _, ok := p.LookupKeyID("key-2"); !ok {
+		t.Error("expected key-2 to be found")
+	}
+}
+
+// testJWKSServer serves a JWKS with an ETag, and responds 304 to requests with
+// a matching If-None-Match header.
+type testJWKSServer struct {
+	srv *httptest.Server
+
+	mu             sync.Mutex
+	body           []byte
+	version        int
+	failing        bool
+	numRequests    int
+	numNotModified int
+}
+
+func newTestJWKSServer(tb testing.TB, kids ...string) *testJWKSServer {
+	tb.Helper()
+
+	s := &testJWKSServer{}
+	s.setKeys(tb, kids...)
+	s.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.numRequests++
+		if s.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		etag := `"` + strconv.Itoa(s.version) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			s.numNotModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(s.body)
+	}))
+	tb.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *testJWKSServer) URL() string {
+	return s.srv.URL + "/.well-known/jwks"
+}
+
+func (s *testJWKSServer) setKeys(tb testing.TB, kids ...string) {
+	tb.Helper()
+
+	set := jwk.NewSet()
+	for _, kid := range kids {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		key, err := jwk.FromRaw(priv.Public())
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+			tb.Fatal(err)
+		}
+		if err := set.AddKey(key); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body = b
+	s.version++
+}
+
+func (s *testJWKSServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *testJWKSServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.numRequests
+}
+
+func (s *testJWKSServer) notModified() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.numNotModified
+}
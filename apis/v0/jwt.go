@@ -15,7 +15,10 @@
 package v0
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/mitchellh/mapstructure"
@@ -35,8 +38,120 @@ const (
 	// RequestorKey is the key in the JWT that holds the identity of the principal
 	// that requested this JWT.
 	RequestorKey string = "req"
+
+	// GroupsKey is the key in the JWT that holds the groups the requestor is a
+	// member of, if the JVS is configured to resolve them. The list may be
+	// filtered and truncated, so services must only use it to grant access,
+	// and never to deny it.
+	GroupsKey string = "groups"
+
+	// VersionKey is the key in the JWT that holds the version of the layout of
+	// the JVS claims, so verifiers can parse tokens minted by newer versions of
+	// the JVS, or reject them, rather than misread them.
+	VersionKey string = "jvs_ver"
 )
 
+const (
+	// ClaimVersionLegacy is the version of tokens without a [VersionKey] claim,
+	// which were minted before claims were versioned. Their layout is the same
+	// as [ClaimVersion1].
+	ClaimVersionLegacy = 0
+
+	// ClaimVersion1 is the layout where [JustificationsKey] holds the list of
+	// justifications and [RequestorKey] the requestor.
+	ClaimVersion1 = 1
+
+	// LatestClaimVersion is the newest claim version this package can parse.
+	LatestClaimVersion = ClaimVersion1
+)
+
+// ErrUnsupportedClaimVersion is returned when parsing the claims of a token
+// whose claim version is newer than [LatestClaimVersion]. Verifiers must be
+// upgraded to read such tokens.
+var ErrUnsupportedClaimVersion = errors.New("unsupported claim version")
+
+// GetClaimVersion retrieves the version of the layout of the JVS claims on the
+// token. Tokens without a version claim are [ClaimVersionLegacy].
+func GetClaimVersion(t jwt.Token) (int, error) {
+	if t == nil {
+		return 0, fmt.Errorf("token cannot be nil")
+	}
+
+	raw, ok := t.Get(VersionKey)
+	if !ok {
+		return ClaimVersionLegacy, nil
+	}
+
+	// Numbers are decoded as float64, unless the token was built in-process.
+	var v int64
+	switch typ := raw.(type) {
+	case float64:
+		if typ != math.Trunc(typ) {
+			return 0, fmt.Errorf("found claim version, but was not an integer: %v", typ)
+		}
+		if typ < 0 || typ > math.MaxInt32 {
+			return 0, fmt.Errorf("found claim version, but was out of range: %v", typ)
+		}
+		v = int64(typ)
+	case int:
+		v = int64(typ)
+	case int64:
+		v = typ
+	case json.Number:
+		n, err := typ.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("found claim version, but was not an integer: %w", err)
+		}
+		v = n
+	default:
+		return 0, fmt.Errorf("found claim version, but was of unknown type %T", raw)
+	}
+
+	if v < 0 || v > math.MaxInt32 {
+		return 0, fmt.Errorf("found claim version, but was out of range: %d", v)
+	}
+	return int(v), nil
+}
+
+// SetClaimVersion sets the jvs_ver field on the JWT. It overwrites any existing
+// value. Setting [ClaimVersionLegacy] removes the field.
+func SetClaimVersion(t jwt.Token, version int) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+
+	if version < ClaimVersionLegacy || version > LatestClaimVersion {
+		return fmt.Errorf("claim version must be between %d and %d, got %d",
+			ClaimVersionLegacy, LatestClaimVersion, version)
+	}
+
+	if version == ClaimVersionLegacy {
+		if err := t.Remove(VersionKey); err != nil {
+			return fmt.Errorf("failed to remove claim version: %w", err)
+		}
+		return nil
+	}
+
+	if err := t.Set(VersionKey, version); err != nil {
+		return fmt.Errorf("failed to set claim version: %w", err)
+	}
+	return nil
+}
+
+// checkClaimVersion returns an error wrapping [ErrUnsupportedClaimVersion] if
+// the claims of the token are newer than this package can parse.
+func checkClaimVersion(t jwt.Token) error {
+	version, err := GetClaimVersion(t)
+	if err != nil {
+		return err
+	}
+	if version > LatestClaimVersion {
+		return fmt.Errorf("%w %d, the latest supported version is %d",
+			ErrUnsupportedClaimVersion, version, LatestClaimVersion)
+	}
+	return nil
+}
+
 // GetRequestor retrieves the identity of the principal that requested this JWT.
 // This is typically an email address that is extracted by the JVS using an
 // incoming authentication header. However, if the JVS is not protected by
@@ -82,6 +197,53 @@ func ClearRequestor(t jwt.Token) error {
 	return nil
 }
 
+// GetGroups retrieves the groups the requestor of this JWT is a member of. It
+// returns nil if the token has no groups.
+func GetGroups(t jwt.Token) ([]string, error) {
+	if t == nil {
+		return nil, fmt.Errorf("token cannot be nil")
+	}
+
+	raw, ok := t.Get(GroupsKey)
+	if !ok {
+		return nil, nil
+	}
+
+	switch list := raw.(type) {
+	case []string:
+		// The token was built in-process.
+		cp := make([]string, 0, len(list))
+		return append(cp, list...), nil
+	case []any:
+		groups := make([]string, 0, len(list))
+		for _, v := range list {
+			str, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("found groups, but a group was of unknown type %T", v)
+			}
+			groups = append(groups, str)
+		}
+		return groups, nil
+	default:
+		return nil, fmt.Errorf("found groups, but was of unknown type %T", raw)
+	}
+}
+
+// SetGroups sets the groups field on the JWT. It overwrites any existing value
+// and uses a copy of the inbound slice.
+func SetGroups(t jwt.Token, groups []string) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+
+	cp := make([]string, 0, len(groups))
+	cp = append(cp, groups...)
+	if err := t.Set(GroupsKey, cp); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	return nil
+}
+
 // WithTypedJustifications is an option for parsing JWTs that will convert
 // decode the [Justification] claims into the correct Go structure. If this is
 // not supplied, the claims will be "any" and future type assertions may fail.
@@ -96,7 +258,9 @@ func WithTypedJustifications() jwt.ParseOption {
 // This function is incredibly defensive against a poorly-parsed jwt. It handles
 // situations where the JWT was not properly decoded (i.e. the caller did not
 // use [WithTypedJustifications]), and when the token uses a single
-// justification instead of a slice.
+// justification instead of a slice. Tokens whose claim version is newer than
+// [LatestClaimVersion] return an error wrapping [ErrUnsupportedClaimVersion],
+// since their justifications may be laid out differently.
 //
 // Modifying the slice does not modify the underlying token - you must call
 // [SetJustifications] to update the data on the token.
@@ -105,6 +269,10 @@ func GetJustifications(t jwt.Token) ([]*Justification, error) {
 		return nil, fmt.Errorf("token cannot be nil")
 	}
 
+	if err := checkClaimVersion(t); err != nil {
+		return nil, err
+	}
+
 	raw, ok := t.Get(JustificationsKey)
 	if !ok {
 		return []*Justification{}, nil
@@ -292,6 +292,41 @@ func TestGetJustifications(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "claim_version_1",
+			token: testTokenBuilder(t, jwt.
+				NewBuilder().
+				Claim(VersionKey, ClaimVersion1).
+				Claim(JustificationsKey, []*Justification{
+					{
+						Category: "category",
+						Value:    "value",
+					},
+				}),
+			),
+			exp: []*Justification{
+				{
+					Category: "category",
+					Value:    "value",
+				},
+			},
+		},
+		{
+			name: "future_claim_version",
+			token: testTokenBuilder(t, jwt.
+				NewBuilder().
+				Claim(VersionKey, LatestClaimVersion+1).
+				Claim(JustificationsKey, map[string]any{"category": "value"}),
+			),
+			expErr: "unsupported claim version 2, the latest supported version is 1",
+		},
+		{
+			name: "invalid_claim_version",
+			token: testTokenBuilder(t, jwt.
+				NewBuilder().
+				Claim(VersionKey, "1")),
+			expErr: "found claim version, but was of unknown type string",
+		},
 	}
 
 	for _, tc := range cases {
@@ -313,6 +348,192 @@ func TestGetJustifications(t *testing.T) {
 	}
 }
 
+func TestGetGroups(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		token  jwt.Token
+		exp    []string
+		expErr string
+	}{
+		{
+			name:   "nil_token",
+			token:  nil,
+			expErr: "token cannot be nil",
+		},
+		{
+			name:  "no_groups",
+			token: testTokenBuilder(t, jwt.NewBuilder()),
+			exp:   nil,
+		},
+		{
+			name:  "returns_groups",
+			token: testTokenBuilder(t, jwt.NewBuilder().Claim(GroupsKey, []string{"a@example.com"})),
+			exp:   []string{"a@example.com"},
+		},
+		{
+			name: "parsed_groups",
+			token: func() jwt.Token {
+				token := testTokenBuilder(t, jwt.NewBuilder().Claim(GroupsKey, []string{"a@example.com", "b@example.com"}))
+				b, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("KEY")))
+				if err != nil {
+					t.Fatal(err)
+				}
+				parsed, err := jwt.ParseInsecure(b, jwt.WithContext(ctx))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return parsed
+			}(),
+			exp: []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name:   "wrong_type",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(GroupsKey, "a@example.com")),
+			expErr: "found groups, but was of unknown type string",
+		},
+		{
+			name:   "wrong_group_type",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(GroupsKey, []any{1})),
+			expErr: "found groups, but a group was of unknown type int",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := GetGroups(tc.token)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("groups: diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetClaimVersion(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		token  jwt.Token
+		exp    int
+		expErr string
+	}{
+		{
+			name:   "nil_token",
+			token:  nil,
+			expErr: "token cannot be nil",
+		},
+		{
+			name:  "legacy",
+			token: testTokenBuilder(t, jwt.NewBuilder()),
+			exp:   ClaimVersionLegacy,
+		},
+		{
+			name:  "int",
+			token: testTokenBuilder(t, jwt.NewBuilder().Claim(VersionKey, 1)),
+			exp:   1,
+		},
+		{
+			// Parsed tokens have float64 numbers.
+			name:  "float",
+			token: testTokenBuilder(t, jwt.NewBuilder().Claim(VersionKey, float64(2))),
+			exp:   2,
+		},
+		{
+			name:   "fraction",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(VersionKey, 1.5)),
+			expErr: "found claim version, but was not an integer: 1.5",
+		},
+		{
+			name:   "negative",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(VersionKey, -1)),
+			expErr: "found claim version, but was out of range: -1",
+		},
+		{
+			name:   "wrong_type",
+			token:  testTokenBuilder(t, jwt.NewBuilder().Claim(VersionKey, true)),
+			expErr: "found claim version, but was of unknown type bool",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := GetClaimVersion(tc.token)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := got, tc.exp; got != want {
+				t.Errorf("expected %d to be %d", got, want)
+			}
+		})
+	}
+}
+
+func TestSetClaimVersion(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		token   jwt.Token
+		version int
+		exp     any
+		expErr  string
+	}{
+		{
+			name:   "nil_token",
+			token:  nil,
+			expErr: "token cannot be nil",
+		},
+		{
+			name:    "sets_version",
+			token:   testTokenBuilder(t, jwt.NewBuilder()),
+			version: ClaimVersion1,
+			exp:     ClaimVersion1,
+		},
+		{
+			name:    "legacy_removes_version",
+			token:   testTokenBuilder(t, jwt.NewBuilder().Claim(VersionKey, 1)),
+			version: ClaimVersionLegacy,
+			exp:     nil,
+		},
+		{
+			name:    "unsupported_version",
+			token:   testTokenBuilder(t, jwt.NewBuilder()),
+			version: LatestClaimVersion + 1,
+			expErr:  "claim version must be between 0 and 1, got 2",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := SetClaimVersion(tc.token, tc.version)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			got, _ := tc.token.Get(VersionKey)
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("version: diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestSetJustifications(t *testing.T) {
 	t.Parallel()
 
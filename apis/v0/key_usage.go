@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"golang.org/x/oauth2"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// usageReportTimeout is how long reporting a single key usage may take.
+const usageReportTimeout = 5 * time.Second
+
+// MinUsageReportSampleRate is the smallest sample rate of key usage reports.
+// Each report estimates 1/SampleRate verifications, so the rate is bounded to
+// keep the estimates from overflowing.
+const MinUsageReportSampleRate = 1e-6
+
+// KeyUsageReport is a sampled report that a verifier verified a token with a
+// key version.
+type KeyUsageReport struct {
+	// KeyID is the "kid" of the key version the token was verified with.
+	KeyID string `json:"kid"`
+
+	// SampleRate is the fraction of verifications the verifier reports, used to
+	// estimate the total number of verifications. It must be between
+	// [MinUsageReportSampleRate] and 1.
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// usageReporter reports a sample of verified tokens' key IDs.
+type usageReporter struct {
+	client *http.Client
+
+	// tokens are the ID tokens authenticating the reports.
+	tokens oauth2.TokenSource
+
+	endpoint   string
+	sampleRate float64
+}
+
+// maybeReport reports the key ID of the verified token, if it is sampled. The
+// report is sent in the background and failures are only logged, so reporting
+// never affects verification.
+func (r *usageReporter) maybeReport(ctx context.Context, token []byte) {
+	if r == nil || rand.Float64() >= r.sampleRate { //nolint:gosec // Sampling doesn't need to be secure.
+		return
+	}
+
+	msg, err := jws.Parse(token)
+	if err != nil || len(msg.Signatures()) == 0 {
+		return
+	}
+	kid := msg.Signatures()[0].ProtectedHeaders().KeyID()
+	if kid == "" {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := r.report(ctx, kid); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to report key usage",
+				"kid", kid,
+				"error", err)
+		}
+	}()
+}
+
+func (r *usageReporter) report(ctx context.Context, kid string) error {
+	ctx, cancel := context.WithTimeout(ctx, usageReportTimeout)
+	defer cancel()
+
+	b, err := json.Marshal(&KeyUsageReport{
+		KeyID:      kid,
+		SampleRate: r.sampleRate,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	token, err := r.tokens.Token()
+	if err != nil {
+		return fmt.Errorf("failed to get id token: %w", err)
+	}
+	token.SetAuthHeader(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
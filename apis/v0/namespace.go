@@ -0,0 +1,130 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// DefaultTokenType is the "typ" header of tokens, unless the JVS is configured
+// with another token type.
+const DefaultTokenType string = "JWT"
+
+// NamespacedClaims are the claims the JVS moves under its claims namespace, if
+// one is configured. Registered claims, such as "sub" and "cnf", never are.
+var NamespacedClaims = []string{
+	AuditRouteKey,
+	EncryptedJustificationsKey,
+	GroupsKey,
+	JustificationsKey,
+	RequestorKey,
+	VersionKey,
+}
+
+var (
+	// tokenTypeRegexp matches media type names per RFC 6838, with or without
+	// the "application/" prefix, as allowed in the "typ" header.
+	tokenTypeRegexp = regexp.MustCompile(`^(?i:application/)?[A-Za-z0-9][A-Za-z0-9!#$&^_.+-]{0,126}$`)
+
+	// claimsNamespaceRegexp matches the prefixes of namespaced claims, e.g.
+	// "jvs_" or "https://jvs.example.com/".
+	claimsNamespaceRegexp = regexp.MustCompile(`^[A-Za-z0-9._~:/+-]{1,128}$`)
+)
+
+// ValidateTokenType returns an error if the token type can't be the "typ"
+// header of tokens, such as "jvs+jwt". The types of the other objects signed
+// by the JVS keys, such as receipts, are rejected, so they can't be mistaken
+// for tokens.
+func ValidateTokenType(typ string) error {
+	if !tokenTypeRegexp.MatchString(typ) {
+		return fmt.Errorf("token type %q must be a media type, e.g. \"jvs+jwt\"", typ)
+	}
+	for _, other := range []string{DPoPProofType, PolicyAttestationType, ReceiptType, SignatureType} {
+		if TokenTypesEqual(typ, other) {
+			return fmt.Errorf("token type %q is the type of another jvs object", typ)
+		}
+	}
+	return nil
+}
+
+// TokenTypesEqual reports whether the "typ" headers are the same media type.
+// Media types are case-insensitive, and the "application/" prefix may be
+// omitted, per RFC 7515.
+func TokenTypesEqual(a, b string) bool {
+	return normalizeTokenType(a) == normalizeTokenType(b)
+}
+
+func normalizeTokenType(typ string) string {
+	typ = strings.ToLower(typ)
+	return strings.TrimPrefix(typ, "application/")
+}
+
+// ValidateClaimsNamespace returns an error if the namespace can't prefix
+// claims names. It must be non-empty, and only have letters, digits and
+// "._~:/+-", e.g. "jvs_" or "https://jvs.example.com/".
+func ValidateClaimsNamespace(namespace string) error {
+	if !claimsNamespaceRegexp.MatchString(namespace) {
+		return fmt.Errorf("claims namespace %q must be up to 128 letters, digits or \"._~:/+-\"", namespace)
+	}
+	return nil
+}
+
+// NamespaceClaims moves the [NamespacedClaims] of the token under the
+// namespace, e.g. "justs" to "https://jvs.example.com/justs". The namespace
+// is not validated.
+func NamespaceClaims(t jwt.Token, namespace string) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+	return moveClaims(t, func(name string) (string, string) {
+		return name, namespace + name
+	})
+}
+
+// UnnamespaceClaims moves the [NamespacedClaims] of the token out of the
+// namespace, so they are read by functions like [GetJustifications]. Claims
+// in the namespace replace claims of the same name outside of it. Tokens
+// without namespaced claims are unchanged.
+func UnnamespaceClaims(t jwt.Token, namespace string) error {
+	if t == nil {
+		return fmt.Errorf("token cannot be nil")
+	}
+	return moveClaims(t, func(name string) (string, string) {
+		return namespace + name, name
+	})
+}
+
+// moveClaims moves each of the [NamespacedClaims] from the first name
+// returned by names to the second one.
+func moveClaims(t jwt.Token, names func(name string) (from, to string)) error {
+	for _, name := range NamespacedClaims {
+		from, to := names(name)
+		v, ok := t.Get(from)
+		if !ok {
+			continue
+		}
+		if err := t.Set(to, v); err != nil {
+			return fmt.Errorf("failed to set %s claim: %w", to, err)
+		}
+		if err := t.Remove(from); err != nil {
+			return fmt.Errorf("failed to remove %s claim: %w", from, err)
+		}
+	}
+	return nil
+}
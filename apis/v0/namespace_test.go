@@ -0,0 +1,131 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestValidateTokenType(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		typ     string
+		wantErr string
+	}{
+		{typ: "jvs+jwt"},
+		{typ: "application/jvs+jwt"},
+		{typ: "at+jwt"},
+		{typ: "", wantErr: "must be a media type"},
+		{typ: "jvs jwt", wantErr: "must be a media type"},
+		{typ: "text/jvs+jwt", wantErr: "must be a media type"},
+		{typ: "JVS-RECEIPT+JSON", wantErr: "is the type of another jvs object"},
+		{typ: "application/dpop+jwt", wantErr: "is the type of another jvs object"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.typ, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateTokenType(tc.typ)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestValidateClaimsNamespace(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		namespace string
+		wantErr   string
+	}{
+		{namespace: "jvs_"},
+		{namespace: "https://jvs.example.com/"},
+		{namespace: "", wantErr: "must be up to 128 letters"},
+		{namespace: "jvs ", wantErr: "must be up to 128 letters"},
+		{namespace: `jvs"`, wantErr: "must be up to 128 letters"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.namespace, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateClaimsNamespace(tc.namespace)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestNamespaceClaims(t *testing.T) {
+	t.Parallel()
+
+	token, err := jwt.NewBuilder().
+		Subject("me@example.com").
+		Claim(JustificationsKey, []any{"debugging"}).
+		Claim(RequestorKey, "me@example.com").
+		Claim(VersionKey, 1).
+		Claim("cnf", map[string]any{"jkt": "thumbprint"}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NamespaceClaims(token, "jvs_"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := token.AsMap(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"sub":         "me@example.com",
+		"cnf":         map[string]any{"jkt": "thumbprint"},
+		"jvs_justs":   []any{"debugging"},
+		"jvs_req":     "me@example.com",
+		"jvs_jvs_ver": 1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("namespaced claims (-want, +got):\n%s", diff)
+	}
+
+	if err := UnnamespaceClaims(token, "jvs_"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = token.AsMap(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]any{
+		"sub":             "me@example.com",
+		"cnf":             map[string]any{"jkt": "thumbprint"},
+		JustificationsKey: []any{"debugging"},
+		RequestorKey:      "me@example.com",
+		VersionKey:        1,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("claims (-want, +got):\n%s", diff)
+	}
+}
@@ -111,6 +111,12 @@ type PluginClient struct {
 	client JVSPluginClient
 }
 
+// NewPluginClient creates a [Validator] for a plugin served on the connection,
+// e.g. a remote plugin that serves [PluginServer] over gRPC.
+func NewPluginClient(cc grpc.ClientConnInterface) *PluginClient {
+	return &PluginClient{client: NewJVSPluginClient(cc)}
+}
+
 func (m *PluginClient) Validate(ctx context.Context, req *ValidateJustificationRequest) (*ValidateJustificationResponse, error) {
 	resp, err := m.client.Validate(ctx, req)
 	if err != nil {
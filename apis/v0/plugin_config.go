@@ -0,0 +1,107 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// PluginConfigKeyEnv is the environment variable the host sets to the KMS
+	// key that decrypts the sealed config of a plugin.
+	PluginConfigKeyEnv = "JVS_PLUGIN_CONFIG_KEY"
+
+	// PluginSealedConfigEnv is the environment variable the host sets to the
+	// sealed config of a plugin, the base64 encoded KMS ciphertext of a JSON
+	// object of strings, e.g. {"JVS_SERVICENOW_PASSWORD": "..."}.
+	PluginSealedConfigEnv = "JVS_PLUGIN_SEALED_CONFIG"
+)
+
+// decryptFunc decrypts with KMS, e.g. [kms.KeyManagementClient.Decrypt].
+type decryptFunc func(context.Context, *kmspb.DecryptRequest) (*kmspb.DecryptResponse, error)
+
+// LoadPluginConfig decrypts the sealed config the host passed to the plugin
+// and returns its values, keyed by the environment variables they set. It
+// returns nil if the host didn't pass a sealed config. Plugins call it before
+// parsing their flags, so that secrets such as API tokens are only decrypted
+// inside the plugin, and never appear in its environment or the host's:
+//
+//	values, err := jvspb.LoadPluginConfig(ctx)
+//	set := cfg.ToFlags(cli.NewFlagSet(cli.WithLookupEnv(
+//		cli.MultiLookuper(cli.MapLookuper(values), os.LookupEnv))))
+//
+// The plugin's credentials need "cloudkms.cryptoKeyVersions.useToDecrypt" on
+// the key.
+func LoadPluginConfig(ctx context.Context, opts ...option.ClientOption) (map[string]string, error) {
+	if _, ok := os.LookupEnv(PluginSealedConfigEnv); !ok {
+		return nil, nil
+	}
+
+	client, err := kms.NewKeyManagementClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kms client: %w", err)
+	}
+	defer client.Close()
+
+	return openPluginConfig(ctx, func(ctx context.Context, req *kmspb.DecryptRequest) (*kmspb.DecryptResponse, error) {
+		return client.Decrypt(ctx, req)
+	}, os.LookupEnv)
+}
+
+// openPluginConfig decrypts the sealed config in the environment of lookup.
+func openPluginConfig(ctx context.Context, decrypt decryptFunc, lookup func(string) (string, bool)) (map[string]string, error) {
+	sealed, ok := lookup(PluginSealedConfigEnv)
+	if !ok {
+		return nil, nil
+	}
+	key, _ := lookup(PluginConfigKeyEnv)
+	if key == "" {
+		return nil, fmt.Errorf("%s is set, but %s is empty", PluginSealedConfigEnv, PluginConfigKeyEnv)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", PluginSealedConfigEnv, err)
+	}
+
+	table := crc32.MakeTable(crc32.Castagnoli)
+	resp, err := decrypt(ctx, &kmspb.DecryptRequest{
+		Name:             key,
+		Ciphertext:       ciphertext,
+		CiphertextCrc32C: wrapperspb.Int64(int64(crc32.Checksum(ciphertext, table))),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt plugin config: %w", err)
+	}
+	if crc := resp.GetPlaintextCrc32C(); crc != nil && crc.GetValue() != int64(crc32.Checksum(resp.GetPlaintext(), table)) {
+		return nil, fmt.Errorf("plugin config was corrupted in transit")
+	}
+
+	var values map[string]string
+	if err := json.Unmarshal(resp.GetPlaintext(), &values); err != nil {
+		return nil, fmt.Errorf("plugin config must be a JSON object of strings: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestOpenPluginConfig(t *testing.T) {
+	t.Parallel()
+
+	const key = "projects/p/locations/global/keyRings/r/cryptoKeys/plugins"
+	sealed := base64.StdEncoding.EncodeToString([]byte("ciphertext"))
+
+	// decrypter returns plaintext for the ciphertext "ciphertext" and the key.
+	decrypter := func(plaintext string, crc int64) decryptFunc {
+		return func(ctx context.Context, req *kmspb.DecryptRequest) (*kmspb.DecryptResponse, error) {
+			if got, want := req.GetName(), key; got != want {
+				return nil, fmt.Errorf("wrong key %q", got)
+			}
+			if got, want := string(req.GetCiphertext()), "ciphertext"; got != want {
+				return nil, fmt.Errorf("wrong ciphertext %q", got)
+			}
+			if got, want := req.GetCiphertextCrc32C().GetValue(), int64(crc32.Checksum(req.GetCiphertext(), crc32.MakeTable(crc32.Castagnoli))); got != want {
+				return nil, fmt.Errorf("wrong ciphertext crc %d", got)
+			}
+			return &kmspb.DecryptResponse{
+				Plaintext:       []byte(plaintext),
+				PlaintextCrc32C: wrapperspb.Int64(crc),
+			}, nil
+		}
+	}
+	crc := func(s string) int64 {
+		return int64(crc32.Checksum([]byte(s), crc32.MakeTable(crc32.Castagnoli)))
+	}
+
+	cases := []struct {
+		name    string
+		env     map[string]string
+		decrypt decryptFunc
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "success",
+			env: map[string]string{
+				PluginConfigKeyEnv:    key,
+				PluginSealedConfigEnv: sealed,
+			},
+			decrypt: decrypter(`{"JVS_SERVICENOW_PASSWORD":"hunter2"}`, crc(`{"JVS_SERVICENOW_PASSWORD":"hunter2"}`)),
+			want:    map[string]string{"JVS_SERVICENOW_PASSWORD": "hunter2"},
+		},
+		{
+			name: "no_sealed_config",
+			env: map[string]string{
+				PluginConfigKeyEnv: key,
+			},
+		},
+		{
+			name: "missing_key",
+			env: map[string]string{
+				PluginSealedConfigEnv: sealed,
+			},
+			wantErr: "JVS_PLUGIN_SEALED_CONFIG is set, but JVS_PLUGIN_CONFIG_KEY is empty",
+		},
+		{
+			name: "invalid_base64",
+			env: map[string]string{
+				PluginConfigKeyEnv:    key,
+				PluginSealedConfigEnv: "!",
+			},
+			wantErr: "failed to decode JVS_PLUGIN_SEALED_CONFIG",
+		},
+		{
+			name: "decrypt_error",
+			env: map[string]string{
+				PluginConfigKeyEnv:    key,
+				PluginSealedConfigEnv: sealed,
+			},
+			decrypt: func(ctx context.Context, req *kmspb.DecryptRequest) (*kmspb.DecryptResponse, error) {
+				return nil, fmt.Errorf("permission denied")
+			},
+			wantErr: "failed to decrypt plugin config: permission denied",
+		},
+		{
+			name: "corrupted",
+			env: map[string]string{
+				PluginConfigKeyEnv:    key,
+				PluginSealedConfigEnv: sealed,
+			},
+			decrypt: decrypter(`{}`, 1),
+			wantErr: "corrupted in transit",
+		},
+		{
+			name: "not_strings",
+			env: map[string]string{
+				PluginConfigKeyEnv:    key,
+				PluginSealedConfigEnv: sealed,
+			},
+			decrypt: decrypter(`{"JVS_SERVICENOW_TIMEOUT":10}`, crc(`{"JVS_SERVICENOW_TIMEOUT":10}`)),
+			wantErr: "plugin config must be a JSON object of strings",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lookup := func(k string) (string, bool) {
+				v, ok := tc.env[k]
+				return v, ok
+			}
+			got, err := openPluginConfig(context.Background(), tc.decrypt, lookup)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("values (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
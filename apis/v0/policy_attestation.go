@@ -0,0 +1,142 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// PolicyAttestationType is the "typ" header of signed policy attestations.
+const PolicyAttestationType string = "jvs-policy+json"
+
+// PolicyAttestation is the issuance policy the JVS enforced when it was
+// signed, so auditors can verify which policy was in force at a given time.
+type PolicyAttestation struct {
+	// Issuer is the "iss" of the tokens the policy applies to.
+	Issuer string `json:"iss"`
+
+	// Version is the hex-encoded SHA-256 digest of the policy, see
+	// [PolicyAttestation.Digest]. It only changes when the policy does.
+	Version string `json:"version"`
+
+	// IssuedAt is when the attestation was signed.
+	IssuedAt time.Time `json:"issued_at"`
+
+	// DefaultTTL and MaxTTL are the TTL of tokens that don't request one, and
+	// the maximum TTL of all tokens, as Go durations, e.g. "15m0s".
+	DefaultTTL string `json:"default_ttl"`
+	MaxTTL     string `json:"max_ttl"`
+
+	// Categories are the accepted justification categories, sorted by name.
+	Categories []*PolicyCategory `json:"categories"`
+
+	// DisabledCategories are the categories disabled with the admin API.
+	DisabledCategories []string `json:"disabled_categories,omitempty"`
+
+	// DefaultAudiences are the audiences of tokens that don't request any.
+	DefaultAudiences []string `json:"default_audiences"`
+
+	// AudienceTemplate is the template audience services are expanded with,
+	// and AudienceTemplateRequired whether audiences must be given as audience
+	// services.
+	AudienceTemplate         string `json:"audience_template,omitempty"`
+	AudienceTemplateRequired bool   `json:"audience_template_required,omitempty"`
+
+	// AudienceAllowlist are the audiences tokens may be issued for. Any
+	// audience is allowed if it is empty.
+	AudienceAllowlist []string `json:"audience_allowlist,omitempty"`
+}
+
+// PolicyCategory is an accepted justification category in a policy
+// attestation.
+type PolicyCategory struct {
+	Name string `json:"name"`
+
+	// MaxTTL is the maximum TTL of tokens with justifications of the category,
+	// as a Go duration.
+	MaxTTL string `json:"max_ttl"`
+
+	ValuePattern      string   `json:"value_pattern,omitempty"`
+	DeprecatedAliases []string `json:"deprecated_aliases,omitempty"`
+
+	// Shadow indicates the category is validated in shadow mode, so failed
+	// validations don't block issuance.
+	Shadow bool `json:"shadow,omitempty"`
+}
+
+// Digest returns the SHA-256 digest of the JSON encoding of the policy,
+// without its version and issue time, so attestations of the same policy have
+// the same digest.
+func (a *PolicyAttestation) Digest() ([]byte, error) {
+	c := *a
+	c.Version = ""
+	c.IssuedAt = time.Time{}
+
+	b, err := json.Marshal(&c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy attestation: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// VerifyPolicyAttestation verifies the signed policy attestation against the
+// given keys, typically the JVS public keys, and returns its contents. It
+// checks the version matches the digest of the policy.
+func VerifyPolicyAttestation(attestation string, keys jwk.Set) (*PolicyAttestation, error) {
+	payload, err := jws.Verify([]byte(attestation),
+		jws.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify policy attestation: %w", err)
+	}
+
+	msg, err := jws.Parse([]byte(attestation))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy attestation: %w", err)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, fmt.Errorf("expected 1 signature, got %d", len(sigs))
+	}
+	if got, want := sigs[0].ProtectedHeaders().Type(), PolicyAttestationType; got != want {
+		return nil, fmt.Errorf("expected policy attestation type %q, got %q", want, got)
+	}
+
+	var out PolicyAttestation
+	if err := json.Unmarshal(payload, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse policy attestation payload: %w", err)
+	}
+	digest, err := out.Digest()
+	if err != nil {
+		return nil, err
+	}
+	if got, want := out.Version, hex.EncodeToString(digest); got != want {
+		return nil, fmt.Errorf("expected policy version %q to be %q", got, want)
+	}
+	return &out, nil
+}
+
+// VerifyPolicyAttestation verifies the signed policy attestation against the
+// keys in the JWKs endpoint.
+func (j *Client) VerifyPolicyAttestation(attestation string) (*PolicyAttestation, error) {
+	return VerifyPolicyAttestation(attestation, j.keys)
+}
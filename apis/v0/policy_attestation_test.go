@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVerifyPolicyAttestation(t *testing.T) {
+	t.Parallel()
+
+	signingKey := testEncryptionKey(t, "ec")
+	otherKey := testEncryptionKey(t, "ec")
+
+	policy := &PolicyAttestation{
+		Issuer:     "jvs.abcxyz.dev",
+		IssuedAt:   time.Unix(1700000000, 0).UTC(),
+		DefaultTTL: "15m0s",
+		MaxTTL:     "4h0m0s",
+		Categories: []*PolicyCategory{
+			{Name: "explanation", MaxTTL: "1h0m0s"},
+		},
+		DefaultAudiences: []string{"dev.abcxyz.jvs"},
+	}
+	digest, err := policy.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Version = hex.EncodeToString(digest)
+
+	sign := func(tb testing.TB, typ string, p *PolicyAttestation) string {
+		tb.Helper()
+
+		payload, err := json.Marshal(p)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		headers := jws.NewHeaders()
+		if err := headers.Set(jws.KeyIDKey, signingKey.KeyID()); err != nil {
+			tb.Fatal(err)
+		}
+		if err := headers.Set(jws.TypeKey, typ); err != nil {
+			tb.Fatal(err)
+		}
+
+		b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, signingKey, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return string(b)
+	}
+
+	wrongVersion := *policy
+	wrongVersion.MaxTTL = "24h0m0s"
+
+	cases := []struct {
+		name        string
+		attestation string
+		keys        []jwk.Key
+		exp         *PolicyAttestation
+		expErr      string
+	}{
+		{
+			name:        "valid",
+			attestation: sign(t, PolicyAttestationType, policy),
+			keys:        []jwk.Key{signingKey},
+			exp:         policy,
+		},
+		{
+			name:        "unknown_key",
+			attestation: sign(t, PolicyAttestationType, policy),
+			keys:        []jwk.Key{otherKey},
+			expErr:      "failed to verify policy attestation",
+		},
+		{
+			name:        "wrong_type",
+			attestation: sign(t, ReceiptType, policy),
+			keys:        []jwk.Key{signingKey},
+			expErr:      `expected policy attestation type "jvs-policy+json", got "jvs-receipt+json"`,
+		},
+		{
+			name:        "wrong_version",
+			attestation: sign(t, PolicyAttestationType, &wrongVersion),
+			keys:        []jwk.Key{signingKey},
+			expErr:      "expected policy version",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := VerifyPolicyAttestation(tc.attestation, testPublicSet(t, tc.keys...))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("policy attestation (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPolicyAttestation_Digest(t *testing.T) {
+	t.Parallel()
+
+	a := &PolicyAttestation{Issuer: "jvs.abcxyz.dev", MaxTTL: "4h0m0s"}
+	b := *a
+	b.Version = "v1"
+	b.IssuedAt = time.Now()
+
+	da, err := a.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := b.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(da) != string(db) {
+		t.Errorf("expected the version and issue time not to change the digest")
+	}
+
+	b.MaxTTL = "1h0m0s"
+	if db, err = b.Digest(); err != nil {
+		t.Fatal(err)
+	}
+	if string(da) == string(db) {
+		t.Errorf("expected the max ttl to change the digest")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TokenHeader is the HTTP header and gRPC metadata key that carries
+// justification tokens between services.
+const TokenHeader string = "justification-token"
+
+// tokenContextKey is the context key of the justification token.
+type tokenContextKey struct{}
+
+// WithJustificationToken returns a copy of the context that carries the
+// justification token. The interceptors and transport in this package send it
+// along with outgoing requests made with the context.
+func WithJustificationToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// JustificationTokenFromContext returns the justification token the context
+// carries, or the empty string if there is none. On servers, the token is put
+// in the context by [TokenUnaryServerInterceptor],
+// [TokenStreamServerInterceptor] or [TokenMiddleware]. The token is not
+// validated, use [Client.ValidateJWT] before trusting it.
+func JustificationTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenContextKey{}).(string)
+	return token
+}
+
+// TokenUnaryClientInterceptor sends the justification token of the context, if
+// any, in the outgoing metadata of unary calls.
+func TokenUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(outgoingTokenContext(ctx), method, req, reply, cc, opts...)
+}
+
+// TokenStreamClientInterceptor sends the justification token of the context,
+// if any, in the outgoing metadata of streams.
+func TokenStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(outgoingTokenContext(ctx), desc, cc, method, opts...)
+}
+
+// outgoingTokenContext sets the justification token of the context in its
+// outgoing metadata, replacing any token already set.
+func outgoingTokenContext(ctx context.Context) context.Context {
+	token := JustificationTokenFromContext(ctx)
+	if token == "" {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(TokenHeader, token)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// TokenUnaryServerInterceptor puts the justification token from the incoming
+// metadata, if any, in the context of unary handlers.
+func TokenUnaryServerInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(incomingTokenContext(ctx), req)
+}
+
+// TokenStreamServerInterceptor puts the justification token from the incoming
+// metadata, if any, in the context of stream handlers.
+func TokenStreamServerInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &tokenServerStream{
+		ServerStream: ss,
+		ctx:          incomingTokenContext(ss.Context()),
+	})
+}
+
+// tokenServerStream is a server stream with the justification token in its
+// context.
+type tokenServerStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint:containedctx // Overrides the stream context.
+}
+
+func (s *tokenServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// incomingTokenContext puts the justification token from the incoming metadata
+// in the context.
+func incomingTokenContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	vals := md.Get(TokenHeader)
+	if len(vals) == 0 {
+		return ctx
+	}
+	token := strings.TrimSpace(vals[0])
+	if token == "" {
+		return ctx
+	}
+	return WithJustificationToken(ctx, token)
+}
+
+// NewTokenTransport returns a transport that sends the justification token of
+// the request context, if any, in the [TokenHeader] of requests. If base is
+// nil, [http.DefaultTransport] is used.
+func NewTokenTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tokenTransport{base: base}
+}
+
+type tokenTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	token := JustificationTokenFromContext(r.Context())
+	if token == "" {
+		return t.base.RoundTrip(r)
+	}
+	// Round trippers must not modify the request.
+	r = r.Clone(r.Context())
+	r.Header.Set(TokenHeader, token)
+	return t.base.RoundTrip(r)
+}
+
+// TokenMiddleware puts the justification token from the [TokenHeader] of
+// requests, if any, in the request context.
+func TokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := strings.TrimSpace(r.Header.Get(TokenHeader)); token != "" {
+			r = r.WithContext(WithJustificationToken(r.Context(), token))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTokenUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{
+			name: "no_token",
+			ctx:  context.Background(),
+		},
+		{
+			name: "token",
+			ctx:  WithJustificationToken(context.Background(), "test-token"),
+			want: []string{"test-token"},
+		},
+		{
+			name: "replaces_outgoing_token",
+			ctx: WithJustificationToken(
+				metadata.AppendToOutgoingContext(context.Background(), TokenHeader, "old-token"),
+				"test-token"),
+			want: []string{"test-token"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				got = md.Get(TokenHeader)
+				return nil
+			}
+			if err := TokenUnaryClientInterceptor(tc.ctx, "/test.Service/Method", nil, nil, nil, invoker); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) || (len(got) > 0 && got[0] != tc.want[0]) {
+				t.Errorf("expected outgoing tokens %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{
+			name: "no_metadata",
+		},
+		{
+			name: "no_token",
+			md:   metadata.Pairs("authorization", "Bearer abc"),
+		},
+		{
+			name: "token",
+			md:   metadata.Pairs(TokenHeader, " test-token "),
+			want: "test-token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tc.md)
+			}
+
+			var got string
+			handler := func(ctx context.Context, _ any) (any, error) {
+				got = JustificationTokenFromContext(ctx)
+				return nil, nil
+			}
+			if _, err := TokenUnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("expected token %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenTransport(t *testing.T) {
+	t.Parallel()
+
+	// The server side of the round trip: the middleware puts the header in the
+	// request context.
+	srv := httptest.NewServer(TokenMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(JustificationTokenFromContext(r.Context())))
+	})))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: NewTokenTransport(srv.Client().Transport)}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "no_token",
+			ctx:  context.Background(),
+		},
+		{
+			name: "token",
+			ctx:  WithJustificationToken(context.Background(), "test-token"),
+			want: "test-token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(tc.ctx, http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tc.want {
+				t.Errorf("expected server token %q to be %q", got, tc.want)
+			}
+			if got := req.Header.Get(TokenHeader); got != "" {
+				t.Errorf("expected the request not to be modified, got header %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// ReceiptType is the "typ" header of signed receipts.
+const ReceiptType string = "jvs-receipt+json"
+
+// Receipt is a record of an issued token, signed by the same key as the token,
+// that can be attached to change tickets. Unlike the token, it doesn't grant
+// access, and it doesn't contain the justification values.
+type Receipt struct {
+	// ID is the token's "jti".
+	ID string `json:"jti"`
+
+	// Requestor is the principal that requested the token, and Subject is the
+	// token's "sub".
+	Requestor string `json:"requestor"`
+	Subject   string `json:"subject,omitempty"`
+
+	// Justifications are the categories and validator annotations of the
+	// token's justifications.
+	Justifications []*ReceiptJustification `json:"justifications"`
+
+	// Approver is the principal that approved the request, if one of the
+	// justifications was an approval.
+	Approver string `json:"approver,omitempty"`
+
+	// IssuedAt and ExpiresAt are the token's "iat" and "exp".
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Redacted indicates the annotations were removed, because the
+	// justifications were encrypted in the token.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// ReceiptJustification is a justification in a receipt.
+type ReceiptJustification struct {
+	Category   string            `json:"category"`
+	Annotation map[string]string `json:"annotation,omitempty"`
+}
+
+// VerifyReceipt verifies the signed receipt against the given keys, typically
+// the JVS public keys, and returns its contents.
+func VerifyReceipt(receipt string, keys jwk.Set) (*Receipt, error) {
+	payload, err := jws.Verify([]byte(receipt),
+		jws.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify receipt: %w", err)
+	}
+
+	msg, err := jws.Parse([]byte(receipt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse receipt: %w", err)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, fmt.Errorf("expected 1 signature, got %d", len(sigs))
+	}
+	if got, want := sigs[0].ProtectedHeaders().Type(), ReceiptType; got != want {
+		return nil, fmt.Errorf("expected receipt type %q, got %q", want, got)
+	}
+
+	var out Receipt
+	if err := json.Unmarshal(payload, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse receipt payload: %w", err)
+	}
+	return &out, nil
+}
+
+// VerifyReceipt verifies the signed receipt against the keys in the JWKs
+// endpoint.
+func (j *Client) VerifyReceipt(receipt string) (*Receipt, error) {
+	return VerifyReceipt(receipt, j.keys)
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVerifyReceipt(t *testing.T) {
+	t.Parallel()
+
+	signingKey := testEncryptionKey(t, "ec")
+	otherKey := testEncryptionKey(t, "ec")
+
+	receipt := &Receipt{
+		ID:        "test-jti",
+		Requestor: "me@example.com",
+		Justifications: []*ReceiptJustification{
+			{Category: "approver", Annotation: map[string]string{"approver": "boss@example.com"}},
+		},
+		Approver:  "boss@example.com",
+		IssuedAt:  time.Unix(1700000000, 0).UTC(),
+		ExpiresAt: time.Unix(1700000900, 0).UTC(),
+	}
+
+	sign := func(tb testing.TB, typ string) string {
+		tb.Helper()
+
+		payload, err := json.Marshal(receipt)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		headers := jws.NewHeaders()
+		if err := headers.Set(jws.KeyIDKey, signingKey.KeyID()); err != nil {
+			tb.Fatal(err)
+		}
+		if err := headers.Set(jws.TypeKey, typ); err != nil {
+			tb.Fatal(err)
+		}
+
+		b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, signingKey, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return string(b)
+	}
+
+	cases := []struct {
+		name    string
+		receipt string
+		keys    []jwk.Key
+		exp     *Receipt
+		expErr  string
+	}{
+		{
+			name:    "valid",
+			receipt: sign(t, ReceiptType),
+			keys:    []jwk.Key{signingKey},
+			exp:     receipt,
+		},
+		{
+			name:    "unknown_key",
+			receipt: sign(t, ReceiptType),
+			keys:    []jwk.Key{otherKey},
+			expErr:  "failed to verify receipt",
+		},
+		{
+			name:    "wrong_type",
+			receipt: sign(t, "JWT"),
+			keys:    []jwk.Key{signingKey},
+			expErr:  `expected receipt type "jvs-receipt+json", got "JWT"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := VerifyReceipt(tc.receipt, testPublicSet(t, tc.keys...))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("receipt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// revocationFetchTimeout is how long fetching the revocation list may take.
+const revocationFetchTimeout = 5 * time.Second
+
+// Revocation is an entry of the revocation list served by the JVS public key
+// server. Tokens of the principal issued at or before RevokedAt are revoked.
+type Revocation struct {
+	// PrincipalSHA256 is the hex encoded SHA-256 digest of the principal's
+	// email, lowercased, see [RevocationPrincipalDigest]. Verifiers only need
+	// to match it, so the list doesn't name who was deprovisioned.
+	PrincipalSHA256 string `json:"principal_sha256"`
+
+	// RevokedAt is the time the principal was revoked.
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// RevocationList is the document served by the revocation endpoint.
+type RevocationList struct {
+	Revocations []*Revocation `json:"revocations"`
+}
+
+// RevocationPrincipalDigest returns the digest a principal is listed under in a
+// [RevocationList].
+func RevocationPrincipalDigest(principal string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(principal))))
+	return hex.EncodeToString(sum[:])
+}
+
+// revocationChecker checks tokens against the revocation list of a JVS,
+// fetched again when it is older than the refresh interval.
+type revocationChecker struct {
+	client   *http.Client
+	endpoint string
+	interval time.Duration
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+	revoked   map[string]time.Time
+}
+
+// newRevocationChecker returns a checker for the endpoint, after checking the
+// list can be fetched.
+func newRevocationChecker(ctx context.Context, endpoint string, interval time.Duration) (*revocationChecker, error) {
+	c := &revocationChecker{
+		client:   &http.Client{Timeout: revocationFetchTimeout},
+		endpoint: endpoint,
+		interval: interval,
+	}
+	if err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// check returns an error if the subject or requestor of the token was revoked
+// at or after the token was issued. If the list can't be refreshed, the last
+// fetched list is used.
+func (c *revocationChecker) check(ctx context.Context, token jwt.Token) error {
+	if c == nil {
+		return nil
+	}
+
+	// Only one caller refreshes a stale list, the others use it meanwhile.
+	c.mu.Lock()
+	stale := time.Since(c.fetchedAt) >= c.interval
+	if stale {
+		c.fetchedAt = time.Now()
+	}
+	c.mu.Unlock()
+	if stale {
+		_ = c.refresh(ctx)
+	}
+
+	principals := []string{token.Subject()}
+	if requestor, err := GetRequestor(token); err == nil && requestor != "" {
+		principals = append(principals, requestor)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, principal := range principals {
+		if principal == "" {
+			continue
+		}
+		revokedAt, ok := c.revoked[RevocationPrincipalDigest(principal)]
+		if ok && !token.IssuedAt().After(revokedAt) {
+			return fmt.Errorf("token of %q was revoked at %s", principal, revokedAt.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// refresh fetches the revocation list.
+func (c *revocationChecker) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build revocation list request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch revocation list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to fetch revocation list: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var list RevocationList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return fmt.Errorf("failed to parse revocation list: %w", err)
+	}
+
+	revoked := make(map[string]time.Time, len(list.Revocations))
+	for _, r := range list.Revocations {
+		if r.RevokedAt.After(revoked[r.PrincipalSHA256]) {
+			revoked[r.PrincipalSHA256] = r.RevokedAt
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetchedAt = time.Now()
+	c.revoked = revoked
+	return nil
+}
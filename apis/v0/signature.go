@@ -0,0 +1,156 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"google.golang.org/grpc"
+)
+
+const (
+	// SignatureRequestorHeader is the protected header of a detached signature
+	// that holds the principal that requested it.
+	SignatureRequestorHeader string = "requestor"
+
+	// SignatureIssuedAtHeader is the protected header of a detached signature
+	// that holds the time it was created, in seconds since the Unix epoch.
+	SignatureIssuedAtHeader string = "iat"
+
+	// SignatureType is the "typ" header of detached signatures.
+	SignatureType string = "jvs-signature+jws"
+
+	// SignatureEnvelopePrefix is prepended to the payload digest to build the
+	// bytes a detached signature signs. Signatures use the unencoded payload
+	// option (RFC 7797), and the dots in the prefix mean the signing input can
+	// never be a valid compact JWS, so a signature can't be turned into a token.
+	SignatureEnvelopePrefix string = "jvs.signature.v1."
+)
+
+// Signature is a verified detached signature.
+type Signature struct {
+	// KeyID is the ID of the JVS key that created the signature.
+	KeyID string
+
+	// Requestor is the principal that requested the signature.
+	Requestor string
+
+	// IssuedAt is the time the signature was created.
+	IssuedAt time.Time
+}
+
+// PayloadDigest returns the SHA-256 digest of the payload, which is what
+// detached signatures sign.
+func PayloadDigest(payload io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, payload); err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	return h.Sum(nil), nil
+}
+
+// SignatureEnvelope returns the bytes a detached signature over the given
+// digest signs.
+func SignatureEnvelope(digest []byte) []byte {
+	out := make([]byte, 0, len(SignatureEnvelopePrefix)+len(digest))
+	out = append(out, SignatureEnvelopePrefix...)
+	return append(out, digest...)
+}
+
+// SignPayload creates a detached signature over the payload using the JVS. The
+// payload itself never leaves the caller, only its digest.
+func SignPayload(ctx context.Context, client JVSServiceClient, payload io.Reader, opts ...grpc.CallOption) (string, error) {
+	digest, err := PayloadDigest(payload)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.SignPayload(ctx, &SignPayloadRequest{
+		Sha256Digest: digest,
+	}, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign payload: %w", err)
+	}
+	return resp.GetSignature(), nil
+}
+
+// VerifyDetachedSignature verifies the detached signature over the payload
+// against the given keys, typically the JVS public keys.
+func VerifyDetachedSignature(signature string, payload io.Reader, keys jwk.Set) (*Signature, error) {
+	b := []byte(signature)
+	if !bytes.Contains(b, []byte("..")) {
+		return nil, fmt.Errorf("signature is not a detached jws")
+	}
+
+	digest, err := PayloadDigest(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := jws.Verify(b,
+		jws.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+		jws.WithDetachedPayload(SignatureEnvelope(digest)),
+	); err != nil {
+		return nil, fmt.Errorf("failed to verify signature: %w", err)
+	}
+
+	msg, err := jws.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signature: %w", err)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, fmt.Errorf("expected 1 signature, got %d", len(sigs))
+	}
+	headers := sigs[0].ProtectedHeaders()
+	if got, want := headers.Type(), SignatureType; got != want {
+		return nil, fmt.Errorf("signature type %q is not %q", got, want)
+	}
+	if v, ok := headers.Get("b64"); !ok || v != false {
+		return nil, fmt.Errorf("signature must have an unencoded payload")
+	}
+
+	out := &Signature{
+		KeyID: headers.KeyID(),
+	}
+	if v, ok := headers.Get(SignatureRequestorHeader); ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("found %s header, but was of unknown type %T", SignatureRequestorHeader, v)
+		}
+		out.Requestor = s
+	}
+	if v, ok := headers.Get(SignatureIssuedAtHeader); ok {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("found %s header, but was of unknown type %T", SignatureIssuedAtHeader, v)
+		}
+		out.IssuedAt = time.Unix(int64(f), 0).UTC()
+	}
+	return out, nil
+}
+
+// VerifyDetachedSignature verifies the detached signature over the payload
+// against the keys in the JWKs endpoint.
+func (j *Client) VerifyDetachedSignature(signature string, payload io.Reader) (*Signature, error) {
+	return VerifyDetachedSignature(signature, payload, j.keys)
+}
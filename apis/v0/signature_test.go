@@ -0,0 +1,164 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	t.Parallel()
+
+	signingKey := testEncryptionKey(t, "ec")
+	otherKey := testEncryptionKey(t, "ec")
+	payload := "apiVersion: apps/v1\nkind: Deployment\n"
+	issuedAt := time.Unix(1700000000, 0).UTC()
+
+	sign := func(tb testing.TB, p string, detached, legacy bool) string {
+		tb.Helper()
+
+		digest, err := PayloadDigest(strings.NewReader(p))
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		hdrs := map[string]any{
+			jws.KeyIDKey:             signingKey.KeyID(),
+			SignatureRequestorHeader: "me@example.com",
+			SignatureIssuedAtHeader:  issuedAt.Unix(),
+			jws.TypeKey:              SignatureType,
+		}
+		// Legacy signatures signed the bare digest, with an encoded payload.
+		if !legacy {
+			hdrs[jws.CriticalKey] = []string{"b64"}
+			hdrs["b64"] = false
+			digest = SignatureEnvelope(digest)
+		}
+		headers := jws.NewHeaders()
+		for k, v := range hdrs {
+			if err := headers.Set(k, v); err != nil {
+				tb.Fatal(err)
+			}
+		}
+
+		opts := []jws.SignOption{jws.WithKey(jwa.ES256, signingKey, jws.WithProtectedHeaders(headers))}
+		var in []byte
+		if detached {
+			opts = append(opts, jws.WithDetachedPayload(digest))
+		} else {
+			in = digest
+		}
+		b, err := jws.Sign(in, opts...)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return string(b)
+	}
+
+	cases := []struct {
+		name      string
+		signature string
+		payload   string
+		keys      []jwk.Key
+		exp       *Signature
+		expErr    string
+	}{
+		{
+			name:      "valid",
+			signature: sign(t, payload, true, false),
+			payload:   payload,
+			keys:      []jwk.Key{signingKey},
+			exp: &Signature{
+				KeyID:     signingKey.KeyID(),
+				Requestor: "me@example.com",
+				IssuedAt:  issuedAt,
+			},
+		},
+		{
+			name:      "different_payload",
+			signature: sign(t, payload, true, false),
+			payload:   payload + "replicas: 100\n",
+			keys:      []jwk.Key{signingKey},
+			expErr:    "failed to verify signature",
+		},
+		{
+			name:      "unknown_key",
+			signature: sign(t, payload, true, false),
+			payload:   payload,
+			keys:      []jwk.Key{otherKey},
+			expErr:    "failed to verify signature",
+		},
+		{
+			name:      "not_detached",
+			signature: sign(t, payload, false, true),
+			payload:   payload,
+			keys:      []jwk.Key{signingKey},
+			expErr:    "signature is not a detached jws",
+		},
+		{
+			name:      "bare_digest",
+			signature: sign(t, payload, true, true),
+			payload:   payload,
+			keys:      []jwk.Key{signingKey},
+			expErr:    "failed to verify signature",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := VerifyDetachedSignature(tc.signature, strings.NewReader(tc.payload), testPublicSet(t, tc.keys...))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.exp, got); diff != "" {
+				t.Errorf("signature (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSignatureEnvelope_notAToken(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Even ignoring its type, a signature over claims disguised as a digest
+	// doesn't verify as a compact JWS.
+	forged := testForgeTokenFromSignature(t, privateKey, "key")
+	if _, err := jws.Verify([]byte(forged), jws.WithKey(jwa.ES256, privateKey.Public())); err == nil {
+		t.Errorf("expected forged token %q to fail verification", forged)
+	}
+	if _, err := jwt.Parse([]byte(forged), jwt.WithKey(jwa.ES256, privateKey.Public())); err == nil {
+		t.Errorf("expected forged token %q to fail parsing", forged)
+	}
+}
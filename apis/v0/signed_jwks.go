@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+const (
+	// SignedJWKSType is the "typ" header of signed key sets.
+	SignedJWKSType string = "jwk-set+jwt"
+
+	// SignedJWKSContentType is the media type of signed key sets. JWKS
+	// endpoints serve the signed key set if it is in the Accept header of the
+	// request and the JVS has a key set signing key.
+	SignedJWKSContentType string = "application/jwk-set+jwt"
+)
+
+// SignedJWKS is a verified signed key set.
+type SignedJWKS struct {
+	// Keys are the keys of the set.
+	Keys jwk.Set
+
+	// RootKey is the JWK thumbprint of the root key that signed the set.
+	RootKey string
+
+	// IssuedAt and ExpiresAt are the "iat" and "exp" of the set.
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// signedJWKSPayload is the payload of a signed key set.
+type signedJWKSPayload struct {
+	Keys      json.RawMessage `json:"keys"`
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp"`
+}
+
+// VerifySignedJWKS verifies a signed key set, a JWT of type [SignedJWKSType]
+// whose "keys" claim is the key set. It is signed with ES256 by a root key,
+// which is in its "jwk" header, and whose JWK thumbprint must be one of the
+// root keys. It returns an error if the set is expired or issued in the
+// future, tolerating the clock skew.
+//
+// A signed key set can be replayed until it expires, so the JVS keeps its
+// lifetime short.
+func VerifySignedJWKS(b []byte, rootKeys []string, now time.Time, skew time.Duration) (*SignedJWKS, error) {
+	msg, err := jws.Parse(b, jws.WithCompact())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signed jwks: %w", err)
+	}
+	sigs := msg.Signatures()
+	if len(sigs) != 1 {
+		return nil, fmt.Errorf("expected 1 signature, got %d", len(sigs))
+	}
+	headers := sigs[0].ProtectedHeaders()
+	if got, want := headers.Type(), SignedJWKSType; !strings.EqualFold(got, want) {
+		return nil, fmt.Errorf("expected signed jwks type %q, got %q", want, got)
+	}
+	if got, want := headers.Algorithm(), jwa.ES256; got != want {
+		return nil, fmt.Errorf("expected signed jwks algorithm %q, got %q", want, got)
+	}
+
+	key := headers.JWK()
+	if key == nil {
+		return nil, fmt.Errorf("signed jwks has no jwk header")
+	}
+	thumbprint, err := JWKThumbprint(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute thumbprint of root key: %w", err)
+	}
+	if !slices.Contains(rootKeys, thumbprint) {
+		return nil, fmt.Errorf("signed jwks root key %q is not one of the pinned root keys", thumbprint)
+	}
+	pub, err := key.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key of root key: %w", err)
+	}
+
+	payload, err := jws.Verify(b, jws.WithKey(jwa.ES256, pub))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify signed jwks: %w", err)
+	}
+
+	var p signedJWKSPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse signed jwks payload: %w", err)
+	}
+	if p.ExpiresAt == 0 {
+		return nil, fmt.Errorf("signed jwks has no exp claim")
+	}
+	iat, exp := time.Unix(p.IssuedAt, 0), time.Unix(p.ExpiresAt, 0)
+	if !now.Before(exp.Add(skew)) {
+		return nil, fmt.Errorf("signed jwks expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+	if iat.After(now.Add(skew)) {
+		return nil, fmt.Errorf("signed jwks was issued in the future")
+	}
+
+	if len(p.Keys) == 0 {
+		return nil, fmt.Errorf("signed jwks has no keys claim")
+	}
+	raw, err := json.Marshal(map[string]json.RawMessage{"keys": p.Keys})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal jwks: %w", err)
+	}
+	set, err := jwk.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	return &SignedJWKS{
+		Keys:      set,
+		RootKey:   thumbprint,
+		IssuedAt:  iat,
+		ExpiresAt: exp,
+	}, nil
+}
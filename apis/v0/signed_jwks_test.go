@@ -0,0 +1,330 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v0
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVerifySignedJWKS(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	rootKey := testRootKey(t)
+	rootThumbprint, err := JWKThumbprint(rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := testRootKey(t)
+	keys := `[{"kty":"EC","crv":"P-256","kid":"key-1",` +
+		`"x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}]`
+
+	cases := []struct {
+		name     string
+		signed   string
+		rootKeys []string
+		skew     time.Duration
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, keys, now, now.Add(time.Hour)),
+			rootKeys: []string{rootThumbprint},
+		},
+		{
+			name:     "second_root_key",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, keys, now, now.Add(time.Hour)),
+			rootKeys: []string{"47DEQpj8HBSa-_TImW-5JCeuQeRkm5NMpJWZG3hSuFU", rootThumbprint},
+		},
+		{
+			name:     "unpinned_root_key",
+			signed:   testSignedJWKS(t, otherKey, SignedJWKSType, keys, now, now.Add(time.Hour)),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "is not one of the pinned root keys",
+		},
+		{
+			name:     "wrong_type",
+			signed:   testSignedJWKS(t, rootKey, "JWT", keys, now, now.Add(time.Hour)),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  `expected signed jwks type "jwk-set+jwt", got "JWT"`,
+		},
+		{
+			name:     "expired",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, keys, now.Add(-2*time.Hour), now.Add(-time.Hour)),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "signed jwks expired at",
+		},
+		{
+			name:     "expired_within_skew",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, keys, now.Add(-time.Hour), now.Add(-time.Second)),
+			rootKeys: []string{rootThumbprint},
+			skew:     5 * time.Second,
+		},
+		{
+			name:     "issued_in_the_future",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, keys, now.Add(time.Minute), now.Add(time.Hour)),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "signed jwks was issued in the future",
+		},
+		{
+			name:     "no_exp",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, keys, now, time.Unix(0, 0)),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "signed jwks has no exp claim",
+		},
+		{
+			name:     "no_keys",
+			signed:   testSignedJWKS(t, rootKey, SignedJWKSType, "", now, now.Add(time.Hour)),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "signed jwks has no keys claim",
+		},
+		{
+			name: "substituted_jwk_header",
+			signed: func() string {
+				// The key in the header is pinned, but didn't sign the set.
+				signed := testSignedJWKS(t, otherKey, SignedJWKSType, keys, now, now.Add(time.Hour))
+				header := testSignedJWKS(t, rootKey, SignedJWKSType, keys, now, now.Add(time.Hour))
+				return header[:strings.Index(header, ".")] + signed[strings.Index(signed, "."):]
+			}(),
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "failed to verify signed jwks",
+		},
+		{
+			name:     "not_a_jws",
+			signed:   `{"keys":[]}`,
+			rootKeys: []string{rootThumbprint},
+			wantErr:  "failed to parse signed jwks",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := VerifySignedJWKS([]byte(tc.signed), tc.rootKeys, now, tc.skew)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if _, ok := got.Keys.LookupKeyID("key-1"); !ok {
+				t.Error("expected key-1 in the signed jwks")
+			}
+			if got, want := got.RootKey, rootThumbprint; got != want {
+				t.Errorf("expected root key %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestJWKSProvider_rootKeys(t *testing.T) {
+	t.Parallel()
+
+	rootKey := testRootKey(t)
+	rootThumbprint, err := JWKThumbprint(rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := `[{"kty":"EC","crv":"P-256","kid":"key-1",` +
+		`"x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}]`
+	now := time.Now()
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{
+			name:        "signed",
+			contentType: SignedJWKSContentType,
+			body:        testSignedJWKS(t, rootKey, SignedJWKSType, keys, now, now.Add(time.Hour)),
+		},
+		{
+			name:        "unsigned",
+			contentType: "application/json",
+			body:        `{"keys":` + keys + `}`,
+			wantErr:     `jwks endpoint returned content type "application/json", expected a signed jwks`,
+		},
+		{
+			name:        "signed_by_other_key",
+			contentType: SignedJWKSContentType,
+			body:        testSignedJWKS(t, testRootKey(t), SignedJWKSType, keys, now, now.Add(time.Hour)),
+			wantErr:     "is not one of the pinned root keys",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.Header.Get("Accept"), SignedJWKSContentType; got != want {
+					t.Errorf("expected accept %q to be %q", got, want)
+				}
+				w.Header().Set("Content-Type", tc.contentType)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			t.Cleanup(srv.Close)
+
+			p, err := NewJWKSProvider(ctx, srv.URL, WithJWKSRootKeys(rootThumbprint))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.Refresh(ctx)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if _, ok := p.LookupKeyID("key-1"); !ok {
+				t.Error("expected key-1 to be found")
+			}
+		})
+	}
+}
+
+func TestJWKSProvider_rootKeysExpired(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	rootKey := testRootKey(t)
+	rootThumbprint, err := JWKThumbprint(rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := `[{"kty":"EC","crv":"P-256","kid":"key-1",` +
+		`"x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}]`
+	now := time.Now()
+	body := testSignedJWKS(t, rootKey, SignedJWKSType, keys, now, now.Add(time.Hour))
+
+	// The endpoint serves the signed key set once, then answers 304 to every
+	// request, like a replaying attacker would.
+	var served atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if served.Swap(true) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", SignedJWKSContentType)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := NewJWKSProvider(ctx, srv.URL, WithJWKSRootKeys(rootThumbprint))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var clock atomic.Pointer[time.Time]
+	clock.Store(&now)
+	p.now = func() time.Time { return *clock.Load() }
+
+	if _, err := p.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Refresh(ctx); err != nil {
+		t.Fatalf("expected 304 before expiry to succeed: %v", err)
+	}
+	if _, ok := p.LookupKeyID("key-1"); !ok {
+		t.Error("expected key-1 to be found")
+	}
+
+	later := now.Add(time.Hour + DefaultAllowedClockSkew + time.Second)
+	clock.Store(&later)
+
+	if _, ok := p.LookupKeyID("key-1"); ok {
+		t.Error("expected key-1 of the expired jwks not to be found")
+	}
+	_, err = p.Refresh(ctx)
+	if diff := testutil.DiffErrString(err, "jwks endpoint returned 304: signed jwks expired"); diff != "" {
+		t.Error(diff)
+	}
+	if _, ok := p.LookupKeyID("key-1"); ok {
+		t.Error("expected key-1 to still not be found after the refresh")
+	}
+}
+
+func testRootKey(tb testing.TB) jwk.Key {
+	tb.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	key, err := jwk.FromRaw(priv)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return key
+}
+
+// testSignedJWKS signs the JSON encoded keys as a signed key set, the way the
+// JVS public key server does.
+func testSignedJWKS(tb testing.TB, rootKey jwk.Key, typ, keys string, iat, exp time.Time) string {
+	tb.Helper()
+
+	claims := map[string]any{"iat": iat.Unix()}
+	if keys != "" {
+		claims["keys"] = json.RawMessage(keys)
+	}
+	if exp.Unix() != 0 {
+		claims["exp"] = exp.Unix()
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	pub, err := rootKey.PublicKey()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.TypeKey, typ); err != nil {
+		tb.Fatal(err)
+	}
+	if err := headers.Set(jws.JWKKey, pub); err != nil {
+		tb.Fatal(err)
+	}
+	b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, rootKey, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,29 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+window.addEventListener("DOMContentLoaded", async () => {
+  const scriptTag = document.querySelector("#error");
+  const targetOrigin = scriptTag.getAttribute("data-origin");
+  const message = scriptTag.getAttribute("data-message");
+
+  // The server only includes this script for openers that speak version 2 of
+  // the message protocol, which handle the error themselves.
+  if (!targetOrigin || !message || !window.opener) {
+    return;
+  }
+
+  window.opener.postMessage(message, targetOrigin);
+
+  window.close();
+}, true);
@@ -0,0 +1,117 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// A helper for applications embedding the JVS UI popup. Load it from the JVS UI,
+// e.g. <script src="https://jvs-ui.example.com/static/js/jvs-popup.js">, and
+// request a token with:
+//
+//   const popup = new JVSPopup({ url: "https://jvs-ui.example.com/popup" });
+//   try {
+//     const token = await popup.requestToken();
+//   } catch (err) {
+//     if (err instanceof JVSPopupError && err.code === "cancel") { ... }
+//   }
+//
+// It speaks version 2 of the message protocol, see docs/web-ui.md.
+
+// JVSPopupError is the error a token request is rejected with. The code is one
+// of the error codes of the protocol, or "cancel" if the user closed the popup.
+class JVSPopupError extends Error {
+  constructor(code, message) {
+    super(message);
+    this.name = "JVSPopupError";
+    this.code = code;
+  }
+}
+
+class JVSPopup {
+  #url;
+  #name;
+  #popup;
+
+  // url is the URL of the JVS UI popup. name is the name of the popup window,
+  // messages from other windows are ignored.
+  constructor({ url, name = "jvs-popup" }) {
+    this.#url = new URL(url);
+    this.#url.searchParams.set("mode", "popup");
+    this.#url.searchParams.set("origin", window.location.origin);
+    this.#url.searchParams.set("version", "2");
+    this.#name = name;
+  }
+
+  // requestToken opens the popup and resolves with the token once the user
+  // submitted the form, or rejects with a JVSPopupError.
+  requestToken() {
+    return new Promise((resolve, reject) => {
+      let closedTimer;
+
+      const done = () => {
+        window.removeEventListener("message", onMessage);
+        clearInterval(closedTimer);
+      };
+
+      const onMessage = (event) => {
+        if (event.origin !== this.#url.origin) {
+          return;
+        }
+
+        let data;
+        try {
+          data = JSON.parse(event.data);
+        } catch {
+          return;
+        }
+        if (data.source !== this.#name) {
+          return;
+        }
+
+        done();
+        switch (data.type) {
+          case "success":
+            resolve(data.payload.token);
+            break;
+          case "cancel":
+            reject(new JVSPopupError("cancel", "the user cancelled the request"));
+            break;
+          case "error":
+            reject(new JVSPopupError(data.error.code, data.error.message));
+            break;
+          default:
+            reject(new JVSPopupError("invalid_request", `unexpected message type ${data.type}`));
+        }
+      };
+      window.addEventListener("message", onMessage);
+
+      if (!this.#popup || this.#popup.closed) {
+        this.#popup = window.open(this.#url.toString(), this.#name, "popup=true,width=500,height=600");
+      } else {
+        this.#popup.focus();
+      }
+      if (!this.#popup) {
+        done();
+        reject(new JVSPopupError("cancel", "the popup was blocked"));
+        return;
+      }
+
+      // The popup can also be closed without a message, e.g. with the window's
+      // close button.
+      closedTimer = setInterval(() => {
+        if (this.#popup.closed) {
+          done();
+          reject(new JVSPopupError("cancel", "the popup was closed"));
+        }
+      }, 500);
+    });
+  }
+}
@@ -16,7 +16,7 @@ document.addEventListener("DOMContentLoaded", async function () {
   const categorySelect = document.querySelector("#category");
   const reasonInput = document.querySelector('#reason');
   const form = document.querySelector('#form');
-  const hintTooltipText = document.querySelector("#hint")
+  const hintText = document.querySelector("#hint")
 
   if (!form) {
     alert("The form cannot be found");
@@ -33,30 +33,95 @@ document.addEventListener("DOMContentLoaded", async function () {
     return;
   }
 
-  if(!hintTooltipText){
-    alert("The hint tooltip cannot be found in the form.");
+  if(!hintText){
+    alert("The hint cannot be found in the form.");
     return;
   }
 
-  // Update the reason input's placeholder with the selected category's hint.
-  function updatePlaceholder() {
+  // Update the reason input's placeholder and hint with the selected category's
+  // hint. The hint is referenced by the input's aria-describedby, so screen
+  // readers announce it with the input.
+  function updateHint() {
     const selectedOption = categorySelect.options[categorySelect.selectedIndex];
-    reasonInput.placeholder = selectedOption.getAttribute("hint");
-    hintTooltipText.innerHTML = selectedOption.getAttribute("hint");
+    const hint = selectedOption.getAttribute("data-hint");
+    reasonInput.placeholder = hint;
+    hintText.textContent = hint;
   }
 
   // Call the function when the page loads.
-  updatePlaceholder();
+  updateHint();
 
   // Call the function when select new category.
-  categorySelect.addEventListener("change", updatePlaceholder);
+  categorySelect.addEventListener("change", updateHint);
+
+  // The second justification is optional. Its rows are hidden behind the add
+  // button, unless it already has a value or an error. Without JavaScript, the
+  // rows are always shown.
+  const secondCategorySelect = document.querySelector("#category2");
+  const secondReasonInput = document.querySelector("#reason2");
+  const secondHintText = document.querySelector("#hint2");
+  const addRow = document.querySelector("#add-justification-row");
+  const addButton = document.querySelector("#add-justification");
+  const secondRows = document.querySelectorAll(".second-justification");
+
+  function updateSecondHint() {
+    const selectedOption = secondCategorySelect.options[secondCategorySelect.selectedIndex];
+    const hint = selectedOption.getAttribute("data-hint");
+    secondReasonInput.placeholder = hint;
+    secondHintText.textContent = hint ? `Optional, ${hint}` : "Optional";
+  }
+
+  function showSecondJustification(show) {
+    secondRows.forEach((row) => { row.hidden = !show; });
+    addRow.hidden = show;
+  }
+
+  if (secondCategorySelect && secondReasonInput && secondHintText && addRow && addButton) {
+    updateSecondHint();
+    secondCategorySelect.addEventListener("change", updateSecondHint);
+
+    const hasError = secondCategorySelect.hasAttribute("aria-invalid") ||
+      secondReasonInput.hasAttribute("aria-invalid");
+    showSecondJustification(secondReasonInput.value !== "" || hasError);
+
+    addButton.addEventListener("click", () => {
+      showSecondJustification(true);
+      secondCategorySelect.focus();
+    });
+  }
 
   form.addEventListener("reset", function(){
     // After resetting, the selectedIndex should be set back to 0.
     categorySelect.selectedIndex = 0;
     const defaultOption = categorySelect.options[categorySelect.selectedIndex];
-    reasonInput.placeholder = defaultOption.getAttribute("hint");
+    reasonInput.placeholder = defaultOption.getAttribute("data-hint");
+    hintText.textContent = defaultOption.getAttribute("data-hint");
+
+    // The reset event fires before the fields are reset, so update the second
+    // justification once they are.
+    if (secondCategorySelect && secondReasonInput && secondHintText && addRow && addButton) {
+      setTimeout(() => {
+        updateSecondHint();
+        showSecondJustification(secondReasonInput.value !== "");
+      });
+    }
   });
+
+  // If the form was submitted with errors, move focus to the error summary so
+  // it is announced, and let its links move focus to the invalid fields.
+  const errorSummary = document.querySelector("#error-summary");
+  if (errorSummary) {
+    errorSummary.focus();
+    errorSummary.querySelectorAll("a").forEach((link) => {
+      link.addEventListener("click", (event) => {
+        const field = document.querySelector(link.getAttribute("href"));
+        if (field) {
+          event.preventDefault();
+          field.focus();
+        }
+      });
+    });
+  }
 });
 
 window.addEventListener("DOMContentLoaded", async () => {
@@ -96,3 +161,28 @@ window.addEventListener("DOMContentLoaded", async () => {
     windowElement.value = window.name;
   }
 }, true);
+
+window.addEventListener("DOMContentLoaded", async () => {
+  const cancelButton = document.querySelector("#cancel");
+  const originElement = document.querySelector("#origin");
+
+  // The cancel button is only shown to openers that speak version 2 of the
+  // message protocol.
+  if (!cancelButton || !originElement) {
+    return;
+  }
+
+  cancelButton.addEventListener("click", () => {
+    if (window.opener && originElement.value) {
+      window.opener.postMessage(
+        JSON.stringify({
+          version: 2,
+          type: "cancel",
+          source: window.name,
+        }),
+        originElement.value
+      );
+    }
+    window.close();
+  });
+}, true);
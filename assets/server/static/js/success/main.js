@@ -16,7 +16,7 @@ window.addEventListener("DOMContentLoaded", async () => {
   const scriptTag = document.querySelector("#success");
   const targetOrigin = scriptTag.getAttribute("data-origin");
   const windowName = scriptTag.getAttribute("data-window-name");
-  const token = scriptTag.getAttribute("data-token");
+  const message = scriptTag.getAttribute("data-message");
 
   if (!targetOrigin) {
     alert("You must pass a target origin from your application to successfully retrieve a token.")
@@ -30,23 +30,16 @@ window.addEventListener("DOMContentLoaded", async () => {
     return;
   }
 
-  if (!token) {
+  if (!message) {
     alert("Something went wrong, unable to retrieve a token.")
     window.close()
     return;
   }
 
-  window.opener.postMessage(
-    JSON.stringify({
-      // notify the requestor of the window name that was provided, 
-      // client should check this as a sanity check
-      source: windowName,
-      payload: {
-        token,
-      },
-    }),
-    targetOrigin
-  );
+  // The message is built by the server, see the controller.Message type. It
+  // includes the window name that was provided as the source, the client
+  // should check this as a sanity check.
+  window.opener.postMessage(message, targetOrigin);
 
   window.close();
 }, true);
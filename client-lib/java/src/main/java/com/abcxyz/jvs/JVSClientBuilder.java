@@ -43,12 +43,16 @@ import lombok.Getter;
  * invalidated.
  *
  * <p>env: ENDPOINT yaml: endpoint. Specifies the url for retrieving public keys.
+ *
+ * <p>env: ALLOWED_CLOCK_SKEW yaml: allowed_clock_skew. Specifies how far the expiry and not before
+ * times of tokens may be off.
  */
 public class JVSClientBuilder {
 
   static final String ENDPOINT_ENV_KEY = "ENDPOINT";
   static final String CACHE_TIMEOUT_ENV_KEY = "CACHE_TIMEOUT";
   static final String VERSION_ENV_KEY = "VERSION";
+  static final String ALLOWED_CLOCK_SKEW_ENV_KEY = "ALLOWED_CLOCK_SKEW";
   private static final int CACHE_SIZE = 10;
 
   @Getter(AccessLevel.PACKAGE)
@@ -82,6 +86,11 @@ public class JVSClientBuilder {
     if (!Strings.isNullOrEmpty(timeoutEnv)) {
       configuration.setCacheTimeout(Duration.parse(timeoutEnv));
     }
+
+    String skewEnv = getFromEnvironmentVars(ALLOWED_CLOCK_SKEW_ENV_KEY);
+    if (!Strings.isNullOrEmpty(skewEnv)) {
+      configuration.setAllowedClockSkew(Duration.parse(skewEnv));
+    }
   }
 
   String getFromEnvironmentVars(String key) {
@@ -98,6 +107,11 @@ public class JVSClientBuilder {
     return this;
   }
 
+  public JVSClientBuilder withAllowedClockSkew(Duration allowedClockSkew) {
+    configuration.setAllowedClockSkew(allowedClockSkew);
+    return this;
+  }
+
   public JVSClientBuilder withAllowBreakglass(boolean allowBreakglass) {
     configuration.setBreakglassAllowed(allowBreakglass);
     return this;
@@ -121,12 +135,13 @@ public class JVSClientBuilder {
     JwkProvider provider =
         new JwkProviderBuilder(url)
             .cached(CACHE_SIZE, configuration.getCacheTimeout())
-            // TODO: by default, the rate limiter allows 10 reqs per minute.
+            // Keys missing from the cache, e.g. right after a key rotation, are
+            // fetched again. By default, the rate limiter allows 10 reqs per minute.
             // https://github.com/auth0/jwks-rsa-java/blob/master/src/main/java/com/auth0/jwk/JwkProviderBuilder.java#L43
-            // we can consider if a different rate limit makes more sense for us.
             .rateLimited(true)
             .build();
 
-    return new JvsClient(provider, configuration.isBreakglassAllowed());
+    return new JvsClient(
+        provider, configuration.isBreakglassAllowed(), configuration.getAllowedClockSkew());
   }
 }
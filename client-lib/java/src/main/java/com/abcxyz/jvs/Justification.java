@@ -17,6 +17,7 @@
 package com.abcxyz.jvs;
 
 import com.fasterxml.jackson.databind.annotation.JsonDeserialize;
+import java.util.Map;
 import lombok.AccessLevel;
 import lombok.AllArgsConstructor;
 import lombok.Data;
@@ -27,4 +28,12 @@ import lombok.Data;
 public class Justification {
   private String category;
   private String value;
+
+  // Annotations added to the justification by its validator, e.g. the URL of a
+  // ticket. May be null.
+  private Map<String, String> annotation;
+
+  public Justification(String category, String value) {
+    this(category, value, null);
+  }
 }
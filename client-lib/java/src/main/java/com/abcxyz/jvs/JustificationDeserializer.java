@@ -23,6 +23,9 @@ import com.fasterxml.jackson.databind.DeserializationContext;
 import com.fasterxml.jackson.databind.JsonNode;
 import com.fasterxml.jackson.databind.deser.std.StdDeserializer;
 import java.io.IOException;
+import java.util.HashMap;
+import java.util.Iterator;
+import java.util.Map;
 
 public class JustificationDeserializer extends StdDeserializer<Justification> {
   public JustificationDeserializer() {
@@ -59,6 +62,17 @@ public class JustificationDeserializer extends StdDeserializer<Justification> {
       value = valueNode.asText();
     }
 
-    return new Justification(category, value);
+    Map<String, String> annotation = null;
+    JsonNode annotationNode = node.get("annotation");
+    if (annotationNode != null && annotationNode.isObject()) {
+      annotation = new HashMap<>();
+      Iterator<Map.Entry<String, JsonNode>> fields = annotationNode.fields();
+      while (fields.hasNext()) {
+        Map.Entry<String, JsonNode> field = fields.next();
+        annotation.put(field.getKey(), field.getValue().asText());
+      }
+    }
+
+    return new Justification(category, value, annotation);
   }
 }
@@ -27,6 +27,9 @@ import com.auth0.jwt.interfaces.DecodedJWT;
 import io.jsonwebtoken.Header;
 import io.jsonwebtoken.SignatureAlgorithm;
 import java.security.interfaces.ECPublicKey;
+import java.time.Duration;
+import java.time.Instant;
+import java.util.Date;
 import java.util.List;
 import lombok.AccessLevel;
 import lombok.RequiredArgsConstructor;
@@ -45,8 +48,50 @@ public class JvsClient {
   // This is the justification category set for breakglass tokens.
   private static final String BREAKGLASS_JUSTIFICATION_CATEGORY = "breakglass";
 
+  // This is the claim that holds the justifications of a token.
+  static final String JUSTIFICATIONS_KEY = "justs";
+
+  // This is the claim that holds the encrypted justifications of a token.
+  static final String ENCRYPTED_JUSTIFICATIONS_KEY = "justs_enc";
+
+  // This is the default clock skew allowed when checking the expiry and not
+  // before times of a token.
+  static final Duration DEFAULT_ALLOWED_CLOCK_SKEW = Duration.ofSeconds(5);
+
   private final JwkProvider provider;
   private final boolean allowBreakglass;
+  private final Duration allowedClockSkew;
+
+  JvsClient(JwkProvider provider, boolean allowBreakglass) {
+    this(provider, allowBreakglass, DEFAULT_ALLOWED_CLOCK_SKEW);
+  }
+
+  /** Returns the justifications of a token validated by this client. */
+  public static List<Justification> getJustifications(DecodedJWT token) {
+    if (token.getClaim(JUSTIFICATIONS_KEY).isNull()) {
+      return List.of();
+    }
+    return token.getClaim(JUSTIFICATIONS_KEY).asList(Justification.class);
+  }
+
+  /**
+   * This checks that the token has an expiry that has not passed and, if it has a not before time,
+   * that it has been reached, allowing for the configured clock skew.
+   */
+  private void validateTimes(DecodedJWT token) throws JwkException {
+    Instant now = Instant.now();
+    Date expiresAt = token.getExpiresAt();
+    if (expiresAt == null) {
+      throw new JwkException("token is missing an expiry");
+    }
+    if (now.isAfter(expiresAt.toInstant().plus(allowedClockSkew))) {
+      throw new JwkException("token is expired");
+    }
+    Date notBefore = token.getNotBefore();
+    if (notBefore != null && now.isBefore(notBefore.toInstant().minus(allowedClockSkew))) {
+      throw new JwkException("token is not valid yet");
+    }
+  }
 
   /**
    * This parses the given token as a breakglass token. If the token's signature is invalid, it
@@ -62,8 +107,9 @@ public class JvsClient {
       throw new JwkException("failed to parse breakglass jwt", e);
     }
 
-    List<Justification> justifications = token.getClaim("justs").asList(Justification.class);
-    for (Justification justification : justifications) {
+    validateTimes(token);
+
+    for (Justification justification : getJustifications(token)) {
       if (BREAKGLASS_JUSTIFICATION_CATEGORY.equals(justification.getCategory())) {
         return;
       }
@@ -90,15 +136,26 @@ public class JvsClient {
       return token;
     }
 
+    // Receipts and other tokens signed with the JVS keys aren't justifications.
+    if (token.getType() != null && !Header.JWT_TYPE.equals(token.getType())) {
+      throw new JwkException(String.format("token has unexpected type %s", token.getType()));
+    }
+
     // Handle regular token processing.
     try {
       Jwk jwk = provider.get(token.getKeyId());
       if (jwk == null) {
-        throw new SigningKeyNotFoundException("daf", null);
+        throw new SigningKeyNotFoundException("no public key found", null);
       }
       Algorithm algorithm = Algorithm.ECDSA256((ECPublicKey) jwk.getPublicKey(), null);
       algorithm.verify(token);
 
+      validateTimes(token);
+      if (token.getClaim(JUSTIFICATIONS_KEY).isNull()
+          && token.getClaim(ENCRYPTED_JUSTIFICATIONS_KEY).isNull()) {
+        throw new JwkException("token is missing justifications");
+      }
+
       if (!expectedSubject.equals(token.getSubject()) && !expectedSubject.isBlank()) {
         String msg =
             String.format(
@@ -43,6 +43,9 @@ public class JvsConfiguration {
   @JsonProperty("allow_breakglass")
   private boolean breakglassAllowed = false;
 
+  @JsonProperty("allowed_clock_skew")
+  private Duration allowedClockSkew = JvsClient.DEFAULT_ALLOWED_CLOCK_SKEW;
+
   public void validate() throws IllegalArgumentException {
     if (!version.equals(EXPECTED_VERSION)) {
       throw new IllegalArgumentException(
@@ -59,5 +62,12 @@ public class JvsConfiguration {
               "Cache timeout is invalid. Must be a positive non-zero duration, but was set to %s",
               cacheTimeout));
     }
+
+    if (allowedClockSkew == null || allowedClockSkew.isNegative()) {
+      throw new IllegalArgumentException(
+          String.format(
+              "Allowed clock skew is invalid. Must be a non-negative duration, but was set to %s",
+              allowedClockSkew));
+    }
   }
 }
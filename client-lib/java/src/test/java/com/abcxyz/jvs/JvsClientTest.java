@@ -32,6 +32,7 @@ import io.jsonwebtoken.security.Keys;
 import java.security.KeyPair;
 import java.security.KeyPairGenerator;
 import java.security.SecureRandom;
+import java.time.Duration;
 import java.util.Date;
 import java.util.HashMap;
 import java.util.List;
@@ -69,10 +70,12 @@ public class JvsClientTest {
     claims.put("id", "jwt-id");
     claims.put("role", "user");
     claims.put("created", new Date());
+    claims.put("justs", List.of(new Justification("explanation", "testing")));
 
     String token =
         Jwts.builder()
             .setClaims(claims)
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
             .setSubject("test_sub")
             .setHeaderParam("kid", keyId)
             .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
@@ -97,10 +100,12 @@ public class JvsClientTest {
     claims.put("id", "jwt-id");
     claims.put("role", "user");
     claims.put("created", new Date());
+    claims.put("justs", List.of(new Justification("explanation", "testing")));
 
     String token =
         Jwts.builder()
             .setClaims(claims)
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
             .setSubject("test_sub")
             .setHeaderParam("kid", keyId)
             .signWith(key2.getPrivate(), SignatureAlgorithm.ES256)
@@ -130,6 +135,7 @@ public class JvsClientTest {
     String token =
         Jwts.builder()
             .setClaims(claims)
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
             .setSubject("test_sub")
             .setHeaderParam("kid", keyId)
             .setHeaderParam(Header.TYPE, Header.JWT_TYPE)
@@ -158,6 +164,7 @@ public class JvsClientTest {
     String token =
         Jwts.builder()
             .setClaims(claims)
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
             .setSubject("test_sub")
             .setHeaderParam("kid", keyId)
             .setHeaderParam(Header.TYPE, Header.JWT_TYPE)
@@ -189,6 +196,7 @@ public class JvsClientTest {
     String token =
         Jwts.builder()
             .setClaims(claims)
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
             .setSubject("test_sub")
             .setHeaderParam("kid", keyId)
             .setHeaderParam(Header.TYPE, Header.JWT_TYPE)
@@ -210,10 +218,12 @@ public class JvsClientTest {
     claims.put("id", "jwt-id");
     claims.put("role", "user");
     claims.put("created", new Date());
+    claims.put("justs", List.of(new Justification("explanation", "testing")));
 
     String token =
         Jwts.builder()
             .setClaims(claims)
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
             .setSubject("bad_sub")
             .setHeaderParam("kid", keyId)
             .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
@@ -227,4 +237,108 @@ public class JvsClientTest {
         Assertions.assertThrows(JwkException.class, () -> client.validateJWT(token, "test_sub"));
     assertThat(thrown.getMessage()).contains("does not match expected subject");
   }
+
+  @Test
+  public void testValidateJWT_Justifications() throws Exception {
+    String keyId = "key1";
+
+    Justification justification =
+        new Justification("jira", "ABC-123", Map.of("jira_issue_url", "https://jira/ABC-123"));
+    String token =
+        Jwts.builder()
+            .claim("justs", List.of(justification))
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
+            .setSubject("test_sub")
+            .setHeaderParam("kid", keyId)
+            .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
+            .compact();
+
+    Jwk jwk = mock(Jwk.class);
+    when(jwk.getPublicKey()).thenReturn(key1.getPublic());
+    when(provider.get(keyId)).thenReturn(jwk);
+    JvsClient client = new JvsClient(provider, false);
+    DecodedJWT returnVal = client.validateJWT(token, "test_sub");
+    Assertions.assertEquals(List.of(justification), JvsClient.getJustifications(returnVal));
+  }
+
+  @Test
+  public void testValidateJWT_Expired() throws Exception {
+    String keyId = "key1";
+
+    String token =
+        Jwts.builder()
+            .claim("justs", List.of(new Justification("explanation", "testing")))
+            .setExpiration(new Date(System.currentTimeMillis() - 60_000))
+            .setSubject("test_sub")
+            .setHeaderParam("kid", keyId)
+            .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
+            .compact();
+
+    Jwk jwk = mock(Jwk.class);
+    when(jwk.getPublicKey()).thenReturn(key1.getPublic());
+    when(provider.get(keyId)).thenReturn(jwk);
+    JvsClient client = new JvsClient(provider, false);
+    JwkException thrown =
+        Assertions.assertThrows(JwkException.class, () -> client.validateJWT(token, "test_sub"));
+    assertThat(thrown.getMessage()).contains("token is expired");
+  }
+
+  @Test
+  public void testValidateJWT_ExpiredWithinSkew() throws Exception {
+    String keyId = "key1";
+
+    String token =
+        Jwts.builder()
+            .claim("justs", List.of(new Justification("explanation", "testing")))
+            .setExpiration(new Date(System.currentTimeMillis() - 60_000))
+            .setSubject("test_sub")
+            .setHeaderParam("kid", keyId)
+            .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
+            .compact();
+
+    Jwk jwk = mock(Jwk.class);
+    when(jwk.getPublicKey()).thenReturn(key1.getPublic());
+    when(provider.get(keyId)).thenReturn(jwk);
+    JvsClient client = new JvsClient(provider, false, Duration.ofMinutes(2));
+    Assertions.assertDoesNotThrow(() -> client.validateJWT(token, "test_sub"));
+  }
+
+  @Test
+  public void testValidateJWT_MissingJustifications() throws Exception {
+    String keyId = "key1";
+
+    String token =
+        Jwts.builder()
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
+            .setSubject("test_sub")
+            .setHeaderParam("kid", keyId)
+            .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
+            .compact();
+
+    Jwk jwk = mock(Jwk.class);
+    when(jwk.getPublicKey()).thenReturn(key1.getPublic());
+    when(provider.get(keyId)).thenReturn(jwk);
+    JvsClient client = new JvsClient(provider, false);
+    JwkException thrown =
+        Assertions.assertThrows(JwkException.class, () -> client.validateJWT(token, "test_sub"));
+    assertThat(thrown.getMessage()).contains("token is missing justifications");
+  }
+
+  @Test
+  public void testValidateJWT_UnexpectedType() throws Exception {
+    String token =
+        Jwts.builder()
+            .claim("justs", List.of(new Justification("explanation", "testing")))
+            .setExpiration(new Date(System.currentTimeMillis() + 60_000))
+            .setSubject("test_sub")
+            .setHeaderParam("kid", "key1")
+            .setHeaderParam(Header.TYPE, "jvs-receipt+json")
+            .signWith(key1.getPrivate(), SignatureAlgorithm.ES256)
+            .compact();
+
+    JvsClient client = new JvsClient(provider, false);
+    JwkException thrown =
+        Assertions.assertThrows(JwkException.class, () -> client.validateJWT(token, "test_sub"));
+    assertThat(thrown.getMessage()).contains("token has unexpected type");
+  }
 }
@@ -0,0 +1,272 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import { decodeBase64URL, decodeText } from './base64url.js';
+import { JWKSProvider } from './jwks.js';
+
+/**
+ * The HMAC key of breakglass tokens. Breakglass tokens are already
+ * "unverified", so having this static secret does not introduce additional
+ * risk, and breakglass is disabled by default.
+ */
+export const BREAKGLASS_HMAC_SECRET = 'BHzwNUbxcgpNoDfzwzt4Dr2nVXByUCWl1m8Eq2Jh26CGqu8IQ0VdiyjxnCtNahh9';
+
+/** The issuer of breakglass tokens created by jvsctl. */
+export const BREAKGLASS_ISSUER = 'jvsctl';
+
+/** The default tolerance, in milliseconds, of the time claims of tokens. */
+export const DEFAULT_ALLOWED_CLOCK_SKEW = 5 * 1000;
+
+/** The newest layout of the JVS claims this library can parse. */
+export const LATEST_CLAIM_VERSION = 1;
+
+/** The claim holding the justifications. */
+export const JUSTIFICATIONS_KEY = 'justs';
+
+/** The claim holding the justifications when they are encrypted. */
+export const ENCRYPTED_JUSTIFICATIONS_KEY = 'justs_enc';
+
+/** The claim holding the principal that requested the token. */
+export const REQUESTOR_KEY = 'req';
+
+/** The claim holding the version of the layout of the JVS claims. */
+export const VERSION_KEY = 'jvs_ver';
+
+const breakglassCategory = 'breakglass';
+
+/** A justification of a token. */
+export interface Justification {
+  category: string;
+  value: string;
+
+  /** The annotations the validator of the category set, if any. */
+  annotation?: Record<string, string>;
+}
+
+/** The claims of a JVS token. */
+export interface Claims {
+  iss?: string;
+  sub?: string;
+  aud?: string | string[];
+  exp?: number;
+  nbf?: number;
+  iat?: number;
+  jti?: string;
+  req?: string;
+  groups?: string[];
+  justs?: Justification[];
+  jvs_ver?: number;
+  [claim: string]: unknown;
+}
+
+/** The configuration of a {@link Client}. Durations are in milliseconds. */
+export interface ClientConfig {
+  /** The JWKS endpoint of the JVS, e.g. "https://jvs.corp/.well-known/jwks". */
+  jwksEndpoint: string;
+
+  /** How long fetched keys are used before they are refreshed. */
+  cacheTimeout?: number;
+
+  /** Whether to accept breakglass tokens. */
+  allowBreakglass?: boolean;
+
+  /** The tolerance of the exp, nbf and iat claims. */
+  allowedClockSkew?: number;
+
+  /** If set, tokens of other issuers, including breakglass tokens, are rejected. */
+  acceptedIssuers?: string[];
+
+  /**
+   * The JWK thumbprints of the root keys the key set must be signed by, if
+   * the JVS signs its key set. Unsigned key sets are rejected.
+   */
+  jwksRootKeys?: string[];
+
+  /** The fetch implementation, e.g. to add headers or for tests. */
+  fetch?: typeof fetch;
+}
+
+/**
+ * Client validates tokens with the keys at the JWKS endpoint of the JVS. It
+ * mirrors the Go client in github.com/abcxyz/jvs/apis/v0.
+ */
+export class Client {
+  private readonly config: ClientConfig;
+  private readonly keys: JWKSProvider;
+  private readonly skew: number;
+
+  constructor(config: ClientConfig) {
+    if (!config.jwksEndpoint) {
+      throw new Error('jwksEndpoint must be set');
+    }
+    if (config.cacheTimeout !== undefined && config.cacheTimeout <= 0) {
+      throw new Error(`cache timeout must be positive, got ${config.cacheTimeout}`);
+    }
+    if (config.allowedClockSkew !== undefined && config.allowedClockSkew < 0) {
+      throw new Error(`allowed clock skew must not be negative, got ${config.allowedClockSkew}`);
+    }
+
+    this.config = config;
+    this.keys = new JWKSProvider(config.jwksEndpoint, {
+      refreshInterval: config.cacheTimeout,
+      rootKeys: config.jwksRootKeys,
+      fetch: config.fetch,
+    });
+    this.skew = config.allowedClockSkew ?? DEFAULT_ALLOWED_CLOCK_SKEW;
+  }
+
+  /**
+   * Parses the token and validates its signature against the keys at the JWKS
+   * endpoint, and its time claims. The token must have an expiry and
+   * justifications, so other objects signed by the JVS keys, such as receipts,
+   * are rejected. If the expected subject is not empty, the token's subject
+   * must match it. Breakglass tokens are accepted if allowed.
+   */
+  async validateJWT(token: string, expectedSubject = ''): Promise<Claims> {
+    const { header, claims, signingInput, signature } = decode(token);
+
+    let verified: boolean;
+    if (header.typ === 'JWT' && header.alg === 'HS256') {
+      if (!this.config.allowBreakglass) {
+        throw new Error('breakglass is forbidden, denying');
+      }
+      const key = await crypto.subtle.importKey(
+        'raw',
+        new TextEncoder().encode(BREAKGLASS_HMAC_SECRET),
+        { name: 'HMAC', hash: 'SHA-256' },
+        false,
+        ['verify'],
+      );
+      verified = await crypto.subtle.verify('HMAC', key, signature, signingInput);
+      if (verified && !getJustifications(claims).some((j) => j.category === breakglassCategory)) {
+        throw new Error('token is breakglass, but is missing breakglass justification');
+      }
+    } else {
+      // Receipts, policy attestations and detached signatures are signed by
+      // the same keys, but aren't tokens.
+      if (header.typ !== undefined && header.typ.toUpperCase() !== 'JWT') {
+        throw new Error(`token type "${header.typ}" is not a jwt`);
+      }
+      if (header.alg !== 'ES256') {
+        throw new Error(`token algorithm "${header.alg}" is not ES256`);
+      }
+      if (header.kid === undefined) {
+        throw new Error('token has no key id');
+      }
+      const key = await this.keys.getKey(header.kid);
+      verified = await crypto.subtle.verify({ name: 'ECDSA', hash: 'SHA-256' }, key, signature, signingInput);
+    }
+    if (!verified) {
+      throw new Error('failed to verify jwt: invalid signature');
+    }
+
+    this.checkTimes(claims);
+    if (claims[JUSTIFICATIONS_KEY] === undefined && claims[ENCRYPTED_JUSTIFICATIONS_KEY] === undefined) {
+      throw new Error(`jwt has no ${JUSTIFICATIONS_KEY} or ${ENCRYPTED_JUSTIFICATIONS_KEY} claim`);
+    }
+
+    const accepted = this.config.acceptedIssuers ?? [];
+    if (accepted.length > 0 && !accepted.includes(claims.iss ?? '')) {
+      throw new Error(`issuer "${claims.iss ?? ''}" is not one of the accepted issuers`);
+    }
+
+    if (expectedSubject !== '' && claims.sub !== expectedSubject) {
+      throw new Error(`subject "${claims.sub ?? ''}" does not match expected subject "${expectedSubject}"`);
+    }
+    return claims;
+  }
+
+  private checkTimes(claims: Claims): void {
+    const now = Date.now();
+    if (claims.exp === undefined) {
+      throw new Error('jwt has no exp claim');
+    }
+    if (now >= claims.exp * 1000 + this.skew) {
+      throw new Error('jwt is expired');
+    }
+    if (claims.nbf !== undefined && now < claims.nbf * 1000 - this.skew) {
+      throw new Error('jwt is not valid yet');
+    }
+    if (claims.iat !== undefined && now < claims.iat * 1000 - this.skew) {
+      throw new Error('jwt was issued in the future');
+    }
+  }
+}
+
+/**
+ * Returns the justifications of the token's claims. It throws if the claims
+ * were minted with a newer claim version than this library supports.
+ */
+export function getJustifications(claims: Claims): Justification[] {
+  const version = claims[VERSION_KEY] ?? 0;
+  if (version > LATEST_CLAIM_VERSION) {
+    throw new Error(
+      `unsupported claim version ${version}, the latest supported version is ${LATEST_CLAIM_VERSION}`,
+    );
+  }
+
+  const raw = claims[JUSTIFICATIONS_KEY];
+  if (raw === undefined) {
+    return [];
+  }
+  if (!Array.isArray(raw)) {
+    throw new Error(`found justifications, but was of unknown type ${typeof raw}`);
+  }
+  return raw.map((j) => {
+    const just: Justification = {
+      category: String(j.category ?? ''),
+      value: String(j.value ?? ''),
+    };
+    if (j.annotation !== undefined && j.annotation !== null) {
+      just.annotation = { ...j.annotation };
+    }
+    return just;
+  });
+}
+
+/** Returns the principal that requested the token, if any. */
+export function getRequestor(claims: Claims): string | undefined {
+  return claims[REQUESTOR_KEY];
+}
+
+interface Header {
+  alg?: string;
+  typ?: string;
+  kid?: string;
+}
+
+interface DecodedToken {
+  header: Header;
+  claims: Claims;
+  signingInput: ArrayBuffer;
+  signature: ArrayBuffer;
+}
+
+/** Splits a compact JWS and decodes its header and claims. */
+function decode(token: string): DecodedToken {
+  const parts = token.split('.');
+  if (parts.length !== 3) {
+    throw new Error('failed to parse jwt: not a compact jws');
+  }
+  try {
+    return {
+      header: JSON.parse(decodeText(parts[0])),
+      claims: JSON.parse(decodeText(parts[1])),
+      signingInput: new TextEncoder().encode(`${parts[0]}.${parts[1]}`).buffer,
+      signature: decodeBase64URL(parts[2]),
+    };
+  } catch (err) {
+    throw new Error(`failed to parse jwt: ${err}`);
+  }
+}
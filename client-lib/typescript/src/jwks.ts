@@ -0,0 +1,286 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import { decodeBase64URL, decodeText, encodeBase64URL } from './base64url.js';
+
+/** The "typ" header of signed key sets. */
+export const SIGNED_JWKS_TYPE = 'jwk-set+jwt';
+
+/** The media type of signed key sets. */
+export const SIGNED_JWKS_CONTENT_TYPE = 'application/jwk-set+jwt';
+
+/** The tolerance, in milliseconds, of the exp and iat claims of signed key sets. */
+const signedJWKSClockSkew = 5 * 1000;
+
+/** The default interval between refreshes of a {@link JWKSProvider}. */
+export const DEFAULT_JWKS_REFRESH_INTERVAL = 5 * 60 * 1000;
+
+/**
+ * The minimum interval between fetches for key IDs that aren't in the
+ * current keys, so tokens with unknown key IDs can't flood the endpoint.
+ */
+export const DEFAULT_JWKS_MIN_REFETCH_INTERVAL = 10 * 1000;
+
+/** A public key of a JWKS. */
+export interface JWK {
+  kty: string;
+  kid?: string;
+  crv?: string;
+  x?: string;
+  y?: string;
+  alg?: string;
+  [member: string]: unknown;
+}
+
+/** Options of a {@link JWKSProvider}. Durations are in milliseconds. */
+export interface JWKSProviderOptions {
+  /** How long fetched keys are used before they are refreshed. */
+  refreshInterval?: number;
+
+  /** How often to refetch the keys for an unknown key ID at most. */
+  minRefetchInterval?: number;
+
+  /**
+   * The JWK thumbprints of the root keys the key set must be signed by, for
+   * a JVS with a key set signing key. If set, unsigned key sets are rejected.
+   */
+  rootKeys?: string[];
+
+  /** The fetch implementation, e.g. to add headers or for tests. */
+  fetch?: typeof fetch;
+
+  /** The current time in milliseconds since the epoch, for tests. */
+  now?: () => number;
+}
+
+/**
+ * JWKSProvider holds the ES256 keys served at a JWKS endpoint. Keys are
+ * refreshed once they are older than the refresh interval, and refetched when a
+ * token has a key ID they don't have, e.g. right after a key rotation.
+ * Refreshes are conditional requests using the ETag of the previous response,
+ * so an unchanged key set costs the endpoint little more than a 304.
+ *
+ * If a refresh fails, the previous keys keep being used until the next one.
+ * Only the first fetch must succeed. Signed key sets are the exception: once
+ * one expires, it is refetched without a condition, and keys aren't returned
+ * until a signed key set that hasn't expired is fetched.
+ */
+export class JWKSProvider {
+  private readonly endpoint: string;
+  private readonly refreshInterval: number;
+  private readonly minRefetchInterval: number;
+  private readonly rootKeys: string[];
+  private readonly fetch: typeof fetch;
+  private readonly now: () => number;
+
+  private keys: Map<string, CryptoKey> | undefined;
+  private etag = '';
+  private expiresAt = Number.POSITIVE_INFINITY;
+  private fetchedAt = 0;
+  private refetchedAt = Number.NEGATIVE_INFINITY;
+  private inflight: Promise<void> | undefined;
+
+  constructor(endpoint: string, opts: JWKSProviderOptions = {}) {
+    this.endpoint = endpoint;
+    this.refreshInterval = opts.refreshInterval ?? DEFAULT_JWKS_REFRESH_INTERVAL;
+    this.minRefetchInterval = opts.minRefetchInterval ?? DEFAULT_JWKS_MIN_REFETCH_INTERVAL;
+    this.rootKeys = opts.rootKeys ?? [];
+    this.fetch = opts.fetch ?? globalThis.fetch.bind(globalThis);
+    this.now = opts.now ?? Date.now;
+  }
+
+  /**
+   * Returns the verification key with the key ID, refreshing the keys if they
+   * are stale or don't have it.
+   */
+  async getKey(kid: string): Promise<CryptoKey> {
+    if (this.keys === undefined || this.expired()) {
+      await this.refresh();
+    } else if (this.now() - this.fetchedAt >= this.refreshInterval) {
+      await this.refresh().catch(() => {
+        // Keep using the previous keys.
+      });
+    }
+
+    let key = this.keys?.get(kid);
+    if (key === undefined && this.now() - this.refetchedAt >= this.minRefetchInterval) {
+      this.refetchedAt = this.now();
+      await this.refresh();
+      key = this.keys?.get(kid);
+    }
+    if (key === undefined) {
+      throw new Error(`key "${kid}" not found at ${this.endpoint}`);
+    }
+    return key;
+  }
+
+  /**
+   * Fetches the keys now. Concurrent calls share a single fetch.
+   */
+  refresh(): Promise<void> {
+    if (this.inflight === undefined) {
+      this.inflight = this.fetchKeys().finally(() => {
+        this.inflight = undefined;
+      });
+    }
+    return this.inflight;
+  }
+
+  private async fetchKeys(): Promise<void> {
+    const signed = this.rootKeys.length > 0;
+    const headers: Record<string, string> = { accept: signed ? SIGNED_JWKS_CONTENT_TYPE : 'application/json' };
+    // An expired key set must be replaced, so don't let the endpoint answer
+    // that it didn't change.
+    if (this.etag !== '' && this.keys !== undefined && !this.expired()) {
+      headers['if-none-match'] = this.etag;
+    }
+
+    const resp = await this.fetch(this.endpoint, { headers });
+    if (resp.status === 304 && this.keys !== undefined) {
+      if (this.expired()) {
+        throw new Error(`jwks endpoint ${this.endpoint} returned 304 for a signed jwks that expired`);
+      }
+      this.fetchedAt = this.now();
+      return;
+    }
+    if (resp.status !== 200) {
+      throw new Error(`failed to fetch jwks from ${this.endpoint}: unexpected status ${resp.status}`);
+    }
+
+    let served: JWK[];
+    let expiresAt = Number.POSITIVE_INFINITY;
+    if (signed) {
+      // An unsigned key set is what a substituted key set would look like.
+      const contentType = resp.headers.get('content-type') ?? '';
+      if (contentType.split(';')[0].trim().toLowerCase() !== SIGNED_JWKS_CONTENT_TYPE) {
+        throw new Error(
+          `jwks endpoint ${this.endpoint} returned content type "${contentType}", expected a signed jwks`,
+        );
+      }
+      ({ keys: served, expiresAt } = await verifySignedJWKSPayload(await resp.text(), this.rootKeys, this.now()));
+    } else {
+      const body: { keys?: JWK[] } = await resp.json();
+      served = body.keys ?? [];
+    }
+
+    const keys = new Map<string, CryptoKey>();
+    for (const jwk of served) {
+      // JVS tokens are signed with ES256, other keys can't verify them.
+      if (jwk.kid === undefined || jwk.kty !== 'EC' || jwk.crv !== 'P-256') {
+        continue;
+      }
+      const key = await crypto.subtle.importKey(
+        'jwk',
+        { kty: jwk.kty, crv: jwk.crv, x: jwk.x, y: jwk.y },
+        { name: 'ECDSA', namedCurve: 'P-256' },
+        false,
+        ['verify'],
+      );
+      keys.set(jwk.kid, key);
+    }
+
+    this.keys = keys;
+    this.etag = resp.headers.get('etag') ?? '';
+    this.expiresAt = expiresAt;
+    this.fetchedAt = this.now();
+  }
+
+  /** Reports whether the keys are a signed key set that expired. */
+  private expired(): boolean {
+    return this.now() >= this.expiresAt + signedJWKSClockSkew;
+  }
+}
+
+/**
+ * Verifies a signed key set, a JWT of type {@link SIGNED_JWKS_TYPE} whose
+ * "keys" claim is the key set, and returns its keys. It is signed with ES256
+ * by the root key in its "jwk" header, whose JWK thumbprint must be one of the
+ * root keys. It mirrors VerifySignedJWKS of the Go client.
+ */
+export async function verifySignedJWKS(signed: string, rootKeys: string[], now = Date.now()): Promise<JWK[]> {
+  return (await verifySignedJWKSPayload(signed, rootKeys, now)).keys;
+}
+
+/** Verifies a signed key set, and returns its keys and expiry in milliseconds. */
+async function verifySignedJWKSPayload(
+  signed: string,
+  rootKeys: string[],
+  now: number,
+): Promise<{ keys: JWK[]; expiresAt: number }> {
+  const parts = signed.split('.');
+  if (parts.length !== 3) {
+    throw new Error('failed to parse signed jwks: not a compact jws');
+  }
+  let header: { typ?: string; alg?: string; jwk?: JWK };
+  let payload: { keys?: JWK[]; iat?: number; exp?: number };
+  try {
+    header = JSON.parse(decodeText(parts[0]));
+    payload = JSON.parse(decodeText(parts[1]));
+  } catch (err) {
+    throw new Error(`failed to parse signed jwks: ${err}`);
+  }
+
+  if (header.typ?.toLowerCase() !== SIGNED_JWKS_TYPE) {
+    throw new Error(`expected signed jwks type "${SIGNED_JWKS_TYPE}", got "${header.typ ?? ''}"`);
+  }
+  if (header.alg !== 'ES256') {
+    throw new Error(`expected signed jwks algorithm "ES256", got "${header.alg ?? ''}"`);
+  }
+  const root = header.jwk;
+  if (root === undefined || root.kty !== 'EC' || root.crv !== 'P-256') {
+    throw new Error('signed jwks has no p-256 jwk header');
+  }
+  const thumbprint = await jwkThumbprint(root);
+  if (!rootKeys.includes(thumbprint)) {
+    throw new Error(`signed jwks root key "${thumbprint}" is not one of the pinned root keys`);
+  }
+
+  const key = await crypto.subtle.importKey(
+    'jwk',
+    { kty: root.kty, crv: root.crv, x: root.x, y: root.y },
+    { name: 'ECDSA', namedCurve: 'P-256' },
+    false,
+    ['verify'],
+  );
+  const verified = await crypto.subtle.verify(
+    { name: 'ECDSA', hash: 'SHA-256' },
+    key,
+    decodeBase64URL(parts[2]),
+    new TextEncoder().encode(`${parts[0]}.${parts[1]}`),
+  );
+  if (!verified) {
+    throw new Error('failed to verify signed jwks: invalid signature');
+  }
+
+  if (payload.exp === undefined) {
+    throw new Error('signed jwks has no exp claim');
+  }
+  if (now >= payload.exp * 1000 + signedJWKSClockSkew) {
+    throw new Error('signed jwks is expired');
+  }
+  if (payload.iat !== undefined && now < payload.iat * 1000 - signedJWKSClockSkew) {
+    throw new Error('signed jwks was issued in the future');
+  }
+  if (!Array.isArray(payload.keys)) {
+    throw new Error('signed jwks has no keys claim');
+  }
+  return { keys: payload.keys, expiresAt: payload.exp * 1000 };
+}
+
+/** Returns the base64url encoded SHA-256 JWK thumbprint (RFC 7638) of the EC key. */
+export async function jwkThumbprint(jwk: JWK): Promise<string> {
+  // The required members in lexicographic order, without whitespace.
+  const canonical = JSON.stringify({ crv: jwk.crv, kty: jwk.kty, x: jwk.x, y: jwk.y });
+  return encodeBase64URL(await crypto.subtle.digest('SHA-256', new TextEncoder().encode(canonical)));
+}
@@ -0,0 +1,332 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import assert from 'node:assert/strict';
+import { createServer, Server } from 'node:http';
+import { AddressInfo } from 'node:net';
+import { after, before, describe, it } from 'node:test';
+
+import {
+  BREAKGLASS_HMAC_SECRET,
+  Client,
+  getJustifications,
+  getRequestor,
+  JWK,
+  jwkThumbprint,
+  JWKSProvider,
+  SIGNED_JWKS_CONTENT_TYPE,
+  SIGNED_JWKS_TYPE,
+  verifySignedJWKS,
+} from '../src/index.js';
+
+interface TestKey {
+  kid: string;
+  privateKey: CryptoKey;
+  jwk: JWK;
+}
+
+async function newKey(kid: string): Promise<TestKey> {
+  const pair = await crypto.subtle.generateKey({ name: 'ECDSA', namedCurve: 'P-256' }, true, ['sign', 'verify']);
+  const jwk = await crypto.subtle.exportKey('jwk', pair.publicKey);
+  return {
+    kid,
+    privateKey: pair.privateKey,
+    jwk: { kty: 'EC', crv: 'P-256', x: jwk.x, y: jwk.y, kid },
+  };
+}
+
+function encode(v: unknown): string {
+  return Buffer.from(JSON.stringify(v)).toString('base64url');
+}
+
+async function sign(key: TestKey, claims: Record<string, unknown>, header: Record<string, unknown> = {}): Promise<string> {
+  const input = `${encode({ alg: 'ES256', kid: key.kid, typ: 'JWT', ...header })}.${encode(claims)}`;
+  const sig = await crypto.subtle.sign({ name: 'ECDSA', hash: 'SHA-256' }, key.privateKey, new TextEncoder().encode(input));
+  return `${input}.${Buffer.from(sig).toString('base64url')}`;
+}
+
+async function signKeySet(root: TestKey, keys: JWK[], claims: Record<string, unknown> = {}, typ = SIGNED_JWKS_TYPE): Promise<string> {
+  const now = Math.floor(Date.now() / 1000);
+  const { kid: _, ...jwk } = root.jwk;
+  const input = `${encode({ alg: 'ES256', typ, jwk })}.${encode({ keys, iat: now, exp: now + 3600, ...claims })}`;
+  const sig = await crypto.subtle.sign({ name: 'ECDSA', hash: 'SHA-256' }, root.privateKey, new TextEncoder().encode(input));
+  return `${input}.${Buffer.from(sig).toString('base64url')}`;
+}
+
+async function signBreakglass(claims: Record<string, unknown>): Promise<string> {
+  const key = await crypto.subtle.importKey(
+    'raw',
+    new TextEncoder().encode(BREAKGLASS_HMAC_SECRET),
+    { name: 'HMAC', hash: 'SHA-256' },
+    false,
+    ['sign'],
+  );
+  const input = `${encode({ alg: 'HS256', typ: 'JWT' })}.${encode(claims)}`;
+  const sig = await crypto.subtle.sign('HMAC', key, new TextEncoder().encode(input));
+  return `${input}.${Buffer.from(sig).toString('base64url')}`;
+}
+
+function validClaims(overrides: Record<string, unknown> = {}): Record<string, unknown> {
+  const now = Math.floor(Date.now() / 1000);
+  return {
+    iss: 'jvs.abcxyz.dev',
+    sub: 'me@example.com',
+    req: 'me@example.com',
+    aud: ['dev.abcxyz.jvs'],
+    iat: now,
+    nbf: now,
+    exp: now + 60,
+    jti: 'test-jti',
+    justs: [{ category: 'jira', value: 'ABC-123', annotation: { jira_issue_url: 'https://jira/ABC-123' } }],
+    ...overrides,
+  };
+}
+
+describe('Client', () => {
+  let server: Server;
+  let endpoint: string;
+  let served: JWK[] = [];
+  let key1: TestKey;
+  let key2: TestKey;
+
+  before(async () => {
+    key1 = await newKey('key1');
+    key2 = await newKey('key2');
+    served = [key1.jwk];
+
+    server = createServer((_req, res) => {
+      res.setHeader('content-type', 'application/json');
+      res.end(JSON.stringify({ keys: served }));
+    });
+    await new Promise<void>((resolve) => server.listen(0, '127.0.0.1', resolve));
+    endpoint = `http://127.0.0.1:${(server.address() as AddressInfo).port}/.well-known/jwks`;
+  });
+
+  after(() => {
+    server.close();
+  });
+
+  it('validates tokens and decodes their justifications', async () => {
+    const client = new Client({ jwksEndpoint: endpoint });
+    const claims = await client.validateJWT(await sign(key1, validClaims()), 'me@example.com');
+
+    assert.equal(claims.jti, 'test-jti');
+    assert.equal(getRequestor(claims), 'me@example.com');
+    assert.deepEqual(getJustifications(claims), [
+      { category: 'jira', value: 'ABC-123', annotation: { jira_issue_url: 'https://jira/ABC-123' } },
+    ]);
+  });
+
+  it('refetches the keys for an unknown key id', async () => {
+    const client = new Client({ jwksEndpoint: endpoint });
+    await client.validateJWT(await sign(key1, validClaims()));
+
+    // The key is rotated after the keys were fetched.
+    served = [key1.jwk, key2.jwk];
+    const claims = await client.validateJWT(await sign(key2, validClaims()));
+    assert.equal(claims.sub, 'me@example.com');
+  });
+
+  const rejected: { name: string; token: () => Promise<string>; want: RegExp; config?: object; subject?: string }[] = [
+    {
+      name: 'expired',
+      token: () => sign(key1, validClaims({ exp: Math.floor(Date.now() / 1000) - 60 })),
+      want: /jwt is expired/,
+    },
+    {
+      name: 'not valid yet',
+      token: () => sign(key1, validClaims({ nbf: Math.floor(Date.now() / 1000) + 60 })),
+      want: /jwt is not valid yet/,
+    },
+    {
+      name: 'no expiry',
+      token: () => sign(key1, validClaims({ exp: undefined })),
+      want: /jwt has no exp claim/,
+    },
+    {
+      name: 'no justifications',
+      token: () => sign(key1, validClaims({ justs: undefined })),
+      want: /jwt has no justs or justs_enc claim/,
+    },
+    {
+      name: 'receipt',
+      token: () => sign(key1, validClaims(), { typ: 'jvs-receipt+jwt' }),
+      want: /token type "jvs-receipt\+jwt" is not a jwt/,
+    },
+    {
+      name: 'unknown key',
+      token: async () => sign(await newKey('key3'), validClaims()),
+      want: /key "key3" not found/,
+    },
+    {
+      name: 'wrong signature',
+      token: async () => sign({ ...(await newKey('other')), kid: 'key1' }, validClaims()),
+      want: /invalid signature/,
+    },
+    {
+      name: 'wrong subject',
+      token: () => sign(key1, validClaims()),
+      subject: 'you@example.com',
+      want: /subject "me@example.com" does not match expected subject "you@example.com"/,
+    },
+    {
+      name: 'issuer not accepted',
+      token: () => sign(key1, validClaims()),
+      config: { acceptedIssuers: ['jvs.corp'] },
+      want: /issuer "jvs.abcxyz.dev" is not one of the accepted issuers/,
+    },
+    {
+      name: 'breakglass not allowed',
+      token: () => signBreakglass(validClaims({ justs: [{ category: 'breakglass', value: 'outage' }] })),
+      want: /breakglass is forbidden/,
+    },
+    {
+      name: 'breakglass without justification',
+      token: () => signBreakglass(validClaims()),
+      config: { allowBreakglass: true },
+      want: /missing breakglass justification/,
+    },
+  ];
+  for (const tc of rejected) {
+    it(`rejects tokens: ${tc.name}`, async () => {
+      const client = new Client({ jwksEndpoint: endpoint, ...tc.config });
+      await assert.rejects(client.validateJWT(await tc.token(), tc.subject), tc.want);
+    });
+  }
+
+  it('accepts breakglass tokens if allowed', async () => {
+    const client = new Client({ jwksEndpoint: endpoint, allowBreakglass: true });
+    const claims = await client.validateJWT(
+      await signBreakglass(validClaims({ iss: 'jvsctl', justs: [{ category: 'breakglass', value: 'outage' }] })),
+    );
+    assert.deepEqual(getJustifications(claims), [{ category: 'breakglass', value: 'outage' }]);
+  });
+
+  it('rejects newer claim versions', () => {
+    assert.throws(() => getJustifications({ jvs_ver: 2, justs: [] }), /unsupported claim version 2/);
+  });
+});
+
+describe('JWKSProvider', () => {
+  it('refreshes stale keys with conditional requests and keeps them on failure', async () => {
+    const key = await newKey('key1');
+    let now = 0;
+    let requests: (string | null)[] = [];
+    let fail = false;
+
+    const provider = new JWKSProvider('https://jvs.test/.well-known/jwks', {
+      refreshInterval: 1000,
+      now: () => now,
+      fetch: async (_url, init) => {
+        const etag = new Headers(init?.headers).get('if-none-match');
+        requests.push(etag);
+        if (fail) {
+          return new Response('unavailable', { status: 503 });
+        }
+        if (etag === '"v1"') {
+          return new Response(null, { status: 304 });
+        }
+        return new Response(JSON.stringify({ keys: [key.jwk] }), { headers: { etag: '"v1"' } });
+      },
+    });
+
+    await provider.getKey('key1');
+    await provider.getKey('key1');
+    assert.deepEqual(requests, [null]);
+
+    now = 1000;
+    await provider.getKey('key1');
+    assert.deepEqual(requests, [null, '"v1"']);
+
+    now = 2000;
+    fail = true;
+    requests = [];
+    await provider.getKey('key1');
+    assert.deepEqual(requests, ['"v1"']);
+  });
+});
+
+describe('signed key sets', () => {
+  it('verifies key sets signed by a pinned root key', async () => {
+    const root = await newKey('root');
+    const key = await newKey('key1');
+    const pinned = [await jwkThumbprint(root.jwk)];
+
+    assert.deepEqual(await verifySignedJWKS(await signKeySet(root, [key.jwk]), pinned), [key.jwk]);
+
+    const other = await newKey('other');
+    await assert.rejects(verifySignedJWKS(await signKeySet(other, [key.jwk]), pinned), /not one of the pinned root keys/);
+    await assert.rejects(verifySignedJWKS(await signKeySet(root, [key.jwk], {}, 'JWT'), pinned), /expected signed jwks type/);
+    await assert.rejects(verifySignedJWKS(await signKeySet(root, [key.jwk], { exp: 1 }), pinned), /signed jwks is expired/);
+
+    // The header names the pinned key, but another key signed the set.
+    const signed = (await signKeySet(other, [key.jwk])).split('.');
+    const header = (await signKeySet(root, [key.jwk])).split('.')[0];
+    await assert.rejects(verifySignedJWKS(`${header}.${signed[1]}.${signed[2]}`, pinned), /invalid signature/);
+  });
+
+  it('rejects unsigned key sets when root keys are pinned', async () => {
+    const root = await newKey('root');
+    const key = await newKey('key1');
+    let body = await signKeySet(root, [key.jwk]);
+    let contentType = SIGNED_JWKS_CONTENT_TYPE;
+    let accept: string | null = null;
+
+    const provider = new JWKSProvider('https://jvs.test/.well-known/jwks', {
+      rootKeys: [await jwkThumbprint(root.jwk)],
+      minRefetchInterval: 0,
+      fetch: async (_url, init) => {
+        accept = new Headers(init?.headers).get('accept');
+        return new Response(body, { headers: { 'content-type': contentType } });
+      },
+    });
+
+    await provider.getKey('key1');
+    assert.equal(accept, SIGNED_JWKS_CONTENT_TYPE);
+
+    body = JSON.stringify({ keys: [key.jwk] });
+    contentType = 'application/json';
+    await assert.rejects(provider.refresh(), /expected a signed jwks/);
+  });
+
+  it('rejects expired key sets that the endpoint answers 304 for', async () => {
+    const root = await newKey('root');
+    const key = await newKey('key1');
+    const body = await signKeySet(root, [key.jwk]);
+    let now = Date.now();
+    let served = false;
+
+    // The endpoint serves the signed key set once, then answers 304 to every
+    // request, like a replaying attacker would.
+    const provider = new JWKSProvider('https://jvs.test/.well-known/jwks', {
+      rootKeys: [await jwkThumbprint(root.jwk)],
+      now: () => now,
+      fetch: async () => {
+        if (served) {
+          return new Response(null, { status: 304 });
+        }
+        served = true;
+        return new Response(body, { headers: { 'content-type': SIGNED_JWKS_CONTENT_TYPE, etag: '"v1"' } });
+      },
+    });
+
+    await provider.getKey('key1');
+    await provider.refresh();
+    await provider.getKey('key1');
+
+    now += 2 * 3600 * 1000;
+    await assert.rejects(provider.getKey('key1'), /returned 304 for a signed jwks that expired/);
+    await assert.rejects(provider.getKey('key1'), /returned 304 for a signed jwks that expired/);
+  });
+});
@@ -0,0 +1,70 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command jvs-plugin-cloudsupport is a validator plugin for the "cloudsupport"
+// justification category. Install it in the JVS plugin directory.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/cloudsupport"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/grpcserver"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// category must match the binary name, without the "jvs-plugin-" prefix.
+const category = "cloudsupport"
+
+func main() {
+	if err := realMain(context.Background(), os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func realMain(ctx context.Context, args []string) error {
+	cfg := &config.CloudSupportConfig{}
+	set := cfg.ToFlags(cli.NewFlagSet())
+	if err := set.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	validator, err := cloudsupport.NewValidator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: jvspb.Handshake,
+		Plugins: map[string]plugin.Plugin{
+			category: &jvspb.ValidatorPlugin{Impl: validator},
+		},
+		GRPCServer: func(opts []grpc.ServerOption) *grpc.Server {
+			return grpcserver.NewChain(logging.NewFromEnv("JVS_"), "").NewServer(opts...)
+		},
+	})
+	return nil
+}
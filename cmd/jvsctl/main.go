@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -36,7 +37,13 @@ func main() {
 	if err := realMain(ctx); err != nil {
 		done()
 		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+
+		code := 1
+		var exitErr *cli.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.Code
+		}
+		os.Exit(code)
 	}
 }
 
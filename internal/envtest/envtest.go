@@ -29,6 +29,7 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/abcxyz/jvs/assets"
+	"github.com/abcxyz/jvs/pkg/audit"
 	"github.com/abcxyz/jvs/pkg/config"
 	"github.com/abcxyz/jvs/pkg/justification"
 	"github.com/abcxyz/jvs/pkg/jvscrypto"
@@ -44,6 +45,9 @@ type ServerConfigResponse struct {
 	Config    *config.UIServiceConfig
 	Renderer  *renderer.Renderer
 	Processor *justification.Processor
+
+	// AuditSink has the audit entries of the tokens issued by the processor.
+	AuditSink *audit.MemorySink
 }
 
 // BuildFormRequest builds an http request and http response recorder for the
@@ -125,11 +129,16 @@ func NewServerConfig(tb testing.TB, port string, allowlist []string, devMode boo
 		tb.Fatal(err)
 	}
 
-	p := justification.NewProcessor(kmsClient, uiCfg.JustificationConfig)
+	sink, err := audit.NewMemorySink("")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p := justification.NewProcessor(kmsClient, uiCfg.JustificationConfig).WithAuditSink(sink)
 
 	return &ServerConfigResponse{
 		Config:    uiCfg,
 		Renderer:  r,
 		Processor: p,
+		AuditSink: sink,
 	}
 }
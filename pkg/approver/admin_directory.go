@@ -0,0 +1,111 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+var _ Directory = (*AdminDirectory)(nil)
+
+// AdminDirectory is a [Directory] backed by the Google Workspace Admin SDK
+// Directory API. The caller needs read access to users and groups, e.g. through
+// the "Groups Reader" and "User Management" admin roles.
+type AdminDirectory struct {
+	service *admin.Service
+}
+
+// NewAdminDirectory creates a new directory with the Admin SDK client options.
+func NewAdminDirectory(ctx context.Context, opts ...option.ClientOption) (*AdminDirectory, error) {
+	opts = append([]option.ClientOption{
+		option.WithScopes(admin.AdminDirectoryUserReadonlyScope, admin.AdminDirectoryGroupReadonlyScope),
+	}, opts...)
+	service, err := admin.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin directory client: %w", err)
+	}
+	return &AdminDirectory{service: service}, nil
+}
+
+// Managers implements [Directory].
+func (d *AdminDirectory) Managers(ctx context.Context, email string) ([]string, error) {
+	user, err := d.service.Users.Get(email).
+		ViewType("admin_view").
+		Fields("relations").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, directoryError(err)
+	}
+	return managers(user.Relations)
+}
+
+// Groups implements [Directory].
+func (d *AdminDirectory) Groups(ctx context.Context, email string) ([]string, error) {
+	var groups []string
+	if err := d.service.Groups.List().
+		UserKey(email).
+		Fields("nextPageToken", "groups/email").
+		Pages(ctx, func(page *admin.Groups) error {
+			for _, g := range page.Groups {
+				groups = append(groups, g.Email)
+			}
+			return nil
+		}); err != nil {
+		return nil, directoryError(err)
+	}
+	return groups, nil
+}
+
+// managers returns the manager emails from a user's relations, which the
+// client library leaves untyped.
+func managers(relations any) ([]string, error) {
+	if relations == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(relations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal relations: %w", err)
+	}
+	var parsed []*admin.UserRelation
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse relations: %w", err)
+	}
+
+	var out []string
+	for _, r := range parsed {
+		if r.Type == RelationManager && r.Value != "" {
+			out = append(out, r.Value)
+		}
+	}
+	return out, nil
+}
+
+func directoryError(err error) error {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusNotFound {
+		return ErrUserNotFound
+	}
+	return fmt.Errorf("admin directory request failed: %w", err)
+}
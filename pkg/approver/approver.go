@@ -0,0 +1,185 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package approver provides the built-in "approver" justification validator,
+// which checks the approver against the requestor in the organization's
+// directory.
+package approver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+const (
+	// Category is the justification category of approvals. The justification
+	// value is the email of the approver.
+	Category    = "approver"
+	DisplayName = "Approver"
+	Hint        = "The email of your manager, or of a member of a group you are in, who approved the request."
+
+	// Annotations set on valid justifications. The relation is either
+	// RelationManager or RelationGroup, in which case the shared group is also
+	// annotated.
+	AnnotationApprover = "approver"
+	AnnotationRelation = "approver_relation"
+	AnnotationGroup    = "approver_group"
+
+	RelationManager = "manager"
+	RelationGroup   = "group"
+)
+
+// ErrUserNotFound is returned by a [Directory] if the user does not exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// Directory looks up users in the organization's directory.
+type Directory interface {
+	// Managers returns the emails of the user's managers.
+	Managers(ctx context.Context, email string) ([]string, error)
+
+	// Groups returns the emails of the groups the user is a member of.
+	Groups(ctx context.Context, email string) ([]string, error)
+}
+
+var _ jvspb.Validator = (*Validator)(nil)
+
+// Validator validates that the approver manages or shares a group with the
+// requestor.
+type Validator struct {
+	directory Directory
+
+	// groups are the groups that approvers may share with the requestor. If
+	// empty, any group counts.
+	groups []string
+}
+
+// NewValidator creates a new validator that looks up users in the directory.
+// If groups are given, only those groups count as shared groups, since
+// organization-wide groups would otherwise allow anyone to approve.
+func NewValidator(directory Directory, groups []string) *Validator {
+	normalized := make([]string, 0, len(groups))
+	for _, g := range groups {
+		normalized = append(normalized, normalizeEmail(g))
+	}
+	return &Validator{
+		directory: directory,
+		groups:    normalized,
+	}
+}
+
+// Validate implements [jvspb.Validator].
+func (v *Validator) Validate(ctx context.Context, req *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	addr, err := mail.ParseAddress(req.GetJustification().GetValue())
+	if err != nil {
+		return invalid("approver must be an email address"), nil
+	}
+	approver := normalizeEmail(addr.Address)
+
+	requestor := normalizeEmail(req.GetRequestor())
+	if requestor == "" {
+		return invalid("requestor is unknown"), nil
+	}
+	if approver == requestor {
+		return invalid("requestor cannot approve their own request"), nil
+	}
+
+	managers, err := v.directory.Managers(ctx, requestor)
+	if errors.Is(err, ErrUserNotFound) {
+		return invalid(fmt.Sprintf("requestor %q not found in the directory", requestor)), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up managers of %q: %w", requestor, err)
+	}
+	for _, m := range managers {
+		if normalizeEmail(m) == approver {
+			return valid(approver, RelationManager, ""), nil
+		}
+	}
+
+	requestorGroups, err := v.directory.Groups(ctx, requestor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up groups of %q: %w", requestor, err)
+	}
+	approverGroups, err := v.directory.Groups(ctx, approver)
+	if errors.Is(err, ErrUserNotFound) {
+		return invalid(fmt.Sprintf("approver %q not found in the directory", approver)), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up groups of %q: %w", approver, err)
+	}
+	if group := v.sharedGroup(requestorGroups, approverGroups); group != "" {
+		return valid(approver, RelationGroup, group), nil
+	}
+
+	return invalid(fmt.Sprintf("approver %q does not manage or share a group with the requestor", approver)), nil
+}
+
+// GetUIData implements [jvspb.Validator].
+func (v *Validator) GetUIData(_ context.Context, _ *jvspb.GetUIDataRequest) (*jvspb.UIData, error) {
+	return &jvspb.UIData{
+		DisplayName: DisplayName,
+		Hint:        Hint,
+	}, nil
+}
+
+// sharedGroup returns the first group, in sorted order, that both users are
+// members of and counts as a shared group. It returns "" if there is none.
+func (v *Validator) sharedGroup(a, b []string) string {
+	shared := make([]string, 0, len(a))
+	for _, g := range a {
+		g = normalizeEmail(g)
+		if len(v.groups) > 0 && !slices.Contains(v.groups, g) {
+			continue
+		}
+		if slices.ContainsFunc(b, func(o string) bool { return normalizeEmail(o) == g }) {
+			shared = append(shared, g)
+		}
+	}
+	if len(shared) == 0 {
+		return ""
+	}
+	slices.Sort(shared)
+	return shared[0]
+}
+
+func normalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func valid(approver, relation, group string) *jvspb.ValidateJustificationResponse {
+	annotation := map[string]string{
+		AnnotationApprover: approver,
+		AnnotationRelation: relation,
+	}
+	if group != "" {
+		annotation[AnnotationGroup] = group
+	}
+	return &jvspb.ValidateJustificationResponse{
+		Valid:      true,
+		Annotation: annotation,
+	}
+}
+
+func invalid(msg string) *jvspb.ValidateJustificationResponse {
+	return &jvspb.ValidateJustificationResponse{
+		Valid: false,
+		Error: []string{msg},
+	}
+}
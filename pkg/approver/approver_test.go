@@ -0,0 +1,229 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+type fakeDirectory struct {
+	managers map[string][]string
+	groups   map[string][]string
+	err      error
+}
+
+func (d *fakeDirectory) Managers(ctx context.Context, email string) ([]string, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if _, ok := d.groups[email]; !ok {
+		return nil, ErrUserNotFound
+	}
+	return d.managers[email], nil
+}
+
+func (d *fakeDirectory) Groups(ctx context.Context, email string) ([]string, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	groups, ok := d.groups[email]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return groups, nil
+}
+
+func TestValidator_Validate(t *testing.T) {
+	t.Parallel()
+
+	directory := &fakeDirectory{
+		managers: map[string][]string{
+			"me@example.com": {"Boss@example.com"},
+		},
+		groups: map[string][]string{
+			"me@example.com":    {"all@example.com", "sre@example.com", "oncall@example.com"},
+			"boss@example.com":  {"all@example.com"},
+			"peer@example.com":  {"all@example.com", "SRE@example.com", "oncall@example.com"},
+			"other@example.com": {"all@example.com"},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		directory *fakeDirectory
+		groups    []string
+		value     string
+		requestor string
+		wantResp  *jvspb.ValidateJustificationResponse
+		wantErr   string
+	}{
+		{
+			name:      "manager",
+			directory: directory,
+			value:     "boss@example.com",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: true,
+				Annotation: map[string]string{
+					AnnotationApprover: "boss@example.com",
+					AnnotationRelation: RelationManager,
+				},
+			},
+		},
+		{
+			name:      "shared_group",
+			directory: directory,
+			value:     "Peer <peer@example.com>",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: true,
+				Annotation: map[string]string{
+					AnnotationApprover: "peer@example.com",
+					AnnotationRelation: RelationGroup,
+					AnnotationGroup:    "all@example.com",
+				},
+			},
+		},
+		{
+			name:      "shared_allowed_group",
+			directory: directory,
+			groups:    []string{"SRE@example.com"},
+			value:     "peer@example.com",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: true,
+				Annotation: map[string]string{
+					AnnotationApprover: "peer@example.com",
+					AnnotationRelation: RelationGroup,
+					AnnotationGroup:    "sre@example.com",
+				},
+			},
+		},
+		{
+			name:      "no_shared_allowed_group",
+			directory: directory,
+			groups:    []string{"sre@example.com"},
+			value:     "other@example.com",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{`approver "other@example.com" does not manage or share a group with the requestor`},
+			},
+		},
+		{
+			name:      "self_approval",
+			directory: directory,
+			value:     "ME@example.com",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{"requestor cannot approve their own request"},
+			},
+		},
+		{
+			name:      "not_an_email",
+			directory: directory,
+			value:     "my boss",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{"approver must be an email address"},
+			},
+		},
+		{
+			name:      "no_requestor",
+			directory: directory,
+			value:     "boss@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{"requestor is unknown"},
+			},
+		},
+		{
+			name:      "requestor_not_found",
+			directory: directory,
+			value:     "boss@example.com",
+			requestor: "sa@project.iam.gserviceaccount.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{`requestor "sa@project.iam.gserviceaccount.com" not found in the directory`},
+			},
+		},
+		{
+			name:      "approver_not_found",
+			directory: directory,
+			value:     "nobody@example.com",
+			requestor: "me@example.com",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{`approver "nobody@example.com" not found in the directory`},
+			},
+		},
+		{
+			name:      "directory_error",
+			directory: &fakeDirectory{err: fmt.Errorf("rate limited")},
+			value:     "boss@example.com",
+			requestor: "me@example.com",
+			wantErr:   "rate limited",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := NewValidator(tc.directory, tc.groups)
+			gotResp, err := v.Validate(context.Background(), &jvspb.ValidateJustificationRequest{
+				Justification: &jvspb.Justification{
+					Category: Category,
+					Value:    tc.value,
+				},
+				Requestor: tc.requestor,
+			})
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.wantResp, gotResp, protocmp.Transform()); diff != "" {
+				t.Errorf("response (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestManagers(t *testing.T) {
+	t.Parallel()
+
+	relations := []any{
+		map[string]any{"type": "manager", "value": "boss@example.com"},
+		map[string]any{"type": "assistant", "value": "assistant@example.com"},
+		map[string]any{"type": "manager", "value": "dotted@example.com"},
+	}
+
+	got, err := managers(relations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"boss@example.com", "dotted@example.com"}, got); diff != "" {
+		t.Errorf("managers (-want, +got):\n%s", diff)
+	}
+}
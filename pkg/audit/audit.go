@@ -0,0 +1,163 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit defines the audit log entries written for issued justification
+// tokens, and how to search for them.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+const (
+	// LogKey is the structured logging key the audit entry is logged under. In
+	// Cloud Logging, entries are found under "jsonPayload.jvs_audit".
+	LogKey = "jvs_audit"
+
+	// LogMessage is the log message of audit entries.
+	LogMessage = "issued justification token"
+)
+
+// Entry is the audit record of an issued justification token.
+type Entry struct {
+	// ID is the token's "jti".
+	ID string `json:"jti"`
+
+	// Requestor is the authenticated principal that requested the token.
+	Requestor string `json:"requestor"`
+
+	// Subject is the token's "sub".
+	Subject string `json:"subject,omitempty"`
+
+	// Categories are the categories of the justifications, denormalized so they
+	// can be filtered on.
+	Categories []string `json:"categories"`
+
+	// Justifications are the justifications in the token.
+	Justifications []*Justification `json:"justifications"`
+
+	// Audiences are the token's "aud".
+	Audiences []string `json:"audiences"`
+
+	// IssuedAt and ExpiresAt are the token's "iat" and "exp".
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Redacted indicates the justification values were removed, because they
+	// were encrypted in the token.
+	Redacted bool `json:"redacted,omitempty"`
+
+	// Receipt is the signed receipt of the token, if the JVS issues receipts.
+	Receipt string `json:"receipt,omitempty"`
+
+	// Transparency is the proof the entry's [Entry.Digest] was published to a
+	// transparency log, if the JVS publishes to one.
+	Transparency *Transparency `json:"transparency,omitempty"`
+}
+
+// Transparency is the record of an entry's digest in a transparency log. Only
+// the digest is published, never the entry itself.
+type Transparency struct {
+	// Digest is the hex-encoded SHA-256 digest of the entry that was published.
+	Digest string `json:"digest"`
+
+	// LogURL is the URL of the log, and UUID, LogID and LogIndex identify the
+	// log entry.
+	LogURL   string `json:"log_url"`
+	UUID     string `json:"uuid"`
+	LogID    string `json:"log_id"`
+	LogIndex int64  `json:"log_index"`
+
+	// IntegratedTime is when the log entry was added to the log.
+	IntegratedTime time.Time `json:"integrated_time"`
+
+	// SignedEntryTimestamp is the base64-encoded signature of the log over the
+	// log entry, a promise to include it.
+	SignedEntryTimestamp string `json:"signed_entry_timestamp,omitempty"`
+
+	// InclusionProof proves the log entry is included in the log.
+	InclusionProof *InclusionProof `json:"inclusion_proof,omitempty"`
+}
+
+// InclusionProof is the Merkle tree inclusion proof of a transparency log
+// entry.
+type InclusionProof struct {
+	LogIndex   int64    `json:"log_index"`
+	TreeSize   int64    `json:"tree_size"`
+	RootHash   string   `json:"root_hash"`
+	Hashes     []string `json:"hashes"`
+	Checkpoint string   `json:"checkpoint,omitempty"`
+}
+
+// Digest returns the SHA-256 digest of the JSON encoding of the entry, without
+// its transparency record. It is what's published to transparency logs, so an
+// entry read back from the audit log can be checked against the log.
+func (e *Entry) Digest() ([]byte, error) {
+	c := *e
+	c.Transparency = nil
+
+	b, err := json.Marshal(&c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// RedactValues removes the justification values and annotations, keeping
+// their categories.
+func (e *Entry) RedactValues() {
+	for _, j := range e.Justifications {
+		j.Value = ""
+		j.Annotation = nil
+	}
+	e.Redacted = true
+}
+
+// Justification is a justification in an audit entry.
+type Justification struct {
+	Category   string            `json:"category"`
+	Value      string            `json:"value"`
+	Annotation map[string]string `json:"annotation,omitempty"`
+}
+
+// NewEntry builds the audit entry for the given token.
+func NewEntry(requestor string, token jwt.Token, justifications []*jvspb.Justification) *Entry {
+	e := &Entry{
+		ID:             token.JwtID(),
+		Requestor:      requestor,
+		Subject:        token.Subject(),
+		Categories:     make([]string, 0, len(justifications)),
+		Justifications: make([]*Justification, 0, len(justifications)),
+		Audiences:      token.Audience(),
+		IssuedAt:       token.IssuedAt().UTC(),
+		ExpiresAt:      token.Expiration().UTC(),
+	}
+	for _, j := range justifications {
+		e.Categories = append(e.Categories, j.GetCategory())
+		e.Justifications = append(e.Justifications, &Justification{
+			Category:   j.GetCategory(),
+			Value:      j.GetValue(),
+			Annotation: j.GetAnnotation(),
+		})
+	}
+	return e
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEntry_Digest(t *testing.T) {
+	t.Parallel()
+
+	entry := &Entry{ID: "test-jti", Requestor: "me@example.com"}
+	want, err := entry.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(want), 32; got != want {
+		t.Errorf("expected digest length %d to be %d", got, want)
+	}
+
+	// The transparency record is added after publishing the digest, so it must
+	// not change the digest.
+	entry.Transparency = &Transparency{LogIndex: 42}
+	got, err := entry.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("expected digest with transparency record %x to be %x", got, want)
+	}
+	if entry.Transparency == nil {
+		t.Errorf("expected digest not to modify the entry")
+	}
+
+	other, err := (&Entry{ID: "other-jti", Requestor: "me@example.com"}).Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other, want) {
+		t.Errorf("expected digests of different entries to differ")
+	}
+}
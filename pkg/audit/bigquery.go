@@ -0,0 +1,217 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// maxInsertRows is the maximum number of rows inserted in one request.
+const maxInsertRows = 500
+
+// tableIDRegexp matches BigQuery table IDs, "project.dataset.table", capturing
+// each part.
+var tableIDRegexp = regexp.MustCompile(`^([a-z][a-z0-9.:-]*)\.([A-Za-z0-9_]+)\.([A-Za-z0-9_-]+)$`)
+
+// BigQuerySchema is the schema of the issuance table. Fields are only ever
+// added to it, so queries and dashboards built on it keep working.
+var BigQuerySchema = &bigquery.TableSchema{
+	Fields: []*bigquery.TableFieldSchema{
+		{Name: "jti", Type: "STRING", Mode: "REQUIRED", Description: "The token ID."},
+		{Name: "requestor", Type: "STRING", Mode: "REQUIRED", Description: "The principal that requested the token."},
+		{Name: "subject", Type: "STRING", Description: "The token subject."},
+		{Name: "categories", Type: "STRING", Mode: "REPEATED", Description: "The justification categories."},
+		{
+			Name:        "justifications",
+			Type:        "RECORD",
+			Mode:        "REPEATED",
+			Description: "The justifications, without values if redacted.",
+			Fields: []*bigquery.TableFieldSchema{
+				{Name: "category", Type: "STRING", Mode: "REQUIRED"},
+				{Name: "value", Type: "STRING"},
+				{Name: "annotations", Type: "JSON", Description: "The validator annotations."},
+			},
+		},
+		{Name: "audiences", Type: "STRING", Mode: "REPEATED", Description: "The token audiences."},
+		{Name: "issued_at", Type: "TIMESTAMP", Mode: "REQUIRED", Description: "When the token was issued."},
+		{Name: "expires_at", Type: "TIMESTAMP", Description: "When the token expires."},
+		{Name: "ttl_seconds", Type: "INTEGER", Description: "The token lifetime in seconds."},
+		{Name: "redacted", Type: "BOOLEAN", Description: "Whether the justification values were redacted."},
+	},
+}
+
+// Sink stores audit entries for analytics, in addition to the audit log.
+type Sink interface {
+	Write(ctx context.Context, entries ...*Entry) error
+}
+
+var _ Sink = (*BigQuerySink)(nil)
+
+// BigQuerySink streams audit entries into a BigQuery table partitioned by day
+// of issuance.
+type BigQuerySink struct {
+	service *bigquery.Service
+	project string
+	dataset string
+	table   string
+}
+
+// NewBigQuerySink creates a sink for the table, "project.dataset.table", with
+// the BigQuery API client options.
+func NewBigQuerySink(ctx context.Context, tableID string, opts ...option.ClientOption) (*BigQuerySink, error) {
+	project, dataset, table, err := ParseTableID(tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := bigquery.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bigquery client: %w", err)
+	}
+	return &BigQuerySink{
+		service: service,
+		project: project,
+		dataset: dataset,
+		table:   table,
+	}, nil
+}
+
+// ParseTableID splits a BigQuery table ID, "project.dataset.table", into its
+// parts.
+func ParseTableID(tableID string) (project, dataset, table string, err error) {
+	matches := tableIDRegexp.FindStringSubmatch(tableID)
+	if matches == nil {
+		return "", "", "", fmt.Errorf("table %q must be in the format project.dataset.table", tableID)
+	}
+	return matches[1], matches[2], matches[3], nil
+}
+
+// EnsureTable creates the table with [BigQuerySchema], partitioned by day on
+// "issued_at", if it does not exist.
+func (s *BigQuerySink) EnsureTable(ctx context.Context) error {
+	_, err := s.service.Tables.Insert(s.project, s.dataset, &bigquery.Table{
+		TableReference: &bigquery.TableReference{
+			ProjectId: s.project,
+			DatasetId: s.dataset,
+			TableId:   s.table,
+		},
+		Description: "Justification tokens issued by JVS.",
+		Schema:      BigQuerySchema,
+		TimePartitioning: &bigquery.TimePartitioning{
+			Type:  "DAY",
+			Field: "issued_at",
+		},
+	}).Context(ctx).Do()
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusConflict {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create table %s.%s.%s: %w", s.project, s.dataset, s.table, err)
+	}
+	return nil
+}
+
+// Write implements [Sink]. It inserts the entries into the table. The token IDs are used as insert
+// IDs, so retried and backfilled entries are deduplicated on a best-effort
+// basis.
+func (s *BigQuerySink) Write(ctx context.Context, entries ...*Entry) error {
+	for len(entries) > 0 {
+		n := min(len(entries), maxInsertRows)
+		if err := s.insert(ctx, entries[:n]); err != nil {
+			return err
+		}
+		entries = entries[n:]
+	}
+	return nil
+}
+
+func (s *BigQuerySink) insert(ctx context.Context, entries []*Entry) error {
+	req := &bigquery.TableDataInsertAllRequest{
+		Rows: make([]*bigquery.TableDataInsertAllRequestRows, 0, len(entries)),
+	}
+	for _, e := range entries {
+		row, err := bigQueryRow(e)
+		if err != nil {
+			return err
+		}
+		req.Rows = append(req.Rows, &bigquery.TableDataInsertAllRequestRows{
+			InsertId: e.ID,
+			Json:     row,
+		})
+	}
+
+	resp, err := s.service.Tabledata.InsertAll(s.project, s.dataset, s.table, req).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to insert rows: %w", err)
+	}
+	if len(resp.InsertErrors) > 0 {
+		msgs := make([]string, 0, len(resp.InsertErrors))
+		for _, ie := range resp.InsertErrors {
+			for _, e := range ie.Errors {
+				msgs = append(msgs, fmt.Sprintf("row %d: %s", ie.Index, e.Message))
+			}
+		}
+		return fmt.Errorf("failed to insert %d rows: %s", len(resp.InsertErrors), strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// bigQueryRow converts the entry to a row of [BigQuerySchema].
+func bigQueryRow(e *Entry) (map[string]bigquery.JsonValue, error) {
+	justifications := make([]map[string]any, 0, len(e.Justifications))
+	for _, j := range e.Justifications {
+		row := map[string]any{
+			"category": j.Category,
+			"value":    j.Value,
+		}
+		if len(j.Annotation) > 0 {
+			b, err := json.Marshal(j.Annotation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal annotations of %s: %w", e.ID, err)
+			}
+			row["annotations"] = string(b)
+		}
+		justifications = append(justifications, row)
+	}
+
+	row := map[string]bigquery.JsonValue{
+		"jti":            e.ID,
+		"requestor":      e.Requestor,
+		"subject":        e.Subject,
+		"categories":     e.Categories,
+		"justifications": justifications,
+		"audiences":      e.Audiences,
+		"issued_at":      e.IssuedAt.UTC().Format(time.RFC3339Nano),
+		"redacted":       e.Redacted,
+	}
+	if !e.ExpiresAt.IsZero() {
+		row["expires_at"] = e.ExpiresAt.UTC().Format(time.RFC3339Nano)
+		row["ttl_seconds"] = int64(e.ExpiresAt.Sub(e.IssuedAt).Seconds())
+	}
+	return row, nil
+}
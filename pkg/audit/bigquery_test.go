@@ -0,0 +1,252 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestParseTableID(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		tableID     string
+		wantProject string
+		wantDataset string
+		wantTable   string
+		wantErr     string
+	}{
+		{
+			name:        "valid",
+			tableID:     "my-project.jvs.issuances",
+			wantProject: "my-project",
+			wantDataset: "jvs",
+			wantTable:   "issuances",
+		},
+		{
+			name:        "domain_scoped_project",
+			tableID:     "example.com:my-project.jvs.issuances",
+			wantProject: "example.com:my-project",
+			wantDataset: "jvs",
+			wantTable:   "issuances",
+		},
+		{
+			name:    "missing_project",
+			tableID: "jvs.issuances",
+			wantErr: `table "jvs.issuances" must be in the format project.dataset.table`,
+		},
+		{
+			name:    "empty",
+			tableID: "",
+			wantErr: `table "" must be in the format project.dataset.table`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			project, dataset, table, err := ParseTableID(tc.tableID)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := project, tc.wantProject; got != want {
+				t.Errorf("project: expected %q to be %q", got, want)
+			}
+			if got, want := dataset, tc.wantDataset; got != want {
+				t.Errorf("dataset: expected %q to be %q", got, want)
+			}
+			if got, want := table, tc.wantTable; got != want {
+				t.Errorf("table: expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestBigQuerySink_EnsureTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{
+			name:   "created",
+			status: http.StatusOK,
+		},
+		{
+			name:   "exists",
+			status: http.StatusConflict,
+		},
+		{
+			name:    "server_error",
+			status:  http.StatusForbidden,
+			wantErr: "failed to create table p.d.t",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got bigquery.Table
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.URL.Path, "/projects/p/datasets/d/tables"; got != want {
+					t.Errorf("expected path %q to be %q", got, want)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(`{}`))
+			}))
+			t.Cleanup(srv.Close)
+
+			sink, err := NewBigQuerySink(ctx, "p.d.t", option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sink.EnsureTable(ctx)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := got.TimePartitioning.Field, "issued_at"; got != want {
+				t.Errorf("partitioning field: expected %q to be %q", got, want)
+			}
+			if got, want := len(got.Schema.Fields), len(BigQuerySchema.Fields); got != want {
+				t.Errorf("expected %d schema fields to be %d", got, want)
+			}
+		})
+	}
+}
+
+func TestBigQuerySink_Write(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	entry := &Entry{
+		ID:         "test-jti",
+		Requestor:  "you@example.com",
+		Categories: []string{"servicenow"},
+		Justifications: []*Justification{{
+			Category:   "servicenow",
+			Value:      "CHG0000001",
+			Annotation: map[string]string{"change_request": "CHG0000001"},
+		}},
+		Audiences: []string{"dev.abcxyz.jvs"},
+		IssuedAt:  time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2023, 6, 1, 12, 15, 0, 0, time.UTC),
+	}
+
+	cases := []struct {
+		name     string
+		status   int
+		response string
+		wantRows []map[string]any
+		wantErr  string
+	}{
+		{
+			name:     "success",
+			status:   http.StatusOK,
+			response: `{}`,
+			wantRows: []map[string]any{{
+				"insertId": "test-jti",
+				"json": map[string]any{
+					"jti":        "test-jti",
+					"requestor":  "you@example.com",
+					"subject":    "",
+					"categories": []any{"servicenow"},
+					"justifications": []any{map[string]any{
+						"category":    "servicenow",
+						"value":       "CHG0000001",
+						"annotations": `{"change_request":"CHG0000001"}`,
+					}},
+					"audiences":   []any{"dev.abcxyz.jvs"},
+					"issued_at":   "2023-06-01T12:00:00Z",
+					"expires_at":  "2023-06-01T12:15:00Z",
+					"ttl_seconds": float64(900),
+					"redacted":    false,
+				},
+			}},
+		},
+		{
+			name:     "insert_errors",
+			status:   http.StatusOK,
+			response: `{"insertErrors":[{"index":0,"errors":[{"message":"no such field"}]}]}`,
+			wantErr:  "failed to insert 1 rows: row 0: no such field",
+		},
+		{
+			name:     "server_error",
+			status:   http.StatusNotFound,
+			response: `{}`,
+			wantErr:  "failed to insert rows",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got struct {
+				Rows []map[string]any `json:"rows"`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.URL.Path, "/projects/p/datasets/d/tables/t/insertAll"; got != want {
+					t.Errorf("expected path %q to be %q", got, want)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			t.Cleanup(srv.Close)
+
+			sink, err := NewBigQuerySink(ctx, "p.d.t", option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sink.Write(ctx, entry)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantRows, got.Rows); diff != "" {
+				t.Errorf("rows (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
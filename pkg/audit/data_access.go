@@ -0,0 +1,159 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DataAccessLogID is the ID of the log data access audit logs are written
+	// to. It is the same log abcxyz/lumberjack writes data access logs to, so
+	// JVS requests land next to other audit trails.
+	DataAccessLogID = "audit.abcxyz/data_access"
+
+	// auditLogType is the type URL of the "protoPayload" of audit logs.
+	auditLogType = "type.googleapis.com/google.cloud.audit.AuditLog"
+)
+
+// Decisions recorded in the metadata of data access audit logs.
+const (
+	DecisionAllowed = "allowed"
+	DecisionDenied  = "denied"
+	DecisionError   = "error"
+)
+
+// AuditLog is the subset of the google.cloud.audit.AuditLog proto written for
+// JVS requests, in its JSON form.
+type AuditLog struct {
+	ServiceName        string              `json:"serviceName"`
+	MethodName         string              `json:"methodName"`
+	ResourceName       string              `json:"resourceName,omitempty"`
+	AuthenticationInfo *AuthenticationInfo `json:"authenticationInfo,omitempty"`
+	Status             *Status             `json:"status,omitempty"`
+
+	// Metadata holds the decision and method specific details, e.g. the
+	// justification categories.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// AuthenticationInfo is the caller identity of an [AuditLog].
+type AuthenticationInfo struct {
+	PrincipalEmail string `json:"principalEmail,omitempty"`
+}
+
+// Status is the google.rpc.Status of the request in an [AuditLog].
+type Status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Writer writes data access audit logs.
+type Writer interface {
+	Write(ctx context.Context, log *AuditLog) error
+}
+
+var _ Writer = (*CloudLoggingWriter)(nil)
+
+// CloudLoggingWriter writes data access audit logs to Cloud Logging.
+type CloudLoggingWriter struct {
+	client   *http.Client
+	endpoint string
+	project  string
+}
+
+// NewCloudLoggingWriter creates a writer for the data access audit log of the
+// given project. The client must add credentials with permission to write
+// logs.
+func NewCloudLoggingWriter(client *http.Client, endpoint, project string) *CloudLoggingWriter {
+	return &CloudLoggingWriter{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		project:  project,
+	}
+}
+
+type writeEntriesRequest struct {
+	Entries []*logEntry `json:"entries"`
+}
+
+type logEntry struct {
+	LogName      string             `json:"logName"`
+	Resource     *monitoredResource `json:"resource"`
+	Severity     string             `json:"severity"`
+	Timestamp    time.Time          `json:"timestamp"`
+	ProtoPayload *auditLogPayload   `json:"protoPayload"`
+}
+
+type monitoredResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+}
+
+// auditLogPayload is an [AuditLog] packed as a google.protobuf.Any.
+type auditLogPayload struct {
+	Type string `json:"@type"`
+	*AuditLog
+}
+
+// Write implements [Writer].
+func (w *CloudLoggingWriter) Write(ctx context.Context, log *AuditLog) error {
+	severity := "INFO"
+	if log.Status != nil && log.Status.Code != 0 {
+		severity = "WARNING"
+	}
+
+	b, err := json.Marshal(&writeEntriesRequest{
+		Entries: []*logEntry{{
+			LogName: "projects/" + w.project + "/logs/" + url.PathEscape(DataAccessLogID),
+			Resource: &monitoredResource{
+				Type:   "global",
+				Labels: map[string]string{"project_id": w.project},
+			},
+			Severity:     severity,
+			Timestamp:    time.Now().UTC(),
+			ProtoPayload: &auditLogPayload{Type: auditLogType, AuditLog: log},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint+"/v2/entries:write", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(r)
+	if err != nil {
+		return fmt.Errorf("failed to write log entry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return fmt.Errorf("failed to write log entry: unexpected status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCloudLoggingWriter_Write(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	log := &AuditLog{
+		ServiceName:        "abcxyz.jvs.JVSService",
+		MethodName:         "abcxyz.jvs.JVSService.CreateJustification",
+		ResourceName:       "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+		AuthenticationInfo: &AuthenticationInfo{PrincipalEmail: "you@example.com"},
+		Metadata: map[string]any{
+			"decision":   DecisionAllowed,
+			"categories": []string{"jira"},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		status  int
+		log     *AuditLog
+		wantErr string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			log:    log,
+		},
+		{
+			name:    "server_error",
+			status:  http.StatusForbidden,
+			log:     log,
+			wantErr: "unexpected status 403",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.URL.Path, "/v2/entries:write"; got != want {
+					t.Errorf("expected path %q to be %q", got, want)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			t.Cleanup(srv.Close)
+
+			writer := NewCloudLoggingWriter(srv.Client(), srv.URL+"/", "my-project")
+			err := writer.Write(ctx, tc.log)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			entries, _ := got["entries"].([]any)
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 entry, got %v", got)
+			}
+			entry, _ := entries[0].(map[string]any)
+			delete(entry, "timestamp")
+
+			want := map[string]any{
+				"logName": "projects/my-project/logs/audit.abcxyz%2Fdata_access",
+				"resource": map[string]any{
+					"type":   "global",
+					"labels": map[string]any{"project_id": "my-project"},
+				},
+				"severity": "INFO",
+				"protoPayload": map[string]any{
+					"@type":        "type.googleapis.com/google.cloud.audit.AuditLog",
+					"serviceName":  "abcxyz.jvs.JVSService",
+					"methodName":   "abcxyz.jvs.JVSService.CreateJustification",
+					"resourceName": "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+					"authenticationInfo": map[string]any{
+						"principalEmail": "you@example.com",
+					},
+					"metadata": map[string]any{
+						"decision":   "allowed",
+						"categories": []any{"jira"},
+					},
+				},
+			}
+			if diff := cmp.Diff(want, entry); diff != "" {
+				t.Errorf("entry (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
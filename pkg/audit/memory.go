@@ -0,0 +1,140 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+var _ Sink = (*MemorySink)(nil)
+
+// MemorySink keeps audit entries in memory, for tests and single node
+// development servers. If it has a snapshot path, the entries are saved to it
+// as JSON after every write, and loaded from it when the sink is created, so
+// they survive restarts.
+type MemorySink struct {
+	mu      sync.RWMutex
+	entries []*Entry
+	index   map[string]int
+	path    string
+}
+
+// NewMemorySink creates an in-memory sink. If snapshotPath is not empty, the
+// entries in the snapshot are loaded if it exists, and it is updated on every
+// write.
+func NewMemorySink(snapshotPath string) (*MemorySink, error) {
+	s := &MemorySink{
+		index: make(map[string]int),
+		path:  snapshotPath,
+	}
+	if snapshotPath == "" {
+		return s, nil
+	}
+
+	b, err := os.ReadFile(snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit snapshot: %w", err)
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse audit snapshot %s: %w", snapshotPath, err)
+	}
+	s.put(entries)
+	return s, nil
+}
+
+// Write implements [Sink]. Entries whose token ID was already written replace
+// the previous entry.
+func (s *MemorySink) Write(ctx context.Context, entries ...*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(entries)
+	if s.path == "" {
+		return nil
+	}
+	return s.save()
+}
+
+// Entries returns the entries in the order they were first written.
+func (s *MemorySink) Entries() []*Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.entries)
+}
+
+// Entry returns the entry for the token ID, or nil if it was not written.
+func (s *MemorySink) Entry(id string) *Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i, ok := s.index[id]
+	if !ok {
+		return nil
+	}
+	return s.entries[i]
+}
+
+// put adds the entries. The caller must hold the lock.
+func (s *MemorySink) put(entries []*Entry) {
+	for _, e := range entries {
+		if i, ok := s.index[e.ID]; ok {
+			s.entries[i] = e
+			continue
+		}
+		s.index[e.ID] = len(s.entries)
+		s.entries = append(s.entries, e)
+	}
+}
+
+// save writes the entries to the snapshot, replacing it atomically so a crash
+// doesn't leave a partial snapshot. The caller must hold the lock.
+func (s *MemorySink) save() error {
+	b, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit snapshot: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create audit snapshot: %w", err)
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write audit snapshot: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write audit snapshot: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to replace audit snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemorySink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	issuedAt := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	first := &Entry{ID: "jti-1", Requestor: "me@example.com", Categories: []string{"explanation"}, IssuedAt: issuedAt}
+	second := &Entry{ID: "jti-2", Requestor: "you@example.com", Categories: []string{"breakglass"}, IssuedAt: issuedAt}
+	retried := &Entry{ID: "jti-1", Requestor: "me@example.com", Categories: []string{"explanation"}, IssuedAt: issuedAt, Receipt: "receipt"}
+
+	sink, err := NewMemorySink("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, first, second); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, retried); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]*Entry{retried, second}, sink.Entries()); diff != "" {
+		t.Errorf("entries (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(second, sink.Entry("jti-2")); diff != "" {
+		t.Errorf("entry (-want, +got):\n%s", diff)
+	}
+	if got := sink.Entry("missing"); got != nil {
+		t.Errorf("expected no entry, got %#v", got)
+	}
+}
+
+func TestMemorySink_snapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "audit.json")
+	entry := &Entry{
+		ID:         "jti-1",
+		Requestor:  "me@example.com",
+		Categories: []string{"explanation"},
+		Justifications: []*Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+		Audiences: []string{"dev.abcxyz.jvs"},
+		IssuedAt:  time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+		ExpiresAt: time.Date(2023, 4, 5, 6, 22, 8, 0, time.UTC),
+	}
+
+	sink, err := NewMemorySink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewMemorySink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*Entry{entry}, reloaded.Entries()); diff != "" {
+		t.Errorf("entries (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMemorySink_invalidSnapshot(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewMemorySink(path); err == nil {
+		t.Errorf("expected error parsing snapshot")
+	}
+}
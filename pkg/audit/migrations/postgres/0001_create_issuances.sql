@@ -0,0 +1,33 @@
+-- Copyright 2023 Google LLC
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+-- The audit entries of issued tokens, one row per token.
+CREATE TABLE jvs_issuances (
+  jti            TEXT PRIMARY KEY,
+  requestor      TEXT NOT NULL,
+  subject        TEXT NOT NULL DEFAULT '',
+  categories     JSONB NOT NULL DEFAULT '[]',
+  justifications JSONB NOT NULL DEFAULT '[]',
+  audiences      JSONB NOT NULL DEFAULT '[]',
+  issued_at      TIMESTAMPTZ NOT NULL,
+  expires_at     TIMESTAMPTZ,
+  ttl_seconds    BIGINT,
+  redacted       BOOLEAN NOT NULL DEFAULT FALSE,
+  receipt        TEXT,
+  transparency   JSONB
+);
+
+CREATE INDEX jvs_issuances_issued_at_idx ON jvs_issuances (issued_at);
+CREATE INDEX jvs_issuances_requestor_idx ON jvs_issuances (requestor, issued_at);
+CREATE INDEX jvs_issuances_categories_idx ON jvs_issuances USING GIN (categories);
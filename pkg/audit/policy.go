@@ -0,0 +1,48 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+const (
+	// PolicyLogKey is the structured logging key policy entries are logged
+	// under. In Cloud Logging, entries are found under "jsonPayload.jvs_policy".
+	PolicyLogKey = "jvs_policy"
+
+	// PolicyLogMessage is the log message of policy entries.
+	PolicyLogMessage = "issuance policy attested"
+)
+
+// PolicyEntry is the audit record of a version of the issuance policy. A new
+// entry is written whenever the JVS attests to a version of the policy it
+// hasn't recorded yet, so the history of the policy can be searched by time.
+type PolicyEntry struct {
+	// Version is the version of the policy.
+	Version string `json:"version"`
+
+	// IssuedAt is when the attestation was signed.
+	IssuedAt time.Time `json:"issued_at"`
+
+	// Policy is the attested policy.
+	Policy *jvspb.PolicyAttestation `json:"policy"`
+
+	// Attestation is the signed policy attestation, which auditors can verify
+	// with the JVS public keys.
+	Attestation string `json:"attestation"`
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// postgresMigrationLockID is the key of the advisory lock held while
+// migrating, so JVS instances starting at the same time don't race.
+const postgresMigrationLockID = 0x6a7673 // "jvs"
+
+// migrationNameRegexp matches migration file names, "0001_description.sql",
+// capturing the version.
+var migrationNameRegexp = regexp.MustCompile(`^([0-9]+)_[a-z0-9_]+\.sql$`)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+var _ Sink = (*PostgresSink)(nil)
+
+// PostgresSink writes audit entries into the "jvs_issuances" table of a
+// Postgres database, such as Cloud SQL. The database is opened by the caller,
+// so any driver for database/sql can be used.
+type PostgresSink struct {
+	db *sql.DB
+}
+
+// NewPostgresSink creates a sink for the database. The schema must have been
+// created with [PostgresSink.Migrate].
+func NewPostgresSink(db *sql.DB) *PostgresSink {
+	return &PostgresSink{db: db}
+}
+
+// migration is a schema migration, applied in order of version.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations returns the migrations in the directory of the file system,
+// sorted by version.
+func migrations(fsys fs.FS, dir string) ([]*migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var ms []*migration
+	seen := make(map[int]string, len(entries))
+	for _, e := range entries {
+		matches := migrationNameRegexp.FindStringSubmatch(e.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("migration %q must be named NNNN_description.sql", e.Name())
+		}
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version of migration %q: %w", e.Name(), err)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %q and %q have the same version", other, e.Name())
+		}
+		seen[version] = e.Name()
+
+		b, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", e.Name(), err)
+		}
+		ms = append(ms, &migration{version: version, name: e.Name(), sql: string(b)})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].version < ms[j].version })
+	return ms, nil
+}
+
+// Migrate applies the schema migrations that have not been applied yet, in
+// one transaction. Applied migrations are recorded in the
+// "jvs_schema_migrations" table.
+func (s *PostgresSink) Migrate(ctx context.Context) error {
+	ms, err := migrations(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // Rollback after commit is a no-op.
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLockID); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS jvs_schema_migrations (
+  version    INTEGER PRIMARY KEY,
+  name       TEXT NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := tx.QueryContext(ctx, `SELECT version FROM jvs_schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[v] = true
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	for _, m := range ms {
+		if applied[m.version] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO jvs_schema_migrations (version, name) VALUES ($1, $2)`,
+			m.version, m.name); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// Write implements [Sink]. It inserts the entries in one transaction. Entries
+// whose token ID is already in the table are skipped, so retried and
+// backfilled entries are deduplicated.
+func (s *PostgresSink) Write(ctx context.Context, entries ...*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // Rollback after commit is a no-op.
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO jvs_issuances (
+  jti, requestor, subject, categories, justifications, audiences,
+  issued_at, expires_at, ttl_seconds, redacted, receipt, transparency
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (jti) DO NOTHING`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		args, err := postgresRow(e)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", e.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit entries: %w", err)
+	}
+	return nil
+}
+
+// postgresRow converts the entry to the arguments of the insert statement of
+// [PostgresSink.Write]. JSONB columns are passed as JSON strings, so the row
+// doesn't depend on how the driver encodes arrays.
+func postgresRow(e *Entry) ([]any, error) {
+	categories, err := json.Marshal(nonNil(e.Categories))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal categories of %s: %w", e.ID, err)
+	}
+	justifications, err := json.Marshal(nonNil(e.Justifications))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal justifications of %s: %w", e.ID, err)
+	}
+	audiences, err := json.Marshal(nonNil(e.Audiences))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audiences of %s: %w", e.ID, err)
+	}
+
+	var transparency sql.NullString
+	if e.Transparency != nil {
+		b, err := json.Marshal(e.Transparency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal transparency of %s: %w", e.ID, err)
+		}
+		transparency = sql.NullString{String: string(b), Valid: true}
+	}
+
+	var expiresAt sql.NullTime
+	var ttl sql.NullInt64
+	if !e.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: e.ExpiresAt.UTC(), Valid: true}
+		ttl = sql.NullInt64{Int64: int64(e.ExpiresAt.Sub(e.IssuedAt).Seconds()), Valid: true}
+	}
+
+	return []any{
+		e.ID,
+		e.Requestor,
+		e.Subject,
+		string(categories),
+		string(justifications),
+		string(audiences),
+		e.IssuedAt.UTC(),
+		expiresAt,
+		ttl,
+		e.Redacted,
+		sql.NullString{String: e.Receipt, Valid: e.Receipt != ""},
+		transparency,
+	}, nil
+}
+
+// nonNil returns s, or an empty slice if s is nil, so it is encoded as a JSON
+// array rather than null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
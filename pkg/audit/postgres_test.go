@@ -0,0 +1,204 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "sorted_by_version",
+			fsys: fstest.MapFS{
+				"m/0010_add_index.sql":    {Data: []byte("CREATE INDEX")},
+				"m/0002_add_column.sql":   {Data: []byte("ALTER TABLE")},
+				"m/0001_create_table.sql": {Data: []byte("CREATE TABLE")},
+			},
+			want: []string{"0001_create_table.sql", "0002_add_column.sql", "0010_add_index.sql"},
+		},
+		{
+			name: "invalid_name",
+			fsys: fstest.MapFS{
+				"m/create_table.sql": {Data: []byte("CREATE TABLE")},
+			},
+			wantErr: `migration "create_table.sql" must be named NNNN_description.sql`,
+		},
+		{
+			name: "duplicate_version",
+			fsys: fstest.MapFS{
+				"m/0001_create_table.sql": {Data: []byte("CREATE TABLE")},
+				"m/001_other_table.sql":   {Data: []byte("CREATE TABLE")},
+			},
+			wantErr: "have the same version",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ms, err := migrations(tc.fsys, "m")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			var got []string
+			for _, m := range ms {
+				got = append(got, m.name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("migrations (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMigrations_embedded(t *testing.T) {
+	t.Parallel()
+
+	ms, err := migrations(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range ms {
+		if got, want := m.version, i+1; got != want {
+			t.Errorf("expected migration %s to have version %d, got %d", m.name, want, got)
+		}
+	}
+}
+
+func TestPostgresRow(t *testing.T) {
+	t.Parallel()
+
+	issuedAt := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	e := &Entry{
+		ID:         "test-jti",
+		Requestor:  "me@example.com",
+		Categories: []string{"explanation"},
+		Justifications: []*Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(15 * time.Minute),
+	}
+
+	args, err := postgresRow(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []any{
+		"test-jti",
+		"me@example.com",
+		"",
+		`["explanation"]`,
+		`[{"category":"explanation","value":"debugging"}]`,
+		`[]`,
+		issuedAt,
+		sql.NullTime{Time: issuedAt.Add(15 * time.Minute), Valid: true},
+		sql.NullInt64{Int64: 900, Valid: true},
+		false,
+		sql.NullString{},
+		sql.NullString{},
+	}
+	if diff := cmp.Diff(want, args); diff != "" {
+		t.Errorf("row (-want, +got):\n%s", diff)
+	}
+}
+
+// TestPostgresSink runs against a real database, and is skipped unless
+// JVS_TEST_POSTGRES_URL is set, e.g. to a local instance started with
+// `docker run -e POSTGRES_PASSWORD=jvs -p 5432:5432 postgres`.
+func TestPostgresSink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dbURL := os.Getenv("JVS_TEST_POSTGRES_URL")
+	if dbURL == "" {
+		t.Skip("Skip postgres test; set env var JVS_TEST_POSTGRES_URL to enable")
+	}
+
+	db, err := sql.Open("pgx", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	sink := NewPostgresSink(db)
+	// Migrating twice is a no-op.
+	for range 2 {
+		if err := sink.Migrate(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jti := "test-" + time.Now().Format(time.RFC3339Nano)
+	e := &Entry{
+		ID:         jti,
+		Requestor:  "me@example.com",
+		Categories: []string{"explanation"},
+		Justifications: []*Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+		Audiences: []string{"dev.abcxyz.jvs"},
+		IssuedAt:  time.Now().UTC(),
+	}
+	// Writing an entry twice doesn't duplicate it.
+	if err := sink.Write(ctx, e, e); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows int
+	var categories []byte
+	if err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*), MIN(categories::text) FROM jvs_issuances WHERE jti = $1`, jti).
+		Scan(&rows, &categories); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows, 1; got != want {
+		t.Errorf("expected %d rows to be %d", got, want)
+	}
+	var got []string
+	if err := json.Unmarshal(categories, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(e.Categories, got); diff != "" {
+		t.Errorf("categories (-want, +got):\n%s", diff)
+	}
+}
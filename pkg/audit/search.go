@@ -0,0 +1,172 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCloudLoggingEndpoint is the Cloud Logging API endpoint.
+const DefaultCloudLoggingEndpoint = "https://logging.googleapis.com"
+
+// maxPageSize is the maximum page size of the Cloud Logging API.
+const maxPageSize = 1000
+
+// Query selects audit entries. Empty fields match everything.
+type Query struct {
+	ID        string
+	Requestor string
+	Category  string
+	Start     time.Time
+	End       time.Time
+
+	// Limit is the maximum number of entries to return.
+	Limit int
+}
+
+// Filter returns the Cloud Logging filter for the query.
+func (q *Query) Filter() string {
+	parts := []string{"jsonPayload." + LogKey + ".jti:*"}
+	if q.ID != "" {
+		parts = append(parts, "jsonPayload."+LogKey+".jti="+strconv.Quote(q.ID))
+	}
+	if q.Requestor != "" {
+		parts = append(parts, "jsonPayload."+LogKey+".requestor="+strconv.Quote(q.Requestor))
+	}
+	if q.Category != "" {
+		parts = append(parts, "jsonPayload."+LogKey+".categories="+strconv.Quote(q.Category))
+	}
+	if !q.Start.IsZero() {
+		parts = append(parts, "timestamp>="+strconv.Quote(q.Start.UTC().Format(time.RFC3339)))
+	}
+	if !q.End.IsZero() {
+		parts = append(parts, "timestamp<"+strconv.Quote(q.End.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// CloudLoggingSearcher searches for audit entries written to Cloud Logging.
+type CloudLoggingSearcher struct {
+	client   *http.Client
+	endpoint string
+	project  string
+}
+
+// NewCloudLoggingSearcher creates a searcher for the audit entries in the given
+// project. The client must add credentials with permission to read logs.
+func NewCloudLoggingSearcher(client *http.Client, endpoint, project string) *CloudLoggingSearcher {
+	return &CloudLoggingSearcher{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		project:  project,
+	}
+}
+
+type listEntriesRequest struct {
+	ResourceNames []string `json:"resourceNames"`
+	Filter        string   `json:"filter"`
+	OrderBy       string   `json:"orderBy"`
+	PageSize      int      `json:"pageSize"`
+	PageToken     string   `json:"pageToken,omitempty"`
+}
+
+type listEntriesResponse struct {
+	Entries []struct {
+		JSONPayload map[string]json.RawMessage `json:"jsonPayload"`
+	} `json:"entries"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// Search returns the entries matching the query, newest first.
+func (s *CloudLoggingSearcher) Search(ctx context.Context, q *Query) ([]*Entry, error) {
+	req := &listEntriesRequest{
+		ResourceNames: []string{"projects/" + s.project},
+		Filter:        q.Filter(),
+		OrderBy:       "timestamp desc",
+		PageSize:      min(q.Limit, maxPageSize),
+	}
+
+	entries := make([]*Entry, 0, q.Limit)
+	for len(entries) < q.Limit {
+		resp, err := s.listEntries(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range resp.Entries {
+			raw, ok := e.JSONPayload[LogKey]
+			if !ok {
+				continue
+			}
+
+			var entry Entry
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return nil, fmt.Errorf("failed to parse audit entry: %w", err)
+			}
+			entries = append(entries, &entry)
+
+			if len(entries) == q.Limit {
+				break
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+	return entries, nil
+}
+
+func (s *CloudLoggingSearcher) listEntries(ctx context.Context, req *listEntriesRequest) (*listEntriesResponse, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/v2/entries:list", bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log entries: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list log entries: unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	var out listEntriesResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &out, nil
+}
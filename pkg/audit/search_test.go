@@ -0,0 +1,170 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestQuery_Filter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		query *Query
+		exp   string
+	}{
+		{
+			name:  "empty",
+			query: &Query{},
+			exp:   `jsonPayload.jvs_audit.jti:*`,
+		},
+		{
+			name: "all",
+			query: &Query{
+				Requestor: "you@example.com",
+				Category:  "jira",
+				Start:     time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+				End:       time.Date(2023, 6, 2, 0, 0, 0, 0, time.UTC),
+			},
+			exp: `jsonPayload.jvs_audit.jti:* AND ` +
+				`jsonPayload.jvs_audit.requestor="you@example.com" AND ` +
+				`jsonPayload.jvs_audit.categories="jira" AND ` +
+				`timestamp>="2023-06-01T00:00:00Z" AND ` +
+				`timestamp<"2023-06-02T00:00:00Z"`,
+		},
+		{
+			name:  "id",
+			query: &Query{ID: "2c9a8f4e-5d2b-4b7e-9a1f-0e6f3c1d2b3a"},
+			exp: `jsonPayload.jvs_audit.jti:* AND ` +
+				`jsonPayload.jvs_audit.jti="2c9a8f4e-5d2b-4b7e-9a1f-0e6f3c1d2b3a"`,
+		},
+		{
+			name:  "escapes",
+			query: &Query{Requestor: `" OR "1"="1`},
+			exp:   `jsonPayload.jvs_audit.jti:* AND jsonPayload.jvs_audit.requestor="\" OR \"1\"=\"1"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := tc.query.Filter(), tc.exp; got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestCloudLoggingSearcher_Search(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// Two pages of two entries each.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req listEntriesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got, want := req.ResourceNames, []string{"projects/my-project"}; !cmp.Equal(got, want) {
+			http.Error(w, fmt.Sprintf("unexpected resource names %q", got), http.StatusBadRequest)
+			return
+		}
+
+		offset, next := 0, "page-2"
+		if req.PageToken == "page-2" {
+			offset, next = 2, ""
+		}
+		fmt.Fprintf(w, `{"entries":[
+			{"jsonPayload":{"message":"issued justification token","jvs_audit":{"jti":"id-%d","requestor":"you@example.com"}}},
+			{"jsonPayload":{"message":"issued justification token","jvs_audit":{"jti":"id-%d","requestor":"you@example.com"}}}
+		],"nextPageToken":%q}`, offset, offset+1, next)
+	}))
+	t.Cleanup(srv.Close)
+
+	errSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "permission denied", http.StatusForbidden)
+	}))
+	t.Cleanup(errSrv.Close)
+
+	cases := []struct {
+		name     string
+		endpoint string
+		limit    int
+		expIDs   []string
+		wantErr  string
+	}{
+		{
+			name:     "single_page",
+			endpoint: srv.URL,
+			limit:    1,
+			expIDs:   []string{"id-0"},
+		},
+		{
+			name:     "multiple_pages",
+			endpoint: srv.URL,
+			limit:    3,
+			expIDs:   []string{"id-0", "id-1", "id-2"},
+		},
+		{
+			name:     "all_pages",
+			endpoint: srv.URL,
+			limit:    100,
+			expIDs:   []string{"id-0", "id-1", "id-2", "id-3"},
+		},
+		{
+			name:     "server_error",
+			endpoint: errSrv.URL,
+			limit:    1,
+			wantErr:  "unexpected status 403",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := NewCloudLoggingSearcher(http.DefaultClient, tc.endpoint, "my-project")
+			entries, err := s.Search(ctx, &Query{Limit: tc.limit})
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			ids := make([]string, 0, len(entries))
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			if len(tc.expIDs) == 0 {
+				tc.expIDs = []string{}
+			}
+			if diff := cmp.Diff(tc.expIDs, ids); diff != "" {
+				t.Errorf("ids (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// maxMutations is the maximum number of mutations applied in one commit, well
+// under the Spanner limit of 80,000 mutated cells.
+const maxMutations = 1000
+
+// spannerDatabaseRegexp matches Spanner database names.
+var spannerDatabaseRegexp = regexp.MustCompile(`^projects/[^/]+/instances/[^/]+/databases/[^/]+$`)
+
+// SpannerSchema is the DDL of the issuance tables. Justifications are
+// interleaved in their issuance, so an issuance and its justifications are
+// stored, and deleted, together.
+var SpannerSchema = []string{
+	`CREATE TABLE Issuances (
+  Jti          STRING(256) NOT NULL,
+  Requestor    STRING(MAX) NOT NULL,
+  Subject      STRING(MAX),
+  Categories   ARRAY<STRING(MAX)>,
+  Audiences    ARRAY<STRING(MAX)>,
+  IssuedAt     TIMESTAMP NOT NULL,
+  ExpiresAt    TIMESTAMP,
+  TtlSeconds   INT64,
+  Redacted     BOOL,
+  Receipt      STRING(MAX),
+  Transparency JSON,
+) PRIMARY KEY (Jti)`,
+	`CREATE TABLE Justifications (
+  Jti         STRING(256) NOT NULL,
+  Position    INT64 NOT NULL,
+  Category    STRING(MAX) NOT NULL,
+  Value       STRING(MAX),
+  Annotations JSON,
+) PRIMARY KEY (Jti, Position),
+  INTERLEAVE IN PARENT Issuances ON DELETE CASCADE`,
+	`CREATE INDEX IssuancesByIssuedAt ON Issuances (IssuedAt)`,
+	`CREATE INDEX IssuancesByRequestor ON Issuances (Requestor, IssuedAt)`,
+}
+
+var _ Sink = (*SpannerSink)(nil)
+
+// SpannerSink writes audit entries into the "Issuances" and "Justifications"
+// tables of a Spanner database.
+type SpannerSink struct {
+	database string
+	client   *spanner.Client
+	opts     []option.ClientOption
+}
+
+// NewSpannerSink creates a sink for the database,
+// "projects/*/instances/*/databases/*", with the Spanner client options. If
+// SPANNER_EMULATOR_HOST is set, the emulator is used.
+func NewSpannerSink(ctx context.Context, db string, opts ...option.ClientOption) (*SpannerSink, error) {
+	if !spannerDatabaseRegexp.MatchString(db) {
+		return nil, fmt.Errorf("database %q must be in the format projects/*/instances/*/databases/*", db)
+	}
+
+	client, err := spanner.NewClient(ctx, db, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spanner client: %w", err)
+	}
+	return &SpannerSink{
+		database: db,
+		client:   client,
+		opts:     opts,
+	}, nil
+}
+
+// Close closes the Spanner client.
+func (s *SpannerSink) Close() error {
+	s.client.Close()
+	return nil
+}
+
+// EnsureSchema creates the tables and indexes of [SpannerSchema] if the
+// "Issuances" table does not exist. The database must exist.
+func (s *SpannerSink) EnsureSchema(ctx context.Context) error {
+	exists, err := s.tableExists(ctx, "Issuances")
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	admin, err := database.NewDatabaseAdminClient(ctx, s.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create spanner admin client: %w", err)
+	}
+	defer admin.Close()
+
+	op, err := admin.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   s.database,
+		Statements: SpannerSchema,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update schema of %s: %w", s.database, err)
+	}
+	if err := op.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to update schema of %s: %w", s.database, err)
+	}
+	return nil
+}
+
+// tableExists returns whether the table is in the default schema of the
+// database.
+func (s *SpannerSink) tableExists(ctx context.Context, table string) (bool, error) {
+	iter := s.client.Single().Query(ctx, spanner.Statement{
+		SQL:    `SELECT 1 FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = '' AND TABLE_NAME = @table`,
+		Params: map[string]any{"table": table},
+	})
+	defer iter.Stop()
+
+	_, err := iter.Next()
+	if errors.Is(err, iterator.Done) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", table, err)
+	}
+	return true, nil
+}
+
+// Write implements [Sink]. It writes each entry and its justifications
+// together. Entries are written with insert-or-update, so retried and
+// backfilled entries are deduplicated.
+func (s *SpannerSink) Write(ctx context.Context, entries ...*Entry) error {
+	var ms []*spanner.Mutation
+	for _, e := range entries {
+		entryMs := spannerMutations(e)
+		if len(ms)+len(entryMs) > maxMutations {
+			if _, err := s.client.Apply(ctx, ms); err != nil {
+				return fmt.Errorf("failed to write entries: %w", err)
+			}
+			ms = nil
+		}
+		ms = append(ms, entryMs...)
+	}
+	if len(ms) == 0 {
+		return nil
+	}
+	if _, err := s.client.Apply(ctx, ms); err != nil {
+		return fmt.Errorf("failed to write entries: %w", err)
+	}
+	return nil
+}
+
+// spannerMutations converts the entry to the mutations of its issuance row
+// and justification rows.
+func spannerMutations(e *Entry) []*spanner.Mutation {
+	issuance := map[string]any{
+		"Jti":        e.ID,
+		"Requestor":  e.Requestor,
+		"Subject":    e.Subject,
+		"Categories": e.Categories,
+		"Audiences":  e.Audiences,
+		"IssuedAt":   e.IssuedAt.UTC(),
+		"ExpiresAt":  spanner.NullTime{},
+		"TtlSeconds": spanner.NullInt64{},
+		"Redacted":   e.Redacted,
+		"Receipt":    spanner.NullString{StringVal: e.Receipt, Valid: e.Receipt != ""},
+		"Transparency": spanner.NullJSON{
+			Value: e.Transparency,
+			Valid: e.Transparency != nil,
+		},
+	}
+	if !e.ExpiresAt.IsZero() {
+		issuance["ExpiresAt"] = spanner.NullTime{Time: e.ExpiresAt.UTC(), Valid: true}
+		issuance["TtlSeconds"] = spanner.NullInt64{Int64: int64(e.ExpiresAt.Sub(e.IssuedAt).Seconds()), Valid: true}
+	}
+
+	ms := make([]*spanner.Mutation, 0, 1+len(e.Justifications))
+	ms = append(ms, spanner.InsertOrUpdateMap("Issuances", issuance))
+	for i, j := range e.Justifications {
+		ms = append(ms, spanner.InsertOrUpdateMap("Justifications", map[string]any{
+			"Jti":         e.ID,
+			"Position":    int64(i),
+			"Category":    j.Category,
+			"Value":       j.Value,
+			"Annotations": spanner.NullJSON{Value: j.Annotation, Valid: len(j.Annotation) > 0},
+		}))
+	}
+	return ms
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestNewSpannerSink_invalidDatabase(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSpannerSink(context.Background(), "projects/p/instances/i")
+	if diff := testutil.DiffErrString(err, `database "projects/p/instances/i" must be in the format projects/*/instances/*/databases/*`); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestSpannerSink runs against the Spanner emulator, and is skipped unless
+// SPANNER_EMULATOR_HOST is set, e.g. to "localhost:9010" for an emulator
+// started with `gcloud emulators spanner start`.
+func TestSpannerSink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	if os.Getenv("SPANNER_EMULATOR_HOST") == "" {
+		t.Skip("Skip spanner test; set env var SPANNER_EMULATOR_HOST to enable")
+	}
+
+	db := testSpannerDatabase(t, "test-project", "jvs-test")
+
+	sink, err := NewSpannerSink(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := sink.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Ensuring the schema twice is a no-op.
+	for range 2 {
+		if err := sink.EnsureSchema(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	issuedAt := time.Now().UTC()
+	e := &Entry{
+		ID:         "test-jti",
+		Requestor:  "me@example.com",
+		Categories: []string{"explanation", "breakglass"},
+		Justifications: []*Justification{
+			{Category: "explanation", Value: "debugging"},
+			{Category: "breakglass", Value: "incident", Annotation: map[string]string{"ticket": "123"}},
+		},
+		Audiences: []string{"dev.abcxyz.jvs"},
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(15 * time.Minute),
+	}
+	// Writing an entry twice doesn't duplicate it.
+	if err := sink.Write(ctx, e, e); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := spanner.NewClient(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	row, err := client.Single().ReadRow(ctx, "Issuances", spanner.Key{"test-jti"}, []string{"Requestor", "TtlSeconds"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requestor string
+	var ttl int64
+	if err := row.Columns(&requestor, &ttl); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := requestor, e.Requestor; got != want {
+		t.Errorf("expected requestor %q to be %q", got, want)
+	}
+	if got, want := ttl, int64(900); got != want {
+		t.Errorf("expected ttl %d to be %d", got, want)
+	}
+
+	var justifications int
+	if err := client.Single().Read(ctx, "Justifications", spanner.Key{"test-jti"}.AsPrefix(), []string{"Category"}).
+		Do(func(*spanner.Row) error {
+			justifications++
+			return nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := justifications, len(e.Justifications); got != want {
+		t.Errorf("expected %d justifications to be %d", got, want)
+	}
+}
+
+// testSpannerDatabase creates an instance, if it does not exist, and a new
+// empty database in it on the emulator, and returns the database name.
+func testSpannerDatabase(tb testing.TB, project, instanceID string) string {
+	tb.Helper()
+
+	ctx := context.Background()
+
+	instanceAdmin, err := instance.NewInstanceAdminClient(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer instanceAdmin.Close()
+
+	instanceName := fmt.Sprintf("projects/%s/instances/%s", project, instanceID)
+	instanceOp, err := instanceAdmin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     "projects/" + project,
+		InstanceId: instanceID,
+		Instance: &instancepb.Instance{
+			Config:      fmt.Sprintf("projects/%s/instanceConfigs/emulator-config", project),
+			DisplayName: instanceID,
+			NodeCount:   1,
+		},
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := instanceOp.Wait(ctx); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	databaseAdmin, err := database.NewDatabaseAdminClient(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer databaseAdmin.Close()
+
+	databaseID := fmt.Sprintf("jvs-%d", time.Now().UnixNano()%1e12)
+	databaseOp, err := databaseAdmin.CreateDatabase(ctx, &databasepb.CreateDatabaseRequest{
+		Parent:          instanceName,
+		CreateStatement: fmt.Sprintf("CREATE DATABASE `%s`", databaseID),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := databaseOp.Wait(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return instanceName + "/databases/" + databaseID
+}
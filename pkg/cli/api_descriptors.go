@@ -0,0 +1,124 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*APIDescriptorsCommand)(nil)
+
+type APIDescriptorsCommand struct {
+	cli.BaseCommand
+
+	flagOutput string
+	flagFormat string
+}
+
+func (c *APIDescriptorsCommand) Desc() string {
+	return `Write the descriptors of the JVS APIs`
+}
+
+func (c *APIDescriptorsCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Write the compiled FileDescriptorSet of the JVS APIs and their imports, which
+  is embedded in jvsctl. Clients can generate stubs or call the APIs with it,
+  e.g. with grpcurl, since server reflection is off by default.
+
+  Call the API with grpcurl:
+
+      jvsctl api descriptors -output "jvs.protoset"
+      grpcurl -protoset "jvs.protoset" jvs.example.com:443 list
+
+  Show the descriptors as JSON:
+
+      jvsctl api descriptors -format "json"
+`
+}
+
+func (c *APIDescriptorsCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Target:  &c.flagOutput,
+		Example: "jvs.protoset",
+		Usage:   `The file to write the descriptors to. If unset, they are written to stdout.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Aliases: []string{"f"},
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: "binary",
+		Usage: `The target output format. Valid values are: binary, like ` +
+			`protoc's --descriptor_set_out, and json.`,
+	})
+
+	return set
+}
+
+func (c *APIDescriptorsCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	var b []byte
+	var err error
+	switch format := strings.TrimSpace(strings.ToLower(c.flagFormat)); format {
+	case "", "binary":
+		b, err = proto.Marshal(jvspb.FileDescriptorSet())
+	case "json":
+		b, err = protojson.MarshalOptions{Multiline: true}.Marshal(jvspb.FileDescriptorSet())
+		b = append(b, '\n')
+	default:
+		return fmt.Errorf("unknown formatter %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal descriptors: %w", err)
+	}
+
+	if c.flagOutput == "" {
+		if _, err := c.Stdout().Write(b); err != nil {
+			return fmt.Errorf("failed to write descriptors: %w", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(c.flagOutput, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write descriptors: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestAPIDescriptorsCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		args      []string
+		unmarshal func([]byte, proto.Message) error
+		expErr    string
+	}{
+		{
+			name:      "binary",
+			unmarshal: proto.Unmarshal,
+		},
+		{
+			name:      "json",
+			args:      []string{"-format", "json"},
+			unmarshal: protojson.Unmarshal,
+		},
+		{
+			name:   "unknown_format",
+			args:   []string{"-format", "yaml"},
+			expErr: `unknown formatter "yaml"`,
+		},
+		{
+			name:   "unexpected_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var cmd APIDescriptorsCommand
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			var set descriptorpb.FileDescriptorSet
+			if err := tc.unmarshal(stdout.Bytes(), &set); err != nil {
+				t.Fatal(err)
+			}
+			files, err := protodesc.NewFiles(&set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := files.FindDescriptorByName("abcxyz.jvs.JVSService"); err != nil {
+				t.Errorf("expected the jvs service to be described: %v", err)
+			}
+		})
+	}
+}
+
+func TestAPIDescriptorsCommand_output(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	path := filepath.Join(t.TempDir(), "jvs.protoset")
+
+	var cmd APIDescriptorsCommand
+	_, stdout, _ := cmd.Pipe()
+	if err := cmd.Run(ctx, []string{"-output", path}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "" {
+		t.Errorf("expected no output, got %q", got)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		t.Fatal(err)
+	}
+	if len(set.GetFile()) == 0 {
+		t.Error("expected descriptors to be written")
+	}
+}
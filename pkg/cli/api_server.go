@@ -16,26 +16,41 @@ package cli
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"net/http"
 
 	kms "cloud.google.com/go/kms/apiv1"
-	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver.
+	"golang.org/x/oauth2/google"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/internal/version"
+	"github.com/abcxyz/jvs/pkg/audit"
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/events"
+	"github.com/abcxyz/jvs/pkg/groups"
+	"github.com/abcxyz/jvs/pkg/grpcserver"
+	"github.com/abcxyz/jvs/pkg/hsm"
 	"github.com/abcxyz/jvs/pkg/justification"
-	"github.com/abcxyz/jvs/pkg/plugin"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/jvs/pkg/opa"
+	"github.com/abcxyz/jvs/pkg/serving"
+	"github.com/abcxyz/jvs/pkg/transparency"
+	"github.com/abcxyz/jvs/pkg/vault"
 	"github.com/abcxyz/pkg/cli"
-	"github.com/abcxyz/pkg/healthcheck"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/multicloser"
-	"github.com/abcxyz/pkg/serving"
 )
 
+// loggingWriteScope is the OAuth scope to write audit logs.
+const loggingWriteScope = "https://www.googleapis.com/auth/logging.write"
+
 var _ cli.Command = (*APIServerCommand)(nil)
 
 type APIServerCommand struct {
@@ -43,6 +58,16 @@ type APIServerCommand struct {
 
 	cfg *config.JustificationConfig
 
+	// adminServer and adminGRPCServer serve the admin API on its own port. They
+	// are nil unless the admin API is enabled.
+	adminServer     *serving.Server
+	adminGRPCServer *grpc.Server
+
+	// webhookServer and webhookHandler serve the deprovision webhook on its own
+	// port. They are nil unless the webhook is enabled.
+	webhookServer  *serving.Server
+	webhookHandler http.Handler
+
 	// testKMSClientOptions are KMS client options to override during testing.
 	testKMSClientOptions []option.ClientOption
 }
@@ -78,7 +103,26 @@ func (c *APIServerCommand) Run(ctx context.Context, args []string) error {
 		return err
 	}
 
-	return server.StartGRPC(ctx, grpcServer)
+	if c.adminServer == nil && c.webhookServer == nil {
+		return server.StartGRPC(ctx, grpcServer)
+	}
+
+	// Stop all servers if any fails.
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return server.StartGRPC(ctx, grpcServer)
+	})
+	if c.adminServer != nil {
+		g.Go(func() error {
+			return c.adminServer.StartGRPC(ctx, c.adminGRPCServer)
+		})
+	}
+	if c.webhookServer != nil {
+		g.Go(func() error {
+			return c.webhookServer.StartHTTPHandler(ctx, c.webhookHandler)
+		})
+	}
+	return g.Wait() //nolint:wrapcheck // Errors are wrapped by the servers.
 }
 
 func (c *APIServerCommand) RunUnstarted(ctx context.Context, args []string) (*serving.Server, *grpc.Server, *multicloser.Closer, error) {
@@ -104,37 +148,332 @@ func (c *APIServerCommand) RunUnstarted(ctx context.Context, args []string) (*se
 	}
 	logger.DebugContext(ctx, "loaded configuration", "config", c.cfg)
 
-	kmsClient, err := kms.NewKeyManagementClient(ctx, c.testKMSClientOptions...)
-	if err != nil {
-		return nil, nil, closer, fmt.Errorf("failed to setup kms client: %w", err)
+	// Keys are only in KMS with the kms signer, the other signers read them
+	// from disk or an HSM.
+	var kmsClient *kms.KeyManagementClient
+	if usesKMS(c.cfg.Signer) {
+		var err error
+		kmsClient, err = jvscrypto.NewKMSClient(ctx, jvscrypto.DefaultKMSRetryPolicy(), append(
+			kmsClientOptions(c.cfg.KMSEndpoint, c.cfg.KMSInsecure),
+			c.testKMSClientOptions...)...)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to setup kms client: %w", err)
+		}
+		closer = multicloser.Append(closer, kmsClient.Close)
+		if err := checkKMSPermissions(ctx, kmsClient, c.cfg.KMSPermissionCheck, c.cfg.KMSInsecure,
+			[]string{c.cfg.KeyName}, jvscrypto.SignerPermissions); err != nil {
+			return nil, nil, closer, err
+		}
 	}
-	closer = multicloser.Append(closer, kmsClient.Close)
-
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(
-			logging.GRPCUnaryInterceptor(logger, c.cfg.ProjectID),
-		),
-		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-	)
 
-	// Create basic health check
-	healthcheck.RegisterGRPCHealthCheck(grpcServer)
+	// Track requests, so the server reports the ones still running if it
+	// doesn't drain in time.
+	tracker := serving.NewTracker()
+	chain := grpcserver.NewChain(logger, c.cfg.ProjectID).WithInterceptors(tracker.UnaryInterceptor)
+	if c.cfg.MaxConcurrentRequests > 0 {
+		limiter := justification.NewConcurrencyLimiter(c.cfg.MaxConcurrentRequests)
+		chain.WithRateLimit(limiter.UnaryInterceptor)
+	}
+	if c.cfg.AuditLogProject != "" {
+		client, err := google.DefaultClient(ctx, loggingWriteScope)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create audit log client: %w", err)
+		}
+		writer := audit.NewCloudLoggingWriter(client, audit.DefaultCloudLoggingEndpoint, c.cfg.AuditLogProject)
+		auditor := justification.NewAuditInterceptor(writer, c.cfg.KeyName).
+			WithCertificateAuthorityKey(c.cfg.CertificateCAKey)
+		chain.WithInterceptors(auditor.UnaryInterceptor)
+		logger.InfoContext(ctx, "audit logging enabled", "project", c.cfg.AuditLogProject)
+	}
+	grpcServer := chain.NewServer()
 
-	validators, pluginClosers, err := plugin.LoadPlugins(c.cfg.PluginDir)
+	validators, pluginClosers, err := loadValidators(ctx, c.cfg)
+	closer = multicloser.Append(closer, pluginClosers.Close)
 	if err != nil {
-		return nil, nil, closer, fmt.Errorf("failed to load plugins: %w", err)
+		return nil, nil, closer, err
 	}
 	logger.InfoContext(ctx, "plugins loaded", "validators", validators)
-	closer = multicloser.Append(closer, pluginClosers.Close)
 
 	p := justification.NewProcessor(kmsClient, c.cfg).WithValidators(validators)
+	if c.cfg.Signer == config.SignerLocal {
+		signer, keyID, err := jvscrypto.LoadSigningKey(c.cfg.KeyPath)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to load signing key: %w", err)
+		}
+		p.WithLocalSigner(signer, keyID)
+		logger.InfoContext(ctx, "signing with local key", "key_id", keyID)
+	}
+	if c.cfg.Signer == config.SignerPKCS11 {
+		keys, err := hsm.Open(ctx, &c.cfg.PKCS11)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to open hsm: %w", err)
+		}
+		closer = multicloser.Append(closer, keys.Close)
+		p.WithKeyManager(keys)
+		logger.InfoContext(ctx, "signing with hsm key", "key", c.cfg.KeyName)
+	}
+	if c.cfg.Signer == config.SignerVault {
+		p.WithKeyManager(vault.NewKeyManager(&c.cfg.Vault))
+		logger.InfoContext(ctx, "signing with vault key", "key", c.cfg.KeyName)
+	}
+
+	cacheTTLs, err := c.cfg.ValidationCacheTTLs()
+	if err != nil {
+		return nil, nil, closer, fmt.Errorf("failed to parse validation cache: %w", err)
+	}
+	p.WithValidationCache(cacheTTLs)
+
+	aliases, err := c.cfg.CategoryAliasTargets()
+	if err != nil {
+		return nil, nil, closer, fmt.Errorf("failed to parse category aliases: %w", err)
+	}
+	p.WithCategoryAliases(aliases)
+	p.WithShadowCategories(c.cfg.ShadowCategories)
+
+	claimAliases, err := c.cfg.ClaimAliasSources()
+	if err != nil {
+		return nil, nil, closer, fmt.Errorf("failed to parse claim aliases: %w", err)
+	}
+	p.WithClaimAliases(claimAliases)
+
+	patterns, err := c.cfg.ValuePatternRegexps()
+	if err != nil {
+		return nil, nil, closer, fmt.Errorf("failed to parse value patterns: %w", err)
+	}
+	p.WithValuePatterns(patterns)
+
+	required, err := c.cfg.RequiredAnnotationKeys()
+	if err != nil {
+		return nil, nil, closer, fmt.Errorf("failed to parse required annotations: %w", err)
+	}
+	p.WithRequiredAnnotations(required)
+
+	if c.cfg.DenyListPath != "" {
+		denyList, err := justification.LoadDenyList(c.cfg.DenyListPath)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to load deny list: %w", err)
+		}
+		go denyList.Watch(ctx, c.cfg.DenyListReloadInterval)
+		p.WithDenyList(denyList)
+		logger.InfoContext(ctx, "deny list enabled",
+			"path", c.cfg.DenyListPath,
+			"principals", denyList.Len())
+	}
+
+	revocations, closer, err := loadRevocations(ctx, &c.cfg.Revocation, closer)
+	if err != nil {
+		return nil, nil, closer, err
+	}
+	if revocations != nil {
+		p.WithRevocations(revocations)
+	}
+
+	quotas, closer, err := loadQuotas(ctx, &c.cfg.Quota, closer)
+	if err != nil {
+		return nil, nil, closer, err
+	}
+	if quotas != nil {
+		p.WithQuotas(quotas)
+	}
+
+	if err := withPAMGrants(ctx, &c.cfg.PAM, p); err != nil {
+		return nil, nil, closer, err
+	}
+
+	var policy *justification.PolicyStore
+	if c.cfg.PolicyPath != "" {
+		var err error
+		policy, err = justification.LoadPolicyStore(c.cfg.PolicyPath)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to load policy: %w", err)
+		}
+		go policy.Watch(ctx, c.cfg.PolicyReloadInterval)
+		p.WithPolicy(policy)
+		logger.InfoContext(ctx, "runtime policy enabled", "path", c.cfg.PolicyPath)
+	}
+
+	if c.cfg.RegoPolicyPath != "" {
+		regoPolicy, err := opa.Load(ctx, c.cfg.RegoPolicyPath)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to load rego policy: %w", err)
+		}
+		go regoPolicy.Watch(ctx, c.cfg.RegoPolicyReloadInterval)
+		p.WithPreIssueHooks(regoPolicy.PreIssueHook(p.MaxTTL))
+		logger.InfoContext(ctx, "rego policy enabled", "path", c.cfg.RegoPolicyPath)
+	}
+
+	if c.cfg.PolicyExpression != "" {
+		expression, err := justification.CompilePolicyExpression(c.cfg.PolicyExpression)
+		if err != nil {
+			return nil, nil, closer, err
+		}
+		p.WithPolicyExpression(expression)
+		logger.InfoContext(ctx, "policy expression enabled", "expression", c.cfg.PolicyExpression)
+	}
+
+	if err := withDecisionTraces(ctx, c.cfg, p); err != nil {
+		return nil, nil, closer, err
+	}
+
+	if c.cfg.MaintenanceMode {
+		logger.WarnContext(ctx, "maintenance mode enabled, not issuing tokens",
+			"message", c.cfg.MaintenanceMessage)
+	}
+
+	if c.cfg.Groups.Source != "" {
+		resolver, err := groups.New(ctx, &c.cfg.Groups)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create groups resolver: %w", err)
+		}
+		p.WithGroupsResolver(resolver)
+		logger.InfoContext(ctx, "requestor groups enabled", "source", c.cfg.Groups.Source)
+	}
+
+	if c.cfg.EventsTopic != "" {
+		publisher, err := events.NewPubSubPublisher(ctx, c.cfg.EventsTopic, events.SourceAPI)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create event publisher: %w", err)
+		}
+		p.WithEventPublisher(publisher)
+		logger.InfoContext(ctx, "event publishing enabled", "topic", c.cfg.EventsTopic)
+	}
+
+	if c.cfg.AuditBigQueryTable != "" {
+		sink, err := audit.NewBigQuerySink(ctx, c.cfg.AuditBigQueryTable)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create audit sink: %w", err)
+		}
+		if err := sink.EnsureTable(ctx); err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create audit table: %w", err)
+		}
+		p.WithAuditSink(sink)
+		logger.InfoContext(ctx, "audit bigquery sink enabled", "table", c.cfg.AuditBigQueryTable)
+	}
+
+	if c.cfg.AuditPostgresURL != "" {
+		db, err := sql.Open("pgx", c.cfg.AuditPostgresURL)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to open audit database: %w", err)
+		}
+		closer = multicloser.Append(closer, db.Close)
+		if err := db.PingContext(ctx); err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to connect to audit database: %w", err)
+		}
+		sink := audit.NewPostgresSink(db)
+		if err := sink.Migrate(ctx); err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to migrate audit database: %w", err)
+		}
+		p.WithAuditSink(sink)
+		logger.InfoContext(ctx, "audit postgres sink enabled")
+	}
+
+	if c.cfg.AuditSpannerDatabase != "" {
+		sink, err := audit.NewSpannerSink(ctx, c.cfg.AuditSpannerDatabase)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create audit sink: %w", err)
+		}
+		closer = multicloser.Append(closer, sink.Close)
+		if err := sink.EnsureSchema(ctx); err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create audit tables: %w", err)
+		}
+		p.WithAuditSink(sink)
+		logger.InfoContext(ctx, "audit spanner sink enabled", "database", c.cfg.AuditSpannerDatabase)
+	}
+
+	if c.cfg.AuditSnapshotPath != "" || (c.cfg.DevMode && c.cfg.AuditBigQueryTable == "" &&
+		c.cfg.AuditPostgresURL == "" && c.cfg.AuditSpannerDatabase == "") {
+		sink, err := audit.NewMemorySink(c.cfg.AuditSnapshotPath)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create audit sink: %w", err)
+		}
+		p.WithAuditSink(sink)
+		logger.InfoContext(ctx, "audit memory sink enabled", "snapshot", c.cfg.AuditSnapshotPath)
+	}
+
+	if c.cfg.TransparencyLogURL != "" {
+		p.WithTransparencyLog(transparency.NewRekor(&http.Client{}, c.cfg.TransparencyLogURL))
+		logger.InfoContext(ctx, "transparency log enabled", "url", c.cfg.TransparencyLogURL)
+	}
+
+	if c.cfg.EncryptionJWKSEndpoint != "" {
+		keys, err := justification.NewEncryptionKeys(ctx, c.cfg.EncryptionJWKSEndpoint)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to load encryption keys: %w", err)
+		}
+		p.WithEncryptionKeys(keys)
+		logger.InfoContext(ctx, "justification encryption enabled", "jwks_endpoint", c.cfg.EncryptionJWKSEndpoint)
+	}
+
+	if c.cfg.CertificateCAKey != "" {
+		ca, err := justification.LoadCertificateAuthority(ctx, kmsClient, c.cfg.CertificateCAKey, c.cfg.CertificateCAPath)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to load certificate authority: %w", err)
+		}
+		p.WithCertificateAuthority(ca)
+		logger.InfoContext(ctx, "certificate issuance enabled", "ca_key", c.cfg.CertificateCAKey)
+	}
+
+	if c.cfg.Canary.Enabled() {
+		go justification.NewCanary(p, &c.cfg.Canary).Run(ctx)
+		logger.InfoContext(ctx, "canary enabled",
+			"interval", c.cfg.Canary.Interval,
+			"jwks_endpoint", c.cfg.Canary.JWKSEndpoint)
+	}
+
 	jvsAgent := justification.NewJVSAgent(p)
+	if c.cfg.TokenExchangeIssuer != "" {
+		exchanger, err := justification.NewTokenExchanger(ctx, c.cfg)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create token exchanger: %w", err)
+		}
+		jvsAgent.Exchanger = exchanger
+		logger.InfoContext(ctx, "token exchange enabled", "issuer", c.cfg.TokenExchangeIssuer)
+	}
 	jvspb.RegisterJVSServiceServer(grpcServer, jvsAgent)
-	reflection.Register(grpcServer)
 
-	server, err := serving.New(c.cfg.Port)
+	// Create health check, with the readiness check under the "ready" service
+	grpc_health_v1.RegisterHealthServer(grpcServer, serving.NewHealthServer(p.Ready))
+	if c.cfg.Reflection {
+		reflection.Register(grpcServer)
+		logger.InfoContext(ctx, "grpc reflection enabled")
+	}
+
+	if c.cfg.AdminPort != "" {
+		admin, err := justification.NewAdminServer(ctx, p, policy, c.cfg)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create admin server: %w", err)
+		}
+		adminTracker := serving.NewTracker()
+		c.adminGRPCServer = grpcserver.NewChain(logger, c.cfg.ProjectID).
+			WithAuth(admin.UnaryInterceptor).
+			WithInterceptors(adminTracker.UnaryInterceptor).
+			NewServer()
+		jvspb.RegisterAdminServiceServer(c.adminGRPCServer, admin)
+
+		c.adminServer, err = serving.New(c.cfg.AdminPort, c.cfg.ShutdownTimeout)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create admin serving infrastructure: %w", err)
+		}
+		c.adminServer.WithTracker(adminTracker)
+		logger.InfoContext(ctx, "admin api enabled",
+			"port", c.adminServer.Port(),
+			"principals", c.cfg.AdminPrincipals)
+	}
+
+	if c.cfg.DeprovisionWebhookPort != "" {
+		c.webhookServer, err = serving.New(c.cfg.DeprovisionWebhookPort, c.cfg.ShutdownTimeout)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create deprovision webhook serving infrastructure: %w", err)
+		}
+		c.webhookHandler = logging.HTTPInterceptor(logger, c.cfg.ProjectID)(
+			justification.NewDeprovisionWebhook(p, c.cfg.DeprovisionWebhookSecret))
+		logger.InfoContext(ctx, "deprovision webhook enabled", "port", c.webhookServer.Port())
+	}
+
+	server, err := serving.New(c.cfg.Port, c.cfg.ShutdownTimeout)
 	if err != nil {
 		return nil, nil, closer, fmt.Errorf("failed to create serving infrastructure: %w", err)
 	}
+	server.WithTracker(tracker).WithWarmup(c.cfg.WarmupTimeout, p.Ready)
 	return server, grpcServer, closer, nil
 }
@@ -16,12 +16,23 @@ package cli
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 
 	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/logging"
@@ -32,6 +43,7 @@ func TestAPIServerCommand(t *testing.T) {
 	t.Parallel()
 
 	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	keyPath := testSigningKeyPath(t)
 
 	cases := []struct {
 		name   string
@@ -54,10 +66,49 @@ func TestAPIServerCommand(t *testing.T) {
 		{
 			name: "starts",
 			env: map[string]string{
-				"PROJECT_ID": "example-project",
-				"JVS_KEY":    "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[JVS_KEY]",
+				"PROJECT_ID":               "example-project",
+				"JVS_KEY":                  "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[JVS_KEY]",
+				"JVS_KMS_PERMISSION_CHECK": "off",
+			},
+		},
+		{
+			name: "starts_with_kms_emulator",
+			env: map[string]string{
+				"PROJECT_ID":       "example-project",
+				"JVS_KEY":          "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[JVS_KEY]",
+				"JVS_KMS_ENDPOINT": "localhost:9090",
+				"JVS_KMS_INSECURE": "true",
 			},
 		},
+		{
+			name: "starts_with_local_signer",
+			env: map[string]string{
+				"PROJECT_ID":   "example-project",
+				"JVS_SIGNER":   "local",
+				"JVS_KEY_PATH": keyPath,
+			},
+		},
+		{
+			name: "starts_with_admin_api",
+			env: map[string]string{
+				"PROJECT_ID":               "example-project",
+				"JVS_SIGNER":               "local",
+				"JVS_KEY_PATH":             keyPath,
+				"JVS_API_POLICY_PATH":      filepath.Join(t.TempDir(), "policy.json"),
+				"JVS_API_ADMIN_PORT":       "0",
+				"JVS_API_ADMIN_PRINCIPALS": "jvs-admin@example.com",
+				"JVS_API_ADMIN_AUDIENCE":   "https://jvs-admin.example.com",
+			},
+		},
+		{
+			name: "local_signer_missing_key",
+			env: map[string]string{
+				"PROJECT_ID":   "example-project",
+				"JVS_SIGNER":   "local",
+				"JVS_KEY_PATH": filepath.Join(t.TempDir(), "missing.pem"),
+			},
+			expErr: "failed to load signing key",
+		},
 	}
 
 	for _, tc := range cases {
@@ -73,6 +124,8 @@ func TestAPIServerCommand(t *testing.T) {
 				cli.MapLookuper(map[string]string{
 					// Make the test choose a random port.
 					"PORT": "0",
+					// Don't wait for KMS, which these tests don't call.
+					"JVS_WARMUP_TIMEOUT": "0",
 				}),
 			))
 			cmd.testKMSClientOptions = []option.ClientOption{
@@ -118,6 +171,49 @@ func TestAPIServerCommand(t *testing.T) {
 			if got, want := res.GetStatus(), healthpb.HealthCheckResponse_SERVING; got != want {
 				t.Errorf("expected status %v to be %v", got, want)
 			}
+
+			if cmd.adminServer == nil {
+				return
+			}
+			go func() {
+				if err := cmd.adminServer.StartGRPC(serverCtx, cmd.adminGRPCServer); err != nil {
+					t.Error(err)
+				}
+			}()
+
+			adminConn, err := grpc.NewClient(cmd.adminServer.Addr(),
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer adminConn.Close()
+
+			// Callers without an admin token are turned away.
+			_, err = jvspb.NewAdminServiceClient(adminConn).ListCategoryPolicies(ctx, &jvspb.ListCategoryPoliciesRequest{})
+			if got, want := status.Code(err), codes.Unauthenticated; got != want {
+				t.Errorf("expected admin api code %s to be %s: %v", got, want, err)
+			}
 		})
 	}
 }
+
+// testSigningKeyPath writes a new P-256 private key to a temporary file, and
+// returns its path.
+func testSigningKeyPath(tb testing.TB) string {
+	tb.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	path := filepath.Join(tb.TempDir(), "signing-key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/cli"
+)
+
+// bigQueryScope is the OAuth scope required to create and write BigQuery
+// tables.
+const bigQueryScope = "https://www.googleapis.com/auth/bigquery"
+
+var _ cli.Command = (*AuditBackfillCommand)(nil)
+
+type AuditBackfillCommand struct {
+	cli.BaseCommand
+
+	flagProject string
+	flagTable   string
+	flagSince   time.Duration
+	flagStart   time.Time
+	flagEnd     time.Time
+	flagLimit   int
+	flagDryRun  bool
+
+	flagAuthToken        string
+	flagLoggingEndpoint  string
+	flagBigQueryEndpoint string
+}
+
+func (c *AuditBackfillCommand) Desc() string {
+	return `Copy issued tokens from the audit log to BigQuery`
+}
+
+func (c *AuditBackfillCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Copy the audit entries of justification tokens issued by the JVS from Cloud
+  Logging into the BigQuery table of the audit sink, e.g. for tokens issued
+  before the sink was enabled. The table is created if it does not exist.
+  Entries already in the table are deduplicated on a best-effort basis.
+
+  Requires permission to read logs in the project the JVS runs in, e.g.
+  "roles/logging.viewer", and to write the table, e.g.
+  "roles/bigquery.dataEditor".
+
+  Backfill the last 30 days:
+
+      jvsctl audit backfill \
+        -project "my-project" \
+        -table "my-project.jvs.issuances" \
+        -since "720h"
+`
+}
+
+func (c *AuditBackfillCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "project",
+		Target:  &c.flagProject,
+		Example: "my-project",
+		EnvVar:  "JVSCTL_AUDIT_PROJECT",
+		Usage:   `The Google Cloud project the JVS writes its logs to.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "table",
+		Target:  &c.flagTable,
+		Example: "my-project.jvs.issuances",
+		EnvVar:  "JVSCTL_AUDIT_BIGQUERY_TABLE",
+		Usage:   `The BigQuery table to copy entries to, in the format project.dataset.table.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "since",
+		Target:  &c.flagSince,
+		Example: "720h",
+		Default: 24 * time.Hour,
+		Usage:   `Copy tokens issued in this duration before now. Ignored if -start is set.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start",
+		Target:  &c.flagStart,
+		Example: "2023-06-01T00:00:00Z",
+		Usage:   `Copy tokens issued at or after this time, in RFC 3339 format.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "end",
+		Target:  &c.flagEnd,
+		Example: "2023-06-02T00:00:00Z",
+		Usage:   `Copy tokens issued before this time, in RFC 3339 format.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "limit",
+		Target:  &c.flagLimit,
+		Default: 10000,
+		Usage:   `The maximum number of tokens to copy.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "dry-run",
+		Target:  &c.flagDryRun,
+		Default: false,
+		Usage:   `Count the tokens to copy, without writing them.`,
+	})
+
+	// Server flags
+	f = set.NewSection("SERVER OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "auth-token",
+		Target:  &c.flagAuthToken,
+		Example: "ya29.c...",
+		EnvVar:  "JVSCTL_AUDIT_AUTH_TOKEN",
+		Usage: `An OAuth access token to read logs and write the table with. If ` +
+			`unset, Application Default Credentials are used.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "logging-endpoint",
+		Target:  &c.flagLoggingEndpoint,
+		Default: audit.DefaultCloudLoggingEndpoint,
+		Hidden:  true,
+		Usage:   `The Cloud Logging API endpoint.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "bigquery-endpoint",
+		Target: &c.flagBigQueryEndpoint,
+		Hidden: true,
+		Usage:  `The BigQuery API endpoint.`,
+	})
+
+	return set
+}
+
+func (c *AuditBackfillCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagProject == "" {
+		return fmt.Errorf("project is required")
+	}
+	if c.flagTable == "" {
+		return fmt.Errorf("table is required")
+	}
+	if _, _, _, err := audit.ParseTableID(c.flagTable); err != nil {
+		return err
+	}
+	if c.flagLimit <= 0 {
+		return fmt.Errorf("limit must be positive")
+	}
+
+	start := c.flagStart
+	if start.IsZero() && c.flagSince > 0 {
+		start = time.Now().Add(-c.flagSince)
+	}
+
+	client, err := auditHTTPClient(ctx, c.flagAuthToken, loggingReadScope, bigQueryScope)
+	if err != nil {
+		return err
+	}
+
+	searcher := audit.NewCloudLoggingSearcher(client, c.flagLoggingEndpoint, c.flagProject)
+	entries, err := searcher.Search(ctx, &audit.Query{
+		Start: start,
+		End:   c.flagEnd,
+		Limit: c.flagLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search audit log: %w", err)
+	}
+
+	if c.flagDryRun {
+		fmt.Fprintf(c.Stdout(), "Found %d tokens to copy to %s\n", len(entries), c.flagTable)
+		return nil
+	}
+
+	opts := []option.ClientOption{option.WithHTTPClient(client)}
+	if c.flagBigQueryEndpoint != "" {
+		opts = append(opts, option.WithEndpoint(c.flagBigQueryEndpoint))
+	}
+	sink, err := audit.NewBigQuerySink(ctx, c.flagTable, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create audit sink: %w", err)
+	}
+	if err := sink.EnsureTable(ctx); err != nil {
+		return fmt.Errorf("failed to create audit table: %w", err)
+	}
+	if err := sink.Write(ctx, entries...); err != nil {
+		return fmt.Errorf("failed to write audit entries: %w", err)
+	}
+
+	fmt.Fprintf(c.Stdout(), "Copied %d tokens to %s\n", len(entries), c.flagTable)
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestAuditBackfillCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/entries:list":
+			_, _ = w.Write([]byte(`{"entries":[{"jsonPayload":{"jvs_audit":{
+				"jti":"test-jti",
+				"requestor":"you@example.com",
+				"categories":["explanation"],
+				"justifications":[{"category":"explanation","value":"prod outage"}],
+				"issued_at":"2023-06-01T00:00:00Z",
+				"expires_at":"2023-06-01T00:15:00Z"
+			}}}]}`))
+		case "/projects/my-project/datasets/jvs/tables":
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":{"code":409,"message":"already exists"}}`))
+		case "/projects/my-project/datasets/jvs/tables/issuances/insertAll":
+			var req struct {
+				Rows []struct {
+					InsertID string `json:"insertId"`
+				} `json:"rows"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if len(req.Rows) != 1 || req.Rows[0].InsertID != "test-jti" {
+				http.Error(w, "unexpected rows", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name      string
+		args      []string
+		expOutput string
+		expErr    string
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "missing_project",
+			args:   []string{"-table", "my-project.jvs.issuances"},
+			expErr: "project is required",
+		},
+		{
+			name:   "missing_table",
+			args:   []string{"-project", "my-project"},
+			expErr: "table is required",
+		},
+		{
+			name:   "invalid_table",
+			args:   []string{"-project", "my-project", "-table", "issuances"},
+			expErr: `table "issuances" must be in the format project.dataset.table`,
+		},
+		{
+			name:   "unauthorized",
+			args:   []string{"-project", "my-project", "-table", "my-project.jvs.issuances", "-auth-token", "wrong"},
+			expErr: "unexpected status 401",
+		},
+		{
+			name:      "dry_run",
+			args:      []string{"-project", "my-project", "-table", "my-project.jvs.issuances", "-auth-token", "test-token", "-dry-run"},
+			expOutput: "Found 1 tokens to copy to my-project.jvs.issuances",
+		},
+		{
+			name:      "success",
+			args:      []string{"-project", "my-project", "-table", "my-project.jvs.issuances", "-auth-token", "test-token"},
+			expOutput: "Copied 1 tokens to my-project.jvs.issuances",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cmd AuditBackfillCommand
+			_, stdout, _ := cmd.Pipe()
+
+			args := append([]string{"-logging-endpoint", srv.URL, "-bigquery-endpoint", srv.URL}, tc.args...)
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+		})
+	}
+}
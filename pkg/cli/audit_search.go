@@ -0,0 +1,269 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/cli"
+)
+
+// loggingReadScope is the OAuth scope required to read Cloud Logging entries.
+const loggingReadScope = "https://www.googleapis.com/auth/logging.read"
+
+var _ cli.Command = (*AuditSearchCommand)(nil)
+
+type AuditSearchCommand struct {
+	cli.BaseCommand
+
+	flagProject   string
+	flagRequestor string
+	flagCategory  string
+	flagSince     time.Duration
+	flagStart     time.Time
+	flagEnd       time.Time
+	flagLimit     int
+	flagFormat    string
+
+	flagAuthToken       string
+	flagLoggingEndpoint string
+}
+
+func (c *AuditSearchCommand) Desc() string {
+	return `Search the audit log for issued tokens`
+}
+
+func (c *AuditSearchCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Search the audit log in Cloud Logging for justification tokens issued by the
+  JVS, newest first. Requires permission to read logs in the project the JVS
+  runs in, e.g. "roles/logging.viewer".
+
+  Find tokens issued to a user in the last week:
+
+      jvsctl audit search \
+        -project "my-project" \
+        -requestor "you@example.com" \
+        -since "168h"
+
+  Find tokens with a JIRA justification in a time range, as JSON:
+
+      jvsctl audit search \
+        -project "my-project" \
+        -category "jira" \
+        -start "2023-06-01T00:00:00Z" \
+        -end "2023-06-02T00:00:00Z" \
+        -format "json"
+`
+}
+
+func (c *AuditSearchCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "project",
+		Target:  &c.flagProject,
+		Example: "my-project",
+		EnvVar:  "JVSCTL_AUDIT_PROJECT",
+		Usage:   `The Google Cloud project the JVS writes its logs to.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "requestor",
+		Target:  &c.flagRequestor,
+		Example: "you@example.com",
+		Usage:   `Only show tokens requested by this principal.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "category",
+		Target:  &c.flagCategory,
+		Example: "jira",
+		Usage:   `Only show tokens with a justification in this category.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "since",
+		Target:  &c.flagSince,
+		Example: "168h",
+		Default: 24 * time.Hour,
+		Usage:   `Only show tokens issued in this duration before now. Ignored if -start is set.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "start",
+		Target:  &c.flagStart,
+		Example: "2023-06-01T00:00:00Z",
+		Usage:   `Only show tokens issued at or after this time, in RFC 3339 format.`,
+	})
+
+	f.TimeVar(time.RFC3339, &cli.TimeVar{
+		Name:    "end",
+		Target:  &c.flagEnd,
+		Example: "2023-06-02T00:00:00Z",
+		Usage:   `Only show tokens issued before this time, in RFC 3339 format.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "limit",
+		Target:  &c.flagLimit,
+		Default: 100,
+		Usage:   `The maximum number of tokens to show.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Aliases: []string{"f"},
+		Target:  &c.flagFormat,
+		Example: "table",
+		Default: "table",
+		Usage:   `The target output format. Valid values are: table, json.`,
+	})
+
+	// Server flags
+	f = set.NewSection("SERVER OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "auth-token",
+		Target:  &c.flagAuthToken,
+		Example: "ya29.c...",
+		EnvVar:  "JVSCTL_AUDIT_AUTH_TOKEN",
+		Usage: `An OAuth access token to read logs with. If unset, Application ` +
+			`Default Credentials are used.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "logging-endpoint",
+		Target:  &c.flagLoggingEndpoint,
+		Default: audit.DefaultCloudLoggingEndpoint,
+		Hidden:  true,
+		Usage:   `The Cloud Logging API endpoint.`,
+	})
+
+	return set
+}
+
+func (c *AuditSearchCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagProject == "" {
+		return fmt.Errorf("project is required")
+	}
+	if c.flagLimit <= 0 {
+		return fmt.Errorf("limit must be positive")
+	}
+
+	format := strings.TrimSpace(strings.ToLower(c.flagFormat))
+	switch format {
+	case "", "table", "text", "json":
+	default:
+		return fmt.Errorf("unknown formatter %q", format)
+	}
+
+	start := c.flagStart
+	if start.IsZero() && c.flagSince > 0 {
+		start = time.Now().Add(-c.flagSince)
+	}
+
+	client, err := auditHTTPClient(ctx, c.flagAuthToken, loggingReadScope)
+	if err != nil {
+		return err
+	}
+
+	searcher := audit.NewCloudLoggingSearcher(client, c.flagLoggingEndpoint, c.flagProject)
+	entries, err := searcher.Search(ctx, &audit.Query{
+		Requestor: c.flagRequestor,
+		Category:  c.flagCategory,
+		Start:     start,
+		End:       c.flagEnd,
+		Limit:     c.flagLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search audit log: %w", err)
+	}
+
+	if format == "json" {
+		if err := json.NewEncoder(c.Stdout()).Encode(entries); err != nil {
+			return fmt.Errorf("failed to encode to json: %w", err)
+		}
+		return nil
+	}
+	return printAuditTable(c.Stdout(), entries)
+}
+
+// auditHTTPClient returns an HTTP client authenticated with the access token,
+// or Application Default Credentials with the scopes if the token is empty.
+func auditHTTPClient(ctx context.Context, accessToken string, scopes ...string) (*http.Client, error) {
+	if accessToken != "" {
+		return oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{
+			AccessToken: accessToken,
+		})), nil
+	}
+
+	client, err := google.DefaultClient(ctx, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find application default credentials "+
+			"(use -auth-token to provide a token explicitly): %w", err)
+	}
+	return client, nil
+}
+
+func printAuditTable(w io.Writer, entries []*audit.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ISSUED\tREQUESTOR\tCATEGORY\tJUSTIFICATION\tEXPIRES\tID")
+	for _, e := range entries {
+		categories := make([]string, 0, len(e.Justifications))
+		values := make([]string, 0, len(e.Justifications))
+		for _, j := range e.Justifications {
+			categories = append(categories, j.Category)
+			values = append(values, j.Value)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.IssuedAt.UTC().Format(time.RFC3339),
+			e.Requestor,
+			strings.Join(categories, ","),
+			strings.Join(values, ","),
+			e.ExpiresAt.UTC().Format(time.RFC3339),
+			e.ID)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to print table: %w", err)
+	}
+	return nil
+}
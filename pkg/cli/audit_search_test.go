@@ -0,0 +1,127 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestAuditSearchCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Filter string `json:"filter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !strings.Contains(req.Filter, `requestor="you@example.com"`) {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"entries":[{"jsonPayload":{"jvs_audit":{
+			"jti":"test-jti",
+			"requestor":"you@example.com",
+			"categories":["explanation"],
+			"justifications":[{"category":"explanation","value":"prod outage"}],
+			"issued_at":"2023-06-01T00:00:00Z",
+			"expires_at":"2023-06-01T00:15:00Z"
+		}}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name      string
+		args      []string
+		expOutput string
+		expErr    string
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "missing_project",
+			args:   []string{"-auth-token", "test-token"},
+			expErr: "project is required",
+		},
+		{
+			name:   "invalid_format",
+			args:   []string{"-project", "my-project", "-format", "xml"},
+			expErr: `unknown formatter "xml"`,
+		},
+		{
+			name:   "unauthorized",
+			args:   []string{"-project", "my-project", "-auth-token", "wrong"},
+			expErr: "unexpected status 401",
+		},
+		{
+			name: "table",
+			args: []string{"-project", "my-project", "-auth-token", "test-token", "-requestor", "you@example.com"},
+			expOutput: `
+ISSUED                REQUESTOR        CATEGORY     JUSTIFICATION  EXPIRES               ID
+2023-06-01T00:00:00Z  you@example.com  explanation  prod outage    2023-06-01T00:15:00Z  test-jti
+`,
+		},
+		{
+			name:      "json",
+			args:      []string{"-project", "my-project", "-auth-token", "test-token", "-requestor", "you@example.com", "-format", "json"},
+			expOutput: `[{"jti":"test-jti","requestor":"you@example.com","categories":["explanation"],"justifications":[{"category":"explanation","value":"prod outage"}],"audiences":null,"issued_at":"2023-06-01T00:00:00Z","expires_at":"2023-06-01T00:15:00Z"}]`,
+		},
+		{
+			name:      "no_results",
+			args:      []string{"-project", "my-project", "-auth-token", "test-token", "-requestor", "nobody@example.com", "-format", "json"},
+			expOutput: `[]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cmd AuditSearchCommand
+			_, stdout, _ := cmd.Pipe()
+
+			args := append([]string{"-logging-endpoint", srv.URL}, tc.args...)
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+		})
+	}
+}
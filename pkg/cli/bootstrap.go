@@ -0,0 +1,406 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/iam"
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*BootstrapCommand)(nil)
+
+// bootstrapGrant is the key ring roles granted to the service account of a
+// JVS service. They match the roles granted by the Terraform modules.
+type bootstrapGrant struct {
+	service string
+	member  string
+	roles   []iam.RoleName
+}
+
+type BootstrapCommand struct {
+	cli.BaseCommand
+
+	flagProject  string
+	flagLocation string
+	flagKeyRing  string
+	flagKey      string
+
+	flagAPIServiceAccount       string
+	flagUIServiceAccount        string
+	flagPublicKeyServiceAccount string
+	flagRotationServiceAccount  string
+
+	flagKMSEndpoint string
+	flagKMSInsecure bool
+
+	// testKMSClientOptions are KMS client options to override during testing.
+	testKMSClientOptions []option.ClientOption
+}
+
+func (c *BootstrapCommand) Desc() string {
+	return `Create the KMS key for the JVS without Terraform`
+}
+
+func (c *BootstrapCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Create the KMS key ring and signing key the JVS services use, mark the first
+  key version as primary, and grant the service accounts of the services the
+  roles they need on the key ring. Then print the configuration of the
+  services. Existing key rings and keys are reused, so it is safe to run again,
+  e.g. to grant roles to more service accounts.
+
+  Requires permission to create keys and set IAM policies in the project, e.g.
+  "roles/cloudkms.admin".
+
+  Bootstrap the JVS in a project:
+
+      jvsctl bootstrap \
+        -project "my-project" \
+        -api-service-account "jvs-api@my-project.iam.gserviceaccount.com" \
+        -public-key-service-account "jvs-public-key@my-project.iam.gserviceaccount.com" \
+        -rotation-service-account "jvs-rotation@my-project.iam.gserviceaccount.com"
+`
+}
+
+func (c *BootstrapCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "project",
+		Target:  &c.flagProject,
+		Example: "my-project",
+		EnvVar:  "PROJECT_ID",
+		Usage:   `The Google Cloud project to create the key in.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "location",
+		Target:  &c.flagLocation,
+		Default: "global",
+		Usage:   `The KMS location of the key ring.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-ring",
+		Target:  &c.flagKeyRing,
+		Default: "jvs",
+		Usage:   `The name of the key ring to create or reuse.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key",
+		Target:  &c.flagKey,
+		Default: "jvs-signing",
+		Usage:   `The name of the signing key to create or reuse.`,
+	})
+
+	// Service account options
+	f = set.NewSection("SERVICE ACCOUNT OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "api-service-account",
+		Target:  &c.flagAPIServiceAccount,
+		Example: "jvs-api@my-project.iam.gserviceaccount.com",
+		Usage:   `The service account of the Justification API, granted permission to sign with the key.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "ui-service-account",
+		Target:  &c.flagUIServiceAccount,
+		Example: "jvs-ui@my-project.iam.gserviceaccount.com",
+		Usage:   `The service account of the UI, granted permission to sign with the key.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "public-key-service-account",
+		Target:  &c.flagPublicKeyServiceAccount,
+		Example: "jvs-public-key@my-project.iam.gserviceaccount.com",
+		Usage:   `The service account of the Public Key API, granted permission to read the public keys.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "rotation-service-account",
+		Target:  &c.flagRotationServiceAccount,
+		Example: "jvs-rotation@my-project.iam.gserviceaccount.com",
+		Usage:   `The service account of the Cert Rotation API, granted permission to manage the key versions.`,
+	})
+
+	// KMS options
+	f = set.NewSection("KMS OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-endpoint",
+		Target:  &c.flagKMSEndpoint,
+		EnvVar:  "JVS_KMS_ENDPOINT",
+		Example: "localhost:9090",
+		Usage:   "Override the Cloud KMS API endpoint, e.g. for a local KMS emulator.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "kms-insecure",
+		Target:  &c.flagKMSInsecure,
+		EnvVar:  "JVS_KMS_INSECURE",
+		Default: false,
+		Usage:   "Connect to the KMS endpoint without TLS or authentication.",
+	})
+
+	return set
+}
+
+func (c *BootstrapCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagProject == "" {
+		return fmt.Errorf("project is required")
+	}
+	if c.flagLocation == "" || c.flagKeyRing == "" || c.flagKey == "" {
+		return fmt.Errorf("location, key-ring and key are required")
+	}
+	if c.flagKMSInsecure && c.flagKMSEndpoint == "" {
+		return fmt.Errorf("kms-insecure requires kms-endpoint to be set")
+	}
+
+	grants := []*bootstrapGrant{
+		{
+			service: "Justification API",
+			member:  c.flagAPIServiceAccount,
+			roles:   []iam.RoleName{"roles/cloudkms.viewer", "roles/cloudkms.cryptoOperator"},
+		},
+		{
+			service: "UI",
+			member:  c.flagUIServiceAccount,
+			roles:   []iam.RoleName{"roles/cloudkms.viewer", "roles/cloudkms.cryptoOperator"},
+		},
+		{
+			service: "Public Key API",
+			member:  c.flagPublicKeyServiceAccount,
+			roles:   []iam.RoleName{"roles/cloudkms.publicKeyViewer", "roles/cloudkms.viewer"},
+		},
+		{
+			service: "Cert Rotation API",
+			member:  c.flagRotationServiceAccount,
+			roles:   []iam.RoleName{"roles/cloudkms.admin"},
+		},
+	}
+
+	kmsClient, err := jvscrypto.NewKMSClient(ctx, jvscrypto.DefaultKMSRetryPolicy(), append(
+		kmsClientOptions(c.flagKMSEndpoint, c.flagKMSInsecure),
+		c.testKMSClientOptions...)...)
+	if err != nil {
+		return fmt.Errorf("failed to setup kms client: %w", err)
+	}
+	defer kmsClient.Close()
+
+	stdout := c.Stdout()
+	parent := fmt.Sprintf("projects/%s/locations/%s", c.flagProject, c.flagLocation)
+	keyRing, err := ensureKeyRing(ctx, stdout, kmsClient, parent, c.flagKeyRing)
+	if err != nil {
+		return err
+	}
+	key, err := ensureKey(ctx, stdout, kmsClient, keyRing, c.flagKey, &kmspb.CryptoKey{
+		Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+		VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+			Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := ensurePrimary(ctx, stdout, kmsClient, key); err != nil {
+		return err
+	}
+	if err := grantRoles(ctx, stdout, kmsClient.ResourceIAM(keyRing), grants); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "\nConfigure the JVS services with:\n\n")
+	fmt.Fprintf(stdout, "  export PROJECT_ID=%q\n\n", c.flagProject)
+	fmt.Fprintf(stdout, "  # Justification API and UI\n")
+	fmt.Fprintf(stdout, "  export JVS_KEY=%q\n\n", key)
+	fmt.Fprintf(stdout, "  # Public Key API and Cert Rotation API\n")
+	fmt.Fprintf(stdout, "  export JVS_KEY_NAMES=%q\n", key)
+	return nil
+}
+
+// ensureKeyRing creates the key ring in the parent location if it does not
+// exist, and returns its name.
+func ensureKeyRing(ctx context.Context, w io.Writer, client *kms.KeyManagementClient, parent, id string) (string, error) {
+	name := parent + "/keyRings/" + id
+
+	if _, err := client.CreateKeyRing(ctx, &kmspb.CreateKeyRingRequest{
+		Parent:    parent,
+		KeyRingId: id,
+		KeyRing:   &kmspb.KeyRing{},
+	}); err != nil {
+		if status.Code(err) != codes.AlreadyExists {
+			return "", fmt.Errorf("failed to create key ring %s: %w", name, err)
+		}
+		fmt.Fprintf(w, "Using existing key ring %s\n", name)
+		return name, nil
+	}
+	fmt.Fprintf(w, "Created key ring %s\n", name)
+	return name, nil
+}
+
+// ensureKey creates the key in the key ring from the template if it does not
+// exist, and returns its name. An existing key must have the purpose and
+// algorithm of the template.
+func ensureKey(ctx context.Context, w io.Writer, client *kms.KeyManagementClient, keyRing, id string, template *kmspb.CryptoKey) (string, error) {
+	name := keyRing + "/cryptoKeys/" + id
+
+	if _, err := client.CreateCryptoKey(ctx, &kmspb.CreateCryptoKeyRequest{
+		Parent:      keyRing,
+		CryptoKeyId: id,
+		CryptoKey:   template,
+	}); err != nil {
+		if status.Code(err) != codes.AlreadyExists {
+			return "", fmt.Errorf("failed to create key %s: %w", name, err)
+		}
+
+		key, err := client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: name})
+		if err != nil {
+			return "", fmt.Errorf("failed to get key %s: %w", name, err)
+		}
+		if key.GetPurpose() != template.GetPurpose() ||
+			key.GetVersionTemplate().GetAlgorithm() != template.GetVersionTemplate().GetAlgorithm() {
+			return "", fmt.Errorf("existing key %s has purpose %s and algorithm %s, not %s and %s",
+				name, key.GetPurpose(), key.GetVersionTemplate().GetAlgorithm(),
+				template.GetPurpose(), template.GetVersionTemplate().GetAlgorithm())
+		}
+		fmt.Fprintf(w, "Using existing key %s\n", name)
+		return name, nil
+	}
+	fmt.Fprintf(w, "Created key %s\n", name)
+	return name, nil
+}
+
+// ensurePrimary marks the newest usable version of the key as primary, unless
+// the key already has a primary version.
+func ensurePrimary(ctx context.Context, w io.Writer, client *kms.KeyManagementClient, key string) error {
+	primary, err := jvscrypto.GetPrimary(ctx, client, key)
+	if err != nil {
+		return fmt.Errorf("failed to get primary of key %s: %w", key, err)
+	}
+	if primary != "" {
+		fmt.Fprintf(w, "Using existing primary version %s\n", primary)
+		return nil
+	}
+
+	// The versions are listed oldest first. A version created with the key may
+	// still be generating, which the JVS services wait for.
+	var version string
+	it := client.ListCryptoKeyVersions(ctx, &kmspb.ListCryptoKeyVersionsRequest{
+		Parent: key,
+		Filter: "state=ENABLED OR state=PENDING_GENERATION",
+	})
+	for {
+		ver, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list versions of key %s: %w", key, err)
+		}
+		version = ver.GetName()
+	}
+
+	if version == "" {
+		ver, err := client.CreateCryptoKeyVersion(ctx, &kmspb.CreateCryptoKeyVersionRequest{
+			Parent:           key,
+			CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create version of key %s: %w", key, err)
+		}
+		version = ver.GetName()
+		fmt.Fprintf(w, "Created version %s\n", version)
+	}
+
+	if err := jvscrypto.SetPrimary(ctx, client, key, version); err != nil {
+		return fmt.Errorf("failed to set primary of key %s: %w", key, err)
+	}
+	fmt.Fprintf(w, "Set primary version %s\n", version)
+	return nil
+}
+
+// grantRoles adds the roles of the service accounts to the IAM policy of the
+// key ring, and updates it if any are missing.
+func grantRoles(ctx context.Context, w io.Writer, h *iam.Handle, grants []*bootstrapGrant) error {
+	policy, err := h.Policy(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get key ring policy: %w", err)
+	}
+
+	var granted []string
+	for _, g := range grants {
+		if g.member == "" {
+			continue
+		}
+		member := iamMember(g.member)
+		for _, role := range g.roles {
+			if policy.HasRole(member, role) {
+				continue
+			}
+			policy.Add(member, role)
+			granted = append(granted, fmt.Sprintf("Granted %s to %s (%s)", role, member, g.service))
+		}
+	}
+	if len(granted) == 0 {
+		return nil
+	}
+
+	if err := h.SetPolicy(ctx, policy); err != nil {
+		return fmt.Errorf("failed to set key ring policy: %w", err)
+	}
+	for _, line := range granted {
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+// iamMember returns the IAM member of the service account email. Members with
+// a type, e.g. "group:jvs@example.com", are returned as is.
+func iamMember(account string) string {
+	if strings.Contains(account, ":") {
+		return account
+	}
+	return "serviceAccount:" + account
+}
@@ -0,0 +1,358 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const (
+	testKeyRing = "projects/my-project/locations/global/keyRings/jvs"
+	testKey     = testKeyRing + "/cryptoKeys/jvs-signing"
+)
+
+func TestBootstrapCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name        string
+		args        []string
+		setup       func(s *fakeBootstrapKMS)
+		expOutput   string
+		expErr      string
+		expLabels   map[string]string
+		expBindings []*iampb.Binding
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "missing_project",
+			args:   []string{},
+			expErr: "project is required",
+		},
+		{
+			name:   "insecure_without_endpoint",
+			args:   []string{"-project", "my-project", "-kms-insecure"},
+			expErr: "kms-insecure requires kms-endpoint to be set",
+		},
+		{
+			name: "creates_key",
+			args: []string{
+				"-project", "my-project",
+				"-api-service-account", "jvs-api@my-project.iam.gserviceaccount.com",
+				"-rotation-service-account", "jvs-rotation@my-project.iam.gserviceaccount.com",
+			},
+			expOutput: `
+Created key ring projects/my-project/locations/global/keyRings/jvs
+Created key projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing
+Set primary version projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/1
+Granted roles/cloudkms.viewer to serviceAccount:jvs-api@my-project.iam.gserviceaccount.com (Justification API)
+Granted roles/cloudkms.cryptoOperator to serviceAccount:jvs-api@my-project.iam.gserviceaccount.com (Justification API)
+Granted roles/cloudkms.admin to serviceAccount:jvs-rotation@my-project.iam.gserviceaccount.com (Cert Rotation API)
+
+Configure the JVS services with:
+
+  export PROJECT_ID="my-project"
+
+  # Justification API and UI
+  export JVS_KEY="projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing"
+
+  # Public Key API and Cert Rotation API
+  export JVS_KEY_NAMES="projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing"
+`,
+			expLabels: map[string]string{"primary": "ver_1"},
+			expBindings: []*iampb.Binding{
+				{Role: "roles/cloudkms.viewer", Members: []string{"serviceAccount:jvs-api@my-project.iam.gserviceaccount.com"}},
+				{Role: "roles/cloudkms.cryptoOperator", Members: []string{"serviceAccount:jvs-api@my-project.iam.gserviceaccount.com"}},
+				{Role: "roles/cloudkms.admin", Members: []string{"serviceAccount:jvs-rotation@my-project.iam.gserviceaccount.com"}},
+			},
+		},
+		{
+			name: "reuses_existing",
+			args: []string{
+				"-project", "my-project",
+				"-public-key-service-account", "group:jvs@example.com",
+			},
+			setup: func(s *fakeBootstrapKMS) {
+				s.keyRings[testKeyRing] = true
+				s.addKey(testKey, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256, 3)
+				s.keys[testKey].Labels = map[string]string{"primary": "ver_3"}
+				s.policies[testKeyRing] = &iampb.Policy{
+					Bindings: []*iampb.Binding{
+						{Role: "roles/cloudkms.viewer", Members: []string{"group:jvs@example.com"}},
+					},
+				}
+			},
+			expOutput: `
+Using existing key ring projects/my-project/locations/global/keyRings/jvs
+Using existing key projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing
+Using existing primary version projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/3
+Granted roles/cloudkms.publicKeyViewer to group:jvs@example.com (Public Key API)
+
+Configure the JVS services with:
+
+  export PROJECT_ID="my-project"
+
+  # Justification API and UI
+  export JVS_KEY="projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing"
+
+  # Public Key API and Cert Rotation API
+  export JVS_KEY_NAMES="projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing"
+`,
+			expLabels: map[string]string{"primary": "ver_3"},
+			expBindings: []*iampb.Binding{
+				{Role: "roles/cloudkms.viewer", Members: []string{"group:jvs@example.com"}},
+				{Role: "roles/cloudkms.publicKeyViewer", Members: []string{"group:jvs@example.com"}},
+			},
+		},
+		{
+			name: "existing_key_without_versions",
+			args: []string{"-project", "my-project"},
+			setup: func(s *fakeBootstrapKMS) {
+				s.keyRings[testKeyRing] = true
+				s.addKey(testKey, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256, 0)
+			},
+			expOutput: `
+Using existing key ring projects/my-project/locations/global/keyRings/jvs
+Using existing key projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing
+Created version projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/1
+Set primary version projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/1
+
+Configure the JVS services with:
+
+  export PROJECT_ID="my-project"
+
+  # Justification API and UI
+  export JVS_KEY="projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing"
+
+  # Public Key API and Cert Rotation API
+  export JVS_KEY_NAMES="projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing"
+`,
+			expLabels: map[string]string{"primary": "ver_1"},
+		},
+		{
+			name: "existing_key_wrong_algorithm",
+			args: []string{"-project", "my-project"},
+			setup: func(s *fakeBootstrapKMS) {
+				s.keyRings[testKeyRing] = true
+				s.addKey(testKey, kmspb.CryptoKeyVersion_RSA_SIGN_PKCS1_2048_SHA256, 1)
+			},
+			expOutput: `
+Using existing key ring projects/my-project/locations/global/keyRings/jvs
+`,
+			expErr: "has purpose ASYMMETRIC_SIGN and algorithm RSA_SIGN_PKCS1_2048_SHA256, " +
+				"not ASYMMETRIC_SIGN and EC_SIGN_P256_SHA256",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &fakeBootstrapKMS{
+				keyRings: make(map[string]bool),
+				keys:     make(map[string]*kmspb.CryptoKey),
+				versions: make(map[string][]*kmspb.CryptoKeyVersion),
+				policies: make(map[string]*iampb.Policy),
+			}
+			if tc.setup != nil {
+				tc.setup(fake)
+			}
+			_, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, &fakeBootstrapKMSServer{fakeBootstrapKMS: fake})
+				iampb.RegisterIAMPolicyServer(s, &fakeBootstrapIAMServer{fakeBootstrapKMS: fake})
+			})
+
+			var cmd BootstrapCommand
+			cmd.testKMSClientOptions = []option.ClientOption{option.WithGRPCConn(conn)}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(tc.expLabels, fake.keys[testKey].GetLabels()); diff != "" {
+				t.Errorf("labels (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.expBindings, fake.policies[testKeyRing].GetBindings(), protocmp.Transform()); diff != "" {
+				t.Errorf("bindings (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// fakeBootstrapKMS is the state of the fake KMS key rings, keys and IAM
+// policies.
+type fakeBootstrapKMS struct {
+	mu       sync.Mutex
+	keyRings map[string]bool
+	keys     map[string]*kmspb.CryptoKey
+	versions map[string][]*kmspb.CryptoKeyVersion
+	policies map[string]*iampb.Policy
+}
+
+// addKey adds an asymmetric signing key with the number of versions. The
+// caller must hold the lock, or not have started the server.
+func (s *fakeBootstrapKMS) addKey(name string, alg kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm, versions int) {
+	s.keys[name] = &kmspb.CryptoKey{
+		Name:    name,
+		Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+		VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+			Algorithm: alg,
+		},
+	}
+	for i := 0; i < versions; i++ {
+		s.addVersion(name, kmspb.CryptoKeyVersion_ENABLED)
+	}
+}
+
+func (s *fakeBootstrapKMS) addVersion(key string, state kmspb.CryptoKeyVersion_CryptoKeyVersionState) *kmspb.CryptoKeyVersion {
+	ver := &kmspb.CryptoKeyVersion{
+		Name:  fmt.Sprintf("%s/cryptoKeyVersions/%d", key, len(s.versions[key])+1),
+		State: state,
+	}
+	s.versions[key] = append(s.versions[key], ver)
+	return ver
+}
+
+// fakeBootstrapKMSServer is the KMS API of the fake.
+type fakeBootstrapKMSServer struct {
+	kmspb.UnimplementedKeyManagementServiceServer
+	*fakeBootstrapKMS
+}
+
+func (s *fakeBootstrapKMSServer) CreateKeyRing(ctx context.Context, req *kmspb.CreateKeyRingRequest) (*kmspb.KeyRing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := req.GetParent() + "/keyRings/" + req.GetKeyRingId()
+	if s.keyRings[name] {
+		return nil, status.Errorf(codes.AlreadyExists, "key ring %s already exists", name)
+	}
+	s.keyRings[name] = true
+	return &kmspb.KeyRing{Name: name}, nil
+}
+
+func (s *fakeBootstrapKMSServer) CreateCryptoKey(ctx context.Context, req *kmspb.CreateCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.keyRings[req.GetParent()] {
+		return nil, status.Errorf(codes.NotFound, "key ring %s not found", req.GetParent())
+	}
+	name := req.GetParent() + "/cryptoKeys/" + req.GetCryptoKeyId()
+	if _, ok := s.keys[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "key %s already exists", name)
+	}
+
+	key := proto.Clone(req.GetCryptoKey()).(*kmspb.CryptoKey) //nolint:forcetypeassert // Clone returns the same type.
+	key.Name = name
+	s.keys[name] = key
+	s.addVersion(name, kmspb.CryptoKeyVersion_PENDING_GENERATION)
+	return key, nil
+}
+
+func (s *fakeBootstrapKMSServer) GetCryptoKey(ctx context.Context, req *kmspb.GetCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "key %s not found", req.GetName())
+	}
+	return key, nil
+}
+
+func (s *fakeBootstrapKMSServer) UpdateCryptoKey(ctx context.Context, req *kmspb.UpdateCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[req.GetCryptoKey().GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "key %s not found", req.GetCryptoKey().GetName())
+	}
+	key.Labels = req.GetCryptoKey().GetLabels()
+	return key, nil
+}
+
+func (s *fakeBootstrapKMSServer) ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest) (*kmspb.ListCryptoKeyVersionsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &kmspb.ListCryptoKeyVersionsResponse{
+		CryptoKeyVersions: s.versions[req.GetParent()],
+	}, nil
+}
+
+func (s *fakeBootstrapKMSServer) CreateCryptoKeyVersion(ctx context.Context, req *kmspb.CreateCryptoKeyVersionRequest) (*kmspb.CryptoKeyVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addVersion(req.GetParent(), kmspb.CryptoKeyVersion_PENDING_GENERATION), nil
+}
+
+// fakeBootstrapIAMServer is the IAM API of the fake.
+type fakeBootstrapIAMServer struct {
+	iampb.UnimplementedIAMPolicyServer
+	*fakeBootstrapKMS
+}
+
+func (s *fakeBootstrapIAMServer) GetIamPolicy(ctx context.Context, req *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy, ok := s.policies[req.GetResource()]; ok {
+		return policy, nil
+	}
+	return &iampb.Policy{}, nil
+}
+
+func (s *fakeBootstrapIAMServer) SetIamPolicy(ctx context.Context, req *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policies[req.GetResource()] = req.GetPolicy()
+	return req.GetPolicy(), nil
+}
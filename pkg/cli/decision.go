@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/decision"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// withDecisionTraces records the traces of denied requests in memory, or in
+// the decision trace directory, if decision traces are enabled.
+func withDecisionTraces(ctx context.Context, cfg *config.JustificationConfig, p *justification.Processor) error {
+	if cfg.DecisionTraceRetention <= 0 {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx)
+	if dir := cfg.DecisionTraceDir; dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to open decision trace dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("decision trace dir %q is not a directory", dir)
+		}
+		p.WithDecisionTraces(decision.NewFileStore(dir, cfg.DecisionTraceRetention))
+		logger.InfoContext(ctx, "decision traces enabled",
+			"retention", cfg.DecisionTraceRetention.String(),
+			"dir", dir)
+		return nil
+	}
+
+	p.WithDecisionTraces(decision.NewMemoryStore(cfg.DecisionTraceRetention))
+	logger.InfoContext(ctx, "decision traces enabled",
+		"retention", cfg.DecisionTraceRetention.String(),
+		"store", "memory")
+	return nil
+}
@@ -0,0 +1,387 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// devIAPHeader is the identity header set by Identity-Aware Proxy, which the
+// dev stack fakes.
+const devIAPHeader = "x-goog-authenticated-user-email"
+
+var _ cli.Command = (*DevUpCommand)(nil)
+
+type DevUpCommand struct {
+	cli.BaseCommand
+
+	flagDataDir       string
+	flagUserEmail     string
+	flagAPIPort       string
+	flagPublicKeyPort string
+	flagUIPort        string
+	flagPluginDir     string
+
+	// ready is called with the addresses of the servers once they are created,
+	// for testing.
+	ready func(addrs *devAddrs)
+}
+
+// devAddrs are the addresses of the servers of the dev stack.
+type devAddrs struct {
+	api       string
+	publicKey string
+	ui        string
+}
+
+func (c *DevUpCommand) Desc() string {
+	return `Run the JVS on localhost for development`
+}
+
+func (c *DevUpCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Run the Justification API, Public Key API and UI on localhost, without any
+  cloud dependencies, to click through the full flow. Tokens are signed with a
+  local key, which is generated in the data directory on first use. The UI
+  trusts every request as the user email, instead of requiring Identity-Aware
+  Proxy in front of it.
+
+  Never expose the dev stack beyond localhost, anyone who can reach the UI can
+  mint tokens as the user.
+
+  Run the dev stack, then open the UI:
+
+      jvsctl dev up -data-dir .jvs-dev
+`
+}
+
+func (c *DevUpCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "data-dir",
+		Target:  &c.flagDataDir,
+		Example: ".jvs-dev",
+		Usage: `The directory of the signing key. Reusing it keeps tokens ` +
+			`verifiable across restarts. If unset, a temporary directory is ` +
+			`used and removed on exit.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "user-email",
+		Target:  &c.flagUserEmail,
+		Default: "dev@example.com",
+		Usage:   `The email of the user the UI signs in.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "plugin-dir",
+		Target:  &c.flagPluginDir,
+		Example: "/var/jvs/plugins",
+		Usage: `The directory to load validator plugins from. If unset, only ` +
+			`the built-in categories are available.`,
+	})
+
+	f = set.NewSection("PORT OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "api-port",
+		Target:  &c.flagAPIPort,
+		Default: "8080",
+		Usage:   `The port of the Justification API.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "public-key-port",
+		Target:  &c.flagPublicKeyPort,
+		Default: "8081",
+		Usage:   `The port of the Public Key API.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "ui-port",
+		Target:  &c.flagUIPort,
+		Default: "9091",
+		Usage:   `The port of the UI.`,
+	})
+
+	return set
+}
+
+func (c *DevUpCommand) Run(ctx context.Context, args []string) (retErr error) {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagUserEmail == "" {
+		return fmt.Errorf("user-email is required")
+	}
+
+	logger := logging.FromContext(ctx)
+
+	dataDir := c.flagDataDir
+	if dataDir == "" {
+		dir, err := os.MkdirTemp("", "jvs-dev-")
+		if err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		dataDir = dir
+	} else if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	keyPath := filepath.Join(dataDir, "signing-key.pem")
+	if err := jvscrypto.GenerateSigningKey(keyPath); err != nil {
+		if !errors.Is(err, fs.ErrExist) {
+			return err //nolint:wrapcheck // Already wrapped
+		}
+		logger.InfoContext(ctx, "using existing signing key", "path", keyPath)
+	}
+
+	// Plugins are loaded from an empty directory by default, rather than the
+	// default plugin directory, so the stack is the same on every machine.
+	pluginDir := c.flagPluginDir
+	if pluginDir == "" {
+		pluginDir = filepath.Join(dataDir, "plugins")
+		if err := os.MkdirAll(pluginDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create plugin directory: %w", err)
+		}
+	}
+
+	var closer *multicloser.Closer
+	defer func() {
+		if err := closer.Close(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to close: %w", err))
+		}
+	}()
+
+	api := &APIServerCommand{}
+	api.SetLookupEnv(c.devLookupEnv(map[string]string{
+		"PORT":           c.flagAPIPort,
+		"JVS_SIGNER":     "local",
+		"JVS_KEY_PATH":   keyPath,
+		"JVS_PLUGIN_DIR": pluginDir,
+
+		"JVS_API_REFLECTION": "true",
+	}))
+	apiServer, grpcServer, apiCloser, err := api.RunUnstarted(ctx, nil)
+	closer = multicloser.Append(closer, apiCloser.Close)
+	if err != nil {
+		return fmt.Errorf("failed to create justification api: %w", err)
+	}
+
+	publicKey := &PublicKeyServerCommand{}
+	publicKey.SetLookupEnv(c.devLookupEnv(map[string]string{
+		"PORT":          c.flagPublicKeyPort,
+		"JVS_SIGNER":    "local",
+		"JVS_KEY_PATHS": keyPath,
+	}))
+	publicKeyServer, publicKeyMux, publicKeyCloser, err := publicKey.RunUnstarted(ctx, nil)
+	closer = multicloser.Append(closer, publicKeyCloser.Close)
+	if err != nil {
+		return fmt.Errorf("failed to create public key api: %w", err)
+	}
+
+	ui := &UIServerCommand{}
+	ui.SetLookupEnv(c.devLookupEnv(map[string]string{
+		"PORT":             c.flagUIPort,
+		"JVS_SIGNER":       "local",
+		"JVS_KEY_PATH":     keyPath,
+		"JVS_PLUGIN_DIR":   pluginDir,
+		"JVS_UI_ALLOWLIST": "localhost",
+		"JVS_UI_AUTH_MODE": "iap",
+	}))
+	uiServer, uiMux, uiCloser, err := ui.RunUnstarted(ctx, nil)
+	closer = multicloser.Append(closer, uiCloser.Close)
+	if err != nil {
+		return fmt.Errorf("failed to create ui: %w", err)
+	}
+
+	addrs := &devAddrs{
+		api:       apiServer.Addr(),
+		publicKey: publicKeyServer.Addr(),
+		ui:        uiServer.Addr(),
+	}
+	uiHandler, err := devUIHandler(c.flagUserEmail, addrs, uiMux)
+	if err != nil {
+		return err
+	}
+
+	c.Outf("JVS dev stack, signed in to the UI as %s:", c.flagUserEmail)
+	c.Outf("")
+	c.Outf("  UI:                http://%s/dev", devLocalhost(addrs.ui))
+	c.Outf("  Justification API: %s", devLocalhost(addrs.api))
+	c.Outf("  Public Key API:    http://%s/.well-known/jwks", devLocalhost(addrs.publicKey))
+	c.Outf("")
+	c.Outf("Mint and validate a token with jvsctl:")
+	c.Outf("")
+	c.Outf("  jvsctl token create -server %s -insecure -justification \"testing\" > token.txt", devLocalhost(addrs.api))
+	c.Outf("  jvsctl token validate -jwks-endpoint http://%s/.well-known/jwks -token \"$(cat token.txt)\"", devLocalhost(addrs.publicKey))
+	c.Outf("")
+
+	if c.ready != nil {
+		c.ready(addrs)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stop the whole stack as soon as any server stops.
+	errCh := make(chan error, 3)
+	start := func(name string, fn func() error) {
+		go func() {
+			defer cancel()
+			if err := fn(); err != nil {
+				errCh <- fmt.Errorf("%s failed: %w", name, err)
+				return
+			}
+			errCh <- nil
+		}()
+	}
+	start("justification api", func() error { return apiServer.StartGRPC(ctx, grpcServer) })
+	start("public key api", func() error { return publicKeyServer.StartHTTPHandler(ctx, publicKeyMux) })
+	start("ui", func() error { return uiServer.StartHTTPHandler(ctx, uiHandler) })
+
+	var merr error
+	for range 3 {
+		merr = errors.Join(merr, <-errCh)
+	}
+	return merr
+}
+
+// devLookupEnv returns the environment of a server of the dev stack. The seeded
+// variables take precedence, so the servers always use the local key and ports.
+// Other variables are read from the environment of the command, so the servers
+// can still be configured, e.g. with JVS_UI_CONFIRM_SUBMISSION.
+func (c *DevUpCommand) devLookupEnv(seed map[string]string) cli.LookupEnvFunc {
+	return cli.MultiLookuper(
+		cli.MapLookuper(seed),
+		cli.MapLookuper(map[string]string{
+			// Only the local key is used, so there is nothing to wait for.
+			"JVS_WARMUP_TIMEOUT": "0",
+		}),
+		c.LookupEnv,
+		cli.MapLookuper(map[string]string{
+			// The project is only used to label logs.
+			"PROJECT_ID": "jvs-dev",
+		}),
+	)
+}
+
+// devLocalhost returns the address with the host replaced by localhost, since
+// the servers listen on all interfaces.
+func devLocalhost(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return net.JoinHostPort("localhost", port)
+}
+
+// devUIHandler serves the UI with a fake Identity-Aware Proxy header, so every
+// request is signed in as the email, and a page at /dev that requests a token
+// with the popup. Requests from other hosts are rejected, since they would be
+// signed in too.
+func devUIHandler(email string, addrs *devAddrs, ui http.Handler) (http.Handler, error) {
+	tmpl, err := template.New("dev").Parse(devPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dev page: %w", err)
+	}
+	data := map[string]string{
+		"Email":        email,
+		"JWKSEndpoint": "http://" + devLocalhost(addrs.publicKey) + "/.well-known/jwks",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dev", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmpl.Execute(w, data); err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to render dev page", "error", err)
+		}
+	})
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set(devIAPHeader, "accounts.google.com:"+email)
+		ui.ServeHTTP(w, r)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			http.Error(w, "the dev stack only serves localhost", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// devPage is the page of the dev stack UI that opens the popup, like a calling
+// application would.
+const devPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>JVS dev stack</title>
+  <script src="/static/js/jvs-popup.js"></script>
+</head>
+<body>
+  <main>
+    <h1>JVS dev stack</h1>
+    <p>Signed in as {{ .Email }}.</p>
+    <button id="request" type="button">Request a token</button>
+    <div id="output" role="status"></div>
+  </main>
+  <script>
+    const output = document.getElementById("output");
+    document.getElementById("request").addEventListener("click", async () => {
+      output.textContent = "";
+      try {
+        const popup = new JVSPopup({ url: new URL("/popup", window.location).toString() });
+        const token = await popup.requestToken();
+        const pre = document.createElement("pre");
+        pre.textContent = token;
+        const validate = document.createElement("pre");
+        validate.textContent = "jvsctl token validate -jwks-endpoint {{ .JWKSEndpoint }} -token " + token;
+        output.append("Token:", pre, "Validate it with:", validate);
+      } catch (err) {
+        output.textContent = "Failed to get a token: " + (err.code ? err.code + ": " : "") + err.message;
+      }
+    });
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,196 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestDevUpCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dataDir := t.TempDir()
+
+	addrsCh := make(chan *devAddrs, 1)
+	cmd := &DevUpCommand{
+		ready: func(addrs *devAddrs) {
+			addrsCh <- addrs
+		},
+	}
+	cmd.SetLookupEnv(cli.MapLookuper(nil))
+	_, stdout, _ := cmd.Pipe()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmd.Run(ctx, []string{
+			"-data-dir", dataDir,
+			"-user-email", "me@example.com",
+			"-api-port", "0",
+			"-public-key-port", "0",
+			"-ui-port", "0",
+		})
+	}()
+
+	var addrs *devAddrs
+	select {
+	case addrs = <-addrsCh:
+	case err := <-errCh:
+		t.Fatalf("dev stack stopped: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the dev stack")
+	}
+
+	if _, err := os.Stat(filepath.Join(dataDir, "signing-key.pem")); err != nil {
+		t.Errorf("expected the signing key to be generated: %v", err)
+	}
+	if got, want := stdout.String(), "signed in to the UI as me@example.com"; !strings.Contains(got, want) {
+		t.Errorf("expected output %q to contain %q", got, want)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	get := func(uri string) string {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.StatusCode, http.StatusOK; got != want {
+			t.Fatalf("expected status code %d of %s to be %d: %s", got, uri, want, b)
+		}
+		return string(b)
+	}
+
+	ui := "http://" + devLocalhost(addrs.ui)
+	if got, want := get(ui+"/dev"), "/static/js/jvs-popup.js"; !strings.Contains(got, want) {
+		t.Errorf("expected dev page to contain %q, got %s", want, got)
+	}
+	// The popup is signed in without Identity-Aware Proxy.
+	if got, want := get(ui+"/popup?origin="+ui+"&windowname=test"), "me@example.com"; !strings.Contains(got, want) {
+		t.Errorf("expected popup to contain %q, got %s", want, got)
+	}
+	if got, want := get("http://"+devLocalhost(addrs.publicKey)+"/.well-known/jwks"), `"kid"`; !strings.Contains(got, want) {
+		t.Errorf("expected jwks to contain %q, got %s", want, got)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("dev stack failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the dev stack to stop")
+	}
+}
+
+func TestDevUpCommand_invalid(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name   string
+		args   []string
+		expErr string
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "no_user_email",
+			args:   []string{"-user-email", ""},
+			expErr: "user-email is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cmd DevUpCommand
+			cmd.SetLookupEnv(cli.MapLookuper(nil))
+			_, _, _ = cmd.Pipe()
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDevUIHandler(t *testing.T) {
+	t.Parallel()
+
+	var gotEmail string
+	ui := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEmail = r.Header.Get(devIAPHeader)
+	})
+	h, err := devUIHandler("me@example.com", &devAddrs{publicKey: "[::]:8081"}, ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Spoofed identities are replaced.
+	r := httptest.NewRequest(http.MethodGet, "/popup", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.Header.Set(devIAPHeader, "accounts.google.com:other@example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Errorf("expected status code %d to be %d", got, want)
+	}
+	if got, want := gotEmail, "accounts.google.com:me@example.com"; got != want {
+		t.Errorf("expected identity %q to be %q", got, want)
+	}
+
+	// Requests from other hosts are rejected.
+	r = httptest.NewRequest(http.MethodGet, "/popup", nil)
+	r.RemoteAddr = "203.0.113.1:1234"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusForbidden; got != want {
+		t.Errorf("expected status code %d to be %d", got, want)
+	}
+}
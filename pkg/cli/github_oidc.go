@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// githubOIDCRequestURLEnv and githubOIDCRequestTokenEnv are set by GitHub
+	// Actions on jobs that are granted the "id-token: write" permission.
+	githubOIDCRequestURLEnv   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	githubOIDCRequestTokenEnv = "ACTIONS_ID_TOKEN_REQUEST_TOKEN" //nolint:gosec // Not a credential.
+)
+
+// githubOIDCToken fetches a GitHub Actions OIDC token for the given audience
+// from the job's token request endpoint. If audience is empty, GitHub uses its
+// default audience, the URL of the repository owner.
+func githubOIDCToken(ctx context.Context, requestURL, requestToken, audience string) (string, error) {
+	if requestURL == "" || requestToken == "" {
+		return "", fmt.Errorf("missing %s or %s, make sure the job has the "+
+			`"id-token: write" permission`, githubOIDCRequestURLEnv, githubOIDCRequestTokenEnv)
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", githubOIDCRequestURLEnv, err)
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build github oidc token request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request github oidc token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read github oidc token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request github oidc token: unexpected status %d: %s",
+			resp.StatusCode, body)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse github oidc token response: %w", err)
+	}
+	if result.Value == "" {
+		return "", fmt.Errorf("github oidc token response did not include a token")
+	}
+	return result.Value, nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/cli"
+)
+
+const (
+	// Expectations of the jwks diff command.
+	jwksExpectRotation  = "rotation"
+	jwksExpectUnchanged = "unchanged"
+)
+
+var _ cli.Command = (*JWKSDiffCommand)(nil)
+
+type JWKSDiffCommand struct {
+	cli.BaseCommand
+
+	flagJWKSEndpoint  string
+	flagSnapshot      string
+	flagWriteSnapshot string
+	flagWait          time.Duration
+	flagPollInterval  time.Duration
+	flagKeys          []string
+	flagExpect        string
+
+	flagKMSEndpoint string
+	flagKMSInsecure bool
+
+	// testKMSClientOptions are KMS client options to override during testing.
+	testKMSClientOptions []option.ClientOption
+}
+
+func (c *JWKSDiffCommand) Desc() string {
+	return `Compare the published public keys before and after a rotation`
+}
+
+func (c *JWKSDiffCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Compare the JWKS published by the public key server against a snapshot, or
+  against itself a while later, and print the key IDs that were added and
+  removed. With -key, also print whether the primary version of each KMS key
+  changed. With -expect, fail unless the keys rotated or stayed unchanged, e.g.
+  to verify a rotation in a runbook or a deploy in CI.
+
+  Snapshot the JWKS before a deploy, and verify it did not change the keys:
+
+      jvsctl jwks diff -write-snapshot "jwks.json" -wait 0
+      jvsctl jwks diff -snapshot "jwks.json" -expect "unchanged"
+
+  Wait up to 10 minutes for a rotation, and verify the new primary version is
+  published:
+
+      jvsctl jwks diff \
+        -key "projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing" \
+        -wait 10m \
+        -expect "rotation"
+`
+}
+
+func (c *JWKSDiffCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "snapshot",
+		Target:  &c.flagSnapshot,
+		Example: "jwks.json",
+		Usage: `A JWKS file to compare the published JWKS against, instead of ` +
+			`fetching it twice.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "write-snapshot",
+		Target:  &c.flagWriteSnapshot,
+		Example: "jwks.json",
+		Usage:   `Write the published JWKS to the file, to compare against later.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "wait",
+		Target:  &c.flagWait,
+		Default: 5 * time.Minute,
+		Usage: `Without -snapshot, how long to wait for the published JWKS to ` +
+			`change after fetching it the first time.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "poll-interval",
+		Target:  &c.flagPollInterval,
+		Default: 10 * time.Second,
+		Usage:   `How often to fetch the JWKS while waiting for it to change.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "key",
+		Target:  &c.flagKeys,
+		Example: "projects/my-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing",
+		Usage: `The KMS keys whose primary versions must be published. ` +
+			`Repeat or separate with commas for multiple keys.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "expect",
+		Target:  &c.flagExpect,
+		Example: jwksExpectRotation,
+		Usage: fmt.Sprintf(`Fail unless the keys changed as expected: %q if `+
+			`keys were added and the primary versions are new, or %q if no `+
+			`keys were added or removed and the primary versions are not new.`,
+			jwksExpectRotation, jwksExpectUnchanged),
+	})
+
+	// Server options
+	f = set.NewSection("SERVER OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "jwks-endpoint",
+		Target:  &c.flagJWKSEndpoint,
+		Example: "https://jvs.example.com:8080/.well-known/jwks",
+		Default: "http://localhost:8080/.well-known/jwks",
+		EnvVar:  "JVSCTL_JWKS_ENDPOINT",
+		Usage: `JVS public key server endpoint including the protocol, ` +
+			`address, port, and .well-known path.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-endpoint",
+		Target:  &c.flagKMSEndpoint,
+		EnvVar:  "JVS_KMS_ENDPOINT",
+		Example: "localhost:9090",
+		Usage:   "Override the Cloud KMS API endpoint, e.g. for a local KMS emulator.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "kms-insecure",
+		Target:  &c.flagKMSInsecure,
+		EnvVar:  "JVS_KMS_INSECURE",
+		Default: false,
+		Usage:   "Connect to the KMS endpoint without TLS or authentication.",
+	})
+
+	return set
+}
+
+func (c *JWKSDiffCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	switch c.flagExpect {
+	case "", jwksExpectRotation, jwksExpectUnchanged:
+	default:
+		return fmt.Errorf("expect must be %q or %q, got %q",
+			jwksExpectRotation, jwksExpectUnchanged, c.flagExpect)
+	}
+	if c.flagWait < 0 {
+		return fmt.Errorf("wait cannot be negative, got %s", c.flagWait)
+	}
+	if c.flagPollInterval <= 0 {
+		return fmt.Errorf("poll-interval must be positive, got %s", c.flagPollInterval)
+	}
+	if c.flagKMSInsecure && c.flagKMSEndpoint == "" {
+		return fmt.Errorf("kms-insecure requires kms-endpoint to be set")
+	}
+
+	var before, after jwk.Set
+	if c.flagSnapshot != "" {
+		b, err := os.ReadFile(c.flagSnapshot)
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if before, err = jwk.Parse(b); err != nil {
+			return fmt.Errorf("failed to parse snapshot %s: %w", c.flagSnapshot, err)
+		}
+		if after, err = fetchJWKS(ctx, c.flagJWKSEndpoint); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if before, err = fetchJWKS(ctx, c.flagJWKSEndpoint); err != nil {
+			return err
+		}
+		if after, err = c.waitForChange(ctx, before); err != nil {
+			return err
+		}
+	}
+
+	if c.flagWriteSnapshot != "" {
+		b, err := json.Marshal(after)
+		if err != nil {
+			return fmt.Errorf("failed to marshal jwks: %w", err)
+		}
+		if err := os.WriteFile(c.flagWriteSnapshot, b, 0o600); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+
+	var primaries []string
+	if len(c.flagKeys) > 0 {
+		kmsClient, err := jvscrypto.NewKMSClient(ctx, jvscrypto.DefaultKMSRetryPolicy(), append(
+			kmsClientOptions(c.flagKMSEndpoint, c.flagKMSInsecure),
+			c.testKMSClientOptions...)...)
+		if err != nil {
+			return fmt.Errorf("failed to setup kms client: %w", err)
+		}
+		defer kmsClient.Close()
+
+		if primaries, err = keyPrimaries(ctx, kmsClient, c.flagKeys); err != nil {
+			return err
+		}
+	}
+
+	return c.report(c.Stdout(), keyIDs(before), keyIDs(after), primaries)
+}
+
+// waitForChange fetches the JWKS until it differs from the JWKS before, or
+// until the wait is over. It returns the JWKS fetched last.
+func (c *JWKSDiffCommand) waitForChange(ctx context.Context, before jwk.Set) (jwk.Set, error) {
+	if c.flagWait == 0 {
+		return before, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.flagWait)
+	defer cancel()
+
+	ticker := time.NewTicker(c.flagPollInterval)
+	defer ticker.Stop()
+
+	want := keyIDs(before)
+	after := before
+	for {
+		select {
+		case <-ctx.Done():
+			return after, nil
+		case <-ticker.C:
+		}
+
+		set, err := fetchJWKS(ctx, c.flagJWKSEndpoint)
+		if err != nil {
+			if ctx.Err() != nil {
+				return after, nil
+			}
+			return nil, err
+		}
+		after = set
+		if !slices.Equal(want, keyIDs(after)) {
+			return after, nil
+		}
+	}
+}
+
+// report prints the key IDs added and removed between the sorted key IDs
+// before and after, and whether the primaries are new. It returns an error if
+// a primary is not published, or if the keys did not change as expected.
+func (c *JWKSDiffCommand) report(w io.Writer, before, after, primaries []string) error {
+	var added, removed []string
+	for _, kid := range after {
+		if !slices.Contains(before, kid) {
+			added = append(added, kid)
+			fmt.Fprintf(w, "Added   %s\n", kid)
+		}
+	}
+	for _, kid := range before {
+		if !slices.Contains(after, kid) {
+			removed = append(removed, kid)
+			fmt.Fprintf(w, "Removed %s\n", kid)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Fprintf(w, "No keys were added or removed\n")
+	}
+
+	for _, primary := range primaries {
+		if !slices.Contains(after, primary) {
+			return fmt.Errorf("%s does not publish primary %s, wait for the public key cache to expire",
+				c.flagJWKSEndpoint, primary)
+		}
+
+		isNew := slices.Contains(added, primary)
+		if isNew {
+			fmt.Fprintf(w, "Primary %s (new)\n", primary)
+		} else {
+			fmt.Fprintf(w, "Primary %s (unchanged)\n", primary)
+		}
+
+		switch {
+		case c.flagExpect == jwksExpectRotation && !isNew:
+			return fmt.Errorf("expected primary %s to be a new key", primary)
+		case c.flagExpect == jwksExpectUnchanged && isNew:
+			return fmt.Errorf("expected primary %s to be unchanged", primary)
+		}
+	}
+
+	switch {
+	case c.flagExpect == jwksExpectRotation && len(added) == 0:
+		return fmt.Errorf("expected keys to be added")
+	case c.flagExpect == jwksExpectUnchanged && (len(added) > 0 || len(removed) > 0):
+		return fmt.Errorf("expected no keys to be added or removed, got %d added and %d removed",
+			len(added), len(removed))
+	}
+	return nil
+}
+
+// keyPrimaries returns the key IDs of the primary versions of the KMS keys.
+func keyPrimaries(ctx context.Context, client *kms.KeyManagementClient, keys []string) ([]string, error) {
+	primaries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		primary, err := jvscrypto.GetPrimary(ctx, client, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get primary of key %s: %w", key, err)
+		}
+		if primary == "" {
+			return nil, fmt.Errorf("key %s has no primary version", key)
+		}
+		primaries = append(primaries, primary)
+	}
+	return primaries, nil
+}
+
+// keyIDs returns the sorted key IDs of the key set.
+func keyIDs(set jwk.Set) []string {
+	kids := make([]string, 0, set.Len())
+	for i := range set.Len() {
+		if key, ok := set.Key(i); ok {
+			kids = append(kids, key.KeyID())
+		}
+	}
+	slices.Sort(kids)
+	return kids
+}
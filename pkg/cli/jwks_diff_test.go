@@ -0,0 +1,240 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestJWKSDiffCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	v1 := testOldKey + "/cryptoKeyVersions/1"
+	v2 := testOldKey + "/cryptoKeyVersions/2"
+	v3 := testOldKey + "/cryptoKeyVersions/3"
+
+	cases := []struct {
+		name      string
+		args      []string
+		snapshot  []string
+		published [][]string
+		primary   string
+		expOutput string
+		expErr    string
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "invalid_expect",
+			args:   []string{"-expect", "flip"},
+			expErr: `expect must be "rotation" or "unchanged", got "flip"`,
+		},
+		{
+			name:   "negative_wait",
+			args:   []string{"-wait", "-1s"},
+			expErr: "wait cannot be negative, got -1s",
+		},
+		{
+			name:   "missing_snapshot",
+			args:   []string{"-snapshot", filepath.Join(t.TempDir(), "missing.json")},
+			expErr: "failed to read snapshot",
+		},
+		{
+			name:      "snapshot_rotation",
+			args:      []string{"-expect", "rotation"},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v2, v3}},
+			expOutput: `
+Added   ` + v3 + `
+Removed ` + v1,
+		},
+		{
+			name:      "snapshot_unchanged",
+			args:      []string{"-expect", "unchanged"},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v1, v2}},
+			expOutput: `No keys were added or removed`,
+		},
+		{
+			name:      "snapshot_not_unchanged",
+			args:      []string{"-expect", "unchanged"},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v1, v2, v3}},
+			expOutput: `Added   ` + v3,
+			expErr:    "expected no keys to be added or removed, got 1 added and 0 removed",
+		},
+		{
+			name:      "snapshot_not_rotated",
+			args:      []string{"-expect", "rotation"},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v1, v2}},
+			expOutput: `No keys were added or removed`,
+			expErr:    "expected keys to be added",
+		},
+		{
+			name:      "primary_rotated",
+			args:      []string{"-key", testOldKey, "-expect", "rotation"},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v1, v2, v3}},
+			primary:   "ver_3",
+			expOutput: `
+Added   ` + v3 + `
+Primary ` + v3 + ` (new)`,
+		},
+		{
+			name:      "primary_not_rotated",
+			args:      []string{"-key", testOldKey, "-expect", "rotation"},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v1, v2, v3}},
+			primary:   "ver_2",
+			expOutput: `
+Added   ` + v3 + `
+Primary ` + v2 + ` (unchanged)`,
+			expErr: "expected primary " + v2 + " to be a new key",
+		},
+		{
+			name:      "primary_not_published",
+			args:      []string{"-key", testOldKey},
+			snapshot:  []string{v1, v2},
+			published: [][]string{{v1, v2}},
+			primary:   "ver_3",
+			expOutput: `No keys were added or removed`,
+			expErr:    "does not publish primary " + v3,
+		},
+		{
+			name:      "wait_for_rotation",
+			args:      []string{"-wait", "1m", "-poll-interval", "10ms", "-expect", "rotation"},
+			published: [][]string{{v1, v2}, {v1, v2}, {v1, v2, v3}},
+			expOutput: `Added   ` + v3,
+		},
+		{
+			name:      "wait_expires",
+			args:      []string{"-wait", "50ms", "-poll-interval", "10ms", "-expect", "unchanged"},
+			published: [][]string{{v1, v2}},
+			expOutput: `No keys were added or removed`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &fakeBootstrapKMS{
+				keyRings: map[string]bool{testOldKeyRing: true},
+				keys:     make(map[string]*kmspb.CryptoKey),
+				versions: make(map[string][]*kmspb.CryptoKeyVersion),
+				policies: make(map[string]*iampb.Policy),
+			}
+			fake.addKey(testOldKey, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256, 3)
+			fake.keys[testOldKey].Labels = map[string]string{"primary": tc.primary}
+			_, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, &fakeBootstrapKMSServer{fakeBootstrapKMS: fake})
+			})
+			jwks := testJWKSSequenceServer(t, tc.published...)
+
+			args := append([]string{"-jwks-endpoint", jwks.URL}, tc.args...)
+			if tc.snapshot != nil {
+				path := filepath.Join(t.TempDir(), "jwks.json")
+				if err := os.WriteFile(path, testJWKS(t, tc.snapshot), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-snapshot", path)
+			}
+
+			var cmd JWKSDiffCommand
+			cmd.testKMSClientOptions = []option.ClientOption{option.WithGRPCConn(conn)}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestJWKSDiffCommand_writeSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	kids := []string{testOldKey + "/cryptoKeyVersions/1"}
+	jwks := testJWKSServer(t, kids)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+
+	var cmd JWKSDiffCommand
+	_, _, _ = cmd.Pipe()
+	if err := cmd.Run(ctx, []string{"-jwks-endpoint", jwks.URL, "-wait", "0", "-write-snapshot", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot compares equal to the JWKS it was written from.
+	_, stdout, _ := cmd.Pipe()
+	if err := cmd.Run(ctx, []string{"-jwks-endpoint", jwks.URL, "-snapshot", path, "-expect", "unchanged"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(stdout.String()), "No keys were added or removed"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+// testJWKSSequenceServer serves a JWKS for each of the sets of key IDs in turn,
+// then keeps serving the last one.
+func testJWKSSequenceServer(tb testing.TB, sets ...[]string) *httptest.Server {
+	tb.Helper()
+
+	jwks := make([][]byte, 0, len(sets))
+	for _, kids := range sets {
+		jwks = append(jwks, testJWKS(tb, kids))
+	}
+
+	var mu sync.Mutex
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		i := min(requests, len(jwks)-1)
+		requests++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(jwks[i])
+	}))
+	tb.Cleanup(srv.Close)
+	return srv
+}
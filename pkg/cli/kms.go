@@ -0,0 +1,90 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// kmsPermissionCheckTimeout bounds the startup KMS permission check, so an
+// unreachable KMS doesn't block startup.
+const kmsPermissionCheckTimeout = 10 * time.Second
+
+// kmsClientOptions returns the KMS client options for the given endpoint
+// override. If endpoint is empty, the default Cloud KMS endpoint is used. If
+// insecure is true, the connection to the endpoint uses neither TLS nor
+// authentication, which is what local KMS emulators expect.
+func kmsClientOptions(endpoint string, insecure bool) []option.ClientOption {
+	if endpoint == "" {
+		return nil
+	}
+
+	opts := []option.ClientOption{
+		option.WithEndpoint(endpoint),
+	}
+	if insecure {
+		opts = append(opts,
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(grpcinsecure.NewCredentials())),
+		)
+	}
+	return opts
+}
+
+// usesKMS returns true if the signer keeps its keys in Cloud KMS. The other
+// signers never call KMS, so servers don't need to create a KMS client.
+func usesKMS(signer string) bool {
+	return signer == "" || signer == config.SignerKMS
+}
+
+// checkKMSPermissions checks the service account has the permissions on the
+// keys, so missing roles are reported at startup rather than as
+// PERMISSION_DENIED on the first request. With config.KMSPermissionCheckFail,
+// missing permissions are returned as an error, otherwise they are logged.
+// Permissions that can't be tested, because KMS is unreachable or doesn't
+// implement IAM, are only ever logged. KMS emulators, which are connected to
+// insecurely, are not checked.
+func checkKMSPermissions(ctx context.Context, client *kms.KeyManagementClient, mode string, insecure bool, keys, permissions []string) error {
+	if mode == config.KMSPermissionCheckOff || insecure || len(keys) == 0 {
+		return nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, kmsPermissionCheckTimeout)
+	defer cancel()
+
+	err := jvscrypto.CheckKMSPermissions(checkCtx, client, keys, permissions)
+	if err == nil {
+		return nil
+	}
+	if mode == config.KMSPermissionCheckFail && !errors.Is(err, jvscrypto.ErrKMSPermissionsUnknown) {
+		return fmt.Errorf("kms permission check failed: %w", err)
+	}
+	logging.FromContext(ctx).WarnContext(ctx, "kms permission check failed",
+		"keys", keys,
+		"error", err)
+	return nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/cli"
+)
+
+const (
+	// Steps of a key migration, in order.
+	migrateStepPrepare = "prepare"
+	migrateStepCutover = "cutover"
+	migrateStepFinish  = "finish"
+
+	// maxJWKSSize is the maximum size of a JWKS response body.
+	maxJWKSSize = 1 << 20
+)
+
+var _ cli.Command = (*MigrateKeysCommand)(nil)
+
+type MigrateKeysCommand struct {
+	cli.BaseCommand
+
+	flagFrom         string
+	flagTo           string
+	flagStep         string
+	flagJWKSEndpoint string
+
+	flagKMSEndpoint string
+	flagKMSInsecure bool
+
+	// testKMSClientOptions are KMS client options to override during testing.
+	testKMSClientOptions []option.ClientOption
+}
+
+func (c *MigrateKeysCommand) Desc() string {
+	return `Migrate the signing key to another key ring or project`
+}
+
+func (c *MigrateKeysCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Migrate the JVS services from one KMS signing key to another, e.g. in a new
+  key ring or project, without downtime. Tokens signed with either key stay
+  valid throughout, since both keys are published while the services switch
+  over. The migration has three steps, run in order:
+
+    prepare  Create the new key with the settings of the old key, and mark its
+             first version as primary. Then publish both keys.
+
+    cutover  Check that the public key server publishes the primary versions
+             of both keys. Then switch the services to sign with the new key.
+
+    finish   Check that the public key server publishes the new key. Then stop
+             publishing the old key, once tokens signed with it have expired.
+
+  Each step prints the configuration changes to make before the next one.
+
+  Prepare to migrate to a key in another project:
+
+      jvsctl migrate keys \
+        -from "projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing" \
+        -to "projects/new-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing" \
+        -step "prepare"
+`
+}
+
+func (c *MigrateKeysCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "from",
+		Target:  &c.flagFrom,
+		Example: "projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing",
+		Usage:   `The KMS key the services sign with now.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "to",
+		Target:  &c.flagTo,
+		Example: "projects/new-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing",
+		Usage:   `The KMS key to migrate to. It is created if it does not exist.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "step",
+		Target:  &c.flagStep,
+		Default: migrateStepPrepare,
+		Usage: fmt.Sprintf(`The step of the migration to run, one of %q, %q or %q.`,
+			migrateStepPrepare, migrateStepCutover, migrateStepFinish),
+	})
+
+	// Server options
+	f = set.NewSection("SERVER OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "jwks-endpoint",
+		Target:  &c.flagJWKSEndpoint,
+		Example: "https://jvs.example.com:8080/.well-known/jwks",
+		Default: "http://localhost:8080/.well-known/jwks",
+		EnvVar:  "JVSCTL_JWKS_ENDPOINT",
+		Usage: `JVS public key server endpoint including the protocol, ` +
+			`address, port, and .well-known path.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-endpoint",
+		Target:  &c.flagKMSEndpoint,
+		EnvVar:  "JVS_KMS_ENDPOINT",
+		Example: "localhost:9090",
+		Usage:   "Override the Cloud KMS API endpoint, e.g. for a local KMS emulator.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "kms-insecure",
+		Target:  &c.flagKMSInsecure,
+		EnvVar:  "JVS_KMS_INSECURE",
+		Default: false,
+		Usage:   "Connect to the KMS endpoint without TLS or authentication.",
+	})
+
+	return set
+}
+
+func (c *MigrateKeysCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagFrom == "" || c.flagTo == "" {
+		return fmt.Errorf("from and to are required")
+	}
+	if c.flagFrom == c.flagTo {
+		return fmt.Errorf("from and to must be different keys")
+	}
+	if _, _, err := splitKeyName(c.flagTo); err != nil {
+		return err
+	}
+	if c.flagKMSInsecure && c.flagKMSEndpoint == "" {
+		return fmt.Errorf("kms-insecure requires kms-endpoint to be set")
+	}
+
+	var step func(ctx context.Context, w io.Writer, client *kms.KeyManagementClient) error
+	switch c.flagStep {
+	case migrateStepPrepare:
+		step = c.prepare
+	case migrateStepCutover:
+		step = c.cutover
+	case migrateStepFinish:
+		step = c.finish
+	default:
+		return fmt.Errorf("step must be %q, %q or %q, got %q",
+			migrateStepPrepare, migrateStepCutover, migrateStepFinish, c.flagStep)
+	}
+
+	kmsClient, err := jvscrypto.NewKMSClient(ctx, jvscrypto.DefaultKMSRetryPolicy(), append(
+		kmsClientOptions(c.flagKMSEndpoint, c.flagKMSInsecure),
+		c.testKMSClientOptions...)...)
+	if err != nil {
+		return fmt.Errorf("failed to setup kms client: %w", err)
+	}
+	defer kmsClient.Close()
+
+	return step(ctx, c.Stdout(), kmsClient)
+}
+
+// prepare creates the new key with the settings of the old key, and marks its
+// first version as primary.
+func (c *MigrateKeysCommand) prepare(ctx context.Context, w io.Writer, client *kms.KeyManagementClient) error {
+	from, err := client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: c.flagFrom})
+	if err != nil {
+		return fmt.Errorf("failed to get key %s: %w", c.flagFrom, err)
+	}
+
+	// The rotation of the new key takes over from the old key, so it shares its
+	// settings, but not its primary.
+	labels := make(map[string]string, len(from.GetLabels()))
+	for k, v := range from.GetLabels() {
+		if k != jvscrypto.PrimaryKey {
+			labels[k] = v
+		}
+	}
+	template := &kmspb.CryptoKey{
+		Purpose: from.GetPurpose(),
+		VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+			Algorithm:       from.GetVersionTemplate().GetAlgorithm(),
+			ProtectionLevel: from.GetVersionTemplate().GetProtectionLevel(),
+		},
+		DestroyScheduledDuration: from.GetDestroyScheduledDuration(),
+		Labels:                   labels,
+	}
+
+	keyRing, id, err := splitKeyName(c.flagTo)
+	if err != nil {
+		return err
+	}
+	location, keyRingID, _ := strings.Cut(keyRing, "/keyRings/")
+	if _, err := ensureKeyRing(ctx, w, client, location, keyRingID); err != nil {
+		return err
+	}
+	if _, err := ensureKey(ctx, w, client, keyRing, id, template); err != nil {
+		return err
+	}
+	if err := ensurePrimary(ctx, w, client, c.flagTo); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nPublish both keys. Configure the Public Key API and Cert Rotation API with:\n\n")
+	fmt.Fprintf(w, "  export JVS_KEY_NAMES=%q\n\n", c.flagFrom+","+c.flagTo)
+	fmt.Fprintf(w, "Then run this command with -step %q.\n", migrateStepCutover)
+	return nil
+}
+
+// cutover checks that the primary versions of both keys are published, so
+// tokens signed with either can be validated.
+func (c *MigrateKeysCommand) cutover(ctx context.Context, w io.Writer, client *kms.KeyManagementClient) error {
+	if err := c.checkPublished(ctx, w, client, c.flagFrom, c.flagTo); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nBoth keys are published. Configure the Justification API and UI with:\n\n")
+	fmt.Fprintf(w, "  export JVS_KEY=%q\n\n", c.flagTo)
+	fmt.Fprintf(w, "Then run this command with -step %q once tokens signed with %s have expired.\n",
+		migrateStepFinish, c.flagFrom)
+	return nil
+}
+
+// finish checks that the primary version of the new key is published, before
+// the old key stops being published.
+func (c *MigrateKeysCommand) finish(ctx context.Context, w io.Writer, client *kms.KeyManagementClient) error {
+	if err := c.checkPublished(ctx, w, client, c.flagTo); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nStop publishing the old key. Configure the Public Key API and Cert Rotation API with:\n\n")
+	fmt.Fprintf(w, "  export JVS_KEY_NAMES=%q\n\n", c.flagTo)
+	fmt.Fprintf(w, "The migration is complete once they are redeployed. Disable the versions of\n%s\nwhen nothing validates tokens with them anymore.\n", c.flagFrom)
+	return nil
+}
+
+// checkPublished checks that the JWKS endpoint publishes the primary versions
+// of the keys.
+func (c *MigrateKeysCommand) checkPublished(ctx context.Context, w io.Writer, client *kms.KeyManagementClient, keys ...string) error {
+	set, err := fetchJWKS(ctx, c.flagJWKSEndpoint)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		primary, err := jvscrypto.GetPrimary(ctx, client, key)
+		if err != nil {
+			return fmt.Errorf("failed to get primary of key %s: %w", key, err)
+		}
+		if primary == "" {
+			return fmt.Errorf("key %s has no primary version", key)
+		}
+		if _, ok := set.LookupKeyID(primary); !ok {
+			return fmt.Errorf("%s does not publish %s yet, wait for the public key cache to expire",
+				c.flagJWKSEndpoint, primary)
+		}
+		fmt.Fprintf(w, "Published %s\n", primary)
+	}
+	return nil
+}
+
+// fetchJWKS fetches the key set from the JWKS endpoint, bypassing any caches.
+func fetchJWKS(ctx context.Context, endpoint string) (jwk.Set, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build jwks request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: status %d: %s", resp.StatusCode, b)
+	}
+
+	set, err := jwk.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+	return set, nil
+}
+
+// splitKeyName splits the KMS key name into the name of its key ring and its
+// ID.
+func splitKeyName(key string) (string, string, error) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" ||
+		parts[4] != "keyRings" || parts[6] != "cryptoKeys" {
+		return "", "", fmt.Errorf("key %q must be in the format "+
+			"projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[KEY]", key)
+	}
+	return strings.Join(parts[:6], "/"), parts[7], nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const (
+	testOldKeyRing = "projects/old-project/locations/global/keyRings/jvs"
+	testOldKey     = testOldKeyRing + "/cryptoKeys/jvs-signing"
+	testNewKeyRing = "projects/new-project/locations/us/keyRings/jvs"
+	testNewKey     = testNewKeyRing + "/cryptoKeys/jvs-signing"
+)
+
+func TestMigrateKeysCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	// withKeys adds the old key with two versions, and optionally the new key
+	// with one, both with a primary.
+	withKeys := func(newKey bool) func(s *fakeBootstrapKMS) {
+		return func(s *fakeBootstrapKMS) {
+			s.keyRings[testOldKeyRing] = true
+			s.addKey(testOldKey, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256, 2)
+			s.keys[testOldKey].VersionTemplate.ProtectionLevel = kmspb.ProtectionLevel_HSM
+			s.keys[testOldKey].Labels = map[string]string{"primary": "ver_2", "team": "security"}
+			if newKey {
+				s.keyRings[testNewKeyRing] = true
+				s.addKey(testNewKey, kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256, 1)
+				s.keys[testNewKey].Labels = map[string]string{"primary": "ver_1"}
+			}
+		}
+	}
+
+	cases := []struct {
+		name      string
+		args      []string
+		setup     func(s *fakeBootstrapKMS)
+		published []string
+		expOutput string
+		expErr    string
+		expNewKey *kmspb.CryptoKey
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "missing_to",
+			args:   []string{"-from", testOldKey},
+			expErr: "from and to are required",
+		},
+		{
+			name:   "same_keys",
+			args:   []string{"-from", testOldKey, "-to", testOldKey},
+			expErr: "from and to must be different keys",
+		},
+		{
+			name:   "invalid_to",
+			args:   []string{"-from", testOldKey, "-to", "jvs-signing"},
+			expErr: `key "jvs-signing" must be in the format`,
+		},
+		{
+			name:   "invalid_step",
+			args:   []string{"-from", testOldKey, "-to", testNewKey, "-step", "flip"},
+			expErr: `step must be "prepare", "cutover" or "finish", got "flip"`,
+		},
+		{
+			name:  "prepare",
+			args:  []string{"-from", testOldKey, "-to", testNewKey},
+			setup: withKeys(false),
+			expOutput: `
+Created key ring projects/new-project/locations/us/keyRings/jvs
+Created key projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing
+Set primary version projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/1
+
+Publish both keys. Configure the Public Key API and Cert Rotation API with:
+
+  export JVS_KEY_NAMES="projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing,projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing"
+
+Then run this command with -step "cutover".
+`,
+			expNewKey: &kmspb.CryptoKey{
+				Name:    testNewKey,
+				Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+				VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+					Algorithm:       kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256,
+					ProtectionLevel: kmspb.ProtectionLevel_HSM,
+				},
+				Labels: map[string]string{"primary": "ver_1", "team": "security"},
+			},
+		},
+		{
+			name:   "prepare_missing_from",
+			args:   []string{"-from", testOldKey, "-to", testNewKey},
+			expErr: "failed to get key " + testOldKey,
+		},
+		{
+			name:      "cutover_not_published",
+			args:      []string{"-from", testOldKey, "-to", testNewKey, "-step", "cutover"},
+			setup:     withKeys(true),
+			published: []string{testOldKey + "/cryptoKeyVersions/2"},
+			expOutput: `
+Published projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/2
+`,
+			expErr: "does not publish " + testNewKey + "/cryptoKeyVersions/1 yet",
+		},
+		{
+			name:      "cutover",
+			args:      []string{"-from", testOldKey, "-to", testNewKey, "-step", "cutover"},
+			setup:     withKeys(true),
+			published: []string{testOldKey + "/cryptoKeyVersions/2", testNewKey + "/cryptoKeyVersions/1"},
+			expOutput: `
+Published projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/2
+Published projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/1
+
+Both keys are published. Configure the Justification API and UI with:
+
+  export JVS_KEY="projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing"
+
+Then run this command with -step "finish" once tokens signed with projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing have expired.
+`,
+		},
+		{
+			name:      "finish",
+			args:      []string{"-from", testOldKey, "-to", testNewKey, "-step", "finish"},
+			setup:     withKeys(true),
+			published: []string{testNewKey + "/cryptoKeyVersions/1"},
+			expOutput: `
+Published projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing/cryptoKeyVersions/1
+
+Stop publishing the old key. Configure the Public Key API and Cert Rotation API with:
+
+  export JVS_KEY_NAMES="projects/new-project/locations/us/keyRings/jvs/cryptoKeys/jvs-signing"
+
+The migration is complete once they are redeployed. Disable the versions of
+projects/old-project/locations/global/keyRings/jvs/cryptoKeys/jvs-signing
+when nothing validates tokens with them anymore.
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := &fakeBootstrapKMS{
+				keyRings: make(map[string]bool),
+				keys:     make(map[string]*kmspb.CryptoKey),
+				versions: make(map[string][]*kmspb.CryptoKeyVersion),
+				policies: make(map[string]*iampb.Policy),
+			}
+			if tc.setup != nil {
+				tc.setup(fake)
+			}
+			_, conn := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, &fakeBootstrapKMSServer{fakeBootstrapKMS: fake})
+			})
+			jwks := testJWKSServer(t, tc.published)
+
+			var cmd MigrateKeysCommand
+			cmd.testKMSClientOptions = []option.ClientOption{option.WithGRPCConn(conn)}
+			_, stdout, _ := cmd.Pipe()
+
+			err := cmd.Run(ctx, append([]string{"-jwks-endpoint", jwks.URL}, tc.args...))
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+			if tc.expNewKey != nil {
+				if diff := cmp.Diff(tc.expNewKey, fake.keys[testNewKey], protocmp.Transform()); diff != "" {
+					t.Errorf("new key (-want, +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+// testJWKSServer serves a JWKS with a public key for each of the key IDs.
+func testJWKSServer(tb testing.TB, kids []string) *httptest.Server {
+	tb.Helper()
+
+	b := testJWKS(tb, kids)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}))
+	tb.Cleanup(srv.Close)
+	return srv
+}
+
+// testJWKS returns a JSON encoded JWKS with a public key for each of the key
+// IDs.
+func testJWKS(tb testing.TB, kids []string) []byte {
+	tb.Helper()
+
+	set := jwk.NewSet()
+	for _, kid := range kids {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		key, err := jwk.FromRaw(priv.Public())
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+			tb.Fatal(err)
+		}
+		if err := set.AddKey(key); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	b, err := json.Marshal(set)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/jvs/pkg/pam"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// withPAMGrants files PAM grants for tokens of the configured categories with
+// the processor. It does nothing if no entitlements are configured.
+func withPAMGrants(ctx context.Context, cfg *config.PAMConfig, p *justification.Processor) error {
+	if !cfg.Enabled() {
+		return nil
+	}
+	entitlements, err := cfg.EntitlementsByCategory()
+	if err != nil {
+		return fmt.Errorf("failed to parse pam entitlements: %w", err)
+	}
+
+	client, err := pam.NewClient(ctx, cfg.Endpoint)
+	if err != nil {
+		return err
+	}
+	p.WithPAMGrants(client, entitlements)
+	logging.FromContext(ctx).InfoContext(ctx, "pam grants enabled", "entitlements", entitlements)
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/approver"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/jvs/pkg/plugin"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// loadValidators loads the local plugins from the plugin directory, connects to
+// the remote plugins and adds the enabled built-in validators.
+func loadValidators(ctx context.Context, cfg *config.JustificationConfig) (map[string]jvspb.Validator, *multicloser.Closer, error) {
+	logger := logging.FromContext(ctx)
+
+	sealed, err := loadSealedConfigs(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	validators, closer, err := plugin.LoadPlugins(cfg.PluginDir, sealed)
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to load plugins: %w", err)
+	}
+
+	addrs, err := cfg.RemotePluginAddrs()
+	if err != nil {
+		return nil, closer, fmt.Errorf("invalid remote plugins: %w", err)
+	}
+	if len(addrs) > 0 {
+		creds, err := plugin.RemoteTransportCredentials(cfg.RemotePluginCAFile,
+			cfg.RemotePluginCertFile, cfg.RemotePluginKeyFile, cfg.RemotePluginInsecure)
+		if err != nil {
+			return nil, closer, err
+		}
+
+		remote, remoteCloser, err := plugin.LoadRemotePlugins(addrs, creds)
+		closer = multicloser.Append(closer, remoteCloser.Close)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to load remote plugins: %w", err)
+		}
+
+		for name, v := range remote {
+			if _, ok := validators[name]; ok {
+				return nil, closer, fmt.Errorf("remote plugin %q conflicts with the local plugin of the same name", name)
+			}
+			validators[name] = v
+		}
+		logger.InfoContext(ctx, "remote plugins loaded", "addrs", addrs)
+	}
+
+	if _, ok := validators[justification.CanaryCategory]; ok {
+		return nil, closer, fmt.Errorf("plugin %q uses the category reserved for canary tokens", justification.CanaryCategory)
+	}
+
+	if cfg.ApproverValidator {
+		if _, ok := validators[approver.Category]; ok {
+			return nil, closer, fmt.Errorf("approver validator conflicts with the plugin of the same name")
+		}
+		directory, err := approver.NewAdminDirectory(ctx)
+		if err != nil {
+			return nil, closer, err
+		}
+		validators[approver.Category] = plugin.NewMonitor(
+			approver.NewValidator(directory, cfg.ApproverGroups), plugin.KindBuiltin, "", "")
+		logger.InfoContext(ctx, "approver validator enabled", "groups", cfg.ApproverGroups)
+	}
+
+	return validators, closer, nil
+}
+
+// loadSealedConfigs reads the sealed configs of the local plugins. It returns
+// nil if there are none.
+func loadSealedConfigs(cfg *config.JustificationConfig) (*plugin.SealedConfigs, error) {
+	paths, err := cfg.PluginSealedConfigPaths()
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin sealed configs: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	sealed := &plugin.SealedConfigs{
+		Key:         cfg.PluginConfigKey,
+		Ciphertexts: make(map[string][]byte, len(paths)),
+	}
+	for name, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sealed config of plugin %s: %w", name, err)
+		}
+		sealed.Ciphertexts[name] = b
+	}
+	return sealed, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/pkg/cli"
+)
+
+const (
+	// Expectations of the policy test command.
+	policyExpectAllow = "allow"
+	policyExpectDeny  = "deny"
+)
+
+var _ cli.Command = (*PolicyTestCommand)(nil)
+
+type PolicyTestCommand struct {
+	cli.BaseCommand
+
+	flagExpression       string
+	flagRequestor        string
+	flagDefaultTTL       time.Duration
+	flagMaxTTL           time.Duration
+	flagAudienceTemplate string
+	flagExpect           string
+}
+
+func (c *PolicyTestCommand) Desc() string {
+	return `Evaluate a policy expression against sample requests`
+}
+
+func (c *PolicyTestCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options] REQUEST...
+
+  Evaluate a policy expression against sample justification requests locally,
+  without a running API server, and print whether each one is allowed or
+  denied. Each REQUEST is a file containing a CreateJustificationRequest in
+  protobuf JSON format, or "-" to read one from stdin. With -expect, fail
+  unless every request is allowed or every request is denied, e.g. to test a
+  policy change in CI.
+
+  Verify that breakglass requests are denied for more than an hour:
+
+      jvsctl policy test \
+        -expression '!("breakglass" in categories) || ttl <= duration("1h")' \
+        -expect "deny" \
+        "breakglass-2h.json"
+`
+}
+
+func (c *PolicyTestCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "expression",
+		Target:  &c.flagExpression,
+		Example: `"breakglass" in categories`,
+		EnvVar:  "JVS_API_POLICY_EXPRESSION",
+		Usage:   `The CEL policy expression to evaluate.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "requestor",
+		Target:  &c.flagRequestor,
+		Example: "me@example.com",
+		Usage:   `The requestor to evaluate the requests as.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "expect",
+		Target:  &c.flagExpect,
+		Example: policyExpectAllow,
+		Usage: fmt.Sprintf(`Fail unless every request is %q or every `+
+			`request is %q.`, policyExpectAllow, policyExpectDeny),
+	})
+
+	// Server options
+	f = set.NewSection("SERVER OPTIONS")
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "default-ttl",
+		Target:  &c.flagDefaultTTL,
+		Default: 15 * time.Minute,
+		EnvVar:  "JVS_API_DEFAULT_TTL",
+		Usage:   `The TTL of requests that do not request one.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "max-ttl",
+		Target:  &c.flagMaxTTL,
+		Default: 4 * time.Hour,
+		EnvVar:  "JVS_API_MAX_TTL",
+		Usage:   `The maximum TTL of requests.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audience-template",
+		Target:  &c.flagAudienceTemplate,
+		Example: "https://" + config.AudienceTemplateService + ".example.com",
+		EnvVar:  "JVS_API_AUDIENCE_TEMPLATE",
+		Usage:   `The template the audience services of requests expand to.`,
+	})
+
+	return set
+}
+
+func (c *PolicyTestCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("expected at least one request file")
+	}
+
+	switch c.flagExpect {
+	case "", policyExpectAllow, policyExpectDeny:
+	default:
+		return fmt.Errorf("expect must be %q or %q, got %q",
+			policyExpectAllow, policyExpectDeny, c.flagExpect)
+	}
+	if c.flagExpression == "" {
+		return fmt.Errorf("expression is required")
+	}
+
+	expr, err := justification.CompilePolicyExpression(c.flagExpression)
+	if err != nil {
+		return err //nolint:wrapcheck // Already wrapped
+	}
+	p := justification.NewProcessor(nil, &config.JustificationConfig{
+		DefaultTTL:       c.flagDefaultTTL,
+		MaxTTL:           c.flagMaxTTL,
+		AudienceTemplate: c.flagAudienceTemplate,
+
+		// The command does not sign tokens, but the processor requires a cache.
+		SignerCacheTimeout: time.Minute,
+	}).WithPolicyExpression(expr)
+
+	var mismatched int
+	for _, name := range args {
+		req, err := c.readRequest(name)
+		if err != nil {
+			return err
+		}
+
+		allowed, err := p.EvaluatePolicyExpression(ctx, c.flagRequestor, req)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate %s: %w", name, err)
+		}
+
+		result := policyExpectDeny
+		if allowed {
+			result = policyExpectAllow
+		}
+		if c.flagExpect != "" && result != c.flagExpect {
+			mismatched++
+		}
+		c.Outf("%s\t%s", result, name)
+	}
+
+	if mismatched > 0 {
+		return fmt.Errorf("%d of %d requests were not %s", mismatched, len(args), c.flagExpect)
+	}
+	return nil
+}
+
+// readRequest reads a justification request in protobuf JSON format from the
+// file, or from stdin if the name is "-".
+func (c *PolicyTestCommand) readRequest(name string) (*jvspb.CreateJustificationRequest, error) {
+	var b []byte
+	var err error
+	if name == "-" {
+		b, err = io.ReadAll(c.Stdin())
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request %s: %w", name, err)
+	}
+
+	var req jvspb.CreateJustificationRequest
+	if err := protojson.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request %s: %w", name, err)
+	}
+	return &req, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPolicyTestCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	dir := t.TempDir()
+	requests := map[string]string{
+		"explanation.json": `{"justifications":[{"category":"explanation","value":"debugging"}]}`,
+		"breakglass.json":  `{"justifications":[{"category":"breakglass","value":"outage"}],"ttl":"7200s"}`,
+		"invalid.json":     `{"justifications":`,
+	}
+	for name, contents := range requests {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	explanation := filepath.Join(dir, "explanation.json")
+	breakglass := filepath.Join(dir, "breakglass.json")
+
+	expr := `!("breakglass" in categories) || ttl <= duration("1h")`
+
+	cases := []struct {
+		name      string
+		args      []string
+		stdin     string
+		expOutput string
+		expErr    string
+	}{
+		{
+			name:   "no_args",
+			args:   []string{"-expression", expr},
+			expErr: "expected at least one request file",
+		},
+		{
+			name:   "invalid_expect",
+			args:   []string{"-expression", expr, "-expect", "maybe", explanation},
+			expErr: `expect must be "allow" or "deny", got "maybe"`,
+		},
+		{
+			name:   "missing_expression",
+			args:   []string{explanation},
+			expErr: "expression is required",
+		},
+		{
+			name:   "invalid_expression",
+			args:   []string{"-expression", "ttl", explanation},
+			expErr: "policy expression must evaluate to a bool",
+		},
+		{
+			name:   "missing_request",
+			args:   []string{"-expression", expr, filepath.Join(dir, "missing.json")},
+			expErr: "failed to read request",
+		},
+		{
+			name:   "invalid_request",
+			args:   []string{"-expression", expr, filepath.Join(dir, "invalid.json")},
+			expErr: "failed to parse request",
+		},
+		{
+			name:      "allow_and_deny",
+			args:      []string{"-expression", expr, explanation, breakglass},
+			expOutput: "allow\t" + explanation + "\ndeny\t" + breakglass,
+		},
+		{
+			name:      "expect_deny",
+			args:      []string{"-expression", expr, "-expect", "deny", breakglass},
+			expOutput: "deny\t" + breakglass,
+		},
+		{
+			name:      "expect_mismatch",
+			args:      []string{"-expression", expr, "-expect", "allow", explanation, breakglass},
+			expOutput: "allow\t" + explanation + "\ndeny\t" + breakglass,
+			expErr:    "1 of 2 requests were not allow",
+		},
+		{
+			name:      "max_ttl",
+			args:      []string{"-expression", `ttl <= duration("1h")`, "-max-ttl", "1h", breakglass},
+			expOutput: "deny\t" + breakglass,
+		},
+		{
+			name:      "stdin",
+			args:      []string{"-expression", `requestor == "me@example.com"`, "-requestor", "me@example.com", "-"},
+			stdin:     `{"justifications":[{"category":"explanation","value":"debugging"}]}`,
+			expOutput: "allow\t-",
+		},
+		{
+			name:      "audience_template",
+			args:      []string{"-expression", `audiences == ["https://foo.example.com"]`, "-audience-template", "https://{service}.example.com", "-"},
+			stdin:     `{"audience_services":["foo"]}`,
+			expOutput: "allow\t-",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cmd PolicyTestCommand
+			stdin, stdout, _ := cmd.Pipe()
+			stdin.WriteString(tc.stdin)
+
+			err := cmd.Run(ctx, tc.args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+		})
+	}
+}
@@ -16,21 +16,28 @@ package cli
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"fmt"
 	"net/http"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"google.golang.org/api/option"
 
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/internal/version"
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/hsm"
+	"github.com/abcxyz/jvs/pkg/idtoken"
 	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/jvs/pkg/serving"
+	"github.com/abcxyz/jvs/pkg/vault"
 	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/healthcheck"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/multicloser"
 	"github.com/abcxyz/pkg/renderer"
-	"github.com/abcxyz/pkg/serving"
 )
 
 var _ cli.Command = (*PublicKeyServerCommand)(nil)
@@ -101,11 +108,23 @@ func (c *PublicKeyServerCommand) RunUnstarted(ctx context.Context, args []string
 	}
 	logger.DebugContext(ctx, "loaded configuration", "config", c.cfg)
 
-	kmsClient, err := kms.NewKeyManagementClient(ctx, c.testKMSClientOptions...)
-	if err != nil {
-		return nil, nil, closer, fmt.Errorf("failed to setup kms client: %w", err)
+	// Keys are only in KMS with the kms signer, the other signers read them
+	// from disk or an HSM.
+	var kmsClient *kms.KeyManagementClient
+	if usesKMS(c.cfg.Signer) {
+		var err error
+		kmsClient, err = jvscrypto.NewKMSClient(ctx, jvscrypto.DefaultKMSRetryPolicy(), append(
+			kmsClientOptions(c.cfg.KMSEndpoint, c.cfg.KMSInsecure),
+			c.testKMSClientOptions...)...)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to setup kms client: %w", err)
+		}
+		closer = multicloser.Append(closer, kmsClient.Close)
+		if err := checkKMSPermissions(ctx, kmsClient, c.cfg.KMSPermissionCheck, c.cfg.KMSInsecure,
+			c.cfg.KeyNames, jvscrypto.PublicKeyPermissions); err != nil {
+			return nil, nil, closer, err
+		}
 	}
-	closer = multicloser.Append(closer, kmsClient.Close)
 
 	// Create the renderer
 	h, err := renderer.New(ctx, nil,
@@ -118,6 +137,39 @@ func (c *PublicKeyServerCommand) RunUnstarted(ctx context.Context, args []string
 	}
 
 	keyServer := jvscrypto.NewKeyServer(ctx, kmsClient, c.cfg, h)
+	if c.cfg.Signer == config.SignerLocal {
+		keyServer.WithLocalKeys(c.cfg.KeyPaths)
+		logger.InfoContext(ctx, "serving local public keys", "key_paths", c.cfg.KeyPaths)
+	}
+	if c.cfg.Signer == config.SignerPKCS11 {
+		keys, err := hsm.Open(ctx, &c.cfg.PKCS11)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to open hsm: %w", err)
+		}
+		closer = multicloser.Append(closer, keys.Close)
+		keyServer.WithKeyManager(keys)
+		logger.InfoContext(ctx, "serving hsm public keys", "keys", c.cfg.KeyNames)
+	}
+	if c.cfg.Signer == config.SignerVault {
+		keyServer.WithKeyManager(vault.NewKeyManager(&c.cfg.Vault))
+		logger.InfoContext(ctx, "serving vault public keys", "keys", c.cfg.KeyNames)
+	}
+
+	if c.cfg.JWKSSigningKey != "" || c.cfg.JWKSSigningKeyPath != "" {
+		signer, kid, err := loadKeySetSigner(ctx, kmsClient, c.cfg)
+		if err != nil {
+			return nil, nil, closer, err
+		}
+		thumbprint, err := jvscrypto.LocalKeyID(signer.Public())
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to compute thumbprint of jwks signing key: %w", err)
+		}
+		keyServer.WithKeySetSigner(signer, kid, c.cfg.JWKSSignatureTTL)
+		logger.InfoContext(ctx, "jwks signing enabled",
+			"kid", kid,
+			"root_key_thumbprint", thumbprint,
+			"ttl", c.cfg.JWKSSignatureTTL)
+	}
 
 	mux := http.NewServeMux()
 
@@ -125,13 +177,78 @@ func (c *PublicKeyServerCommand) RunUnstarted(ctx context.Context, args []string
 	//
 	// See: https://cloud.google.com/run/docs/issues#ah
 	mux.Handle("/health", healthcheck.HandleHTTPHealthCheck())
+	mux.Handle("/ready", serving.HandleHTTPReadiness(keyServer.Ready))
 	mux.Handle("/.well-known/jwks", keyServer)
+	mux.HandleFunc("GET /keys/{kid...}", keyServer.ServeKey)
+	mux.Handle("GET /openapi.json", jvscrypto.HandleOpenAPI())
+	if c.cfg.KeyUsageTelemetry {
+		// Keys are fetched lazily on first use.
+		keys, err := jvspb.NewJWKSProvider(ctx, c.cfg.KeyUsageJWKSEndpoint)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create key usage jwks provider: %w", err)
+		}
+		verifier := idtoken.NewVerifier(keys, c.cfg.KeyUsageIssuer, c.cfg.KeyUsageAudience)
+		mux.Handle("/key-usage", jvscrypto.NewIDTokenAuthenticator(verifier, c.cfg.KeyUsagePrincipals, "key usage request", h).
+			Handler(jvscrypto.NewKeyUsageServer(c.cfg, h)))
+	}
+
+	revocations, closer, err := loadRevocations(ctx, &c.cfg.Revocation, closer)
+	if err != nil {
+		return nil, nil, closer, err
+	}
+	if revocations != nil {
+		mux.Handle("GET /revocations", revocations)
+	}
+
+	clientSecrets, err := c.cfg.IntrospectionClientSecrets()
+	if err != nil {
+		return nil, nil, closer, fmt.Errorf("failed to parse introspection clients: %w", err)
+	}
+	if len(clientSecrets) > 0 {
+		introspection := jvscrypto.NewIntrospectionServer(keyServer, clientSecrets, h).
+			WithClaimsNamespace(c.cfg.ClaimsNamespace).
+			WithIssuers(c.cfg.IntrospectionIssuers...).
+			WithTokenTypes(c.cfg.IntrospectionTokenTypes...)
+		if revocations != nil {
+			introspection.WithRevocations(revocations)
+		}
+		mux.Handle("/introspect", introspection)
+		logger.InfoContext(ctx, "token introspection enabled", "clients", introspection.ClientIDs())
+	}
 
 	root := logging.HTTPInterceptor(logger, c.cfg.ProjectID)(mux)
 
-	server, err := serving.New(c.cfg.Port)
+	server, err := serving.New(c.cfg.Port, c.cfg.ShutdownTimeout)
 	if err != nil {
 		return nil, nil, closer, fmt.Errorf("failed to create serving infrastructure: %w", err)
 	}
+	server.WithWarmup(c.cfg.WarmupTimeout, keyServer.Ready)
 	return server, root, closer, nil
 }
+
+// loadKeySetSigner returns the root key that signs the JWKS and its key ID,
+// either the KMS key version or the local key.
+func loadKeySetSigner(ctx context.Context, kmsClient *kms.KeyManagementClient, cfg *config.PublicKeyConfig) (crypto.Signer, string, error) {
+	var signer crypto.Signer
+	var kid string
+	if cfg.JWKSSigningKeyPath != "" {
+		var err error
+		signer, kid, err = jvscrypto.LoadSigningKey(cfg.JWKSSigningKeyPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load jwks signing key: %w", err)
+		}
+	} else {
+		var err error
+		signer, err = jvscrypto.NewKMSKeyManager(kmsClient).Signer(ctx, cfg.JWKSSigningKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to load jwks signing key: %w", err)
+		}
+		kid = cfg.JWKSSigningKey
+	}
+
+	// Signed key sets are verified with ES256.
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P256() {
+		return nil, "", fmt.Errorf("jwks signing key must be an ecdsa p-256 key, got %T", signer.Public())
+	}
+	return signer, kid, nil
+}
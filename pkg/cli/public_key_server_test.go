@@ -32,6 +32,7 @@ func TestPublicKeyServerCommand(t *testing.T) {
 	t.Parallel()
 
 	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	keyPath := testSigningKeyPath(t)
 
 	cases := []struct {
 		name   string
@@ -53,9 +54,50 @@ func TestPublicKeyServerCommand(t *testing.T) {
 		},
 		{
 			name: "starts",
+			env: map[string]string{
+				"PROJECT_ID":               "example-project",
+				"JVS_KEY_NAMES":            "fake/key",
+				"JVS_KMS_PERMISSION_CHECK": "off",
+			},
+		},
+		{
+			name: "missing_pkcs11_module",
+			env: map[string]string{
+				"PROJECT_ID":             "example-project",
+				"JVS_SIGNER":             "pkcs11",
+				"JVS_KEY_NAMES":          "jvs-signing",
+				"JVS_PKCS11_MODULE":      "/nonexistent/libpkcs11.so",
+				"JVS_PKCS11_TOKEN_LABEL": "jvs",
+				"JVS_PKCS11_PIN":         "1234",
+			},
+			expErr: "failed to open hsm",
+		},
+		{
+			name: "starts_with_vault",
+			env: map[string]string{
+				"PROJECT_ID":      "example-project",
+				"JVS_SIGNER":      "vault",
+				"JVS_KEY_NAMES":   "jvs-signing",
+				"JVS_VAULT_ADDR":  "http://127.0.0.1:8200",
+				"JVS_VAULT_TOKEN": "s.token",
+			},
+		},
+		{
+			name: "missing_vault_token",
+			env: map[string]string{
+				"PROJECT_ID":     "example-project",
+				"JVS_SIGNER":     "vault",
+				"JVS_KEY_NAMES":  "jvs-signing",
+				"JVS_VAULT_ADDR": "http://127.0.0.1:8200",
+			},
+			expErr: "empty Vault Token",
+		},
+		{
+			name: "starts_with_local_keys",
 			env: map[string]string{
 				"PROJECT_ID":    "example-project",
-				"JVS_KEY_NAMES": "fake/key",
+				"JVS_SIGNER":    "local",
+				"JVS_KEY_PATHS": keyPath,
 			},
 		},
 	}
@@ -73,6 +115,8 @@ func TestPublicKeyServerCommand(t *testing.T) {
 				cli.MapLookuper(map[string]string{
 					// Make the test choose a random port.
 					"PORT": "0",
+					// Don't wait for KMS, which these tests don't call.
+					"JVS_WARMUP_TIMEOUT": "0",
 				}),
 			))
 			cmd.testKMSClientOptions = []option.ClientOption{
@@ -0,0 +1,66 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/quota"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// loadQuotas opens the quota usage store. It returns nil if no quotas are
+// configured.
+func loadQuotas(ctx context.Context, cfg *config.QuotaConfig, closer *multicloser.Closer) (*quota.Quotas, *multicloser.Closer, error) {
+	logger := logging.FromContext(ctx)
+
+	if !cfg.Enabled() {
+		return nil, closer, nil
+	}
+	limits, err := cfg.LimitsByCategory()
+	if err != nil {
+		return nil, closer, fmt.Errorf("failed to parse quotas: %w", err)
+	}
+
+	var store quota.Store
+	switch {
+	case cfg.Path != "":
+		store = quota.NewFileStore(cfg.Path)
+		logger.InfoContext(ctx, "quotas enabled", "limits", limits, "path", cfg.Path)
+	case cfg.PostgresURL != "":
+		db, err := sql.Open("pgx", cfg.PostgresURL)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to open quota database: %w", err)
+		}
+		closer = multicloser.Append(closer, db.Close)
+		if err := db.PingContext(ctx); err != nil {
+			return nil, closer, fmt.Errorf("failed to connect to quota database: %w", err)
+		}
+		pgStore := quota.NewPostgresStore(db)
+		if err := pgStore.Migrate(ctx); err != nil {
+			return nil, closer, fmt.Errorf("failed to migrate quota database: %w", err)
+		}
+		store = pgStore
+		logger.InfoContext(ctx, "quotas enabled", "limits", limits, "store", "postgres")
+	default:
+		return nil, closer, fmt.Errorf("quota path or quota postgres url must be set with quota limits")
+	}
+
+	return quota.New(store, limits), closer, nil
+}
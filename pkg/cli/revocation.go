@@ -0,0 +1,64 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/revocation"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// loadRevocations opens the revocation store and loads the revocations, which
+// are reloaded until the context is done. It returns nil if revocation is not
+// enabled.
+func loadRevocations(ctx context.Context, cfg *config.RevocationConfig, closer *multicloser.Closer) (*revocation.List, *multicloser.Closer, error) {
+	logger := logging.FromContext(ctx)
+
+	var store revocation.Store
+	switch {
+	case cfg.Path != "":
+		store = revocation.NewFileStore(cfg.Path)
+		logger.InfoContext(ctx, "revocation enabled", "path", cfg.Path)
+	case cfg.PostgresURL != "":
+		db, err := sql.Open("pgx", cfg.PostgresURL)
+		if err != nil {
+			return nil, closer, fmt.Errorf("failed to open revocation database: %w", err)
+		}
+		closer = multicloser.Append(closer, db.Close)
+		if err := db.PingContext(ctx); err != nil {
+			return nil, closer, fmt.Errorf("failed to connect to revocation database: %w", err)
+		}
+		pgStore := revocation.NewPostgresStore(db)
+		if err := pgStore.Migrate(ctx); err != nil {
+			return nil, closer, fmt.Errorf("failed to migrate revocation database: %w", err)
+		}
+		store = pgStore
+		logger.InfoContext(ctx, "revocation enabled", "store", "postgres")
+	default:
+		return nil, closer, nil
+	}
+
+	list, err := revocation.Load(ctx, store)
+	if err != nil {
+		return nil, closer, err //nolint:wrapcheck // Already wrapped.
+	}
+	go list.Watch(ctx, cfg.ReloadInterval)
+	return list, closer, nil
+}
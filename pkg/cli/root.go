@@ -18,13 +18,14 @@ package cli
 import (
 	"context"
 
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/internal/version"
 	"github.com/abcxyz/pkg/cli"
 )
 
 const (
 	// Issuer is the default issuer (iss) for tokens created by the CLI.
-	Issuer = "jvsctl"
+	Issuer = jvspb.BreakglassIssuer
 )
 
 // rootCmd defines the starting command structure.
@@ -38,12 +39,76 @@ var rootCmd = func() cli.Command {
 					Name:        "api",
 					Description: "Perform API operations",
 					Commands: map[string]cli.CommandFactory{
+						"descriptors": func() cli.Command {
+							return &APIDescriptorsCommand{}
+						},
 						"server": func() cli.Command {
 							return &APIServerCommand{}
 						},
 					},
 				}
 			},
+			"audit": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "audit",
+					Description: "Perform audit operations",
+					Commands: map[string]cli.CommandFactory{
+						"backfill": func() cli.Command {
+							return &AuditBackfillCommand{}
+						},
+						"search": func() cli.Command {
+							return &AuditSearchCommand{}
+						},
+					},
+				}
+			},
+			"bootstrap": func() cli.Command {
+				return &BootstrapCommand{}
+			},
+			"dev": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "dev",
+					Description: "Perform development operations",
+					Commands: map[string]cli.CommandFactory{
+						"up": func() cli.Command {
+							return &DevUpCommand{}
+						},
+					},
+				}
+			},
+			"jwks": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "jwks",
+					Description: "Perform jwks operations",
+					Commands: map[string]cli.CommandFactory{
+						"diff": func() cli.Command {
+							return &JWKSDiffCommand{}
+						},
+					},
+				}
+			},
+			"migrate": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "migrate",
+					Description: "Perform migration operations",
+					Commands: map[string]cli.CommandFactory{
+						"keys": func() cli.Command {
+							return &MigrateKeysCommand{}
+						},
+					},
+				}
+			},
+			"policy": func() cli.Command {
+				return &cli.RootCommand{
+					Name:        "policy",
+					Description: "Perform policy operations",
+					Commands: map[string]cli.CommandFactory{
+						"test": func() cli.Command {
+							return &PolicyTestCommand{}
+						},
+					},
+				}
+			},
 			"public-key": func() cli.Command {
 				return &cli.RootCommand{
 					Name:        "public-key",
@@ -74,6 +139,9 @@ var rootCmd = func() cli.Command {
 						"create": func() cli.Command {
 							return &TokenCreateCommand{}
 						},
+						"receipt": func() cli.Command {
+							return &TokenReceiptCommand{}
+						},
 						"validate": func() cli.Command {
 							return &TokenValidateCommand{}
 						},
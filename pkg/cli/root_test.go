@@ -26,6 +26,12 @@ func TestRootCommand_Help(t *testing.T) {
 Usage: jvsctl COMMAND
 
   api           Perform API operations
+  audit         Perform audit operations
+  bootstrap     Create the KMS key for the JVS without Terraform
+  dev           Perform development operations
+  jwks          Perform jwks operations
+  migrate       Perform migration operations
+  policy        Perform policy operations
   public-key    Perform public-key operations
   rotation      Perform rotation operations
   token         Perform token operations
@@ -22,15 +22,20 @@ import (
 	kms "cloud.google.com/go/kms/apiv1"
 	"google.golang.org/api/option"
 
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/internal/version"
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/events"
+	"github.com/abcxyz/jvs/pkg/hsm"
+	"github.com/abcxyz/jvs/pkg/idtoken"
 	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/jvs/pkg/serving"
+	"github.com/abcxyz/jvs/pkg/vault"
 	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/healthcheck"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/multicloser"
 	"github.com/abcxyz/pkg/renderer"
-	"github.com/abcxyz/pkg/serving"
 )
 
 var _ cli.Command = (*RotationServerCommand)(nil)
@@ -101,12 +106,22 @@ func (c *RotationServerCommand) RunUnstarted(ctx context.Context, args []string)
 	}
 	logger.DebugContext(ctx, "loaded configuration", "config", c.cfg)
 
-	// Create the client
-	kmsClient, err := kms.NewKeyManagementClient(ctx, c.testKMSClientOptions...)
-	if err != nil {
-		return nil, nil, closer, fmt.Errorf("failed to setup kms client: %w", err)
+	// Create the client, keys are only in KMS with the kms signer.
+	var kmsClient *kms.KeyManagementClient
+	if usesKMS(c.cfg.Signer) {
+		var err error
+		kmsClient, err = jvscrypto.NewKMSClient(ctx, jvscrypto.DefaultKMSRetryPolicy(), append(
+			kmsClientOptions(c.cfg.KMSEndpoint, c.cfg.KMSInsecure),
+			c.testKMSClientOptions...)...)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to setup kms client: %w", err)
+		}
+		closer = multicloser.Append(closer, kmsClient.Close)
+		if err := checkKMSPermissions(ctx, kmsClient, c.cfg.KMSPermissionCheck, c.cfg.KMSInsecure,
+			c.cfg.KeyNames, jvscrypto.RotationPermissions); err != nil {
+			return nil, nil, closer, err
+		}
 	}
-	closer = multicloser.Append(closer, kmsClient.Close)
 
 	// Create the renderer
 	h, err := renderer.New(ctx, nil,
@@ -120,6 +135,27 @@ func (c *RotationServerCommand) RunUnstarted(ctx context.Context, args []string)
 
 	// Create the rotation handler
 	rotationHandler := jvscrypto.NewRotationHandler(ctx, kmsClient, c.cfg)
+	if c.cfg.Signer == config.SignerPKCS11 {
+		keys, err := hsm.Open(ctx, &c.cfg.PKCS11)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to open hsm: %w", err)
+		}
+		closer = multicloser.Append(closer, keys.Close)
+		rotationHandler.WithKeyManager(keys)
+		logger.InfoContext(ctx, "rotating hsm keys", "keys", c.cfg.KeyNames)
+	}
+	if c.cfg.Signer == config.SignerVault {
+		rotationHandler.WithKeyManager(vault.NewKeyManager(&c.cfg.Vault))
+		logger.InfoContext(ctx, "rotating vault keys", "keys", c.cfg.KeyNames)
+	}
+	if c.cfg.EventsTopic != "" {
+		publisher, err := events.NewPubSubPublisher(ctx, c.cfg.EventsTopic, events.SourceRotation)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create event publisher: %w", err)
+		}
+		rotationHandler.WithEventPublisher(publisher)
+		logger.InfoContext(ctx, "event publishing enabled", "topic", c.cfg.EventsTopic)
+	}
 
 	mux := http.NewServeMux()
 
@@ -127,7 +163,18 @@ func (c *RotationServerCommand) RunUnstarted(ctx context.Context, args []string)
 	//
 	// See: https://cloud.google.com/run/docs/issues#ah
 	mux.Handle("/health", healthcheck.HandleHTTPHealthCheck())
-	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/ready", serving.HandleHTTPReadiness(rotationHandler.Ready))
+	if c.cfg.ChatOpsSigningSecret != "" {
+		service := &jvscrypto.CertificateActionService{
+			Handler:   rotationHandler,
+			KMSClient: kmsClient,
+			KeyNames:  c.cfg.KeyNames,
+		}
+		mux.Handle("/chatops/slack", jvscrypto.NewChatOpsHandler(service,
+			[]byte(c.cfg.ChatOpsSigningSecret), c.cfg.ChatOpsAllowedUsers))
+		logger.InfoContext(ctx, "chatops enabled", "allowed_users", c.cfg.ChatOpsAllowedUsers)
+	}
+	var trigger http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		logger := logging.FromContext(ctx)
@@ -140,11 +187,24 @@ func (c *RotationServerCommand) RunUnstarted(ctx context.Context, args []string)
 		}
 
 		h.RenderJSON(w, http.StatusOK, nil)
-	}))
+	})
+	if c.cfg.TriggerAudience != "" {
+		// Keys are fetched lazily on first use.
+		keys, err := jvspb.NewJWKSProvider(ctx, c.cfg.TriggerJWKSEndpoint)
+		if err != nil {
+			return nil, nil, closer, fmt.Errorf("failed to create trigger jwks provider: %w", err)
+		}
+		verifier := idtoken.NewVerifier(keys, c.cfg.TriggerIssuer, c.cfg.TriggerAudience)
+		trigger = jvscrypto.NewIDTokenAuthenticator(verifier, c.cfg.TriggerPrincipals, "rotation trigger", h).Handler(trigger)
+		logger.InfoContext(ctx, "rotation trigger authentication enabled",
+			"audience", c.cfg.TriggerAudience,
+			"principals", c.cfg.TriggerPrincipals)
+	}
+	mux.Handle("/", trigger)
 
 	root := logging.HTTPInterceptor(logger, c.cfg.ProjectID)(mux)
 
-	server, err := serving.New(c.cfg.Port)
+	server, err := serving.New(c.cfg.Port, c.cfg.ShutdownTimeout)
 	if err != nil {
 		return nil, nil, closer, fmt.Errorf("failed to create serving infrastructure: %w", err)
 	}
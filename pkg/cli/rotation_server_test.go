@@ -55,10 +55,54 @@ func TestRotationServerCommand(t *testing.T) {
 		{
 			name: "starts",
 			env: map[string]string{
-				"PROJECT_ID":    "example-project",
-				"JVS_KEY_NAMES": "fake/key",
+				"PROJECT_ID":               "example-project",
+				"JVS_KEY_NAMES":            "fake/key",
+				"JVS_KMS_PERMISSION_CHECK": "off",
 			},
 		},
+		{
+			name: "starts_with_trigger_auth",
+			env: map[string]string{
+				"PROJECT_ID":                         "example-project",
+				"JVS_KEY_NAMES":                      "fake/key",
+				"JVS_KMS_PERMISSION_CHECK":           "off",
+				"JVS_ROTATION_TRIGGER_AUDIENCE":      "https://jvs-cert-rotator.example.com",
+				"JVS_ROTATION_TRIGGER_PRINCIPALS":    "scheduler@example-project.iam.gserviceaccount.com",
+				"JVS_ROTATION_TRIGGER_JWKS_ENDPOINT": "http://127.0.0.1:0/jwks",
+			},
+		},
+		{
+			name: "missing_pkcs11_module",
+			env: map[string]string{
+				"PROJECT_ID":             "example-project",
+				"JVS_SIGNER":             "pkcs11",
+				"JVS_KEY_NAMES":          "jvs-signing",
+				"JVS_PKCS11_MODULE":      "/nonexistent/libpkcs11.so",
+				"JVS_PKCS11_TOKEN_LABEL": "jvs",
+				"JVS_PKCS11_PIN":         "1234",
+			},
+			expErr: "failed to open hsm",
+		},
+		{
+			name: "starts_with_vault",
+			env: map[string]string{
+				"PROJECT_ID":      "example-project",
+				"JVS_SIGNER":      "vault",
+				"JVS_KEY_NAMES":   "jvs-signing",
+				"JVS_VAULT_ADDR":  "http://127.0.0.1:8200",
+				"JVS_VAULT_TOKEN": "s.token",
+			},
+		},
+		{
+			name: "missing_vault_token",
+			env: map[string]string{
+				"PROJECT_ID":     "example-project",
+				"JVS_SIGNER":     "vault",
+				"JVS_KEY_NAMES":  "jvs-signing",
+				"JVS_VAULT_ADDR": "http://127.0.0.1:8200",
+			},
+			expErr: "empty Vault Token",
+		},
 	}
 
 	for _, tc := range cases {
@@ -16,12 +16,25 @@ package cli
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
@@ -29,9 +42,12 @@ import (
 	grpcinsecure "google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/credentials/oauth"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/idtoken"
 	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/jvs/pkg/tokenstore"
 	"github.com/abcxyz/pkg/cli"
 )
 
@@ -41,11 +57,24 @@ type TokenCreateCommand struct {
 	cli.BaseCommand
 
 	flagAudiences         []string
+	flagAudienceServices  []string
 	flagAuthToken         string
+	flagAuthAudience      string
+	flagImpersonateSA     string
 	flagBreakglass        bool
+	flagCache             bool
+	flagCacheDir          string
+	flagCacheStorage      string
 	flagExplanation       string
-	flagCategory          string
-	flagJustificationText string
+	flagCategories        []string
+	flagGitHubOIDC        bool
+	flagGitHubOIDCAud     string
+	flagJustifications    []string
+	flagJustificationFile string
+	flagJWKThumbprint     string
+	flagMetadataFile      string
+	flagReceiptFile       string
+	flagStartTime         string
 	flagSubject           string
 	flagTTL               time.Duration
 
@@ -84,12 +113,44 @@ Usage: {{ COMMAND }} [options]
         -justification "JIRACOMPONENT/123" \
         -ttl "30m"
 
+  Generate a token with a Jira ticket and an explanation:
+
+      jvsctl token create \
+        -category "jira" -justification "JIRACOMPONENT/123" \
+        -category "explanation" -justification "rolling back the release"
+
+  Generate a token with a long justification read from stdin:
+
+      git log -1 --format=%B | jvsctl token create \
+        -justification-file -
+
+  Generate a token for a change window starting later:
+
+      jvsctl token create \
+        -justification "CHG0012345" \
+        -start-time "2023-06-01T22:00:00Z" \
+        -ttl "2h"
+
   Generate a token with custom audiences:
 
       jvsctl token create \
         -justification "access production" \
         -audiences "my.service.dev"
 
+  Generate a token for the payments service, if the JVS has an audience
+  template:
+
+      jvsctl token create \
+        -justification "access production" \
+        -audience-service "payments"
+
+  Generate a token from a GitHub Actions workflow, exchanging the job's OIDC
+  token instead of using long-lived credentials:
+
+      jvsctl token create \
+        -justification "deploying release v1.2.3" \
+        -github-oidc
+
   Generate a breakglass token:
 
       jvsctl token create \
@@ -114,12 +175,41 @@ func (c *TokenCreateCommand) Flags() *cli.FlagSet {
 			`justification token.`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "audience-service",
+		Target:  &c.flagAudienceServices,
+		Example: "payments,billing",
+		EnvVar:  "JVSCTL_TOKEN_AUDIENCE_SERVICES",
+		Usage: `The short names of services to mint the token for. The JVS ` +
+			`expands them into audiences with its audience template.`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:    "auth-token",
 		Target:  &c.flagAuthToken,
 		Example: "ya29.c...",
 		EnvVar:  "JVSCTL_AUTH_TOKEN",
-		Usage:   `An OIDC token to use for authentication.`,
+		Usage: `An OIDC token to use for authentication. If unset, an ID ` +
+			`token is minted from Application Default Credentials, unless ` +
+			`-insecure is set.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "auth-audience",
+		Target:  &c.flagAuthAudience,
+		Example: "https://jvs.example.com",
+		EnvVar:  "JVSCTL_AUTH_AUDIENCE",
+		Usage: `The audience of the ID token minted from Application Default ` +
+			`Credentials. Defaults to the server URL.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "impersonate-service-account",
+		Target:  &c.flagImpersonateSA,
+		Example: "jvs-user@my-project.iam.gserviceaccount.com",
+		EnvVar:  "JVSCTL_IMPERSONATE_SERVICE_ACCOUNT",
+		Usage: `Mint the ID token as this service account, using Application ` +
+			`Default Credentials to impersonate it.`,
 	})
 
 	f.BoolVar(&cli.BoolVar{
@@ -138,19 +228,76 @@ func (c *TokenCreateCommand) Flags() *cli.FlagSet {
 		Hidden:  true,
 	})
 
-	f.StringVar(&cli.StringVar{
+	repeatableStringVar(f, &cli.StringSliceVar{
 		Name:    "category",
-		Target:  &c.flagCategory,
+		Target:  &c.flagCategories,
 		EnvVar:  "JVSCTL_CATEGORY",
-		Default: jvspb.DefaultJustificationCategory,
-		Usage:   `The justification category.`,
+		Default: []string{jvspb.DefaultJustificationCategory},
+		Usage: `The justification category. Repeat it to give each ` +
+			`justification its own category, in order, otherwise all ` +
+			`justifications have the same category.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "github-oidc",
+		Target:  &c.flagGitHubOIDC,
+		Default: false,
+		Usage: `Authenticate with the GitHub Actions OIDC token of the current ` +
+			`job and exchange it for a justification token.`,
 	})
 
 	f.StringVar(&cli.StringVar{
+		Name:    "github-oidc-audience",
+		Target:  &c.flagGitHubOIDCAud,
+		Example: "https://github.com/my-org",
+		EnvVar:  "JVSCTL_GITHUB_OIDC_AUDIENCE",
+		Usage: `The audience to request for the GitHub Actions OIDC token. ` +
+			`Defaults to the GitHub default audience.`,
+	})
+
+	repeatableStringVar(f, &cli.StringSliceVar{
 		Name:    "justification",
-		Target:  &c.flagJustificationText,
+		Target:  &c.flagJustifications,
 		Aliases: []string{"j"},
-		Usage:   `The justification text. The format depends on the justification category.`,
+		Usage: `The justification text. The format depends on the ` +
+			`justification category. Repeat it to add several justifications.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "justification-file",
+		Target:  &c.flagJustificationFile,
+		Example: "justification.txt",
+		Usage: `Read a justification from this file, or from stdin if "-". ` +
+			`It is added after the -justification flags, without its ` +
+			`trailing newline.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "jwk-thumbprint",
+		Target:  &c.flagJWKThumbprint,
+		Example: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		EnvVar:  "JVSCTL_TOKEN_JWK_THUMBPRINT",
+		Usage: `The base64url-encoded SHA-256 JWK thumbprint of a key you hold. ` +
+			`If set, the token is bound to the key, and can only be used with a ` +
+			`DPoP proof signed by it.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "metadata-file",
+		Target:  &c.flagMetadataFile,
+		Example: "token.json",
+		EnvVar:  "JVSCTL_TOKEN_METADATA_FILE",
+		Usage: `Write the expiration, ID, key ID and issuer of the token to ` +
+			`this file as JSON, e.g. to schedule refreshing it.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "receipt-file",
+		Target:  &c.flagReceiptFile,
+		Example: "receipt.jws",
+		EnvVar:  "JVSCTL_TOKEN_RECEIPT_FILE",
+		Usage: `Write the signed receipt of the token to this file, if the JVS ` +
+			`issues receipts. Receipts are not returned for cached tokens.`,
 	})
 
 	f.StringVar(&cli.StringVar{
@@ -170,6 +317,16 @@ func (c *TokenCreateCommand) Flags() *cli.FlagSet {
 		Usage:   `The token lifetime, as a duration.`,
 	})
 
+	f.StringVar(&cli.StringVar{
+		Name:    "start-time",
+		Target:  &c.flagStartTime,
+		Example: "2023-06-01T22:00:00Z",
+		EnvVar:  "JVSCTL_TOKEN_START_TIME",
+		Usage: `When the token becomes valid, in RFC 3339 format, e.g. the ` +
+			`start of a change window. The ttl is measured from it. Defaults ` +
+			`to now.`,
+	})
+
 	f.Int64Var(&cli.Int64Var{
 		Name:    "now",
 		Target:  &c.flagNowUnix,
@@ -178,6 +335,37 @@ func (c *TokenCreateCommand) Flags() *cli.FlagSet {
 		Usage:   `Current timestamp, in unix seconds.`,
 	})
 
+	// Cache flags
+	f = set.NewSection("CACHE OPTIONS")
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "cache",
+		Target:  &c.flagCache,
+		Default: false,
+		EnvVar:  "JVSCTL_TOKEN_CACHE",
+		Usage: `Reuse a previously created token for the same request while it ` +
+			`is still valid, instead of creating a new one.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "cache-storage",
+		Target:  &c.flagCacheStorage,
+		Default: tokenstore.StorageAuto,
+		EnvVar:  "JVSCTL_TOKEN_CACHE_STORAGE",
+		Usage: `Where cached tokens are stored, one of "auto", "keychain", or ` +
+			`"file". "auto" uses the OS keychain (Keychain, DPAPI, or ` +
+			`libsecret) and falls back to files when it is unavailable.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "cache-dir",
+		Target:  &c.flagCacheDir,
+		Example: "~/.cache/jvsctl/tokens",
+		EnvVar:  "JVSCTL_TOKEN_CACHE_DIR",
+		Usage: `The directory for file storage of cached tokens. Defaults to ` +
+			`"jvsctl/tokens" in the user cache directory.`,
+	})
+
 	// Server flags
 	f = set.NewSection("SERVER OPTIONS")
 
@@ -213,13 +401,14 @@ func (c *TokenCreateCommand) Run(ctx context.Context, args []string) error {
 	if c.flagExplanation != "" {
 		c.Errf(`WARNING: the "-explanation" flag is deprecated and will be removed in a future release. Use "-justification" instead.`)
 		// TODO(#308): For now, still support the old "explanation" flag if the new flag is not used.
-		if c.flagJustificationText == "" {
-			c.flagJustificationText = c.flagExplanation
+		if len(c.flagJustifications) == 0 {
+			c.flagJustifications = []string{c.flagExplanation}
 		}
 	}
 
-	if c.flagJustificationText == "" {
-		return fmt.Errorf("justification is required")
+	justs, err := c.justifications()
+	if err != nil {
+		return err
 	}
 
 	// Explicitly set this here because, if it's set as a default, there's no way
@@ -230,8 +419,17 @@ func (c *TokenCreateCommand) Run(ctx context.Context, args []string) error {
 
 	// breakglass won't require JVS server. Handle that first.
 	if c.flagBreakglass {
+		if c.flagStartTime != "" {
+			return fmt.Errorf("-start-time is not supported with -breakglass")
+		}
+		if len(c.flagAudienceServices) > 0 {
+			return fmt.Errorf("-audience-service is not supported with -breakglass")
+		}
+		if len(justs) > 1 {
+			return fmt.Errorf("multiple justifications are not supported with -breakglass")
+		}
 		c.Errf("WARNING: In breakglass mode, the justification token is not signed.")
-		tok, err := c.breakglassToken(ctx)
+		tok, err := c.breakglassToken(ctx, justs[0].GetValue())
 		if err != nil {
 			return fmt.Errorf("failed to generate breakglass token: %w", err)
 		}
@@ -239,6 +437,31 @@ func (c *TokenCreateCommand) Run(ctx context.Context, args []string) error {
 		return nil
 	}
 
+	var store tokenstore.Store
+	var cacheKey string
+	if c.flagCache {
+		var err error
+		store, err = c.tokenStore()
+		if err != nil {
+			return err
+		}
+		cacheKey = c.cacheKey(justs)
+
+		if tok, ok := c.cachedToken(ctx, store, cacheKey); ok {
+			if c.flagMetadataFile != "" {
+				m, err := cachedTokenMetadata(tok)
+				if err != nil {
+					return err
+				}
+				if err := writeTokenMetadata(c.flagMetadataFile, m); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintln(c.Stdout(), tok)
+			return nil
+		}
+	}
+
 	dialOpts, err := dialOptions(c.flagInsecure)
 	if err != nil {
 		return err
@@ -250,28 +473,304 @@ func (c *TokenCreateCommand) Run(ctx context.Context, args []string) error {
 	}
 	jvsclient := jvspb.NewJVSServiceClient(conn)
 
-	callOpts, err := callOptions(ctx, c.flagAuthToken)
+	ts, err := c.authTokenSource(ctx)
 	if err != nil {
 		return err
 	}
+	callOpts := callOptions(ts)
 
 	req := &jvspb.CreateJustificationRequest{
-		Subject: c.flagSubject,
-		Justifications: []*jvspb.Justification{{
-			Category: c.flagCategory,
-			Value:    c.flagJustificationText,
-		}},
-		Ttl: durationpb.New(c.flagTTL),
-	}
-	resp, err := jvsclient.CreateJustification(ctx, req, callOpts...)
-	if err != nil {
-		return fmt.Errorf("failed to create justification: %w", err)
+		Subject:          c.flagSubject,
+		Justifications:   justs,
+		Ttl:              durationpb.New(c.flagTTL),
+		JwkThumbprint:    c.flagJWKThumbprint,
+		AudienceServices: c.flagAudienceServices,
+	}
+	if c.flagStartTime != "" {
+		start, err := time.Parse(time.RFC3339, c.flagStartTime)
+		if err != nil {
+			return fmt.Errorf("failed to parse -start-time: %w", err)
+		}
+		req.StartTime = timestamppb.New(start)
+	}
+
+	var resp *jvspb.CreateJustificationResponse
+	if c.flagGitHubOIDC {
+		subjectToken, err := githubOIDCToken(ctx,
+			c.GetEnv(githubOIDCRequestURLEnv), c.GetEnv(githubOIDCRequestTokenEnv), c.flagGitHubOIDCAud)
+		if err != nil {
+			return fmt.Errorf("failed to get github oidc token: %w", err)
+		}
+
+		resp, err = jvsclient.ExchangeToken(ctx, &jvspb.ExchangeTokenRequest{
+			SubjectToken: subjectToken,
+			Request:      req,
+		}, callOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to exchange github oidc token: %w", err)
+		}
+	} else {
+		resp, err = jvsclient.CreateJustification(ctx, req, callOpts...)
+		if err != nil {
+			return fmt.Errorf("failed to create justification: %w", err)
+		}
+	}
+
+	if store != nil {
+		if err := store.Set(ctx, cacheKey, []byte(resp.GetToken())); err != nil {
+			c.Errf("WARNING: failed to cache token: %s", err)
+		}
+	}
+
+	if c.flagReceiptFile != "" {
+		if receipt := resp.GetReceipt(); receipt == "" {
+			c.Errf("WARNING: the JVS did not return a receipt")
+		} else if err := os.WriteFile(c.flagReceiptFile, []byte(receipt+"\n"), 0o600); err != nil {
+			return fmt.Errorf("failed to write receipt: %w", err)
+		}
+	}
+
+	if c.flagMetadataFile != "" {
+		if err := writeTokenMetadata(c.flagMetadataFile, &tokenMetadata{
+			ExpiresAt: resp.GetExpiresAt().AsTime(),
+			JTI:       resp.GetJti(),
+			KeyID:     resp.GetKid(),
+			Issuer:    resp.GetIssuer(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Warnings go to stderr, so scripts can still capture the token from
+	// stdout.
+	for _, w := range resp.GetWarnings() {
+		c.Errf("WARNING: %s: %s", w.GetCategory(), w.GetMessage())
 	}
 
 	fmt.Fprintln(c.Stdout(), resp.GetToken())
 	return nil
 }
 
+// justifications returns the justifications of the -justification and
+// -justification-file flags, with the categories of the -category flags.
+func (c *TokenCreateCommand) justifications() ([]*jvspb.Justification, error) {
+	values := slices.Clone(c.flagJustifications)
+	if c.flagJustificationFile != "" {
+		v, err := c.readJustificationFile()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("justification is required")
+	}
+
+	categories := c.flagCategories
+	if len(categories) != 1 && len(categories) != len(values) {
+		return nil, fmt.Errorf("got %d categories for %d justifications, give one "+
+			"-category for all of them or one per justification", len(categories), len(values))
+	}
+
+	justs := make([]*jvspb.Justification, 0, len(values))
+	var size int
+	for i, v := range values {
+		category := categories[0]
+		if len(categories) > 1 {
+			category = categories[i]
+		}
+		size += len(category) + len(v)
+		justs = append(justs, &jvspb.Justification{
+			Category: category,
+			Value:    v,
+		})
+	}
+
+	// Fail fast with the same limit the server enforces.
+	if got, maximum := size, justification.MaxJustificationsSize; got > maximum {
+		return nil, fmt.Errorf("justification size (%d bytes) must be less than %d bytes", got, maximum)
+	}
+	return justs, nil
+}
+
+// readJustificationFile reads the justification file, or stdin if it is "-".
+// At most one byte over the size limit is read, so the limit is still
+// enforced without reading huge inputs.
+func (c *TokenCreateCommand) readJustificationFile() (string, error) {
+	var r io.Reader
+	if c.flagJustificationFile == "-" {
+		r = c.Stdin()
+	} else {
+		f, err := os.Open(c.flagJustificationFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to open justification file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r, justification.MaxJustificationsSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read justification file: %w", err)
+	}
+
+	v := strings.TrimRight(string(b), "\r\n")
+	if strings.TrimSpace(v) == "" {
+		return "", fmt.Errorf("justification file is empty")
+	}
+	return v, nil
+}
+
+// repeatableStringVar is like [cli.FlagSection.StringSliceVar], but each
+// occurrence of the flag is one value, which is not split on commas, since
+// justifications may have commas.
+func repeatableStringVar(f *cli.FlagSection, i *cli.StringSliceVar) {
+	// The first call sets the default or environment value, which the first
+	// flag replaces.
+	var calls int
+	cli.Flag(f, &cli.Var[[]string]{
+		Name:    i.Name,
+		Aliases: i.Aliases,
+		Usage:   i.Usage,
+		Example: i.Example,
+		Default: i.Default,
+		Hidden:  i.Hidden,
+		EnvVar:  i.EnvVar,
+		Target:  i.Target,
+		Parser: func(s string) ([]string, error) {
+			return []string{s}, nil
+		},
+		Printer: func(v []string) string {
+			return strings.Join(v, ",")
+		},
+		Setter: func(cur *[]string, val []string) {
+			calls++
+			if calls <= 2 {
+				*cur = slices.Clone(val)
+				return
+			}
+			*cur = append(*cur, val...)
+		},
+	})
+}
+
+// cacheMinRemaining is the minimum remaining lifetime of a cached token for it
+// to be reused.
+const cacheMinRemaining = time.Minute
+
+// tokenStore returns the store for cached tokens.
+func (c *TokenCreateCommand) tokenStore() (tokenstore.Store, error) {
+	dir := c.flagCacheDir
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user cache directory, set -cache-dir: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "jvsctl", "tokens")
+	}
+
+	store, err := tokenstore.New(c.flagCacheStorage, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token cache: %w", err)
+	}
+	return store, nil
+}
+
+// cacheKey returns the cache key for the request described by the flags. Any
+// flag that changes the resulting token must be part of the key.
+func (c *TokenCreateCommand) cacheKey(justs []*jvspb.Justification) string {
+	values := []string{
+		c.flagServer,
+		strings.Join(c.flagAudiences, ","),
+		strings.Join(c.flagAudienceServices, ","),
+	}
+	for _, j := range justs {
+		values = append(values, j.GetCategory(), j.GetValue())
+	}
+	values = append(values,
+		c.flagJWKThumbprint,
+		c.flagSubject,
+		c.flagTTL.String(),
+		c.flagStartTime,
+		strconv.FormatBool(c.flagGitHubOIDC),
+		c.flagImpersonateSA,
+	)
+
+	h := sha256.New()
+	for _, v := range values {
+		// Length-prefix each value so different values can't collide.
+		fmt.Fprintf(h, "%d:%s;", len(v), v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedToken returns the cached token for the key if it exists and is still
+// valid for at least [cacheMinRemaining]. The token was verified by the server
+// when it was issued, so this only inspects its lifetime.
+func (c *TokenCreateCommand) cachedToken(ctx context.Context, store tokenstore.Store, key string) (string, bool) {
+	b, err := store.Get(ctx, key)
+	if err != nil {
+		if !errors.Is(err, tokenstore.ErrNotFound) {
+			c.Errf("WARNING: failed to read cached token: %s", err)
+		}
+		return "", false
+	}
+
+	token, err := jwt.ParseInsecure(b)
+	if err != nil {
+		return "", false
+	}
+
+	now := time.Unix(c.flagNowUnix, 0).UTC()
+	if token.Expiration().Before(now.Add(cacheMinRemaining)) {
+		return "", false
+	}
+	return string(b), true
+}
+
+// tokenMetadata is the metadata of a token written to the -metadata-file.
+type tokenMetadata struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	JTI       string    `json:"jti"`
+	KeyID     string    `json:"kid"`
+	Issuer    string    `json:"issuer"`
+}
+
+// cachedTokenMetadata returns the metadata of a cached token. Unlike new
+// tokens, it has to be read from the token itself.
+func cachedTokenMetadata(tok string) (*tokenMetadata, error) {
+	msg, err := jws.Parse([]byte(tok))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cached token: %w", err)
+	}
+	token, err := jwt.ParseInsecure([]byte(tok))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cached token: %w", err)
+	}
+
+	var kid string
+	if sigs := msg.Signatures(); len(sigs) > 0 {
+		kid = sigs[0].ProtectedHeaders().KeyID()
+	}
+	return &tokenMetadata{
+		ExpiresAt: token.Expiration().UTC(),
+		JTI:       token.JwtID(),
+		KeyID:     kid,
+		Issuer:    token.Issuer(),
+	}, nil
+}
+
+func writeTokenMetadata(path string, m *tokenMetadata) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token metadata: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write token metadata: %w", err)
+	}
+	return nil
+}
+
 func dialOptions(insecure bool) ([]grpc.DialOption, error) {
 	if insecure {
 		return []grpc.DialOption{
@@ -293,27 +792,69 @@ func dialOptions(insecure bool) ([]grpc.DialOption, error) {
 	}, nil
 }
 
-func callOptions(ctx context.Context, authToken string) ([]grpc.CallOption, error) {
-	if authToken == "" {
+// authTokenSource returns the token source for authenticating to the JVS
+// server. An explicit -auth-token always wins. Otherwise, an ID token is minted
+// from Application Default Credentials, except for insecure connections (which
+// are typically local servers) and GitHub OIDC token exchange (which must not
+// require any credentials). It returns nil if no authentication should be used.
+func (c *TokenCreateCommand) authTokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if c.flagAuthToken != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{
+			AccessToken: c.flagAuthToken,
+		}), nil
+	}
+
+	if c.flagImpersonateSA == "" && (c.flagInsecure || c.flagGitHubOIDC) {
 		return nil, nil
 	}
 
-	token := &oauth2.Token{
-		AccessToken: authToken,
+	audience := c.flagAuthAudience
+	if audience == "" {
+		audience = audienceFromServer(c.flagServer)
+	}
+
+	ts, err := idtoken.ForAudience(ctx, audience, c.flagImpersonateSA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mint id token from application default credentials "+
+			"(use -auth-token to provide one explicitly): %w", err)
+	}
+	return ts, nil
+}
+
+// audienceFromServer converts a server address like "jvs.example.com:443" into
+// the URL that identity-aware platforms like Cloud Run expect as the ID token
+// audience, e.g. "https://jvs.example.com".
+func audienceFromServer(server string) string {
+	if strings.Contains(server, "://") {
+		if u, err := url.Parse(server); err == nil {
+			return u.Scheme + "://" + u.Hostname()
+		}
+	}
+
+	host := server
+	if h, _, err := net.SplitHostPort(server); err == nil {
+		host = h
+	}
+	return "https://" + host
+}
+
+func callOptions(ts oauth2.TokenSource) []grpc.CallOption {
+	if ts == nil {
+		return nil
 	}
 
 	rpcCreds := oauth.TokenSource{
-		TokenSource: oauth2.StaticTokenSource(token),
+		TokenSource: ts,
 	}
 
 	return []grpc.CallOption{
 		grpc.PerRPCCredentials(rpcCreds),
-	}, nil
+	}
 }
 
 // breakglassToken creates a new breakglass token from the CLI flags. See
 // [jvspb.CreateBreakglassToken] for more information.
-func (c *TokenCreateCommand) breakglassToken(ctx context.Context) (string, error) {
+func (c *TokenCreateCommand) breakglassToken(ctx context.Context, justification string) (string, error) {
 	now := time.Unix(c.flagNowUnix, 0)
 	id := uuid.New().String()
 	exp := now.Add(c.flagTTL)
@@ -331,7 +872,16 @@ func (c *TokenCreateCommand) breakglassToken(ctx context.Context) (string, error
 		return "", fmt.Errorf("failed to build breakglass token: %w", err)
 	}
 
-	str, err := jvspb.CreateBreakglassToken(token, c.flagJustificationText)
+	if c.flagJWKThumbprint != "" {
+		if err := jvspb.ValidateJWKThumbprint(c.flagJWKThumbprint); err != nil {
+			return "", fmt.Errorf("invalid jwk thumbprint: %w", err)
+		}
+		if err := jvspb.SetConfirmation(token, c.flagJWKThumbprint); err != nil {
+			return "", fmt.Errorf("failed to bind breakglass token: %w", err)
+		}
+	}
+
+	str, err := jvspb.CreateBreakglassToken(token, justification)
 	if err != nil {
 		return "", fmt.Errorf("failed to create breakglass token: %w", err)
 	}
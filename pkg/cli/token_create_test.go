@@ -16,21 +16,31 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/testutil"
 )
@@ -49,12 +59,30 @@ func TestTokenCreateCommand(t *testing.T) {
 		jvspb.RegisterJVSServiceServer(s, &fakeJVS{returnErr: fmt.Errorf("testing server error")})
 	})
 
+	githubOIDC := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer gh-request-token"; got != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"value":"gh-oidc-token-for-%s"}`, r.URL.Query().Get("audience"))
+	}))
+	t.Cleanup(githubOIDC.Close)
+
+	justificationFile := filepath.Join(t.TempDir(), "justification.txt")
+	if err := os.WriteFile(justificationFile, []byte("rolling back, see the incident doc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
 	cases := []struct {
 		name              string
 		args              []string
+		env               map[string]string
+		stdin             string
 		expSubject        string
 		expAudiences      []string
 		expJustifications []*jvspb.Justification
+		expJKT            string
+		expNotBefore      time.Time
 		expErr            string
 	}{
 		{
@@ -118,6 +146,127 @@ func TestTokenCreateCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "multiple_justifications",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-justification", "rolling back, then rolling forward",
+				"-server", goodJVS,
+			},
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "for testing purposes",
+				},
+				{
+					Category: "explanation",
+					Value:    "rolling back, then rolling forward",
+				},
+			},
+		},
+		{
+			name: "multiple_categories",
+			args: []string{
+				"-category", "jira",
+				"-justification", "JIRACOMPONENT/123",
+				"-category", "explanation",
+				"-justification", "for testing purposes",
+				"-server", goodJVS,
+			},
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "jira",
+					Value:    "JIRACOMPONENT/123",
+				},
+				{
+					Category: "explanation",
+					Value:    "for testing purposes",
+				},
+			},
+		},
+		{
+			name: "mismatched_categories",
+			args: []string{
+				"-category", "jira",
+				"-category", "explanation",
+				"-justification", "JIRACOMPONENT/123",
+				"-justification", "for testing purposes",
+				"-justification", "and another",
+				"-server", goodJVS,
+			},
+			expErr: "got 2 categories for 3 justifications",
+		},
+		{
+			name: "justification_file",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-justification-file", justificationFile,
+				"-server", goodJVS,
+			},
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "for testing purposes",
+				},
+				{
+					Category: "explanation",
+					Value:    "rolling back, see the incident doc",
+				},
+			},
+		},
+		{
+			name: "justification_stdin",
+			args: []string{
+				"-justification-file", "-",
+				"-server", goodJVS,
+			},
+			stdin:        "line one\nline two\n",
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "line one\nline two",
+				},
+			},
+		},
+		{
+			name: "justification_stdin_empty",
+			args: []string{
+				"-justification-file", "-",
+				"-server", goodJVS,
+			},
+			stdin:  "\n",
+			expErr: "justification file is empty",
+		},
+		{
+			name: "justification_file_missing",
+			args: []string{
+				"-justification-file", filepath.Join(t.TempDir(), "missing.txt"),
+				"-server", goodJVS,
+			},
+			expErr: "failed to open justification file",
+		},
+		{
+			name: "justification_too_large",
+			args: []string{
+				"-justification-file", "-",
+				"-server", goodJVS,
+			},
+			stdin:  strings.Repeat("a", justification.MaxJustificationsSize),
+			expErr: "justification size (4011 bytes) must be less than 4000 bytes",
+		},
+		{
+			name: "multiple_justifications_breakglass",
+			args: []string{
+				"-justification", "prod is down",
+				"-justification", "really",
+				"-breakglass",
+			},
+			expErr: "multiple justifications are not supported with -breakglass",
+		},
 		{
 			name: "breakglass",
 			args: []string{
@@ -148,6 +297,99 @@ func TestTokenCreateCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "bound_to_key",
+			args: []string{
+				"-justification=prod is down",
+				"-jwk-thumbprint=NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+				"-server", goodJVS,
+			},
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "prod is down",
+				},
+			},
+			expJKT: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			name: "breakglass_bound_to_key",
+			args: []string{
+				"-justification=prod is down",
+				"-jwk-thumbprint=NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+				"-breakglass",
+			},
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "breakglass",
+					Value:    "prod is down",
+				},
+			},
+			expJKT: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			name: "start_time",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-start-time", "1970-01-01T02:00:00Z",
+				"-server", goodJVS,
+			},
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "for testing purposes",
+				},
+			},
+			expNotBefore: now.Add(2 * time.Hour),
+		},
+		{
+			name: "invalid_start_time",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-start-time", "tomorrow",
+				"-server", goodJVS,
+			},
+			expErr: "failed to parse -start-time",
+		},
+		{
+			name: "start_time_breakglass",
+			args: []string{
+				"-justification", "prod is down",
+				"-breakglass",
+				"-start-time", "1970-01-01T02:00:00Z",
+			},
+			expErr: "-start-time is not supported with -breakglass",
+		},
+		{
+			name: "audience_services",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-audience-service", "payments,billing",
+				"-server", goodJVS,
+			},
+			expAudiences: []string{
+				"https://payments.internal.example.com",
+				"https://billing.internal.example.com",
+			},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "for testing purposes",
+				},
+			},
+		},
+		{
+			name: "audience_services_breakglass",
+			args: []string{
+				"-justification", "prod is down",
+				"-breakglass",
+				"-audience-service", "payments",
+			},
+			expErr: "-audience-service is not supported with -breakglass",
+		},
 		{
 			name: "custom_audiences",
 			args: []string{
@@ -164,6 +406,49 @@ func TestTokenCreateCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "github_oidc",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-github-oidc",
+				"-github-oidc-audience", "jvs",
+				"-server", goodJVS,
+			},
+			env: map[string]string{
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   githubOIDC.URL,
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "gh-request-token",
+			},
+			expSubject:   "gh-oidc-token-for-jvs",
+			expAudiences: []string{justification.DefaultAudience},
+			expJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "for testing purposes",
+				},
+			},
+		},
+		{
+			name: "github_oidc_missing_env",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-github-oidc",
+				"-server", goodJVS,
+			},
+			expErr: "missing ACTIONS_ID_TOKEN_REQUEST_URL or ACTIONS_ID_TOKEN_REQUEST_TOKEN",
+		},
+		{
+			name: "github_oidc_bad_request_token",
+			args: []string{
+				"-justification", "for testing purposes",
+				"-github-oidc",
+				"-server", goodJVS,
+			},
+			env: map[string]string{
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   githubOIDC.URL,
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "wrong",
+			},
+			expErr: "unexpected status 401",
+		},
 	}
 
 	for _, tc := range cases {
@@ -171,7 +456,9 @@ func TestTokenCreateCommand(t *testing.T) {
 			t.Parallel()
 
 			var cmd TokenCreateCommand
-			_, stdout, _ := cmd.Pipe()
+			cmd.SetLookupEnv(cli.MapLookuper(tc.env))
+			stdin, stdout, _ := cmd.Pipe()
+			stdin.WriteString(tc.stdin)
 
 			args := append([]string{
 				// Always append insecure for tests.
@@ -216,6 +503,9 @@ func TestTokenCreateCommand(t *testing.T) {
 			if got, want := token.Subject(), tc.expSubject; got != want {
 				t.Errorf("sub: expected %q to be %q", got, want)
 			}
+			if want := tc.expNotBefore; !want.IsZero() && !token.NotBefore().Equal(want) {
+				t.Errorf("nbf: expected %q to be %q", token.NotBefore(), want)
+			}
 
 			// Validate custom claims.
 			justifications, err := jvspb.GetJustifications(token)
@@ -225,6 +515,13 @@ func TestTokenCreateCommand(t *testing.T) {
 			if diff := cmp.Diff(tc.expJustifications, justifications, cmpopts.IgnoreUnexported(jvspb.Justification{})); diff != "" {
 				t.Errorf("justs: diff (-want, +got):\n%s", diff)
 			}
+			jkt, err := jvspb.GetConfirmation(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := jkt, tc.expJKT; got != want {
+				t.Errorf("cnf: expected %q to be %q", got, want)
+			}
 		})
 	}
 }
@@ -232,12 +529,256 @@ func TestTokenCreateCommand(t *testing.T) {
 type fakeJVS struct {
 	jvspb.UnimplementedJVSServiceServer
 	returnErr error
+
+	// receipt is returned with the tokens, if set.
+	receipt string
+
+	// warnings are returned with the tokens.
+	warnings []*jvspb.ValidationWarning
+
+	// calls is the number of tokens created.
+	calls atomic.Int64
+}
+
+func TestTokenCreateCommand_Cache(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	jvs := &fakeJVS{}
+	addr, _ := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		jvspb.RegisterJVSServiceServer(s, jvs)
+	})
+	cacheDir := t.TempDir()
+
+	run := func(tb testing.TB, now int64, justification string) string {
+		tb.Helper()
+
+		var cmd TokenCreateCommand
+		_, stdout, _ := cmd.Pipe()
+
+		if err := cmd.Run(ctx, []string{
+			"-insecure",
+			"-server", addr,
+			"-now", strconv.FormatInt(now, 10),
+			"-justification", justification,
+			"-cache",
+			"-cache-storage", "file",
+			"-cache-dir", cacheDir,
+		}); err != nil {
+			tb.Fatal(err)
+		}
+		return strings.TrimSpace(stdout.String())
+	}
+
+	// The fake server issues tokens expiring 5 minutes after the unix epoch.
+	first := run(t, 0, "prod access")
+	if got, want := jvs.calls.Load(), int64(1); got != want {
+		t.Fatalf("expected %d calls to be %d", got, want)
+	}
+
+	if got, want := run(t, 0, "prod access"), first; got != want {
+		t.Errorf("expected cached token %q to be %q", got, want)
+	}
+	if got, want := jvs.calls.Load(), int64(1); got != want {
+		t.Errorf("expected cached request to make %d calls, got %d", want, got)
+	}
+
+	run(t, 0, "other reason")
+	if got, want := jvs.calls.Load(), int64(2); got != want {
+		t.Errorf("expected different request to make %d calls, got %d", want, got)
+	}
+
+	// Less than a minute left on the cached token.
+	run(t, int64((4*time.Minute + 30*time.Second).Seconds()), "prod access")
+	if got, want := jvs.calls.Load(), int64(3); got != want {
+		t.Errorf("expected expiring token to make %d calls, got %d", want, got)
+	}
+}
+
+func TestTokenCreateCommand_ReceiptFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	withReceipt, _ := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		jvspb.RegisterJVSServiceServer(s, &fakeJVS{receipt: "test-receipt"})
+	})
+	withoutReceipt, _ := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		jvspb.RegisterJVSServiceServer(s, &fakeJVS{})
+	})
+
+	cases := []struct {
+		name       string
+		server     string
+		expReceipt string
+		expStderr  string
+	}{
+		{
+			name:       "receipt",
+			server:     withReceipt,
+			expReceipt: "test-receipt\n",
+		},
+		{
+			name:      "no_receipt",
+			server:    withoutReceipt,
+			expStderr: "the JVS did not return a receipt",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "receipt.jws")
+
+			var cmd TokenCreateCommand
+			_, _, stderr := cmd.Pipe()
+
+			if err := cmd.Run(ctx, []string{
+				"-insecure",
+				"-server", tc.server,
+				"-justification", "prod access",
+				"-receipt-file", path,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(stderr.String(), tc.expStderr) {
+				t.Errorf("expected stderr %q to contain %q", stderr.String(), tc.expStderr)
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				t.Fatal(err)
+			}
+			if got, want := string(b), tc.expReceipt; got != want {
+				t.Errorf("expected receipt %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTokenCreateCommand_MetadataFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	jvs := &fakeJVS{}
+	addr, _ := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		jvspb.RegisterJVSServiceServer(s, jvs)
+	})
+	cacheDir := t.TempDir()
+
+	// The fake server issues tokens expiring 5 minutes after the unix epoch.
+	want := fmt.Sprintf(`{"expires_at":"1970-01-01T00:05:00Z","jti":"test-jwt","kid":"test-kid","issuer":%q}`+"\n", Issuer)
+
+	// The second run reads the metadata from the cached token.
+	for _, name := range []string{"new", "cached"} {
+		path := filepath.Join(t.TempDir(), "token.json")
+
+		var cmd TokenCreateCommand
+		_, _, _ = cmd.Pipe()
+
+		if err := cmd.Run(ctx, []string{
+			"-insecure",
+			"-server", addr,
+			"-now", "0",
+			"-justification", "prod access",
+			"-cache",
+			"-cache-storage", "file",
+			"-cache-dir", cacheDir,
+			"-metadata-file", path,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != want {
+			t.Errorf("%s: expected metadata %s to be %s", name, got, want)
+		}
+	}
+	if got, want := jvs.calls.Load(), int64(1); got != want {
+		t.Errorf("expected %d calls to be %d", got, want)
+	}
+}
+
+func TestTokenCreateCommand_Warnings(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	addr, _ := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		jvspb.RegisterJVSServiceServer(s, &fakeJVS{
+			warnings: []*jvspb.ValidationWarning{
+				{Category: "jira", Message: "ticket closes in 20 minutes"},
+			},
+		})
+	})
+
+	var cmd TokenCreateCommand
+	_, stdout, stderr := cmd.Pipe()
+
+	if err := cmd.Run(ctx, []string{
+		"-insecure",
+		"-server", addr,
+		"-justification", "prod access",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stderr.String(), "WARNING: jira: ticket closes in 20 minutes"; !strings.Contains(got, want) {
+		t.Errorf("expected stderr %q to contain %q", got, want)
+	}
+	if got := strings.TrimSpace(stdout.String()); strings.Contains(got, "WARNING") || got == "" {
+		t.Errorf("expected stdout %q to be only the token", got)
+	}
+}
+
+func TestAudienceFromServer(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		server string
+		exp    string
+	}{
+		{
+			name:   "host_port",
+			server: "jvs.example.com:443",
+			exp:    "https://jvs.example.com",
+		},
+		{
+			name:   "host_only",
+			server: "jvs.example.com",
+			exp:    "https://jvs.example.com",
+		},
+		{
+			name:   "url",
+			server: "https://jvs.example.com:8443/foo",
+			exp:    "https://jvs.example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := audienceFromServer(tc.server), tc.exp; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
 }
 
 func (j *fakeJVS) CreateJustification(ctx context.Context, req *jvspb.CreateJustificationRequest) (*jvspb.CreateJustificationResponse, error) {
 	if j.returnErr != nil {
 		return nil, j.returnErr
 	}
+	j.calls.Add(1)
 
 	now := time.Unix(0, 0).UTC()
 	token, err := jwt.NewBuilder().
@@ -252,17 +793,54 @@ func (j *fakeJVS) CreateJustification(ctx context.Context, req *jvspb.CreateJust
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token: %w", err)
 	}
+	if services := req.GetAudienceServices(); len(services) > 0 {
+		aud := make([]string, 0, len(services))
+		for _, s := range services {
+			aud = append(aud, "https://"+s+".internal.example.com")
+		}
+		if err := token.Set(jwt.AudienceKey, aud); err != nil {
+			return nil, fmt.Errorf("failed to set aud: %w", err)
+		}
+	}
+	if start := req.GetStartTime(); start != nil {
+		if err := token.Set(jwt.NotBeforeKey, start.AsTime()); err != nil {
+			return nil, fmt.Errorf("failed to set nbf: %w", err)
+		}
+	}
 
 	if err := jvspb.SetJustifications(token, req.GetJustifications()); err != nil {
 		return nil, fmt.Errorf("failed to set justifications: %w", err)
 	}
+	if thumbprint := req.GetJwkThumbprint(); thumbprint != "" {
+		if err := jvspb.SetConfirmation(token, thumbprint); err != nil {
+			return nil, fmt.Errorf("failed to set confirmation: %w", err)
+		}
+	}
 
-	b, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("testing")))
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, "test-kid"); err != nil {
+		return nil, fmt.Errorf("failed to set kid: %w", err)
+	}
+	b, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("testing"), jws.WithProtectedHeaders(headers)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
 
 	return &jvspb.CreateJustificationResponse{
-		Token: string(b),
+		Token:     string(b),
+		Receipt:   j.receipt,
+		Warnings:  j.warnings,
+		ExpiresAt: timestamppb.New(token.Expiration()),
+		Jti:       token.JwtID(),
+		Kid:       "test-kid",
+		Issuer:    token.Issuer(),
 	}, nil
 }
+
+// ExchangeToken mints a token like CreateJustification, but uses the subject
+// token as the subject so tests can assert it was forwarded.
+func (j *fakeJVS) ExchangeToken(ctx context.Context, req *jvspb.ExchangeTokenRequest) (*jvspb.CreateJustificationResponse, error) {
+	r := req.GetRequest()
+	r.Subject = req.GetSubjectToken()
+	return j.CreateJustification(ctx, r)
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/cli"
+)
+
+var _ cli.Command = (*TokenReceiptCommand)(nil)
+
+type TokenReceiptCommand struct {
+	cli.BaseCommand
+
+	flagJTI     string
+	flagProject string
+	flagSince   time.Duration
+	flagFormat  string
+
+	flagAuthToken       string
+	flagLoggingEndpoint string
+}
+
+func (c *TokenReceiptCommand) Desc() string {
+	return `Fetch the receipt of an issued token`
+}
+
+func (c *TokenReceiptCommand) Help() string {
+	return `
+Usage: {{ COMMAND }} [options]
+
+  Fetch the signed receipt of a justification token from the audit log in
+  Cloud Logging, e.g. to attach it to a change ticket. Receipts are only
+  recorded if the JVS issues them. Requires permission to read logs in the
+  project the JVS runs in, e.g. "roles/logging.viewer".
+
+  Fetch the receipt of a token:
+
+      jvsctl token receipt \
+        -project "my-project" \
+        -jti "2c9a8f4e-5d2b-4b7e-9a1f-0e6f3c1d2b3a"
+
+  Show the contents of the receipt as JSON:
+
+      jvsctl token receipt \
+        -project "my-project" \
+        -jti "2c9a8f4e-5d2b-4b7e-9a1f-0e6f3c1d2b3a" \
+        -format "json"
+`
+}
+
+func (c *TokenReceiptCommand) Flags() *cli.FlagSet {
+	set := c.NewFlagSet()
+
+	// Command options
+	f := set.NewSection("COMMAND OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "jti",
+		Target:  &c.flagJTI,
+		Example: "2c9a8f4e-5d2b-4b7e-9a1f-0e6f3c1d2b3a",
+		Usage:   `The ID of the token, its "jti" claim.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "project",
+		Target:  &c.flagProject,
+		Example: "my-project",
+		EnvVar:  "JVSCTL_AUDIT_PROJECT",
+		Usage:   `The Google Cloud project the JVS writes its logs to.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "since",
+		Target:  &c.flagSince,
+		Example: "168h",
+		Default: 30 * 24 * time.Hour,
+		Usage:   `How far back to search for the token.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "format",
+		Aliases: []string{"f"},
+		Target:  &c.flagFormat,
+		Example: "json",
+		Default: "jws",
+		Usage: `The target output format. Valid values are: jws, the signed ` +
+			`receipt, and json, its contents.`,
+	})
+
+	// Server flags
+	f = set.NewSection("SERVER OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "auth-token",
+		Target:  &c.flagAuthToken,
+		Example: "ya29.c...",
+		EnvVar:  "JVSCTL_AUDIT_AUTH_TOKEN",
+		Usage: `An OAuth access token to read logs with. If unset, Application ` +
+			`Default Credentials are used.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "logging-endpoint",
+		Target:  &c.flagLoggingEndpoint,
+		Default: audit.DefaultCloudLoggingEndpoint,
+		Hidden:  true,
+		Usage:   `The Cloud Logging API endpoint.`,
+	})
+
+	return set
+}
+
+func (c *TokenReceiptCommand) Run(ctx context.Context, args []string) error {
+	f := c.Flags()
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("failed to parse flags: %w", err)
+	}
+	args = f.Args()
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %q", args)
+	}
+
+	if c.flagJTI == "" {
+		return fmt.Errorf("jti is required")
+	}
+	if c.flagProject == "" {
+		return fmt.Errorf("project is required")
+	}
+
+	format := strings.TrimSpace(strings.ToLower(c.flagFormat))
+	switch format {
+	case "", "jws", "json":
+	default:
+		return fmt.Errorf("unknown formatter %q", format)
+	}
+
+	client, err := auditHTTPClient(ctx, c.flagAuthToken, loggingReadScope)
+	if err != nil {
+		return err
+	}
+
+	q := &audit.Query{
+		ID:    c.flagJTI,
+		Limit: 1,
+	}
+	if c.flagSince > 0 {
+		q.Start = time.Now().Add(-c.flagSince)
+	}
+
+	searcher := audit.NewCloudLoggingSearcher(client, c.flagLoggingEndpoint, c.flagProject)
+	entries, err := searcher.Search(ctx, q)
+	if err != nil {
+		return fmt.Errorf("failed to search audit log: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no audit entry found for token %q", c.flagJTI)
+	}
+	receipt := entries[0].Receipt
+	if receipt == "" {
+		return fmt.Errorf("token %q was issued without a receipt", c.flagJTI)
+	}
+
+	if format != "json" {
+		fmt.Fprintln(c.Stdout(), receipt)
+		return nil
+	}
+
+	// The receipt comes from the audit log, so it isn't verified here. Use
+	// [jvspb.VerifyReceipt] to verify receipts from untrusted sources.
+	msg, err := jws.Parse([]byte(receipt))
+	if err != nil {
+		return fmt.Errorf("failed to parse receipt: %w", err)
+	}
+	var out jvspb.Receipt
+	if err := json.Unmarshal(msg.Payload(), &out); err != nil {
+		return fmt.Errorf("failed to parse receipt payload: %w", err)
+	}
+	if err := json.NewEncoder(c.Stdout()).Encode(&out); err != nil {
+		return fmt.Errorf("failed to encode to json: %w", err)
+	}
+	return nil
+}
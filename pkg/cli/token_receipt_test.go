@@ -0,0 +1,142 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestTokenReceiptCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := `{"jti":"test-jti","requestor":"you@example.com","justifications":[{"category":"approver","annotation":{"approver":"boss@example.com"}}],"approver":"boss@example.com","issued_at":"2023-06-01T00:00:00Z","expires_at":"2023-06-01T00:15:00Z"}`
+	receipt, err := jws.Sign([]byte(payload), jws.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("Authorization"), "Bearer test-token"; got != want {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Filter string `json:"filter"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case strings.Contains(req.Filter, `jti="test-jti"`):
+			fmt.Fprintf(w, `{"entries":[{"jsonPayload":{"jvs_audit":{"jti":"test-jti","receipt":%q}}}]}`, receipt)
+		case strings.Contains(req.Filter, `jti="no-receipt"`):
+			_, _ = w.Write([]byte(`{"entries":[{"jsonPayload":{"jvs_audit":{"jti":"no-receipt"}}}]}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name      string
+		args      []string
+		expOutput string
+		expErr    string
+	}{
+		{
+			name:   "too_many_args",
+			args:   []string{"foo"},
+			expErr: `unexpected arguments: ["foo"]`,
+		},
+		{
+			name:   "missing_jti",
+			args:   []string{"-project", "my-project"},
+			expErr: "jti is required",
+		},
+		{
+			name:   "missing_project",
+			args:   []string{"-jti", "test-jti"},
+			expErr: "project is required",
+		},
+		{
+			name:   "invalid_format",
+			args:   []string{"-project", "my-project", "-jti", "test-jti", "-format", "xml"},
+			expErr: `unknown formatter "xml"`,
+		},
+		{
+			name:      "jws",
+			args:      []string{"-project", "my-project", "-auth-token", "test-token", "-jti", "test-jti"},
+			expOutput: string(receipt),
+		},
+		{
+			name:      "json",
+			args:      []string{"-project", "my-project", "-auth-token", "test-token", "-jti", "test-jti", "-format", "json"},
+			expOutput: payload,
+		},
+		{
+			name:   "not_found",
+			args:   []string{"-project", "my-project", "-auth-token", "test-token", "-jti", "unknown"},
+			expErr: `no audit entry found for token "unknown"`,
+		},
+		{
+			name:   "no_receipt",
+			args:   []string{"-project", "my-project", "-auth-token", "test-token", "-jti", "no-receipt"},
+			expErr: `token "no-receipt" was issued without a receipt`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cmd TokenReceiptCommand
+			_, stdout, _ := cmd.Pipe()
+
+			args := append([]string{"-logging-endpoint", srv.URL}, tc.args...)
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if got, want := strings.TrimSpace(stdout.String()), strings.TrimSpace(tc.expOutput); got != want {
+				t.Errorf("expected\n\n%s\n\nto be\n\n%s", got, want)
+			}
+		})
+	}
+}
@@ -16,10 +16,15 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
 	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/pkg/formatter"
 	"github.com/abcxyz/pkg/cli"
@@ -28,15 +33,57 @@ import (
 // cacheTimeout is required for creating jvs client via jvs config, it is not really used since cache is expired when CLI exits.
 const cacheTimeout = 5 * time.Minute
 
+// Exit codes of the token validate command, so scripts can tell why a token
+// was rejected. Other errors, e.g. failing to fetch the public keys, exit with
+// code 1.
+const (
+	// ExitCodeInvalidToken is the exit code when the token is malformed or its
+	// signature cannot be verified.
+	ExitCodeInvalidToken = 2
+
+	// ExitCodeExpiredToken is the exit code when the token is expired or not
+	// valid yet.
+	ExitCodeExpiredToken = 3
+
+	// ExitCodePolicyViolation is the exit code when the token is valid, but
+	// does not satisfy the required audiences, categories, subject or max age.
+	ExitCodePolicyViolation = 4
+)
+
+// Results in the JSON verdict of the token validate command.
+const (
+	verdictValid           = "valid"
+	verdictInvalidToken    = "invalid_token"
+	verdictExpiredToken    = "expired_token"
+	verdictPolicyViolation = "policy_violation"
+)
+
 var _ cli.Command = (*TokenValidateCommand)(nil)
 
 type TokenValidateCommand struct {
 	cli.BaseCommand
 
-	flagToken        string
-	flagSubject      string
-	flagJWKSEndpoint string
-	flagFormat       string
+	flagToken              string
+	flagSubject            string
+	flagIssuers            []string
+	flagAudiences          []string
+	flagCategories         []string
+	flagMaxAge             time.Duration
+	flagVerdict            bool
+	flagJWKSEndpoint       string
+	flagJWKSRootKeys       []string
+	flagRevocationEndpoint string
+	flagTokenTypes         []string
+	flagClaimsNamespace    string
+	flagFormat             string
+}
+
+// tokenVerdict is the JSON verdict of the token validate command.
+type tokenVerdict struct {
+	Valid    bool     `json:"valid"`
+	Result   string   `json:"result"`
+	ExitCode int      `json:"exit_code"`
+	Errors   []string `json:"errors,omitempty"`
 }
 
 func (c *TokenValidateCommand) Desc() string {
@@ -58,6 +105,19 @@ Usage: {{ COMMAND }} [options]
   Validate the justification token read from pipe:
 
       cat token.txt | jvsctl validate -token -
+
+  Require the token to be for an audience, with a Jira justification, and
+  issued in the last 10 minutes, and print a JSON verdict:
+
+      jvsctl token validate -token "example token string" \
+        -require-audience "example.com" \
+        -require-category "jira" \
+        -max-age 10m \
+        -verdict
+
+  The command exits with code 2 if the token is malformed or its signature is
+  invalid, 3 if it is expired or not valid yet, and 4 if it does not satisfy
+  the required audiences, categories, subject or max age.
 `
 }
 
@@ -76,12 +136,54 @@ func (c *TokenValidateCommand) Flags() *cli.FlagSet {
 	})
 
 	f.StringVar(&cli.StringVar{
-		Name:    "subject",
+		Name:    "require-subject",
+		Aliases: []string{"subject"},
 		Target:  &c.flagSubject,
 		Example: "you@example.com",
 		Usage:   `The subject to validate in the token.`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "require-issuer",
+		Target:  &c.flagIssuers,
+		Example: "jvs.example.com",
+		Usage: `An issuer (iss) the token may have. Can be repeated to accept ` +
+			`multiple issuers. Breakglass tokens have the "jvsctl" issuer.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "require-audience",
+		Target:  &c.flagAudiences,
+		Example: "example.com",
+		Usage: `An audience the token must have, or an audience pattern it ` +
+			`must have an audience matching, e.g. "https://*.example.com" or ` +
+			`"https://example.com/*". Can be repeated to require multiple ` +
+			`audiences.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "require-category",
+		Target:  &c.flagCategories,
+		Example: "jira",
+		Usage: `A justification category the token must have. Can be ` +
+			`repeated to require multiple categories.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "max-age",
+		Target:  &c.flagMaxAge,
+		Example: "10m",
+		Usage:   `The maximum time since the token was issued.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "verdict",
+		Target:  &c.flagVerdict,
+		Default: false,
+		Usage: `Print a JSON verdict with the validation result instead of ` +
+			`the token.`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:    "format",
 		Aliases: []string{"f"},
@@ -104,6 +206,41 @@ func (c *TokenValidateCommand) Flags() *cli.FlagSet {
 			`address, port, and .well-known path.`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "jwks-root-key",
+		Target:  &c.flagJWKSRootKeys,
+		EnvVar:  "JVSCTL_JWKS_ROOT_KEYS",
+		Example: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		Usage: `JWK thumbprint of a root key the JWKS must be signed by, if ` +
+			`the JVS signs its key set. Can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "revocation-endpoint",
+		Target:  &c.flagRevocationEndpoint,
+		Example: "https://jvs.example.com:8080/revocations",
+		EnvVar:  "JVSCTL_REVOCATION_ENDPOINT",
+		Usage: `JVS public key server revocation list endpoint. If set, tokens ` +
+			`of deprovisioned principals are rejected.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "accept-token-type",
+		Target:  &c.flagTokenTypes,
+		EnvVar:  "JVSCTL_ACCEPTED_TOKEN_TYPES",
+		Example: "jvs+jwt",
+		Usage: `A "typ" header of tokens to accept, if the JVS sets a token ` +
+			`type. Can be repeated. Defaults to "JWT".`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "claims-namespace",
+		Target:  &c.flagClaimsNamespace,
+		EnvVar:  "JVSCTL_CLAIMS_NAMESPACE",
+		Example: "https://jvs.example.com/",
+		Usage:   `The claims namespace of the JVS, if it has one.`,
+	})
+
 	return set
 }
 
@@ -121,6 +258,16 @@ func (c *TokenValidateCommand) Run(ctx context.Context, args []string) error {
 		return fmt.Errorf("token is required")
 	}
 
+	if c.flagMaxAge < 0 {
+		return fmt.Errorf("max age cannot be negative, got %s", c.flagMaxAge)
+	}
+
+	for _, pattern := range c.flagAudiences {
+		if err := jvspb.ValidateAudiencePattern(pattern); err != nil {
+			return fmt.Errorf("invalid -require-audience: %w", err)
+		}
+	}
+
 	// Compute the formatter
 	var format formatter.Formatter
 	switch v := strings.TrimSpace(strings.ToLower(c.flagFormat)); v {
@@ -147,28 +294,127 @@ func (c *TokenValidateCommand) Run(ctx context.Context, args []string) error {
 	breakglass := false
 	token, err := jvspb.ParseBreakglassToken(ctx, c.flagToken)
 	if err != nil {
-		return fmt.Errorf("failed to parse breakglass token: %w", err)
+		return c.reject(tokenErrorCode(err), fmt.Errorf("failed to parse breakglass token: %w", err), []string{err.Error()})
 	}
 	if token != nil {
 		breakglass = true
 	} else {
 		jvsclient, err := jvspb.NewClient(ctx, &jvspb.Config{
-			JWKSEndpoint:    c.flagJWKSEndpoint,
-			CacheTimeout:    cacheTimeout,
-			AllowBreakglass: true,
+			JWKSEndpoint:       c.flagJWKSEndpoint,
+			JWKSRootKeys:       c.flagJWKSRootKeys,
+			CacheTimeout:       cacheTimeout,
+			AllowBreakglass:    true,
+			RevocationEndpoint: c.flagRevocationEndpoint,
+			AcceptedTokenTypes: c.flagTokenTypes,
+			ClaimsNamespace:    c.flagClaimsNamespace,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create jvs client: %w", err)
 		}
 
-		token, err = jvsclient.ValidateJWT(ctx, c.flagToken, c.flagSubject)
+		token, err = jvsclient.ValidateJWT(ctx, c.flagToken, "")
 		if err != nil {
-			return fmt.Errorf("failed to validate jwt: %w", err)
+			return c.reject(tokenErrorCode(err), fmt.Errorf("failed to validate jwt: %w", err), []string{err.Error()})
 		}
 	}
 
+	violations, err := c.checkPolicy(token, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return c.reject(ExitCodePolicyViolation, fmt.Errorf("token does not satisfy policy: %s",
+			strings.Join(violations, "; ")), violations)
+	}
+
+	if c.flagVerdict {
+		return c.writeVerdict(&tokenVerdict{Valid: true, Result: verdictValid})
+	}
+
 	if err := format.FormatTo(ctx, c.Stdout(), token, breakglass); err != nil {
 		return fmt.Errorf("failed to format token: %w", err)
 	}
 	return nil
 }
+
+// checkPolicy checks that the verified token satisfies the required subject,
+// audiences, categories and max age. It returns all violations.
+func (c *TokenValidateCommand) checkPolicy(token jwt.Token, now time.Time) ([]string, error) {
+	var violations []string
+
+	if got, want := token.Subject(), c.flagSubject; want != "" && got != want {
+		violations = append(violations, fmt.Sprintf("subject %q does not match expected subject %q", got, want))
+	}
+
+	if got := token.Issuer(); len(c.flagIssuers) > 0 && !slices.Contains(c.flagIssuers, got) {
+		violations = append(violations, fmt.Sprintf("issuer %q is not one of %q", got, c.flagIssuers))
+	}
+
+	for _, want := range c.flagAudiences {
+		if !jvspb.MatchAudiences([]string{want}, token.Audience()) {
+			violations = append(violations, fmt.Sprintf("audiences %q do not include %q", token.Audience(), want))
+		}
+	}
+
+	if len(c.flagCategories) > 0 {
+		justifications, err := jvspb.GetJustifications(token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get justifications from token: %w", err)
+		}
+		for _, want := range c.flagCategories {
+			if !slices.ContainsFunc(justifications, func(j *jvspb.Justification) bool {
+				return j.GetCategory() == want
+			}) {
+				violations = append(violations, fmt.Sprintf("no justification of category %q", want))
+			}
+		}
+	}
+
+	if c.flagMaxAge > 0 {
+		if age := now.Sub(token.IssuedAt()); age > c.flagMaxAge {
+			violations = append(violations, fmt.Sprintf("issued %s ago, more than the max age %s",
+				age.Truncate(time.Second), c.flagMaxAge))
+		}
+	}
+
+	return violations, nil
+}
+
+// reject returns an [ExitError] with the code for a token that failed
+// validation. If requested, it also prints the JSON verdict with the reasons.
+func (c *TokenValidateCommand) reject(code int, err error, reasons []string) error {
+	if c.flagVerdict {
+		result := verdictInvalidToken
+		switch code {
+		case ExitCodeExpiredToken:
+			result = verdictExpiredToken
+		case ExitCodePolicyViolation:
+			result = verdictPolicyViolation
+		}
+
+		if verr := c.writeVerdict(&tokenVerdict{
+			Result:   result,
+			ExitCode: code,
+			Errors:   reasons,
+		}); verr != nil {
+			return verr
+		}
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// writeVerdict prints the verdict as JSON.
+func (c *TokenValidateCommand) writeVerdict(v *tokenVerdict) error {
+	if err := json.NewEncoder(c.Stdout()).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode verdict: %w", err)
+	}
+	return nil
+}
+
+// tokenErrorCode returns the exit code for an error verifying a token.
+func tokenErrorCode(err error) int {
+	if errors.Is(err, jwt.ErrTokenExpired()) || errors.Is(err, jwt.ErrTokenNotYetValid()) {
+		return ExitCodeExpiredToken
+	}
+	return ExitCodeInvalidToken
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -97,12 +98,33 @@ func TestNewValidateCmd(t *testing.T) {
 		t.Fatalf("failed to build breakglass token: %v", err)
 	}
 
+	expiredToken := testTokenBuilder(t)
+	if err := expiredToken.Set(jwt.ExpirationKey, time.Unix(60, 0).UTC()); err != nil {
+		t.Fatal(err)
+	}
+	signedExpiredToken := testSignToken(t, expiredToken, privateKey, keyID)
+
+	recentToken := testTokenBuilder(t)
+	if err := recentToken.Set(jwt.IssuedAtKey, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := jvspb.SetJustifications(recentToken, []*jvspb.Justification{
+		{
+			Category: "jira",
+			Value:    "ABC-123",
+		},
+	}); err != nil {
+		t.Fatalf("failed to set justifications in token: %v", err)
+	}
+	signedRecentToken := testSignToken(t, recentToken, privateKey, keyID)
+
 	cases := []struct {
-		name   string
-		args   []string
-		stdin  io.Reader
-		expOut string
-		expErr string
+		name    string
+		args    []string
+		stdin   io.Reader
+		expOut  string
+		expErr  string
+		expCode int
 	}{
 		{
 			name:   "too_many_args",
@@ -119,7 +141,8 @@ func TestNewValidateCmd(t *testing.T) {
 			args: []string{
 				"-token=invalid token",
 			},
-			expErr: `failed to parse token headers`,
+			expErr:  `failed to parse token headers`,
+			expCode: ExitCodeInvalidToken,
 		},
 		{
 			name: "bad_jwks_endpoint",
@@ -142,6 +165,7 @@ foo            bar
 
 ----- Claims -----
 aud    [dev.abcxyz.jvs]
+exp    2100-01-01 12:00AM UTC
 iat    1970-01-01 12:00AM UTC
 iss    jvsctl
 jti    test-jwt
@@ -162,6 +186,7 @@ breakglass    prod is down
 
 ----- Claims -----
 aud    [dev.abcxyz.jvs]
+exp    2100-01-01 12:00AM UTC
 iat    1970-01-01 12:00AM UTC
 iss    jvsctl
 jti    test-jwt
@@ -183,6 +208,7 @@ foo            bar
 
 ----- Claims -----
 aud    [dev.abcxyz.jvs]
+exp    2100-01-01 12:00AM UTC
 iat    1970-01-01 12:00AM UTC
 iss    jvsctl
 jti    test-jwt
@@ -197,7 +223,125 @@ sub    test-sub
 				"-subject", "bad-sub",
 				"-jwks-endpoint", goodJWKSEndpoint,
 			},
-			expErr: `does not match expected subject`,
+			expErr:  `does not match expected subject`,
+			expCode: ExitCodePolicyViolation,
+		},
+		{
+			name: "expired",
+			args: []string{
+				"-token", signedExpiredToken,
+				"-jwks-endpoint", goodJWKSEndpoint,
+			},
+			expErr:  `"exp" not satisfied`,
+			expCode: ExitCodeExpiredToken,
+		},
+		{
+			name: "expired_verdict",
+			args: []string{
+				"-token", signedExpiredToken,
+				"-jwks-endpoint", goodJWKSEndpoint,
+				"-verdict",
+			},
+			expOut:  `{"valid":false,"result":"expired_token","exit_code":3,"errors":["failed to verify jwt: \"exp\" not satisfied"]}`,
+			expErr:  `"exp" not satisfied`,
+			expCode: ExitCodeExpiredToken,
+		},
+		{
+			name: "invalid_signature_verdict",
+			args: []string{
+				"-token", signedToken[:len(signedToken)-4] + "AAAA",
+				"-jwks-endpoint", goodJWKSEndpoint,
+				"-verdict",
+			},
+			expOut:  `{"valid":false,"result":"invalid_token","exit_code":2,"errors":["failed to verify jwt: could not verify message using any of the signatures or keys"]}`,
+			expErr:  `failed to validate jwt`,
+			expCode: ExitCodeInvalidToken,
+		},
+		{
+			name: "policy_satisfied_verdict",
+			args: []string{
+				"-token", signedRecentToken,
+				"-jwks-endpoint", goodJWKSEndpoint,
+				"-require-subject", "test-sub",
+				"-require-audience", justification.DefaultAudience,
+				"-require-category", "jira",
+				"-max-age", "1h",
+				"-verdict",
+			},
+			expOut: `{"valid":true,"result":"valid","exit_code":0}`,
+		},
+		{
+			name: "audience_pattern_verdict",
+			args: []string{
+				"-token", signedRecentToken,
+				"-jwks-endpoint", goodJWKSEndpoint,
+				"-require-audience", "dev.abcxyz.*",
+				"-verdict",
+			},
+			expOut: `{"valid":true,"result":"valid","exit_code":0}`,
+		},
+		{
+			name: "invalid_audience_pattern",
+			args: []string{
+				"-token", signedToken,
+				"-require-audience", "*",
+			},
+			expErr: `invalid -require-audience: audience pattern "*" must not match every audience`,
+		},
+		{
+			name: "policy_violations_verdict",
+			args: []string{
+				"-token", signedToken,
+				"-jwks-endpoint", goodJWKSEndpoint,
+				"-require-audience", "example.com",
+				"-require-category", "jira",
+				"-require-category", "foo",
+				"-max-age", "1h",
+				"-verdict",
+			},
+			expOut: `{"valid":false,"result":"policy_violation","exit_code":4,"errors":[` +
+				`"audiences [\"dev.abcxyz.jvs\"] do not include \"example.com\"",` +
+				`"no justification of category \"jira\""` +
+				`,"issued`,
+			expErr:  `token does not satisfy policy: audiences ["dev.abcxyz.jvs"] do not include "example.com"; no justification of category "jira"; issued`,
+			expCode: ExitCodePolicyViolation,
+		},
+		{
+			name: "issuer_accepted_verdict",
+			args: []string{
+				"-token", signedRecentToken,
+				"-jwks-endpoint", goodJWKSEndpoint,
+				"-require-issuer", "jvs.example.com",
+				"-require-issuer", Issuer,
+				"-verdict",
+			},
+			expOut: `{"valid":true,"result":"valid","exit_code":0}`,
+		},
+		{
+			name: "breakglass_issuer_violation",
+			args: []string{
+				"-token", breakglassToken,
+				"-require-issuer", "jvs.example.com",
+			},
+			expErr:  `issuer "jvsctl" is not one of ["jvs.example.com"]`,
+			expCode: ExitCodePolicyViolation,
+		},
+		{
+			name: "breakglass_policy_violation",
+			args: []string{
+				"-token", breakglassToken,
+				"-require-category", "jira",
+			},
+			expErr:  `no justification of category "jira"`,
+			expCode: ExitCodePolicyViolation,
+		},
+		{
+			name: "negative_max_age",
+			args: []string{
+				"-token", signedToken,
+				"-max-age", "-1m",
+			},
+			expErr: `max age cannot be negative`,
 		},
 		{
 			name: "from_stdin",
@@ -213,6 +357,7 @@ foo            bar
 
 ----- Claims -----
 aud    [dev.abcxyz.jvs]
+exp    2100-01-01 12:00AM UTC
 iat    1970-01-01 12:00AM UTC
 iss    jvsctl
 jti    test-jwt
@@ -226,7 +371,7 @@ sub    test-sub
 				"-token", breakglassToken,
 				"-format", "json",
 			},
-			expOut: `{"breakglass":true,"justifications":[{"category":"breakglass","value":"prod is down","annotation":null}],"claims":{"aud":["dev.abcxyz.jvs"],"iat":"1970-01-01T00:00:00Z","iss":"jvsctl","jti":"test-jwt","nbf":"1970-01-01T00:00:00Z","sub":"test-sub"}}`,
+			expOut: `{"breakglass":true,"justifications":[{"category":"breakglass","value":"prod is down","annotation":null}],"claims":{"aud":["dev.abcxyz.jvs"],"exp":"2100-01-01T00:00:00Z","iat":"1970-01-01T00:00:00Z","iss":"jvsctl","jti":"test-jwt","nbf":"1970-01-01T00:00:00Z","sub":"test-sub"}}`,
 		},
 		{
 			name: "yaml",
@@ -247,6 +392,7 @@ justifications:
 claims:
   aud:
     - dev.abcxyz.jvs
+  exp: 2100-01-01T00:00:00Z
   iat: 1970-01-01T00:00:00Z
   iss: jvsctl
   jti: test-jwt
@@ -272,16 +418,29 @@ claims:
 
 			args := append([]string{}, tc.args...)
 
-			if err := cmd.Run(ctx, args); err != nil {
-				if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
-					t.Fatal(diff)
+			err := cmd.Run(ctx, args)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if tc.expCode != 0 {
+				var exitErr *ExitError
+				if !errors.As(err, &exitErr) {
+					t.Fatalf("expected %v to be an ExitError", err)
 				}
-				if err != nil {
-					return
+				if got, want := exitErr.Code, tc.expCode; got != want {
+					t.Errorf("exit code: expected %d to be %d", got, want)
 				}
 			}
+			if err != nil && tc.expOut == "" {
+				return
+			}
 
-			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), strings.TrimSpace(stdout.String())); diff != "" {
+			got := strings.TrimSpace(stdout.String())
+			if err != nil {
+				// Policy violations include the token age, only compare the prefix.
+				got = got[:min(len(got), len(tc.expOut))]
+			}
+			if diff := cmp.Diff(strings.TrimSpace(tc.expOut), got); diff != "" {
 				t.Errorf("output: diff (-want, +got):\n%s", diff)
 			}
 		})
@@ -294,6 +453,7 @@ func testTokenBuilder(tb testing.TB) jwt.Token {
 	now := time.Unix(0, 0).UTC()
 	token, err := jwt.NewBuilder().
 		Audience([]string{justification.DefaultAudience}).
+		Expiration(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)).
 		IssuedAt(now).
 		Issuer(Issuer).
 		JwtID("test-jwt").
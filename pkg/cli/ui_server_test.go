@@ -55,9 +55,10 @@ func TestUIServerCommand(t *testing.T) {
 		{
 			name: "starts",
 			env: map[string]string{
-				"PROJECT_ID":       "example-project",
-				"JVS_KEY":          "fake/key",
-				"JVS_UI_ALLOWLIST": "example.com,*.foo.bar",
+				"PROJECT_ID":               "example-project",
+				"JVS_KEY":                  "fake/key",
+				"JVS_UI_ALLOWLIST":         "example.com,*.foo.bar",
+				"JVS_KMS_PERMISSION_CHECK": "off",
 			},
 		},
 	}
@@ -75,6 +76,8 @@ func TestUIServerCommand(t *testing.T) {
 				cli.MapLookuper(map[string]string{
 					// Make the test choose a random port.
 					"PORT": "0",
+					// Don't wait for KMS, which these tests don't call.
+					"JVS_WARMUP_TIMEOUT": "0",
 				}),
 			))
 			cmd.testKMSClientOptions = []option.ClientOption{
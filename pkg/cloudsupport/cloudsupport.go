@@ -0,0 +1,125 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cloudsupport provides a justification validator that checks Google
+// Cloud support cases.
+package cloudsupport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/cloudsupport/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+)
+
+const (
+	DisplayName = "Google Cloud support case"
+	Hint        = "The ID of an open Google Cloud support case, e.g. 43595344."
+
+	// Annotations set on valid justifications.
+	AnnotationCase  = "support_case"
+	AnnotationState = "support_case_state"
+
+	// stateClosed is the state of resolved cases.
+	stateClosed = "CLOSED"
+)
+
+// caseIDRegexp matches support case IDs, optionally as the full resource name.
+var caseIDRegexp = regexp.MustCompile(`^(?:organizations/([0-9]+)/cases/)?([0-9]+)$`)
+
+var _ jvspb.Validator = (*Validator)(nil)
+
+// Validator validates that the justification is the ID of an open support case
+// of the organization.
+type Validator struct {
+	config  *config.CloudSupportConfig
+	service *cloudsupport.Service
+}
+
+// NewValidator creates a new validator with the Cloud Support API client
+// options.
+func NewValidator(ctx context.Context, cfg *config.CloudSupportConfig, opts ...option.ClientOption) (*Validator, error) {
+	if cfg.Endpoint != "" {
+		opts = append([]option.ClientOption{option.WithEndpoint(cfg.Endpoint)}, opts...)
+	}
+	service, err := cloudsupport.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud support client: %w", err)
+	}
+	return &Validator{
+		config:  cfg,
+		service: service,
+	}, nil
+}
+
+// Validate implements [jvspb.Validator].
+func (v *Validator) Validate(ctx context.Context, req *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	value := strings.TrimSpace(req.GetJustification().GetValue())
+	matches := caseIDRegexp.FindStringSubmatch(value)
+	if matches == nil {
+		return invalid(fmt.Sprintf("%q is not a support case ID", value)), nil
+	}
+	if org := matches[1]; org != "" && org != v.config.OrganizationID {
+		return invalid(fmt.Sprintf("support case %s does not belong to the organization", value)), nil
+	}
+
+	name := fmt.Sprintf("organizations/%s/cases/%s", v.config.OrganizationID, matches[2])
+	c, err := v.service.Cases.Get(name).
+		Fields("name", "state").
+		Context(ctx).
+		Do()
+	if err != nil {
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == http.StatusNotFound {
+			return invalid(fmt.Sprintf("support case %s not found in the organization", matches[2])), nil
+		}
+		return nil, fmt.Errorf("failed to get support case %s: %w", name, err)
+	}
+
+	if c.State == stateClosed {
+		return invalid(fmt.Sprintf("support case %s is closed", matches[2])), nil
+	}
+
+	return &jvspb.ValidateJustificationResponse{
+		Valid: true,
+		Annotation: map[string]string{
+			AnnotationCase:  c.Name,
+			AnnotationState: c.State,
+		},
+	}, nil
+}
+
+// GetUIData implements [jvspb.Validator].
+func (v *Validator) GetUIData(_ context.Context, _ *jvspb.GetUIDataRequest) (*jvspb.UIData, error) {
+	return &jvspb.UIData{
+		DisplayName: DisplayName,
+		Hint:        Hint,
+	}, nil
+}
+
+func invalid(msg string) *jvspb.ValidateJustificationResponse {
+	return &jvspb.ValidateJustificationResponse{
+		Valid: false,
+		Error: []string{msg},
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudsupport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	t.Parallel()
+
+	states := map[string]string{
+		"organizations/123/cases/1": "IN_PROGRESS_GOOGLE_SUPPORT",
+		"organizations/123/cases/2": "CLOSED",
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/v2/")
+		if name == "organizations/123/cases/500" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		state, ok := states[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"name":  name,
+			"state": state,
+		}); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name     string
+		value    string
+		wantResp *jvspb.ValidateJustificationResponse
+		wantErr  string
+	}{
+		{
+			name:  "open",
+			value: "1",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: true,
+				Annotation: map[string]string{
+					AnnotationCase:  "organizations/123/cases/1",
+					AnnotationState: "IN_PROGRESS_GOOGLE_SUPPORT",
+				},
+			},
+		},
+		{
+			name:  "open_full_name",
+			value: "organizations/123/cases/1",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: true,
+				Annotation: map[string]string{
+					AnnotationCase:  "organizations/123/cases/1",
+					AnnotationState: "IN_PROGRESS_GOOGLE_SUPPORT",
+				},
+			},
+		},
+		{
+			name:  "closed",
+			value: "2",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"support case 2 is closed"},
+			},
+		},
+		{
+			name:  "not_found",
+			value: "3",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"support case 3 not found in the organization"},
+			},
+		},
+		{
+			name:  "other_organization",
+			value: "organizations/456/cases/1",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"support case organizations/456/cases/1 does not belong to the organization"},
+			},
+		},
+		{
+			name:  "invalid_id",
+			value: "projects/p/cases/1",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{`"projects/p/cases/1" is not a support case ID`},
+			},
+		},
+		{
+			name:    "server_error",
+			value:   "500",
+			wantErr: "failed to get support case organizations/123/cases/500",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			v, err := NewValidator(ctx, &config.CloudSupportConfig{
+				OrganizationID: "123",
+				Endpoint:       srv.URL + "/",
+			}, option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotResp, err := v.Validate(ctx, &jvspb.ValidateJustificationRequest{
+				Justification: &jvspb.Justification{
+					Category: "cloudsupport",
+					Value:    tc.value,
+				},
+			})
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.wantResp, gotResp, protocmp.Transform()); diff != "" {
+				t.Errorf("response (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
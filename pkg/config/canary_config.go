@@ -0,0 +1,98 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// CanaryConfig is the configuration of the synthetic probe, which periodically
+// mints a canary token and validates it against the JWKS endpoint of the JVS.
+type CanaryConfig struct {
+	// Interval is the interval between probes. 0 disables the probe.
+	Interval time.Duration `env:"JVS_API_CANARY_INTERVAL,overwrite,default=0"`
+
+	// JWKSEndpoint is the JWKS endpoint of the public key server that serves
+	// the keys of this JVS, e.g. "https://keys.corp/.well-known/jwks".
+	JWKSEndpoint string `env:"JVS_API_CANARY_JWKS_ENDPOINT,overwrite"`
+
+	// Timeout is the timeout of each probe, including fetching the keys.
+	Timeout time.Duration `env:"JVS_API_CANARY_TIMEOUT,overwrite,default=10s"`
+}
+
+// Enabled reports whether the probe is enabled.
+func (cfg *CanaryConfig) Enabled() bool {
+	return cfg.Interval > 0
+}
+
+// Validate checks if the config is valid.
+func (cfg *CanaryConfig) Validate() (merr error) {
+	if cfg.Interval < 0 {
+		merr = errors.Join(merr, fmt.Errorf("canary interval must not be negative, got %s", cfg.Interval))
+	}
+
+	if !cfg.Enabled() {
+		return
+	}
+
+	if cfg.JWKSEndpoint == "" {
+		merr = errors.Join(merr, fmt.Errorf("canary jwks endpoint must be set with a canary interval"))
+	} else if u, err := url.Parse(cfg.JWKSEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		merr = errors.Join(merr, fmt.Errorf("canary jwks endpoint %q must be an absolute URL", cfg.JWKSEndpoint))
+	}
+
+	if cfg.Timeout <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("canary timeout must be a positive duration, got %s", cfg.Timeout))
+	} else if cfg.Timeout > cfg.Interval {
+		merr = errors.Join(merr, fmt.Errorf("canary timeout %s must not be longer than the canary interval %s", cfg.Timeout, cfg.Interval))
+	}
+
+	return
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *CanaryConfig) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("CANARY OPTIONS")
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "canary-interval",
+		Target:  &cfg.Interval,
+		EnvVar:  "JVS_API_CANARY_INTERVAL",
+		Default: 0,
+		Usage: `The interval between canary probes, which mint a canary token ` +
+			`and validate it against the JWKS endpoint. 0 disables them.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "canary-jwks-endpoint",
+		Target:  &cfg.JWKSEndpoint,
+		EnvVar:  "JVS_API_CANARY_JWKS_ENDPOINT",
+		Example: "https://keys.corp/.well-known/jwks",
+		Usage:   `The JWKS endpoint canary tokens are validated against.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "canary-timeout",
+		Target:  &cfg.Timeout,
+		EnvVar:  "JVS_API_CANARY_TIMEOUT",
+		Default: 10 * time.Second,
+		Usage:   `The timeout of each canary probe.`,
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCanaryConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *CanaryConfig
+		wantErr string
+	}{
+		{
+			name: "disabled",
+			cfg:  &CanaryConfig{},
+		},
+		{
+			name: "enabled",
+			cfg: &CanaryConfig{
+				Interval:     time.Minute,
+				JWKSEndpoint: "https://keys.example.com/.well-known/jwks",
+				Timeout:      10 * time.Second,
+			},
+		},
+		{
+			name:    "negative_interval",
+			cfg:     &CanaryConfig{Interval: -time.Minute},
+			wantErr: "canary interval must not be negative, got -1m0s",
+		},
+		{
+			name: "missing_endpoint",
+			cfg: &CanaryConfig{
+				Interval: time.Minute,
+				Timeout:  10 * time.Second,
+			},
+			wantErr: "canary jwks endpoint must be set with a canary interval",
+		},
+		{
+			name: "relative_endpoint",
+			cfg: &CanaryConfig{
+				Interval:     time.Minute,
+				JWKSEndpoint: "/.well-known/jwks",
+				Timeout:      10 * time.Second,
+			},
+			wantErr: `canary jwks endpoint "/.well-known/jwks" must be an absolute URL`,
+		},
+		{
+			name: "invalid_timeout",
+			cfg: &CanaryConfig{
+				Interval:     time.Minute,
+				JWKSEndpoint: "https://keys.example.com/.well-known/jwks",
+			},
+			wantErr: "canary timeout must be a positive duration, got 0s",
+		},
+		{
+			name: "timeout_longer_than_interval",
+			cfg: &CanaryConfig{
+				Interval:     time.Minute,
+				JWKSEndpoint: "https://keys.example.com/.well-known/jwks",
+				Timeout:      2 * time.Minute,
+			},
+			wantErr: "canary timeout 2m0s must not be longer than the canary interval 1m0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
@@ -18,11 +18,25 @@ package config
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/abcxyz/pkg/cli"
 )
 
+// KeyAlgorithmECP256 is the only KMS key version algorithm the JVS can sign
+// tokens with, as ES256, and serve public keys for.
+const KeyAlgorithmECP256 = "EC_SIGN_P256_SHA256"
+
+// The KMS protection levels new key versions can be created with.
+const (
+	ProtectionLevelSoftware = "SOFTWARE"
+	ProtectionLevelHSM      = "HSM"
+)
+
+// eventsTopicRegexp matches Pub/Sub topic names.
+var eventsTopicRegexp = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+
 // CertRotationConfig is a configuration for cert rotation services.
 type CertRotationConfig struct {
 	// ProjectID is the Google Cloud project ID.
@@ -34,6 +48,11 @@ type CertRotationConfig struct {
 	// Port is the port where the service runs.
 	Port string `env:"PORT,default=8080"`
 
+	// ShutdownTimeout is how long the server waits for in-flight requests to
+	// finish on shutdown, before abandoning them. If it is 0, the server stops
+	// right away.
+	ShutdownTimeout time.Duration `env:"JVS_SHUTDOWN_TIMEOUT,overwrite,default=30s"`
+
 	// -- Crypto variables --
 	// KeyTTL is the length of time that we expect a key to be valid for.
 	KeyTTL time.Duration `env:"JVS_ROTATION_KEY_TTL,overwrite"`
@@ -47,9 +66,76 @@ type CertRotationConfig struct {
 	// DisabledPeriod is a time between when the key is disabled, and when we delete the key.
 	DisabledPeriod time.Duration `env:"JVS_ROTATION_DISABLED_PERIOD,overwrite"`
 
+	// DisableOrphanedVersions disables orphaned key versions: enabled versions
+	// that were never promoted to primary, and are older than KeyTTL plus
+	// DisabledPeriod. Orphaned versions are always logged.
+	DisableOrphanedVersions bool `env:"JVS_ROTATION_DISABLE_ORPHANED_VERSIONS,overwrite,default=false"`
+
+	// DestroyScheduledDuration is the destroy_scheduled_duration of the KMS
+	// keys: how long destroyed versions stay scheduled for destruction before
+	// their key material is destroyed. If set, keys are checked to have it, and
+	// versions are destroyed that much earlier, so their key material is
+	// destroyed DestroyAge after they were created. KMS doesn't allow changing
+	// it once the key is created.
+	DestroyScheduledDuration time.Duration `env:"JVS_ROTATION_DESTROY_SCHEDULED_DURATION,overwrite"`
+
+	// KeyAlgorithm and KeyProtectionLevel, if set, are the algorithm and
+	// protection level new KMS key versions are created with. The version
+	// template of the key is updated to them before creating a version, instead
+	// of creating it with whatever the template has. KeyAlgorithm can only be
+	// KeyAlgorithmECP256, and KeyProtectionLevel ProtectionLevelSoftware or
+	// ProtectionLevelHSM.
+	KeyAlgorithm       string `env:"JVS_ROTATION_KEY_ALGORITHM,overwrite"`
+	KeyProtectionLevel string `env:"JVS_ROTATION_KEY_PROTECTION_LEVEL,overwrite"`
+
+	// Signer is where the keys are stored, either SignerKMS, SignerPKCS11 or
+	// SignerVault. Local keys are not rotated.
+	Signer string `env:"JVS_SIGNER,overwrite,default=kms"`
+
 	// KeyName format: `projects/*/locations/*/keyRings/*/cryptoKeys/*`
 	// https://pkg.go.dev/google.golang.org/genproto/googleapis/cloud/kms/v1#CryptoKey
+	// With SignerPKCS11, they are the labels of the keys in the HSM, and with
+	// SignerVault, the names of the transit keys.
 	KeyNames []string `env:"JVS_KEY_NAMES,overwrite"`
+
+	// PKCS11 is the HSM the keys are in with SignerPKCS11.
+	PKCS11 PKCS11Config
+
+	// Vault is the Vault server the keys are in with SignerVault.
+	Vault VaultConfig
+
+	// KMSEndpoint overrides the Cloud KMS API endpoint, e.g. to point at a local
+	// KMS emulator. KMSInsecure disables TLS and authentication for that
+	// endpoint.
+	KMSEndpoint string `env:"JVS_KMS_ENDPOINT,overwrite"`
+	KMSInsecure bool   `env:"JVS_KMS_INSECURE,overwrite,default=false"`
+
+	// KMSPermissionCheck is whether the permissions of the service account on
+	// the KMS keys are checked at startup: KMSPermissionCheckOff,
+	// KMSPermissionCheckWarn or KMSPermissionCheckFail.
+	KMSPermissionCheck string `env:"JVS_KMS_PERMISSION_CHECK,overwrite,default=warn"`
+
+	// EventsTopic, if set, is the Pub/Sub topic key events are published to,
+	// in the format `projects/*/topics/*`.
+	EventsTopic string `env:"JVS_EVENTS_TOPIC,overwrite"`
+
+	// ChatOpsSigningSecret, if set, serves Slack compatible slash commands to
+	// rotate, describe and disable keys at /chatops/slack. Requests must be
+	// signed with it, the signing secret of the Slack app.
+	ChatOpsSigningSecret string `json:"-" env:"JVS_ROTATION_CHATOPS_SIGNING_SECRET,overwrite"`
+
+	// ChatOpsAllowedUsers are the Slack user IDs allowed to run slash commands.
+	ChatOpsAllowedUsers []string `env:"JVS_ROTATION_CHATOPS_ALLOWED_USERS,overwrite"`
+
+	// TriggerAudience, if set, requires requests to the rotation trigger to
+	// present an ID token from TriggerIssuer, verified with the keys at
+	// TriggerJWKSEndpoint, whose audience is TriggerAudience and whose email is
+	// one of TriggerPrincipals, e.g. the service account of the Cloud Scheduler
+	// job or Pub/Sub push subscription that triggers rotation.
+	TriggerAudience     string   `env:"JVS_ROTATION_TRIGGER_AUDIENCE,overwrite"`
+	TriggerPrincipals   []string `env:"JVS_ROTATION_TRIGGER_PRINCIPALS,overwrite"`
+	TriggerIssuer       string   `env:"JVS_ROTATION_TRIGGER_ISSUER,overwrite,default=https://accounts.google.com"`
+	TriggerJWKSEndpoint string   `env:"JVS_ROTATION_TRIGGER_JWKS_ENDPOINT,overwrite,default=https://www.googleapis.com/oauth2/v3/certs"`
 }
 
 // Validate checks if the config is valid.
@@ -58,10 +144,48 @@ func (cfg *CertRotationConfig) Validate() (merr error) {
 		merr = errors.Join(merr, fmt.Errorf("empty ProjectID"))
 	}
 
+	if got := cfg.ShutdownTimeout; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("shutdown timeout cannot be negative, got %s", got))
+	}
+
 	if len(cfg.KeyNames) == 0 {
 		merr = errors.Join(merr, fmt.Errorf("empty KeyNames"))
 	}
 
+	switch cfg.Signer {
+	case "", SignerKMS:
+	case SignerPKCS11:
+		merr = errors.Join(merr, cfg.PKCS11.Validate())
+	case SignerVault:
+		merr = errors.Join(merr, cfg.Vault.Validate())
+	default:
+		merr = errors.Join(merr, fmt.Errorf("signer must be %q, %q or %q, got %q",
+			SignerKMS, SignerPKCS11, SignerVault, cfg.Signer))
+	}
+
+	if got := cfg.KeyAlgorithm; got != "" && got != KeyAlgorithmECP256 {
+		merr = errors.Join(merr, fmt.Errorf("key algorithm must be %q, got %q", KeyAlgorithmECP256, got))
+	}
+
+	switch cfg.KeyProtectionLevel {
+	case "", ProtectionLevelSoftware, ProtectionLevelHSM:
+	default:
+		merr = errors.Join(merr, fmt.Errorf("key protection level must be %q or %q, got %q",
+			ProtectionLevelSoftware, ProtectionLevelHSM, cfg.KeyProtectionLevel))
+	}
+
+	if (cfg.KeyAlgorithm != "" || cfg.KeyProtectionLevel != "") && cfg.Signer != "" && cfg.Signer != SignerKMS {
+		merr = errors.Join(merr, fmt.Errorf("key algorithm and protection level are only supported with the %q signer", SignerKMS))
+	}
+
+	if cfg.KMSInsecure && cfg.KMSEndpoint == "" {
+		merr = errors.Join(merr, fmt.Errorf("KMSInsecure requires KMSEndpoint to be set"))
+	}
+
+	if err := validateKMSPermissionCheck(cfg.KMSPermissionCheck); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
 	if got := cfg.KeyTTL; got <= 0 {
 		merr = errors.Join(merr, fmt.Errorf("key ttl must be a positive duration, got %q", got))
 	}
@@ -74,6 +198,20 @@ func (cfg *CertRotationConfig) Validate() (merr error) {
 		merr = errors.Join(merr, fmt.Errorf("disabled period must be a positive duration, got %q", got))
 	}
 
+	if got := cfg.DestroyScheduledDuration; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("destroy scheduled duration cannot be negative, got %q", got))
+	}
+
+	if cfg.DestroyScheduledDuration > 0 {
+		if cfg.DestroyScheduledDuration >= cfg.DisabledPeriod {
+			merr = errors.Join(merr, fmt.Errorf("destroy scheduled duration %q must be less than disabled period %q",
+				cfg.DestroyScheduledDuration, cfg.DisabledPeriod))
+		}
+		if cfg.Signer != "" && cfg.Signer != SignerKMS {
+			merr = errors.Join(merr, fmt.Errorf("destroy scheduled duration is only supported with the %q signer", SignerKMS))
+		}
+	}
+
 	// Propagation delay must be positive but less than the grace period.
 	if got := cfg.PropagationDelay; got <= 0 {
 		merr = errors.Join(merr, fmt.Errorf("propagation delay must be a positive duration, got %q", got))
@@ -84,6 +222,32 @@ func (cfg *CertRotationConfig) Validate() (merr error) {
 			cfg.PropagationDelay, cfg.GracePeriod))
 	}
 
+	if cfg.EventsTopic != "" && !eventsTopicRegexp.MatchString(cfg.EventsTopic) {
+		merr = errors.Join(merr, fmt.Errorf("events topic %q must be in the format projects/*/topics/*", cfg.EventsTopic))
+	}
+
+	if cfg.ChatOpsSigningSecret != "" {
+		if len(cfg.ChatOpsAllowedUsers) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("chatops allowed users must be set with the chatops signing secret"))
+		}
+		if cfg.Signer != "" && cfg.Signer != SignerKMS {
+			merr = errors.Join(merr, fmt.Errorf("chatops is only supported with the %q signer", SignerKMS))
+		}
+	} else if len(cfg.ChatOpsAllowedUsers) > 0 {
+		merr = errors.Join(merr, fmt.Errorf("chatops allowed users require the chatops signing secret"))
+	}
+
+	if cfg.TriggerAudience != "" {
+		if len(cfg.TriggerPrincipals) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("trigger principals must be set with the trigger audience"))
+		}
+		if cfg.TriggerIssuer == "" || cfg.TriggerJWKSEndpoint == "" {
+			merr = errors.Join(merr, fmt.Errorf("trigger issuer and jwks endpoint must be set with the trigger audience"))
+		}
+	} else if len(cfg.TriggerPrincipals) > 0 {
+		merr = errors.Join(merr, fmt.Errorf("trigger principals require the trigger audience"))
+	}
+
 	return
 }
 
@@ -97,6 +261,13 @@ func (cfg *CertRotationConfig) DestroyAge() time.Duration {
 	return cfg.KeyTTL + cfg.DisabledPeriod
 }
 
+// DestroyRequestAge gets the duration after a key has been created when it
+// should be destroyed, so its key material is destroyed by DestroyAge once the
+// destroy schedule has passed.
+func (cfg *CertRotationConfig) DestroyRequestAge() time.Duration {
+	return cfg.DestroyAge() - cfg.DestroyScheduledDuration
+}
+
 // ToFlags binds the config to the give [cli.FlagSet] and returns it.
 func (cfg *CertRotationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 	f := set.NewSection("COMMON SERVER OPTIONS")
@@ -124,6 +295,15 @@ func (cfg *CertRotationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   `The port the server listens to.`,
 	})
 
+	f.DurationVar(&cli.DurationVar{
+		Name:    "shutdown-timeout",
+		Target:  &cfg.ShutdownTimeout,
+		EnvVar:  "JVS_SHUTDOWN_TIMEOUT",
+		Default: 30 * time.Second,
+		Usage: "How long to wait for in-flight requests to finish on shutdown, " +
+			"before abandoning them.",
+	})
+
 	f = set.NewSection("KEY ROTATION OPTIONS")
 
 	f.DurationVar(&cli.DurationVar{
@@ -158,13 +338,145 @@ func (cfg *CertRotationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   "The time between when the key is disabled and when we delete the key.",
 	})
 
+	f.BoolVar(&cli.BoolVar{
+		Name:    "disable-orphaned-versions",
+		Target:  &cfg.DisableOrphanedVersions,
+		EnvVar:  "JVS_ROTATION_DISABLE_ORPHANED_VERSIONS",
+		Default: false,
+		Usage:   "Whether to disable enabled key versions that were never promoted and are older than the destroy age.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:   "destroy-scheduled-duration",
+		Target: &cfg.DestroyScheduledDuration,
+		EnvVar: "JVS_ROTATION_DESTROY_SCHEDULED_DURATION",
+		Usage: "The destroy_scheduled_duration of the KMS keys. If set, keys are checked to have it, " +
+			"and versions are destroyed that much earlier, so their key material is destroyed by the destroy age.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-algorithm",
+		Target:  &cfg.KeyAlgorithm,
+		EnvVar:  "JVS_ROTATION_KEY_ALGORITHM",
+		Example: KeyAlgorithmECP256,
+		Usage:   "The algorithm new KMS key versions are created with. If unset, the key's version template is used.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-protection-level",
+		Target:  &cfg.KeyProtectionLevel,
+		EnvVar:  "JVS_ROTATION_KEY_PROTECTION_LEVEL",
+		Example: ProtectionLevelHSM,
+		Usage: fmt.Sprintf("The protection level new KMS key versions are created with, %q or %q. "+
+			"If unset, the key's version template is used.", ProtectionLevelSoftware, ProtectionLevelHSM),
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "signer",
+		Target:  &cfg.Signer,
+		EnvVar:  "JVS_SIGNER",
+		Default: SignerKMS,
+		Usage: `Where the keys are stored, "kms" for Cloud KMS, "pkcs11" for ` +
+			`an HSM or "vault" for the Vault transit engine.`,
+	})
+
 	f.StringSliceVar(&cli.StringSliceVar{
 		Name:    "key-names",
 		Target:  &cfg.KeyNames,
 		EnvVar:  "JVS_KEY_NAMES",
 		Example: "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[JVS_KEY]",
-		Usage:   "List of KMS key names",
+		Usage: `List of KMS key names, HSM key labels with the "pkcs11" ` +
+			`signer, or transit key names with the "vault" signer.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-endpoint",
+		Target:  &cfg.KMSEndpoint,
+		EnvVar:  "JVS_KMS_ENDPOINT",
+		Example: "localhost:9090",
+		Usage:   "Override the Cloud KMS API endpoint, e.g. for a local KMS emulator.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "kms-insecure",
+		Target:  &cfg.KMSInsecure,
+		EnvVar:  "JVS_KMS_INSECURE",
+		Default: false,
+		Usage:   "Connect to the KMS endpoint without TLS or authentication.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-permission-check",
+		Target:  &cfg.KMSPermissionCheck,
+		EnvVar:  "JVS_KMS_PERMISSION_CHECK",
+		Default: KMSPermissionCheckWarn,
+		Usage: `Whether to check the permissions on the KMS keys at startup, ` +
+			`one of "off", "warn" or "fail".`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "events-topic",
+		Target:  &cfg.EventsTopic,
+		EnvVar:  "JVS_EVENTS_TOPIC",
+		Example: "projects/[PROJECT]/topics/[TOPIC]",
+		Usage:   "The Pub/Sub topic to publish key rotation events to. Events are not published if unset.",
 	})
 
+	f = set.NewSection("CHATOPS OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:   "chatops-signing-secret",
+		Target: &cfg.ChatOpsSigningSecret,
+		EnvVar: "JVS_ROTATION_CHATOPS_SIGNING_SECRET",
+		Usage: "The signing secret of the Slack app. If set, slash commands to rotate, " +
+			"describe and disable keys are served at /chatops/slack.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "chatops-allowed-users",
+		Target:  &cfg.ChatOpsAllowedUsers,
+		EnvVar:  "JVS_ROTATION_CHATOPS_ALLOWED_USERS",
+		Example: "U012AB3CD",
+		Usage:   "Slack user IDs allowed to run slash commands. Can be repeated.",
+	})
+
+	f = set.NewSection("TRIGGER OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "trigger-audience",
+		Target:  &cfg.TriggerAudience,
+		EnvVar:  "JVS_ROTATION_TRIGGER_AUDIENCE",
+		Example: "https://jvs-cert-rotator.example.com",
+		Usage: "The audience of the ID tokens of rotation trigger callers. If " +
+			"unset, the trigger is not authenticated by the server.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "trigger-principals",
+		Target:  &cfg.TriggerPrincipals,
+		EnvVar:  "JVS_ROTATION_TRIGGER_PRINCIPALS",
+		Example: "jvs-scheduler@[PROJECT].iam.gserviceaccount.com",
+		Usage:   "The emails of the principals allowed to trigger rotation. Can be repeated.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "trigger-issuer",
+		Target:  &cfg.TriggerIssuer,
+		EnvVar:  "JVS_ROTATION_TRIGGER_ISSUER",
+		Default: "https://accounts.google.com",
+		Usage:   "The issuer of the ID tokens of rotation trigger callers.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "trigger-jwks-endpoint",
+		Target:  &cfg.TriggerJWKSEndpoint,
+		EnvVar:  "JVS_ROTATION_TRIGGER_JWKS_ENDPOINT",
+		Default: "https://www.googleapis.com/oauth2/v3/certs",
+		Usage:   "The JWKS endpoint to verify the ID tokens of rotation trigger callers.",
+	})
+
+	cfg.PKCS11.addFlags(set)
+	cfg.Vault.addFlags(set)
+
 	return set
 }
@@ -35,34 +35,96 @@ func TestCertRotationConfig_ToFlags(t *testing.T) {
 		{
 			name: "all_values_specified",
 			envs: map[string]string{
-				"PROJECT_ID":                     "example-project",
-				"DEV_MODE":                       "true",
-				"PORT":                           "0",
-				"JVS_ROTATION_KEY_TTL":           "15m",
-				"JVS_ROTATION_GRACE_PERIOD":      "10m",
-				"JVS_ROTATION_PROPAGATION_DELAY": "10m",
-				"JVS_ROTATION_DISABLED_PERIOD":   "3m",
-				"JVS_KEY_NAMES":                  "fake/key",
+				"PROJECT_ID":                              "example-project",
+				"DEV_MODE":                                "true",
+				"PORT":                                    "0",
+				"JVS_SHUTDOWN_TIMEOUT":                    "1m",
+				"JVS_ROTATION_KEY_TTL":                    "15m",
+				"JVS_ROTATION_GRACE_PERIOD":               "10m",
+				"JVS_ROTATION_PROPAGATION_DELAY":          "10m",
+				"JVS_ROTATION_DISABLED_PERIOD":            "3m",
+				"JVS_ROTATION_DISABLE_ORPHANED_VERSIONS":  "true",
+				"JVS_ROTATION_DESTROY_SCHEDULED_DURATION": "1m",
+				"JVS_ROTATION_KEY_ALGORITHM":              "EC_SIGN_P256_SHA256",
+				"JVS_ROTATION_KEY_PROTECTION_LEVEL":       "HSM",
+				"JVS_KEY_NAMES":                           "fake/key",
+				"JVS_KMS_ENDPOINT":                        "localhost:9090",
+				"JVS_KMS_PERMISSION_CHECK":                "fail",
+				"JVS_KMS_INSECURE":                        "true",
+				"JVS_EVENTS_TOPIC":                        "projects/p/topics/jvs-events",
+				"JVS_SIGNER":                              "pkcs11",
+				"JVS_PKCS11_MODULE":                       "/usr/lib/softhsm/libsofthsm2.so",
+				"JVS_PKCS11_TOKEN_LABEL":                  "jvs",
+				"JVS_PKCS11_SLOT":                         "2",
+				"JVS_PKCS11_PIN":                          "1234",
+				"JVS_VAULT_ADDR":                          "https://vault.example.com:8200",
+				"JVS_VAULT_TOKEN":                         "s.token",
+				"JVS_VAULT_NAMESPACE":                     "team",
+				"JVS_VAULT_TRANSIT_MOUNT":                 "jvs-transit",
+				"JVS_VAULT_TIMEOUT":                       "5s",
+				"JVS_ROTATION_CHATOPS_SIGNING_SECRET":     "slack-secret",
+				"JVS_ROTATION_CHATOPS_ALLOWED_USERS":      "U012AB3CD,U045EF6GH",
+				"JVS_ROTATION_TRIGGER_AUDIENCE":           "https://jvs-cert-rotator.example.com",
+				"JVS_ROTATION_TRIGGER_PRINCIPALS":         "scheduler@p.iam.gserviceaccount.com",
+				"JVS_ROTATION_TRIGGER_ISSUER":             "https://issuer.example.com",
+				"JVS_ROTATION_TRIGGER_JWKS_ENDPOINT":      "https://issuer.example.com/jwks",
 			},
 			wantConfig: &CertRotationConfig{
-				ProjectID:        "example-project",
-				DevMode:          true,
-				Port:             "0",
-				KeyTTL:           15 * time.Minute,
-				GracePeriod:      10 * time.Minute,
-				PropagationDelay: 10 * time.Minute,
-				DisabledPeriod:   3 * time.Minute,
-				KeyNames:         []string{"fake/key"},
+				ProjectID:                "example-project",
+				DevMode:                  true,
+				Port:                     "0",
+				ShutdownTimeout:          time.Minute,
+				KeyTTL:                   15 * time.Minute,
+				GracePeriod:              10 * time.Minute,
+				PropagationDelay:         10 * time.Minute,
+				DisabledPeriod:           3 * time.Minute,
+				DisableOrphanedVersions:  true,
+				DestroyScheduledDuration: time.Minute,
+				KeyAlgorithm:             "EC_SIGN_P256_SHA256",
+				KeyProtectionLevel:       "HSM",
+				KeyNames:                 []string{"fake/key"},
+				KMSEndpoint:              "localhost:9090",
+				KMSPermissionCheck:       "fail",
+				KMSInsecure:              true,
+				EventsTopic:              "projects/p/topics/jvs-events",
+				Signer:                   "pkcs11",
+				PKCS11: PKCS11Config{
+					Module:     "/usr/lib/softhsm/libsofthsm2.so",
+					TokenLabel: "jvs",
+					Slot:       2,
+					PIN:        "1234",
+				},
+				Vault: VaultConfig{
+					Address:      "https://vault.example.com:8200",
+					Token:        "s.token",
+					Namespace:    "team",
+					TransitMount: "jvs-transit",
+					Timeout:      5 * time.Second,
+				},
+				ChatOpsSigningSecret: "slack-secret",
+				ChatOpsAllowedUsers:  []string{"U012AB3CD", "U045EF6GH"},
+				TriggerAudience:      "https://jvs-cert-rotator.example.com",
+				TriggerPrincipals:    []string{"scheduler@p.iam.gserviceaccount.com"},
+				TriggerIssuer:        "https://issuer.example.com",
+				TriggerJWKSEndpoint:  "https://issuer.example.com/jwks",
 			},
 		},
 		{
 			name: "default_values",
 			wantConfig: &CertRotationConfig{
-				Port:             "8080",
-				KeyTTL:           10 * time.Minute,
-				GracePeriod:      5 * time.Minute,
-				PropagationDelay: 5 * time.Minute,
-				DisabledPeriod:   2 * time.Minute,
+				Port:               "8080",
+				ShutdownTimeout:    30 * time.Second,
+				KeyTTL:             10 * time.Minute,
+				GracePeriod:        5 * time.Minute,
+				PropagationDelay:   5 * time.Minute,
+				DisabledPeriod:     2 * time.Minute,
+				Signer:             "kms",
+				KMSPermissionCheck: "warn",
+				PKCS11:             PKCS11Config{Slot: -1},
+				Vault:              VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+
+				TriggerIssuer:       "https://accounts.google.com",
+				TriggerJWKSEndpoint: "https://www.googleapis.com/oauth2/v3/certs",
 			},
 		},
 	}
@@ -104,6 +166,96 @@ func TestCertRotationConfig_Validate(t *testing.T) {
 				KeyNames:         []string{"fake/key"},
 			},
 		},
+		{
+			name: "pkcs11_signer",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				Signer:           SignerPKCS11,
+				KeyNames:         []string{"jvs-signing"},
+				PKCS11: PKCS11Config{
+					Module:     "/usr/lib/softhsm/libsofthsm2.so",
+					TokenLabel: "jvs",
+					Slot:       -1,
+					PIN:        "1234",
+				},
+			},
+		},
+		{
+			name: "pkcs11_signer_missing_module",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				Signer:           SignerPKCS11,
+				KeyNames:         []string{"jvs-signing"},
+				PKCS11: PKCS11Config{
+					TokenLabel: "jvs",
+					Slot:       -1,
+					PIN:        "1234",
+				},
+			},
+			wantErr: "empty PKCS#11 Module",
+		},
+		{
+			name: "vault_signer",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				Signer:           SignerVault,
+				KeyNames:         []string{"jvs-signing"},
+				Vault: VaultConfig{
+					Address:      "https://vault.example.com:8200",
+					Token:        "s.token",
+					TransitMount: "transit",
+					Timeout:      10 * time.Second,
+				},
+			},
+		},
+		{
+			name: "vault_signer_missing_token",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				Signer:           SignerVault,
+				KeyNames:         []string{"jvs-signing"},
+				Vault: VaultConfig{
+					Address:      "https://vault.example.com:8200",
+					TransitMount: "transit",
+					Timeout:      10 * time.Second,
+				},
+			},
+			wantErr: "empty Vault Token",
+		},
+		{
+			name: "local_signer",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				Signer:           SignerLocal,
+				KeyNames:         []string{"fake/key"},
+			},
+			wantErr: `signer must be "kms", "pkcs11" or "vault", got "local"`,
+		},
 		{
 			name: "empty_project",
 			cfg: &CertRotationConfig{
@@ -116,6 +268,20 @@ func TestCertRotationConfig_Validate(t *testing.T) {
 			},
 			wantErr: "empty ProjectID",
 		},
+		{
+			name: "negative_shutdown_timeout",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				ShutdownTimeout:  -time.Second,
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				KeyNames:         []string{"fake/key"},
+			},
+			wantErr: "shutdown timeout cannot be negative, got -1s",
+		},
 		{
 			name: "empty_key_names",
 			cfg: &CertRotationConfig{
@@ -193,6 +359,251 @@ func TestCertRotationConfig_Validate(t *testing.T) {
 			},
 			wantErr: "must be less than grace period",
 		},
+		{
+			name: "kms_insecure_without_endpoint",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				KeyNames:         []string{"fake/key"},
+				KMSInsecure:      true,
+			},
+			wantErr: "KMSInsecure requires KMSEndpoint to be set",
+		},
+		{
+			name: "invalid_events_topic",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				KeyNames:         []string{"fake/key"},
+				EventsTopic:      "jvs-events",
+			},
+			wantErr: `events topic "jvs-events" must be in the format projects/*/topics/*`,
+		},
+		{
+			name: "negative_destroy_scheduled_duration",
+			cfg: &CertRotationConfig{
+				ProjectID:                "example-project",
+				Port:                     "8080",
+				KeyTTL:                   10 * time.Minute,
+				GracePeriod:              5 * time.Minute,
+				PropagationDelay:         5 * time.Minute,
+				DisabledPeriod:           2 * time.Minute,
+				KeyNames:                 []string{"fake/key"},
+				DestroyScheduledDuration: -time.Minute,
+			},
+			wantErr: `destroy scheduled duration cannot be negative, got "-1m0s"`,
+		},
+		{
+			name: "destroy_scheduled_duration_not_less_than_disabled_period",
+			cfg: &CertRotationConfig{
+				ProjectID:                "example-project",
+				Port:                     "8080",
+				KeyTTL:                   10 * time.Minute,
+				GracePeriod:              5 * time.Minute,
+				PropagationDelay:         5 * time.Minute,
+				DisabledPeriod:           2 * time.Minute,
+				KeyNames:                 []string{"fake/key"},
+				DestroyScheduledDuration: 2 * time.Minute,
+			},
+			wantErr: `destroy scheduled duration "2m0s" must be less than disabled period "2m0s"`,
+		},
+		{
+			name: "destroy_scheduled_duration_without_kms",
+			cfg: &CertRotationConfig{
+				ProjectID:                "example-project",
+				Port:                     "8080",
+				KeyTTL:                   10 * time.Minute,
+				GracePeriod:              5 * time.Minute,
+				PropagationDelay:         5 * time.Minute,
+				DisabledPeriod:           2 * time.Minute,
+				KeyNames:                 []string{"fake/key"},
+				DestroyScheduledDuration: time.Minute,
+				Signer:                   SignerVault,
+				Vault: VaultConfig{
+					Address: "https://vault.example.com:8200",
+					Token:   "s.token",
+				},
+			},
+			wantErr: `destroy scheduled duration is only supported with the "kms" signer`,
+		},
+		{
+			name: "unsupported_key_algorithm",
+			cfg: &CertRotationConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyTTL:           10 * time.Minute,
+				GracePeriod:      5 * time.Minute,
+				PropagationDelay: 5 * time.Minute,
+				DisabledPeriod:   2 * time.Minute,
+				KeyNames:         []string{"fake/key"},
+				KeyAlgorithm:     "RSA_SIGN_PSS_2048_SHA256",
+			},
+			wantErr: `key algorithm must be "EC_SIGN_P256_SHA256", got "RSA_SIGN_PSS_2048_SHA256"`,
+		},
+		{
+			name: "invalid_key_protection_level",
+			cfg: &CertRotationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyTTL:             10 * time.Minute,
+				GracePeriod:        5 * time.Minute,
+				PropagationDelay:   5 * time.Minute,
+				DisabledPeriod:     2 * time.Minute,
+				KeyNames:           []string{"fake/key"},
+				KeyProtectionLevel: "EXTERNAL",
+			},
+			wantErr: `key protection level must be "SOFTWARE" or "HSM", got "EXTERNAL"`,
+		},
+		{
+			name: "key_protection_level_without_kms",
+			cfg: &CertRotationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyTTL:             10 * time.Minute,
+				GracePeriod:        5 * time.Minute,
+				PropagationDelay:   5 * time.Minute,
+				DisabledPeriod:     2 * time.Minute,
+				KeyNames:           []string{"fake/key"},
+				KeyProtectionLevel: ProtectionLevelHSM,
+				Signer:             SignerVault,
+				Vault: VaultConfig{
+					Address: "https://vault.example.com:8200",
+					Token:   "s.token",
+				},
+			},
+			wantErr: `key algorithm and protection level are only supported with the "kms" signer`,
+		},
+		{
+			name: "chatops",
+			cfg: &CertRotationConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyTTL:               10 * time.Minute,
+				GracePeriod:          5 * time.Minute,
+				PropagationDelay:     5 * time.Minute,
+				DisabledPeriod:       2 * time.Minute,
+				KeyNames:             []string{"fake/key"},
+				ChatOpsSigningSecret: "slack-secret",
+				ChatOpsAllowedUsers:  []string{"U012AB3CD"},
+			},
+		},
+		{
+			name: "chatops_without_allowed_users",
+			cfg: &CertRotationConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyTTL:               10 * time.Minute,
+				GracePeriod:          5 * time.Minute,
+				PropagationDelay:     5 * time.Minute,
+				DisabledPeriod:       2 * time.Minute,
+				KeyNames:             []string{"fake/key"},
+				ChatOpsSigningSecret: "slack-secret",
+			},
+			wantErr: "chatops allowed users must be set with the chatops signing secret",
+		},
+		{
+			name: "chatops_allowed_users_without_secret",
+			cfg: &CertRotationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyTTL:              10 * time.Minute,
+				GracePeriod:         5 * time.Minute,
+				PropagationDelay:    5 * time.Minute,
+				DisabledPeriod:      2 * time.Minute,
+				KeyNames:            []string{"fake/key"},
+				ChatOpsAllowedUsers: []string{"U012AB3CD"},
+			},
+			wantErr: "chatops allowed users require the chatops signing secret",
+		},
+		{
+			name: "chatops_without_kms",
+			cfg: &CertRotationConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyTTL:               10 * time.Minute,
+				GracePeriod:          5 * time.Minute,
+				PropagationDelay:     5 * time.Minute,
+				DisabledPeriod:       2 * time.Minute,
+				KeyNames:             []string{"fake/key"},
+				ChatOpsSigningSecret: "slack-secret",
+				ChatOpsAllowedUsers:  []string{"U012AB3CD"},
+				Signer:               SignerVault,
+				Vault: VaultConfig{
+					Address: "https://vault.example.com:8200",
+					Token:   "s.token",
+				},
+			},
+			wantErr: `chatops is only supported with the "kms" signer`,
+		},
+		{
+			name: "trigger_auth",
+			cfg: &CertRotationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyTTL:              10 * time.Minute,
+				GracePeriod:         5 * time.Minute,
+				PropagationDelay:    5 * time.Minute,
+				DisabledPeriod:      2 * time.Minute,
+				KeyNames:            []string{"fake/key"},
+				TriggerAudience:     "https://jvs-cert-rotator.example.com",
+				TriggerPrincipals:   []string{"scheduler@p.iam.gserviceaccount.com"},
+				TriggerIssuer:       "https://accounts.google.com",
+				TriggerJWKSEndpoint: "https://www.googleapis.com/oauth2/v3/certs",
+			},
+		},
+		{
+			name: "trigger_audience_without_principals",
+			cfg: &CertRotationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyTTL:              10 * time.Minute,
+				GracePeriod:         5 * time.Minute,
+				PropagationDelay:    5 * time.Minute,
+				DisabledPeriod:      2 * time.Minute,
+				KeyNames:            []string{"fake/key"},
+				TriggerAudience:     "https://jvs-cert-rotator.example.com",
+				TriggerIssuer:       "https://accounts.google.com",
+				TriggerJWKSEndpoint: "https://www.googleapis.com/oauth2/v3/certs",
+			},
+			wantErr: "trigger principals must be set with the trigger audience",
+		},
+		{
+			name: "trigger_audience_without_issuer",
+			cfg: &CertRotationConfig{
+				ProjectID:         "example-project",
+				Port:              "8080",
+				KeyTTL:            10 * time.Minute,
+				GracePeriod:       5 * time.Minute,
+				PropagationDelay:  5 * time.Minute,
+				DisabledPeriod:    2 * time.Minute,
+				KeyNames:          []string{"fake/key"},
+				TriggerAudience:   "https://jvs-cert-rotator.example.com",
+				TriggerPrincipals: []string{"scheduler@p.iam.gserviceaccount.com"},
+			},
+			wantErr: "trigger issuer and jwks endpoint must be set with the trigger audience",
+		},
+		{
+			name: "trigger_principals_without_audience",
+			cfg: &CertRotationConfig{
+				ProjectID:         "example-project",
+				Port:              "8080",
+				KeyTTL:            10 * time.Minute,
+				GracePeriod:       5 * time.Minute,
+				PropagationDelay:  5 * time.Minute,
+				DisabledPeriod:    2 * time.Minute,
+				KeyNames:          []string{"fake/key"},
+				TriggerPrincipals: []string{"scheduler@p.iam.gserviceaccount.com"},
+			},
+			wantErr: "trigger principals require the trigger audience",
+		},
 	}
 
 	for _, tc := range cases {
@@ -240,3 +651,21 @@ func TestDestroyAge(t *testing.T) {
 		t.Errorf("unexpected destroy age. Want: %s, but got: %s\n", expected, cfg.DestroyAge())
 	}
 }
+
+func TestDestroyRequestAge(t *testing.T) {
+	t.Parallel()
+
+	cfg := &CertRotationConfig{
+		KeyTTL:                   720 * time.Hour, // 30 days
+		GracePeriod:              2 * time.Hour,   // 2 hours
+		DisabledPeriod:           720 * time.Hour, // 30 days
+		DestroyScheduledDuration: 24 * time.Hour,  // 1 day
+	}
+	expected, err := time.ParseDuration("1416h") // 59 days
+	if err != nil {
+		t.Error("Couldn't parse duration")
+	}
+	if expected != cfg.DestroyRequestAge() {
+		t.Errorf("unexpected destroy request age. Want: %s, but got: %s\n", expected, cfg.DestroyRequestAge())
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// organizationIDRegexp matches Google Cloud organization IDs.
+var organizationIDRegexp = regexp.MustCompile(`^[0-9]+$`)
+
+// CloudSupportConfig is the config of the Google Cloud support case validator
+// plugin.
+type CloudSupportConfig struct {
+	// OrganizationID is the ID of the Google Cloud organization that support
+	// cases must belong to.
+	OrganizationID string `env:"JVS_CLOUD_SUPPORT_ORGANIZATION_ID,overwrite"`
+
+	// Endpoint overrides the Cloud Support API endpoint, e.g. for testing.
+	Endpoint string `env:"JVS_CLOUD_SUPPORT_ENDPOINT,overwrite"`
+}
+
+// Validate checks if the config is valid.
+func (cfg *CloudSupportConfig) Validate() (merr error) {
+	if cfg.OrganizationID == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty OrganizationID"))
+	} else if !organizationIDRegexp.MatchString(cfg.OrganizationID) {
+		merr = errors.Join(merr, fmt.Errorf("OrganizationID %q must be numeric", cfg.OrganizationID))
+	}
+
+	return
+}
+
+// ToFlags binds the config to the give [cli.FlagSet] and returns it.
+func (cfg *CloudSupportConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
+	f := set.NewSection("CLOUD SUPPORT OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "organization-id",
+		Target:  &cfg.OrganizationID,
+		EnvVar:  "JVS_CLOUD_SUPPORT_ORGANIZATION_ID",
+		Example: "123456789012",
+		Usage:   `The ID of the Google Cloud organization support cases must belong to.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "endpoint",
+		Target:  &cfg.Endpoint,
+		EnvVar:  "JVS_CLOUD_SUPPORT_ENDPOINT",
+		Example: "https://cloudsupport.googleapis.com/",
+		Usage:   `Override the Cloud Support API endpoint.`,
+	})
+
+	return set
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCloudSupportConfig_ToFlags(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		envs       map[string]string
+		wantConfig *CloudSupportConfig
+	}{
+		{
+			name: "all_values_specified",
+			envs: map[string]string{
+				"JVS_CLOUD_SUPPORT_ORGANIZATION_ID": "123456789012",
+				"JVS_CLOUD_SUPPORT_ENDPOINT":        "http://localhost:8080/",
+			},
+			wantConfig: &CloudSupportConfig{
+				OrganizationID: "123456789012",
+				Endpoint:       "http://localhost:8080/",
+			},
+		},
+		{
+			name:       "default_values",
+			wantConfig: &CloudSupportConfig{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotConfig := &CloudSupportConfig{}
+			set := cli.NewFlagSet(cli.WithLookupEnv(cli.MapLookuper(tc.envs)))
+			set = gotConfig.ToFlags(set)
+			if err := set.Parse([]string{}); err != nil {
+				t.Errorf("unexpected flag set parse error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantConfig, gotConfig); diff != "" {
+				t.Errorf("Config unexpected diff (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCloudSupportConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *CloudSupportConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg: &CloudSupportConfig{
+				OrganizationID: "123456789012",
+			},
+		},
+		{
+			name:    "empty_organization_id",
+			cfg:     &CloudSupportConfig{},
+			wantErr: "empty OrganizationID",
+		},
+		{
+			name: "invalid_organization_id",
+			cfg: &CloudSupportConfig{
+				OrganizationID: "organizations/123456789012",
+			},
+			wantErr: `OrganizationID "organizations/123456789012" must be numeric`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
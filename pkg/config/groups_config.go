@@ -0,0 +1,259 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+const (
+	// GroupsSourceCloudIdentity resolves groups with the Cloud Identity Groups
+	// API.
+	GroupsSourceCloudIdentity = "cloudidentity"
+
+	// GroupsSourceLDAP resolves groups with an LDAP directory.
+	GroupsSourceLDAP = "ldap"
+)
+
+// GroupsConfig is the configuration of the resolution of the requestor's group
+// memberships, which are embedded in the "groups" claim of tokens.
+type GroupsConfig struct {
+	// Source is where groups are resolved, either GroupsSourceCloudIdentity or
+	// GroupsSourceLDAP. Groups are not resolved if it is empty.
+	Source string `env:"JVS_GROUPS_SOURCE,overwrite"`
+
+	// Filter is an RE2 regular expression groups must match to be embedded,
+	// e.g. "^jvs-.*@example.com$", so tokens only carry the groups downstream
+	// services authorize on.
+	Filter string `env:"JVS_GROUPS_FILTER,overwrite"`
+
+	// MaxGroups is the maximum number of groups embedded in a token, to bound
+	// its size. Groups over the limit are dropped, in alphabetical order.
+	MaxGroups int `env:"JVS_GROUPS_MAX,overwrite,default=50"`
+
+	// Timeout is the timeout of resolving a requestor's groups.
+	Timeout time.Duration `env:"JVS_GROUPS_TIMEOUT,overwrite,default=5s"`
+
+	// CacheTTL is how long a requestor's groups are cached. Membership changes
+	// take up to the TTL to be reflected in tokens. If it is 0, groups are
+	// resolved on every request.
+	CacheTTL time.Duration `env:"JVS_GROUPS_CACHE_TTL,overwrite,default=5m"`
+
+	// LDAPURL is the URL of the LDAP server with GroupsSourceLDAP, e.g.
+	// "ldaps://ldap.example.com".
+	LDAPURL string `env:"JVS_GROUPS_LDAP_URL,overwrite"`
+
+	// LDAPBindDN and LDAPBindPassword are the credentials to bind to the LDAP
+	// server with. The server is searched anonymously if they are empty.
+	LDAPBindDN       string `env:"JVS_GROUPS_LDAP_BIND_DN,overwrite"`
+	LDAPBindPassword string `json:"-" env:"JVS_GROUPS_LDAP_BIND_PASSWORD,overwrite"`
+
+	// LDAPBaseDN is the DN users and groups are searched under.
+	LDAPBaseDN string `env:"JVS_GROUPS_LDAP_BASE_DN,overwrite"`
+
+	// LDAPUserFilter finds the requestor's entry, "{email}" is replaced with
+	// the requestor's email.
+	LDAPUserFilter string `env:"JVS_GROUPS_LDAP_USER_FILTER,overwrite,default=(mail={email})"`
+
+	// LDAPGroupFilter finds the groups the requestor is a member of, "{dn}" is
+	// replaced with the DN of the requestor's entry.
+	LDAPGroupFilter string `env:"JVS_GROUPS_LDAP_GROUP_FILTER,overwrite,default=(member={dn})"`
+
+	// LDAPGroupAttribute is the attribute of group entries embedded in tokens.
+	LDAPGroupAttribute string `env:"JVS_GROUPS_LDAP_GROUP_ATTRIBUTE,overwrite,default=cn"`
+}
+
+// Validate checks if the config is valid.
+func (cfg *GroupsConfig) Validate() (merr error) {
+	switch cfg.Source {
+	case "":
+		return nil
+	case GroupsSourceCloudIdentity:
+	case GroupsSourceLDAP:
+		merr = errors.Join(merr, cfg.validateLDAP())
+	default:
+		merr = errors.Join(merr, fmt.Errorf("groups source must be %q or %q, got %q",
+			GroupsSourceCloudIdentity, GroupsSourceLDAP, cfg.Source))
+	}
+
+	if _, err := cfg.FilterRegexp(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if got := cfg.MaxGroups; got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("max groups must be positive, got %d", got))
+	}
+
+	if got := cfg.Timeout; got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("groups timeout must be a positive duration, got %s", got))
+	}
+
+	if got := cfg.CacheTTL; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("groups cache ttl cannot be negative, got %s", got))
+	}
+
+	return
+}
+
+func (cfg *GroupsConfig) validateLDAP() (merr error) {
+	if cfg.LDAPURL == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty LDAPURL"))
+	} else if u, err := url.Parse(cfg.LDAPURL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		merr = errors.Join(merr, fmt.Errorf("LDAPURL %q must be an ldap:// or ldaps:// URL", cfg.LDAPURL))
+	}
+
+	if cfg.LDAPBaseDN == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty LDAPBaseDN"))
+	}
+
+	if (cfg.LDAPBindDN == "") != (cfg.LDAPBindPassword == "") {
+		merr = errors.Join(merr, fmt.Errorf("LDAPBindDN and LDAPBindPassword must be set together"))
+	}
+
+	if !strings.Contains(cfg.LDAPUserFilter, "{email}") {
+		merr = errors.Join(merr, fmt.Errorf("LDAPUserFilter %q must contain {email}", cfg.LDAPUserFilter))
+	}
+
+	if !strings.Contains(cfg.LDAPGroupFilter, "{dn}") {
+		merr = errors.Join(merr, fmt.Errorf("LDAPGroupFilter %q must contain {dn}", cfg.LDAPGroupFilter))
+	}
+
+	if cfg.LDAPGroupAttribute == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty LDAPGroupAttribute"))
+	}
+
+	return
+}
+
+// FilterRegexp returns the compiled Filter, or nil if it is empty.
+func (cfg *GroupsConfig) FilterRegexp() (*regexp.Regexp, error) {
+	if cfg.Filter == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(cfg.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("groups filter is invalid: %w", err)
+	}
+	return re, nil
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *GroupsConfig) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("GROUPS OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-source",
+		Target:  &cfg.Source,
+		EnvVar:  "JVS_GROUPS_SOURCE",
+		Example: "cloudidentity",
+		Usage: `Where to resolve the requestor's groups to embed in tokens, ` +
+			`"cloudidentity" or "ldap". Groups are not resolved if empty.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-filter",
+		Target:  &cfg.Filter,
+		EnvVar:  "JVS_GROUPS_FILTER",
+		Example: "^jvs-.*@example.com$",
+		Usage:   `Only embed the groups matching this RE2 regular expression.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "groups-max",
+		Target:  &cfg.MaxGroups,
+		EnvVar:  "JVS_GROUPS_MAX",
+		Default: 50,
+		Usage:   `The maximum number of groups embedded in a token.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "groups-timeout",
+		Target:  &cfg.Timeout,
+		EnvVar:  "JVS_GROUPS_TIMEOUT",
+		Default: 5 * time.Second,
+		Usage:   `The timeout of resolving the requestor's groups.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "groups-cache-ttl",
+		Target:  &cfg.CacheTTL,
+		EnvVar:  "JVS_GROUPS_CACHE_TTL",
+		Default: 5 * time.Minute,
+		Usage:   `How long to cache the requestor's groups. 0 disables caching.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-ldap-url",
+		Target:  &cfg.LDAPURL,
+		EnvVar:  "JVS_GROUPS_LDAP_URL",
+		Example: "ldaps://ldap.example.com",
+		Usage:   `The URL of the LDAP server, with the "ldap" groups source.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-ldap-bind-dn",
+		Target:  &cfg.LDAPBindDN,
+		EnvVar:  "JVS_GROUPS_LDAP_BIND_DN",
+		Example: "cn=jvs,ou=services,dc=example,dc=com",
+		Usage:   `The DN to bind to the LDAP server with.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "groups-ldap-bind-password",
+		Target: &cfg.LDAPBindPassword,
+		EnvVar: "JVS_GROUPS_LDAP_BIND_PASSWORD",
+		Usage:  `The password of the bind DN. Prefer setting it in the environment.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-ldap-base-dn",
+		Target:  &cfg.LDAPBaseDN,
+		EnvVar:  "JVS_GROUPS_LDAP_BASE_DN",
+		Example: "dc=example,dc=com",
+		Usage:   `The DN users and groups are searched under.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-ldap-user-filter",
+		Target:  &cfg.LDAPUserFilter,
+		EnvVar:  "JVS_GROUPS_LDAP_USER_FILTER",
+		Default: "(mail={email})",
+		Usage:   `The filter of the requestor's entry, {email} is the requestor's email.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-ldap-group-filter",
+		Target:  &cfg.LDAPGroupFilter,
+		EnvVar:  "JVS_GROUPS_LDAP_GROUP_FILTER",
+		Default: "(member={dn})",
+		Usage:   `The filter of the requestor's groups, {dn} is the DN of the requestor's entry.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "groups-ldap-group-attribute",
+		Target:  &cfg.LDAPGroupAttribute,
+		EnvVar:  "JVS_GROUPS_LDAP_GROUP_ATTRIBUTE",
+		Default: "cn",
+		Usage:   `The attribute of group entries embedded in tokens.`,
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// defaultGroupsConfig is the groups config with the default flag values.
+var defaultGroupsConfig = GroupsConfig{
+	MaxGroups:          50,
+	Timeout:            5 * time.Second,
+	CacheTTL:           5 * time.Minute,
+	LDAPUserFilter:     "(mail={email})",
+	LDAPGroupFilter:    "(member={dn})",
+	LDAPGroupAttribute: "cn",
+}
+
+func TestGroupsConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *GroupsConfig
+		wantErr string
+	}{
+		{
+			name: "disabled",
+			cfg:  &GroupsConfig{},
+		},
+		{
+			name: "cloud_identity",
+			cfg: &GroupsConfig{
+				Source:    GroupsSourceCloudIdentity,
+				Filter:    "^jvs-.*@example.com$",
+				MaxGroups: 50,
+				Timeout:   5 * time.Second,
+				CacheTTL:  5 * time.Minute,
+			},
+		},
+		{
+			name: "ldap",
+			cfg: &GroupsConfig{
+				Source:             GroupsSourceLDAP,
+				MaxGroups:          50,
+				Timeout:            5 * time.Second,
+				LDAPURL:            "ldaps://ldap.example.com",
+				LDAPBindDN:         "cn=jvs,dc=example,dc=com",
+				LDAPBindPassword:   "password",
+				LDAPBaseDN:         "dc=example,dc=com",
+				LDAPUserFilter:     "(mail={email})",
+				LDAPGroupFilter:    "(member={dn})",
+				LDAPGroupAttribute: "cn",
+			},
+		},
+		{
+			name: "unknown_source",
+			cfg: &GroupsConfig{
+				Source:    "workday",
+				MaxGroups: 50,
+				Timeout:   5 * time.Second,
+			},
+			wantErr: `groups source must be "cloudidentity" or "ldap", got "workday"`,
+		},
+		{
+			name: "invalid_values",
+			cfg: &GroupsConfig{
+				Source:   GroupsSourceCloudIdentity,
+				Filter:   "(",
+				CacheTTL: -time.Minute,
+			},
+			wantErr: "groups filter is invalid: error parsing regexp: missing closing ): `(`\n" +
+				"max groups must be positive, got 0\n" +
+				"groups timeout must be a positive duration, got 0s\n" +
+				"groups cache ttl cannot be negative, got -1m0s",
+		},
+		{
+			name: "invalid_ldap",
+			cfg: &GroupsConfig{
+				Source:         GroupsSourceLDAP,
+				MaxGroups:      50,
+				Timeout:        5 * time.Second,
+				LDAPURL:        "https://ldap.example.com",
+				LDAPBindDN:     "cn=jvs,dc=example,dc=com",
+				LDAPUserFilter: "(mail=me@example.com)",
+			},
+			wantErr: `LDAPURL "https://ldap.example.com" must be an ldap:// or ldaps:// URL` + "\n" +
+				"empty LDAPBaseDN\n" +
+				"LDAPBindDN and LDAPBindPassword must be set together\n" +
+				`LDAPUserFilter "(mail=me@example.com)" must contain {email}` + "\n" +
+				`LDAPGroupFilter "" must contain {dn}` + "\n" +
+				"empty LDAPGroupAttribute",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
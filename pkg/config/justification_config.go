@@ -18,12 +18,91 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/abcxyz/pkg/cli"
 	"github.com/abcxyz/pkg/timeutil"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 )
 
+const (
+	// SignerKMS signs tokens with Cloud KMS keys.
+	SignerKMS = "kms"
+
+	// SignerLocal signs tokens with a private key on disk, and serves public
+	// keys from disk, without any cloud dependencies.
+	SignerLocal = "local"
+
+	// SignerPKCS11 signs tokens with keys in an HSM, through its PKCS#11
+	// module.
+	SignerPKCS11 = "pkcs11"
+
+	// SignerVault signs tokens with keys in the HashiCorp Vault transit secrets
+	// engine.
+	SignerVault = "vault"
+)
+
+const (
+	// KMSPermissionCheckOff skips the startup check of the KMS permissions of
+	// the service account.
+	KMSPermissionCheckOff = "off"
+
+	// KMSPermissionCheckWarn logs missing KMS permissions at startup.
+	KMSPermissionCheckWarn = "warn"
+
+	// KMSPermissionCheckFail fails startup if KMS permissions are missing.
+	KMSPermissionCheckFail = "fail"
+)
+
+// minDeprovisionWebhookSecretLength is the minimum length of the bearer token
+// of the deprovision webhook, so it can't be guessed.
+const minDeprovisionWebhookSecretLength = 32
+
+// validateKMSPermissionCheck checks the mode of the startup KMS permission
+// check is known. An empty mode is KMSPermissionCheckWarn.
+func validateKMSPermissionCheck(mode string) error {
+	switch mode {
+	case "", KMSPermissionCheckOff, KMSPermissionCheckWarn, KMSPermissionCheckFail:
+		return nil
+	default:
+		return fmt.Errorf("kms permission check must be one of %q, %q or %q, got %q",
+			KMSPermissionCheckOff, KMSPermissionCheckWarn, KMSPermissionCheckFail, mode)
+	}
+}
+
+// AudienceTemplateService is the placeholder in audience templates that is
+// replaced with the service name.
+const AudienceTemplateService = "{service}"
+
+// bigQueryTableRegexp matches BigQuery table IDs, "project.dataset.table".
+var bigQueryTableRegexp = regexp.MustCompile(`^[a-z][a-z0-9.:-]*\.[A-Za-z0-9_]+\.[A-Za-z0-9_-]+$`)
+
+// spannerDatabaseRegexp matches Spanner database names.
+var spannerDatabaseRegexp = regexp.MustCompile(`^projects/[^/]+/instances/[^/]+/databases/[^/]+$`)
+
+// auditLogIDRegexp matches Cloud Logging log IDs, e.g.
+// "audit.abcxyz/data_access".
+var auditLogIDRegexp = regexp.MustCompile(`^[A-Za-z0-9/_.-]{1,512}$`)
+
+// reservedClaims are the claims of minted tokens that claim aliases can't
+// overwrite.
+var reservedClaims = []string{
+	"aud", "exp", "iat", "iss", "jti", "nbf", "sub",
+	jvspb.AuditRouteKey,
+	jvspb.ConfirmationKey,
+	jvspb.EncryptedJustificationsKey,
+	jvspb.GroupsKey,
+	jvspb.JustificationsKey,
+	jvspb.RequestorKey,
+	jvspb.VersionKey,
+}
+
 // JustificationConfig is the full jvs config.
 type JustificationConfig struct {
 	// ProjectID is the Google Cloud project ID.
@@ -32,13 +111,99 @@ type JustificationConfig struct {
 	// Service configuration.
 	Port string `yaml:"port,omitempty" env:"PORT,overwrite,default=8080"`
 
+	// ShutdownTimeout is how long the server waits for in-flight requests to
+	// finish on shutdown, before abandoning them. If it is 0, the server stops
+	// right away.
+	ShutdownTimeout time.Duration `env:"JVS_SHUTDOWN_TIMEOUT,overwrite,default=30s"`
+
+	// WarmupTimeout is how long the server retries loading its caches on
+	// startup, before it starts serving. If it is 0, the server starts right
+	// away, and loads them on the first request.
+	WarmupTimeout time.Duration `env:"JVS_WARMUP_TIMEOUT,overwrite,default=30s"`
+
 	// DevMode enables more granular debugging in logs.
 	DevMode bool `env:"DEV_MODE,default=false"`
 
+	// Signer is where tokens are signed, either SignerKMS, SignerLocal,
+	// SignerPKCS11 or SignerVault.
+	Signer string `env:"JVS_SIGNER,overwrite,default=kms"`
+
 	// KeyName format: `projects/*/locations/*/keyRings/*/cryptoKeys/*`
 	// https://pkg.go.dev/google.golang.org/genproto/googleapis/cloud/kms/v1#CryptoKey
+	// It is required with SignerKMS. With SignerPKCS11, it is the label of the
+	// key in the HSM, and with SignerVault, the name of the transit key.
 	KeyName string `env:"JVS_KEY,overwrite"`
 
+	// KeyPath is the path of the PEM encoded ECDSA P-256 private key to sign
+	// tokens with. It is required with SignerLocal.
+	KeyPath string `env:"JVS_KEY_PATH,overwrite"`
+
+	// PKCS11 is the HSM the key is in with SignerPKCS11.
+	PKCS11 PKCS11Config
+
+	// Vault is the Vault server the key is in with SignerVault.
+	Vault VaultConfig
+
+	// KMSEndpoint overrides the Cloud KMS API endpoint, e.g. to point at a local
+	// KMS emulator. KMSInsecure disables TLS and authentication for that
+	// endpoint.
+	KMSEndpoint string `env:"JVS_KMS_ENDPOINT,overwrite"`
+	KMSInsecure bool   `env:"JVS_KMS_INSECURE,overwrite,default=false"`
+
+	// KMSPermissionCheck is whether the permissions of the service account on
+	// the KMS keys are checked at startup: KMSPermissionCheckOff,
+	// KMSPermissionCheckWarn or KMSPermissionCheckFail.
+	KMSPermissionCheck string `env:"JVS_KMS_PERMISSION_CHECK,overwrite,default=warn"`
+
+	// MaxConcurrentRequests is the maximum number of in-flight justification
+	// requests. Requests over the limit fail with RESOURCE_EXHAUSTED, so a
+	// thundering herd can't exhaust the server while KMS or plugins are slow.
+	// There is no limit if it is 0.
+	MaxConcurrentRequests int `env:"JVS_API_MAX_CONCURRENT_REQUESTS,overwrite,default=0"`
+
+	// RequestDeadline is the maximum time to process a justification request,
+	// including validation by plugins, getting the signer from KMS and signing.
+	// Requests that take longer fail with DEADLINE_EXCEEDED and the stage that
+	// timed out. There is no deadline if it is 0.
+	RequestDeadline time.Duration `env:"JVS_API_REQUEST_DEADLINE,overwrite,default=10s"`
+
+	// AuditLogProject, if set, is the project data access audit logs of
+	// requests are written to, in the abcxyz/lumberjack log.
+	AuditLogProject string `env:"JVS_API_AUDIT_LOG_PROJECT,overwrite"`
+
+	// AuditRouteProject, if set, is the project services should write the data
+	// access audit logs of requests made with tokens to, e.g. with the
+	// abcxyz/lumberjack client SDK. It and AuditRouteLogID are embedded in the
+	// "audit_route" claim of tokens.
+	AuditRouteProject string `env:"JVS_API_AUDIT_ROUTE_PROJECT,overwrite"`
+	AuditRouteLogID   string `env:"JVS_API_AUDIT_ROUTE_LOG_ID,overwrite,default=audit.abcxyz/data_access"`
+
+	// EventsTopic, if set, is the Pub/Sub topic token events are published to,
+	// in the format `projects/*/topics/*`.
+	EventsTopic string `env:"JVS_EVENTS_TOPIC,overwrite"`
+
+	// AuditBigQueryTable, if set, is the BigQuery table the audit entries of
+	// issued tokens are streamed to, in the format `project.dataset.table`. The
+	// table is created if it does not exist.
+	AuditBigQueryTable string `env:"JVS_API_AUDIT_BIGQUERY_TABLE,overwrite"`
+
+	// AuditPostgresURL, if set, is the connection URL of the Postgres database
+	// the audit entries of issued tokens are written to, such as a Cloud SQL
+	// instance. The schema is migrated on startup.
+	AuditPostgresURL string `env:"JVS_API_AUDIT_POSTGRES_URL,overwrite"`
+
+	// AuditSpannerDatabase, if set, is the Spanner database the audit entries of
+	// issued tokens are written to, in the format
+	// `projects/*/instances/*/databases/*`. The tables are created if they do
+	// not exist.
+	AuditSpannerDatabase string `env:"JVS_API_AUDIT_SPANNER_DATABASE,overwrite"`
+
+	// AuditSnapshotPath, if set, is the file the audit entries of issued tokens
+	// are kept in by the in-memory audit sink, which is used in dev mode when no
+	// other audit sink is set. Setting it enables the in-memory sink outside of
+	// dev mode too.
+	AuditSnapshotPath string `env:"JVS_API_AUDIT_SNAPSHOT_PATH,overwrite"`
+
 	// SignerCacheTimeout is the duration that keys stay in cache before being revoked.
 	SignerCacheTimeout time.Duration `env:"JVS_API_SIGNER_CACHE_TIMEOUT,overwrite,default=5m"`
 
@@ -48,6 +213,176 @@ type JustificationConfig struct {
 	// PluginDir is the path of the directory to load plugins.
 	PluginDir string `env:"JVS_PLUGIN_DIR,overwrite,default=/var/jvs/plugins"`
 
+	// PluginSealedConfigs are the files with the KMS encrypted configs of the
+	// plugins in PluginDir, in the format "name=path". Each plugin is passed its
+	// ciphertext and PluginConfigKey, and decrypts the config itself.
+	PluginSealedConfigs []string `env:"JVS_PLUGIN_SEALED_CONFIGS,overwrite"`
+
+	// PluginConfigKey is the KMS key that the sealed plugin configs are
+	// encrypted with.
+	PluginConfigKey string `env:"JVS_PLUGIN_CONFIG_KEY,overwrite"`
+
+	// RemotePlugins are validator plugins served over gRPC by other services, in
+	// the format "category=host:port". Unlike the plugins in PluginDir, which run
+	// as subprocesses, remote plugins can be shared across JVS replicas.
+	RemotePlugins []string `env:"JVS_REMOTE_PLUGINS,overwrite"`
+
+	// RemotePluginCAFile is the PEM encoded CA bundle to verify remote plugin
+	// servers with. If unset, the system roots are used. RemotePluginCertFile
+	// and RemotePluginKeyFile are the PEM encoded client certificate and key to
+	// authenticate to remote plugins with (mTLS).
+	RemotePluginCAFile   string `env:"JVS_REMOTE_PLUGIN_CA_FILE,overwrite"`
+	RemotePluginCertFile string `env:"JVS_REMOTE_PLUGIN_CERT_FILE,overwrite"`
+	RemotePluginKeyFile  string `env:"JVS_REMOTE_PLUGIN_KEY_FILE,overwrite"`
+
+	// RemotePluginInsecure connects to remote plugins without TLS, e.g. for
+	// local development.
+	RemotePluginInsecure bool `env:"JVS_REMOTE_PLUGIN_INSECURE,overwrite,default=false"`
+
+	// ApproverValidator enables the built-in "approver" justification category,
+	// whose value is the email of a manager or group member of the requestor
+	// who approved the request. It is checked against the Google Workspace
+	// directory. If ApproverGroups are set, only those groups count as shared
+	// groups.
+	ApproverValidator bool     `env:"JVS_APPROVER_VALIDATOR,overwrite,default=false"`
+	ApproverGroups    []string `env:"JVS_APPROVER_GROUPS,overwrite"`
+
+	// DenyListPath, if set, is the path of a file listing principals who may
+	// not mint tokens or be named as their subjects, one per line. The file is
+	// reloaded every DenyListReloadInterval, so principals can be denied
+	// without a restart.
+	DenyListPath           string        `env:"JVS_API_DENY_LIST_PATH,overwrite"`
+	DenyListReloadInterval time.Duration `env:"JVS_API_DENY_LIST_RELOAD_INTERVAL,overwrite,default=30s"`
+
+	// MaintenanceMode makes the JVS refuse to issue tokens, certificates and
+	// signatures with an Unavailable error, e.g. during key migrations or
+	// incidents. Public keys and categories are still served, so issued tokens
+	// can still be validated. MaintenanceMessage is returned to callers, to
+	// tell them why and for how long.
+	MaintenanceMode    bool   `env:"JVS_API_MAINTENANCE_MODE,overwrite,default=false"`
+	MaintenanceMessage string `env:"JVS_API_MAINTENANCE_MESSAGE,overwrite"`
+
+	// PolicyPath, if set, is the path of the JSON file of the runtime policy:
+	// the disabled categories, the TTL caps of categories, and the audience
+	// allowlist. It is created when the policy is first changed. The file is
+	// reloaded every PolicyReloadInterval, so replicas sharing it, e.g. on a
+	// Cloud Storage volume, apply changes made through any of them.
+	PolicyPath           string        `env:"JVS_API_POLICY_PATH,overwrite"`
+	PolicyReloadInterval time.Duration `env:"JVS_API_POLICY_RELOAD_INTERVAL,overwrite,default=30s"`
+
+	// RegoPolicyPath, if set, is the path of a Rego policy evaluated with every
+	// token request, which can deny it, or change its TTL and audiences. The
+	// file is reloaded every RegoPolicyReloadInterval, so it can be updated on
+	// a Cloud Storage volume without a restart. It must be a local path, gs://
+	// URLs aren't supported.
+	RegoPolicyPath           string        `env:"JVS_API_REGO_POLICY_PATH,overwrite"`
+	RegoPolicyReloadInterval time.Duration `env:"JVS_API_REGO_POLICY_RELOAD_INTERVAL,overwrite,default=30s"`
+
+	// PolicyExpression, if set, is a CEL expression token requests must
+	// satisfy, a lighter alternative to a Rego policy, e.g.
+	// `ttl <= duration("1h") || !("breakglass" in categories)`. Requests that
+	// don't are denied before their justifications are validated.
+	PolicyExpression string `env:"JVS_API_POLICY_EXPRESSION,overwrite"`
+
+	// DecisionTraceRetention, if set, is how long the traces of denied token
+	// requests are kept, to look them up with the admin API by the request ID
+	// returned with their errors. Traces are kept in memory, or in
+	// DecisionTraceDir if set, e.g. on a Cloud Storage volume shared by all
+	// replicas.
+	DecisionTraceRetention time.Duration `env:"JVS_API_DECISION_TRACE_RETENTION,overwrite"`
+	DecisionTraceDir       string        `env:"JVS_API_DECISION_TRACE_DIR,overwrite"`
+
+	// AdminPort, if set, is the port of a separate listener serving the
+	// AdminService, which changes the runtime policy without a restart. Callers
+	// must present an ID token from AdminIssuer, verified with the keys at
+	// AdminJWKSEndpoint, whose audience is AdminAudience and whose email is one
+	// of AdminPrincipals. Changes are persisted to PolicyPath.
+	AdminPort         string   `env:"JVS_API_ADMIN_PORT,overwrite"`
+	AdminPrincipals   []string `env:"JVS_API_ADMIN_PRINCIPALS,overwrite"`
+	AdminAudience     string   `env:"JVS_API_ADMIN_AUDIENCE,overwrite"`
+	AdminIssuer       string   `env:"JVS_API_ADMIN_ISSUER,overwrite,default=https://accounts.google.com"`
+	AdminJWKSEndpoint string   `env:"JVS_API_ADMIN_JWKS_ENDPOINT,overwrite,default=https://www.googleapis.com/oauth2/v3/certs"`
+
+	// Reflection registers gRPC server reflection on the public port. It is off
+	// by default, so the APIs aren't open to discovery in production. The API
+	// descriptors are served by the admin api's GetAPIDescriptors instead.
+	Reflection bool `env:"JVS_API_REFLECTION,overwrite,default=false"`
+
+	// DeprovisionWebhookPort, if set, is the port of a separate HTTP listener
+	// that receives deprovisioning webhooks and SCIM requests from the identity
+	// provider, and deprovisions the principal like the admin api's
+	// DeprovisionUser. Callers must send DeprovisionWebhookSecret as a bearer
+	// token.
+	DeprovisionWebhookPort   string `env:"JVS_API_DEPROVISION_WEBHOOK_PORT,overwrite"`
+	DeprovisionWebhookSecret string `json:"-" env:"JVS_API_DEPROVISION_WEBHOOK_SECRET,overwrite"`
+
+	// Receipts makes the JVS return a signed receipt of each issued token, with
+	// its ID, requestor, categories, annotations and approver, for attaching
+	// to change tickets. Receipts are also recorded in the audit log.
+	Receipts bool `env:"JVS_API_RECEIPTS,overwrite,default=false"`
+
+	// TransparencyLogURL is the URL of a Rekor transparency log, e.g.
+	// "https://rekor.sigstore.dev". If set, the digest of the audit entry of
+	// each issued token is published to the log, and the log's inclusion proof
+	// is recorded in the audit entry, so tampering with the audit log can be
+	// detected. Publishing waits up to TransparencyLogTimeout.
+	TransparencyLogURL     string        `env:"JVS_API_TRANSPARENCY_LOG_URL,overwrite"`
+	TransparencyLogTimeout time.Duration `env:"JVS_API_TRANSPARENCY_LOG_TIMEOUT,overwrite,default=5s"`
+
+	// Groups resolves the requestor's group memberships to embed in tokens.
+	Groups GroupsConfig
+
+	// Revocation is where deprovisioned principals are stored. They can't mint
+	// tokens on any replica, and the tokens they were issued before are revoked
+	// for verifiers that check the revocation list.
+	Revocation RevocationConfig
+
+	// Quota limits how many tokens each principal can mint per category per
+	// day.
+	Quota QuotaConfig
+
+	// PAM files Privileged Access Manager grants for tokens with justifications
+	// of the configured categories.
+	PAM PAMConfig
+
+	// Canary periodically mints a canary token and validates it against the
+	// JWKS endpoint, to catch broken signing or key distribution.
+	Canary CanaryConfig
+
+	// CategoryAliases map deprecated category names to their new names, in the
+	// format "old=new", so categories can be renamed without breaking existing
+	// requestors. Justifications with a deprecated category are validated and
+	// issued as the new category, with a warning annotation.
+	CategoryAliases []string `env:"JVS_API_CATEGORY_ALIASES,overwrite"`
+
+	// ShadowCategories are validated in shadow mode: their validators are
+	// called and the results logged, but failures never block issuance.
+	ShadowCategories []string `env:"JVS_API_SHADOW_CATEGORIES,overwrite"`
+
+	// ValidationCache enables caching of successful validation results for a
+	// category, in the format "category=ttl". Results are cached per
+	// justification value and requestor, so repeated requests against the same
+	// ticket don't call the validator's backend every time.
+	ValidationCache []string `env:"JVS_API_VALIDATION_CACHE,overwrite"`
+
+	// ValuePatterns are the RE2 regular expressions the justification values of
+	// a category must match, in the format "category=pattern", e.g.
+	// "jira=^[A-Z]+-\d+$". They override the patterns plugins declare in their
+	// UI data. Patterns cannot contain commas.
+	ValuePatterns []string `env:"JVS_API_VALUE_PATTERNS,overwrite"`
+
+	// RequiredAnnotations are annotations validators must set on
+	// justifications of a category, in the format "category=annotation", e.g.
+	// "jira=jira_issue_url". Tokens aren't issued if a validator drops one, so
+	// verifiers whose policy depends on it can rely on it being present.
+	RequiredAnnotations []string `env:"JVS_API_REQUIRED_ANNOTATIONS,overwrite"`
+
+	// MaxAnnotationSize is the maximum total size, in bytes, of the annotations
+	// validators add to a request's justifications. Requests with larger
+	// annotations are rejected, so a misbehaving plugin can't bloat tokens past
+	// the header limits of downstream proxies.
+	MaxAnnotationSize int `env:"JVS_API_MAX_ANNOTATION_SIZE,overwrite,default=2000"`
+
 	// DefaultTTL sets the default TTL for JVS tokens that do not explicitly
 	// request a TTL. MaxTTL is the system-configured maximum TTL that a token can
 	// request.
@@ -55,6 +390,86 @@ type JustificationConfig struct {
 	// The DefaultTTL must be less than or equal to MaxTTL.
 	DefaultTTL time.Duration `env:"JVS_API_DEFAULT_TTL,overwrite,default=15m"`
 	MaxTTL     time.Duration `env:"JVS_API_MAX_TTL,overwrite,default=4h"`
+
+	// NotBeforeLeeway backdates the "nbf" claim of tokens, so verifiers whose
+	// clocks are behind the JVS don't reject new tokens as not yet valid.
+	NotBeforeLeeway time.Duration `env:"JVS_API_NOT_BEFORE_LEEWAY,overwrite,default=0"`
+
+	// MaxStartDelay is how far ahead of now a request's start time can be, for
+	// tokens scheduled to become valid during an upcoming change window. If it
+	// is 0, tokens can't be scheduled.
+	MaxStartDelay time.Duration `env:"JVS_API_MAX_START_DELAY,overwrite,default=0"`
+
+	// AudienceTemplate expands the audience services of requests into
+	// audiences, by replacing "{service}" with the service name, e.g.
+	// "https://{service}.internal.example.com". If it is empty, requests can't
+	// have audience services. If AudienceTemplateRequired is true, requests
+	// can only set audiences through audience services, so tokens can't be
+	// minted for arbitrary audiences.
+	AudienceTemplate         string `env:"JVS_API_AUDIENCE_TEMPLATE,overwrite"`
+	AudienceTemplateRequired bool   `env:"JVS_API_AUDIENCE_TEMPLATE_REQUIRED,overwrite,default=false"`
+
+	// ClaimVersion is the version of the claim layout of minted tokens, set as
+	// their "jvs_ver" claim. Keep it at the version every verifier supports
+	// while they are upgraded to read a newer layout. If it is 0, tokens are
+	// minted without a version claim, like before claims were versioned.
+	ClaimVersion int `env:"JVS_API_CLAIM_VERSION,overwrite,default=1"`
+
+	// ClaimAliases duplicate claims of minted tokens under alternate names, in
+	// the format "alias=claim", for verifiers and policy engines that expect
+	// them elsewhere, e.g. "justifications=justs". Verifiers using the client
+	// library ignore the aliases.
+	ClaimAliases []string `env:"JVS_API_CLAIM_ALIASES,overwrite"`
+
+	// FlatJustificationsClaim, if set, is the name of a claim with the
+	// justifications of minted tokens flattened into a string, e.g.
+	// "jira:ABC-123, explanation:debugging", for consumers that can't read
+	// the structured claim. It can't be used with justification encryption.
+	FlatJustificationsClaim string `env:"JVS_API_FLAT_JUSTIFICATIONS_CLAIM,overwrite"`
+
+	// TokenType, if set, is the "typ" header of minted tokens, e.g. "jvs+jwt",
+	// so they can't be confused with the JWTs of other issuers. Verifiers must
+	// accept it before it is set. If it is empty, tokens are typed "JWT".
+	TokenType string `env:"JVS_API_TOKEN_TYPE,overwrite"`
+
+	// ClaimsNamespace, if set, prefixes the JVS claims of minted tokens, such
+	// as "justs" and "req", e.g. "https://jvs.example.com/" for
+	// "https://jvs.example.com/justs", so they don't collide with the claims
+	// of other JWT ecosystems. Registered claims and claim aliases aren't
+	// prefixed. Verifiers must be configured with the same namespace.
+	ClaimsNamespace string `env:"JVS_API_CLAIMS_NAMESPACE,overwrite"`
+
+	// TokenExchangeIssuer is the issuer of third-party OIDC tokens that can be
+	// exchanged for JVS tokens, e.g. "https://token.actions.githubusercontent.com"
+	// for GitHub Actions. Token exchange is disabled if empty. Tokens must
+	// have an expiry, and have been issued less than an hour ago.
+	//
+	// TokenExchangeJWKSEndpoint and TokenExchangeAudience are required when
+	// token exchange is enabled.
+	TokenExchangeIssuer       string `env:"JVS_API_TOKEN_EXCHANGE_ISSUER,overwrite"`
+	TokenExchangeJWKSEndpoint string `env:"JVS_API_TOKEN_EXCHANGE_JWKS_ENDPOINT,overwrite"`
+	TokenExchangeAudience     string `env:"JVS_API_TOKEN_EXCHANGE_AUDIENCE,overwrite"`
+
+	// TokenExchangeAllowlist are the claim patterns a third-party OIDC token
+	// must match to be exchanged, in the format "claim=pattern", where claim is
+	// one of [TokenExchangeAllowlistClaims] and pattern is a regular expression
+	// that must match the whole claim. A token is accepted if it matches any
+	// entry. It's required when token exchange is enabled, since the issuer and
+	// audience alone don't tell apart tokens from different repositories.
+	TokenExchangeAllowlist []string `env:"JVS_API_TOKEN_EXCHANGE_ALLOWLIST,overwrite"`
+
+	// EncryptionJWKSEndpoint is the JWKS endpoint of the recipient keys that
+	// justifications are encrypted to. If set, justifications are stored as a
+	// JWE in the "justs_enc" claim instead of in plaintext.
+	EncryptionJWKSEndpoint string `env:"JVS_API_ENCRYPTION_JWKS_ENDPOINT,overwrite"`
+
+	// CertificateCAKey is the KMS key version that signs short-lived client
+	// certificates, in the format
+	// `projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*`, and
+	// CertificateCAPath is the path of its PEM encoded CA certificate.
+	// Certificate issuance is disabled if both are empty.
+	CertificateCAKey  string `env:"JVS_API_CERTIFICATE_CA_KEY,overwrite"`
+	CertificateCAPath string `env:"JVS_API_CERTIFICATE_CA_PATH,overwrite"`
 }
 
 // Validate checks if the config is valid.
@@ -63,8 +478,48 @@ func (cfg *JustificationConfig) Validate() (merr error) {
 		merr = errors.Join(merr, fmt.Errorf("empty ProjectID"))
 	}
 
-	if cfg.KeyName == "" {
-		merr = errors.Join(merr, fmt.Errorf("empty KeyName"))
+	if got := cfg.ShutdownTimeout; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("shutdown timeout cannot be negative, got %s", got))
+	}
+
+	if got := cfg.WarmupTimeout; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("warmup timeout cannot be negative, got %s", got))
+	}
+
+	switch cfg.Signer {
+	case "", SignerKMS:
+		if cfg.KeyName == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyName"))
+		}
+	case SignerLocal:
+		if cfg.KeyPath == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyPath"))
+		}
+	case SignerPKCS11:
+		if cfg.KeyName == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyName"))
+		}
+		merr = errors.Join(merr, cfg.PKCS11.Validate())
+	case SignerVault:
+		if cfg.KeyName == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyName"))
+		}
+		merr = errors.Join(merr, cfg.Vault.Validate())
+	default:
+		merr = errors.Join(merr, fmt.Errorf("signer must be %q, %q, %q or %q, got %q",
+			SignerKMS, SignerLocal, SignerPKCS11, SignerVault, cfg.Signer))
+	}
+
+	if cfg.CertificateCAKey != "" && cfg.Signer != "" && cfg.Signer != SignerKMS {
+		merr = errors.Join(merr, fmt.Errorf("CertificateCAKey requires the %q signer", SignerKMS))
+	}
+
+	if cfg.KMSInsecure && cfg.KMSEndpoint == "" {
+		merr = errors.Join(merr, fmt.Errorf("KMSInsecure requires KMSEndpoint to be set"))
+	}
+
+	if err := validateKMSPermissionCheck(cfg.KMSPermissionCheck); err != nil {
+		merr = errors.Join(merr, err)
 	}
 
 	if got := cfg.SignerCacheTimeout; got <= 0 {
@@ -72,14 +527,412 @@ func (cfg *JustificationConfig) Validate() (merr error) {
 			got))
 	}
 
+	if got := cfg.MaxConcurrentRequests; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("max concurrent requests cannot be negative, got %d", got))
+	}
+
+	if got := cfg.RequestDeadline; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("request deadline cannot be negative, got %s", got))
+	}
+
+	if cfg.AuditRouteProject != "" && !auditLogIDRegexp.MatchString(cfg.AuditRouteLogID) {
+		merr = errors.Join(merr, fmt.Errorf("audit route log id %q must be 1-512 letters, digits, "+
+			"forward slashes, underscores, hyphens or periods", cfg.AuditRouteLogID))
+	}
+
+	if got := cfg.MaxAnnotationSize; got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("max annotation size must be positive, got %d", got))
+	}
+
 	if def, maximum := cfg.DefaultTTL, cfg.MaxTTL; def > maximum {
 		merr = errors.Join(merr, fmt.Errorf("default ttl (%s) must be less than or equal to the max ttl (%s)",
 			timeutil.HumanDuration(def), timeutil.HumanDuration(maximum)))
 	}
 
+	if cfg.EventsTopic != "" && !eventsTopicRegexp.MatchString(cfg.EventsTopic) {
+		merr = errors.Join(merr, fmt.Errorf("events topic %q must be in the format projects/*/topics/*", cfg.EventsTopic))
+	}
+
+	if cfg.AuditBigQueryTable != "" && !bigQueryTableRegexp.MatchString(cfg.AuditBigQueryTable) {
+		merr = errors.Join(merr, fmt.Errorf("audit bigquery table %q must be in the format project.dataset.table", cfg.AuditBigQueryTable))
+	}
+
+	if cfg.AuditPostgresURL != "" {
+		if u, err := url.Parse(cfg.AuditPostgresURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			merr = errors.Join(merr, fmt.Errorf("audit postgres url must be a postgres:// url"))
+		}
+	}
+
+	if cfg.AuditSpannerDatabase != "" && !spannerDatabaseRegexp.MatchString(cfg.AuditSpannerDatabase) {
+		merr = errors.Join(merr, fmt.Errorf("audit spanner database %q must be in the format projects/*/instances/*/databases/*", cfg.AuditSpannerDatabase))
+	}
+
+	sinks := 0
+	for _, v := range []string{cfg.AuditBigQueryTable, cfg.AuditPostgresURL, cfg.AuditSpannerDatabase, cfg.AuditSnapshotPath} {
+		if v != "" {
+			sinks++
+		}
+	}
+	if sinks > 1 {
+		merr = errors.Join(merr, fmt.Errorf("only one of audit bigquery table, audit postgres url, audit spanner database and audit snapshot path can be set"))
+	}
+
+	if got := cfg.NotBeforeLeeway; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("not before leeway cannot be negative, got %s", got))
+	}
+
+	if got := cfg.MaxStartDelay; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("max start delay cannot be negative, got %s", got))
+	}
+
+	if got := cfg.AudienceTemplate; got != "" && strings.Count(got, AudienceTemplateService) != 1 {
+		merr = errors.Join(merr, fmt.Errorf("audience template must contain %s exactly once, got %q",
+			AudienceTemplateService, got))
+	}
+	if cfg.AudienceTemplateRequired && cfg.AudienceTemplate == "" {
+		merr = errors.Join(merr, fmt.Errorf("audience template must be set to require audience services"))
+	}
+
+	if got := cfg.ClaimVersion; got < jvspb.ClaimVersionLegacy || got > jvspb.LatestClaimVersion {
+		merr = errors.Join(merr, fmt.Errorf("claim version must be between %d and %d, got %d",
+			jvspb.ClaimVersionLegacy, jvspb.LatestClaimVersion, got))
+	}
+
+	if got := cfg.TokenType; got != "" && !jvspb.TokenTypesEqual(got, jvspb.DefaultTokenType) {
+		if err := jvspb.ValidateTokenType(got); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+
+	if got := cfg.ClaimsNamespace; got != "" {
+		if err := jvspb.ValidateClaimsNamespace(got); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+
+	if aliases, err := cfg.ClaimAliasSources(); err != nil {
+		merr = errors.Join(merr, err)
+	} else {
+		if _, ok := aliases[cfg.FlatJustificationsClaim]; ok {
+			merr = errors.Join(merr, fmt.Errorf("flat justifications claim %q is also a claim alias", cfg.FlatJustificationsClaim))
+		}
+		for _, alias := range slices.Sorted(maps.Keys(aliases)) {
+			if cfg.isNamespacedClaim(alias) {
+				merr = errors.Join(merr, fmt.Errorf("claim alias %q is a namespaced claim", alias))
+			}
+		}
+	}
+	if cfg.isNamespacedClaim(cfg.FlatJustificationsClaim) {
+		merr = errors.Join(merr, fmt.Errorf("flat justifications claim %q is a namespaced claim", cfg.FlatJustificationsClaim))
+	}
+	if got := cfg.FlatJustificationsClaim; got != "" {
+		if slices.Contains(reservedClaims, got) {
+			merr = errors.Join(merr, fmt.Errorf("flat justifications claim %q is a reserved claim", got))
+		}
+		if cfg.EncryptionJWKSEndpoint != "" {
+			merr = errors.Join(merr, fmt.Errorf("flat justifications claim can't be used with justification encryption"))
+		}
+	}
+
+	if got := cfg.DenyListReloadInterval; cfg.DenyListPath != "" && got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("deny list reload interval must be a positive duration, got %s", got))
+	}
+
+	if got := cfg.PolicyReloadInterval; cfg.PolicyPath != "" && got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("policy reload interval must be a positive duration, got %s", got))
+	}
+
+	if got := cfg.RegoPolicyReloadInterval; cfg.RegoPolicyPath != "" && got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("rego policy reload interval must be a positive duration, got %s", got))
+	}
+	if strings.HasPrefix(cfg.RegoPolicyPath, "gs://") {
+		merr = errors.Join(merr, fmt.Errorf("rego policy path must be a local path, mount %s as a volume instead", cfg.RegoPolicyPath))
+	}
+
+	if got := cfg.DecisionTraceRetention; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("decision trace retention cannot be negative, got %s", got))
+	}
+	if cfg.DecisionTraceDir != "" && cfg.DecisionTraceRetention <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("decision trace dir requires decision trace retention to be set"))
+	}
+
+	if cfg.AdminPort != "" {
+		// Port 0 picks a random port, so both may be 0.
+		if cfg.AdminPort == cfg.Port && cfg.Port != "0" {
+			merr = errors.Join(merr, fmt.Errorf("admin port must be different from port %q", cfg.Port))
+		}
+		if cfg.PolicyPath == "" {
+			merr = errors.Join(merr, fmt.Errorf("policy path must be set to serve the admin api"))
+		}
+		if len(cfg.AdminPrincipals) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("admin principals must be set to serve the admin api"))
+		}
+		if cfg.AdminAudience == "" {
+			merr = errors.Join(merr, fmt.Errorf("admin audience must be set to serve the admin api"))
+		}
+		if cfg.AdminIssuer == "" || cfg.AdminJWKSEndpoint == "" {
+			merr = errors.Join(merr, fmt.Errorf("admin issuer and jwks endpoint must be set to serve the admin api"))
+		}
+	}
+
+	if cfg.DeprovisionWebhookPort != "" {
+		for _, port := range []string{cfg.Port, cfg.AdminPort} {
+			if cfg.DeprovisionWebhookPort == port && port != "0" {
+				merr = errors.Join(merr, fmt.Errorf("deprovision webhook port must be different from port %q", port))
+			}
+		}
+		if len(cfg.DeprovisionWebhookSecret) < minDeprovisionWebhookSecretLength {
+			merr = errors.Join(merr, fmt.Errorf("deprovision webhook secret must be at least %d characters", minDeprovisionWebhookSecretLength))
+		}
+		if cfg.DenyListPath == "" && !cfg.Revocation.Enabled() {
+			merr = errors.Join(merr, fmt.Errorf("deny list or revocation must be enabled to serve the deprovision webhook"))
+		}
+	}
+
+	if got := cfg.TransparencyLogTimeout; cfg.TransparencyLogURL != "" && got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("transparency log timeout must be a positive duration, got %s", got))
+	}
+
+	merr = errors.Join(merr, cfg.Groups.Validate())
+	merr = errors.Join(merr, cfg.Revocation.Validate())
+	merr = errors.Join(merr, cfg.Quota.Validate())
+	merr = errors.Join(merr, cfg.PAM.Validate())
+	merr = errors.Join(merr, cfg.Canary.Validate())
+
+	if _, err := cfg.RemotePluginAddrs(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if paths, err := cfg.PluginSealedConfigPaths(); err != nil {
+		merr = errors.Join(merr, err)
+	} else if len(paths) > 0 && cfg.PluginConfigKey == "" {
+		merr = errors.Join(merr, fmt.Errorf("PluginConfigKey must be set with PluginSealedConfigs"))
+	}
+
+	if _, err := cfg.CategoryAliasTargets(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if _, err := cfg.ValidationCacheTTLs(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if _, err := cfg.ValuePatternRegexps(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if _, err := cfg.RequiredAnnotationKeys(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if (cfg.RemotePluginCertFile == "") != (cfg.RemotePluginKeyFile == "") {
+		merr = errors.Join(merr, fmt.Errorf("RemotePluginCertFile and RemotePluginKeyFile must be set together"))
+	}
+
+	if cfg.RemotePluginInsecure && (cfg.RemotePluginCAFile != "" || cfg.RemotePluginCertFile != "") {
+		merr = errors.Join(merr, fmt.Errorf("RemotePluginInsecure cannot be used with remote plugin TLS files"))
+	}
+
+	if cfg.TokenExchangeIssuer != "" {
+		if cfg.TokenExchangeJWKSEndpoint == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty TokenExchangeJWKSEndpoint"))
+		}
+		if cfg.TokenExchangeAudience == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty TokenExchangeAudience"))
+		}
+		if len(cfg.TokenExchangeAllowlist) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("empty TokenExchangeAllowlist"))
+		}
+	}
+
+	if _, err := cfg.TokenExchangeAllowlistRegexps(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if (cfg.CertificateCAKey == "") != (cfg.CertificateCAPath == "") {
+		merr = errors.Join(merr, fmt.Errorf("CertificateCAKey and CertificateCAPath must be set together"))
+	}
+
 	return
 }
 
+// isNamespacedClaim reports whether the claim is one of the JVS claims moved
+// under the claims namespace.
+func (cfg *JustificationConfig) isNamespacedClaim(claim string) bool {
+	if cfg.ClaimsNamespace == "" || claim == "" {
+		return false
+	}
+	name, ok := strings.CutPrefix(claim, cfg.ClaimsNamespace)
+	return ok && slices.Contains(jvspb.NamespacedClaims, name)
+}
+
+// RemotePluginAddrs returns the address of each remote plugin, keyed by
+// category.
+func (cfg *JustificationConfig) RemotePluginAddrs() (map[string]string, error) {
+	addrs := make(map[string]string, len(cfg.RemotePlugins))
+	for _, v := range cfg.RemotePlugins {
+		category, addr, ok := strings.Cut(v, "=")
+		category, addr = strings.TrimSpace(category), strings.TrimSpace(addr)
+		if !ok || category == "" || addr == "" {
+			return nil, fmt.Errorf("remote plugin %q must be in the format category=host:port", v)
+		}
+		if _, ok := addrs[category]; ok {
+			return nil, fmt.Errorf("remote plugin category %q is specified more than once", category)
+		}
+		addrs[category] = addr
+	}
+	return addrs, nil
+}
+
+// PluginSealedConfigPaths returns the path of the sealed config of each local
+// plugin, keyed by plugin name.
+func (cfg *JustificationConfig) PluginSealedConfigPaths() (map[string]string, error) {
+	paths := make(map[string]string, len(cfg.PluginSealedConfigs))
+	for _, v := range cfg.PluginSealedConfigs {
+		name, path, ok := strings.Cut(v, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("plugin sealed config %q must be in the format name=path", v)
+		}
+		if _, ok := paths[name]; ok {
+			return nil, fmt.Errorf("sealed config of plugin %q is specified more than once", name)
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
+
+// CategoryAliasTargets returns the new name of each deprecated category, keyed
+// by the deprecated name.
+func (cfg *JustificationConfig) CategoryAliasTargets() (map[string]string, error) {
+	targets := make(map[string]string, len(cfg.CategoryAliases))
+	for _, v := range cfg.CategoryAliases {
+		alias, target, ok := strings.Cut(v, "=")
+		alias, target = strings.TrimSpace(alias), strings.TrimSpace(target)
+		if !ok || alias == "" || target == "" {
+			return nil, fmt.Errorf("category alias %q must be in the format old=new", v)
+		}
+		if alias == target {
+			return nil, fmt.Errorf("category alias %q cannot map to itself", alias)
+		}
+		if _, ok := targets[alias]; ok {
+			return nil, fmt.Errorf("category alias %q is specified more than once", alias)
+		}
+		targets[alias] = target
+	}
+	for alias, target := range targets {
+		if _, ok := targets[target]; ok {
+			return nil, fmt.Errorf("category alias %q maps to %q, which is also an alias", alias, target)
+		}
+	}
+	return targets, nil
+}
+
+// ClaimAliasSources returns the claim each claim alias duplicates, keyed by the
+// alias.
+func (cfg *JustificationConfig) ClaimAliasSources() (map[string]string, error) {
+	sources := make(map[string]string, len(cfg.ClaimAliases))
+	for _, v := range cfg.ClaimAliases {
+		alias, claim, ok := strings.Cut(v, "=")
+		alias, claim = strings.TrimSpace(alias), strings.TrimSpace(claim)
+		if !ok || alias == "" || claim == "" {
+			return nil, fmt.Errorf("claim alias %q must be in the format alias=claim", v)
+		}
+		if slices.Contains(reservedClaims, alias) {
+			return nil, fmt.Errorf("claim alias %q is a reserved claim", alias)
+		}
+		if _, ok := sources[alias]; ok {
+			return nil, fmt.Errorf("claim alias %q is specified more than once", alias)
+		}
+		sources[alias] = claim
+	}
+	for alias, claim := range sources {
+		if _, ok := sources[claim]; ok {
+			return nil, fmt.Errorf("claim alias %q duplicates %q, which is also an alias", alias, claim)
+		}
+	}
+	return sources, nil
+}
+
+// ValidationCacheTTLs returns how long validation results are cached, keyed by
+// category.
+func (cfg *JustificationConfig) ValidationCacheTTLs() (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration, len(cfg.ValidationCache))
+	for _, v := range cfg.ValidationCache {
+		category, ttl, ok := strings.Cut(v, "=")
+		category, ttl = strings.TrimSpace(category), strings.TrimSpace(ttl)
+		if !ok || category == "" || ttl == "" {
+			return nil, fmt.Errorf("validation cache %q must be in the format category=ttl", v)
+		}
+		if _, ok := ttls[category]; ok {
+			return nil, fmt.Errorf("validation cache category %q is specified more than once", category)
+		}
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("validation cache ttl for category %q is invalid: %w", category, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("validation cache ttl for category %q must be a positive duration, got %s", category, d)
+		}
+		ttls[category] = d
+	}
+	return ttls, nil
+}
+
+// ValuePatternRegexps returns the compiled patterns justification values must
+// match, keyed by category.
+func (cfg *JustificationConfig) ValuePatternRegexps() (map[string]*regexp.Regexp, error) {
+	patterns := make(map[string]*regexp.Regexp, len(cfg.ValuePatterns))
+	for _, v := range cfg.ValuePatterns {
+		category, pattern, ok := strings.Cut(v, "=")
+		category, pattern = strings.TrimSpace(category), strings.TrimSpace(pattern)
+		if !ok || category == "" || pattern == "" {
+			return nil, fmt.Errorf("value pattern %q must be in the format category=pattern", v)
+		}
+		if _, ok := patterns[category]; ok {
+			return nil, fmt.Errorf("value pattern category %q is specified more than once", category)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("value pattern for category %q is invalid: %w", category, err)
+		}
+		patterns[category] = re
+	}
+	return patterns, nil
+}
+
+// RequiredAnnotationKeys returns the annotations validators must set, keyed by
+// category.
+func (cfg *JustificationConfig) RequiredAnnotationKeys() (map[string][]string, error) {
+	return jvspb.ParseRequiredAnnotations(cfg.RequiredAnnotations)
+}
+
+// TokenExchangeAllowlistClaims are the claims of third-party OIDC tokens that
+// [JustificationConfig.TokenExchangeAllowlist] entries can match.
+var TokenExchangeAllowlistClaims = []string{"repository_owner", "repository", "sub"}
+
+// TokenExchangeAllowlistRegexps returns the compiled token exchange allowlist
+// patterns, keyed by claim. Each pattern is anchored to match the whole claim.
+func (cfg *JustificationConfig) TokenExchangeAllowlistRegexps() (map[string][]*regexp.Regexp, error) {
+	patterns := make(map[string][]*regexp.Regexp, len(TokenExchangeAllowlistClaims))
+	for _, v := range cfg.TokenExchangeAllowlist {
+		claim, pattern, ok := strings.Cut(v, "=")
+		claim, pattern = strings.TrimSpace(claim), strings.TrimSpace(pattern)
+		if !ok || claim == "" || pattern == "" {
+			return nil, fmt.Errorf("token exchange allowlist entry %q must be in the format claim=pattern", v)
+		}
+		if !slices.Contains(TokenExchangeAllowlistClaims, claim) {
+			return nil, fmt.Errorf("token exchange allowlist claim %q must be one of %q", claim, TokenExchangeAllowlistClaims)
+		}
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("token exchange allowlist pattern for claim %q is invalid: %w", claim, err)
+		}
+		patterns[claim] = append(patterns[claim], re)
+	}
+	return patterns, nil
+}
+
 // ToFlags binds the config to the give [cli.FlagSet] and returns it.
 func (cfg *JustificationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 	f := set.NewSection("COMMON SERVER OPTIONS")
@@ -107,14 +960,77 @@ func (cfg *JustificationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   `The port the server listens to.`,
 	})
 
+	f.DurationVar(&cli.DurationVar{
+		Name:    "shutdown-timeout",
+		Target:  &cfg.ShutdownTimeout,
+		EnvVar:  "JVS_SHUTDOWN_TIMEOUT",
+		Default: 30 * time.Second,
+		Usage: "How long to wait for in-flight requests to finish on shutdown, " +
+			"before abandoning them.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "warmup-timeout",
+		Target:  &cfg.WarmupTimeout,
+		EnvVar:  "JVS_WARMUP_TIMEOUT",
+		Default: 30 * time.Second,
+		Usage: "How long to retry loading caches on startup, before serving " +
+			"anyway. Set to 0 to disable warming up.",
+	})
+
 	f = set.NewSection("API OPTIONS")
 
+	f.StringVar(&cli.StringVar{
+		Name:    "signer",
+		Target:  &cfg.Signer,
+		EnvVar:  "JVS_SIGNER",
+		Default: SignerKMS,
+		Usage: `Where to sign JVS tokens, "kms" for Cloud KMS, "local" for ` +
+			`a private key on disk, "pkcs11" for an HSM or "vault" for Vault.`,
+	})
+
 	f.StringVar(&cli.StringVar{
 		Name:    "key-name",
 		Target:  &cfg.KeyName,
 		EnvVar:  "JVS_KEY",
 		Example: "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[JVS_KEY]",
-		Usage:   `The KMS key for signing JVS tokens.`,
+		Usage: `The KMS key for signing JVS tokens, with the "kms" signer, ` +
+			`the label of the HSM key, with the "pkcs11" signer, or the name of ` +
+			`the transit key, with the "vault" signer.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-path",
+		Target:  &cfg.KeyPath,
+		EnvVar:  "JVS_KEY_PATH",
+		Example: "/etc/jvs/signing-key.pem",
+		Usage: `The PEM encoded ECDSA P-256 private key for signing JVS ` +
+			`tokens, with the "local" signer.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-endpoint",
+		Target:  &cfg.KMSEndpoint,
+		EnvVar:  "JVS_KMS_ENDPOINT",
+		Example: "localhost:9090",
+		Usage:   `Override the Cloud KMS API endpoint, e.g. for a local KMS emulator.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "kms-insecure",
+		Target:  &cfg.KMSInsecure,
+		EnvVar:  "JVS_KMS_INSECURE",
+		Default: false,
+		Usage:   `Connect to the KMS endpoint without TLS or authentication.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-permission-check",
+		Target:  &cfg.KMSPermissionCheck,
+		EnvVar:  "JVS_KMS_PERMISSION_CHECK",
+		Default: KMSPermissionCheckWarn,
+		Usage: `Whether to check the permissions on the KMS keys at startup, ` +
+			`one of "off", "warn" or "fail".`,
 	})
 
 	f.StringVar(&cli.StringVar{
@@ -133,6 +1049,110 @@ func (cfg *JustificationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   `The path of the directory to load plugins.`,
 	})
 
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "plugin-sealed-config",
+		Target:  &cfg.PluginSealedConfigs,
+		EnvVar:  "JVS_PLUGIN_SEALED_CONFIGS",
+		Example: "servicenow=/etc/jvs/servicenow.sealed",
+		Usage: `The KMS encrypted config of a plugin, as name=path. The plugin ` +
+			`decrypts it with the plugin config key. Can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "plugin-config-key",
+		Target:  &cfg.PluginConfigKey,
+		EnvVar:  "JVS_PLUGIN_CONFIG_KEY",
+		Example: "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[PLUGIN_KEY]",
+		Usage:   `The KMS key the sealed plugin configs are encrypted with.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "max-concurrent-requests",
+		Target:  &cfg.MaxConcurrentRequests,
+		EnvVar:  "JVS_API_MAX_CONCURRENT_REQUESTS",
+		Default: 0,
+		Usage:   "The maximum number of in-flight justification requests. Unlimited if 0.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "request-deadline",
+		Target:  &cfg.RequestDeadline,
+		EnvVar:  "JVS_API_REQUEST_DEADLINE",
+		Default: 10 * time.Second,
+		Usage: "The maximum time to process a justification request, including " +
+			"plugins, KMS and signing. No deadline if 0.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-log-project",
+		Target:  &cfg.AuditLogProject,
+		EnvVar:  "JVS_API_AUDIT_LOG_PROJECT",
+		Example: "my-project",
+		Usage: `The project to write data access audit logs of requests to. ` +
+			`Audit logs are not written if unset.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-route-project",
+		Target:  &cfg.AuditRouteProject,
+		EnvVar:  "JVS_API_AUDIT_ROUTE_PROJECT",
+		Example: "my-project",
+		Usage: `The project services should write the data access audit logs of ` +
+			`requests made with tokens to, embedded in the tokens. Not embedded if unset.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-route-log-id",
+		Target:  &cfg.AuditRouteLogID,
+		EnvVar:  "JVS_API_AUDIT_ROUTE_LOG_ID",
+		Default: "audit.abcxyz/data_access",
+		Usage:   `The ID of the log in the audit route project, embedded in the tokens.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "events-topic",
+		Target:  &cfg.EventsTopic,
+		EnvVar:  "JVS_EVENTS_TOPIC",
+		Example: "projects/[PROJECT]/topics/[TOPIC]",
+		Usage:   "The Pub/Sub topic to publish token events to. Events are not published if unset.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-bigquery-table",
+		Target:  &cfg.AuditBigQueryTable,
+		EnvVar:  "JVS_API_AUDIT_BIGQUERY_TABLE",
+		Example: "my-project.jvs.issuances",
+		Usage: `The BigQuery table to stream audit entries of issued tokens to. ` +
+			`Entries are only logged if unset.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-postgres-url",
+		Target:  &cfg.AuditPostgresURL,
+		EnvVar:  "JVS_API_AUDIT_POSTGRES_URL",
+		Example: "postgres://jvs@10.0.0.3:5432/jvs?sslmode=require",
+		Usage: `The connection URL of the Postgres database to write audit entries ` +
+			`of issued tokens to. Cannot be used with another audit sink.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-spanner-database",
+		Target:  &cfg.AuditSpannerDatabase,
+		EnvVar:  "JVS_API_AUDIT_SPANNER_DATABASE",
+		Example: "projects/my-project/instances/jvs/databases/jvs",
+		Usage: `The Spanner database to write audit entries of issued tokens to. ` +
+			`Cannot be used with another audit sink.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audit-snapshot-path",
+		Target:  &cfg.AuditSnapshotPath,
+		EnvVar:  "JVS_API_AUDIT_SNAPSHOT_PATH",
+		Example: "/tmp/jvs-audit.json",
+		Usage: `The file to keep audit entries of issued tokens in, for single ` +
+			`node development servers. Cannot be used with another audit sink.`,
+	})
+
 	f.DurationVar(&cli.DurationVar{
 		Name:    "signer-cache-timeout",
 		Target:  &cfg.SignerCacheTimeout,
@@ -157,5 +1177,464 @@ func (cfg *JustificationConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   "The maximum TTL that a token can have.",
 	})
 
+	f.IntVar(&cli.IntVar{
+		Name:    "max-annotation-size",
+		Target:  &cfg.MaxAnnotationSize,
+		EnvVar:  "JVS_API_MAX_ANNOTATION_SIZE",
+		Default: 2000,
+		Usage:   "The maximum total size in bytes of the annotations validators add to justifications.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "deny-list-path",
+		Target:  &cfg.DenyListPath,
+		EnvVar:  "JVS_API_DENY_LIST_PATH",
+		Example: "/etc/jvs/deny-list",
+		Usage: `A file of principals who may not mint tokens or be their ` +
+			`subjects, one per line.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "deny-list-reload-interval",
+		Target:  &cfg.DenyListReloadInterval,
+		EnvVar:  "JVS_API_DENY_LIST_RELOAD_INTERVAL",
+		Default: 30 * time.Second,
+		Usage:   `How often to reload the deny list file.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "maintenance-mode",
+		Target:  &cfg.MaintenanceMode,
+		EnvVar:  "JVS_API_MAINTENANCE_MODE",
+		Default: false,
+		Usage: `Refuse to issue tokens, certificates and signatures, while ` +
+			`still serving public keys.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "maintenance-message",
+		Target:  &cfg.MaintenanceMessage,
+		EnvVar:  "JVS_API_MAINTENANCE_MESSAGE",
+		Example: "Key migration in progress, back by 14:00 UTC.",
+		Usage:   `The message returned to callers in maintenance mode.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "policy-path",
+		Target:  &cfg.PolicyPath,
+		EnvVar:  "JVS_API_POLICY_PATH",
+		Example: "/var/lib/jvs/policy.json",
+		Usage: `The JSON file of the runtime policy, managed with the admin ` +
+			`api.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "policy-reload-interval",
+		Target:  &cfg.PolicyReloadInterval,
+		EnvVar:  "JVS_API_POLICY_RELOAD_INTERVAL",
+		Default: 30 * time.Second,
+		Usage:   `How often to reload the runtime policy file.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "rego-policy-path",
+		Target:  &cfg.RegoPolicyPath,
+		EnvVar:  "JVS_API_REGO_POLICY_PATH",
+		Example: "/mnt/jvs/issuance.rego",
+		Usage: `The Rego policy file evaluated with every token request. It ` +
+			`must be a local path, mount Cloud Storage buckets as a volume ` +
+			`instead of using gs:// URLs. If empty, no policy is evaluated.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "rego-policy-reload-interval",
+		Target:  &cfg.RegoPolicyReloadInterval,
+		EnvVar:  "JVS_API_REGO_POLICY_RELOAD_INTERVAL",
+		Default: 30 * time.Second,
+		Usage:   `How often to reload the Rego policy file.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "policy-expression",
+		Target:  &cfg.PolicyExpression,
+		EnvVar:  "JVS_API_POLICY_EXPRESSION",
+		Example: `ttl <= duration("1h") || !("breakglass" in categories)`,
+		Usage: `A CEL expression token requests must satisfy. If empty, no ` +
+			`expression is evaluated.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "decision-trace-retention",
+		Target:  &cfg.DecisionTraceRetention,
+		EnvVar:  "JVS_API_DECISION_TRACE_RETENTION",
+		Example: "24h",
+		Usage: `How long to keep the traces of denied token requests. If ` +
+			`zero, traces are not recorded.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "decision-trace-dir",
+		Target:  &cfg.DecisionTraceDir,
+		EnvVar:  "JVS_API_DECISION_TRACE_DIR",
+		Example: "/mnt/jvs/decisions",
+		Usage: `The directory to store decision traces in, shared by all ` +
+			`replicas. If empty, traces are kept in memory.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "admin-port",
+		Target:  &cfg.AdminPort,
+		EnvVar:  "JVS_API_ADMIN_PORT",
+		Example: "8081",
+		Usage:   `The port to serve the admin api on. If empty, it is not served.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "admin-principals",
+		Target:  &cfg.AdminPrincipals,
+		EnvVar:  "JVS_API_ADMIN_PRINCIPALS",
+		Example: "jvs-admin@example.com",
+		Usage:   `The emails of the principals allowed to call the admin api.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "admin-audience",
+		Target:  &cfg.AdminAudience,
+		EnvVar:  "JVS_API_ADMIN_AUDIENCE",
+		Example: "https://jvs-admin.example.com",
+		Usage:   `The audience of the ID tokens of admin api callers.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "admin-issuer",
+		Target:  &cfg.AdminIssuer,
+		EnvVar:  "JVS_API_ADMIN_ISSUER",
+		Default: "https://accounts.google.com",
+		Usage:   `The issuer of the ID tokens of admin api callers.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "admin-jwks-endpoint",
+		Target:  &cfg.AdminJWKSEndpoint,
+		EnvVar:  "JVS_API_ADMIN_JWKS_ENDPOINT",
+		Default: "https://www.googleapis.com/oauth2/v3/certs",
+		Usage:   `The JWKS endpoint to verify the ID tokens of admin api callers.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "reflection",
+		Target:  &cfg.Reflection,
+		EnvVar:  "JVS_API_REFLECTION",
+		Default: false,
+		Usage: `Whether to register gRPC server reflection on the public port. ` +
+			`Keep it off in production.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "deprovision-webhook-port",
+		Target:  &cfg.DeprovisionWebhookPort,
+		EnvVar:  "JVS_API_DEPROVISION_WEBHOOK_PORT",
+		Example: "8082",
+		Usage: `The port of a separate listener receiving deprovisioning ` +
+			`webhooks and SCIM requests.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "deprovision-webhook-secret",
+		Target: &cfg.DeprovisionWebhookSecret,
+		EnvVar: "JVS_API_DEPROVISION_WEBHOOK_SECRET",
+		Usage:  `The bearer token deprovisioning webhook callers must send.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "receipts",
+		Target:  &cfg.Receipts,
+		EnvVar:  "JVS_API_RECEIPTS",
+		Default: false,
+		Usage:   `Return and record a signed receipt of each issued token.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "transparency-log-url",
+		Target:  &cfg.TransparencyLogURL,
+		EnvVar:  "JVS_API_TRANSPARENCY_LOG_URL",
+		Example: "https://rekor.sigstore.dev",
+		Usage: `The Rekor transparency log to publish the digest of each ` +
+			`issuance audit entry to.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "transparency-log-timeout",
+		Target:  &cfg.TransparencyLogTimeout,
+		EnvVar:  "JVS_API_TRANSPARENCY_LOG_TIMEOUT",
+		Default: 5 * time.Second,
+		Usage:   `How long to wait for the transparency log to record an entry.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "category-alias",
+		Target:  &cfg.CategoryAliases,
+		EnvVar:  "JVS_API_CATEGORY_ALIASES",
+		Example: "explanation=freeform",
+		Usage: `Map a deprecated category name to its new name, as old=new. ` +
+			`Can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "shadow-category",
+		Target:  &cfg.ShadowCategories,
+		EnvVar:  "JVS_API_SHADOW_CATEGORIES",
+		Example: "servicenow",
+		Usage: `Validate a category in shadow mode, where validation results ` +
+			`are logged but never block issuance. Can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "validation-cache",
+		Target:  &cfg.ValidationCache,
+		EnvVar:  "JVS_API_VALIDATION_CACHE",
+		Example: "jira=5m",
+		Usage: `Cache successful validation results of a category, as ` +
+			`category=ttl. Can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "value-pattern",
+		Target:  &cfg.ValuePatterns,
+		EnvVar:  "JVS_API_VALUE_PATTERNS",
+		Example: `jira=^[A-Z]+-\d+$`,
+		Usage: `Require the justification values of a category to match an ` +
+			`RE2 regular expression, as category=pattern. Patterns cannot ` +
+			`contain commas. Can be repeated.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "required-annotation",
+		Target:  &cfg.RequiredAnnotations,
+		EnvVar:  "JVS_API_REQUIRED_ANNOTATIONS",
+		Example: "jira=jira_issue_url",
+		Usage: `Require validators to set an annotation on justifications of a ` +
+			`category, as category=annotation. Can be repeated.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "not-before-leeway",
+		Target:  &cfg.NotBeforeLeeway,
+		EnvVar:  "JVS_API_NOT_BEFORE_LEEWAY",
+		Default: 0,
+		Example: "30s",
+		Usage:   "How long to backdate the nbf claim of tokens, to tolerate verifier clock skew.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "max-start-delay",
+		Target:  &cfg.MaxStartDelay,
+		EnvVar:  "JVS_API_MAX_START_DELAY",
+		Default: 0,
+		Example: "168h",
+		Usage:   "How far ahead tokens can be scheduled to become valid. 0 disables scheduling.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "audience-template",
+		Target:  &cfg.AudienceTemplate,
+		EnvVar:  "JVS_API_AUDIENCE_TEMPLATE",
+		Example: "https://{service}.internal.example.com",
+		Usage: `The template that audience services of requests are expanded ` +
+			`into audiences with, by replacing {service}.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "audience-template-required",
+		Target:  &cfg.AudienceTemplateRequired,
+		EnvVar:  "JVS_API_AUDIENCE_TEMPLATE_REQUIRED",
+		Default: false,
+		Usage:   `Only allow audiences expanded from audience services.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "claim-version",
+		Target:  &cfg.ClaimVersion,
+		EnvVar:  "JVS_API_CLAIM_VERSION",
+		Default: jvspb.LatestClaimVersion,
+		Usage: `The version of the claim layout of minted tokens, set as their ` +
+			`jvs_ver claim. 0 omits the claim.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "claim-alias",
+		Target:  &cfg.ClaimAliases,
+		EnvVar:  "JVS_API_CLAIM_ALIASES",
+		Example: "justifications=justs",
+		Usage: `Duplicate a claim of minted tokens under another name, as ` +
+			`alias=claim. Can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "flat-justifications-claim",
+		Target:  &cfg.FlatJustificationsClaim,
+		EnvVar:  "JVS_API_FLAT_JUSTIFICATIONS_CLAIM",
+		Example: "justification",
+		Usage: `The name of a claim with the justifications of minted tokens ` +
+			`flattened into a string, e.g. "jira:ABC-123, explanation:debugging".`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "token-type",
+		Target:  &cfg.TokenType,
+		EnvVar:  "JVS_API_TOKEN_TYPE",
+		Example: "jvs+jwt",
+		Usage: `The "typ" header of minted tokens. Verifiers must accept it ` +
+			`first. Defaults to "JWT".`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "claims-namespace",
+		Target:  &cfg.ClaimsNamespace,
+		EnvVar:  "JVS_API_CLAIMS_NAMESPACE",
+		Example: "https://jvs.example.com/",
+		Usage: `A prefix for the JVS claims of minted tokens, such as justs ` +
+			`and req. Verifiers must be configured with the same namespace.`,
+	})
+
+	f = set.NewSection("REMOTE PLUGIN OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "remote-plugin",
+		Target:  &cfg.RemotePlugins,
+		EnvVar:  "JVS_REMOTE_PLUGINS",
+		Example: "jira=jira-validator.internal:443",
+		Usage: `A validator plugin served over gRPC, as category=host:port. ` +
+			`Can be repeated.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "remote-plugin-ca-file",
+		Target:  &cfg.RemotePluginCAFile,
+		EnvVar:  "JVS_REMOTE_PLUGIN_CA_FILE",
+		Example: "/etc/jvs/plugin-ca.pem",
+		Usage:   `The CA bundle to verify remote plugins with. Defaults to the system roots.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "remote-plugin-cert-file",
+		Target:  &cfg.RemotePluginCertFile,
+		EnvVar:  "JVS_REMOTE_PLUGIN_CERT_FILE",
+		Example: "/etc/jvs/plugin-client.pem",
+		Usage:   `The client certificate to authenticate to remote plugins with.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "remote-plugin-key-file",
+		Target:  &cfg.RemotePluginKeyFile,
+		EnvVar:  "JVS_REMOTE_PLUGIN_KEY_FILE",
+		Example: "/etc/jvs/plugin-client-key.pem",
+		Usage:   `The private key of the remote plugin client certificate.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "remote-plugin-insecure",
+		Target:  &cfg.RemotePluginInsecure,
+		EnvVar:  "JVS_REMOTE_PLUGIN_INSECURE",
+		Default: false,
+		Usage:   `Connect to remote plugins without TLS.`,
+	})
+
+	f = set.NewSection("APPROVER OPTIONS")
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "approver-validator",
+		Target:  &cfg.ApproverValidator,
+		EnvVar:  "JVS_APPROVER_VALIDATOR",
+		Default: false,
+		Usage: `Enable the "approver" justification category, which checks that ` +
+			`the approver manages or shares a group with the requestor.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "approver-group",
+		Target:  &cfg.ApproverGroups,
+		EnvVar:  "JVS_APPROVER_GROUPS",
+		Example: "oncall@example.com",
+		Usage: `A group that approvers may share with the requestor. Any group ` +
+			`counts if unset. Can be repeated.`,
+	})
+
+	f = set.NewSection("TOKEN EXCHANGE OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "token-exchange-issuer",
+		Target:  &cfg.TokenExchangeIssuer,
+		EnvVar:  "JVS_API_TOKEN_EXCHANGE_ISSUER",
+		Example: "https://token.actions.githubusercontent.com",
+		Usage: `The issuer of third-party OIDC tokens that can be exchanged for ` +
+			`JVS tokens. Token exchange is disabled if empty.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "token-exchange-jwks-endpoint",
+		Target:  &cfg.TokenExchangeJWKSEndpoint,
+		EnvVar:  "JVS_API_TOKEN_EXCHANGE_JWKS_ENDPOINT",
+		Example: "https://token.actions.githubusercontent.com/.well-known/jwks",
+		Usage:   `The JWKS endpoint used to verify exchanged OIDC tokens.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "token-exchange-audience",
+		Target:  &cfg.TokenExchangeAudience,
+		EnvVar:  "JVS_API_TOKEN_EXCHANGE_AUDIENCE",
+		Example: "https://github.com/my-org",
+		Usage:   `The audience that exchanged OIDC tokens must be issued for.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "token-exchange-allow",
+		Target:  &cfg.TokenExchangeAllowlist,
+		EnvVar:  "JVS_API_TOKEN_EXCHANGE_ALLOWLIST",
+		Example: "repository=abcxyz/jvs",
+		Usage: `Allow exchanged OIDC tokens whose claim matches a pattern, as ` +
+			`claim=pattern. The claim is repository_owner, repository or sub, ` +
+			`and the pattern is a regular expression matching the whole claim. ` +
+			`Required with token exchange. Can be repeated.`,
+	})
+
+	f = set.NewSection("ENCRYPTION OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "encryption-jwks-endpoint",
+		Target:  &cfg.EncryptionJWKSEndpoint,
+		EnvVar:  "JVS_API_ENCRYPTION_JWKS_ENDPOINT",
+		Example: "https://keys.example.com/.well-known/jwks",
+		Usage: `The JWKS endpoint of the recipient keys to encrypt ` +
+			`justifications to. Justifications are not encrypted if empty.`,
+	})
+
+	f = set.NewSection("CERTIFICATE OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "certificate-ca-key",
+		Target:  &cfg.CertificateCAKey,
+		EnvVar:  "JVS_API_CERTIFICATE_CA_KEY",
+		Example: "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[CA_KEY]/cryptoKeyVersions/1",
+		Usage: `The KMS key version that signs client certificates. ` +
+			`Certificate issuance is disabled if empty.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "certificate-ca-path",
+		Target:  &cfg.CertificateCAPath,
+		EnvVar:  "JVS_API_CERTIFICATE_CA_PATH",
+		Example: "/etc/jvs/ca.pem",
+		Usage:   `The CA certificate of the certificate-ca-key.`,
+	})
+
+	cfg.PKCS11.addFlags(set)
+	cfg.Vault.addFlags(set)
+	cfg.Groups.addFlags(set)
+	cfg.Revocation.addFlags(set)
+	cfg.Quota.addFlags(set)
+	cfg.PAM.addFlags(set)
+	cfg.Canary.addFlags(set)
+
 	return set
 }
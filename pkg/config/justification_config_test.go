@@ -38,34 +38,239 @@ func TestJustificationConfig_ToFlags(t *testing.T) {
 				"PROJECT_ID":                   "example-project",
 				"DEV_MODE":                     "true",
 				"PORT":                         "0",
+				"JVS_SHUTDOWN_TIMEOUT":         "1m",
+				"JVS_WARMUP_TIMEOUT":           "10s",
+				"JVS_SIGNER":                   "local",
 				"JVS_KEY":                      "fake/key",
+				"JVS_KEY_PATH":                 "/etc/jvs/signing-key.pem",
 				"JVS_API_SIGNER_CACHE_TIMEOUT": "10m",
 				"JVS_API_ISSUER":               "example.com",
 				"JVS_PLUGIN_DIR":               "/var/jvs/pluginsDir",
+				"JVS_PLUGIN_SEALED_CONFIGS":    "servicenow=/etc/jvs/servicenow.sealed",
+				"JVS_PLUGIN_CONFIG_KEY":        "fake/plugin/key",
 				"JVS_API_DEFAULT_TTL":          "30m",
 				"JVS_API_MAX_TTL":              "8h",
+				"JVS_KMS_ENDPOINT":             "localhost:9090",
+				"JVS_KMS_PERMISSION_CHECK":     "fail",
+				"JVS_KMS_INSECURE":             "true",
+
+				"JVS_API_TOKEN_EXCHANGE_ISSUER":        "https://token.actions.githubusercontent.com",
+				"JVS_API_TOKEN_EXCHANGE_JWKS_ENDPOINT": "https://token.actions.githubusercontent.com/.well-known/jwks",
+				"JVS_API_TOKEN_EXCHANGE_AUDIENCE":      "https://github.com/abcxyz",
+				"JVS_API_TOKEN_EXCHANGE_ALLOWLIST":     "repository=abcxyz/jvs,sub=repo:abcxyz/pkg:.*",
+
+				"JVS_API_ENCRYPTION_JWKS_ENDPOINT": "https://keys.example.com/.well-known/jwks",
+
+				"JVS_API_CERTIFICATE_CA_KEY":  "fake/ca/key/cryptoKeyVersions/1",
+				"JVS_API_CERTIFICATE_CA_PATH": "/etc/jvs/jvs-ca.pem",
+
+				"JVS_REMOTE_PLUGINS":          "jira=jira.internal:443,github=github.internal:443",
+				"JVS_REMOTE_PLUGIN_CA_FILE":   "/etc/jvs/ca.pem",
+				"JVS_REMOTE_PLUGIN_CERT_FILE": "/etc/jvs/cert.pem",
+				"JVS_REMOTE_PLUGIN_KEY_FILE":  "/etc/jvs/key.pem",
+
+				"JVS_API_VALIDATION_CACHE":     "jira=5m,github=1m",
+				"JVS_API_MAX_ANNOTATION_SIZE":  "4000",
+				"JVS_API_CATEGORY_ALIASES":     "explanation=freeform",
+				"JVS_API_VALUE_PATTERNS":       `jira=^[A-Z]+-\d+$`,
+				"JVS_API_REQUIRED_ANNOTATIONS": "jira=jira_issue_url",
+
+				"JVS_API_MAX_CONCURRENT_REQUESTS":    "50",
+				"JVS_API_REQUEST_DEADLINE":           "5s",
+				"JVS_API_SHADOW_CATEGORIES":          "servicenow",
+				"JVS_API_NOT_BEFORE_LEEWAY":          "30s",
+				"JVS_API_MAX_START_DELAY":            "168h",
+				"JVS_API_AUDIENCE_TEMPLATE":          "https://{service}.internal.example.com",
+				"JVS_API_AUDIENCE_TEMPLATE_REQUIRED": "true",
+				"JVS_API_CLAIM_VERSION":              "0",
+				"JVS_API_CLAIM_ALIASES":              "justifications=justs",
+				"JVS_API_FLAT_JUSTIFICATIONS_CLAIM":  "justification",
+				"JVS_API_TOKEN_TYPE":                 "jvs+jwt",
+				"JVS_API_CLAIMS_NAMESPACE":           "https://jvs.example.com/",
+				"JVS_API_AUDIT_LOG_PROJECT":          "audit-project",
+				"JVS_API_AUDIT_ROUTE_PROJECT":        "route-project",
+				"JVS_API_AUDIT_ROUTE_LOG_ID":         "audit.example/data_access",
+				"JVS_EVENTS_TOPIC":                   "projects/p/topics/jvs-events",
+				"JVS_API_AUDIT_BIGQUERY_TABLE":       "audit-project.jvs.issuances",
+
+				"JVS_APPROVER_VALIDATOR": "true",
+				"JVS_APPROVER_GROUPS":    "oncall@example.com,sre@example.com",
+
+				"JVS_API_DENY_LIST_PATH":            "/etc/jvs/deny-list",
+				"JVS_API_DENY_LIST_RELOAD_INTERVAL": "1m",
+
+				"JVS_API_POLICY_PATH":                 "/var/lib/jvs/policy.json",
+				"JVS_API_POLICY_RELOAD_INTERVAL":      "2m",
+				"JVS_API_REGO_POLICY_PATH":            "/mnt/jvs/issuance.rego",
+				"JVS_API_REGO_POLICY_RELOAD_INTERVAL": "1m",
+				"JVS_API_POLICY_EXPRESSION":           `ttl <= duration("1h")`,
+				"JVS_API_DECISION_TRACE_RETENTION":    "24h",
+				"JVS_API_DECISION_TRACE_DIR":          "/mnt/jvs/decisions",
+				"JVS_API_ADMIN_PORT":                  "8081",
+				"JVS_API_ADMIN_PRINCIPALS":            "jvs-admin@example.com",
+				"JVS_API_ADMIN_AUDIENCE":              "https://jvs-admin.example.com",
+				"JVS_API_ADMIN_ISSUER":                "https://issuer.example.com",
+				"JVS_API_ADMIN_JWKS_ENDPOINT":         "https://issuer.example.com/jwks",
+				"JVS_API_REFLECTION":                  "true",
+
+				"JVS_API_DEPROVISION_WEBHOOK_PORT":   "8082",
+				"JVS_API_DEPROVISION_WEBHOOK_SECRET": "0123456789abcdef0123456789abcdef",
+				"JVS_REVOCATION_PATH":                "/mnt/jvs/revocations.json",
+				"JVS_REVOCATION_RELOAD_INTERVAL":     "1m",
+
+				"JVS_QUOTA_LIMITS": "breakglass=5,jira=20",
+				"JVS_QUOTA_PATH":   "/mnt/jvs/quota.json",
+
+				"JVS_PAM_ENTITLEMENTS": "breakglass=projects/p/locations/global/entitlements/prod-admin",
+
+				"JVS_API_CANARY_INTERVAL":      "1m",
+				"JVS_API_CANARY_JWKS_ENDPOINT": "https://keys.example.com/.well-known/jwks",
+				"JVS_API_CANARY_TIMEOUT":       "5s",
+
+				"JVS_API_TRANSPARENCY_LOG_URL":     "https://rekor.example.com",
+				"JVS_API_TRANSPARENCY_LOG_TIMEOUT": "10s",
+
+				"JVS_GROUPS_SOURCE":   "ldap",
+				"JVS_GROUPS_MAX":      "20",
+				"JVS_GROUPS_LDAP_URL": "ldaps://ldap.example.com",
 			},
 			wantConfig: &JustificationConfig{
-				ProjectID:          "example-project",
-				DevMode:            true,
-				Port:               "0",
-				KeyName:            "fake/key",
-				SignerCacheTimeout: 10 * time.Minute,
-				Issuer:             "example.com",
-				PluginDir:          "/var/jvs/pluginsDir",
-				DefaultTTL:         30 * time.Minute,
-				MaxTTL:             8 * time.Hour,
+				ProjectID:                "example-project",
+				DevMode:                  true,
+				Port:                     "0",
+				ShutdownTimeout:          time.Minute,
+				WarmupTimeout:            10 * time.Second,
+				Signer:                   "local",
+				PKCS11:                   PKCS11Config{Slot: -1},
+				Vault:                    VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+				DenyListPath:             "/etc/jvs/deny-list",
+				DenyListReloadInterval:   time.Minute,
+				PolicyPath:               "/var/lib/jvs/policy.json",
+				PolicyReloadInterval:     2 * time.Minute,
+				RegoPolicyPath:           "/mnt/jvs/issuance.rego",
+				RegoPolicyReloadInterval: time.Minute,
+				PolicyExpression:         `ttl <= duration("1h")`,
+				DecisionTraceRetention:   24 * time.Hour,
+				DecisionTraceDir:         "/mnt/jvs/decisions",
+				AdminPort:                "8081",
+				AdminPrincipals:          []string{"jvs-admin@example.com"},
+				AdminAudience:            "https://jvs-admin.example.com",
+				AdminIssuer:              "https://issuer.example.com",
+				AdminJWKSEndpoint:        "https://issuer.example.com/jwks",
+				Reflection:               true,
+				DeprovisionWebhookPort:   "8082",
+				DeprovisionWebhookSecret: "0123456789abcdef0123456789abcdef",
+				Revocation: RevocationConfig{
+					Path:           "/mnt/jvs/revocations.json",
+					ReloadInterval: time.Minute,
+				},
+				Quota: QuotaConfig{
+					Limits: []string{"breakglass=5", "jira=20"},
+					Path:   "/mnt/jvs/quota.json",
+				},
+				PAM: PAMConfig{
+					Entitlements: []string{"breakglass=projects/p/locations/global/entitlements/prod-admin"},
+				},
+				Canary: CanaryConfig{
+					Interval:     time.Minute,
+					JWKSEndpoint: "https://keys.example.com/.well-known/jwks",
+					Timeout:      5 * time.Second,
+				},
+				TransparencyLogURL:     "https://rekor.example.com",
+				TransparencyLogTimeout: 10 * time.Second,
+				Groups: GroupsConfig{
+					Source:             GroupsSourceLDAP,
+					MaxGroups:          20,
+					Timeout:            5 * time.Second,
+					CacheTTL:           5 * time.Minute,
+					LDAPURL:            "ldaps://ldap.example.com",
+					LDAPUserFilter:     "(mail={email})",
+					LDAPGroupFilter:    "(member={dn})",
+					LDAPGroupAttribute: "cn",
+				},
+				KeyName:             "fake/key",
+				KeyPath:             "/etc/jvs/signing-key.pem",
+				SignerCacheTimeout:  10 * time.Minute,
+				Issuer:              "example.com",
+				PluginDir:           "/var/jvs/pluginsDir",
+				PluginSealedConfigs: []string{"servicenow=/etc/jvs/servicenow.sealed"},
+				PluginConfigKey:     "fake/plugin/key",
+				DefaultTTL:          30 * time.Minute,
+				MaxTTL:              8 * time.Hour,
+				KMSEndpoint:         "localhost:9090",
+				KMSPermissionCheck:  "fail",
+				KMSInsecure:         true,
+
+				TokenExchangeIssuer:       "https://token.actions.githubusercontent.com",
+				TokenExchangeJWKSEndpoint: "https://token.actions.githubusercontent.com/.well-known/jwks",
+				TokenExchangeAudience:     "https://github.com/abcxyz",
+				TokenExchangeAllowlist:    []string{"repository=abcxyz/jvs", "sub=repo:abcxyz/pkg:.*"},
+
+				EncryptionJWKSEndpoint: "https://keys.example.com/.well-known/jwks",
+
+				CertificateCAKey:  "fake/ca/key/cryptoKeyVersions/1",
+				CertificateCAPath: "/etc/jvs/jvs-ca.pem",
+
+				RemotePlugins:        []string{"jira=jira.internal:443", "github=github.internal:443"},
+				RemotePluginCAFile:   "/etc/jvs/ca.pem",
+				RemotePluginCertFile: "/etc/jvs/cert.pem",
+				RemotePluginKeyFile:  "/etc/jvs/key.pem",
+
+				ValidationCache:     []string{"jira=5m", "github=1m"},
+				MaxAnnotationSize:   4000,
+				CategoryAliases:     []string{"explanation=freeform"},
+				ValuePatterns:       []string{`jira=^[A-Z]+-\d+$`},
+				RequiredAnnotations: []string{"jira=jira_issue_url"},
+
+				MaxConcurrentRequests:    50,
+				RequestDeadline:          5 * time.Second,
+				ShadowCategories:         []string{"servicenow"},
+				NotBeforeLeeway:          30 * time.Second,
+				MaxStartDelay:            168 * time.Hour,
+				AudienceTemplate:         "https://{service}.internal.example.com",
+				AudienceTemplateRequired: true,
+				ClaimAliases:             []string{"justifications=justs"},
+				FlatJustificationsClaim:  "justification",
+				TokenType:                "jvs+jwt",
+				ClaimsNamespace:          "https://jvs.example.com/",
+				AuditLogProject:          "audit-project",
+				AuditRouteProject:        "route-project",
+				AuditRouteLogID:          "audit.example/data_access",
+				EventsTopic:              "projects/p/topics/jvs-events",
+				AuditBigQueryTable:       "audit-project.jvs.issuances",
+
+				ApproverValidator: true,
+				ApproverGroups:    []string{"oncall@example.com", "sre@example.com"},
 			},
 		},
 		{
 			name: "default_values",
 			wantConfig: &JustificationConfig{
-				Port:               "8080",
-				SignerCacheTimeout: 5 * time.Minute,
-				Issuer:             "jvs.abcxyz.dev",
-				PluginDir:          "/var/jvs/plugins",
-				DefaultTTL:         15 * time.Minute,
-				MaxTTL:             4 * time.Hour,
+				Port:                     "8080",
+				ShutdownTimeout:          30 * time.Second,
+				WarmupTimeout:            30 * time.Second,
+				Signer:                   "kms",
+				KMSPermissionCheck:       "warn",
+				PKCS11:                   PKCS11Config{Slot: -1},
+				Vault:                    VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+				Groups:                   defaultGroupsConfig,
+				Revocation:               defaultRevocationConfig,
+				Canary:                   CanaryConfig{Timeout: 10 * time.Second},
+				DenyListReloadInterval:   30 * time.Second,
+				PolicyReloadInterval:     30 * time.Second,
+				RegoPolicyReloadInterval: 30 * time.Second,
+				AdminIssuer:              "https://accounts.google.com",
+				AdminJWKSEndpoint:        "https://www.googleapis.com/oauth2/v3/certs",
+				TransparencyLogTimeout:   5 * time.Second,
+				RequestDeadline:          10 * time.Second,
+				AuditRouteLogID:          "audit.abcxyz/data_access",
+				SignerCacheTimeout:       5 * time.Minute,
+				Issuer:                   "jvs.abcxyz.dev",
+				PluginDir:                "/var/jvs/plugins",
+				DefaultTTL:               15 * time.Minute,
+				MaxTTL:                   4 * time.Hour,
+				MaxAnnotationSize:        2000,
+				ClaimVersion:             1,
 			},
 		},
 	}
@@ -106,6 +311,7 @@ func TestJustificationConfig_Validate(t *testing.T) {
 				PluginDir:          "/var/jvs/pluginsDir",
 				DefaultTTL:         15 * time.Minute,
 				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
 			},
 		},
 		{
@@ -119,6 +325,7 @@ func TestJustificationConfig_Validate(t *testing.T) {
 				PluginDir:          "./pluginsDir",
 				DefaultTTL:         15 * time.Minute,
 				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
 			},
 		},
 		{
@@ -131,6 +338,7 @@ func TestJustificationConfig_Validate(t *testing.T) {
 				PluginDir:          "/var/jvs/pluginsDir",
 				DefaultTTL:         15 * time.Minute,
 				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
 			},
 			wantErr: "empty ProjectID",
 		},
@@ -144,6 +352,7 @@ func TestJustificationConfig_Validate(t *testing.T) {
 				PluginDir:          "/var/jvs/pluginsDir",
 				DefaultTTL:         15 * time.Minute,
 				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
 			},
 			wantErr: "empty KeyName",
 		},
@@ -158,6 +367,7 @@ func TestJustificationConfig_Validate(t *testing.T) {
 				PluginDir:          "/var/jvs/pluginsDir",
 				DefaultTTL:         15 * time.Minute,
 				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
 			},
 			wantErr: "cache timeout must be a positive duration",
 		},
@@ -172,9 +382,955 @@ func TestJustificationConfig_Validate(t *testing.T) {
 				PluginDir:          "/var/jvs/pluginsDir",
 				DefaultTTL:         15 * time.Minute,
 				MaxTTL:             10 * time.Minute,
+				MaxAnnotationSize:  2000,
 			},
 			wantErr: "must be less than or equal to the max ttl",
 		},
+		{
+			name: "kms_insecure_without_endpoint",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				KMSInsecure:        true,
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+			wantErr: "KMSInsecure requires KMSEndpoint to be set",
+		},
+		{
+			name: "token_exchange_missing_options",
+			cfg: &JustificationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyName:             "fake/key",
+				SignerCacheTimeout:  5 * time.Minute,
+				Issuer:              "jvs.abcxyz.dev",
+				PluginDir:           "/var/jvs/pluginsDir",
+				DefaultTTL:          15 * time.Minute,
+				MaxTTL:              4 * time.Hour,
+				MaxAnnotationSize:   2000,
+				TokenExchangeIssuer: "https://token.actions.githubusercontent.com",
+			},
+			wantErr: "empty TokenExchangeJWKSEndpoint\nempty TokenExchangeAudience\nempty TokenExchangeAllowlist",
+		},
+		{
+			name: "token_exchange_invalid_allowlist",
+			cfg: &JustificationConfig{
+				ProjectID:                 "example-project",
+				Port:                      "8080",
+				KeyName:                   "fake/key",
+				SignerCacheTimeout:        5 * time.Minute,
+				Issuer:                    "jvs.abcxyz.dev",
+				PluginDir:                 "/var/jvs/pluginsDir",
+				DefaultTTL:                15 * time.Minute,
+				MaxTTL:                    4 * time.Hour,
+				MaxAnnotationSize:         2000,
+				TokenExchangeIssuer:       "https://token.actions.githubusercontent.com",
+				TokenExchangeJWKSEndpoint: "https://token.actions.githubusercontent.com/.well-known/jwks",
+				TokenExchangeAudience:     "https://github.com/abcxyz",
+				TokenExchangeAllowlist:    []string{"workflow=deploy"},
+			},
+			wantErr: `token exchange allowlist claim "workflow" must be one of ["repository_owner" "repository" "sub"]`,
+		},
+		{
+			name: "certificate_ca_path_missing",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				CertificateCAKey:   "fake/ca/key/cryptoKeyVersions/1",
+			},
+			wantErr: "CertificateCAKey and CertificateCAPath must be set together",
+		},
+		{
+			name: "invalid_remote_plugin",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				RemotePlugins:      []string{"jira.internal:443"},
+			},
+			wantErr: `remote plugin "jira.internal:443" must be in the format category=host:port`,
+		},
+		{
+			name: "duplicate_remote_plugin",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				RemotePlugins:      []string{"jira=a:443", "jira=b:443"},
+			},
+			wantErr: `remote plugin category "jira" is specified more than once`,
+		},
+		{
+			name: "invalid_plugin_sealed_config",
+			cfg: &JustificationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyName:             "fake/key",
+				SignerCacheTimeout:  5 * time.Minute,
+				Issuer:              "jvs.abcxyz.dev",
+				PluginDir:           "/var/jvs/pluginsDir",
+				DefaultTTL:          15 * time.Minute,
+				MaxTTL:              4 * time.Hour,
+				MaxAnnotationSize:   2000,
+				PluginSealedConfigs: []string{"/etc/jvs/servicenow.sealed"},
+				PluginConfigKey:     "fake/plugin/key",
+			},
+			wantErr: `plugin sealed config "/etc/jvs/servicenow.sealed" must be in the format name=path`,
+		},
+		{
+			name: "duplicate_plugin_sealed_config",
+			cfg: &JustificationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyName:             "fake/key",
+				SignerCacheTimeout:  5 * time.Minute,
+				Issuer:              "jvs.abcxyz.dev",
+				PluginDir:           "/var/jvs/pluginsDir",
+				DefaultTTL:          15 * time.Minute,
+				MaxTTL:              4 * time.Hour,
+				MaxAnnotationSize:   2000,
+				PluginSealedConfigs: []string{"servicenow=a.sealed", "servicenow=b.sealed"},
+				PluginConfigKey:     "fake/plugin/key",
+			},
+			wantErr: `sealed config of plugin "servicenow" is specified more than once`,
+		},
+		{
+			name: "plugin_sealed_config_without_key",
+			cfg: &JustificationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyName:             "fake/key",
+				SignerCacheTimeout:  5 * time.Minute,
+				Issuer:              "jvs.abcxyz.dev",
+				PluginDir:           "/var/jvs/pluginsDir",
+				DefaultTTL:          15 * time.Minute,
+				MaxTTL:              4 * time.Hour,
+				MaxAnnotationSize:   2000,
+				PluginSealedConfigs: []string{"servicenow=/etc/jvs/servicenow.sealed"},
+			},
+			wantErr: "PluginConfigKey must be set with PluginSealedConfigs",
+		},
+		{
+			name: "remote_plugin_cert_without_key",
+			cfg: &JustificationConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyName:              "fake/key",
+				SignerCacheTimeout:   5 * time.Minute,
+				Issuer:               "jvs.abcxyz.dev",
+				PluginDir:            "/var/jvs/pluginsDir",
+				DefaultTTL:           15 * time.Minute,
+				MaxTTL:               4 * time.Hour,
+				MaxAnnotationSize:    2000,
+				RemotePlugins:        []string{"jira=jira.internal:443"},
+				RemotePluginCertFile: "/etc/jvs/cert.pem",
+			},
+			wantErr: "RemotePluginCertFile and RemotePluginKeyFile must be set together",
+		},
+		{
+			name: "remote_plugin_insecure_with_tls",
+			cfg: &JustificationConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyName:              "fake/key",
+				SignerCacheTimeout:   5 * time.Minute,
+				Issuer:               "jvs.abcxyz.dev",
+				PluginDir:            "/var/jvs/pluginsDir",
+				DefaultTTL:           15 * time.Minute,
+				MaxTTL:               4 * time.Hour,
+				MaxAnnotationSize:    2000,
+				RemotePlugins:        []string{"jira=jira.internal:443"},
+				RemotePluginCAFile:   "/etc/jvs/ca.pem",
+				RemotePluginInsecure: true,
+			},
+			wantErr: "RemotePluginInsecure cannot be used with remote plugin TLS files",
+		},
+		{
+			name: "invalid_validation_cache",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ValidationCache:    []string{"jira"},
+			},
+			wantErr: `validation cache "jira" must be in the format category=ttl`,
+		},
+		{
+			name: "negative_max_concurrent_requests",
+			cfg: &JustificationConfig{
+				ProjectID:             "example-project",
+				Port:                  "8080",
+				KeyName:               "fake/key",
+				SignerCacheTimeout:    5 * time.Minute,
+				Issuer:                "jvs.abcxyz.dev",
+				PluginDir:             "/var/jvs/pluginsDir",
+				DefaultTTL:            15 * time.Minute,
+				MaxTTL:                4 * time.Hour,
+				MaxAnnotationSize:     2000,
+				MaxConcurrentRequests: -1,
+			},
+			wantErr: "max concurrent requests cannot be negative, got -1",
+		},
+		{
+			name: "negative_request_deadline",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				RequestDeadline:    -time.Second,
+			},
+			wantErr: "request deadline cannot be negative, got -1s",
+		},
+		{
+			name: "invalid_audit_route_log_id",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				AuditRouteProject:  "route-project",
+				AuditRouteLogID:    "audit log",
+			},
+			wantErr: `audit route log id "audit log" must be 1-512 letters`,
+		},
+		{
+			name: "local_signer",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				Signer:             SignerLocal,
+				KeyPath:            "/etc/jvs/signing-key.pem",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+		},
+		{
+			name: "local_signer_missing_options",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				Signer:             SignerLocal,
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				CertificateCAKey:   "fake/ca/key/cryptoKeyVersions/1",
+				CertificateCAPath:  "/etc/jvs/jvs-ca.pem",
+			},
+			wantErr: "empty KeyPath\nCertificateCAKey requires the \"kms\" signer",
+		},
+		{
+			name: "pkcs11_signer",
+			cfg: &JustificationConfig{
+				ProjectID: "example-project",
+				Port:      "8080",
+				Signer:    SignerPKCS11,
+				KeyName:   "jvs-signing",
+				PKCS11: PKCS11Config{
+					Module:     "/usr/lib/softhsm/libsofthsm2.so",
+					TokenLabel: "jvs",
+					Slot:       -1,
+					PIN:        "1234",
+				},
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+		},
+		{
+			name: "vault_signer",
+			cfg: &JustificationConfig{
+				ProjectID: "example-project",
+				Port:      "8080",
+				Signer:    SignerVault,
+				KeyName:   "jvs-signing",
+				Vault: VaultConfig{
+					Address:      "https://vault.example.com:8200",
+					Token:        "s.token",
+					TransitMount: "transit",
+					Timeout:      10 * time.Second,
+				},
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+		},
+		{
+			name: "pkcs11_signer_missing_options",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				Signer:             SignerPKCS11,
+				PKCS11:             PKCS11Config{Slot: -1},
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				CertificateCAKey:   "fake/ca/key/cryptoKeyVersions/1",
+				CertificateCAPath:  "/etc/jvs/jvs-ca.pem",
+			},
+			wantErr: "empty KeyName\n" +
+				"empty PKCS#11 Module\n" +
+				"PKCS#11 TokenLabel or Slot must be set\n" +
+				"empty PKCS#11 PIN\n" +
+				"CertificateCAKey requires the \"kms\" signer",
+		},
+		{
+			name: "invalid_signer",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				Signer:             "hsm",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+			wantErr: `signer must be "kms", "local", "pkcs11" or "vault", got "hsm"`,
+		},
+		{
+			name: "invalid_events_topic",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				EventsTopic:        "projects/p/subscriptions/s",
+			},
+			wantErr: `events topic "projects/p/subscriptions/s" must be in the format projects/*/topics/*`,
+		},
+		{
+			name: "invalid_audit_bigquery_table",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				AuditBigQueryTable: "jvs.issuances",
+			},
+			wantErr: `audit bigquery table "jvs.issuances" must be in the format project.dataset.table`,
+		},
+		{
+			name: "audit_bigquery_and_postgres",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				AuditBigQueryTable: "audit-project.jvs.issuances",
+				AuditPostgresURL:   "postgres://jvs@localhost/jvs",
+			},
+			wantErr: `only one of audit bigquery table, audit postgres url, audit spanner database and audit snapshot path can be set`,
+		},
+		{
+			name: "invalid_audit_postgres_url",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				AuditPostgresURL:   "mysql://jvs@localhost/jvs",
+			},
+			wantErr: `audit postgres url must be a postgres:// url`,
+		},
+		{
+			name: "invalid_audit_spanner_database",
+			cfg: &JustificationConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyName:              "fake/key",
+				SignerCacheTimeout:   5 * time.Minute,
+				Issuer:               "jvs.abcxyz.dev",
+				PluginDir:            "/var/jvs/pluginsDir",
+				DefaultTTL:           15 * time.Minute,
+				MaxTTL:               4 * time.Hour,
+				MaxAnnotationSize:    2000,
+				AuditSpannerDatabase: "projects/p/instances/i",
+			},
+			wantErr: `audit spanner database "projects/p/instances/i" must be in the format projects/*/instances/*/databases/*`,
+		},
+		{
+			name: "invalid_kms_permission_check",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				KMSPermissionCheck: "strict",
+			},
+			wantErr: `kms permission check must be one of "off", "warn" or "fail", got "strict"`,
+		},
+		{
+			name: "negative_shutdown_timeout",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				ShutdownTimeout:    -time.Second,
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+			wantErr: "shutdown timeout cannot be negative, got -1s",
+		},
+		{
+			name: "negative_warmup_timeout",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				WarmupTimeout:      -time.Second,
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+			},
+			wantErr: "warmup timeout cannot be negative, got -1s",
+		},
+		{
+			name: "negative_not_before_leeway",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				NotBeforeLeeway:    -30 * time.Second,
+			},
+			wantErr: "not before leeway cannot be negative, got -30s",
+		},
+		{
+			name: "negative_max_start_delay",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				MaxStartDelay:      -time.Hour,
+			},
+			wantErr: "max start delay cannot be negative, got -1h0m0s",
+		},
+		{
+			name: "audience_template_without_service",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				AudienceTemplate:   "https://internal.example.com",
+			},
+			wantErr: `audience template must contain {service} exactly once, got "https://internal.example.com"`,
+		},
+		{
+			name: "audience_template_required_without_template",
+			cfg: &JustificationConfig{
+				ProjectID:                "example-project",
+				Port:                     "8080",
+				KeyName:                  "fake/key",
+				SignerCacheTimeout:       5 * time.Minute,
+				Issuer:                   "jvs.abcxyz.dev",
+				PluginDir:                "/var/jvs/pluginsDir",
+				DefaultTTL:               15 * time.Minute,
+				MaxTTL:                   4 * time.Hour,
+				MaxAnnotationSize:        2000,
+				AudienceTemplateRequired: true,
+			},
+			wantErr: "audience template must be set to require audience services",
+		},
+		{
+			name: "deny_list_without_reload_interval",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				DenyListPath:       "/etc/jvs/deny-list",
+			},
+			wantErr: "deny list reload interval must be a positive duration, got 0s",
+		},
+		{
+			name: "rego_policy_in_cloud_storage",
+			cfg: &JustificationConfig{
+				ProjectID:                "example-project",
+				Port:                     "8080",
+				KeyName:                  "fake/key",
+				SignerCacheTimeout:       5 * time.Minute,
+				Issuer:                   "jvs.abcxyz.dev",
+				PluginDir:                "/var/jvs/pluginsDir",
+				DefaultTTL:               15 * time.Minute,
+				MaxTTL:                   4 * time.Hour,
+				MaxAnnotationSize:        2000,
+				RegoPolicyPath:           "gs://jvs-policies/issuance.rego",
+				RegoPolicyReloadInterval: time.Minute,
+			},
+			wantErr: "rego policy path must be a local path, mount gs://jvs-policies/issuance.rego as a volume instead",
+		},
+		{
+			name: "decision_trace_dir_without_retention",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				DecisionTraceDir:   "/mnt/jvs/decisions",
+			},
+			wantErr: "decision trace dir requires decision trace retention to be set",
+		},
+		{
+			name: "transparency_log_without_timeout",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				TransparencyLogURL: "https://rekor.example.com",
+			},
+			wantErr: "transparency log timeout must be a positive duration, got 0s",
+		},
+		{
+			name: "admin_port_missing_options",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				AdminPort:          "8080",
+			},
+			wantErr: `admin port must be different from port "8080"
+policy path must be set to serve the admin api
+admin principals must be set to serve the admin api
+admin audience must be set to serve the admin api
+admin issuer and jwks endpoint must be set to serve the admin api`,
+		},
+		{
+			name: "deprovision_webhook_missing_options",
+			cfg: &JustificationConfig{
+				ProjectID:                "example-project",
+				Port:                     "8080",
+				KeyName:                  "fake/key",
+				SignerCacheTimeout:       5 * time.Minute,
+				Issuer:                   "jvs.abcxyz.dev",
+				PluginDir:                "/var/jvs/pluginsDir",
+				DefaultTTL:               15 * time.Minute,
+				MaxTTL:                   4 * time.Hour,
+				MaxAnnotationSize:        2000,
+				DeprovisionWebhookPort:   "8080",
+				DeprovisionWebhookSecret: "short",
+			},
+			wantErr: `deprovision webhook port must be different from port "8080"
+deprovision webhook secret must be at least 32 characters
+deny list or revocation must be enabled to serve the deprovision webhook`,
+		},
+		{
+			name: "revocation_path_and_postgres_url",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				Revocation: RevocationConfig{
+					Path:           "/mnt/jvs/revocations.json",
+					PostgresURL:    "postgres://jvs@localhost/jvs",
+					ReloadInterval: 30 * time.Second,
+				},
+			},
+			wantErr: "only one of revocation path and revocation postgres url can be set",
+		},
+		{
+			name: "unsupported_claim_version",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ClaimVersion:       2,
+			},
+			wantErr: "claim version must be between 0 and 1, got 2",
+		},
+		{
+			name: "chained_category_alias",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				CategoryAliases:    []string{"reason=explanation", "explanation=freeform"},
+			},
+			wantErr: `category alias "reason" maps to "explanation", which is also an alias`,
+		},
+		{
+			name: "invalid_category_alias",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				CategoryAliases:    []string{"explanation"},
+			},
+			wantErr: `category alias "explanation" must be in the format old=new`,
+		},
+		{
+			name: "invalid_claim_alias",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ClaimAliases:       []string{"justifications"},
+			},
+			wantErr: `claim alias "justifications" must be in the format alias=claim`,
+		},
+		{
+			name: "reserved_claim_alias",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ClaimAliases:       []string{"sub=req"},
+			},
+			wantErr: `claim alias "sub" is a reserved claim`,
+		},
+		{
+			name: "chained_claim_alias",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ClaimAliases:       []string{"justifications=justs", "reasons=justifications"},
+			},
+			wantErr: `claim alias "reasons" duplicates "justifications", which is also an alias`,
+		},
+		{
+			name: "flat_justifications_claim_alias",
+			cfg: &JustificationConfig{
+				ProjectID:               "example-project",
+				Port:                    "8080",
+				KeyName:                 "fake/key",
+				SignerCacheTimeout:      5 * time.Minute,
+				Issuer:                  "jvs.abcxyz.dev",
+				PluginDir:               "/var/jvs/pluginsDir",
+				DefaultTTL:              15 * time.Minute,
+				MaxTTL:                  4 * time.Hour,
+				MaxAnnotationSize:       2000,
+				ClaimAliases:            []string{"justification=justs"},
+				FlatJustificationsClaim: "justification",
+			},
+			wantErr: `flat justifications claim "justification" is also a claim alias`,
+		},
+		{
+			name: "flat_justifications_claim_encrypted",
+			cfg: &JustificationConfig{
+				ProjectID:               "example-project",
+				Port:                    "8080",
+				KeyName:                 "fake/key",
+				SignerCacheTimeout:      5 * time.Minute,
+				Issuer:                  "jvs.abcxyz.dev",
+				PluginDir:               "/var/jvs/pluginsDir",
+				DefaultTTL:              15 * time.Minute,
+				MaxTTL:                  4 * time.Hour,
+				MaxAnnotationSize:       2000,
+				FlatJustificationsClaim: "justs",
+				EncryptionJWKSEndpoint:  "https://keys.example.com/.well-known/jwks",
+			},
+			wantErr: `flat justifications claim "justs" is a reserved claim
+flat justifications claim can't be used with justification encryption`,
+		},
+		{
+			name: "token_type_and_claims_namespace",
+			cfg: &JustificationConfig{
+				ProjectID:               "example-project",
+				Port:                    "8080",
+				KeyName:                 "fake/key",
+				SignerCacheTimeout:      5 * time.Minute,
+				Issuer:                  "jvs.abcxyz.dev",
+				PluginDir:               "/var/jvs/pluginsDir",
+				DefaultTTL:              15 * time.Minute,
+				MaxTTL:                  4 * time.Hour,
+				MaxAnnotationSize:       2000,
+				TokenType:               "application/jvs+jwt",
+				ClaimsNamespace:         "jvs_",
+				ClaimAliases:            []string{"justifications=justs"},
+				FlatJustificationsClaim: "justification",
+			},
+		},
+		{
+			name: "invalid_token_type_and_claims_namespace",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				TokenType:          "jvs-receipt+json",
+				ClaimsNamespace:    "jvs ",
+			},
+			wantErr: `token type "jvs-receipt+json" is the type of another jvs object
+claims namespace "jvs " must be up to 128 letters, digits or "._~:/+-"`,
+		},
+		{
+			name: "namespaced_claim_aliases",
+			cfg: &JustificationConfig{
+				ProjectID:               "example-project",
+				Port:                    "8080",
+				KeyName:                 "fake/key",
+				SignerCacheTimeout:      5 * time.Minute,
+				Issuer:                  "jvs.abcxyz.dev",
+				PluginDir:               "/var/jvs/pluginsDir",
+				DefaultTTL:              15 * time.Minute,
+				MaxTTL:                  4 * time.Hour,
+				MaxAnnotationSize:       2000,
+				ClaimsNamespace:         "jvs_",
+				ClaimAliases:            []string{"jvs_req=justs"},
+				FlatJustificationsClaim: "jvs_justs",
+			},
+			wantErr: `claim alias "jvs_req" is a namespaced claim
+flat justifications claim "jvs_justs" is a namespaced claim`,
+		},
+		{
+			name: "non_positive_validation_cache_ttl",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ValidationCache:    []string{"jira=0s"},
+			},
+			wantErr: `validation cache ttl for category "jira" must be a positive duration, got 0s`,
+		},
+		{
+			name: "invalid_value_pattern_format",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ValuePatterns:      []string{"jira"},
+			},
+			wantErr: `value pattern "jira" must be in the format category=pattern`,
+		},
+		{
+			name: "duplicate_value_pattern",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ValuePatterns:      []string{"jira=^JVS-", "jira=^OPS-"},
+			},
+			wantErr: `value pattern category "jira" is specified more than once`,
+		},
+		{
+			name: "invalid_value_pattern",
+			cfg: &JustificationConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyName:            "fake/key",
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "jvs.abcxyz.dev",
+				PluginDir:          "/var/jvs/pluginsDir",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  2000,
+				ValuePatterns:      []string{"jira=^[A-Z"},
+			},
+			wantErr: `value pattern for category "jira" is invalid`,
+		},
+		{
+			name: "invalid_required_annotation",
+			cfg: &JustificationConfig{
+				ProjectID:           "example-project",
+				Port:                "8080",
+				KeyName:             "fake/key",
+				SignerCacheTimeout:  5 * time.Minute,
+				Issuer:              "jvs.abcxyz.dev",
+				PluginDir:           "/var/jvs/pluginsDir",
+				DefaultTTL:          15 * time.Minute,
+				MaxTTL:              4 * time.Hour,
+				MaxAnnotationSize:   2000,
+				RequiredAnnotations: []string{"jira="},
+			},
+			wantErr: `required annotation "jira=" must be in the format category=annotation`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -0,0 +1,113 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// entitlementRegexp matches the resource names of PAM entitlements.
+var entitlementRegexp = regexp.MustCompile(
+	`^(?:projects|folders|organizations)/[^/]+/locations/[^/]+/entitlements/[^/]+$`)
+
+// PAMConfig is the configuration of the Privileged Access Manager grants filed
+// when tokens are minted with justifications of the configured categories.
+type PAMConfig struct {
+	// Entitlements are the PAM entitlements of categories, each in the format
+	// "category=entitlement", e.g.
+	// "breakglass=projects/p/locations/global/entitlements/prod-admin".
+	Entitlements []string `env:"JVS_PAM_ENTITLEMENTS,overwrite"`
+
+	// Endpoint is the endpoint of the Privileged Access Manager API. If empty,
+	// the default endpoint is used.
+	Endpoint string `env:"JVS_PAM_ENDPOINT,overwrite"`
+}
+
+// Enabled reports whether any entitlements are configured.
+func (cfg *PAMConfig) Enabled() bool {
+	return len(cfg.Entitlements) > 0
+}
+
+// Validate checks if the config is valid.
+func (cfg *PAMConfig) Validate() (merr error) {
+	if _, err := cfg.EntitlementsByCategory(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if cfg.Endpoint != "" {
+		if u, err := url.Parse(cfg.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			merr = errors.Join(merr, fmt.Errorf("pam endpoint %q must be an absolute URL", cfg.Endpoint))
+		}
+	}
+
+	return
+}
+
+// EntitlementsByCategory parses the Entitlements into the entitlement names of
+// the categories.
+func (cfg *PAMConfig) EntitlementsByCategory() (map[string]string, error) {
+	var merr error
+	entitlements := make(map[string]string, len(cfg.Entitlements))
+	for _, v := range cfg.Entitlements {
+		category, name, ok := strings.Cut(v, "=")
+		category, name = strings.TrimSpace(category), strings.TrimSpace(name)
+		if !ok || category == "" {
+			merr = errors.Join(merr, fmt.Errorf("pam entitlement %q must be in the format category=entitlement", v))
+			continue
+		}
+		if !entitlementRegexp.MatchString(name) {
+			merr = errors.Join(merr, fmt.Errorf("pam entitlement of category %q must be an entitlement name, got %q", category, name))
+			continue
+		}
+		if _, ok := entitlements[category]; ok {
+			merr = errors.Join(merr, fmt.Errorf("pam entitlement of category %q is specified more than once", category))
+			continue
+		}
+		entitlements[category] = name
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	return entitlements, nil
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *PAMConfig) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("PAM OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "pam-entitlement",
+		Target:  &cfg.Entitlements,
+		EnvVar:  "JVS_PAM_ENTITLEMENTS",
+		Example: "breakglass=projects/p/locations/global/entitlements/prod-admin",
+		Usage: `The Privileged Access Manager entitlement a grant of is filed ` +
+			`for tokens with a justification of the category, in the format ` +
+			`category=entitlement. Repeat it for more categories.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "pam-endpoint",
+		Target:  &cfg.Endpoint,
+		EnvVar:  "JVS_PAM_ENDPOINT",
+		Example: "https://privilegedaccessmanager.googleapis.com/",
+		Usage:   `The endpoint of the Privileged Access Manager API.`,
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPAMConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name             string
+		cfg              *PAMConfig
+		wantEntitlements map[string]string
+		wantErr          string
+	}{
+		{
+			name:             "disabled",
+			cfg:              &PAMConfig{},
+			wantEntitlements: map[string]string{},
+		},
+		{
+			name: "entitlements",
+			cfg: &PAMConfig{
+				Entitlements: []string{
+					"breakglass=projects/p/locations/global/entitlements/prod-admin",
+					" jira = folders/123/locations/global/entitlements/support ",
+				},
+				Endpoint: "https://privilegedaccessmanager.googleapis.com/",
+			},
+			wantEntitlements: map[string]string{
+				"breakglass": "projects/p/locations/global/entitlements/prod-admin",
+				"jira":       "folders/123/locations/global/entitlements/support",
+			},
+		},
+		{
+			name: "invalid_endpoint",
+			cfg: &PAMConfig{
+				Entitlements: []string{"breakglass=projects/p/locations/global/entitlements/prod-admin"},
+				Endpoint:     "privilegedaccessmanager.googleapis.com",
+			},
+			wantEntitlements: map[string]string{
+				"breakglass": "projects/p/locations/global/entitlements/prod-admin",
+			},
+			wantErr: `pam endpoint "privilegedaccessmanager.googleapis.com" must be an absolute URL`,
+		},
+		{
+			name: "invalid_entitlements",
+			cfg: &PAMConfig{
+				Entitlements: []string{
+					"breakglass",
+					"jira=prod-admin",
+					"github=projects/p/locations/global/entitlements/a",
+					"github=projects/p/locations/global/entitlements/b",
+				},
+			},
+			wantErr: `pam entitlement "breakglass" must be in the format category=entitlement` + "\n" +
+				`pam entitlement of category "jira" must be an entitlement name, got "prod-admin"` + "\n" +
+				`pam entitlement of category "github" is specified more than once`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+
+			entitlements, _ := tc.cfg.EntitlementsByCategory()
+			if diff := cmp.Diff(tc.wantEntitlements, entitlements); diff != "" {
+				t.Errorf("entitlements (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
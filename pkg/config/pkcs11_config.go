@@ -0,0 +1,94 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// PKCS11Config is the configuration of the HSM keys are stored in with the
+// SignerPKCS11 signer.
+type PKCS11Config struct {
+	// Module is the path of the PKCS#11 module of the HSM, e.g.
+	// `/usr/lib/softhsm/libsofthsm2.so`.
+	Module string `env:"JVS_PKCS11_MODULE,overwrite"`
+
+	// TokenLabel is the label of the token that holds the keys. The slot of the
+	// token is discovered by its label, unless Slot is set.
+	TokenLabel string `env:"JVS_PKCS11_TOKEN_LABEL,overwrite"`
+
+	// Slot is the ID of the slot that holds the keys. It is -1 to discover the
+	// slot by TokenLabel.
+	Slot int `env:"JVS_PKCS11_SLOT,overwrite,default=-1"`
+
+	// PIN is the user PIN of the token.
+	PIN string `json:"-" env:"JVS_PKCS11_PIN,overwrite"`
+}
+
+// Validate checks if the config is valid.
+func (cfg *PKCS11Config) Validate() (merr error) {
+	if cfg.Module == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty PKCS#11 Module"))
+	}
+
+	if cfg.TokenLabel == "" && cfg.Slot < 0 {
+		merr = errors.Join(merr, fmt.Errorf("PKCS#11 TokenLabel or Slot must be set"))
+	}
+
+	if cfg.PIN == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty PKCS#11 PIN"))
+	}
+
+	return
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *PKCS11Config) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("PKCS#11 OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "pkcs11-module",
+		Target:  &cfg.Module,
+		EnvVar:  "JVS_PKCS11_MODULE",
+		Example: "/usr/lib/softhsm/libsofthsm2.so",
+		Usage:   `The PKCS#11 module of the HSM, with the "pkcs11" signer.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "pkcs11-token-label",
+		Target:  &cfg.TokenLabel,
+		EnvVar:  "JVS_PKCS11_TOKEN_LABEL",
+		Example: "jvs",
+		Usage:   `The label of the HSM token that holds the keys.`,
+	})
+
+	f.IntVar(&cli.IntVar{
+		Name:    "pkcs11-slot",
+		Target:  &cfg.Slot,
+		EnvVar:  "JVS_PKCS11_SLOT",
+		Default: -1,
+		Usage:   `The HSM slot that holds the keys. If -1, the slot is found by token label.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "pkcs11-pin",
+		Target: &cfg.PIN,
+		EnvVar: "JVS_PKCS11_PIN",
+		Usage:  `The user PIN of the HSM token. Prefer setting it in the environment.`,
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPKCS11Config_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *PKCS11Config
+		wantErr string
+	}{
+		{
+			name: "token_label",
+			cfg: &PKCS11Config{
+				Module:     "/usr/lib/softhsm/libsofthsm2.so",
+				TokenLabel: "jvs",
+				Slot:       -1,
+				PIN:        "1234",
+			},
+		},
+		{
+			name: "slot",
+			cfg: &PKCS11Config{
+				Module: "/usr/lib/softhsm/libsofthsm2.so",
+				Slot:   0,
+				PIN:    "1234",
+			},
+		},
+		{
+			name: "empty_values",
+			cfg: &PKCS11Config{
+				Slot: -1,
+			},
+			wantErr: "empty PKCS#11 Module\n" +
+				"PKCS#11 TokenLabel or Slot must be set\n" +
+				"empty PKCS#11 PIN",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
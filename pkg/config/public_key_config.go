@@ -17,11 +17,19 @@ package config
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/abcxyz/pkg/cli"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 )
 
+// minIntrospectionClientSecretLength is the minimum length of the secret of an
+// introspection client, so it can't be guessed.
+const minIntrospectionClientSecretLength = 32
+
 // PublicKeyConfig is the config used for public key hosting.
 type PublicKeyConfig struct {
 	// ProjectID is the Google Cloud project ID.
@@ -32,10 +40,107 @@ type PublicKeyConfig struct {
 
 	Port string `env:"PORT,default=8080"`
 
+	// ShutdownTimeout is how long the server waits for in-flight requests to
+	// finish on shutdown, before abandoning them. If it is 0, the server stops
+	// right away.
+	ShutdownTimeout time.Duration `env:"JVS_SHUTDOWN_TIMEOUT,overwrite,default=30s"`
+
+	// WarmupTimeout is how long the server retries loading its caches on
+	// startup, before it starts serving. If it is 0, the server starts right
+	// away, and loads them on the first request.
+	WarmupTimeout time.Duration `env:"JVS_WARMUP_TIMEOUT,overwrite,default=30s"`
+
+	// Signer is where tokens are signed, either SignerKMS, SignerLocal,
+	// SignerPKCS11 or SignerVault.
+	Signer string `env:"JVS_SIGNER,overwrite,default=kms"`
+
 	// KeyNames format: `projects/*/locations/*/keyRings/*/cryptoKeys/*`
 	// https://pkg.go.dev/google.golang.org/genproto/googleapis/cloud/kms/v1#PublicKeyKey
+	// They are required with SignerKMS. With SignerPKCS11, they are the labels
+	// of the keys in the HSM, and with SignerVault, the names of the transit
+	// keys.
 	KeyNames     []string      `env:"JVS_KEY_NAMES,overwrite"`
 	CacheTimeout time.Duration `env:"JVS_PUBLIC_KEY_CACHE_TIMEOUT, default=5m"`
+
+	// KeyPaths are the paths of the PEM encoded ECDSA P-256 public or private
+	// keys to serve. They are required with SignerLocal.
+	KeyPaths []string `env:"JVS_KEY_PATHS,overwrite"`
+
+	// PKCS11 is the HSM the keys are in with SignerPKCS11.
+	PKCS11 PKCS11Config
+
+	// Vault is the Vault server the keys are in with SignerVault.
+	Vault VaultConfig
+
+	// KMSEndpoint overrides the Cloud KMS API endpoint, e.g. to point at a local
+	// KMS emulator. KMSInsecure disables TLS and authentication for that
+	// endpoint.
+	KMSEndpoint string `env:"JVS_KMS_ENDPOINT,overwrite"`
+	KMSInsecure bool   `env:"JVS_KMS_INSECURE,overwrite,default=false"`
+
+	// KMSPermissionCheck is whether the permissions of the service account on
+	// the KMS keys are checked at startup: KMSPermissionCheckOff,
+	// KMSPermissionCheckWarn or KMSPermissionCheckFail.
+	KMSPermissionCheck string `env:"JVS_KMS_PERMISSION_CHECK,overwrite,default=warn"`
+
+	// KeyUsageTelemetry enables the endpoint verifiers report the key versions
+	// they verify tokens with to.
+	KeyUsageTelemetry bool `env:"JVS_KEY_USAGE_TELEMETRY,overwrite,default=false"`
+
+	// KeyUsageAudience and KeyUsagePrincipals are required with
+	// KeyUsageTelemetry. Requests to the key usage endpoint must present an ID
+	// token from KeyUsageIssuer, verified with the keys at
+	// KeyUsageJWKSEndpoint, whose audience is KeyUsageAudience and whose email
+	// is one of KeyUsagePrincipals, e.g. the service accounts of the verifiers.
+	KeyUsageAudience     string   `env:"JVS_KEY_USAGE_AUDIENCE,overwrite"`
+	KeyUsagePrincipals   []string `env:"JVS_KEY_USAGE_PRINCIPALS,overwrite"`
+	KeyUsageIssuer       string   `env:"JVS_KEY_USAGE_ISSUER,overwrite,default=https://accounts.google.com"`
+	KeyUsageJWKSEndpoint string   `env:"JVS_KEY_USAGE_JWKS_ENDPOINT,overwrite,default=https://www.googleapis.com/oauth2/v3/certs"`
+
+	// CertificateDir, if set, is a directory of PEM encoded X.509 certificate
+	// chains for the keys, one per ".pem" file with the key's certificate
+	// first. Keys are served with the x5c and x5t fields of the chain whose
+	// certificate has their public key. It is read again whenever the cache
+	// expires.
+	CertificateDir string `env:"JVS_PUBLIC_KEY_CERTIFICATE_DIR,overwrite"`
+
+	// Revocation is where deprovisioned principals are stored. If set, their
+	// digests and revocation times are served at the revocation list endpoint,
+	// so verifiers can reject tokens issued to them before.
+	Revocation RevocationConfig
+
+	// IntrospectionClients are the clients allowed to introspect tokens, in the
+	// format client_id=client_secret. If set, tokens are introspected at the
+	// introspection endpoint per RFC 7662.
+	IntrospectionClients []string `json:"-" env:"JVS_PUBLIC_KEY_INTROSPECTION_CLIENTS,overwrite"`
+
+	// IntrospectionIssuers are the issuers (iss) of the API servers. Tokens of
+	// other issuers are inactive. They must be set with IntrospectionClients.
+	IntrospectionIssuers []string `env:"JVS_PUBLIC_KEY_INTROSPECTION_ISSUERS,overwrite"`
+
+	// IntrospectionTokenTypes are the "typ" headers of the tokens of the API
+	// servers, if they set one. If empty, tokens must be untyped or "JWT".
+	IntrospectionTokenTypes []string `env:"JVS_PUBLIC_KEY_INTROSPECTION_TOKEN_TYPES,overwrite"`
+
+	// ClaimsNamespace is the claims namespace of the API servers, if they have
+	// one, so the requestor of introspected tokens is checked for revocation.
+	ClaimsNamespace string `env:"JVS_PUBLIC_KEY_CLAIMS_NAMESPACE,overwrite"`
+
+	// JWKSSigningKey is the KMS key version of the root key that signs the
+	// JWKS, in the format
+	// `projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*`.
+	// JWKSSigningKeyPath is the path of a PEM encoded private key to sign with
+	// instead. If either is set, verifiers that pin the root key can fetch the
+	// JWKS as a signed key set, so keys substituted by the layer serving the
+	// JWKS are rejected. The root key must be an ECDSA P-256 key, and must not
+	// sign tokens.
+	JWKSSigningKey     string `env:"JVS_PUBLIC_KEY_JWKS_SIGNING_KEY,overwrite"`
+	JWKSSigningKeyPath string `env:"JVS_PUBLIC_KEY_JWKS_SIGNING_KEY_PATH,overwrite"`
+
+	// JWKSSignatureTTL is how long signed key sets are valid. It bounds how
+	// long a replayed key set is accepted, and must be longer than the cache
+	// timeout, since signed key sets are cached as long as the keys.
+	JWKSSignatureTTL time.Duration `env:"JVS_PUBLIC_KEY_JWKS_SIGNATURE_TTL,overwrite,default=1h"`
 }
 
 func (cfg *PublicKeyConfig) Validate() (merr error) {
@@ -43,17 +148,139 @@ func (cfg *PublicKeyConfig) Validate() (merr error) {
 		merr = errors.Join(merr, fmt.Errorf("empty ProjectID"))
 	}
 
-	if len(cfg.KeyNames) == 0 {
-		merr = errors.Join(merr, fmt.Errorf("empty KeyNames"))
+	if got := cfg.ShutdownTimeout; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("shutdown timeout cannot be negative, got %s", got))
+	}
+
+	if got := cfg.WarmupTimeout; got < 0 {
+		merr = errors.Join(merr, fmt.Errorf("warmup timeout cannot be negative, got %s", got))
+	}
+
+	switch cfg.Signer {
+	case "", SignerKMS:
+		if len(cfg.KeyNames) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyNames"))
+		}
+	case SignerLocal:
+		if len(cfg.KeyPaths) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyPaths"))
+		}
+	case SignerPKCS11:
+		if len(cfg.KeyNames) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyNames"))
+		}
+		merr = errors.Join(merr, cfg.PKCS11.Validate())
+	case SignerVault:
+		if len(cfg.KeyNames) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("empty KeyNames"))
+		}
+		merr = errors.Join(merr, cfg.Vault.Validate())
+	default:
+		merr = errors.Join(merr, fmt.Errorf("signer must be %q, %q, %q or %q, got %q",
+			SignerKMS, SignerLocal, SignerPKCS11, SignerVault, cfg.Signer))
+	}
+
+	if cfg.KMSInsecure && cfg.KMSEndpoint == "" {
+		merr = errors.Join(merr, fmt.Errorf("KMSInsecure requires KMSEndpoint to be set"))
+	}
+
+	if err := validateKMSPermissionCheck(cfg.KMSPermissionCheck); err != nil {
+		merr = errors.Join(merr, err)
 	}
 
 	if got := cfg.CacheTimeout; got <= 0 {
 		merr = errors.Join(merr, fmt.Errorf("cache_timeout must be a positive duration, got %q", got))
 	}
 
+	merr = errors.Join(merr, cfg.Revocation.Validate())
+
+	if cfg.KeyUsageTelemetry {
+		if cfg.KeyUsageAudience == "" || len(cfg.KeyUsagePrincipals) == 0 {
+			merr = errors.Join(merr, fmt.Errorf("key usage audience and principals must be set with key usage telemetry"))
+		}
+		if cfg.KeyUsageIssuer == "" || cfg.KeyUsageJWKSEndpoint == "" {
+			merr = errors.Join(merr, fmt.Errorf("key usage issuer and jwks endpoint must be set with key usage telemetry"))
+		}
+	}
+
+	if _, err := cfg.IntrospectionClientSecrets(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+	if len(cfg.IntrospectionClients) > 0 && len(cfg.IntrospectionIssuers) == 0 {
+		merr = errors.Join(merr, fmt.Errorf("introspection issuers must be set with introspection clients"))
+	}
+	for _, typ := range cfg.IntrospectionTokenTypes {
+		if jvspb.TokenTypesEqual(typ, jvspb.DefaultTokenType) {
+			continue
+		}
+		if err := jvspb.ValidateTokenType(typ); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+
+	if got := cfg.ClaimsNamespace; got != "" {
+		if err := jvspb.ValidateClaimsNamespace(got); err != nil {
+			merr = errors.Join(merr, err)
+		}
+	}
+
+	merr = errors.Join(merr, cfg.validateJWKSSigning())
+
+	return
+}
+
+func (cfg *PublicKeyConfig) validateJWKSSigning() (merr error) {
+	if cfg.JWKSSigningKey == "" && cfg.JWKSSigningKeyPath == "" {
+		return nil
+	}
+	if cfg.JWKSSigningKey != "" && cfg.JWKSSigningKeyPath != "" {
+		merr = errors.Join(merr, fmt.Errorf("JWKSSigningKey and JWKSSigningKeyPath are mutually exclusive"))
+	}
+	if key := cfg.JWKSSigningKey; key != "" {
+		if cfg.Signer != "" && cfg.Signer != SignerKMS {
+			merr = errors.Join(merr, fmt.Errorf("JWKSSigningKey requires the %q signer", SignerKMS))
+		}
+		if !strings.Contains(key, "/cryptoKeyVersions/") {
+			merr = errors.Join(merr, fmt.Errorf("JWKSSigningKey %q must be a key version", key))
+		}
+		// A token signing key would let a compromised JVS key vouch for the
+		// key set it is in.
+		if slices.ContainsFunc(cfg.KeyNames, func(name string) bool {
+			return strings.HasPrefix(key, name+"/")
+		}) {
+			merr = errors.Join(merr, fmt.Errorf("JWKSSigningKey must not be a version of one of the KeyNames"))
+		}
+	}
+	if got := cfg.JWKSSignatureTTL; got <= cfg.CacheTimeout {
+		merr = errors.Join(merr, fmt.Errorf("jwks signature ttl must be longer than the cache timeout %s, got %s",
+			cfg.CacheTimeout, got))
+	}
 	return
 }
 
+// IntrospectionClientSecrets returns the secret of each introspection client,
+// keyed by client ID.
+func (cfg *PublicKeyConfig) IntrospectionClientSecrets() (map[string]string, error) {
+	secrets := make(map[string]string, len(cfg.IntrospectionClients))
+	for _, v := range cfg.IntrospectionClients {
+		// The secret isn't in the errors, since they are logged.
+		id, secret, ok := strings.Cut(v, "=")
+		id = strings.TrimSpace(id)
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("introspection clients must be in the format client_id=client_secret")
+		}
+		if len(secret) < minIntrospectionClientSecretLength {
+			return nil, fmt.Errorf("secret of introspection client %q must be at least %d characters",
+				id, minIntrospectionClientSecretLength)
+		}
+		if _, ok := secrets[id]; ok {
+			return nil, fmt.Errorf("introspection client %q is specified more than once", id)
+		}
+		secrets[id] = secret
+	}
+	return secrets, nil
+}
+
 // ToFlags binds the config to the give [cli.FlagSet] and returns it.
 func (cfg *PublicKeyConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 	// Command options
@@ -82,14 +309,76 @@ func (cfg *PublicKeyConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   `The port the server listens to.`,
 	})
 
+	f.DurationVar(&cli.DurationVar{
+		Name:    "shutdown-timeout",
+		Target:  &cfg.ShutdownTimeout,
+		EnvVar:  "JVS_SHUTDOWN_TIMEOUT",
+		Default: 30 * time.Second,
+		Usage: "How long to wait for in-flight requests to finish on shutdown, " +
+			"before abandoning them.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "warmup-timeout",
+		Target:  &cfg.WarmupTimeout,
+		EnvVar:  "JVS_WARMUP_TIMEOUT",
+		Default: 30 * time.Second,
+		Usage: "How long to retry loading caches on startup, before serving " +
+			"anyway. Set to 0 to disable warming up.",
+	})
+
 	f = set.NewSection("KEY OPTIONS")
 
+	f.StringVar(&cli.StringVar{
+		Name:    "signer",
+		Target:  &cfg.Signer,
+		EnvVar:  "JVS_SIGNER",
+		Default: SignerKMS,
+		Usage: `Where tokens are signed, "kms" to serve Cloud KMS keys, ` +
+			`"local" to serve keys on disk, "pkcs11" to serve HSM keys or ` +
+			`"vault" to serve Vault transit keys.`,
+	})
+
 	f.StringSliceVar(&cli.StringSliceVar{
 		Name:    "key-names",
 		Target:  &cfg.KeyNames,
 		EnvVar:  "JVS_KEY_NAMES",
 		Example: "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[JVS_KEY]",
-		Usage:   "List of KMS key names",
+		Usage: `List of KMS key names, HSM key labels with the "pkcs11" ` +
+			`signer, or transit key names with the "vault" signer.`,
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "key-paths",
+		Target:  &cfg.KeyPaths,
+		EnvVar:  "JVS_KEY_PATHS",
+		Example: "/etc/jvs/signing-key.pem",
+		Usage:   `List of PEM encoded public or private keys, with the "local" signer.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-endpoint",
+		Target:  &cfg.KMSEndpoint,
+		EnvVar:  "JVS_KMS_ENDPOINT",
+		Example: "localhost:9090",
+		Usage:   "Override the Cloud KMS API endpoint, e.g. for a local KMS emulator.",
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "kms-insecure",
+		Target:  &cfg.KMSInsecure,
+		EnvVar:  "JVS_KMS_INSECURE",
+		Default: false,
+		Usage:   "Connect to the KMS endpoint without TLS or authentication.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "kms-permission-check",
+		Target:  &cfg.KMSPermissionCheck,
+		EnvVar:  "JVS_KMS_PERMISSION_CHECK",
+		Default: KMSPermissionCheckWarn,
+		Usage: `Whether to check the permissions on the KMS keys at startup, ` +
+			`one of "off", "warn" or "fail".`,
 	})
 
 	f.DurationVar(&cli.DurationVar{
@@ -100,5 +389,129 @@ func (cfg *PublicKeyConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   "The duration that a KMS key will be cached.",
 	})
 
+	f.StringVar(&cli.StringVar{
+		Name:    "certificate-dir",
+		Target:  &cfg.CertificateDir,
+		EnvVar:  "JVS_PUBLIC_KEY_CERTIFICATE_DIR",
+		Example: "/etc/jvs/certs",
+		Usage: "Directory of PEM encoded certificate chains for the keys, one per .pem file, " +
+			"to serve the keys with their x5c and x5t fields.",
+	})
+
+	f = set.NewSection("JWKS SIGNING OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "jwks-signing-key",
+		Target:  &cfg.JWKSSigningKey,
+		EnvVar:  "JVS_PUBLIC_KEY_JWKS_SIGNING_KEY",
+		Example: "projects/[JVS_PROJECT]/locations/global/keyRings/[JVS_KEYRING]/cryptoKeys/[ROOT_KEY]/cryptoKeyVersions/1",
+		Usage: "KMS key version of the root key to serve the JWKS as a signed " +
+			"key set with, for verifiers that pin it.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "jwks-signing-key-path",
+		Target:  &cfg.JWKSSigningKeyPath,
+		EnvVar:  "JVS_PUBLIC_KEY_JWKS_SIGNING_KEY_PATH",
+		Example: "/etc/jvs/root-key.pem",
+		Usage:   "PEM encoded private root key to sign the JWKS with, instead of a KMS key.",
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "jwks-signature-ttl",
+		Target:  &cfg.JWKSSignatureTTL,
+		EnvVar:  "JVS_PUBLIC_KEY_JWKS_SIGNATURE_TTL",
+		Default: time.Hour,
+		Usage: "How long signed key sets are valid. Must be longer than the " +
+			"cache timeout.",
+	})
+
+	f = set.NewSection("TELEMETRY OPTIONS")
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "key-usage-telemetry",
+		Target:  &cfg.KeyUsageTelemetry,
+		EnvVar:  "JVS_KEY_USAGE_TELEMETRY",
+		Default: false,
+		Usage: "Accept reports of the key versions verifiers use at /key-usage, " +
+			"to tell when old versions are safe to destroy.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-usage-audience",
+		Target:  &cfg.KeyUsageAudience,
+		EnvVar:  "JVS_KEY_USAGE_AUDIENCE",
+		Example: "https://jvs-public-key.example.com",
+		Usage: "The audience of the ID tokens of key usage callers. Required " +
+			"with key usage telemetry.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "key-usage-principals",
+		Target:  &cfg.KeyUsagePrincipals,
+		EnvVar:  "JVS_KEY_USAGE_PRINCIPALS",
+		Example: "my-verifier@[PROJECT].iam.gserviceaccount.com",
+		Usage: "The emails of the principals allowed to report and read key " +
+			"usage. Can be repeated.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-usage-issuer",
+		Target:  &cfg.KeyUsageIssuer,
+		EnvVar:  "JVS_KEY_USAGE_ISSUER",
+		Default: "https://accounts.google.com",
+		Usage:   "The issuer of the ID tokens of key usage callers.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "key-usage-jwks-endpoint",
+		Target:  &cfg.KeyUsageJWKSEndpoint,
+		EnvVar:  "JVS_KEY_USAGE_JWKS_ENDPOINT",
+		Default: "https://www.googleapis.com/oauth2/v3/certs",
+		Usage:   "The JWKS endpoint to verify the ID tokens of key usage callers.",
+	})
+
+	f = set.NewSection("INTROSPECTION OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "introspection-clients",
+		Target:  &cfg.IntrospectionClients,
+		EnvVar:  "JVS_PUBLIC_KEY_INTROSPECTION_CLIENTS",
+		Example: "api-gateway=[CLIENT_SECRET]",
+		Usage: "List of client_id=client_secret pairs allowed to introspect " +
+			"tokens at /introspect. Introspection is disabled if empty.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "introspection-issuers",
+		Target:  &cfg.IntrospectionIssuers,
+		EnvVar:  "JVS_PUBLIC_KEY_INTROSPECTION_ISSUERS",
+		Example: "jvs.example.com",
+		Usage: "List of issuers of the API servers. Introspected tokens of " +
+			"other issuers are inactive. Required with introspection clients.",
+	})
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "introspection-token-types",
+		Target:  &cfg.IntrospectionTokenTypes,
+		EnvVar:  "JVS_PUBLIC_KEY_INTROSPECTION_TOKEN_TYPES",
+		Example: "jvs+jwt",
+		Usage: "List of typ headers of the tokens of the API servers. If " +
+			"empty, introspected tokens must be untyped or JWT.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "claims-namespace",
+		Target:  &cfg.ClaimsNamespace,
+		EnvVar:  "JVS_PUBLIC_KEY_CLAIMS_NAMESPACE",
+		Example: "https://jvs.example.com/",
+		Usage: "The claims namespace of the API servers, to read the requestor " +
+			"of introspected tokens.",
+	})
+
+	cfg.PKCS11.addFlags(set)
+	cfg.Vault.addFlags(set)
+	cfg.Revocation.addFlags(set)
+
 	return set
 }
@@ -35,25 +35,79 @@ func TestPublicKeyConfig_ToFlags(t *testing.T) {
 		{
 			name: "all_values_specified",
 			envs: map[string]string{
-				"PROJECT_ID":                   "example-project",
-				"DEV_MODE":                     "true",
-				"PORT":                         "0",
-				"JVS_KEY_NAMES":                "fake/key",
-				"JVS_PUBLIC_KEY_CACHE_TIMEOUT": "10m",
+				"PROJECT_ID":                     "example-project",
+				"DEV_MODE":                       "true",
+				"PORT":                           "0",
+				"JVS_SHUTDOWN_TIMEOUT":           "1m",
+				"JVS_WARMUP_TIMEOUT":             "10s",
+				"JVS_SIGNER":                     "local",
+				"JVS_KEY_NAMES":                  "fake/key",
+				"JVS_KEY_PATHS":                  "/etc/jvs/key-1.pem,/etc/jvs/key-2.pem",
+				"JVS_PUBLIC_KEY_CACHE_TIMEOUT":   "10m",
+				"JVS_KMS_ENDPOINT":               "localhost:9090",
+				"JVS_KMS_PERMISSION_CHECK":       "fail",
+				"JVS_KMS_INSECURE":               "true",
+				"JVS_KEY_USAGE_TELEMETRY":        "true",
+				"JVS_KEY_USAGE_AUDIENCE":         "https://jvs-public-key.example.com",
+				"JVS_KEY_USAGE_PRINCIPALS":       "verifier@example-project.iam.gserviceaccount.com",
+				"JVS_KEY_USAGE_ISSUER":           "https://issuer.example.com",
+				"JVS_KEY_USAGE_JWKS_ENDPOINT":    "https://issuer.example.com/jwks",
+				"JVS_PUBLIC_KEY_CERTIFICATE_DIR": "/etc/jvs/certs",
+				"JVS_REVOCATION_POSTGRES_URL":    "postgres://jvs@10.0.0.3:5432/jvs",
+
+				"JVS_PUBLIC_KEY_INTROSPECTION_CLIENTS": "gateway=0123456789abcdef0123456789abcdef",
+				"JVS_PUBLIC_KEY_CLAIMS_NAMESPACE":      "https://jvs.example.com/",
+				"JVS_PUBLIC_KEY_JWKS_SIGNING_KEY_PATH": "/etc/jvs/root-key.pem",
+				"JVS_PUBLIC_KEY_JWKS_SIGNATURE_TTL":    "30m",
 			},
 			wantConfig: &PublicKeyConfig{
-				ProjectID:    "example-project",
-				DevMode:      true,
-				Port:         "0",
-				KeyNames:     []string{"fake/key"},
-				CacheTimeout: 10 * time.Minute,
+				ProjectID:          "example-project",
+				DevMode:            true,
+				Port:               "0",
+				ShutdownTimeout:    time.Minute,
+				WarmupTimeout:      10 * time.Second,
+				Signer:             "local",
+				PKCS11:             PKCS11Config{Slot: -1},
+				Vault:              VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+				KeyNames:           []string{"fake/key"},
+				KeyPaths:           []string{"/etc/jvs/key-1.pem", "/etc/jvs/key-2.pem"},
+				CacheTimeout:       10 * time.Minute,
+				KMSEndpoint:        "localhost:9090",
+				KMSPermissionCheck: "fail",
+				KMSInsecure:        true,
+
+				KeyUsageTelemetry:    true,
+				KeyUsageAudience:     "https://jvs-public-key.example.com",
+				KeyUsagePrincipals:   []string{"verifier@example-project.iam.gserviceaccount.com"},
+				KeyUsageIssuer:       "https://issuer.example.com",
+				KeyUsageJWKSEndpoint: "https://issuer.example.com/jwks",
+				CertificateDir:       "/etc/jvs/certs",
+				Revocation: RevocationConfig{
+					PostgresURL:    "postgres://jvs@10.0.0.3:5432/jvs",
+					ReloadInterval: 30 * time.Second,
+				},
+				IntrospectionClients: []string{"gateway=0123456789abcdef0123456789abcdef"},
+				ClaimsNamespace:      "https://jvs.example.com/",
+				JWKSSigningKeyPath:   "/etc/jvs/root-key.pem",
+				JWKSSignatureTTL:     30 * time.Minute,
 			},
 		},
 		{
 			name: "default_values",
 			wantConfig: &PublicKeyConfig{
-				Port:         "8080",
-				CacheTimeout: 5 * time.Minute,
+				Port:               "8080",
+				ShutdownTimeout:    30 * time.Second,
+				WarmupTimeout:      30 * time.Second,
+				Signer:             "kms",
+				KMSPermissionCheck: "warn",
+				PKCS11:             PKCS11Config{Slot: -1},
+				Vault:              VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+				CacheTimeout:       5 * time.Minute,
+				Revocation:         defaultRevocationConfig,
+				JWKSSignatureTTL:   time.Hour,
+
+				KeyUsageIssuer:       "https://accounts.google.com",
+				KeyUsageJWKSEndpoint: "https://www.googleapis.com/oauth2/v3/certs",
 			},
 		},
 	}
@@ -101,6 +155,28 @@ func TestPublicKeyConfig_Validate(t *testing.T) {
 			},
 			wantErr: "empty ProjectID",
 		},
+		{
+			name: "negative_shutdown_timeout",
+			cfg: &PublicKeyConfig{
+				ProjectID:       "example-project",
+				Port:            "8080",
+				ShutdownTimeout: -time.Second,
+				KeyNames:        []string{"fake/key"},
+				CacheTimeout:    5 * time.Minute,
+			},
+			wantErr: "shutdown timeout cannot be negative, got -1s",
+		},
+		{
+			name: "negative_warmup_timeout",
+			cfg: &PublicKeyConfig{
+				ProjectID:     "example-project",
+				Port:          "8080",
+				WarmupTimeout: -time.Second,
+				KeyNames:      []string{"fake/key"},
+				CacheTimeout:  5 * time.Minute,
+			},
+			wantErr: "warmup timeout cannot be negative, got -1s",
+		},
 		{
 			name: "empty_key_names",
 			cfg: &PublicKeyConfig{
@@ -110,6 +186,85 @@ func TestPublicKeyConfig_Validate(t *testing.T) {
 			},
 			wantErr: "empty KeyNames",
 		},
+		{
+			name: "local_signer",
+			cfg: &PublicKeyConfig{
+				ProjectID:    "example-project",
+				Port:         "8080",
+				Signer:       SignerLocal,
+				KeyPaths:     []string{"/etc/jvs/key.pem"},
+				CacheTimeout: 5 * time.Minute,
+			},
+		},
+		{
+			name: "local_signer_empty_key_paths",
+			cfg: &PublicKeyConfig{
+				ProjectID:    "example-project",
+				Port:         "8080",
+				Signer:       SignerLocal,
+				KeyNames:     []string{"fake/key"},
+				CacheTimeout: 5 * time.Minute,
+			},
+			wantErr: "empty KeyPaths",
+		},
+		{
+			name: "pkcs11_signer",
+			cfg: &PublicKeyConfig{
+				ProjectID: "example-project",
+				Port:      "8080",
+				Signer:    SignerPKCS11,
+				KeyNames:  []string{"jvs-signing"},
+				PKCS11: PKCS11Config{
+					Module: "/usr/lib/softhsm/libsofthsm2.so",
+					Slot:   0,
+					PIN:    "1234",
+				},
+				CacheTimeout: 5 * time.Minute,
+			},
+		},
+		{
+			name: "pkcs11_signer_empty_key_names",
+			cfg: &PublicKeyConfig{
+				ProjectID: "example-project",
+				Port:      "8080",
+				Signer:    SignerPKCS11,
+				PKCS11: PKCS11Config{
+					Module: "/usr/lib/softhsm/libsofthsm2.so",
+					Slot:   0,
+					PIN:    "1234",
+				},
+				CacheTimeout: 5 * time.Minute,
+			},
+			wantErr: "empty KeyNames",
+		},
+		{
+			name: "vault_signer_invalid_address",
+			cfg: &PublicKeyConfig{
+				ProjectID: "example-project",
+				Port:      "8080",
+				Signer:    SignerVault,
+				KeyNames:  []string{"jvs-signing"},
+				Vault: VaultConfig{
+					Address:      "vault.example.com",
+					Token:        "s.token",
+					TransitMount: "transit",
+					Timeout:      10 * time.Second,
+				},
+				CacheTimeout: 5 * time.Minute,
+			},
+			wantErr: `Vault Address "vault.example.com" must be an absolute URL`,
+		},
+		{
+			name: "invalid_signer",
+			cfg: &PublicKeyConfig{
+				ProjectID:    "example-project",
+				Port:         "8080",
+				Signer:       "hsm",
+				KeyNames:     []string{"fake/key"},
+				CacheTimeout: 5 * time.Minute,
+			},
+			wantErr: `signer must be "kms", "local", "pkcs11" or "vault", got "hsm"`,
+		},
 		{
 			name: "invalid_cache_timeout",
 			cfg: &PublicKeyConfig{
@@ -120,6 +275,217 @@ func TestPublicKeyConfig_Validate(t *testing.T) {
 			},
 			wantErr: "cache_timeout must be a positive duration",
 		},
+		{
+			name: "kms_insecure_without_endpoint",
+			cfg: &PublicKeyConfig{
+				ProjectID:    "example-project",
+				Port:         "8080",
+				KeyNames:     []string{"fake/key"},
+				CacheTimeout: 5 * time.Minute,
+				KMSInsecure:  true,
+			},
+			wantErr: "KMSInsecure requires KMSEndpoint to be set",
+		},
+		{
+			name: "key_usage_telemetry",
+			cfg: &PublicKeyConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyNames:             []string{"fake/key"},
+				CacheTimeout:         5 * time.Minute,
+				KeyUsageTelemetry:    true,
+				KeyUsageAudience:     "https://jvs-public-key.example.com",
+				KeyUsagePrincipals:   []string{"verifier@example-project.iam.gserviceaccount.com"},
+				KeyUsageIssuer:       "https://accounts.google.com",
+				KeyUsageJWKSEndpoint: "https://www.googleapis.com/oauth2/v3/certs",
+			},
+		},
+		{
+			name: "key_usage_telemetry_without_auth",
+			cfg: &PublicKeyConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyNames:             []string{"fake/key"},
+				CacheTimeout:         5 * time.Minute,
+				KeyUsageTelemetry:    true,
+				KeyUsageIssuer:       "https://accounts.google.com",
+				KeyUsageJWKSEndpoint: "https://www.googleapis.com/oauth2/v3/certs",
+			},
+			wantErr: "key usage audience and principals must be set with key usage telemetry",
+		},
+		{
+			name: "key_usage_telemetry_without_issuer",
+			cfg: &PublicKeyConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyNames:           []string{"fake/key"},
+				CacheTimeout:       5 * time.Minute,
+				KeyUsageTelemetry:  true,
+				KeyUsageAudience:   "https://jvs-public-key.example.com",
+				KeyUsagePrincipals: []string{"verifier@example-project.iam.gserviceaccount.com"},
+			},
+			wantErr: "key usage issuer and jwks endpoint must be set with key usage telemetry",
+		},
+		{
+			name: "introspection_clients",
+			cfg: &PublicKeyConfig{
+				ProjectID:    "example-project",
+				Port:         "8080",
+				KeyNames:     []string{"fake/key"},
+				CacheTimeout: 5 * time.Minute,
+				IntrospectionClients: []string{
+					"gateway=0123456789abcdef0123456789abcdef",
+					"mesh=fedcba9876543210fedcba9876543210",
+				},
+				IntrospectionIssuers:    []string{"jvs.example.com"},
+				IntrospectionTokenTypes: []string{"jvs+jwt", "JWT"},
+			},
+		},
+		{
+			name: "introspection_clients_without_issuers",
+			cfg: &PublicKeyConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyNames:             []string{"fake/key"},
+				CacheTimeout:         5 * time.Minute,
+				IntrospectionClients: []string{"gateway=0123456789abcdef0123456789abcdef"},
+			},
+			wantErr: "introspection issuers must be set with introspection clients",
+		},
+		{
+			name: "invalid_introspection_token_type",
+			cfg: &PublicKeyConfig{
+				ProjectID:               "example-project",
+				Port:                    "8080",
+				KeyNames:                []string{"fake/key"},
+				CacheTimeout:            5 * time.Minute,
+				IntrospectionClients:    []string{"gateway=0123456789abcdef0123456789abcdef"},
+				IntrospectionIssuers:    []string{"jvs.example.com"},
+				IntrospectionTokenTypes: []string{"jvs-receipt+json"},
+			},
+			wantErr: `token type "jvs-receipt+json" is the type of another jvs object`,
+		},
+		{
+			name: "invalid_introspection_client",
+			cfg: &PublicKeyConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyNames:             []string{"fake/key"},
+				CacheTimeout:         5 * time.Minute,
+				IntrospectionClients: []string{"gateway"},
+			},
+			wantErr: "introspection clients must be in the format client_id=client_secret",
+		},
+		{
+			name: "short_introspection_client_secret",
+			cfg: &PublicKeyConfig{
+				ProjectID:            "example-project",
+				Port:                 "8080",
+				KeyNames:             []string{"fake/key"},
+				CacheTimeout:         5 * time.Minute,
+				IntrospectionClients: []string{"gateway=secret"},
+			},
+			wantErr: `secret of introspection client "gateway" must be at least 32 characters`,
+		},
+		{
+			name: "duplicate_introspection_client",
+			cfg: &PublicKeyConfig{
+				ProjectID:    "example-project",
+				Port:         "8080",
+				KeyNames:     []string{"fake/key"},
+				CacheTimeout: 5 * time.Minute,
+				IntrospectionClients: []string{
+					"gateway=0123456789abcdef0123456789abcdef",
+					"gateway=fedcba9876543210fedcba9876543210",
+				},
+			},
+			wantErr: `introspection client "gateway" is specified more than once`,
+		},
+		{
+			name: "jwks_signing_key",
+			cfg: &PublicKeyConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyNames:         []string{"fake/key"},
+				CacheTimeout:     5 * time.Minute,
+				JWKSSigningKey:   "fake/root/cryptoKeyVersions/1",
+				JWKSSignatureTTL: time.Hour,
+			},
+		},
+		{
+			name: "jwks_signing_key_path",
+			cfg: &PublicKeyConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				Signer:             SignerLocal,
+				KeyPaths:           []string{"/etc/jvs/key.pem"},
+				CacheTimeout:       5 * time.Minute,
+				JWKSSigningKeyPath: "/etc/jvs/root-key.pem",
+				JWKSSignatureTTL:   time.Hour,
+			},
+		},
+		{
+			name: "jwks_signing_key_and_path",
+			cfg: &PublicKeyConfig{
+				ProjectID:          "example-project",
+				Port:               "8080",
+				KeyNames:           []string{"fake/key"},
+				CacheTimeout:       5 * time.Minute,
+				JWKSSigningKey:     "fake/root/cryptoKeyVersions/1",
+				JWKSSigningKeyPath: "/etc/jvs/root-key.pem",
+				JWKSSignatureTTL:   time.Hour,
+			},
+			wantErr: "JWKSSigningKey and JWKSSigningKeyPath are mutually exclusive",
+		},
+		{
+			name: "jwks_signing_key_without_kms_signer",
+			cfg: &PublicKeyConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				Signer:           SignerLocal,
+				KeyPaths:         []string{"/etc/jvs/key.pem"},
+				CacheTimeout:     5 * time.Minute,
+				JWKSSigningKey:   "fake/root/cryptoKeyVersions/1",
+				JWKSSignatureTTL: time.Hour,
+			},
+			wantErr: `JWKSSigningKey requires the "kms" signer`,
+		},
+		{
+			name: "jwks_signing_key_not_a_version",
+			cfg: &PublicKeyConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyNames:         []string{"fake/key"},
+				CacheTimeout:     5 * time.Minute,
+				JWKSSigningKey:   "fake/root",
+				JWKSSignatureTTL: time.Hour,
+			},
+			wantErr: `JWKSSigningKey "fake/root" must be a key version`,
+		},
+		{
+			name: "jwks_signing_key_signs_tokens",
+			cfg: &PublicKeyConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyNames:         []string{"fake/key"},
+				CacheTimeout:     5 * time.Minute,
+				JWKSSigningKey:   "fake/key/cryptoKeyVersions/1",
+				JWKSSignatureTTL: time.Hour,
+			},
+			wantErr: "JWKSSigningKey must not be a version of one of the KeyNames",
+		},
+		{
+			name: "jwks_signature_ttl_within_cache_timeout",
+			cfg: &PublicKeyConfig{
+				ProjectID:        "example-project",
+				Port:             "8080",
+				KeyNames:         []string{"fake/key"},
+				CacheTimeout:     5 * time.Minute,
+				JWKSSigningKey:   "fake/root/cryptoKeyVersions/1",
+				JWKSSignatureTTL: 5 * time.Minute,
+			},
+			wantErr: "jwks signature ttl must be longer than the cache timeout 5m0s, got 5m0s",
+		},
 	}
 
 	for _, tc := range cases {
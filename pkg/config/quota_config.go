@@ -0,0 +1,129 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// QuotaConfig is the configuration of the daily quotas of tokens each
+// principal can mint per category, and where their usage is stored. Exactly
+// one of Path and PostgresURL must be set with quotas.
+type QuotaConfig struct {
+	// Limits are the daily limits of categories, each in the format
+	// "category=limit", e.g. "breakglass=5". Other categories are unlimited.
+	Limits []string `env:"JVS_QUOTA_LIMITS,overwrite"`
+
+	// Path is the JSON file of usage. With several replicas, it must be on a
+	// volume they all share, such as a Cloud Storage volume.
+	Path string `env:"JVS_QUOTA_PATH,overwrite"`
+
+	// PostgresURL is the connection URL of a Postgres database, such as Cloud
+	// SQL, whose "jvs_quota_usage" table holds the usage. The table is created
+	// if it doesn't exist.
+	PostgresURL string `json:"-" env:"JVS_QUOTA_POSTGRES_URL,overwrite"`
+}
+
+// Enabled reports whether any quotas are configured.
+func (cfg *QuotaConfig) Enabled() bool {
+	return len(cfg.Limits) > 0
+}
+
+// Validate checks if the config is valid.
+func (cfg *QuotaConfig) Validate() (merr error) {
+	if _, err := cfg.LimitsByCategory(); err != nil {
+		merr = errors.Join(merr, err)
+	}
+
+	if cfg.Path != "" && cfg.PostgresURL != "" {
+		merr = errors.Join(merr, fmt.Errorf("only one of quota path and quota postgres url can be set"))
+	}
+	if cfg.Enabled() && cfg.Path == "" && cfg.PostgresURL == "" {
+		merr = errors.Join(merr, fmt.Errorf("quota path or quota postgres url must be set with quota limits"))
+	}
+
+	if cfg.PostgresURL != "" {
+		if u, err := url.Parse(cfg.PostgresURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			merr = errors.Join(merr, fmt.Errorf("quota postgres url must be a postgres:// or postgresql:// URL"))
+		}
+	}
+
+	return
+}
+
+// LimitsByCategory parses the Limits into the daily limits of the categories.
+func (cfg *QuotaConfig) LimitsByCategory() (map[string]int, error) {
+	var merr error
+	limits := make(map[string]int, len(cfg.Limits))
+	for _, v := range cfg.Limits {
+		category, value, ok := strings.Cut(v, "=")
+		category, value = strings.TrimSpace(category), strings.TrimSpace(value)
+		if !ok || category == "" {
+			merr = errors.Join(merr, fmt.Errorf("quota %q must be in the format category=limit", v))
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			merr = errors.Join(merr, fmt.Errorf("quota limit of category %q must be a positive integer, got %q", category, value))
+			continue
+		}
+		if _, ok := limits[category]; ok {
+			merr = errors.Join(merr, fmt.Errorf("quota of category %q is specified more than once", category))
+			continue
+		}
+		limits[category] = limit
+	}
+	if merr != nil {
+		return nil, merr
+	}
+	return limits, nil
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *QuotaConfig) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("QUOTA OPTIONS")
+
+	f.StringSliceVar(&cli.StringSliceVar{
+		Name:    "quota",
+		Target:  &cfg.Limits,
+		EnvVar:  "JVS_QUOTA_LIMITS",
+		Example: "breakglass=5",
+		Usage: `The daily limit of tokens each principal can mint with a ` +
+			`justification of the category, in the format category=limit. ` +
+			`Repeat it for more categories.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "quota-path",
+		Target:  &cfg.Path,
+		EnvVar:  "JVS_QUOTA_PATH",
+		Example: "/mnt/jvs/quota.json",
+		Usage:   `The JSON file of quota usage, on a volume shared by all replicas.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "quota-postgres-url",
+		Target:  &cfg.PostgresURL,
+		EnvVar:  "JVS_QUOTA_POSTGRES_URL",
+		Example: "postgres://jvs@10.0.0.3:5432/jvs?sslmode=require",
+		Usage:   `The Postgres database that stores quota usage.`,
+	})
+}
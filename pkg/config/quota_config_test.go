@@ -0,0 +1,102 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestQuotaConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		cfg        *QuotaConfig
+		wantLimits map[string]int
+		wantErr    string
+	}{
+		{
+			name:       "disabled",
+			cfg:        &QuotaConfig{},
+			wantLimits: map[string]int{},
+		},
+		{
+			name: "path",
+			cfg: &QuotaConfig{
+				Limits: []string{"breakglass=5", " jira = 20 "},
+				Path:   "/mnt/jvs/quota.json",
+			},
+			wantLimits: map[string]int{"breakglass": 5, "jira": 20},
+		},
+		{
+			name: "postgres",
+			cfg: &QuotaConfig{
+				Limits:      []string{"breakglass=5"},
+				PostgresURL: "postgres://jvs@10.0.0.3:5432/jvs",
+			},
+			wantLimits: map[string]int{"breakglass": 5},
+		},
+		{
+			name: "no_store",
+			cfg: &QuotaConfig{
+				Limits: []string{"breakglass=5"},
+			},
+			wantLimits: map[string]int{"breakglass": 5},
+			wantErr:    "quota path or quota postgres url must be set with quota limits",
+		},
+		{
+			name: "both_stores",
+			cfg: &QuotaConfig{
+				Limits:      []string{"breakglass=5"},
+				Path:        "/mnt/jvs/quota.json",
+				PostgresURL: "mysql://jvs@10.0.0.3:3306/jvs",
+			},
+			wantLimits: map[string]int{"breakglass": 5},
+			wantErr: "only one of quota path and quota postgres url can be set\n" +
+				"quota postgres url must be a postgres:// or postgresql:// URL",
+		},
+		{
+			name: "invalid_limits",
+			cfg: &QuotaConfig{
+				Limits: []string{"breakglass", "jira=0", "github=many", "servicenow=1", "servicenow=2"},
+				Path:   "/mnt/jvs/quota.json",
+			},
+			wantErr: `quota "breakglass" must be in the format category=limit` + "\n" +
+				`quota limit of category "jira" must be a positive integer, got "0"` + "\n" +
+				`quota limit of category "github" must be a positive integer, got "many"` + "\n" +
+				`quota of category "servicenow" is specified more than once`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+
+			limits, _ := tc.cfg.LimitsByCategory()
+			if diff := cmp.Diff(tc.wantLimits, limits); diff != "" {
+				t.Errorf("limits (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
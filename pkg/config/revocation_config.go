@@ -0,0 +1,97 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// RevocationConfig is where the principals whose tokens are revoked are
+// stored. It is shared by the API server, which revokes principals when they
+// are deprovisioned, and the public key server, which serves the revocation
+// list to verifiers. At most one of Path and PostgresURL may be set.
+type RevocationConfig struct {
+	// Path is the JSON file of revocations. With several replicas, it must be
+	// on a volume they all share, such as a Cloud Storage volume.
+	Path string `env:"JVS_REVOCATION_PATH,overwrite"`
+
+	// PostgresURL is the connection URL of a Postgres database, such as Cloud
+	// SQL, whose "jvs_revocations" table holds the revocations. The table is
+	// created if it doesn't exist.
+	PostgresURL string `json:"-" env:"JVS_REVOCATION_POSTGRES_URL,overwrite"`
+
+	// ReloadInterval is how often the revocations are reloaded from the store,
+	// so every replica picks up the ones made through others.
+	ReloadInterval time.Duration `env:"JVS_REVOCATION_RELOAD_INTERVAL,overwrite,default=30s"`
+}
+
+// Enabled reports whether a revocation store is configured.
+func (cfg *RevocationConfig) Enabled() bool {
+	return cfg.Path != "" || cfg.PostgresURL != ""
+}
+
+// Validate checks if the config is valid.
+func (cfg *RevocationConfig) Validate() (merr error) {
+	if cfg.Path != "" && cfg.PostgresURL != "" {
+		merr = errors.Join(merr, fmt.Errorf("only one of revocation path and revocation postgres url can be set"))
+	}
+
+	if cfg.PostgresURL != "" {
+		if u, err := url.Parse(cfg.PostgresURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			merr = errors.Join(merr, fmt.Errorf("revocation postgres url must be a postgres:// or postgresql:// URL"))
+		}
+	}
+
+	if got := cfg.ReloadInterval; cfg.Enabled() && got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("revocation reload interval must be a positive duration, got %s", got))
+	}
+
+	return
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *RevocationConfig) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("REVOCATION OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "revocation-path",
+		Target:  &cfg.Path,
+		EnvVar:  "JVS_REVOCATION_PATH",
+		Example: "/mnt/jvs/revocations.json",
+		Usage: `The JSON file of revoked principals, on a volume shared by all ` +
+			`replicas.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "revocation-postgres-url",
+		Target:  &cfg.PostgresURL,
+		EnvVar:  "JVS_REVOCATION_POSTGRES_URL",
+		Example: "postgres://jvs@10.0.0.3:5432/jvs?sslmode=require",
+		Usage:   `The Postgres database that stores revoked principals.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "revocation-reload-interval",
+		Target:  &cfg.ReloadInterval,
+		EnvVar:  "JVS_REVOCATION_RELOAD_INTERVAL",
+		Default: 30 * time.Second,
+		Usage:   `How often to reload revoked principals from the store.`,
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// defaultRevocationConfig is the revocation config with the default flag
+// values.
+var defaultRevocationConfig = RevocationConfig{
+	ReloadInterval: 30 * time.Second,
+}
+
+func TestRevocationConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *RevocationConfig
+		wantErr string
+	}{
+		{
+			name: "disabled",
+			cfg:  &RevocationConfig{},
+		},
+		{
+			name: "path",
+			cfg: &RevocationConfig{
+				Path:           "/mnt/jvs/revocations.json",
+				ReloadInterval: 30 * time.Second,
+			},
+		},
+		{
+			name: "postgres",
+			cfg: &RevocationConfig{
+				PostgresURL:    "postgres://jvs@10.0.0.3:5432/jvs",
+				ReloadInterval: 30 * time.Second,
+			},
+		},
+		{
+			name: "both",
+			cfg: &RevocationConfig{
+				Path:           "/mnt/jvs/revocations.json",
+				PostgresURL:    "postgres://jvs@10.0.0.3:5432/jvs",
+				ReloadInterval: 30 * time.Second,
+			},
+			wantErr: "only one of revocation path and revocation postgres url can be set",
+		},
+		{
+			name: "invalid",
+			cfg: &RevocationConfig{
+				PostgresURL: "mysql://jvs@10.0.0.3:3306/jvs",
+			},
+			wantErr: "revocation postgres url must be a postgres:// or postgresql:// URL\n" +
+				"revocation reload interval must be a positive duration, got 0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
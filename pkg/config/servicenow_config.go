@@ -0,0 +1,116 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// ServiceNowConfig is the config of the ServiceNow change management validator
+// plugin.
+type ServiceNowConfig struct {
+	// InstanceURL is the URL of the ServiceNow instance, e.g.
+	// "https://example.service-now.com".
+	InstanceURL string `env:"JVS_SERVICENOW_INSTANCE_URL,overwrite"`
+
+	// Username and Password are the basic auth credentials of a ServiceNow user
+	// that can read change requests.
+	Username string `env:"JVS_SERVICENOW_USERNAME,overwrite"`
+	Password string `env:"JVS_SERVICENOW_PASSWORD,overwrite"`
+
+	// ImplementState is the value of the "state" field of change requests in
+	// the Implement state. The default matches the base ServiceNow instance.
+	ImplementState string `env:"JVS_SERVICENOW_IMPLEMENT_STATE,overwrite,default=-1"`
+
+	// Timeout is the timeout of requests to ServiceNow.
+	Timeout time.Duration `env:"JVS_SERVICENOW_TIMEOUT,overwrite,default=10s"`
+}
+
+// Validate checks if the config is valid.
+func (cfg *ServiceNowConfig) Validate() (merr error) {
+	if cfg.InstanceURL == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty InstanceURL"))
+	} else if u, err := url.Parse(cfg.InstanceURL); err != nil || u.Scheme == "" || u.Host == "" {
+		merr = errors.Join(merr, fmt.Errorf("InstanceURL %q must be an absolute URL", cfg.InstanceURL))
+	}
+
+	if cfg.Username == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty Username"))
+	}
+
+	if cfg.Password == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty Password"))
+	}
+
+	if cfg.ImplementState == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty ImplementState"))
+	}
+
+	if got := cfg.Timeout; got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("timeout must be a positive duration, got %s", got))
+	}
+
+	return
+}
+
+// ToFlags binds the config to the give [cli.FlagSet] and returns it.
+func (cfg *ServiceNowConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
+	f := set.NewSection("SERVICENOW OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "instance-url",
+		Target:  &cfg.InstanceURL,
+		EnvVar:  "JVS_SERVICENOW_INSTANCE_URL",
+		Example: "https://example.service-now.com",
+		Usage:   `The URL of the ServiceNow instance.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "username",
+		Target: &cfg.Username,
+		EnvVar: "JVS_SERVICENOW_USERNAME",
+		Usage:  `The ServiceNow user to read change requests as.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "password",
+		Target: &cfg.Password,
+		EnvVar: "JVS_SERVICENOW_PASSWORD",
+		Usage:  `The password of the ServiceNow user.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "implement-state",
+		Target:  &cfg.ImplementState,
+		EnvVar:  "JVS_SERVICENOW_IMPLEMENT_STATE",
+		Default: "-1",
+		Usage:   `The "state" value of change requests in the Implement state.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "timeout",
+		Target:  &cfg.Timeout,
+		EnvVar:  "JVS_SERVICENOW_TIMEOUT",
+		Default: 10 * time.Second,
+		Usage:   `The timeout of requests to ServiceNow.`,
+	})
+
+	return set
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/cli"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestServiceNowConfig_ToFlags(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		envs       map[string]string
+		wantConfig *ServiceNowConfig
+	}{
+		{
+			name: "all_values_specified",
+			envs: map[string]string{
+				"JVS_SERVICENOW_INSTANCE_URL":    "https://example.service-now.com",
+				"JVS_SERVICENOW_USERNAME":        "jvs",
+				"JVS_SERVICENOW_PASSWORD":        "secret",
+				"JVS_SERVICENOW_IMPLEMENT_STATE": "implement",
+				"JVS_SERVICENOW_TIMEOUT":         "30s",
+			},
+			wantConfig: &ServiceNowConfig{
+				InstanceURL:    "https://example.service-now.com",
+				Username:       "jvs",
+				Password:       "secret",
+				ImplementState: "implement",
+				Timeout:        30 * time.Second,
+			},
+		},
+		{
+			name: "default_values",
+			wantConfig: &ServiceNowConfig{
+				ImplementState: "-1",
+				Timeout:        10 * time.Second,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			gotConfig := &ServiceNowConfig{}
+			set := cli.NewFlagSet(cli.WithLookupEnv(cli.MapLookuper(tc.envs)))
+			set = gotConfig.ToFlags(set)
+			if err := set.Parse([]string{}); err != nil {
+				t.Errorf("unexpected flag set parse error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantConfig, gotConfig); diff != "" {
+				t.Errorf("Config unexpected diff (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServiceNowConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *ServiceNowConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg: &ServiceNowConfig{
+				InstanceURL:    "https://example.service-now.com",
+				Username:       "jvs",
+				Password:       "secret",
+				ImplementState: "-1",
+				Timeout:        10 * time.Second,
+			},
+		},
+		{
+			name: "empty_values",
+			cfg: &ServiceNowConfig{
+				ImplementState: "-1",
+				Timeout:        10 * time.Second,
+			},
+			wantErr: "empty InstanceURL\nempty Username\nempty Password",
+		},
+		{
+			name: "relative_instance_url",
+			cfg: &ServiceNowConfig{
+				InstanceURL:    "example.service-now.com",
+				Username:       "jvs",
+				Password:       "secret",
+				ImplementState: "-1",
+				Timeout:        10 * time.Second,
+			},
+			wantErr: `InstanceURL "example.service-now.com" must be an absolute URL`,
+		},
+		{
+			name: "invalid_timeout",
+			cfg: &ServiceNowConfig{
+				InstanceURL:    "https://example.service-now.com",
+				Username:       "jvs",
+				Password:       "secret",
+				ImplementState: "-1",
+			},
+			wantErr: "timeout must be a positive duration, got 0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
@@ -15,18 +15,45 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/abcxyz/pkg/cli"
 )
 
+const (
+	// UIAuthModeIAP authenticates users with the identity header set by Google
+	// Identity-Aware Proxy.
+	UIAuthModeIAP = "iap"
+
+	// UIAuthModeOIDC authenticates users with an OIDC authorization code flow
+	// performed by the UI server itself, tracked in a session cookie.
+	UIAuthModeOIDC = "oidc"
+)
+
+// minSessionKeyLength is the minimum length, in bytes, of the session key.
+const minSessionKeyLength = 32
+
 // UIServiceConfig defines the set over environment variables required
 // for running this application.
 type UIServiceConfig struct {
 	*JustificationConfig
 
 	Allowlist []string `env:"JVS_UI_ALLOWLIST,required"`
+
+	AuthMode         string        `env:"JVS_UI_AUTH_MODE,default=iap"`
+	OIDCIssuer       string        `env:"JVS_UI_OIDC_ISSUER"`
+	OIDCClientID     string        `env:"JVS_UI_OIDC_CLIENT_ID"`
+	OIDCClientSecret string        `env:"JVS_UI_OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL  string        `env:"JVS_UI_OIDC_REDIRECT_URL"`
+	SessionKey       string        `env:"JVS_UI_SESSION_KEY"`
+	SessionTTL       time.Duration `env:"JVS_UI_SESSION_TTL,default=12h"`
+
+	// ConfirmSubmission shows users what will be encoded in the token, and asks
+	// them to confirm, before it is minted.
+	ConfirmSubmission bool `env:"JVS_UI_CONFIRM_SUBMISSION,default=false"`
 }
 
 // Validate checks if the config is valid.
@@ -51,9 +78,45 @@ func (cfg *UIServiceConfig) Validate() (merr error) {
 		}
 	}
 
+	switch cfg.AuthMode {
+	case "", UIAuthModeIAP:
+	case UIAuthModeOIDC:
+		if cfg.OIDCIssuer == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty OIDCIssuer"))
+		}
+		if cfg.OIDCClientID == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty OIDCClientID"))
+		}
+		if cfg.OIDCClientSecret == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty OIDCClientSecret"))
+		}
+		if cfg.OIDCRedirectURL == "" {
+			merr = errors.Join(merr, fmt.Errorf("empty OIDCRedirectURL"))
+		}
+		if key, err := cfg.SessionKeyBytes(); err != nil {
+			merr = errors.Join(merr, err)
+		} else if len(key) < minSessionKeyLength {
+			merr = errors.Join(merr, fmt.Errorf("SessionKey must be at least %d bytes, got %d", minSessionKeyLength, len(key)))
+		}
+		if cfg.SessionTTL <= 0 {
+			merr = errors.Join(merr, fmt.Errorf("SessionTTL must be a positive duration, got %q", cfg.SessionTTL))
+		}
+	default:
+		merr = errors.Join(merr, fmt.Errorf("AuthMode must be one of %q or %q, got %q", UIAuthModeIAP, UIAuthModeOIDC, cfg.AuthMode))
+	}
+
 	return merr
 }
 
+// SessionKeyBytes returns the base64-decoded session key.
+func (cfg *UIServiceConfig) SessionKeyBytes() ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(cfg.SessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SessionKey as base64: %w", err)
+	}
+	return b, nil
+}
+
 // ToFlags binds the config to the give [cli.FlagSet] and returns it.
 func (cfg *UIServiceConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 	if cfg.JustificationConfig == nil {
@@ -70,5 +133,72 @@ func (cfg *UIServiceConfig) ToFlags(set *cli.FlagSet) *cli.FlagSet {
 		Usage:   "List of allowed domains.",
 	})
 
+	f.StringVar(&cli.StringVar{
+		Name:    "auth-mode",
+		Target:  &cfg.AuthMode,
+		EnvVar:  "JVS_UI_AUTH_MODE",
+		Default: UIAuthModeIAP,
+		Usage: `How users are authenticated, either "iap" to trust the ` +
+			`Identity-Aware Proxy header, or "oidc" to sign users in with an ` +
+			`OIDC provider and keep a session cookie.`,
+	})
+
+	f.BoolVar(&cli.BoolVar{
+		Name:    "confirm-submission",
+		Target:  &cfg.ConfirmSubmission,
+		EnvVar:  "JVS_UI_CONFIRM_SUBMISSION",
+		Default: false,
+		Usage: `Show users the subject, audience, category, reason, TTL and ` +
+			`expiry of the token, and ask them to confirm, before minting it.`,
+	})
+
+	f = set.NewSection("UI OIDC OPTIONS")
+	f.StringVar(&cli.StringVar{
+		Name:    "oidc-issuer",
+		Target:  &cfg.OIDCIssuer,
+		EnvVar:  "JVS_UI_OIDC_ISSUER",
+		Example: "https://accounts.google.com",
+		Usage:   "The OIDC provider issuer URL, used for discovery.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "oidc-client-id",
+		Target:  &cfg.OIDCClientID,
+		EnvVar:  "JVS_UI_OIDC_CLIENT_ID",
+		Example: "1234.apps.googleusercontent.com",
+		Usage:   "The OAuth client ID registered with the OIDC provider.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "oidc-client-secret",
+		Target: &cfg.OIDCClientSecret,
+		EnvVar: "JVS_UI_OIDC_CLIENT_SECRET",
+		Usage:  "The OAuth client secret registered with the OIDC provider.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "oidc-redirect-url",
+		Target:  &cfg.OIDCRedirectURL,
+		EnvVar:  "JVS_UI_OIDC_REDIRECT_URL",
+		Example: "https://jvs-ui.example.com/auth/callback",
+		Usage:   "The externally-reachable URL of the UI server's /auth/callback path.",
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "session-key",
+		Target: &cfg.SessionKey,
+		EnvVar: "JVS_UI_SESSION_KEY",
+		Usage: `Base64-encoded key of at least 32 bytes used to sign session ` +
+			`cookies, e.g. the output of "openssl rand -base64 32".`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "session-ttl",
+		Target:  &cfg.SessionTTL,
+		EnvVar:  "JVS_UI_SESSION_TTL",
+		Default: 12 * time.Hour,
+		Usage:   "How long a signed-in session lasts before users must sign in again.",
+	})
+
 	return set
 }
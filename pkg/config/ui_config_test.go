@@ -38,6 +38,7 @@ func TestUIServiceConfig_ToFlags(t *testing.T) {
 				"PROJECT_ID":                   "example-project",
 				"DEV_MODE":                     "true",
 				"PORT":                         "0",
+				"JVS_SHUTDOWN_TIMEOUT":         "1m",
 				"JVS_KEY":                      "fake/key",
 				"JVS_API_SIGNER_CACHE_TIMEOUT": "10m",
 				"JVS_API_ISSUER":               "example.com",
@@ -45,33 +46,87 @@ func TestUIServiceConfig_ToFlags(t *testing.T) {
 				"JVS_API_DEFAULT_TTL":          "30m",
 				"JVS_API_MAX_TTL":              "8h",
 				"JVS_UI_ALLOWLIST":             "example.com,*.foo.bar",
+				"JVS_UI_AUTH_MODE":             "oidc",
+				"JVS_UI_OIDC_ISSUER":           "https://accounts.example.com",
+				"JVS_UI_OIDC_CLIENT_ID":        "client-id",
+				"JVS_UI_OIDC_CLIENT_SECRET":    "client-secret",
+				"JVS_UI_OIDC_REDIRECT_URL":     "https://jvs-ui.example.com/auth/callback",
+				"JVS_UI_SESSION_KEY":           "c2Vzc2lvbi1rZXk=",
+				"JVS_UI_SESSION_TTL":           "1h",
 			},
 			wantConfig: &UIServiceConfig{
 				JustificationConfig: &JustificationConfig{
-					ProjectID:          "example-project",
-					DevMode:            true,
-					Port:               "0",
-					KeyName:            "fake/key",
-					SignerCacheTimeout: 10 * time.Minute,
-					Issuer:             "example.com",
-					PluginDir:          "/var/jvs/pluginsDir",
-					DefaultTTL:         30 * time.Minute,
-					MaxTTL:             8 * time.Hour,
+					ProjectID:                "example-project",
+					DevMode:                  true,
+					Port:                     "0",
+					ShutdownTimeout:          time.Minute,
+					WarmupTimeout:            30 * time.Second,
+					Signer:                   "kms",
+					KMSPermissionCheck:       "warn",
+					PKCS11:                   PKCS11Config{Slot: -1},
+					Vault:                    VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+					Groups:                   defaultGroupsConfig,
+					Revocation:               defaultRevocationConfig,
+					Canary:                   CanaryConfig{Timeout: 10 * time.Second},
+					DenyListReloadInterval:   30 * time.Second,
+					PolicyReloadInterval:     30 * time.Second,
+					RegoPolicyReloadInterval: 30 * time.Second,
+					AdminIssuer:              "https://accounts.google.com",
+					AdminJWKSEndpoint:        "https://www.googleapis.com/oauth2/v3/certs",
+					TransparencyLogTimeout:   5 * time.Second,
+					RequestDeadline:          10 * time.Second,
+					AuditRouteLogID:          "audit.abcxyz/data_access",
+					KeyName:                  "fake/key",
+					SignerCacheTimeout:       10 * time.Minute,
+					Issuer:                   "example.com",
+					PluginDir:                "/var/jvs/pluginsDir",
+					DefaultTTL:               30 * time.Minute,
+					MaxTTL:                   8 * time.Hour,
+					MaxAnnotationSize:        2000,
+					ClaimVersion:             1,
 				},
-				Allowlist: []string{"example.com", "*.foo.bar"},
+				Allowlist:        []string{"example.com", "*.foo.bar"},
+				AuthMode:         "oidc",
+				OIDCIssuer:       "https://accounts.example.com",
+				OIDCClientID:     "client-id",
+				OIDCClientSecret: "client-secret",
+				OIDCRedirectURL:  "https://jvs-ui.example.com/auth/callback",
+				SessionKey:       "c2Vzc2lvbi1rZXk=",
+				SessionTTL:       1 * time.Hour,
 			},
 		},
 		{
 			name: "default_values",
 			wantConfig: &UIServiceConfig{
 				JustificationConfig: &JustificationConfig{
-					Port:               "8080",
-					SignerCacheTimeout: 5 * time.Minute,
-					Issuer:             "jvs.abcxyz.dev",
-					PluginDir:          "/var/jvs/plugins",
-					DefaultTTL:         15 * time.Minute,
-					MaxTTL:             4 * time.Hour,
+					Port:                     "8080",
+					ShutdownTimeout:          30 * time.Second,
+					WarmupTimeout:            30 * time.Second,
+					Signer:                   "kms",
+					KMSPermissionCheck:       "warn",
+					PKCS11:                   PKCS11Config{Slot: -1},
+					Vault:                    VaultConfig{TransitMount: "transit", Timeout: 10 * time.Second},
+					Groups:                   defaultGroupsConfig,
+					Revocation:               defaultRevocationConfig,
+					Canary:                   CanaryConfig{Timeout: 10 * time.Second},
+					DenyListReloadInterval:   30 * time.Second,
+					PolicyReloadInterval:     30 * time.Second,
+					RegoPolicyReloadInterval: 30 * time.Second,
+					AdminIssuer:              "https://accounts.google.com",
+					AdminJWKSEndpoint:        "https://www.googleapis.com/oauth2/v3/certs",
+					TransparencyLogTimeout:   5 * time.Second,
+					RequestDeadline:          10 * time.Second,
+					AuditRouteLogID:          "audit.abcxyz/data_access",
+					SignerCacheTimeout:       5 * time.Minute,
+					Issuer:                   "jvs.abcxyz.dev",
+					PluginDir:                "/var/jvs/plugins",
+					DefaultTTL:               15 * time.Minute,
+					MaxTTL:                   4 * time.Hour,
+					MaxAnnotationSize:        2000,
+					ClaimVersion:             1,
 				},
+				AuthMode:   "iap",
+				SessionTTL: 12 * time.Hour,
 			},
 		},
 	}
@@ -113,6 +168,7 @@ func TestUIServiceConfig_Validate(t *testing.T) {
 					PluginDir:          "/var/jvs/pluginsDir",
 					DefaultTTL:         15 * time.Minute,
 					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
 				},
 				Allowlist: []string{"example.com", "*.foo.bar"},
 			},
@@ -129,6 +185,7 @@ func TestUIServiceConfig_Validate(t *testing.T) {
 					PluginDir:          "/var/jvs/pluginsDir",
 					DefaultTTL:         15 * time.Minute,
 					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
 				},
 			},
 			wantErr: "empty Allowlist",
@@ -145,11 +202,100 @@ func TestUIServiceConfig_Validate(t *testing.T) {
 					PluginDir:          "/var/jvs/pluginsDir",
 					DefaultTTL:         15 * time.Minute,
 					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
 				},
 				Allowlist: []string{"*", "example.com"},
 			},
 			wantErr: "asterisk(*) must be exclusive, no other domains allowed",
 		},
+		{
+			name: "valid_oidc",
+			cfg: &UIServiceConfig{
+				JustificationConfig: &JustificationConfig{
+					ProjectID:          "example-project",
+					Port:               "8080",
+					KeyName:            "fake/key",
+					SignerCacheTimeout: 5 * time.Minute,
+					Issuer:             "jvs.abcxyz.dev",
+					PluginDir:          "/var/jvs/pluginsDir",
+					DefaultTTL:         15 * time.Minute,
+					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
+				},
+				Allowlist:        []string{"example.com"},
+				AuthMode:         "oidc",
+				OIDCIssuer:       "https://accounts.example.com",
+				OIDCClientID:     "client-id",
+				OIDCClientSecret: "client-secret",
+				OIDCRedirectURL:  "https://jvs-ui.example.com/auth/callback",
+				SessionKey:       "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+				SessionTTL:       12 * time.Hour,
+			},
+		},
+		{
+			name: "invalid_auth_mode",
+			cfg: &UIServiceConfig{
+				JustificationConfig: &JustificationConfig{
+					ProjectID:          "example-project",
+					Port:               "8080",
+					KeyName:            "fake/key",
+					SignerCacheTimeout: 5 * time.Minute,
+					Issuer:             "jvs.abcxyz.dev",
+					PluginDir:          "/var/jvs/pluginsDir",
+					DefaultTTL:         15 * time.Minute,
+					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
+				},
+				Allowlist: []string{"example.com"},
+				AuthMode:  "basic",
+			},
+			wantErr: `AuthMode must be one of "iap" or "oidc", got "basic"`,
+		},
+		{
+			name: "oidc_missing_values",
+			cfg: &UIServiceConfig{
+				JustificationConfig: &JustificationConfig{
+					ProjectID:          "example-project",
+					Port:               "8080",
+					KeyName:            "fake/key",
+					SignerCacheTimeout: 5 * time.Minute,
+					Issuer:             "jvs.abcxyz.dev",
+					PluginDir:          "/var/jvs/pluginsDir",
+					DefaultTTL:         15 * time.Minute,
+					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
+				},
+				Allowlist:  []string{"example.com"},
+				AuthMode:   "oidc",
+				SessionTTL: 12 * time.Hour,
+			},
+			wantErr: "empty OIDCIssuer",
+		},
+		{
+			name: "oidc_short_session_key",
+			cfg: &UIServiceConfig{
+				JustificationConfig: &JustificationConfig{
+					ProjectID:          "example-project",
+					Port:               "8080",
+					KeyName:            "fake/key",
+					SignerCacheTimeout: 5 * time.Minute,
+					Issuer:             "jvs.abcxyz.dev",
+					PluginDir:          "/var/jvs/pluginsDir",
+					DefaultTTL:         15 * time.Minute,
+					MaxTTL:             4 * time.Hour,
+					MaxAnnotationSize:  2000,
+				},
+				Allowlist:        []string{"example.com"},
+				AuthMode:         "oidc",
+				OIDCIssuer:       "https://accounts.example.com",
+				OIDCClientID:     "client-id",
+				OIDCClientSecret: "client-secret",
+				OIDCRedirectURL:  "https://jvs-ui.example.com/auth/callback",
+				SessionKey:       "c2Vzc2lvbi1rZXk=",
+				SessionTTL:       12 * time.Hour,
+			},
+			wantErr: "SessionKey must be at least 32 bytes, got 11",
+		},
 	}
 
 	for _, tc := range cases {
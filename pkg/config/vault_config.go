@@ -0,0 +1,112 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/abcxyz/pkg/cli"
+)
+
+// VaultConfig is the configuration of the HashiCorp Vault transit secrets
+// engine keys are stored in with the SignerVault signer.
+type VaultConfig struct {
+	// Address is the URL of the Vault server, e.g. "https://vault.example.com:8200".
+	Address string `env:"JVS_VAULT_ADDR,overwrite"`
+
+	// Token is the Vault token to authenticate with. Its policy must allow
+	// reading, rotating, configuring and trimming the keys, and signing with
+	// them.
+	Token string `json:"-" env:"JVS_VAULT_TOKEN,overwrite"`
+
+	// Namespace is the Vault Enterprise namespace of the transit engine, if
+	// any.
+	Namespace string `env:"JVS_VAULT_NAMESPACE,overwrite"`
+
+	// TransitMount is the path the transit engine is mounted at.
+	TransitMount string `env:"JVS_VAULT_TRANSIT_MOUNT,overwrite,default=transit"`
+
+	// Timeout is the timeout of requests to Vault.
+	Timeout time.Duration `env:"JVS_VAULT_TIMEOUT,overwrite,default=10s"`
+}
+
+// Validate checks if the config is valid.
+func (cfg *VaultConfig) Validate() (merr error) {
+	if cfg.Address == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty Vault Address"))
+	} else if u, err := url.Parse(cfg.Address); err != nil || u.Scheme == "" || u.Host == "" {
+		merr = errors.Join(merr, fmt.Errorf("Vault Address %q must be an absolute URL", cfg.Address))
+	}
+
+	if cfg.Token == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty Vault Token"))
+	}
+
+	if cfg.TransitMount == "" {
+		merr = errors.Join(merr, fmt.Errorf("empty Vault TransitMount"))
+	}
+
+	if got := cfg.Timeout; got <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("vault timeout must be a positive duration, got %s", got))
+	}
+
+	return
+}
+
+// addFlags binds the config to a new section of the [cli.FlagSet].
+func (cfg *VaultConfig) addFlags(set *cli.FlagSet) {
+	f := set.NewSection("VAULT OPTIONS")
+
+	f.StringVar(&cli.StringVar{
+		Name:    "vault-addr",
+		Target:  &cfg.Address,
+		EnvVar:  "JVS_VAULT_ADDR",
+		Example: "https://vault.example.com:8200",
+		Usage:   `The URL of the Vault server, with the "vault" signer.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "vault-token",
+		Target: &cfg.Token,
+		EnvVar: "JVS_VAULT_TOKEN",
+		Usage:  `The Vault token to authenticate with. Prefer setting it in the environment.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:   "vault-namespace",
+		Target: &cfg.Namespace,
+		EnvVar: "JVS_VAULT_NAMESPACE",
+		Usage:  `The Vault Enterprise namespace of the transit engine.`,
+	})
+
+	f.StringVar(&cli.StringVar{
+		Name:    "vault-transit-mount",
+		Target:  &cfg.TransitMount,
+		EnvVar:  "JVS_VAULT_TRANSIT_MOUNT",
+		Default: "transit",
+		Usage:   `The path the Vault transit engine is mounted at.`,
+	})
+
+	f.DurationVar(&cli.DurationVar{
+		Name:    "vault-timeout",
+		Target:  &cfg.Timeout,
+		EnvVar:  "JVS_VAULT_TIMEOUT",
+		Default: 10 * time.Second,
+		Usage:   `The timeout of requests to Vault.`,
+	})
+}
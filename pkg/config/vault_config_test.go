@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVaultConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		cfg     *VaultConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cfg: &VaultConfig{
+				Address:      "https://vault.example.com:8200",
+				Token:        "s.token",
+				Namespace:    "team",
+				TransitMount: "transit",
+				Timeout:      10 * time.Second,
+			},
+		},
+		{
+			name: "relative_address",
+			cfg: &VaultConfig{
+				Address:      "/vault",
+				Token:        "s.token",
+				TransitMount: "transit",
+				Timeout:      10 * time.Second,
+			},
+			wantErr: `Vault Address "/vault" must be an absolute URL`,
+		},
+		{
+			name: "empty_values",
+			cfg:  &VaultConfig{},
+			wantErr: "empty Vault Address\n" +
+				"empty Vault Token\n" +
+				"empty Vault TransitMount\n" +
+				"vault timeout must be a positive duration, got 0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cfg.Validate()
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -49,6 +51,26 @@ type Controller struct {
 	p                   *justification.Processor
 	allowlist           []string
 	categoryDisplayData map[string]*jvspb.UIData
+	auth                Authenticator
+
+	// confirm is whether users confirm the token's claims before it is minted.
+	confirm bool
+}
+
+// Authenticator identifies the user making a request.
+type Authenticator interface {
+	// Email returns the email of the authenticated user, or an error if the
+	// request is not authenticated.
+	Email(r *http.Request) (string, error)
+}
+
+// IAPAuthenticator authenticates users with the identity header set by Google
+// Identity-Aware Proxy. It is the default.
+type IAPAuthenticator struct{}
+
+// Email implements [Authenticator].
+func (IAPAuthenticator) Email(r *http.Request) (string, error) {
+	return getEmail(r)
 }
 
 // Content defines the displayable parts of the token retrieval form.
@@ -59,12 +81,20 @@ type Content struct {
 	TTLLabel      string
 	Categories    map[string]*jvspb.UIData
 	TTLs          map[string]struct{}
+
+	// ErrorSummaryLabel is the heading of the list of form errors.
+	ErrorSummaryLabel string
+
+	// SecondJustificationLabel is the label of the button that shows the
+	// second justification row.
+	SecondJustificationLabel string
 }
 
 // FormDetails represents all the input and content used for the token retrievlal form.
 type FormDetails struct {
 	WindowName  string
 	Origin      string
+	Version     int
 	PageTitle   string
 	Description string
 	UserEmail   string
@@ -73,6 +103,23 @@ type FormDetails struct {
 	Reason      string
 	TTL         string
 	Errors      map[string]string
+
+	// SecondCategory and SecondReason are an optional second justification,
+	// e.g. free-form context for a ticket. It is only added to the token if
+	// SecondReason is set.
+	SecondCategory string
+	SecondReason   string
+
+	// ErrorSummary lists the form errors in the order of the fields, for the
+	// summary at the top of the form. It is set with Errors.
+	ErrorSummary []*FieldError
+}
+
+// FieldError is an error of a form field.
+type FieldError struct {
+	// FieldID is the id of the invalid input, the summary links to it.
+	FieldID string
+	Message string
 }
 
 // SuccessDetails represents the data used for the success page and the postMessage response to the client.
@@ -82,6 +129,37 @@ type SuccessDetails struct {
 	PageTitle   string
 	Description string
 	Token       string
+
+	// Issuer is the issuer (iss) of the token, which verifiers must accept.
+	Issuer string
+
+	// ExpiresAt is the expiration (exp) of the token.
+	ExpiresAt time.Time
+
+	// TokenID is the ID (jti) of the token.
+	TokenID string
+
+	// PostMessage is the JSON encoded [Message] to post to the opener.
+	PostMessage string
+}
+
+// ConfirmDetails represents the data used for the confirmation page, which
+// shows what will be encoded in the token before it is minted.
+type ConfirmDetails struct {
+	*FormDetails
+
+	// CategoryDisplayName is the display name of the selected category.
+	CategoryDisplayName string
+
+	// SecondCategoryDisplayName is the display name of the category of the
+	// second justification, if there is one.
+	SecondCategoryDisplayName string
+
+	// Audiences are the audiences of the token.
+	Audiences []string
+
+	// ExpiresAt is when the token would expire if it was minted now.
+	ExpiresAt time.Time
 }
 
 // ErrorDetails represents the data used for the 400 page.
@@ -89,6 +167,12 @@ type ErrorDetails struct {
 	PageTitle   string
 	Description string
 	Message     string
+
+	// Origin and PostMessage are the opener's origin and the JSON encoded
+	// [Message] to post to it. They are only set if the opener speaks
+	// [MessageVersion] and its origin is allowed.
+	Origin      string
+	PostMessage string
 }
 
 const iapHeaderName = "x-goog-authenticated-user-email"
@@ -104,9 +188,23 @@ func New(ctx context.Context, h *renderer.Renderer, p *justification.Processor,
 		p:                   p,
 		allowlist:           allowlist,
 		categoryDisplayData: categories,
+		auth:                IAPAuthenticator{},
 	}, nil
 }
 
+// WithAuthenticator overrides how users are authenticated.
+func (c *Controller) WithAuthenticator(a Authenticator) *Controller {
+	c.auth = a
+	return c
+}
+
+// WithConfirmation sets whether users are shown what will be encoded in the
+// token, and asked to confirm, before it is minted.
+func (c *Controller) WithConfirmation(confirm bool) *Controller {
+	c.confirm = confirm
+	return c
+}
+
 func (c *Controller) HandleHealth() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		c.h.RenderJSON(w, http.StatusOK, nil)
@@ -130,7 +228,7 @@ func (c *Controller) HandlePopup() http.Handler {
 func (c *Controller) handlePopupGet(w http.ResponseWriter, r *http.Request) {
 	formDetails, err := c.getFormDetails(r)
 	if err != nil {
-		c.renderBadRequest(w, err.Error())
+		c.renderBadRequest(w, r, ErrorCodeUnauthenticated, err.Error())
 		return
 	}
 
@@ -138,6 +236,9 @@ func (c *Controller) handlePopupGet(w http.ResponseWriter, r *http.Request) {
 	if formDetails.Category == "" {
 		formDetails.Category = jvspb.DefaultJustificationCategory
 	}
+	if formDetails.SecondCategory == "" {
+		formDetails.SecondCategory = formDetails.Category
+	}
 	if formDetails.TTL == "" {
 		formDetails.TTL = defaultTTL
 	}
@@ -149,7 +250,7 @@ func (c *Controller) handlePopupGet(w http.ResponseWriter, r *http.Request) {
 func (c *Controller) handlePopupPost(w http.ResponseWriter, r *http.Request) {
 	formDetails, err := c.getFormDetails(r)
 	if err != nil {
-		c.renderBadRequest(w, err.Error())
+		c.renderBadRequest(w, r, ErrorCodeUnauthenticated, err.Error())
 		return
 	}
 
@@ -163,46 +264,64 @@ func (c *Controller) handlePopupPost(w http.ResponseWriter, r *http.Request) {
 			m = "Unexpected origin provided"
 		}
 
-		c.renderBadRequest(w, m)
+		c.renderBadRequest(w, r, ErrorCodeInvalidRequest, m)
 		return
 	}
 
-	// 2. Validate input
-	if !c.validateForm(formDetails) {
+	// 2. Validate input, or go back to the form from the confirmation page
+	if !c.validateForm(r.Context(), formDetails) || r.FormValue("edit") == "true" {
 		c.h.RenderHTML(w, "popup.html", formDetails)
 		return
 	}
 
-	// 3. Request a token
 	dur, err := time.ParseDuration(formDetails.TTL)
 	if err != nil {
-		c.renderBadRequest(w, err.Error())
+		c.renderBadRequest(w, r, ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// 3. Show what will be encoded in the token, until the user confirms it
+	if c.confirm && r.FormValue("confirmed") != "true" {
+		c.h.RenderHTML(w, "confirm.html", &ConfirmDetails{
+			FormDetails:         formDetails,
+			CategoryDisplayName: c.categoryDisplayData[formDetails.Category].GetDisplayName(),
+			Audiences:           []string{justification.DefaultAudience},
+			ExpiresAt:           time.Now().UTC().Add(dur).Truncate(time.Second),
+
+			SecondCategoryDisplayName: c.categoryDisplayData[formDetails.SecondCategory].GetDisplayName(),
+		})
 		return
 	}
 
+	// 4. Request a token
+
 	req := &jvspb.CreateJustificationRequest{
-		Justifications: []*jvspb.Justification{
-			{
-				Category: formDetails.Category,
-				Value:    formDetails.Reason,
-			},
-		},
-		Ttl: durationpb.New(dur),
+		Justifications: formDetails.justifications(),
+		Ttl:            durationpb.New(dur),
 	}
 
-	token, err := c.p.CreateToken(context.Background(), formDetails.UserEmail, req)
+	resp, err := c.p.CreateJustification(context.Background(), formDetails.UserEmail, req)
 	if err != nil {
-		c.renderBadRequest(w, err.Error())
+		c.renderBadRequest(w, r, tokenErrorCode(err), err.Error())
 		return
 	}
 
-	// 4. Redirect to a confirmation page with context, ultimately needed to postMessage back to the client
+	// 5. Redirect to a success page with context, ultimately needed to postMessage back to the client
+	msg, err := successMessage(formDetails.Version, formDetails.WindowName, resp)
+	if err != nil {
+		c.renderBadRequest(w, r, ErrorCodeInternal, err.Error())
+		return
+	}
 	successDetails := &SuccessDetails{
 		PageTitle:   "JVS - Successful token retrieval",
 		Description: "Successful token page",
-		Token:       string(token),
+		Token:       resp.GetToken(),
+		Issuer:      resp.GetIssuer(),
+		ExpiresAt:   resp.GetExpiresAt().AsTime(),
+		TokenID:     resp.GetJti(),
 		Origin:      formDetails.Origin,
 		WindowName:  formDetails.WindowName,
+		PostMessage: msg,
 	}
 	c.h.RenderHTML(w, "success.html", successDetails)
 }
@@ -269,32 +388,80 @@ func validateLocalIP(originParam string) (bool, error) {
 	return (parsedIP.IsLoopback() || parsedIP.IsPrivate()), nil
 }
 
-func (c *Controller) validateForm(formDetails *FormDetails) bool {
+func (c *Controller) validateForm(ctx context.Context, formDetails *FormDetails) bool {
 	// This only does sanity check of the form, e.g. field not empty.
 	// The actual verification only happens when submitting the form.
 	formDetails.Errors = make(map[string]string)
+	formDetails.ErrorSummary = nil
+	addError := func(field, id, msg string) {
+		formDetails.Errors[field] = msg
+		formDetails.ErrorSummary = append(formDetails.ErrorSummary, &FieldError{FieldID: id, Message: msg})
+	}
 
 	if _, ok := c.categoryDisplayData[formDetails.Category]; !ok {
-		formDetails.Errors["Category"] = "Category must be selected"
+		addError("Category", "category", "Category must be selected")
 	}
 
 	if strings.TrimSpace(formDetails.Reason) == "" {
-		formDetails.Errors["Reason"] = "Reason is required"
+		addError("Reason", "reason", "Reason is required")
+	} else if re := c.valuePattern(ctx, formDetails.Category); re != nil && !re.MatchString(formDetails.Reason) {
+		addError("Reason", "reason", fmt.Sprintf("Reason must match the format %s", re))
+	}
+
+	// The second justification is optional, it is only checked if it is given.
+	if strings.TrimSpace(formDetails.SecondReason) != "" {
+		if _, ok := c.categoryDisplayData[formDetails.SecondCategory]; !ok {
+			addError("SecondCategory", "category2", "Second category must be selected")
+		} else if re := c.valuePattern(ctx, formDetails.SecondCategory); re != nil && !re.MatchString(formDetails.SecondReason) {
+			addError("SecondReason", "reason2", fmt.Sprintf("Second reason must match the format %s", re))
+		}
 	}
 
 	if _, ok := ttls[formDetails.TTL]; !ok {
-		formDetails.Errors["TTL"] = "TTL is required"
+		addError("TTL", "ttl", "TTL is required")
 	}
 
 	return len(formDetails.Errors) == 0
 }
 
+// justifications returns the justifications of the form, the second one only
+// if its reason is set.
+func (f *FormDetails) justifications() []*jvspb.Justification {
+	justs := []*jvspb.Justification{
+		{
+			Category: f.Category,
+			Value:    f.Reason,
+		},
+	}
+	if strings.TrimSpace(f.SecondReason) != "" {
+		justs = append(justs, &jvspb.Justification{
+			Category: f.SecondCategory,
+			Value:    f.SecondReason,
+		})
+	}
+	return justs
+}
+
+// valuePattern returns the pattern reasons of the category must match, or nil
+// if it has none. Patterns that can't be looked up aren't checked in the form,
+// since the processor checks them again anyway.
+func (c *Controller) valuePattern(ctx context.Context, category string) *regexp.Regexp {
+	if _, ok := c.categoryDisplayData[category]; !ok {
+		return nil
+	}
+	re, err := c.p.ValuePattern(ctx, category)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
 func isValidOneOf(selection string, options []string) bool {
 	return slices.Contains(options, selection)
 }
 
 func (c *Controller) getFormDetails(r *http.Request) (*FormDetails, error) {
-	email, err := getEmail(r)
+	email, err := c.auth.Email(r)
 	if err != nil {
 		return nil, err
 	}
@@ -302,7 +469,8 @@ func (c *Controller) getFormDetails(r *http.Request) (*FormDetails, error) {
 	return &FormDetails{
 		WindowName:  r.FormValue("windowname"),
 		Origin:      r.FormValue("origin"),
-		Category:    c.getCategory(),
+		Version:     messageVersion(r),
+		Category:    c.formCategory(r),
 		Reason:      r.FormValue("reason"),
 		UserEmail:   email,
 		TTL:         r.FormValue("ttl"),
@@ -315,18 +483,49 @@ func (c *Controller) getFormDetails(r *http.Request) (*FormDetails, error) {
 			TTLLabel:      "TTL",
 			Categories:    c.categoryDisplayData,
 			TTLs:          ttls,
+
+			ErrorSummaryLabel:        "There is a problem with your request",
+			SecondJustificationLabel: "Add another justification",
 		},
+
+		SecondCategory: r.FormValue("category2"),
+		SecondReason:   r.FormValue("reason2"),
 	}, nil
 }
 
-// Renders a bad request page with a custom message.
-func (c *Controller) renderBadRequest(w http.ResponseWriter, m string) {
+// Renders a bad request page with a custom message. Openers that speak
+// [MessageVersion] are also posted an error message with the code, so they can
+// handle the error instead of leaving the user on the page.
+func (c *Controller) renderBadRequest(w http.ResponseWriter, r *http.Request, code, m string) {
 	t := http.StatusText(http.StatusBadRequest)
-	c.h.RenderHTMLStatus(w, http.StatusBadRequest, "400.html", &ErrorDetails{
+	details := &ErrorDetails{
 		PageTitle:   t,
 		Description: t,
 		Message:     m,
-	})
+	}
+
+	// Errors are only posted to origins that may receive tokens.
+	origin := r.FormValue("origin")
+	if messageVersion(r) >= MessageVersion {
+		if valid, err := validateOrigin(origin, c.allowlist); err == nil && valid {
+			if msg, err := errorMessage(r.FormValue("windowname"), code, m); err == nil {
+				details.Origin = origin
+				details.PostMessage = msg
+			}
+		}
+	}
+
+	c.h.RenderHTMLStatus(w, http.StatusBadRequest, "400.html", details)
+}
+
+// messageVersion returns the postMessage protocol version the opener
+// requested, defaulting to 1.
+func messageVersion(r *http.Request) int {
+	v, err := strconv.Atoi(r.FormValue("version"))
+	if err != nil || v < 1 {
+		return 1
+	}
+	return min(v, MessageVersion)
 }
 
 func getEmail(r *http.Request) (string, error) {
@@ -364,6 +563,15 @@ func catagoriesDisplayData(ctx context.Context, validators map[string]jvspb.Vali
 	return displayData, nil
 }
 
+// formCategory returns the category selected in the form, or the default
+// category if none is.
+func (c *Controller) formCategory(r *http.Request) string {
+	if v := r.FormValue("category"); v != "" {
+		return v
+	}
+	return c.getCategory()
+}
+
 // The category with the lowest alphabetical value will be selected.
 func (c *Controller) getCategory() string {
 	// Due to the presence of the DefaultJustificationCategory, the categoryDisplayData list cannot be empty.
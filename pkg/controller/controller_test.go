@@ -16,10 +16,16 @@ package controller
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
+	"html"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -34,9 +40,10 @@ import (
 )
 
 type mockValidator struct {
-	Valid       bool
-	DisplayName string
-	Hint        string
+	Valid        bool
+	DisplayName  string
+	Hint         string
+	ValuePattern string
 }
 
 func (v *mockValidator) Validate(context.Context, *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
@@ -45,8 +52,9 @@ func (v *mockValidator) Validate(context.Context, *jvspb.ValidateJustificationRe
 
 func (v *mockValidator) GetUIData(context.Context, *jvspb.GetUIDataRequest) (*jvspb.UIData, error) {
 	return &jvspb.UIData{
-		DisplayName: v.DisplayName,
-		Hint:        v.Hint,
+		DisplayName:  v.DisplayName,
+		Hint:         v.Hint,
+		ValuePattern: v.ValuePattern,
 	}, nil
 }
 
@@ -128,6 +136,268 @@ func TestHandlePopup(t *testing.T) {
 	}
 }
 
+func TestHandlePopup_messages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	form := func(version string) *url.Values {
+		v := &url.Values{
+			"origin":     {"https://localhost:3000"},
+			"windowname": {"jvs-popup"},
+			"category":   {jvspb.DefaultJustificationCategory},
+			"reason":     {"issue/1234"},
+			"ttl":        {"15m"},
+		}
+		if version != "" {
+			v.Set("version", version)
+		}
+		return v
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		form        *url.Values
+		localSigner bool
+		wantResCode int
+		wantBody    []string
+		wantNoBody  []string
+	}{
+		{
+			name:        "v1_popup",
+			method:      http.MethodGet,
+			form:        form(""),
+			wantResCode: http.StatusOK,
+			wantBody:    []string{`name="version" value="1"`},
+			wantNoBody:  []string{`id="cancel"`},
+		},
+		{
+			name:        "v2_popup",
+			method:      http.MethodGet,
+			form:        form("2"),
+			wantResCode: http.StatusOK,
+			wantBody:    []string{`name="version" value="2"`, `id="cancel"`},
+		},
+		{
+			name:   "invalid_form",
+			method: http.MethodPost,
+			form: func() *url.Values {
+				v := form("")
+				v.Set("reason", " ")
+				v.Set("ttl", "3d")
+				return v
+			}(),
+			wantResCode: http.StatusOK,
+			wantBody: []string{
+				`id="error-summary" role="alert"`,
+				`<a href="#reason">Reason is required</a>`,
+				`<a href="#ttl">TTL is required</a>`,
+				`aria-describedby="hint reason-error"`,
+				`id="reason-error"`,
+				`aria-invalid="true" aria-describedby="ttl-error"`,
+			},
+			wantNoBody: []string{`id="category-error"`},
+		},
+		{
+			name:        "v1_error",
+			method:      http.MethodPost,
+			form:        form(""),
+			wantResCode: http.StatusBadRequest,
+			wantNoBody:  []string{`id="error"`},
+		},
+		{
+			name:        "v2_success",
+			method:      http.MethodPost,
+			form:        form("2"),
+			localSigner: true,
+			wantResCode: http.StatusOK,
+			wantBody: []string{
+				`id="success"`,
+				`id="issuer">Issued by jvs.abcxyz.dev.`,
+				html.EscapeString(`"issuer":"jvs.abcxyz.dev","expires_at":`),
+				`id="token-details">Token `,
+			},
+		},
+		{
+			name:        "v2_error",
+			method:      http.MethodPost,
+			form:        form("2"),
+			wantResCode: http.StatusBadRequest,
+			wantBody: []string{
+				`id="error"`,
+				`data-origin="https://localhost:3000"`,
+				html.EscapeString(`{"version":2,"type":"error","source":"jvs-popup","error":{"code":"internal"`),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			harness := envtest.NewServerConfig(t, "9091", []string{"*"}, true)
+			if tc.localSigner {
+				privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				harness.Processor.WithLocalSigner(privateKey, "test-key")
+			}
+			c, err := New(ctx, harness.Renderer, harness.Processor, []string{"*"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The initial page load gets its parameters from the query.
+			path, form := "/popup", tc.form
+			if tc.method == http.MethodGet {
+				path, form = path+"?"+tc.form.Encode(), nil
+			}
+			w, r := envtest.BuildFormRequest(ctx, t, tc.method, path, form)
+			r.Header.Set(iapHeaderName, "acccounts.google.com:test@email.com")
+
+			c.HandlePopup().ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.wantResCode; got != want {
+				t.Fatalf("expected %d to be %d:\n\n%s", got, want, w.Body.String())
+			}
+			body := w.Body.String()
+			for _, want := range tc.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected body to contain %q:\n\n%s", want, body)
+				}
+			}
+			for _, want := range tc.wantNoBody {
+				if strings.Contains(body, want) {
+					t.Errorf("expected body not to contain %q:\n\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePopup_confirm(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	form := func(extra ...string) *url.Values {
+		v := &url.Values{
+			"origin":     {"https://localhost:3000"},
+			"windowname": {"jvs-popup"},
+			"category":   {jvspb.DefaultJustificationCategory},
+			"reason":     {"issue/1234"},
+			"ttl":        {"1h"},
+		}
+		for i := 0; i < len(extra); i += 2 {
+			v.Set(extra[i], extra[i+1])
+		}
+		return v
+	}
+
+	cases := []struct {
+		name        string
+		confirm     bool
+		form        *url.Values
+		wantResCode int
+		wantBody    []string
+		wantNoBody  []string
+	}{
+		{
+			name:        "disabled",
+			form:        form(),
+			wantResCode: http.StatusBadRequest,
+			wantNoBody:  []string{"Confirm your justification"},
+		},
+		{
+			name:        "preview",
+			confirm:     true,
+			form:        form(),
+			wantResCode: http.StatusOK,
+			wantBody: []string{
+				"Confirm your justification",
+				"<dd>test@email.com</dd>",
+				"<dd>" + justification.DefaultAudience + "</dd>",
+				"<dd>issue/1234</dd>",
+				"<dd>1h</dd>",
+				`<time datetime="`,
+				`name="reason" value="issue/1234"`,
+				`name="confirmed" value="true"`,
+			},
+		},
+		{
+			name:        "preview_second_justification",
+			confirm:     true,
+			form:        form("category2", jvspb.DefaultJustificationCategory, "reason2", "rolling back the release"),
+			wantResCode: http.StatusOK,
+			wantBody: []string{
+				"<dt>Reason 2</dt>",
+				"<dd>rolling back the release</dd>",
+				`name="reason2" value="rolling back the release"`,
+			},
+		},
+		{
+			name:        "preview_invalid_form",
+			confirm:     true,
+			form:        form("reason", ""),
+			wantResCode: http.StatusOK,
+			wantBody:    []string{`id="reason-error"`},
+			wantNoBody:  []string{"Confirm your justification"},
+		},
+		{
+			name:        "back",
+			confirm:     true,
+			form:        form("edit", "true"),
+			wantResCode: http.StatusOK,
+			wantBody:    []string{`id="form"`, `value="issue/1234"`},
+			wantNoBody:  []string{"Confirm your justification"},
+		},
+		{
+			// The envtest processor can't sign tokens, so getting past the
+			// preview fails.
+			name:        "confirmed",
+			confirm:     true,
+			form:        form("confirmed", "true"),
+			wantResCode: http.StatusBadRequest,
+			wantNoBody:  []string{"Confirm your justification"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			harness := envtest.NewServerConfig(t, "9091", []string{"*"}, true)
+			c, err := New(ctx, harness.Renderer, harness.Processor, []string{"*"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.WithConfirmation(tc.confirm)
+
+			w, r := envtest.BuildFormRequest(ctx, t, http.MethodPost, "/popup", tc.form)
+			r.Header.Set(iapHeaderName, "acccounts.google.com:test@email.com")
+
+			c.HandlePopup().ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.wantResCode; got != want {
+				t.Fatalf("expected %d to be %d:\n\n%s", got, want, w.Body.String())
+			}
+			body := w.Body.String()
+			for _, want := range tc.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected body to contain %q:\n\n%s", want, body)
+				}
+			}
+			for _, want := range tc.wantNoBody {
+				if strings.Contains(body, want) {
+					t.Errorf("expected body not to contain %q:\n\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
 func TestValidateOrigin(t *testing.T) {
 	t.Parallel()
 
@@ -356,7 +626,7 @@ func TestValidateForm(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			got := controller.validateForm(&tc.detail)
+			got := controller.validateForm(context.Background(), &tc.detail)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("Failed validating (-want,+got):\n%s", diff)
 			}
@@ -364,6 +634,201 @@ func TestValidateForm(t *testing.T) {
 	}
 }
 
+func TestValidateForm_errorSummary(t *testing.T) {
+	t.Parallel()
+
+	p := justification.NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	})
+	controller, err := New(context.Background(), nil, p, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	details := &FormDetails{}
+	if controller.validateForm(context.Background(), details) {
+		t.Fatal("expected the form to be invalid")
+	}
+
+	want := []*FieldError{
+		{FieldID: "category", Message: "Category must be selected"},
+		{FieldID: "reason", Message: "Reason is required"},
+		{FieldID: "ttl", Message: "TTL is required"},
+	}
+	if diff := cmp.Diff(want, details.ErrorSummary); diff != "" {
+		t.Errorf("error summary (-want, +got):\n%s", diff)
+	}
+
+	// Fixing the form clears the errors.
+	details.Category = jvspb.DefaultJustificationCategory
+	details.Reason = "reason"
+	details.TTL = defaultTTL
+	if !controller.validateForm(context.Background(), details) {
+		t.Fatalf("expected the form to be valid: %v", details.Errors)
+	}
+	if got := details.ErrorSummary; len(got) != 0 {
+		t.Errorf("expected no errors, got %v", got)
+	}
+}
+
+func TestValidateForm_valuePattern(t *testing.T) {
+	t.Parallel()
+
+	p := justification.NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	}).WithValidators(map[string]jvspb.Validator{
+		"github": &mockValidator{
+			Valid:        true,
+			DisplayName:  "GitHub issue",
+			ValuePattern: `^issues/\d+$`,
+		},
+		"jira": &mockValidator{
+			Valid:       true,
+			DisplayName: "Jira issue key",
+		},
+	}).WithValuePatterns(map[string]*regexp.Regexp{
+		"jira": regexp.MustCompile(`^JVS-\d+$`),
+	})
+	controller, err := New(context.Background(), nil, p, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		category string
+		reason   string
+		wantErr  string
+	}{
+		{
+			name:     "plugin_match",
+			category: "github",
+			reason:   "issues/123",
+		},
+		{
+			name:     "plugin_mismatch",
+			category: "github",
+			reason:   "JVS-123",
+			wantErr:  `Reason must match the format ^issues/\d+$`,
+		},
+		{
+			name:     "config_match",
+			category: "jira",
+			reason:   "JVS-123",
+		},
+		{
+			name:     "config_mismatch",
+			category: "jira",
+			reason:   "issues/123",
+			wantErr:  `Reason must match the format ^JVS-\d+$`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			details := &FormDetails{
+				Category: tc.category,
+				Reason:   tc.reason,
+				TTL:      defaultTTL,
+			}
+			controller.validateForm(context.Background(), details)
+			if diff := cmp.Diff(tc.wantErr, details.Errors["Reason"]); diff != "" {
+				t.Errorf("reason error (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateForm_secondJustification(t *testing.T) {
+	t.Parallel()
+
+	p := justification.NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	}).WithValidators(map[string]jvspb.Validator{
+		"github": &mockValidator{
+			Valid:        true,
+			DisplayName:  "GitHub issue",
+			ValuePattern: `^issues/\d+$`,
+		},
+		"jira": &mockValidator{
+			Valid:       true,
+			DisplayName: "Jira issue key",
+		},
+	})
+	controller, err := New(context.Background(), nil, p, []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name               string
+		secondCategory     string
+		secondReason       string
+		wantErrors         map[string]string
+		wantJustifications []*jvspb.Justification
+	}{
+		{
+			name:           "not_given",
+			secondCategory: "github",
+			wantErrors:     map[string]string{},
+			wantJustifications: []*jvspb.Justification{
+				{Category: "jira", Value: "JVS-123"},
+			},
+		},
+		{
+			name:           "valid",
+			secondCategory: "github",
+			secondReason:   "issues/123",
+			wantErrors:     map[string]string{},
+			wantJustifications: []*jvspb.Justification{
+				{Category: "jira", Value: "JVS-123"},
+				{Category: "github", Value: "issues/123"},
+			},
+		},
+		{
+			name:         "missing_category",
+			secondReason: "issues/123",
+			wantErrors: map[string]string{
+				"SecondCategory": "Second category must be selected",
+			},
+		},
+		{
+			name:           "pattern_mismatch",
+			secondCategory: "github",
+			secondReason:   "JVS-123",
+			wantErrors: map[string]string{
+				"SecondReason": `Second reason must match the format ^issues/\d+$`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			details := &FormDetails{
+				Category:       "jira",
+				Reason:         "JVS-123",
+				TTL:            defaultTTL,
+				SecondCategory: tc.secondCategory,
+				SecondReason:   tc.secondReason,
+			}
+			controller.validateForm(context.Background(), details)
+			if diff := cmp.Diff(tc.wantErrors, details.Errors); diff != "" {
+				t.Errorf("errors (-want, +got):\n%s", diff)
+			}
+			if len(details.Errors) > 0 {
+				return
+			}
+			if diff := cmp.Diff(tc.wantJustifications, details.justifications(), protocmp.Transform()); diff != "" {
+				t.Errorf("justifications (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestIsValidOneOf(t *testing.T) {
 	t.Parallel()
 
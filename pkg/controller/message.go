@@ -0,0 +1,172 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// MessageVersion is the version of the postMessage protocol the popup speaks
+// when the opener requests it with the "version" query parameter. Without it,
+// the popup only posts the version 1 success message.
+const MessageVersion = 2
+
+// Types of the messages the popup posts to its opener.
+const (
+	MessageTypeSuccess = "success"
+	MessageTypeError   = "error"
+	MessageTypeCancel  = "cancel"
+)
+
+// Codes of error messages.
+const (
+	// ErrorCodeUnauthenticated means the user couldn't be identified.
+	ErrorCodeUnauthenticated = "unauthenticated"
+
+	// ErrorCodeInvalidRequest means the popup was opened or submitted with
+	// invalid parameters.
+	ErrorCodeInvalidRequest = "invalid_request"
+
+	// ErrorCodeDenied means the justification was rejected, or the user
+	// exhausted the daily quota of its category.
+	ErrorCodeDenied = "denied"
+
+	// ErrorCodeInternal means the token couldn't be issued because of a server
+	// error. The request may be retried.
+	ErrorCodeInternal = "internal"
+
+	// ErrorCodeUnavailable means the JVS is in maintenance mode and is not
+	// issuing tokens. The message is the operator's explanation.
+	ErrorCodeUnavailable = "unavailable"
+)
+
+// Message is a message the popup posts to its opener, JSON encoded. Openers
+// must check Source is the name of the window they opened.
+type Message struct {
+	// Version is the protocol version, it is omitted in version 1 messages.
+	Version int `json:"version,omitempty"`
+
+	// Type is one of the MessageType constants. It is omitted in version 1
+	// messages, which are always successes.
+	Type string `json:"type,omitempty"`
+
+	// Source is the name of the popup window.
+	Source string `json:"source"`
+
+	// Payload is set for success messages.
+	Payload *MessagePayload `json:"payload,omitempty"`
+
+	// Error is set for error messages.
+	Error *MessageError `json:"error,omitempty"`
+}
+
+// MessagePayload is the payload of a success message.
+type MessagePayload struct {
+	Token string `json:"token"`
+
+	// Issuer is the issuer (iss) of the token. It is omitted in version 1
+	// messages.
+	Issuer string `json:"issuer,omitempty"`
+
+	// ExpiresAt is the expiration (exp) of the token in RFC 3339 format, e.g.
+	// to schedule refreshing it. It is omitted in version 1 messages.
+	ExpiresAt string `json:"expires_at,omitempty"`
+
+	// Warnings are the non-fatal warnings of the validators, for the opener
+	// to show next to the token. They are omitted in version 1 messages.
+	Warnings []*MessageWarning `json:"warnings,omitempty"`
+}
+
+// MessageWarning is a non-fatal warning from validating a justification.
+type MessageWarning struct {
+	// Category is the category of the justification.
+	Category string `json:"category"`
+
+	// Message is a human readable description of the warning.
+	Message string `json:"message"`
+}
+
+// MessageError is the error of an error message.
+type MessageError struct {
+	// Code is one of the ErrorCode constants.
+	Code string `json:"code"`
+
+	// Message is a human readable description of the error.
+	Message string `json:"message"`
+}
+
+// successMessage returns the JSON encoded success message of the response for
+// the version.
+func successMessage(version int, source string, resp *jvspb.CreateJustificationResponse) (string, error) {
+	m := &Message{
+		Source:  source,
+		Payload: &MessagePayload{Token: resp.GetToken()},
+	}
+	if version >= MessageVersion {
+		m.Version = MessageVersion
+		m.Type = MessageTypeSuccess
+		m.Payload.Issuer = resp.GetIssuer()
+		if exp := resp.GetExpiresAt(); exp != nil {
+			m.Payload.ExpiresAt = exp.AsTime().Format(time.RFC3339)
+		}
+		for _, w := range resp.GetWarnings() {
+			m.Payload.Warnings = append(m.Payload.Warnings, &MessageWarning{
+				Category: w.GetCategory(),
+				Message:  w.GetMessage(),
+			})
+		}
+	}
+	return encodeMessage(m)
+}
+
+// errorMessage returns the JSON encoded error message.
+func errorMessage(source, code, msg string) (string, error) {
+	return encodeMessage(&Message{
+		Version: MessageVersion,
+		Type:    MessageTypeError,
+		Source:  source,
+		Error: &MessageError{
+			Code:    code,
+			Message: msg,
+		},
+	})
+}
+
+func encodeMessage(m *Message) (string, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return string(b), nil
+}
+
+// tokenErrorCode returns the error code for an error creating a token.
+func tokenErrorCode(err error) string {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.PermissionDenied, codes.ResourceExhausted:
+		return ErrorCodeDenied
+	case codes.Unavailable:
+		return ErrorCodeUnavailable
+	default:
+	}
+	return ErrorCodeInternal
+}
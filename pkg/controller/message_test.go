@@ -0,0 +1,197 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+func TestSuccessMessage(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		version  int
+		warnings []*jvspb.ValidationWarning
+		want     string
+	}{
+		{
+			name:    "v1",
+			version: 1,
+			want:    `{"source":"jvs-popup","payload":{"token":"TOKEN"}}`,
+		},
+		{
+			name:    "v2",
+			version: 2,
+			want:    `{"version":2,"type":"success","source":"jvs-popup","payload":{"token":"TOKEN","issuer":"jvs.corp","expires_at":"2023-06-01T22:00:00Z"}}`,
+		},
+		{
+			name:    "v1_warnings",
+			version: 1,
+			warnings: []*jvspb.ValidationWarning{
+				{Category: "jira", Message: "ticket closes in 20 minutes"},
+			},
+			want: `{"source":"jvs-popup","payload":{"token":"TOKEN"}}`,
+		},
+		{
+			name:    "v2_warnings",
+			version: 2,
+			warnings: []*jvspb.ValidationWarning{
+				{Category: "jira", Message: "ticket closes in 20 minutes"},
+			},
+			want: `{"version":2,"type":"success","source":"jvs-popup","payload":{"token":"TOKEN","issuer":"jvs.corp","expires_at":"2023-06-01T22:00:00Z",` +
+				`"warnings":[{"category":"jira","message":"ticket closes in 20 minutes"}]}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := successMessage(tc.version, "jvs-popup", &jvspb.CreateJustificationResponse{
+				Token:     "TOKEN",
+				Warnings:  tc.warnings,
+				Issuer:    "jvs.corp",
+				ExpiresAt: timestamppb.New(time.Date(2023, 6, 1, 22, 0, 0, 0, time.UTC)),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %s to be %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	t.Parallel()
+
+	got, err := errorMessage("jvs-popup", ErrorCodeDenied, "justification rejected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":2,"type":"error","source":"jvs-popup","error":{"code":"denied","message":"justification rejected"}}`
+	if got != want {
+		t.Errorf("expected %s to be %s", got, want)
+	}
+}
+
+func TestMessageVersion(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		version string
+		want    int
+	}{
+		{
+			name: "missing",
+			want: 1,
+		},
+		{
+			name:    "v2",
+			version: "2",
+			want:    2,
+		},
+		{
+			name:    "newer",
+			version: "3",
+			want:    MessageVersion,
+		},
+		{
+			name:    "invalid",
+			version: "two",
+			want:    1,
+		},
+		{
+			name:    "zero",
+			version: "0",
+			want:    1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/popup?version="+tc.version, nil)
+			if got := messageVersion(r); got != tc.want {
+				t.Errorf("expected %d to be %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenErrorCode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "invalid_argument",
+			err:  status.Error(codes.InvalidArgument, "failed to validate justifications"),
+			want: ErrorCodeDenied,
+		},
+		{
+			name: "permission_denied",
+			err:  status.Error(codes.PermissionDenied, `requestor "me@example.com" is on the deny list`),
+			want: ErrorCodeDenied,
+		},
+		{
+			name: "resource_exhausted",
+			err:  status.Error(codes.ResourceExhausted, `daily quota of 5 tokens of category "breakglass" is exhausted`),
+			want: ErrorCodeDenied,
+		},
+		{
+			name: "unavailable",
+			err:  status.Error(codes.Unavailable, "key migration in progress"),
+			want: ErrorCodeUnavailable,
+		},
+		{
+			name: "internal",
+			err:  status.Error(codes.Internal, "failed to sign token"),
+			want: ErrorCodeInternal,
+		},
+		{
+			name: "not_status",
+			err:  fmt.Errorf("boom"),
+			want: ErrorCodeInternal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tokenErrorCode(tc.err); got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package decision stores the traces of denied token requests, so admins can
+// look up why a request was denied by the request ID returned with its error.
+package decision
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// ErrNotFound is returned when there is no trace for the request ID, or it
+// expired.
+var ErrNotFound = errors.New("decision trace not found")
+
+// Store is where traces are stored. A store in storage shared by all replicas
+// can serve the traces of requests denied by any of them.
+type Store interface {
+	// Put stores the trace until it is older than the retention of the store.
+	Put(ctx context.Context, trace *jvspb.DecisionTrace) error
+
+	// Get returns the trace of the request ID, or [ErrNotFound].
+	Get(ctx context.Context, requestID string) (*jvspb.DecisionTrace, error)
+}
+
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// ValidRequestID reports whether the ID is one returned by [NewRequestID].
+func ValidRequestID(id string) bool {
+	return uuid.Validate(id) == nil && len(id) == 36
+}
+
+// HashInput returns the hex-encoded SHA-256 hash of the input, so traces can
+// be matched against the inputs of a request without recording them.
+func HashInput(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
+
+// expired reports whether the trace is older than the retention.
+func expired(trace *jvspb.DecisionTrace, retention time.Duration, now time.Time) bool {
+	return now.Sub(trace.GetTime().AsTime()) > retention
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decision
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+func TestStores(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		newStore func(t *testing.T, retention time.Duration) Store
+	}{
+		{
+			name: "memory",
+			newStore: func(t *testing.T, retention time.Duration) Store {
+				t.Helper()
+				return NewMemoryStore(retention)
+			},
+		},
+		{
+			name: "file",
+			newStore: func(t *testing.T, retention time.Duration) Store {
+				t.Helper()
+				return NewFileStore(t.TempDir(), retention)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			store := tc.newStore(t, time.Hour)
+
+			trace := &jvspb.DecisionTrace{
+				RequestId: NewRequestID(),
+				Time:      timestamppb.Now(),
+				Requestor: "me@example.com",
+				Steps: []*jvspb.DecisionStep{
+					{Stage: "deny_list", Rule: "deny_list", Denied: true, Detail: "requestor is on the deny list"},
+				},
+				Code: "PermissionDenied",
+			}
+			if err := store.Put(ctx, trace); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := store.Get(ctx, trace.GetRequestId())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(trace, got, protocmp.Transform()); diff != "" {
+				t.Errorf("trace (-want, +got):\n%s", diff)
+			}
+
+			if _, err := store.Get(ctx, NewRequestID()); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected %v to be %v", err, ErrNotFound)
+			}
+
+			expired := &jvspb.DecisionTrace{
+				RequestId: NewRequestID(),
+				Time:      timestamppb.New(time.Now().Add(-2 * time.Hour)),
+			}
+			if err := store.Put(ctx, expired); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(ctx, expired.GetRequestId()); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected expired trace to not be found, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStore_invalidRequestID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "traces"), time.Hour)
+
+	if err := os.WriteFile(filepath.Join(dir, "secret.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "../secret"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v to be %v", err, ErrNotFound)
+	}
+	if err := store.Put(ctx, &jvspb.DecisionTrace{RequestId: "../secret"}); err == nil {
+		t.Errorf("expected an invalid request id to be rejected")
+	}
+}
+
+func TestFileStore_prune(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewFileStore(dir, time.Hour)
+
+	old := filepath.Join(dir, NewRequestID()+".json")
+	if err := os.WriteFile(old, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := &jvspb.DecisionTrace{RequestId: NewRequestID(), Time: timestamppb.Now()}
+	if err := store.Put(ctx, trace); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(old); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected expired trace file to be removed, got %v", err)
+	}
+	if _, err := store.Get(ctx, trace.GetRequestId()); err != nil {
+		t.Errorf("expected new trace to be kept, got %v", err)
+	}
+}
+
+func TestMemoryStore_capacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour)
+
+	first := NewRequestID()
+	for i := 0; i <= memoryStoreCapacity; i++ {
+		id := NewRequestID()
+		if i == 0 {
+			id = first
+		}
+		if err := store.Put(ctx, &jvspb.DecisionTrace{RequestId: id, Time: timestamppb.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.Get(ctx, first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the oldest trace to be dropped, got %v", err)
+	}
+	if got, want := len(store.traces), memoryStoreCapacity; got != want {
+		t.Errorf("expected %d traces to be %d", got, want)
+	}
+}
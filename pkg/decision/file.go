@@ -0,0 +1,149 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// filePruneInterval is how often a [FileStore] removes expired traces.
+const filePruneInterval = time.Minute
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore stores traces as JSON files in a directory, one per request. With
+// several replicas, the directory must be on a volume they all share, e.g. a
+// Cloud Storage volume, so any of them can serve any trace. Expired traces are
+// removed when traces are put.
+type FileStore struct {
+	dir       string
+	retention time.Duration
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewFileStore creates a store in the directory that keeps traces for the
+// retention. The directory must exist.
+func NewFileStore(dir string, retention time.Duration) *FileStore {
+	return &FileStore{
+		dir:       dir,
+		retention: retention,
+	}
+}
+
+// Put implements [Store].
+func (s *FileStore) Put(ctx context.Context, trace *jvspb.DecisionTrace) error {
+	if !ValidRequestID(trace.GetRequestId()) {
+		return fmt.Errorf("invalid request id %q", trace.GetRequestId())
+	}
+
+	b, err := protojson.Marshal(trace)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decision trace: %w", err)
+	}
+
+	// Write to a temporary file and rename it, so readers never see a partial
+	// trace.
+	f, err := os.CreateTemp(s.dir, trace.GetRequestId()+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create decision trace file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write decision trace file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close decision trace file: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path(trace.GetRequestId())); err != nil {
+		return fmt.Errorf("failed to rename decision trace file: %w", err)
+	}
+
+	s.prune()
+	return nil
+}
+
+// Get implements [Store].
+func (s *FileStore) Get(ctx context.Context, requestID string) (*jvspb.DecisionTrace, error) {
+	if !ValidRequestID(requestID) {
+		return nil, ErrNotFound
+	}
+
+	b, err := os.ReadFile(s.path(requestID))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to read decision trace: %w", err)
+	}
+
+	var trace jvspb.DecisionTrace
+	if err := protojson.Unmarshal(b, &trace); err != nil {
+		return nil, fmt.Errorf("failed to parse decision trace: %w", err)
+	}
+	if expired(&trace, s.retention, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &trace, nil
+}
+
+func (s *FileStore) path(requestID string) string {
+	return filepath.Join(s.dir, requestID+".json")
+}
+
+// prune removes the traces older than the retention, at most once every
+// filePruneInterval. Traces that can't be removed are left for the next prune.
+func (s *FileStore) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPrune) < filePruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if now.Sub(info.ModTime()) > s.retention {
+			_ = os.Remove(filepath.Join(s.dir, e.Name()))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package decision
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// memoryStoreCapacity is the maximum number of traces a [MemoryStore] keeps.
+// The oldest traces are dropped first.
+const memoryStoreCapacity = 10_000
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore stores traces in memory. It only serves the traces of requests
+// denied by its own replica, and loses them on restart.
+type MemoryStore struct {
+	retention time.Duration
+
+	mu     sync.Mutex
+	traces map[string]*jvspb.DecisionTrace
+	order  []string
+}
+
+// NewMemoryStore creates a store that keeps traces for the retention.
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		retention: retention,
+		traces:    make(map[string]*jvspb.DecisionTrace),
+	}
+}
+
+// Put implements [Store].
+func (s *MemoryStore) Put(ctx context.Context, trace *jvspb.DecisionTrace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Traces are put in order, so the expired ones are at the front.
+	now := time.Now()
+	for len(s.order) > 0 {
+		if len(s.order) < memoryStoreCapacity && !expired(s.traces[s.order[0]], s.retention, now) {
+			break
+		}
+		delete(s.traces, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	if _, ok := s.traces[trace.GetRequestId()]; !ok {
+		s.order = append(s.order, trace.GetRequestId())
+	}
+	s.traces[trace.GetRequestId()] = trace
+	return nil
+}
+
+// Get implements [Store].
+func (s *MemoryStore) Get(ctx context.Context, requestID string) (*jvspb.DecisionTrace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trace, ok := s.traces[requestID]
+	if !ok || expired(trace, s.retention, time.Now()) {
+		return nil, ErrNotFound
+	}
+	return trace, nil
+}
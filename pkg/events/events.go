@@ -0,0 +1,150 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events publishes token issuance and key rotation events as
+// CloudEvents, so they can be ingested downstream, e.g. by a SIEM.
+package events
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+)
+
+// Event types.
+const (
+	TypeTokenIssued  = "dev.abcxyz.jvs.token.issued"
+	TypeTokenDenied  = "dev.abcxyz.jvs.token.denied"
+	TypeKeyRotated   = "dev.abcxyz.jvs.key.rotated"
+	TypeKeyDisabled  = "dev.abcxyz.jvs.key.disabled"
+	TypeKeyDestroyed = "dev.abcxyz.jvs.key.destroyed"
+
+	TypePrincipalDeprovisioned = "dev.abcxyz.jvs.principal.deprovisioned"
+)
+
+// Event sources.
+const (
+	SourceAPI      = "//jvs.abcxyz.dev/api"
+	SourceRotation = "//jvs.abcxyz.dev/rotation"
+)
+
+// specVersion is the CloudEvents spec version of events.
+const specVersion = "1.0"
+
+// Event is a CloudEvent. Data is encoded as JSON.
+type Event struct {
+	ID      string
+	Type    string
+	Subject string
+	Time    time.Time
+	Data    any
+}
+
+// New creates an event with a random ID at the current time.
+func New(typ, subject string, data any) *Event {
+	return &Event{
+		ID:      uuid.New().String(),
+		Type:    typ,
+		Subject: subject,
+		Time:    time.Now().UTC(),
+		Data:    data,
+	}
+}
+
+// TokenDenied is the data of [TypeTokenDenied] events.
+type TokenDenied struct {
+	Requestor  string   `json:"requestor"`
+	Categories []string `json:"categories"`
+	Reason     string   `json:"reason"`
+}
+
+// PrincipalDeprovisioned is the data of [TypePrincipalDeprovisioned] events.
+type PrincipalDeprovisioned struct {
+	Principal string `json:"principal"`
+	Admin     string `json:"admin"`
+	Reason    string `json:"reason"`
+}
+
+// KeyVersion is the data of key events.
+type KeyVersion struct {
+	Key     string `json:"key"`
+	Version string `json:"version"`
+}
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+var _ Publisher = (*PubSubPublisher)(nil)
+
+// PubSubPublisher publishes events to a Pub/Sub topic, in the binary content
+// mode of the CloudEvents Pub/Sub protocol binding: the event data is the
+// message data, and the other attributes are "ce-" message attributes.
+type PubSubPublisher struct {
+	service *pubsub.Service
+	topic   string
+	source  string
+}
+
+// NewPubSubPublisher creates a publisher to the topic, in the format
+// "projects/*/topics/*", with the Pub/Sub API client options. source is the
+// CloudEvents source of the events.
+func NewPubSubPublisher(ctx context.Context, topic, source string, opts ...option.ClientOption) (*PubSubPublisher, error) {
+	service, err := pubsub.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
+	}
+	return &PubSubPublisher{
+		service: service,
+		topic:   topic,
+		source:  source,
+	}, nil
+}
+
+// Publish implements [Publisher].
+func (p *PubSubPublisher) Publish(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	attrs := map[string]string{
+		"ce-specversion": specVersion,
+		"ce-id":          event.ID,
+		"ce-type":        event.Type,
+		"ce-source":      p.source,
+		"ce-time":        event.Time.UTC().Format(time.RFC3339Nano),
+		"content-type":   "application/json",
+	}
+	if event.Subject != "" {
+		attrs["ce-subject"] = event.Subject
+	}
+
+	if _, err := p.service.Projects.Topics.Publish(p.topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Attributes: attrs,
+			Data:       base64.StdEncoding.EncodeToString(data),
+		}},
+	}).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", event.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPubSubPublisher_Publish(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	event := &Event{
+		ID:      "event-id",
+		Type:    TypeKeyDisabled,
+		Subject: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		Time:    time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+		Data: &KeyVersion{
+			Key:     "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+			Version: "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+		},
+	}
+
+	cases := []struct {
+		name      string
+		status    int
+		wantAttrs map[string]string
+		wantData  string
+		wantErr   string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			wantAttrs: map[string]string{
+				"ce-specversion": "1.0",
+				"ce-id":          "event-id",
+				"ce-type":        "dev.abcxyz.jvs.key.disabled",
+				"ce-source":      SourceRotation,
+				"ce-subject":     "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+				"ce-time":        "2023-06-01T12:00:00Z",
+				"content-type":   "application/json",
+			},
+			wantData: `{"key":"projects/p/locations/global/keyRings/r/cryptoKeys/k",` +
+				`"version":"projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1"}`,
+		},
+		{
+			name:    "server_error",
+			status:  http.StatusForbidden,
+			wantErr: "failed to publish dev.abcxyz.jvs.key.disabled event",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got pubsub.PublishRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.URL.Path, "/v1/projects/p/topics/jvs-events:publish"; got != want {
+					t.Errorf("expected path %q to be %q", got, want)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tc.status)
+				if tc.status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"messageIds":["1"]}`))
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			publisher, err := NewPubSubPublisher(ctx, "projects/p/topics/jvs-events", SourceRotation,
+				option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = publisher.Publish(ctx, event)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			if got, want := len(got.Messages), 1; got != want {
+				t.Fatalf("expected %d messages to be %d", got, want)
+			}
+			if diff := cmp.Diff(tc.wantAttrs, got.Messages[0].Attributes); diff != "" {
+				t.Errorf("attributes (-want, +got):\n%s", diff)
+			}
+			data, err := base64.StdEncoding.DecodeString(got.Messages[0].Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(data), tc.wantData; got != want {
+				t.Errorf("expected data %s to be %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cloudidentity "google.golang.org/api/cloudidentity/v1"
+	"google.golang.org/api/option"
+)
+
+var _ Resolver = (*CloudIdentity)(nil)
+
+// CloudIdentity is a [Resolver] backed by the Cloud Identity Groups API. It
+// resolves transitive memberships, and returns the emails of the groups. The
+// caller needs to be able to read the groups, e.g. through the "Groups Reader"
+// admin role.
+type CloudIdentity struct {
+	service *cloudidentity.Service
+}
+
+// NewCloudIdentity creates a new resolver with the Cloud Identity client
+// options.
+func NewCloudIdentity(ctx context.Context, opts ...option.ClientOption) (*CloudIdentity, error) {
+	opts = append([]option.ClientOption{
+		option.WithScopes(cloudidentity.CloudIdentityGroupsReadonlyScope),
+	}, opts...)
+	service, err := cloudidentity.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud identity client: %w", err)
+	}
+	return &CloudIdentity{service: service}, nil
+}
+
+// Groups implements [Resolver].
+func (c *CloudIdentity) Groups(ctx context.Context, email string) ([]string, error) {
+	var groups []string
+	if err := c.service.Groups.Memberships.SearchTransitiveGroups("groups/-").
+		Query(transitiveGroupsQuery(email)).
+		Fields("nextPageToken", "memberships/groupKey/id").
+		Pages(ctx, func(page *cloudidentity.SearchTransitiveGroupsResponse) error {
+			for _, m := range page.Memberships {
+				if m.GroupKey != nil {
+					groups = append(groups, m.GroupKey.Id)
+				}
+			}
+			return nil
+		}); err != nil {
+		return nil, fmt.Errorf("failed to search transitive groups: %w", err)
+	}
+	return groups, nil
+}
+
+// transitiveGroupsQuery returns the query of the Google Groups the user is a
+// member of, directly or through other groups.
+func transitiveGroupsQuery(email string) string {
+	email = strings.ReplaceAll(email, `\`, `\\`)
+	email = strings.ReplaceAll(email, `'`, `\'`)
+	return fmt.Sprintf("member_key_id == '%s' && 'cloudidentity.googleapis.com/groups.discussion_forum' in labels", email)
+}
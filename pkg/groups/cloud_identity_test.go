@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCloudIdentity_Groups(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		status  int
+		pages   map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "paginated",
+			status: http.StatusOK,
+			pages: map[string]string{
+				"":   `{"memberships": [{"groupKey": {"id": "a@example.com"}}], "nextPageToken": "p2"}`,
+				"p2": `{"memberships": [{"groupKey": {"id": "b@example.com"}}, {}]}`,
+			},
+			want: []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name:   "no_groups",
+			status: http.StatusOK,
+			pages:  map[string]string{"": `{}`},
+		},
+		{
+			name:    "server_error",
+			status:  http.StatusForbidden,
+			pages:   map[string]string{"": `{}`},
+			wantErr: "failed to search transitive groups",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.URL.Path, "/v1/groups/-/memberships:searchTransitiveGroups"; got != want {
+					t.Errorf("expected path %q to be %q", got, want)
+				}
+				if got, want := r.URL.Query().Get("query"), transitiveGroupsQuery("me@example.com"); got != want {
+					t.Errorf("expected query %q to be %q", got, want)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.pages[r.URL.Query().Get("pageToken")]))
+			}))
+			t.Cleanup(srv.Close)
+
+			ci, err := NewCloudIdentity(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ci.Groups(ctx, "me@example.com")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("groups: diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTransitiveGroupsQuery(t *testing.T) {
+	t.Parallel()
+
+	got := transitiveGroupsQuery(`o'brien\@example.com`)
+	want := `member_key_id == 'o\'brien\\@example.com' && 'cloudidentity.googleapis.com/groups.discussion_forum' in labels`
+	if got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package groups resolves the group memberships of requestors, which the JVS
+// embeds in tokens so downstream services can authorize on them without extra
+// lookups.
+package groups
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/cache"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Resolver resolves the groups a user is a member of.
+type Resolver interface {
+	// Groups returns the groups the user with the email is a member of. Users
+	// who don't exist are members of no groups.
+	Groups(ctx context.Context, email string) ([]string, error)
+}
+
+// New creates the resolver of the configured source. Its results are filtered,
+// bounded and cached according to the config.
+func New(ctx context.Context, cfg *config.GroupsConfig) (Resolver, error) {
+	var source Resolver
+	switch cfg.Source {
+	case config.GroupsSourceCloudIdentity:
+		ci, err := NewCloudIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		source = ci
+	case config.GroupsSourceLDAP:
+		source = NewLDAP(cfg)
+	default:
+		return nil, fmt.Errorf("unknown groups source %q", cfg.Source)
+	}
+
+	filter, err := cfg.FilterRegexp()
+	if err != nil {
+		return nil, err
+	}
+	return newBounded(source, filter, cfg.MaxGroups, cfg.Timeout, cfg.CacheTTL), nil
+}
+
+// bounded is a [Resolver] that filters, sorts and truncates the groups of
+// another one, and caches them.
+type bounded struct {
+	source  Resolver
+	filter  *regexp.Regexp
+	max     int
+	timeout time.Duration
+
+	// cache is nil if caching is disabled.
+	cache *cache.Cache[[]string]
+}
+
+func newBounded(source Resolver, filter *regexp.Regexp, maxGroups int, timeout, cacheTTL time.Duration) *bounded {
+	b := &bounded{
+		source:  source,
+		filter:  filter,
+		max:     maxGroups,
+		timeout: timeout,
+	}
+	if cacheTTL > 0 {
+		b.cache = cache.New[[]string](cacheTTL)
+	}
+	return b
+}
+
+// Groups implements [Resolver].
+func (b *bounded) Groups(ctx context.Context, email string) ([]string, error) {
+	if b.cache != nil {
+		if groups, ok := b.cache.Lookup(email); ok {
+			return slices.Clone(groups), nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	all, err := b.source.Groups(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(all))
+	for _, g := range all {
+		if g == "" || (b.filter != nil && !b.filter.MatchString(g)) {
+			continue
+		}
+		groups = append(groups, g)
+	}
+	slices.Sort(groups)
+	groups = slices.Compact(groups)
+
+	if len(groups) > b.max {
+		logging.FromContext(ctx).WarnContext(ctx, "requestor groups truncated, set a groups filter",
+			"requestor", email,
+			"groups", len(groups),
+			"max_groups", b.max)
+		groups = groups[:b.max]
+	}
+
+	if b.cache != nil {
+		b.cache.Set(email, groups)
+	}
+	return slices.Clone(groups), nil
+}
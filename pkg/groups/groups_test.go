@@ -0,0 +1,141 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+type fakeResolver struct {
+	mu     sync.Mutex
+	calls  int
+	groups []string
+	err    error
+}
+
+func (r *fakeResolver) Groups(_ context.Context, _ string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	return r.groups, r.err
+}
+
+func TestBounded_Groups(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name    string
+		source  *fakeResolver
+		filter  *regexp.Regexp
+		max     int
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "sorted",
+			source: &fakeResolver{groups: []string{"b@example.com", "a@example.com", "b@example.com", ""}},
+			max:    10,
+			want:   []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name:   "filtered",
+			source: &fakeResolver{groups: []string{"jvs-admins@example.com", "all@example.com"}},
+			filter: regexp.MustCompile(`^jvs-`),
+			max:    10,
+			want:   []string{"jvs-admins@example.com"},
+		},
+		{
+			name:   "truncated",
+			source: &fakeResolver{groups: []string{"c@example.com", "b@example.com", "a@example.com"}},
+			max:    2,
+			want:   []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name:   "no_groups",
+			source: &fakeResolver{},
+			max:    10,
+			want:   []string{},
+		},
+		{
+			name:    "source_error",
+			source:  &fakeResolver{err: fmt.Errorf("directory unavailable")},
+			max:     10,
+			wantErr: "directory unavailable",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newBounded(tc.source, tc.filter, tc.max, time.Second, 0)
+			got, err := r.Groups(ctx, "me@example.com")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("groups: diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBounded_cache(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	source := &fakeResolver{groups: []string{"a@example.com"}}
+	r := newBounded(source, nil, 10, time.Second, time.Minute)
+
+	for range 3 {
+		got, err := r.Groups(ctx, "me@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"a@example.com"}, got); diff != "" {
+			t.Errorf("groups: diff (-want, +got):\n%s", diff)
+		}
+		// Callers can't modify the cached groups.
+		got[0] = "modified"
+	}
+	if got, want := source.calls, 1; got != want {
+		t.Errorf("expected %d calls to be %d", got, want)
+	}
+
+	// Errors are not cached.
+	source.err = fmt.Errorf("directory unavailable")
+	if _, err := r.Groups(ctx, "you@example.com"); err == nil {
+		t.Error("expected an error")
+	}
+	if _, err := r.Groups(ctx, "you@example.com"); err == nil {
+		t.Error("expected an error")
+	}
+	if got, want := source.calls, 3; got != want {
+		t.Errorf("expected %d calls to be %d", got, want)
+	}
+}
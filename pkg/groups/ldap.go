@@ -0,0 +1,108 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/abcxyz/jvs/pkg/config"
+)
+
+var _ Resolver = (*LDAP)(nil)
+
+// LDAP is a [Resolver] backed by an LDAP directory. It finds the user's entry
+// with the user filter, then the groups whose group filter matches the DN of
+// that entry, and returns their group attribute.
+type LDAP struct {
+	cfg *config.GroupsConfig
+}
+
+// NewLDAP creates a new resolver with the LDAP options of the config.
+func NewLDAP(cfg *config.GroupsConfig) *LDAP {
+	return &LDAP{cfg: cfg}
+}
+
+// Groups implements [Resolver]. It connects to the server on every call, so
+// it is unaffected by the server closing idle connections.
+func (l *LDAP) Groups(ctx context.Context, email string) ([]string, error) {
+	// The client doesn't take contexts, so the deadline is applied to the
+	// connection instead.
+	timeout := 30 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	conn, err := ldap.DialURL(l.cfg.LDAPURL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap server: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+
+	if l.cfg.LDAPBindDN != "" {
+		if err := conn.Bind(l.cfg.LDAPBindDN, l.cfg.LDAPBindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind to ldap server: %w", err)
+		}
+	}
+
+	users, err := conn.Search(ldap.NewSearchRequest(
+		l.cfg.LDAPBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, timeLimit(timeout), false,
+		ldapFilter(l.cfg.LDAPUserFilter, "{email}", email),
+		[]string{"dn"}, nil))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search ldap user: %w", err)
+	}
+	switch len(users.Entries) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("ldap user filter matched more than one entry for %q", email)
+	}
+
+	found, err := conn.Search(ldap.NewSearchRequest(
+		l.cfg.LDAPBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, timeLimit(timeout), false,
+		ldapFilter(l.cfg.LDAPGroupFilter, "{dn}", users.Entries[0].DN),
+		[]string{l.cfg.LDAPGroupAttribute}, nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ldap groups: %w", err)
+	}
+
+	groups := make([]string, 0, len(found.Entries))
+	for _, entry := range found.Entries {
+		if v := entry.GetAttributeValue(l.cfg.LDAPGroupAttribute); v != "" {
+			groups = append(groups, v)
+		}
+	}
+	return groups, nil
+}
+
+// ldapFilter replaces the placeholder in the filter with the escaped value.
+func ldapFilter(filter, placeholder, value string) string {
+	return strings.ReplaceAll(filter, placeholder, ldap.EscapeFilter(value))
+}
+
+// timeLimit returns the server-side time limit of searches, in seconds.
+func timeLimit(timeout time.Duration) int {
+	return max(1, int(timeout.Seconds()))
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package groups
+
+import (
+	"testing"
+)
+
+func TestLDAPFilter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		filter      string
+		placeholder string
+		value       string
+		want        string
+	}{
+		{
+			name:        "email",
+			filter:      "(mail={email})",
+			placeholder: "{email}",
+			value:       "me@example.com",
+			want:        "(mail=me@example.com)",
+		},
+		{
+			name:        "escaped",
+			filter:      "(&(objectClass=person)(mail={email}))",
+			placeholder: "{email}",
+			value:       "*)(uid=*",
+			want:        `(&(objectClass=person)(mail=\2a\29\28uid=\2a))`,
+		},
+		{
+			name:        "dn",
+			filter:      "(|(member={dn})(uniqueMember={dn}))",
+			placeholder: "{dn}",
+			value:       "uid=me,ou=people,dc=example,dc=com",
+			want:        "(|(member=uid=me,ou=people,dc=example,dc=com)(uniqueMember=uid=me,ou=people,dc=example,dc=com))",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := ldapFilter(tc.filter, tc.placeholder, tc.value), tc.want; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcserver builds the gRPC servers of the JVS binaries, so they all
+// share the same interceptor chain.
+package grpcserver
+
+import (
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Chain is the interceptor chain of a gRPC server. Interceptors run in this
+// order:
+//
+//  1. Logging, which adds the request logger to the context.
+//  2. Recovery, which turns panics in the rest of the chain into INTERNAL
+//     errors.
+//  3. Auth.
+//  4. Rate limiting.
+//  5. Other interceptors, e.g. audit logging, in the order they were added.
+//
+// Requests are also traced with OpenTelemetry, around the whole chain.
+type Chain struct {
+	logger    *slog.Logger
+	projectID string
+
+	auth      []grpc.UnaryServerInterceptor
+	rateLimit []grpc.UnaryServerInterceptor
+	others    []grpc.UnaryServerInterceptor
+}
+
+// NewChain creates a chain that logs with the logger. projectID is the Google
+// Cloud project logs are correlated with traces in, and may be empty.
+func NewChain(logger *slog.Logger, projectID string) *Chain {
+	return &Chain{
+		logger:    logger,
+		projectID: projectID,
+	}
+}
+
+// WithAuth adds interceptors that authenticate requests.
+func (c *Chain) WithAuth(interceptors ...grpc.UnaryServerInterceptor) *Chain {
+	c.auth = append(c.auth, interceptors...)
+	return c
+}
+
+// WithRateLimit adds interceptors that limit requests, after authentication.
+func (c *Chain) WithRateLimit(interceptors ...grpc.UnaryServerInterceptor) *Chain {
+	c.rateLimit = append(c.rateLimit, interceptors...)
+	return c
+}
+
+// WithInterceptors adds interceptors that run after rate limiting, right before
+// the handler.
+func (c *Chain) WithInterceptors(interceptors ...grpc.UnaryServerInterceptor) *Chain {
+	c.others = append(c.others, interceptors...)
+	return c
+}
+
+// UnaryInterceptors returns the unary interceptors of the chain, in order.
+func (c *Chain) UnaryInterceptors() []grpc.UnaryServerInterceptor {
+	interceptors := make([]grpc.UnaryServerInterceptor, 0, 2+len(c.auth)+len(c.rateLimit)+len(c.others))
+	interceptors = append(interceptors,
+		logging.GRPCUnaryInterceptor(c.logger, c.projectID),
+		UnaryRecoveryInterceptor)
+	interceptors = append(interceptors, c.auth...)
+	interceptors = append(interceptors, c.rateLimit...)
+	interceptors = append(interceptors, c.others...)
+	return interceptors
+}
+
+// ServerOptions returns the server options that install the chain.
+func (c *Chain) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(c.UnaryInterceptors()...),
+		grpc.ChainStreamInterceptor(StreamRecoveryInterceptor),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+	}
+}
+
+// NewServer creates a gRPC server with the chain and the extra options.
+func (c *Chain) NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	return grpc.NewServer(append(c.ServerOptions(), opts...)...)
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	// recorder records the order interceptors run in.
+	type recorder struct {
+		mu    sync.Mutex
+		order []string
+	}
+	record := func(r *recorder, name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			r.mu.Lock()
+			r.order = append(r.order, name)
+			r.mu.Unlock()
+			return handler(ctx, req)
+		}
+	}
+	panics := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		panic("boom")
+	}
+
+	cases := []struct {
+		name      string
+		chain     func(r *recorder) *Chain
+		wantOrder []string
+		wantCode  codes.Code
+	}{
+		{
+			name: "ordered",
+			chain: func(r *recorder) *Chain {
+				return NewChain(logging.TestLogger(t), "").
+					WithInterceptors(record(r, "audit")).
+					WithRateLimit(record(r, "limit")).
+					WithAuth(record(r, "auth"))
+			},
+			wantOrder: []string{"auth", "limit", "audit"},
+			wantCode:  codes.OK,
+		},
+		{
+			name: "recovers",
+			chain: func(r *recorder) *Chain {
+				return NewChain(logging.TestLogger(t), "").
+					WithAuth(record(r, "auth")).
+					WithInterceptors(panics)
+			},
+			wantOrder: []string{"auth"},
+			wantCode:  codes.Internal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &recorder{}
+			lis := bufconn.Listen(1 << 20)
+			s := tc.chain(r).NewServer()
+			grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+			go func() {
+				if err := s.Serve(lis); err != nil {
+					t.Error(err)
+				}
+			}()
+			t.Cleanup(s.Stop)
+
+			conn, err := grpc.NewClient("passthrough:///bufnet",
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return lis.DialContext(ctx)
+				}),
+				grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+
+			_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if diff := cmp.Diff(tc.wantOrder, r.order); diff != "" {
+				t.Errorf("order (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnaryRecoveryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	info := &grpc.UnaryServerInfo{FullMethod: "/abcxyz.jvs.JVSService/CreateJustification"}
+
+	cases := []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		wantResp any
+		wantCode codes.Code
+	}{
+		{
+			name: "success",
+			handler: func(ctx context.Context, req any) (any, error) {
+				return "ok", nil
+			},
+			wantResp: "ok",
+			wantCode: codes.OK,
+		},
+		{
+			name: "error",
+			handler: func(ctx context.Context, req any) (any, error) {
+				return nil, status.Error(codes.InvalidArgument, "bad request")
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "panic",
+			handler: func(ctx context.Context, req any) (any, error) {
+				var m map[string]string
+				m["boom"] = "boom"
+				return "unreachable", nil
+			},
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := UnaryRecoveryInterceptor(ctx, nil, info, tc.handler)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+			if got, want := resp, tc.wantResp; got != want {
+				t.Errorf("expected response %v to be %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcserver
+
+import (
+	"context"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// UnaryRecoveryInterceptor is a [grpc.UnaryServerInterceptor] that recovers
+// from panics in the handler, logs them with their stack trace, and returns an
+// INTERNAL error instead of crashing the server.
+func UnaryRecoveryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// StreamRecoveryInterceptor is the [grpc.StreamServerInterceptor] equivalent
+// of [UnaryRecoveryInterceptor].
+func StreamRecoveryInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ss.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// recovered logs the recovered panic and returns the error for the caller. The
+// panic value isn't returned, since it may contain internal details.
+func recovered(ctx context.Context, method string, r any) error {
+	logging.FromContext(ctx).ErrorContext(ctx, "recovered from panic",
+		"method", method,
+		"panic", r,
+		"stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,32 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hsm manages signing keys in an HSM, such as SoftHSM, Luna or
+// YubiHSM, through its PKCS#11 module.
+//
+// Each version of a key is an ECDSA P-256 key pair labeled with the key name,
+// whose ID is the version ID, the unix time the version was created at. The
+// version name is "[KEY]/versions/[ID]". A version is disabled by clearing the
+// CKA_SIGN attribute of its private key, and destroyed by deleting the key
+// pair. The primary version of a key is stored in a data object with the key
+// label.
+package hsm
+
+import (
+	"errors"
+)
+
+// ErrUnsupported is returned when the binary is built without cgo, which the
+// PKCS#11 modules must be loaded with.
+var ErrUnsupported = errors.New("pkcs11 is not supported in builds without cgo")
@@ -0,0 +1,631 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+
+package hsm
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	p11 "github.com/miekg/pkcs11"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// primaryApplication is the CKA_APPLICATION of the data objects that store
+	// the primary version of keys.
+	primaryApplication = "jvs-primary"
+
+	// versionsSeparator separates the key label and the version ID in version
+	// names.
+	versionsSeparator = "/versions/"
+)
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+)
+
+// module is the part of the PKCS#11 API the key manager uses, implemented by
+// [p11.Ctx].
+type module interface {
+	FindObjectsInit(sh p11.SessionHandle, temp []*p11.Attribute) error
+	FindObjects(sh p11.SessionHandle, max int) ([]p11.ObjectHandle, bool, error)
+	FindObjectsFinal(sh p11.SessionHandle) error
+	GetAttributeValue(sh p11.SessionHandle, o p11.ObjectHandle, a []*p11.Attribute) ([]*p11.Attribute, error)
+	SetAttributeValue(sh p11.SessionHandle, o p11.ObjectHandle, a []*p11.Attribute) error
+	CreateObject(sh p11.SessionHandle, temp []*p11.Attribute) (p11.ObjectHandle, error)
+	DestroyObject(sh p11.SessionHandle, oh p11.ObjectHandle) error
+	GenerateKeyPair(sh p11.SessionHandle, m []*p11.Mechanism, public, private []*p11.Attribute) (p11.ObjectHandle, p11.ObjectHandle, error)
+	SignInit(sh p11.SessionHandle, m []*p11.Mechanism, o p11.ObjectHandle) error
+	Sign(sh p11.SessionHandle, message []byte) ([]byte, error)
+}
+
+// KeyManager manages the versions of keys in an HSM. It implements
+// [jvscrypto.KeyManager]. Key names are the labels of the keys.
+type KeyManager struct {
+	mod     module
+	session p11.SessionHandle
+	close   func() error
+
+	// mu guards the session, since PKCS#11 sessions can't run operations
+	// concurrently.
+	mu sync.Mutex
+
+	// now returns the current time, which new version IDs are derived from.
+	now func() time.Time
+}
+
+var _ jvscrypto.KeyManager = (*KeyManager)(nil)
+
+// Open loads the PKCS#11 module, and logs in to the token in the configured
+// slot, or the slot of the token with the configured label. The key manager
+// must be closed to log out and unload the module.
+func Open(ctx context.Context, cfg *config.PKCS11Config) (*KeyManager, error) {
+	c := p11.New(cfg.Module)
+	if c == nil {
+		return nil, fmt.Errorf("failed to load pkcs11 module %s", cfg.Module)
+	}
+	if err := c.Initialize(); err != nil {
+		c.Destroy()
+		return nil, fmt.Errorf("failed to initialize pkcs11 module: %w", err)
+	}
+
+	finalize := func() error {
+		defer c.Destroy()
+		if err := c.Finalize(); err != nil {
+			return fmt.Errorf("failed to finalize pkcs11 module: %w", err)
+		}
+		return nil
+	}
+
+	slot, err := findSlot(c, cfg)
+	if err != nil {
+		return nil, errors.Join(err, finalize())
+	}
+
+	session, err := c.OpenSession(slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to open session on slot %d: %w", slot, err), finalize())
+	}
+	if err := c.Login(session, p11.CKU_USER, cfg.PIN); err != nil && !errors.Is(err, p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN)) {
+		return nil, errors.Join(fmt.Errorf("failed to log in to slot %d: %w", slot, err),
+			c.CloseSession(session), finalize())
+	}
+	logging.FromContext(ctx).InfoContext(ctx, "opened pkcs11 session", "slot", slot)
+
+	m := newKeyManager(c, session)
+	m.close = func() error {
+		// Logging out fails if another session of the process is still logged
+		// in, which is fine, since finalizing closes every session.
+		_ = c.Logout(session)
+		return errors.Join(c.CloseSession(session), finalize())
+	}
+	return m, nil
+}
+
+func newKeyManager(mod module, session p11.SessionHandle) *KeyManager {
+	return &KeyManager{
+		mod:     mod,
+		session: session,
+		now:     time.Now,
+	}
+}
+
+// findSlot returns the configured slot, or the slot of the token with the
+// configured label.
+func findSlot(c *p11.Ctx, cfg *config.PKCS11Config) (uint, error) {
+	if cfg.Slot >= 0 {
+		return uint(cfg.Slot), nil
+	}
+
+	slots, err := c.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := c.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get token info of slot %d: %w", slot, err)
+		}
+		if info.Label == cfg.TokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("no token with label %q found", cfg.TokenLabel)
+}
+
+// Close logs out of the token, and unloads the module.
+func (m *KeyManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.close == nil {
+		return nil
+	}
+	return m.close()
+}
+
+// ListVersions returns the versions of the key, oldest first.
+func (m *KeyManager) ListVersions(ctx context.Context, key string) ([]*kmspb.CryptoKeyVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	objs, err := m.findObjects([]*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_LABEL, key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vers := make([]*kmspb.CryptoKeyVersion, 0, len(objs))
+	for _, obj := range objs {
+		ver, err := m.version(key, obj)
+		if err != nil {
+			return nil, err
+		}
+		vers = append(vers, ver)
+	}
+	sort.Slice(vers, func(i, j int) bool {
+		return vers[i].GetCreateTime().AsTime().Before(vers[j].GetCreateTime().AsTime())
+	})
+	return vers, nil
+}
+
+// GetVersion returns the version with the name.
+func (m *KeyManager) GetVersion(ctx context.Context, version string) (*kmspb.CryptoKeyVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, _, err := parseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := m.findVersion(version, p11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return nil, err
+	}
+	return m.version(key, obj)
+}
+
+// GetPrimary returns the name of the primary version of the key, or "" if it
+// has none.
+func (m *KeyManager) GetPrimary(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	obj, ok, err := m.findPrimary(key)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", nil
+	}
+
+	attrs, err := m.mod.GetAttributeValue(m.session, obj, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_VALUE, nil),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read primary of key %s: %w", key, err)
+	}
+	return key + versionsSeparator + string(attrs[0].Value), nil
+}
+
+// SetPrimary marks the version as the primary version of the key.
+func (m *KeyManager) SetPrimary(ctx context.Context, key, version string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	verKey, id, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+	if verKey != key {
+		return fmt.Errorf("version %s is not a version of key %s", version, key)
+	}
+
+	obj, ok, err := m.findPrimary(key)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := m.mod.SetAttributeValue(m.session, obj, []*p11.Attribute{
+			p11.NewAttribute(p11.CKA_VALUE, id),
+		}); err != nil {
+			return fmt.Errorf("failed to set primary of key %s: %w", key, err)
+		}
+		return nil
+	}
+
+	if _, err := m.mod.CreateObject(m.session, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_DATA),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_PRIVATE, false),
+		p11.NewAttribute(p11.CKA_MODIFIABLE, true),
+		p11.NewAttribute(p11.CKA_LABEL, key),
+		p11.NewAttribute(p11.CKA_APPLICATION, primaryApplication),
+		p11.NewAttribute(p11.CKA_VALUE, id),
+	}); err != nil {
+		return fmt.Errorf("failed to create primary of key %s: %w", key, err)
+	}
+	return nil
+}
+
+// CreateVersion generates a new ECDSA P-256 key pair for the key in the HSM.
+// The private key can't be extracted.
+func (m *KeyManager) CreateVersion(ctx context.Context, key string) (*kmspb.CryptoKeyVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "creating new key version")
+
+	now := m.now().UTC()
+	id := strconv.FormatInt(now.Unix(), 10)
+	version := key + versionsSeparator + id
+
+	// Version IDs have a precision of a second, so refuse to create a second
+	// version in the same second rather than having two with the same ID.
+	existing, err := m.findObjects(versionTemplate(key, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("version %s already exists", version)
+	}
+
+	params, err := asn1.Marshal(oidNamedCurveP256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal curve: %w", err)
+	}
+	if _, _, err := m.mod.GenerateKeyPair(m.session,
+		[]*p11.Mechanism{p11.NewMechanism(p11.CKM_EC_KEY_PAIR_GEN, nil)},
+		[]*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PUBLIC_KEY),
+			p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+			p11.NewAttribute(p11.CKA_TOKEN, true),
+			p11.NewAttribute(p11.CKA_VERIFY, true),
+			p11.NewAttribute(p11.CKA_EC_PARAMS, params),
+			p11.NewAttribute(p11.CKA_LABEL, key),
+			p11.NewAttribute(p11.CKA_ID, id),
+		},
+		[]*p11.Attribute{
+			p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+			p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+			p11.NewAttribute(p11.CKA_TOKEN, true),
+			p11.NewAttribute(p11.CKA_PRIVATE, true),
+			p11.NewAttribute(p11.CKA_SENSITIVE, true),
+			p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+			p11.NewAttribute(p11.CKA_SIGN, true),
+			p11.NewAttribute(p11.CKA_LABEL, key),
+			p11.NewAttribute(p11.CKA_ID, id),
+		},
+	); err != nil {
+		return nil, fmt.Errorf("key creation failed: %w", err)
+	}
+
+	return &kmspb.CryptoKeyVersion{
+		Name:       version,
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		CreateTime: timestamppb.New(time.Unix(now.Unix(), 0)),
+	}, nil
+}
+
+// DisableVersion clears the CKA_SIGN attribute of the private key of the
+// version, so the HSM refuses to sign with it.
+func (m *KeyManager) DisableVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "disabling key version", "version_name", ver.GetName())
+
+	obj, err := m.findVersion(ver.GetName(), p11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return err
+	}
+	if err := m.mod.SetAttributeValue(m.session, obj, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_SIGN, false),
+	}); err != nil {
+		return fmt.Errorf("key disable failed: %w", err)
+	}
+	return nil
+}
+
+// DestroyVersion deletes the key pair of the version.
+func (m *KeyManager) DestroyVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "destroying key version", "version_name", ver.GetName())
+
+	key, id, err := parseVersion(ver.GetName())
+	if err != nil {
+		return err
+	}
+	objs, err := m.findObjects(versionTemplate(key, id))
+	if err != nil {
+		return err
+	}
+	if len(objs) == 0 {
+		return fmt.Errorf("version %s not found", ver.GetName())
+	}
+	for _, obj := range objs {
+		if err := m.mod.DestroyObject(m.session, obj); err != nil {
+			return fmt.Errorf("key destroy failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// PublicKey returns the public key of the version.
+func (m *KeyManager) PublicKey(ctx context.Context, version string) (crypto.PublicKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.publicKey(version)
+}
+
+// Signer returns a signer that signs with the private key of the version in
+// the HSM.
+func (m *KeyManager) Signer(ctx context.Context, version string) (crypto.Signer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pub, err := m.publicKey(version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	obj, err := m.findVersion(version, p11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	return &signer{
+		m:   m,
+		obj: obj,
+		pub: pub,
+	}, nil
+}
+
+// signer signs digests with a private key in the HSM.
+type signer struct {
+	m   *KeyManager
+	obj p11.ObjectHandle
+	pub *ecdsa.PublicKey
+}
+
+// Public returns the public key of the signer.
+func (s *signer) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign signs the digest with CKM_ECDSA, and returns the ASN.1 DER encoded
+// signature, like [ecdsa.PrivateKey.Sign].
+func (s *signer) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	mech := []*p11.Mechanism{p11.NewMechanism(p11.CKM_ECDSA, nil)}
+	if err := s.m.mod.SignInit(s.m.session, mech, s.obj); err != nil {
+		return nil, fmt.Errorf("failed to initialize signing: %w", err)
+	}
+	sig, err := s.m.mod.Sign(s.m.session, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	// PKCS#11 returns the concatenated r and s.
+	if len(sig) == 0 || len(sig)%2 != 0 {
+		return nil, fmt.Errorf("unexpected signature length %d", len(sig))
+	}
+	half := len(sig) / 2
+	der, err := asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		R: new(big.Int).SetBytes(sig[:half]),
+		S: new(big.Int).SetBytes(sig[half:]),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signature: %w", err)
+	}
+	return der, nil
+}
+
+// version reads the version of the key from its private key. The caller must
+// hold the lock.
+func (m *KeyManager) version(key string, obj p11.ObjectHandle) (*kmspb.CryptoKeyVersion, error) {
+	attrs, err := m.mod.GetAttributeValue(m.session, obj, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_ID, nil),
+		p11.NewAttribute(p11.CKA_SIGN, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key attributes: %w", err)
+	}
+
+	id := string(attrs[0].Value)
+	created, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("key %s has an unexpected id %q", key, id)
+	}
+	state := kmspb.CryptoKeyVersion_DISABLED
+	if len(attrs[1].Value) > 0 && attrs[1].Value[0] != 0 {
+		state = kmspb.CryptoKeyVersion_ENABLED
+	}
+
+	return &kmspb.CryptoKeyVersion{
+		Name:       key + versionsSeparator + id,
+		State:      state,
+		CreateTime: timestamppb.New(time.Unix(created, 0)),
+	}, nil
+}
+
+// publicKey reads the public key of the version. The caller must hold the
+// lock.
+func (m *KeyManager) publicKey(version string) (*ecdsa.PublicKey, error) {
+	obj, err := m.findVersion(version, p11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := m.mod.GetAttributeValue(m.session, obj, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key of %s: %w", version, err)
+	}
+
+	pub, err := parseECPoint(attrs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of %s: %w", version, err)
+	}
+	return pub, nil
+}
+
+// findVersion returns the object of the class for the version. The caller must
+// hold the lock.
+func (m *KeyManager) findVersion(version string, class uint) (p11.ObjectHandle, error) {
+	key, id, err := parseVersion(version)
+	if err != nil {
+		return 0, err
+	}
+	objs, err := m.findObjects(append(versionTemplate(key, id),
+		p11.NewAttribute(p11.CKA_CLASS, class)))
+	if err != nil {
+		return 0, err
+	}
+	if len(objs) == 0 {
+		return 0, fmt.Errorf("version %s not found", version)
+	}
+	return objs[0], nil
+}
+
+// findPrimary returns the data object with the primary version of the key, if
+// there is one. The caller must hold the lock.
+func (m *KeyManager) findPrimary(key string) (p11.ObjectHandle, bool, error) {
+	objs, err := m.findObjects([]*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_DATA),
+		p11.NewAttribute(p11.CKA_LABEL, key),
+		p11.NewAttribute(p11.CKA_APPLICATION, primaryApplication),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if len(objs) == 0 {
+		return 0, false, nil
+	}
+	return objs[0], true, nil
+}
+
+// findObjects returns all the objects that match the template. The caller must
+// hold the lock.
+func (m *KeyManager) findObjects(template []*p11.Attribute) (_ []p11.ObjectHandle, merr error) {
+	if err := m.mod.FindObjectsInit(m.session, template); err != nil {
+		return nil, fmt.Errorf("failed to find objects: %w", err)
+	}
+	defer func() {
+		if err := m.mod.FindObjectsFinal(m.session); err != nil {
+			merr = errors.Join(merr, fmt.Errorf("failed to finish finding objects: %w", err))
+		}
+	}()
+
+	var all []p11.ObjectHandle
+	for {
+		objs, _, err := m.mod.FindObjects(m.session, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find objects: %w", err)
+		}
+		if len(objs) == 0 {
+			return all, nil
+		}
+		all = append(all, objs...)
+	}
+}
+
+// versionTemplate matches the key pair of the version.
+func versionTemplate(key, id string) []*p11.Attribute {
+	return []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_LABEL, key),
+		p11.NewAttribute(p11.CKA_ID, id),
+	}
+}
+
+// parseVersion splits the version name into the key label and version ID.
+func parseVersion(version string) (string, string, error) {
+	i := strings.LastIndex(version, versionsSeparator)
+	if i <= 0 || i+len(versionsSeparator) == len(version) {
+		return "", "", fmt.Errorf("version %q must be in the format [KEY]%s[ID]", version, versionsSeparator)
+	}
+	return version[:i], version[i+len(versionsSeparator):], nil
+}
+
+// parseECPoint parses the CKA_EC_POINT of a P-256 public key. It is a DER
+// encoded octet string holding the uncompressed point, though some modules
+// return the point directly.
+func parseECPoint(b []byte) (*ecdsa.PublicKey, error) {
+	// An uncompressed P-256 point is 65 bytes, starting with 0x04.
+	point := b
+	var raw []byte
+	if rest, err := asn1.Unmarshal(b, &raw); err == nil && len(rest) == 0 && len(raw) == 65 {
+		point = raw
+	}
+
+	params, err := asn1.Marshal(oidNamedCurveP256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal curve: %w", err)
+	}
+	spki, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(spki)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key must be an ecdsa key, got %T", pub)
+	}
+	return ecPub, nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !cgo
+
+package hsm
+
+import (
+	"context"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+)
+
+// KeyManager manages the versions of keys in an HSM. It is not available in
+// builds without cgo.
+type KeyManager struct {
+	jvscrypto.KeyManager
+}
+
+// Open always returns [ErrUnsupported] in builds without cgo.
+func Open(ctx context.Context, cfg *config.PKCS11Config) (*KeyManager, error) {
+	return nil, ErrUnsupported
+}
+
+// Close does nothing.
+func (m *KeyManager) Close() error {
+	return nil
+}
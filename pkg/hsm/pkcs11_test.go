@@ -0,0 +1,502 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo
+
+package hsm
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	p11 "github.com/miekg/pkcs11"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestKeyManager(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	m := testKeyManager(t)
+	created := time.Unix(1_700_000_000, 0)
+	m.now = func() time.Time { return created }
+
+	ver, err := m.CreateVersion(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &kmspb.CryptoKeyVersion{
+		Name:       "jvs-signing/versions/1700000000",
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		CreateTime: timestamppb.New(created),
+	}
+	if diff := cmp.Diff(want, ver, protocmp.Transform()); diff != "" {
+		t.Errorf("version (-want, +got):\n%s", diff)
+	}
+
+	if _, err := m.CreateVersion(ctx, "jvs-signing"); err == nil {
+		t.Errorf("expected creating a second version in the same second to fail")
+	}
+
+	// Versions of other keys are not listed.
+	m.now = func() time.Time { return created.Add(time.Hour) }
+	if _, err := m.CreateVersion(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	primary, err := m.GetPrimary(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary != "" {
+		t.Errorf("expected no primary, got %q", primary)
+	}
+	if err := m.SetPrimary(ctx, "jvs-signing", ver.GetName()); err != nil {
+		t.Fatal(err)
+	}
+	if err := jvscrypto.CheckPrimaryVersion(ctx, m, "jvs-signing"); err != nil {
+		t.Errorf("expected the primary to be ready: %s", err)
+	}
+
+	signer, err := m.Signer(ctx, ver.GetName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := m.PublicKey(ctx, ver.GetName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], sig) {
+		t.Errorf("expected the signature to verify with the public key")
+	}
+
+	if err := m.DisableVersion(ctx, ver); err != nil {
+		t.Fatal(err)
+	}
+	vers, err := m.ListVersions(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.State = kmspb.CryptoKeyVersion_DISABLED
+	if diff := cmp.Diff([]*kmspb.CryptoKeyVersion{want}, vers, protocmp.Transform()); diff != "" {
+		t.Errorf("versions (-want, +got):\n%s", diff)
+	}
+	if _, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256); err == nil {
+		t.Errorf("expected signing with a disabled version to fail")
+	}
+	if diff := pkgtestutil.DiffErrString(jvscrypto.CheckPrimaryVersion(ctx, m, "jvs-signing"),
+		"primary version jvs-signing/versions/1700000000 is DISABLED"); diff != "" {
+		t.Error(diff)
+	}
+
+	if err := m.DestroyVersion(ctx, ver); err != nil {
+		t.Fatal(err)
+	}
+	vers, err = m.ListVersions(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(vers); got != 0 {
+		t.Errorf("expected no versions after destroying, got %d", got)
+	}
+	if _, err := m.PublicKey(ctx, ver.GetName()); err == nil {
+		t.Errorf("expected the public key to be destroyed")
+	}
+}
+
+func TestKeyManager_SetPrimary(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	m := testKeyManager(t)
+	first, err := m.CreateVersion(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.now = func() time.Time { return time.Now().Add(time.Minute) }
+	second, err := m.CreateVersion(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		key     string
+		version string
+		wantErr string
+	}{
+		{
+			name:    "first",
+			key:     "jvs-signing",
+			version: first.GetName(),
+		},
+		{
+			name:    "update",
+			key:     "jvs-signing",
+			version: second.GetName(),
+		},
+		{
+			name:    "other_key",
+			key:     "other",
+			version: second.GetName(),
+			wantErr: "is not a version of key other",
+		},
+		{
+			name:    "invalid_name",
+			key:     "jvs-signing",
+			version: "jvs-signing",
+			wantErr: "must be in the format [KEY]/versions/[ID]",
+		},
+	}
+
+	// The cases run in order, since they update the same primary.
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := m.SetPrimary(ctx, tc.key, tc.version)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			primary, err := m.GetPrimary(ctx, tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := primary, tc.version; got != want {
+				t.Errorf("expected primary %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Rotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	m := testKeyManager(t)
+	handler := jvscrypto.NewRotationHandler(ctx, nil, &config.CertRotationConfig{
+		KeyTTL:           time.Hour,
+		GracePeriod:      30 * time.Minute,
+		PropagationDelay: 10 * time.Minute,
+		DisabledPeriod:   time.Hour,
+	}).WithKeyManager(m)
+
+	// The first rotation creates a version and promotes it to primary. It is
+	// created in the past, so it has reached its rotation age.
+	m.now = func() time.Time { return time.Now().Add(-45 * time.Minute) }
+	if err := handler.RotateKey(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	vers, err := m.ListVersions(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vers), 1; got != want {
+		t.Fatalf("expected %d versions to be %d", got, want)
+	}
+	first := vers[0].GetName()
+	primary, err := m.GetPrimary(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := primary, first; got != want {
+		t.Errorf("expected primary %q to be %q", got, want)
+	}
+
+	// The second rotation creates a new version, without promoting it until
+	// the propagation delay has passed.
+	m.now = time.Now
+	if err := handler.RotateKey(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	if vers, err = m.ListVersions(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vers), 2; got != want {
+		t.Fatalf("expected %d versions to be %d", got, want)
+	}
+	if primary, err = m.GetPrimary(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := primary, first; got != want {
+		t.Errorf("expected primary %q to be %q", got, want)
+	}
+}
+
+func TestKeyManager_Tokens(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	m := testKeyManager(t)
+	ver, err := m.CreateVersion(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetPrimary(ctx, "jvs-signing", ver.GetName()); err != nil {
+		t.Fatal(err)
+	}
+
+	// No KMS client, signing and serving public keys must not call KMS.
+	processor := justification.NewProcessor(nil, &config.JustificationConfig{
+		Signer:             config.SignerPKCS11,
+		KeyName:            "jvs-signing",
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithKeyManager(m)
+	if err := processor.Ready(ctx); err != nil {
+		t.Fatalf("expected the processor to be ready: %s", err)
+	}
+
+	b, err := processor.CreateToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := jvscrypto.NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:       config.SignerPKCS11,
+		KeyNames:     []string{"jvs-signing"},
+		CacheTimeout: 5 * time.Minute,
+	}, h).WithKeyManager(m)
+	if err := keyServer.Ready(ctx); err != nil {
+		t.Fatalf("expected the key server to be ready: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	keyServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+	set, err := jwk.Parse(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Parse(b, jwt.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)))
+	if err != nil {
+		t.Fatalf("failed to verify token with the jwks: %s", err)
+	}
+	if got, want := token.Subject(), "me@example.com"; got != want {
+		t.Errorf("subject: expected %q to be %q", got, want)
+	}
+}
+
+// testKeyManager returns a key manager backed by an in-memory HSM.
+func testKeyManager(tb testing.TB) *KeyManager {
+	tb.Helper()
+
+	return newKeyManager(&fakeModule{
+		objects: make(map[p11.ObjectHandle]*fakeObject),
+	}, 1)
+}
+
+// fakeObject is an object in the fake HSM.
+type fakeObject struct {
+	attrs map[uint][]byte
+	key   *ecdsa.PrivateKey
+}
+
+// fakeModule is an in-memory PKCS#11 module. It supports the operations of the
+// key manager, but no access control.
+type fakeModule struct {
+	objects map[p11.ObjectHandle]*fakeObject
+	next    p11.ObjectHandle
+
+	found   []p11.ObjectHandle
+	signing *fakeObject
+}
+
+func (f *fakeModule) FindObjectsInit(_ p11.SessionHandle, temp []*p11.Attribute) error {
+	f.found = nil
+	for h := p11.ObjectHandle(1); h <= f.next; h++ {
+		obj, ok := f.objects[h]
+		if !ok {
+			continue
+		}
+		match := true
+		for _, a := range temp {
+			if !bytes.Equal(obj.attrs[a.Type], a.Value) {
+				match = false
+				break
+			}
+		}
+		if match {
+			f.found = append(f.found, h)
+		}
+	}
+	return nil
+}
+
+func (f *fakeModule) FindObjects(_ p11.SessionHandle, max int) ([]p11.ObjectHandle, bool, error) {
+	n := min(max, len(f.found))
+	objs := f.found[:n]
+	f.found = f.found[n:]
+	return objs, false, nil
+}
+
+func (f *fakeModule) FindObjectsFinal(_ p11.SessionHandle) error {
+	f.found = nil
+	return nil
+}
+
+func (f *fakeModule) GetAttributeValue(_ p11.SessionHandle, o p11.ObjectHandle, a []*p11.Attribute) ([]*p11.Attribute, error) {
+	obj, ok := f.objects[o]
+	if !ok {
+		return nil, p11.Error(p11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	attrs := make([]*p11.Attribute, 0, len(a))
+	for _, attr := range a {
+		v, ok := obj.attrs[attr.Type]
+		if !ok {
+			return nil, p11.Error(p11.CKR_ATTRIBUTE_TYPE_INVALID)
+		}
+		attrs = append(attrs, p11.NewAttribute(attr.Type, v))
+	}
+	return attrs, nil
+}
+
+func (f *fakeModule) SetAttributeValue(_ p11.SessionHandle, o p11.ObjectHandle, a []*p11.Attribute) error {
+	obj, ok := f.objects[o]
+	if !ok {
+		return p11.Error(p11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	for _, attr := range a {
+		obj.attrs[attr.Type] = attr.Value
+	}
+	return nil
+}
+
+func (f *fakeModule) CreateObject(_ p11.SessionHandle, temp []*p11.Attribute) (p11.ObjectHandle, error) {
+	return f.add(temp, nil), nil
+}
+
+func (f *fakeModule) DestroyObject(_ p11.SessionHandle, o p11.ObjectHandle) error {
+	if _, ok := f.objects[o]; !ok {
+		return p11.Error(p11.CKR_OBJECT_HANDLE_INVALID)
+	}
+	delete(f.objects, o)
+	return nil
+}
+
+func (f *fakeModule) GenerateKeyPair(_ p11.SessionHandle, m []*p11.Mechanism, public, private []*p11.Attribute) (p11.ObjectHandle, p11.ObjectHandle, error) {
+	if len(m) != 1 || m[0].Mechanism != p11.CKM_EC_KEY_PAIR_GEN {
+		return 0, 0, p11.Error(p11.CKR_MECHANISM_INVALID)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to generate key: %w", err)
+	}
+	pub, err := key.PublicKey.ECDH()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to convert key: %w", err)
+	}
+	point, err := asn1.Marshal(pub.Bytes())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to marshal point: %w", err)
+	}
+
+	pubHandle := f.add(append(public, p11.NewAttribute(p11.CKA_EC_POINT, point)), nil)
+	privHandle := f.add(private, key)
+	return pubHandle, privHandle, nil
+}
+
+func (f *fakeModule) SignInit(_ p11.SessionHandle, m []*p11.Mechanism, o p11.ObjectHandle) error {
+	obj, ok := f.objects[o]
+	if !ok {
+		return p11.Error(p11.CKR_KEY_HANDLE_INVALID)
+	}
+	if len(m) != 1 || m[0].Mechanism != p11.CKM_ECDSA {
+		return p11.Error(p11.CKR_MECHANISM_INVALID)
+	}
+	if sign := obj.attrs[p11.CKA_SIGN]; len(sign) == 0 || sign[0] == 0 {
+		return p11.Error(p11.CKR_KEY_FUNCTION_NOT_PERMITTED)
+	}
+	f.signing = obj
+	return nil
+}
+
+func (f *fakeModule) Sign(_ p11.SessionHandle, message []byte) ([]byte, error) {
+	obj := f.signing
+	f.signing = nil
+	if obj == nil {
+		return nil, p11.Error(p11.CKR_OPERATION_NOT_INITIALIZED)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, obj.key, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig, nil
+}
+
+func (f *fakeModule) add(temp []*p11.Attribute, key *ecdsa.PrivateKey) p11.ObjectHandle {
+	obj := &fakeObject{
+		attrs: make(map[uint][]byte, len(temp)),
+		key:   key,
+	}
+	for _, a := range temp {
+		obj.attrs[a.Type] = a.Value
+	}
+	f.next++
+	f.objects[f.next] = obj
+	return f.next
+}
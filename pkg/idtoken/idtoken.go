@@ -12,17 +12,62 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package idtoken provides functions to generate id tokens for end users.
+// Package idtoken provides functions to generate id tokens for end users, and
+// to verify the id tokens of callers.
 package idtoken
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	googleidtoken "google.golang.org/api/idtoken"
+	"google.golang.org/api/impersonate"
 )
 
+// ForAudience creates an ID token source for the given audience from the
+// application default credentials (ADC), similar to
+// `gcloud auth print-identity-token`.
+//
+// If impersonateServiceAccount is not empty, the ADC principal is used to mint
+// ID tokens for that service account instead, which requires the
+// "roles/iam.serviceAccountTokenCreator" role on it. Otherwise service account
+// and workload credentials mint tokens for the audience directly, and end user
+// credentials fall back to [FromDefaultCredentials], which ignores the
+// audience.
+func ForAudience(ctx context.Context, audience, impersonateServiceAccount string) (oauth2.TokenSource, error) {
+	if impersonateServiceAccount != "" {
+		ts, err := impersonate.IDTokenSource(ctx, impersonate.IDTokenConfig{
+			Audience:        audience,
+			TargetPrincipal: impersonateServiceAccount,
+			IncludeEmail:    true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create impersonated id token source for %s: %w",
+				impersonateServiceAccount, err)
+		}
+		return ts, nil
+	}
+
+	creds, err := google.FindDefaultCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find google default credential: %w", err)
+	}
+
+	// End user credentials cannot mint ID tokens for arbitrary audiences.
+	if creds.JSON == nil || !isUserCredential(creds.JSON) {
+		ts, err := googleidtoken.NewTokenSource(ctx, audience)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create id token source: %w", err)
+		}
+		return ts, nil
+	}
+
+	return FromDefaultCredentials(ctx)
+}
+
 // FromDefaultCredentials creates a token source with the application default credentials (ADC).
 // https://developers.google.com/accounts/docs/application-default-credentials
 // It only works when the application default credentials is of an end user.
@@ -38,6 +83,18 @@ func FromDefaultCredentials(ctx context.Context) (oauth2.TokenSource, error) {
 	}), nil
 }
 
+// isUserCredential reports whether the credential JSON is for an end user, as
+// created by `gcloud auth application-default login`.
+func isUserCredential(b []byte) bool {
+	var f struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return false
+	}
+	return f.Type == "authorized_user"
+}
+
 type tokenSource struct {
 	tokenSource oauth2.TokenSource
 }
@@ -0,0 +1,139 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idtoken
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// acceptableSkew is the clock skew allowed with the issuer of ID tokens.
+const acceptableSkew = 5 * time.Second
+
+// Verifier verifies OIDC ID tokens, such as the Google-signed ID tokens of
+// service accounts, or the tokens of a third-party identity provider like
+// GitHub Actions.
+type Verifier struct {
+	keys     jwk.Set
+	issuer   string
+	audience string
+	maxAge   time.Duration
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// VerifierOption is an option for [NewVerifier].
+type VerifierOption func(v *Verifier)
+
+// WithMaxAge rejects tokens issued longer ago than d, even if they haven't
+// expired yet, e.g. tokens of an identity provider issuing long-lived tokens.
+func WithMaxAge(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.maxAge = d
+	}
+}
+
+// NewVerifier returns a verifier of the ID tokens issued by the issuer for the
+// audience, which are verified with the keys.
+func NewVerifier(keys jwk.Set, issuer, audience string, opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify verifies the signature, issuer, audience and lifetime of the ID token,
+// and returns it. Tokens must have an expiry and an issued at time.
+func (v *Verifier) Verify(ctx context.Context, raw string) (jwt.Token, error) {
+	token, err := jwt.Parse([]byte(raw),
+		jwt.WithContext(ctx),
+		jwt.WithKeySet(v.keys, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithRequiredClaim(jwt.IssuedAtKey),
+		jwt.WithAcceptableSkew(acceptableSkew),
+		jwt.WithClock(jwt.ClockFunc(v.now)),
+	)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Callers add their own context.
+	}
+	if v.maxAge > 0 {
+		if age := v.now().Sub(token.IssuedAt()); age > v.maxAge+acceptableSkew {
+			return nil, fmt.Errorf("token was issued %s ago, more than the maximum of %s",
+				age.Truncate(time.Second), v.maxAge)
+		}
+	}
+	return token, nil
+}
+
+// BearerToken returns the token of an authorization header value of the
+// bearer scheme.
+func BearerToken(authorization string) (string, error) {
+	raw := strings.TrimSpace(authorization)
+	if raw == "" {
+		return "", fmt.Errorf("missing authorization")
+	}
+	if len(raw) < 8 || !strings.EqualFold(raw[:7], "bearer ") {
+		return "", fmt.Errorf("authorization must be a bearer token")
+	}
+	return raw[7:], nil
+}
+
+// VerifiedEmail returns the email of the ID token. It returns an error if the
+// token has no email, or its email_verified claim is false. Google only sets
+// the emails of service accounts and users verified, but other issuers may
+// not.
+func VerifiedEmail(token jwt.Token) (string, error) {
+	email, _ := token.PrivateClaims()["email"].(string)
+	if email == "" {
+		return "", fmt.Errorf("token has no email")
+	}
+	if verified, ok := token.PrivateClaims()["email_verified"].(bool); ok && !verified {
+		return email, fmt.Errorf("email %q is not verified", email)
+	}
+	return email, nil
+}
+
+// Principals is a set of principal emails, compared case-insensitively.
+type Principals map[string]struct{}
+
+// NewPrincipals returns the set of the principal emails.
+func NewPrincipals(emails []string) Principals {
+	p := make(Principals, len(emails))
+	for _, email := range emails {
+		p[strings.ToLower(strings.TrimSpace(email))] = struct{}{}
+	}
+	return p
+}
+
+// Contains reports whether the email is one of the principals.
+func (p Principals) Contains(email string) bool {
+	_, ok := p[strings.ToLower(email)]
+	return ok && email != ""
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idtoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/jvs/pkg/testutil"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+const (
+	testIssuer   = "https://accounts.google.com"
+	testAudience = "https://jvs.example.com"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	issuer := testutil.NewIDTokenIssuer(t)
+	other := testutil.NewIDTokenIssuer(t)
+
+	cases := []struct {
+		name    string
+		opts    []VerifierOption
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			token: issuer.IDToken(t, testIssuer, testAudience, "1234567890", now, nil),
+		},
+		{
+			name:    "wrong_key",
+			token:   other.IDToken(t, testIssuer, testAudience, "1234567890", now, nil),
+			wantErr: "could not verify message",
+		},
+		{
+			name:    "wrong_issuer",
+			token:   issuer.IDToken(t, "https://other.example.com", testAudience, "1234567890", now, nil),
+			wantErr: `"iss" not satisfied`,
+		},
+		{
+			name:    "wrong_audience",
+			token:   issuer.IDToken(t, testIssuer, "https://other.example.com", "1234567890", now, nil),
+			wantErr: `"aud" not satisfied`,
+		},
+		{
+			name:    "expired",
+			token:   issuer.IDToken(t, testIssuer, testAudience, "1234567890", now.Add(-time.Hour), nil),
+			wantErr: `"exp" not satisfied`,
+		},
+		{
+			name:    "missing_expiration",
+			token:   issuer.IDToken(t, testIssuer, testAudience, "1234567890", now, map[string]any{jwt.ExpirationKey: nil}),
+			wantErr: `"exp" not satisfied: required claim not found`,
+		},
+		{
+			name:    "missing_issued_at",
+			token:   issuer.IDToken(t, testIssuer, testAudience, "1234567890", now, map[string]any{jwt.IssuedAtKey: nil}),
+			wantErr: `"iat" not satisfied: required claim not found`,
+		},
+		{
+			name: "within_max_age",
+			opts: []VerifierOption{WithMaxAge(time.Hour)},
+			token: issuer.IDToken(t, testIssuer, testAudience, "1234567890", now, map[string]any{
+				jwt.IssuedAtKey: now.Add(-59 * time.Minute),
+			}),
+		},
+		{
+			name: "too_old",
+			opts: []VerifierOption{WithMaxAge(time.Hour)},
+			token: issuer.IDToken(t, testIssuer, testAudience, "1234567890", now, map[string]any{
+				jwt.IssuedAtKey: now.Add(-2 * time.Hour),
+			}),
+			wantErr: "more than the maximum of 1h0m0s",
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-jwt",
+			wantErr: "invalid JWT",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := NewVerifier(issuer.Keys, testIssuer, testAudience, tc.opts...)
+			token, err := v.Verify(ctx, tc.token)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := token.Subject(), "1234567890"; got != want {
+				t.Errorf("expected subject %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		authorization string
+		want          string
+		wantErr       string
+	}{
+		{
+			name:          "bearer",
+			authorization: "Bearer abc.def.ghi",
+			want:          "abc.def.ghi",
+		},
+		{
+			name:          "case_insensitive",
+			authorization: " bearer abc.def.ghi ",
+			want:          "abc.def.ghi",
+		},
+		{
+			name:    "missing",
+			wantErr: "missing authorization",
+		},
+		{
+			name:          "basic",
+			authorization: "Basic dXNlcjpwYXNz",
+			wantErr:       "authorization must be a bearer token",
+		},
+		{
+			name:          "empty_token",
+			authorization: "Bearer ",
+			wantErr:       "authorization must be a bearer token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := BearerToken(tc.authorization)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifiedEmail(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		claims  map[string]any
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "email",
+			claims: map[string]any{"email": "me@example.com"},
+			want:   "me@example.com",
+		},
+		{
+			name:   "verified",
+			claims: map[string]any{"email": "me@example.com", "email_verified": true},
+			want:   "me@example.com",
+		},
+		{
+			name:    "unverified",
+			claims:  map[string]any{"email": "me@example.com", "email_verified": false},
+			want:    "me@example.com",
+			wantErr: `email "me@example.com" is not verified`,
+		},
+		{
+			name:    "missing",
+			wantErr: "token has no email",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			token := jwt.New()
+			for k, v := range tc.claims {
+				if err := token.Set(k, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := VerifiedEmail(token)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrincipals_Contains(t *testing.T) {
+	t.Parallel()
+
+	p := NewPrincipals([]string{" Admin@example.com", "ops@example.com"})
+	for email, want := range map[string]bool{
+		"admin@example.com": true,
+		"ADMIN@example.com": true,
+		"ops@example.com":   true,
+		"me@example.com":    false,
+		"":                  false,
+	} {
+		if got := p.Contains(email); got != want {
+			t.Errorf("expected Contains(%q) %t to be %t", email, got, want)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/decision"
+	"github.com/abcxyz/jvs/pkg/idtoken"
+	"github.com/abcxyz/jvs/pkg/quota"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// PolicyChangedLogMessage is logged for every change to the runtime
+	// policy, so changes can be audited with a log-based metric.
+	PolicyChangedLogMessage = "runtime policy changed"
+
+	// PrincipalDeprovisionedLogMessage is logged when a principal is
+	// deprovisioned with the admin API or the deprovision webhook.
+	PrincipalDeprovisionedLogMessage = "principal deprovisioned"
+)
+
+// AdminServer is the implementation of the admin API, which changes the
+// runtime policy of a [Processor]. It must be served with its
+// [AdminServer.UnaryInterceptor], which only lets in callers with an ID token
+// of one of the admin principals.
+type AdminServer struct {
+	jvspb.UnimplementedAdminServiceServer
+
+	processor *Processor
+	policy    *PolicyStore
+
+	verifier   *idtoken.Verifier
+	principals idtoken.Principals
+}
+
+// NewAdminServer creates an admin server for the processor, which must use the
+// same policy store, from the admin options in the config. Keys are fetched
+// lazily on first use.
+func NewAdminServer(ctx context.Context, p *Processor, policy *PolicyStore, cfg *config.JustificationConfig) (*AdminServer, error) {
+	keys, err := jvspb.NewJWKSProvider(ctx, cfg.AdminJWKSEndpoint, jvspb.WithJWKSRefreshInterval(exchangeKeysRefreshInterval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin jwks provider: %w", err)
+	}
+
+	return &AdminServer{
+		processor:  p,
+		policy:     policy,
+		verifier:   idtoken.NewVerifier(keys, cfg.AdminIssuer, cfg.AdminAudience),
+		principals: idtoken.NewPrincipals(cfg.AdminPrincipals),
+	}, nil
+}
+
+// ListCategoryPolicies lists the policies of the categories of the processor,
+// including disabled ones.
+func (s *AdminServer) ListCategoryPolicies(ctx context.Context, _ *jvspb.ListCategoryPoliciesRequest) (*jvspb.ListCategoryPoliciesResponse, error) {
+	validators := s.processor.Validators()
+	categories := make([]*jvspb.CategoryPolicy, 0, len(validators))
+	for name := range validators {
+		categories = append(categories, s.categoryPolicy(name))
+	}
+	slices.SortFunc(categories, func(a, b *jvspb.CategoryPolicy) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+
+	return &jvspb.ListCategoryPoliciesResponse{
+		Categories: categories,
+	}, nil
+}
+
+// EnableCategory enables the category.
+func (s *AdminServer) EnableCategory(ctx context.Context, req *jvspb.EnableCategoryRequest) (*jvspb.CategoryPolicy, error) {
+	return s.updateCategory(ctx, req.GetCategory(), "enable_category", func(p *jvspb.Policy) error {
+		p.DisabledCategories = slices.DeleteFunc(p.DisabledCategories, func(c string) bool {
+			return c == req.GetCategory()
+		})
+		return nil
+	})
+}
+
+// DisableCategory disables the category, so justifications with it are
+// rejected and it is no longer listed.
+func (s *AdminServer) DisableCategory(ctx context.Context, req *jvspb.DisableCategoryRequest) (*jvspb.CategoryPolicy, error) {
+	return s.updateCategory(ctx, req.GetCategory(), "disable_category", func(p *jvspb.Policy) error {
+		p.DisabledCategories = append(p.DisabledCategories, req.GetCategory())
+		return nil
+	})
+}
+
+// SetCategoryMaxTTL caps the TTL of tokens with the category, or removes the
+// cap if the max TTL is zero.
+func (s *AdminServer) SetCategoryMaxTTL(ctx context.Context, req *jvspb.SetCategoryMaxTTLRequest) (*jvspb.CategoryPolicy, error) {
+	maxTTL := req.GetMaxTtl().AsDuration()
+	if maxTTL < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "max ttl cannot be negative, got %s", maxTTL)
+	}
+	if limit := s.processor.config.MaxTTL; maxTTL > limit {
+		return nil, status.Errorf(codes.InvalidArgument, "max ttl %s cannot be greater than the server max ttl %s", maxTTL, limit)
+	}
+
+	return s.updateCategory(ctx, req.GetCategory(), "set_category_max_ttl", func(p *jvspb.Policy) error {
+		if maxTTL == 0 {
+			delete(p.CategoryMaxTtls, req.GetCategory())
+			return nil
+		}
+		if p.CategoryMaxTtls == nil {
+			p.CategoryMaxTtls = make(map[string]*durationpb.Duration, 1)
+		}
+		p.CategoryMaxTtls[req.GetCategory()] = durationpb.New(maxTTL)
+		return nil
+	})
+}
+
+// GetAudienceAllowlist returns the audience allowlist.
+func (s *AdminServer) GetAudienceAllowlist(ctx context.Context, _ *jvspb.GetAudienceAllowlistRequest) (*jvspb.AudienceAllowlist, error) {
+	return &jvspb.AudienceAllowlist{
+		Audiences: s.policy.Policy().GetAudienceAllowlist(),
+	}, nil
+}
+
+// UpdateAudienceAllowlist replaces the audience allowlist. An empty allowlist
+// allows any audience.
+func (s *AdminServer) UpdateAudienceAllowlist(ctx context.Context, req *jvspb.UpdateAudienceAllowlistRequest) (*jvspb.AudienceAllowlist, error) {
+	for _, aud := range req.GetAudiences() {
+		if strings.TrimSpace(aud) == "" {
+			return nil, status.Error(codes.InvalidArgument, "audiences cannot be empty")
+		}
+	}
+
+	policy, err := s.policy.Update(func(p *jvspb.Policy) error {
+		p.AudienceAllowlist = slices.Clone(req.GetAudiences())
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to update policy", "error", err)
+		return nil, status.Error(codes.Internal, "failed to update policy")
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, PolicyChangedLogMessage,
+		"admin", adminFromContext(ctx),
+		"change", "update_audience_allowlist",
+		"audiences", policy.GetAudienceAllowlist())
+	s.attestPolicy(ctx)
+
+	return &jvspb.AudienceAllowlist{
+		Audiences: policy.GetAudienceAllowlist(),
+	}, nil
+}
+
+// DeprovisionUser deprovisions the principal with [Processor.Deprovision]:
+// it is added to the deny list, and the tokens already issued to it are
+// revoked, whichever are enabled.
+func (s *AdminServer) DeprovisionUser(ctx context.Context, req *jvspb.DeprovisionUserRequest) (*jvspb.DeprovisionUserResponse, error) {
+	principal := req.GetPrincipal()
+	alreadyDenied, err := s.processor.Deprovision(ctx, principal, adminFromContext(ctx), req.GetReason())
+	switch {
+	case errors.Is(err, errDeprovisionDisabled):
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+	case errors.Is(err, errInvalidPrincipal):
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	case err != nil:
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to deprovision principal", "error", err)
+		return nil, status.Error(codes.Internal, "failed to deprovision principal")
+	}
+
+	return &jvspb.DeprovisionUserResponse{
+		Principal:     principal,
+		AlreadyDenied: alreadyDenied,
+	}, nil
+}
+
+// GrantQuota allows the principal more tokens of the category than its daily
+// quota with [Processor.GrantQuota].
+func (s *AdminServer) GrantQuota(ctx context.Context, req *jvspb.GrantQuotaRequest) (*jvspb.QuotaUsage, error) {
+	grant, err := s.processor.GrantQuota(ctx, req.GetPrincipal(), req.GetCategory(), int(req.GetTokens()),
+		adminFromContext(ctx), req.GetReason())
+	switch {
+	case errors.Is(err, errQuotaDisabled):
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+	case errors.Is(err, errInvalidPrincipal), errors.Is(err, errInvalidQuotaGrant), errors.Is(err, quota.ErrNoQuota):
+		return nil, status.Errorf(codes.InvalidArgument, "%s", err)
+	case err != nil:
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to grant quota", "error", err)
+		return nil, status.Error(codes.Internal, "failed to grant quota")
+	}
+
+	return &jvspb.QuotaUsage{
+		Principal: grant.Usage.Principal,
+		Category:  grant.Usage.Category,
+		Limit:     int32(grant.Limit),
+		Used:      int32(grant.Usage.Used),
+		Granted:   int32(grant.Usage.Granted),
+		ResetTime: timestamppb.New(grant.ResetAt),
+	}, nil
+}
+
+// ListValidators returns the versions and health of the validators of every
+// category, as seen by this instance.
+func (s *AdminServer) ListValidators(_ context.Context, _ *jvspb.ListValidatorsRequest) (*jvspb.ListValidatorsResponse, error) {
+	return &jvspb.ListValidatorsResponse{
+		Validators: s.processor.ValidatorStatuses(),
+	}, nil
+}
+
+// GetAPIDescriptors returns the compiled descriptors of the JVS APIs. They are
+// served here, to admins only, instead of through server reflection on the
+// public port.
+func (s *AdminServer) GetAPIDescriptors(_ context.Context, _ *jvspb.GetAPIDescriptorsRequest) (*descriptorpb.FileDescriptorSet, error) {
+	return jvspb.FileDescriptorSet(), nil
+}
+
+// GetDecisionTrace returns the trace of the denied token request with
+// [Processor.DecisionTrace]. Without a shared trace directory, only the traces
+// of requests denied by this instance are found.
+func (s *AdminServer) GetDecisionTrace(ctx context.Context, req *jvspb.GetDecisionTraceRequest) (*jvspb.DecisionTrace, error) {
+	id := strings.TrimSpace(req.GetRequestId())
+	if !decision.ValidRequestID(id) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request id %q", req.GetRequestId())
+	}
+
+	trace, err := s.processor.DecisionTrace(ctx, id)
+	switch {
+	case errors.Is(err, errDecisionTracesDisabled):
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+	case errors.Is(err, decision.ErrNotFound):
+		return nil, status.Errorf(codes.NotFound, "no decision trace for request id %q, it may have expired", id)
+	case err != nil:
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to get decision trace", "error", err)
+		return nil, status.Error(codes.Internal, "failed to get decision trace")
+	}
+	return trace, nil
+}
+
+// updateCategory checks the category exists, and applies the change to the
+// policy.
+func (s *AdminServer) updateCategory(ctx context.Context, category, change string, fn func(p *jvspb.Policy) error) (*jvspb.CategoryPolicy, error) {
+	if _, ok := s.processor.Validators()[category]; !ok {
+		return nil, status.Errorf(codes.NotFound, "category %q is not supported", category)
+	}
+
+	if _, err := s.policy.Update(fn); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to update policy", "error", err)
+		return nil, status.Error(codes.Internal, "failed to update policy")
+	}
+
+	resp := s.categoryPolicy(category)
+	logging.FromContext(ctx).InfoContext(ctx, PolicyChangedLogMessage,
+		"admin", adminFromContext(ctx),
+		"change", change,
+		"category", category,
+		"enabled", resp.GetEnabled(),
+		"max_ttl", resp.GetMaxTtl().AsDuration().String())
+	s.attestPolicy(ctx)
+	return resp, nil
+}
+
+// attestPolicy attests to the changed policy, so its new version is recorded
+// in the audit log. Failing to attest is logged by the processor, and doesn't
+// fail the change.
+func (s *AdminServer) attestPolicy(ctx context.Context) {
+	_, _ = s.processor.PolicyAttestation(ctx)
+}
+
+// categoryPolicy returns the current policy of the category.
+func (s *AdminServer) categoryPolicy(category string) *jvspb.CategoryPolicy {
+	resp := &jvspb.CategoryPolicy{
+		Name:    category,
+		Enabled: !s.policy.CategoryDisabled(category),
+	}
+	if maxTTL := s.policy.CategoryMaxTTL(category); maxTTL > 0 {
+		resp.MaxTtl = durationpb.New(maxTTL)
+	}
+	return resp
+}
+
+// adminContextKey is the context key of the email of the authorized admin.
+type adminContextKey struct{}
+
+// adminFromContext returns the email of the admin authorized by
+// [AdminServer.UnaryInterceptor].
+func adminFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(adminContextKey{}).(string)
+	return v
+}
+
+// UnaryInterceptor rejects requests without an ID token of one of the admin
+// principals.
+func (s *AdminServer) UnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	admin, err := s.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, adminContextKey{}, admin), req)
+}
+
+// authorize verifies the ID token in the incoming context, and returns its
+// email if it is one of the admin principals and not unverified.
+func (s *AdminServer) authorize(ctx context.Context) (string, error) {
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+	var authorization string
+	if vals := md.Get("authorization"); len(vals) > 0 {
+		authorization = vals[0]
+	}
+	raw, err := idtoken.BearerToken(authorization)
+	if err != nil {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	token, err := s.verifier.Verify(ctx, raw)
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to verify admin token", "error", err)
+		return "", status.Errorf(codes.Unauthenticated, "invalid token: %s", err)
+	}
+
+	email, err := idtoken.VerifiedEmail(token)
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "denied admin request", "principal", email, "error", err)
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+	if !s.principals.Contains(email) {
+		logging.FromContext(ctx).WarnContext(ctx, "denied admin request", "principal", email)
+		return "", status.Errorf(codes.PermissionDenied, "%q is not an admin", email)
+	}
+	return email, nil
+}
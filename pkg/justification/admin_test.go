@@ -0,0 +1,540 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/decision"
+	"github.com/abcxyz/jvs/pkg/events"
+	"github.com/abcxyz/jvs/pkg/quota"
+	"github.com/abcxyz/jvs/pkg/testutil"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	testAdminIssuer   = "https://accounts.google.com"
+	testAdminAudience = "https://jvs-admin.example.com"
+)
+
+func TestAdminServer_UnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	now := time.Now().UTC()
+
+	admin, issuer := testAdminServer(t)
+
+	cases := []struct {
+		name      string
+		auth      string
+		wantCode  codes.Code
+		wantAdmin string
+	}{
+		{
+			name:     "missing_authorization",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "not_bearer",
+			auth:     "Basic dXNlcjpwYXNz",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "invalid_token",
+			auth:     "Bearer not-a-jwt",
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "wrong_audience",
+			auth:     "Bearer " + issuer.IDToken(t, testAdminIssuer, "https://other.example.com", "1234567890", now, map[string]any{"email": "jvs-admin@example.com"}),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "not_admin",
+			auth:     "Bearer " + issuer.IDToken(t, testAdminIssuer, testAdminAudience, "1234567890", now, map[string]any{"email": "me@example.com"}),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "missing_email",
+			auth:     "Bearer " + issuer.IDToken(t, testAdminIssuer, testAdminAudience, "1234567890", now, nil),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "missing_expiration",
+			auth: "Bearer " + issuer.IDToken(t, testAdminIssuer, testAdminAudience, "1234567890", now, map[string]any{
+				"email":           "jvs-admin@example.com",
+				jwt.ExpirationKey: nil,
+			}),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "unverified_email",
+			auth: "Bearer " + issuer.IDToken(t, testAdminIssuer, testAdminAudience, "1234567890", now, map[string]any{
+				"email":          "jvs-admin@example.com",
+				"email_verified": false,
+			}),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "verified_email",
+			auth: "Bearer " + issuer.IDToken(t, testAdminIssuer, testAdminAudience, "1234567890", now, map[string]any{
+				"email":          "jvs-admin@example.com",
+				"email_verified": true,
+			}),
+			wantCode:  codes.OK,
+			wantAdmin: "jvs-admin@example.com",
+		},
+		{
+			name:      "admin",
+			auth:      "bearer " + issuer.IDToken(t, testAdminIssuer, testAdminAudience, "1234567890", now, map[string]any{"email": "JVS-Admin@example.com"}),
+			wantCode:  codes.OK,
+			wantAdmin: "JVS-Admin@example.com",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := ctx
+			if tc.auth != "" {
+				ctx = grpcmetadata.NewIncomingContext(ctx, grpcmetadata.Pairs("authorization", tc.auth))
+			}
+
+			var gotAdmin string
+			_, err := admin.UnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+				gotAdmin = adminFromContext(ctx)
+				return &jvspb.ListCategoryPoliciesResponse{}, nil
+			})
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+			if got, want := gotAdmin, tc.wantAdmin; got != want {
+				t.Errorf("expected admin %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAdminServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+
+	// Disabled categories stay listed for admins, and can be enabled again.
+	if _, err := admin.DisableCategory(ctx, &jvspb.DisableCategoryRequest{Category: "jira"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.SetCategoryMaxTTL(ctx, &jvspb.SetCategoryMaxTTLRequest{
+		Category: "explanation",
+		MaxTtl:   durationpb.New(30 * time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := admin.ListCategoryPolicies(ctx, &jvspb.ListCategoryPoliciesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &jvspb.ListCategoryPoliciesResponse{
+		Categories: []*jvspb.CategoryPolicy{
+			{Name: "explanation", Enabled: true, MaxTtl: durationpb.New(30 * time.Minute)},
+			{Name: "jira", Enabled: false},
+		},
+	}
+	if diff := cmp.Diff(want, resp, protocmp.Transform()); diff != "" {
+		t.Errorf("category policies (-want, +got):\n%s", diff)
+	}
+
+	got, err := admin.EnableCategory(ctx, &jvspb.EnableCategoryRequest{Category: "jira"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&jvspb.CategoryPolicy{Name: "jira", Enabled: true}, got, protocmp.Transform()); diff != "" {
+		t.Errorf("enabled category (-want, +got):\n%s", diff)
+	}
+
+	// A zero max ttl removes the cap.
+	got, err = admin.SetCategoryMaxTTL(ctx, &jvspb.SetCategoryMaxTTLRequest{Category: "explanation"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&jvspb.CategoryPolicy{Name: "explanation", Enabled: true}, got, protocmp.Transform()); diff != "" {
+		t.Errorf("uncapped category (-want, +got):\n%s", diff)
+	}
+
+	allowlist, err := admin.UpdateAudienceAllowlist(ctx, &jvspb.UpdateAudienceAllowlistRequest{
+		Audiences: []string{"https://b.example.com", "https://a.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAllowlist := &jvspb.AudienceAllowlist{Audiences: []string{"https://a.example.com", "https://b.example.com"}}
+	if diff := cmp.Diff(wantAllowlist, allowlist, protocmp.Transform()); diff != "" {
+		t.Errorf("updated allowlist (-want, +got):\n%s", diff)
+	}
+	allowlist, err = admin.GetAudienceAllowlist(ctx, &jvspb.GetAudienceAllowlistRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantAllowlist, allowlist, protocmp.Transform()); diff != "" {
+		t.Errorf("allowlist (-want, +got):\n%s", diff)
+	}
+}
+
+func TestAdminServer_errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+
+	cases := []struct {
+		name     string
+		call     func() error
+		wantCode codes.Code
+	}{
+		{
+			name: "disable_unknown_category",
+			call: func() error {
+				_, err := admin.DisableCategory(ctx, &jvspb.DisableCategoryRequest{Category: "unknown"})
+				return err
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "negative_max_ttl",
+			call: func() error {
+				_, err := admin.SetCategoryMaxTTL(ctx, &jvspb.SetCategoryMaxTTLRequest{
+					Category: "jira",
+					MaxTtl:   durationpb.New(-time.Minute),
+				})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "max_ttl_over_server_max",
+			call: func() error {
+				_, err := admin.SetCategoryMaxTTL(ctx, &jvspb.SetCategoryMaxTTLRequest{
+					Category: "jira",
+					MaxTtl:   durationpb.New(2 * time.Hour),
+				})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "empty_audience",
+			call: func() error {
+				_, err := admin.UpdateAudienceAllowlist(ctx, &jvspb.UpdateAudienceAllowlistRequest{
+					Audiences: []string{" "},
+				})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := status.Code(tc.call()), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAdminServer_DeprovisionUser(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+
+	// The deny list must be enabled.
+	_, err := admin.DeprovisionUser(ctx, &jvspb.DeprovisionUserRequest{Principal: "left@example.com"})
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "deny-list")
+	writeDenyList(t, path, "")
+	denyList, err := LoadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &fakePublisher{}
+	admin.processor.WithDenyList(denyList).WithEventPublisher(publisher)
+
+	_, err = admin.DeprovisionUser(ctx, &jvspb.DeprovisionUserRequest{Principal: "left@example.com\nother@example.com"})
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	ctx = context.WithValue(ctx, adminContextKey{}, "jvs-admin@example.com")
+	for _, wantAlreadyDenied := range []bool{false, true} {
+		resp, err := admin.DeprovisionUser(ctx, &jvspb.DeprovisionUserRequest{
+			Principal: "left@example.com",
+			Reason:    "scim user deactivated",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.GetAlreadyDenied(), wantAlreadyDenied; got != want {
+			t.Errorf("expected already denied %t to be %t", got, want)
+		}
+	}
+
+	if _, err := admin.processor.CreateToken(ctx, "left@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{{Category: "explanation", Value: "debugging"}},
+	}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected deprovisioned principal to be denied, got %v", err)
+	}
+
+	// Only the first deprovisioning is published, followed by the denied token.
+	want := []*events.Event{{
+		Type:    events.TypePrincipalDeprovisioned,
+		Subject: "left@example.com",
+		Data: &events.PrincipalDeprovisioned{
+			Principal: "left@example.com",
+			Admin:     "jvs-admin@example.com",
+			Reason:    "scim user deactivated",
+		},
+	}}
+	if len(publisher.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(publisher.events))
+	}
+	if diff := cmp.Diff(want, publisher.events[:1], cmpopts.IgnoreFields(events.Event{}, "ID", "Time")); diff != "" {
+		t.Errorf("events (-want, +got):\n%s", diff)
+	}
+}
+
+func TestAdminServer_GrantQuota(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+	req := &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "incident"}},
+	}
+
+	// Quotas must be enabled.
+	_, err := admin.GrantQuota(ctx, &jvspb.GrantQuotaRequest{Principal: "me@example.com", Category: "breakglass", Tokens: 1})
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	admin.processor.WithValidators(map[string]jvspb.Validator{
+		"breakglass": &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}},
+	}).WithQuotas(quota.New(quota.NewFileStore(filepath.Join(t.TempDir(), "quota.json")),
+		map[string]int{"breakglass": 1}))
+
+	for _, req := range []*jvspb.GrantQuotaRequest{
+		{Principal: "me@example.com\nother@example.com", Category: "breakglass", Tokens: 1},
+		{Principal: "me@example.com", Category: "explanation", Tokens: 1},
+		{Principal: "me@example.com", Category: "breakglass", Tokens: 0},
+	} {
+		_, err := admin.GrantQuota(ctx, req)
+		if got, want := status.Code(err), codes.InvalidArgument; got != want {
+			t.Errorf("%v: expected code %s to be %s: %v", req, got, want, err)
+		}
+	}
+
+	if _, err := admin.processor.CreateToken(ctx, "me@example.com", req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.processor.CreateToken(ctx, "me@example.com", req); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected exhausted quota, got %v", err)
+	}
+
+	ctx = context.WithValue(ctx, adminContextKey{}, "jvs-admin@example.com")
+	got, err := admin.GrantQuota(ctx, &jvspb.GrantQuotaRequest{
+		Principal: "me@example.com",
+		Category:  "breakglass",
+		Tokens:    1,
+		Reason:    "incident",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &jvspb.QuotaUsage{
+		Principal: "me@example.com",
+		Category:  "breakglass",
+		Limit:     1,
+		Used:      1,
+		Granted:   1,
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform(), protocmp.IgnoreFields(&jvspb.QuotaUsage{}, "reset_time")); diff != "" {
+		t.Errorf("quota usage (-want, +got):\n%s", diff)
+	}
+	if got, want := got.GetResetTime().AsTime(), time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1); !got.Equal(want) {
+		t.Errorf("expected reset time %s to be %s", got, want)
+	}
+
+	if _, err := admin.processor.CreateToken(ctx, "me@example.com", req); err != nil {
+		t.Errorf("expected granted token to be issued, got %v", err)
+	}
+}
+
+func TestAdminServer_GetAPIDescriptors(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+
+	set, err := admin.GetAPIDescriptors(ctx, &jvspb.GetAPIDescriptorsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files.FindDescriptorByName("abcxyz.jvs.JVSService"); err != nil {
+		t.Errorf("expected the jvs service to be described: %v", err)
+	}
+}
+
+func TestAdminServer_ListValidators(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+
+	// Disabled categories are still listed.
+	if _, err := admin.DisableCategory(ctx, &jvspb.DisableCategoryRequest{Category: "jira"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := admin.ListValidators(ctx, &jvspb.ListValidatorsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &jvspb.ListValidatorsResponse{
+		Validators: []*jvspb.ValidatorStatus{
+			{Category: "explanation", Kind: "builtin"},
+			{Category: "jira", Kind: "builtin"},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("validators (-want, +got):\n%s", diff)
+	}
+}
+
+func TestAdminServer_GetDecisionTrace(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	admin, _ := testAdminServer(t)
+	req := &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "incident"}},
+	}
+
+	// Decision traces must be enabled.
+	_, err := admin.GetDecisionTrace(ctx, &jvspb.GetDecisionTraceRequest{RequestId: decision.NewRequestID()})
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	admin.processor.WithDecisionTraces(decision.NewMemoryStore(time.Hour))
+
+	_, err = admin.GetDecisionTrace(ctx, &jvspb.GetDecisionTraceRequest{RequestId: "../policy"})
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+	_, err = admin.GetDecisionTrace(ctx, &jvspb.GetDecisionTraceRequest{RequestId: decision.NewRequestID()})
+	if got, want := status.Code(err), codes.NotFound; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	_, err = admin.processor.CreateToken(ctx, "me@example.com", req)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Fatalf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	got, err := admin.GetDecisionTrace(ctx, &jvspb.GetDecisionTraceRequest{RequestId: requestIDFromError(err)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.GetMessage(), `failed to validate request: category "breakglass" is not supported`; got != want {
+		t.Errorf("expected message %q to be %q", got, want)
+	}
+}
+
+// testAdminServer creates an admin server for a processor with the
+// "explanation" and "jira" categories, and returns it with the key admin
+// tokens are signed with.
+func testAdminServer(tb testing.TB) (*AdminServer, *testutil.IDTokenIssuer) {
+	tb.Helper()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(tb))
+
+	issuer := testutil.NewIDTokenIssuer(tb)
+	cfg := &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		AdminPrincipals:    []string{"jvs-admin@example.com"},
+		AdminAudience:      testAdminAudience,
+		AdminIssuer:        testAdminIssuer,
+		AdminJWKSEndpoint:  issuer.JWKSEndpoint,
+	}
+
+	store, err := LoadPolicyStore(filepath.Join(tb.TempDir(), "policy.json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p := NewProcessor(nil, cfg).WithValidators(map[string]jvspb.Validator{
+		"jira": &mockValidator{},
+	}).WithPolicy(store).WithLocalSigner(signingKey, "test-key")
+
+	admin, err := NewAdminServer(ctx, p, store, cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return admin, issuer
+}
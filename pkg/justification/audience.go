@@ -0,0 +1,74 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+)
+
+// audienceServiceRegexp matches audience service names. They are DNS labels,
+// so they can't change the rest of the audience they are expanded into.
+var audienceServiceRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// validateAudiences checks the request's audience services can be expanded
+// with the audience template, and that it only has audiences the config and
+// the audience allowlist of the runtime policy allow.
+func (p *Processor) validateAudiences(req *jvspb.CreateJustificationRequest) error {
+	services := req.GetAudienceServices()
+	if len(services) > 0 && p.config.AudienceTemplate == "" {
+		return fmt.Errorf("audience services are not enabled")
+	}
+	for _, s := range services {
+		if !audienceServiceRegexp.MatchString(s) {
+			return fmt.Errorf("audience service %q must be a lowercase DNS label", s)
+		}
+	}
+	if p.config.AudienceTemplateRequired && len(req.GetAudiences()) > 0 {
+		return fmt.Errorf("audiences must be given as audience services")
+	}
+	for _, aud := range p.audiences(req) {
+		if p.policy != nil && !p.policy.AudienceAllowed(aud) {
+			return fmt.Errorf("audience %q is not allowed", aud)
+		}
+	}
+	return nil
+}
+
+// audiences returns the audiences of the request, followed by its audience
+// services expanded with the audience template, or [DefaultAudience] if it has
+// neither. These are the audiences of the token, so the allowlist is checked
+// against them. The request must have been validated with
+// [Processor.validateAudiences].
+func (p *Processor) audiences(req *jvspb.CreateJustificationRequest) []string {
+	services := req.GetAudienceServices()
+	if len(services) == 0 {
+		if len(req.GetAudiences()) == 0 {
+			return []string{DefaultAudience}
+		}
+		return req.GetAudiences()
+	}
+
+	aud := make([]string, 0, len(req.GetAudiences())+len(services))
+	aud = append(aud, req.GetAudiences()...)
+	for _, s := range services {
+		aud = append(aud, strings.Replace(p.config.AudienceTemplate, config.AudienceTemplateService, s, 1))
+	}
+	return aud
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestCreateToken_audienceServices(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	const template = "https://{service}.internal.example.com"
+
+	cases := []struct {
+		name      string
+		template  string
+		required  bool
+		audiences []string
+		services  []string
+		allowlist []string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name: "default_audience",
+			want: []string{DefaultAudience},
+		},
+		{
+			name:     "services",
+			template: template,
+			services: []string{"payments", "billing-api"},
+			want: []string{
+				"https://payments.internal.example.com",
+				"https://billing-api.internal.example.com",
+			},
+		},
+		{
+			name:      "audiences_and_services",
+			template:  template,
+			audiences: []string{"dev.abcxyz.other"},
+			services:  []string{"payments"},
+			want:      []string{"dev.abcxyz.other", "https://payments.internal.example.com"},
+		},
+		{
+			name:     "services_not_enabled",
+			services: []string{"payments"},
+			wantErr:  "audience services are not enabled",
+		},
+		{
+			name:     "invalid_service",
+			template: template,
+			services: []string{"evil.example.com/x"},
+			wantErr:  `audience service "evil.example.com/x" must be a lowercase DNS label`,
+		},
+		{
+			name:     "uppercase_service",
+			template: template,
+			services: []string{"Payments"},
+			wantErr:  `audience service "Payments" must be a lowercase DNS label`,
+		},
+		{
+			name:      "audiences_not_allowed",
+			template:  template,
+			required:  true,
+			audiences: []string{"https://evil.example.com"},
+			wantErr:   "audiences must be given as audience services",
+		},
+		{
+			name:     "required_services",
+			template: template,
+			required: true,
+			services: []string{"payments"},
+			want:     []string{"https://payments.internal.example.com"},
+		},
+		{
+			name:      "allowed_default_audience",
+			allowlist: []string{DefaultAudience},
+			want:      []string{DefaultAudience},
+		},
+		{
+			name:      "default_audience_not_allowed",
+			allowlist: []string{"https://payments.internal.example.com"},
+			wantErr:   `audience "dev.abcxyz.jvs" is not allowed`,
+		},
+		{
+			name:      "service_not_allowed",
+			template:  template,
+			services:  []string{"billing-api"},
+			allowlist: []string{"https://payments.internal.example.com"},
+			wantErr:   `audience "https://billing-api.internal.example.com" is not allowed`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout:       5 * time.Minute,
+				Issuer:                   "test-iss",
+				DefaultTTL:               15 * time.Minute,
+				MaxTTL:                   1 * time.Hour,
+				MaxAnnotationSize:        100,
+				AudienceTemplate:         tc.template,
+				AudienceTemplateRequired: tc.required,
+			})
+			if tc.allowlist != nil {
+				policy, err := LoadPolicyStore(filepath.Join(t.TempDir(), "policy.json"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := policy.Update(func(p *jvspb.Policy) error {
+					p.AudienceAllowlist = tc.allowlist
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+				processor.WithPolicy(policy)
+			}
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+				},
+				Audiences:        tc.audiences,
+				AudienceServices: tc.services,
+			}
+
+			err := processor.validateAudiences(req)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			token, _, err := processor.createToken(ctx, "me@example.com", req, time.Now().UTC())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, token.Audience()); diff != "" {
+				t.Errorf("aud (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
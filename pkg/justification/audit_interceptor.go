@@ -0,0 +1,191 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// AuditInterceptor writes a data access audit log for every CreateJustification,
+// CreateCertificate and CertificateAction request, with the caller, decision and resource.
+type AuditInterceptor struct {
+	writer  audit.Writer
+	keyName string
+
+	// caKeyName is the KMS key client certificates are signed with, which is
+	// the resource of CreateCertificate requests.
+	caKeyName string
+}
+
+// NewAuditInterceptor creates an interceptor that writes audit logs with the
+// writer. keyName is the KMS key justification tokens are signed with, which is
+// the resource of CreateJustification requests.
+func NewAuditInterceptor(writer audit.Writer, keyName string) *AuditInterceptor {
+	return &AuditInterceptor{
+		writer:  writer,
+		keyName: keyName,
+	}
+}
+
+// WithCertificateAuthorityKey sets the KMS key version client certificates are
+// signed with, as the resource of CreateCertificate requests.
+func (a *AuditInterceptor) WithCertificateAuthorityKey(name string) *AuditInterceptor {
+	a.caKeyName = name
+	return a
+}
+
+// UnaryInterceptor is a [grpc.UnaryServerInterceptor] that writes audit logs
+// after audited requests are handled. Failing to write an audit log is logged,
+// but does not fail the request.
+func (a *AuditInterceptor) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+
+	var logs []*audit.AuditLog
+	switch r := req.(type) {
+	case *jvspb.CreateJustificationRequest:
+		logs = a.createJustificationLogs(a.keyName, r, err)
+	case *jvspb.CreateCertificateRequest:
+		logs = a.createJustificationLogs(a.caKeyName, r.GetRequest(), err)
+	case *jvspb.CertificateActionRequest:
+		res, _ := resp.(*jvspb.CertificateActionResponse)
+		logs = a.certificateActionLogs(r, res, err)
+	default:
+		return resp, err
+	}
+
+	// A missing or malformed identity is recorded as an empty principal, the
+	// handler has already decided whether to allow it.
+	principal, _ := extractRequestorFromIncomingContext(ctx)
+
+	service, method := splitFullMethod(info.FullMethod)
+	for _, l := range logs {
+		l.ServiceName = service
+		l.MethodName = method
+		l.AuthenticationInfo = &audit.AuthenticationInfo{PrincipalEmail: principal}
+
+		if werr := a.writer.Write(ctx, l); werr != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to write audit log",
+				"method", info.FullMethod,
+				"resource", l.ResourceName,
+				"error", werr)
+		}
+	}
+	return resp, err
+}
+
+// createJustificationLogs returns the audit log of a CreateJustification or
+// CreateCertificate request, with the justification categories.
+func (a *AuditInterceptor) createJustificationLogs(resource string, req *jvspb.CreateJustificationRequest, err error) []*audit.AuditLog {
+	categories := make([]string, 0, len(req.GetJustifications()))
+	for _, j := range req.GetJustifications() {
+		categories = append(categories, j.GetCategory())
+	}
+
+	decision, st := decisionFor(err)
+	metadata := map[string]any{
+		"decision":   decision,
+		"categories": categories,
+		"audiences":  req.GetAudiences(),
+	}
+	if services := req.GetAudienceServices(); len(services) > 0 {
+		metadata["audience_services"] = services
+	}
+	if reason := errorReason(err); reason != "" {
+		metadata["reason"] = reason
+	}
+	if id := requestIDFromError(err); id != "" {
+		metadata["request_id"] = id
+	}
+	return []*audit.AuditLog{{
+		ResourceName: resource,
+		Status:       st,
+		Metadata:     metadata,
+	}}
+}
+
+// certificateActionLogs returns an audit log for each action of a
+// CertificateAction request, since actions succeed or fail independently.
+func (a *AuditInterceptor) certificateActionLogs(req *jvspb.CertificateActionRequest, resp *jvspb.CertificateActionResponse, err error) []*audit.AuditLog {
+	logs := make([]*audit.AuditLog, 0, len(req.GetActions()))
+	for i, action := range req.GetActions() {
+		decision, st := decisionFor(err)
+		if err == nil && i < len(resp.GetResults()) {
+			if msg := resp.GetResults()[i].GetError(); msg != "" {
+				decision = audit.DecisionDenied
+				st = &audit.Status{Code: int(codes.FailedPrecondition), Message: msg}
+			}
+		}
+
+		logs = append(logs, &audit.AuditLog{
+			ResourceName: action.GetVersion(),
+			Status:       st,
+			Metadata: map[string]any{
+				"decision": decision,
+				"action":   action.GetAction().String(),
+				"dry_run":  req.GetDryRun(),
+			},
+		})
+	}
+	return logs
+}
+
+// decisionFor returns the decision and status of a request that returned err.
+// Errors caused by the request, such as invalid justifications, are denials.
+func decisionFor(err error) (string, *audit.Status) {
+	if err == nil {
+		return audit.DecisionAllowed, nil
+	}
+
+	st := status.Convert(err)
+	decision := audit.DecisionError
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted:
+		decision = audit.DecisionDenied
+	default:
+	}
+	return decision, &audit.Status{Code: int(st.Code()), Message: st.Message()}
+}
+
+// errorReason returns the reason of the error's [errdetails.ErrorInfo], such
+// as [ReasonPrincipalDenied], or "" if it has none.
+func errorReason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// splitFullMethod splits a gRPC method, e.g.
+// "/abcxyz.jvs.JVSService/CreateJustification", into its service,
+// "abcxyz.jvs.JVSService", and the audit log method name,
+// "abcxyz.jvs.JVSService.CreateJustification".
+func splitFullMethod(fullMethod string) (string, string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	service, _, _ := strings.Cut(name, "/")
+	return service, strings.Replace(name, "/", ".", 1)
+}
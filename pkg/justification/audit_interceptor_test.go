@@ -0,0 +1,302 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// fakeAuditWriter records the audit logs written.
+type fakeAuditWriter struct {
+	mu   sync.Mutex
+	logs []*audit.AuditLog
+}
+
+func (w *fakeAuditWriter) Write(_ context.Context, l *audit.AuditLog) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.logs = append(w.logs, l)
+	return nil
+}
+
+func TestAuditInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	ctx = grpcmetadata.NewIncomingContext(ctx, grpcmetadata.New(map[string]string{
+		"authorization": "Bearer " + testToken(t, "you@example.com"),
+	}))
+
+	keyName := "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	version := keyName + "/cryptoKeyVersions/1"
+	caKeyName := "projects/p/locations/global/keyRings/r/cryptoKeys/ca/cryptoKeyVersions/1"
+	principal := &audit.AuthenticationInfo{PrincipalEmail: "you@example.com"}
+
+	cases := []struct {
+		name     string
+		method   string
+		req      any
+		resp     any
+		err      error
+		wantLogs []*audit.AuditLog
+	}{
+		{
+			name:   "create_justification_allowed",
+			method: "/abcxyz.jvs.JVSService/CreateJustification",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "test"},
+					{Category: "jira", Value: "ABC-123"},
+				},
+				Audiences: []string{"example.com"},
+			},
+			resp: &jvspb.CreateJustificationResponse{Token: "token"},
+			wantLogs: []*audit.AuditLog{{
+				ServiceName:        "abcxyz.jvs.JVSService",
+				MethodName:         "abcxyz.jvs.JVSService.CreateJustification",
+				ResourceName:       keyName,
+				AuthenticationInfo: principal,
+				Metadata: map[string]any{
+					"decision":   audit.DecisionAllowed,
+					"categories": []string{"explanation", "jira"},
+					"audiences":  []string{"example.com"},
+				},
+			}},
+		},
+		{
+			name:   "create_justification_denied",
+			method: "/abcxyz.jvs.JVSService/CreateJustification",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "jira", Value: "nope"},
+				},
+			},
+			err: status.Error(codes.InvalidArgument, "failed to validate justification"),
+			wantLogs: []*audit.AuditLog{{
+				ServiceName:        "abcxyz.jvs.JVSService",
+				MethodName:         "abcxyz.jvs.JVSService.CreateJustification",
+				ResourceName:       keyName,
+				AuthenticationInfo: principal,
+				Status: &audit.Status{
+					Code:    int(codes.InvalidArgument),
+					Message: "failed to validate justification",
+				},
+				Metadata: map[string]any{
+					"decision":   audit.DecisionDenied,
+					"categories": []string{"jira"},
+					"audiences":  []string(nil),
+				},
+			}},
+		},
+		{
+			name:   "create_justification_deny_listed",
+			method: "/abcxyz.jvs.JVSService/CreateJustification",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "test"},
+				},
+			},
+			err: func() error {
+				st, err := status.New(codes.PermissionDenied, `requestor "you@example.com" is on the deny list`).
+					WithDetails(&errdetails.ErrorInfo{Reason: ReasonPrincipalDenied, Domain: errorDomain})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return st.Err()
+			}(),
+			wantLogs: []*audit.AuditLog{{
+				ServiceName:        "abcxyz.jvs.JVSService",
+				MethodName:         "abcxyz.jvs.JVSService.CreateJustification",
+				ResourceName:       keyName,
+				AuthenticationInfo: principal,
+				Status: &audit.Status{
+					Code:    int(codes.PermissionDenied),
+					Message: `requestor "you@example.com" is on the deny list`,
+				},
+				Metadata: map[string]any{
+					"decision":   audit.DecisionDenied,
+					"categories": []string{"explanation"},
+					"audiences":  []string(nil),
+					"reason":     ReasonPrincipalDenied,
+				},
+			}},
+		},
+		{
+			name:   "create_justification_request_id",
+			method: "/abcxyz.jvs.JVSService/CreateJustification",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "jira", Value: "ABC-123"},
+				},
+			},
+			err: func() error {
+				st, err := status.New(codes.InvalidArgument, "failed to validate justification (request ID 2b2f8c1e-0b1a-4d8e-9f3a-2f0e8f6a1c3d)").
+					WithDetails(&errdetails.RequestInfo{RequestId: "2b2f8c1e-0b1a-4d8e-9f3a-2f0e8f6a1c3d"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return st.Err()
+			}(),
+			wantLogs: []*audit.AuditLog{{
+				ServiceName:        "abcxyz.jvs.JVSService",
+				MethodName:         "abcxyz.jvs.JVSService.CreateJustification",
+				ResourceName:       keyName,
+				AuthenticationInfo: principal,
+				Status: &audit.Status{
+					Code:    int(codes.InvalidArgument),
+					Message: "failed to validate justification (request ID 2b2f8c1e-0b1a-4d8e-9f3a-2f0e8f6a1c3d)",
+				},
+				Metadata: map[string]any{
+					"decision":   audit.DecisionDenied,
+					"categories": []string{"jira"},
+					"audiences":  []string(nil),
+					"request_id": "2b2f8c1e-0b1a-4d8e-9f3a-2f0e8f6a1c3d",
+				},
+			}},
+		},
+		{
+			name:   "create_justification_error",
+			method: "/abcxyz.jvs.JVSService/CreateJustification",
+			req:    &jvspb.CreateJustificationRequest{},
+			err:    status.Error(codes.Internal, "failed to sign token"),
+			wantLogs: []*audit.AuditLog{{
+				ServiceName:        "abcxyz.jvs.JVSService",
+				MethodName:         "abcxyz.jvs.JVSService.CreateJustification",
+				ResourceName:       keyName,
+				AuthenticationInfo: principal,
+				Status: &audit.Status{
+					Code:    int(codes.Internal),
+					Message: "failed to sign token",
+				},
+				Metadata: map[string]any{
+					"decision":   audit.DecisionError,
+					"categories": []string{},
+					"audiences":  []string(nil),
+				},
+			}},
+		},
+		{
+			name:   "create_certificate_allowed",
+			method: "/abcxyz.jvs.JVSService/CreateCertificate",
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{
+					Justifications: []*jvspb.Justification{
+						{Category: "explanation", Value: "test"},
+					},
+				},
+				Csr: []byte("csr"),
+			},
+			resp: &jvspb.CreateCertificateResponse{CertificateChain: "chain"},
+			wantLogs: []*audit.AuditLog{{
+				ServiceName:        "abcxyz.jvs.JVSService",
+				MethodName:         "abcxyz.jvs.JVSService.CreateCertificate",
+				ResourceName:       caKeyName,
+				AuthenticationInfo: principal,
+				Metadata: map[string]any{
+					"decision":   audit.DecisionAllowed,
+					"categories": []string{"explanation"},
+					"audiences":  []string(nil),
+				},
+			}},
+		},
+		{
+			name:   "certificate_action",
+			method: "/abcxyz.jvs.CertificateActionService/CertificateAction",
+			req: &jvspb.CertificateActionRequest{
+				Actions: []*jvspb.Action{
+					{Version: version, Action: jvspb.Action_ROTATE},
+					{Version: version, Action: jvspb.Action_FORCE_DISABLE},
+				},
+			},
+			resp: &jvspb.CertificateActionResponse{
+				Results: []*jvspb.ActionResult{
+					{Version: version, Success: true},
+					{Version: version, Error: "version is primary"},
+				},
+			},
+			wantLogs: []*audit.AuditLog{
+				{
+					ServiceName:        "abcxyz.jvs.CertificateActionService",
+					MethodName:         "abcxyz.jvs.CertificateActionService.CertificateAction",
+					ResourceName:       version,
+					AuthenticationInfo: principal,
+					Metadata: map[string]any{
+						"decision": audit.DecisionAllowed,
+						"action":   "ROTATE",
+						"dry_run":  false,
+					},
+				},
+				{
+					ServiceName:        "abcxyz.jvs.CertificateActionService",
+					MethodName:         "abcxyz.jvs.CertificateActionService.CertificateAction",
+					ResourceName:       version,
+					AuthenticationInfo: principal,
+					Status: &audit.Status{
+						Code:    int(codes.FailedPrecondition),
+						Message: "version is primary",
+					},
+					Metadata: map[string]any{
+						"decision": audit.DecisionDenied,
+						"action":   "FORCE_DISABLE",
+						"dry_run":  false,
+					},
+				},
+			},
+		},
+		{
+			name:   "not_audited",
+			method: "/abcxyz.jvs.JVSService/ListCategories",
+			req:    &jvspb.ListCategoriesRequest{},
+			resp:   &jvspb.ListCategoriesResponse{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &fakeAuditWriter{}
+			auditor := NewAuditInterceptor(writer, keyName).WithCertificateAuthorityKey(caKeyName)
+
+			resp, err := auditor.UnaryInterceptor(ctx, tc.req, &grpc.UnaryServerInfo{FullMethod: tc.method},
+				func(ctx context.Context, req any) (any, error) {
+					return tc.resp, tc.err
+				})
+			if resp != tc.resp {
+				t.Errorf("expected response %v to be %v", resp, tc.resp)
+			}
+			if err != tc.err { //nolint:errorlint // Want the same error.
+				t.Errorf("expected error %v to be %v", err, tc.err)
+			}
+
+			if diff := cmp.Diff(tc.wantLogs, writer.logs); diff != "" {
+				t.Errorf("audit logs (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
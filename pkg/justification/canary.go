@@ -0,0 +1,198 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// CanaryCategory is the reserved justification category of canary tokens. No
+// validator may be registered under it, so canary tokens can't be requested.
+const CanaryCategory = "jvs-canary"
+
+// CanarySubject is the subject and audience of canary tokens. Verifiers never
+// expect it, so canary tokens aren't accepted anywhere.
+const CanarySubject = "jvs-canary"
+
+// CanaryProbeLogMessage is the message of the log of each canary probe. Its
+// success and latencies can be turned into log-based metrics.
+const CanaryProbeLogMessage = "canary probe"
+
+// canaryTTL is the lifetime of canary tokens. They are validated right after
+// they are minted, so it only needs to cover the probe.
+const canaryTTL = time.Minute
+
+// The stages of a canary probe.
+const (
+	canaryStageSigning    = "signing"
+	canaryStageValidation = "validation"
+)
+
+// Canary periodically mints a token with the reserved canary category, and
+// validates it against the JWKS the server publishes, to monitor the whole
+// issuance and verification path.
+type Canary struct {
+	processor *Processor
+	cfg       *config.CanaryConfig
+}
+
+// NewCanary creates a canary that mints tokens with the processor.
+func NewCanary(p *Processor, cfg *config.CanaryConfig) *Canary {
+	return &Canary{
+		processor: p,
+		cfg:       cfg,
+	}
+}
+
+// Run probes every interval until the context is done.
+func (c *Canary) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Failures are logged by the probe.
+		_ = c.Probe(ctx)
+	}
+}
+
+// Probe mints a canary token and validates it against the JWKS endpoint. It
+// logs the result and latencies of each stage, and returns an error if the
+// probe failed.
+func (c *Canary) Probe(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	token, kid, err := c.processor.createCanaryToken(ctx)
+	signed := time.Now()
+	if err != nil {
+		logger.ErrorContext(ctx, CanaryProbeLogMessage,
+			"success", false,
+			"stage", canaryStageSigning,
+			"sign_ms", signed.Sub(started).Milliseconds(),
+			"error", err)
+		return fmt.Errorf("failed to mint canary token: %w", err)
+	}
+
+	err = c.validate(ctx, token)
+	validated := time.Now()
+	if err != nil {
+		logger.ErrorContext(ctx, CanaryProbeLogMessage,
+			"success", false,
+			"stage", canaryStageValidation,
+			"kid", kid,
+			"sign_ms", signed.Sub(started).Milliseconds(),
+			"validate_ms", validated.Sub(signed).Milliseconds(),
+			"error", err)
+		return fmt.Errorf("failed to validate canary token: %w", err)
+	}
+
+	logger.InfoContext(ctx, CanaryProbeLogMessage,
+		"success", true,
+		"kid", kid,
+		"sign_ms", signed.Sub(started).Milliseconds(),
+		"validate_ms", validated.Sub(signed).Milliseconds(),
+		"total_ms", validated.Sub(started).Milliseconds())
+	return nil
+}
+
+// validate validates the token against the keys currently served at the JWKS
+// endpoint. A new client is created for every probe, so a stale JWKS isn't
+// hidden by a cache.
+func (c *Canary) validate(ctx context.Context, token []byte) error {
+	cfg := &jvspb.Config{
+		JWKSEndpoint:    c.cfg.JWKSEndpoint,
+		CacheTimeout:    5 * time.Minute,
+		ClaimsNamespace: c.processor.config.ClaimsNamespace,
+	}
+	if typ := c.processor.config.TokenType; typ != "" {
+		cfg.AcceptedTokenTypes = []string{typ}
+	}
+	client, err := jvspb.NewClient(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create jvs client: %w", err)
+	}
+	if _, err := client.ValidateJWT(ctx, string(token), CanarySubject); err != nil {
+		return err //nolint:wrapcheck // Wrapped by the caller.
+	}
+	return nil
+}
+
+// createCanaryToken mints a signed canary token, and returns it with the ID of
+// the key that signed it. Canary tokens skip validation, quotas and auditing,
+// and aren't recorded as issued.
+func (p *Processor) createCanaryToken(ctx context.Context) (_ []byte, _ string, retErr error) {
+	req := &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{
+				Category: CanaryCategory,
+				Value:    "synthetic probe",
+			},
+		},
+		Subject:   CanarySubject,
+		Audiences: []string{CanarySubject},
+		Ttl:       durationpb.New(canaryTTL),
+	}
+
+	// Without a requestor, no groups are resolved for the token.
+	token, grants, err := p.createToken(ctx, "", req, time.Now().UTC())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create token: %w", err)
+	}
+	defer func() {
+		if retErr != nil {
+			p.revokeGrants(ctx, grants, token.JwtID(), "failed to sign canary token")
+		}
+	}()
+
+	signer, err := p.signer(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get token signer: %w", err)
+	}
+
+	headers, err := p.tokenHeaders(signer.id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var b []byte
+	if err := runWithContext(ctx, func() error {
+		var err error
+		b, err = jwt.Sign(token, jwt.WithKey(jwa.ES256, signer, jws.WithProtectedHeaders(headers)))
+		return err //nolint:wrapcheck // Wrapped below.
+	}); err != nil {
+		return nil, "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return b, signer.id, nil
+}
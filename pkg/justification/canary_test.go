@@ -0,0 +1,89 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/testutil"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestCanary_Probe(t *testing.T) {
+	t.Parallel()
+
+	issuer := testutil.NewIDTokenIssuer(t)
+	privateKey, endpoint := issuer.PrivateKey, issuer.JWKSEndpoint
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		signer   *ecdsa.PrivateKey
+		endpoint string
+		wantErr  string
+	}{
+		{
+			name:     "success",
+			signer:   privateKey,
+			endpoint: endpoint,
+		},
+		{
+			name:     "unpublished_key",
+			signer:   otherKey,
+			endpoint: endpoint,
+			wantErr:  "failed to validate canary token: failed to verify jwt",
+		},
+		{
+			name:     "unreachable_jwks",
+			signer:   privateKey,
+			endpoint: "http://127.0.0.1:0/.well-known/jwks",
+			wantErr:  "failed to validate canary token: failed to create jvs client",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+			}).WithLocalSigner(tc.signer, "test-key")
+
+			canary := NewCanary(p, &config.CanaryConfig{
+				Interval:     time.Minute,
+				JWKSEndpoint: tc.endpoint,
+				Timeout:      10 * time.Second,
+			})
+
+			err := canary.Probe(context.Background())
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
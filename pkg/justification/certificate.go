@@ -0,0 +1,207 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"os"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/sethvargo/go-gcpkms/pkg/gcpkms"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// CertificateAuthority issues short-lived client certificates.
+type CertificateAuthority struct {
+	cert   *x509.Certificate
+	signer crypto.Signer
+}
+
+// NewCertificateAuthority creates a certificate authority with the CA
+// certificate and its private key.
+func NewCertificateAuthority(cert *x509.Certificate, signer crypto.Signer) (*CertificateAuthority, error) {
+	if cert == nil {
+		return nil, fmt.Errorf("ca certificate cannot be nil")
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("certificate %q is not a ca", cert.Subject)
+	}
+
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return nil, fmt.Errorf("ca certificate does not match key")
+	}
+
+	return &CertificateAuthority{
+		cert:   cert,
+		signer: signer,
+	}, nil
+}
+
+// LoadCertificateAuthority creates a certificate authority that signs with the
+// KMS key version, using the PEM-encoded CA certificate at the path.
+func LoadCertificateAuthority(ctx context.Context, kms *kms.KeyManagementClient, keyVersion, certPath string) (*CertificateAuthority, error) {
+	b, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ca certificate: %w", err)
+	}
+	chain, err := jvspb.ParseCertificateChain(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ca certificate: %w", err)
+	}
+
+	signer, err := gcpkms.NewSigner(ctx, kms, keyVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ca signer: %w", err)
+	}
+
+	return NewCertificateAuthority(chain[0], signer)
+}
+
+// WithCertificateAuthority enables issuing client certificates signed by the
+// certificate authority.
+func (p *Processor) WithCertificateAuthority(ca *CertificateAuthority) *Processor {
+	p.ca = ca
+	return p
+}
+
+// CreateCertificate implements the create certificate API which issues a
+// short-lived client certificate for the key in the certificate signing
+// request if the provided justifications are valid. The certificate embeds
+// the same claims as a justification token. It returns the PEM-encoded
+// certificate, followed by the CA certificate.
+func (p *Processor) CreateCertificate(ctx context.Context, requestor string, req *jvspb.CreateCertificateRequest) ([]byte, error) {
+	now := time.Now().UTC()
+
+	logger := logging.FromContext(ctx)
+
+	if p.ca == nil {
+		return nil, status.Error(codes.Unimplemented, "certificate issuance is not enabled")
+	}
+
+	if err := p.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(req.GetCsr())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse certificate signing request: %s", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid certificate signing request signature: %s", err)
+	}
+
+	// Certificates are bound to the key in the CSR, they can't be bound to
+	// another key.
+	justReq := req.GetRequest()
+	if justReq.GetJwkThumbprint() != "" {
+		return nil, status.Error(codes.InvalidArgument, "failed to validate request: jwk thumbprint is not supported for certificates")
+	}
+
+	_, quotaKeys, err := p.validateRequest(ctx, requestor, justReq)
+	if err != nil {
+		return nil, err
+	}
+	var issued bool
+	defer func() {
+		if !issued {
+			p.refundQuotas(ctx, quotaKeys)
+		}
+	}()
+
+	token, grants, err := p.createToken(ctx, requestor, justReq, now)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create claims", "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to create claims: %s", err)
+	}
+	defer func() {
+		if !issued {
+			p.revokeGrants(ctx, grants, token.JwtID(), "failed to sign certificate")
+		}
+	}()
+
+	template, err := certificateTemplate(token)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to create certificate template", "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to create certificate: %s", err)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca.cert, csr.PublicKey, p.ca.signer)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to sign certificate", "error", err)
+		return nil, status.Error(codes.Internal, "failed to sign certificate")
+	}
+
+	var b bytes.Buffer
+	for _, cert := range [][]byte{der, p.ca.cert.Raw} {
+		if err := pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert}); err != nil {
+			logger.ErrorContext(ctx, "failed to encode certificate", "error", err)
+			return nil, status.Error(codes.Internal, "failed to encode certificate")
+		}
+	}
+
+	issued = true
+	p.recordIssued(ctx, &IssuedToken{
+		Requestor: requestor,
+		Request:   justReq,
+		Token:     token,
+		Entry:     p.auditEntry(requestor, token, justReq.GetJustifications()),
+	})
+
+	return b.Bytes(), nil
+}
+
+// certificateTemplate builds the template of a client certificate with the
+// validity and claims of the token.
+func certificateTemplate(token jwt.Token) (*x509.Certificate, error) {
+	id, err := uuid.Parse(token.JwtID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jti: %w", err)
+	}
+	ext, err := jvspb.NewClaimsExtension(token)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:    new(big.Int).SetBytes(id[:]),
+		Subject:         pkix.Name{CommonName: token.Subject()},
+		NotBefore:       token.NotBefore(),
+		NotAfter:        token.Expiration(),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		ExtraExtensions: []pkix.Extension{ext},
+	}
+	if addr, err := mail.ParseAddress(token.Subject()); err == nil && addr.Address == token.Subject() {
+		template.EmailAddresses = []string{addr.Address}
+	}
+	return template, nil
+}
@@ -0,0 +1,294 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestNewCertificateAuthority(t *testing.T) {
+	t.Parallel()
+
+	caKey, caCert := testCertificateAuthority(t, true)
+	_, notCACert := testCertificateAuthority(t, false)
+	otherKey, _ := testCertificateAuthority(t, true)
+
+	cases := []struct {
+		name    string
+		cert    *x509.Certificate
+		key     *ecdsa.PrivateKey
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cert: caCert,
+			key:  caKey,
+		},
+		{
+			name:    "nil_cert",
+			key:     caKey,
+			wantErr: "ca certificate cannot be nil",
+		},
+		{
+			name:    "not_ca",
+			cert:    notCACert,
+			key:     caKey,
+			wantErr: "is not a ca",
+		},
+		{
+			name:    "key_mismatch",
+			cert:    caCert,
+			key:     otherKey,
+			wantErr: "ca certificate does not match key",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewCertificateAuthority(tc.cert, tc.key)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestCreateCertificate(t *testing.T) {
+	t.Parallel()
+
+	caKey, caCert := testCertificateAuthority(t, true)
+	ca, err := NewCertificateAuthority(caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	justs := []*jvspb.Justification{
+		{Category: "explanation", Value: "debugging"},
+	}
+
+	cases := []struct {
+		name      string
+		ca        *CertificateAuthority
+		req       *jvspb.CreateCertificateRequest
+		wantEmail []string
+		wantTTL   time.Duration
+		wantErr   string
+	}{
+		{
+			name: "happy_path",
+			ca:   ca,
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{
+					Justifications: justs,
+					Ttl:            durationpb.New(10 * time.Minute),
+				},
+				Csr: csr,
+			},
+			wantEmail: []string{"me@example.com"},
+			wantTTL:   10 * time.Minute,
+		},
+		{
+			name: "non_email_subject",
+			ca:   ca,
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{
+					Justifications: justs,
+					Subject:        "service-a",
+				},
+				Csr: csr,
+			},
+			wantTTL: 15 * time.Minute,
+		},
+		{
+			name: "disabled",
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{
+					Justifications: justs,
+				},
+				Csr: csr,
+			},
+			wantErr: "certificate issuance is not enabled",
+		},
+		{
+			name: "invalid_csr",
+			ca:   ca,
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{
+					Justifications: justs,
+				},
+				Csr: []byte("not a csr"),
+			},
+			wantErr: "failed to parse certificate signing request",
+		},
+		{
+			name: "jwk_thumbprint",
+			ca:   ca,
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{
+					Justifications: justs,
+					JwkThumbprint:  "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+				},
+				Csr: csr,
+			},
+			wantErr: "jwk thumbprint is not supported for certificates",
+		},
+		{
+			name: "no_justifications",
+			ca:   ca,
+			req: &jvspb.CreateCertificateRequest{
+				Request: &jvspb.CreateJustificationRequest{},
+				Csr:     csr,
+			},
+			wantErr: "no justifications specified",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+			now := time.Now().UTC()
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+			})
+			if tc.ca != nil {
+				processor.WithCertificateAuthority(tc.ca)
+			}
+
+			got, gotErr := processor.CreateCertificate(ctx, "me@example.com", tc.req)
+			if diff := pkgtestutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if gotErr != nil {
+				return
+			}
+
+			chain, err := jvspb.ParseCertificateChain(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(chain), 2; got != want {
+				t.Fatalf("expected %d certificates to be %d", got, want)
+			}
+			cert := chain[0]
+			if !chain[1].Equal(caCert) {
+				t.Errorf("expected the chain to end with the ca certificate")
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(caCert)
+			if _, err := cert.Verify(x509.VerifyOptions{
+				Roots:       roots,
+				KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+				CurrentTime: now.Add(time.Minute),
+			}); err != nil {
+				t.Errorf("failed to verify certificate: %s", err)
+			}
+			if !clientKey.PublicKey.Equal(cert.PublicKey) {
+				t.Errorf("expected the certificate to be for the csr key")
+			}
+			if diff := cmp.Diff(tc.wantEmail, cert.EmailAddresses); diff != "" {
+				t.Errorf("email addresses (-want, +got):\n%s", diff)
+			}
+			if got, want := cert.NotAfter.Sub(cert.NotBefore), tc.wantTTL; got != want {
+				t.Errorf("validity: expected %s to be %s", got, want)
+			}
+
+			claims, err := jvspb.CertificateClaims(cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := claims.Subject(), cert.Subject.CommonName; got != want {
+				t.Errorf("subject: expected %q to be %q", got, want)
+			}
+			requestor, err := jvspb.GetRequestor(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := requestor, "me@example.com"; got != want {
+				t.Errorf("requestor: expected %q to be %q", got, want)
+			}
+			gotJusts, err := jvspb.GetJustifications(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(justs, gotJusts, protocmp.Transform()); diff != "" {
+				t.Errorf("justifications (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testCertificateAuthority creates a self-signed certificate and its key.
+func testCertificateAuthority(tb testing.TB, isCA bool) (*ecdsa.PrivateKey, *x509.Certificate) {
+	tb.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return key, cert
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// setCompatClaims sets the claims of the compatibility profile on the token:
+// the claim aliases, and the flattened justifications. They are set last, so
+// aliases duplicate the final value of their claim.
+func (p *Processor) setCompatClaims(token jwt.Token, justs []*jvspb.Justification) error {
+	for _, alias := range slices.Sorted(maps.Keys(p.claimAliases)) {
+		claim := p.claimAliases[alias]
+		v, ok := token.Get(claim)
+		if !ok {
+			continue
+		}
+		if err := token.Set(alias, v); err != nil {
+			return fmt.Errorf("failed to set claim alias %s of %s on jwt: %w", alias, claim, err)
+		}
+	}
+
+	if name := p.config.FlatJustificationsClaim; name != "" {
+		if err := token.Set(name, flattenJustifications(justs)); err != nil {
+			return fmt.Errorf("failed to set flat justifications on jwt: %w", err)
+		}
+	}
+	return nil
+}
+
+// flattenJustifications joins the justifications into a string of
+// "category:value" pairs, separated by commas, e.g.
+// "jira:ABC-123, explanation:debugging".
+func flattenJustifications(justs []*jvspb.Justification) string {
+	pairs := make([]string, 0, len(justs))
+	for _, j := range justs {
+		pairs = append(pairs, j.GetCategory()+":"+j.GetValue())
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestCreateToken_compatClaims(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	justs := []any{
+		map[string]any{"category": "jira", "value": "ABC-123"},
+		map[string]any{"category": "explanation", "value": "debugging"},
+	}
+
+	cases := []struct {
+		name        string
+		aliases     map[string]string
+		flatClaim   string
+		namespace   string
+		wantClaims  map[string]any
+		wantMissing []string
+	}{
+		{
+			name: "no_profile",
+			wantMissing: []string{
+				"justifications",
+				"justification",
+			},
+		},
+		{
+			name: "aliases",
+			aliases: map[string]string{
+				"justifications": "justs",
+				"requestor":      "req",
+				"member_of":      "groups",
+			},
+			wantClaims: map[string]any{
+				"justs":          justs,
+				"justifications": justs,
+				"requestor":      "me@example.com",
+			},
+			wantMissing: []string{
+				"member_of",
+			},
+		},
+		{
+			name:      "flat_justifications",
+			flatClaim: "justification",
+			wantClaims: map[string]any{
+				"justs":         justs,
+				"justification": "jira:ABC-123, explanation:debugging",
+			},
+		},
+		{
+			name:      "claims_namespace",
+			namespace: "https://jvs.example.com/",
+			aliases: map[string]string{
+				"justifications": "justs",
+			},
+			wantClaims: map[string]any{
+				"https://jvs.example.com/justs":   justs,
+				"https://jvs.example.com/req":     "me@example.com",
+				"https://jvs.example.com/jvs_ver": float64(1),
+				"justifications":                  justs,
+				"sub":                             "me@example.com",
+			},
+			wantMissing: []string{
+				"justs",
+				"req",
+				"jvs_ver",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout:      5 * time.Minute,
+				Issuer:                  "test-iss",
+				DefaultTTL:              15 * time.Minute,
+				MaxTTL:                  1 * time.Hour,
+				MaxAnnotationSize:       100,
+				FlatJustificationsClaim: tc.flatClaim,
+				ClaimVersion:            jvspb.ClaimVersion1,
+				ClaimsNamespace:         tc.namespace,
+			}).WithClaimAliases(tc.aliases)
+
+			token, _, err := processor.createToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "jira", Value: "ABC-123"},
+					{Category: "explanation", Value: "debugging"},
+				},
+			}, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := json.Marshal(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims map[string]any
+			if err := json.Unmarshal(b, &claims); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range tc.wantClaims {
+				if diff := cmp.Diff(want, claims[name]); diff != "" {
+					t.Errorf("claim %s: diff (-want, +got):\n%s", name, diff)
+				}
+			}
+			for _, name := range tc.wantMissing {
+				if got, ok := claims[name]; ok {
+					t.Errorf("expected no claim %s, got %v", name, got)
+				}
+			}
+		})
+	}
+}
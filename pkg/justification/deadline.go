@@ -0,0 +1,108 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// RequestStagesLogMessage is the message of the log of how long each stage of
+// a justification request took. Its stage durations can be turned into
+// log-based distribution metrics, to get a histogram per stage.
+const RequestStagesLogMessage = "justification request stages"
+
+// The stages of a justification request.
+const (
+	// stageValidation checks the deny list and validates the justifications,
+	// including calls to plugins.
+	stageValidation = "validation"
+
+	// stageClaims builds the claims of the token, including resolving the
+	// requestor's groups.
+	stageClaims = "claims"
+
+	// stageSigner gets the signer of the primary key version, from KMS unless
+	// it is cached.
+	stageSigner = "signer"
+
+	// stageSigning signs the token, and the receipt if receipts are enabled.
+	stageSigning = "signing"
+)
+
+// requestStages times the stages of a justification request.
+type requestStages struct {
+	current   string
+	started   time.Time
+	durations []any
+}
+
+// start ends the current stage, if any, and starts the next one.
+func (s *requestStages) start(stage string) {
+	s.end()
+	s.current = stage
+	s.started = time.Now()
+}
+
+// end ends the current stage.
+func (s *requestStages) end() {
+	if s.current == "" {
+		return
+	}
+	s.durations = append(s.durations, slog.Int64(s.current+"_ms", time.Since(s.started).Milliseconds()))
+	s.current = ""
+}
+
+// log logs the durations of the stages, and the stage the request timed out
+// in, if it did.
+func (s *requestStages) log(ctx context.Context, timedOut string) {
+	s.end()
+	logging.FromContext(ctx).InfoContext(ctx, RequestStagesLogMessage,
+		slog.Group("stage_durations", s.durations...),
+		"timed_out_stage", timedOut)
+}
+
+// deadlineError returns a DEADLINE_EXCEEDED error naming the stage if the
+// context's deadline expired, or nil if it didn't.
+func deadlineError(ctx context.Context, stage string) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	return status.Errorf(codes.DeadlineExceeded, "request deadline exceeded during %s", stage)
+}
+
+// runWithContext runs fn, which can't be canceled, and returns the context's
+// error if it is done first. fn then keeps running in the background until it
+// returns, and its result is dropped.
+func runWithContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck // Checked with errors.Is by callers.
+	}
+}
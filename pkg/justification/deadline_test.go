@@ -0,0 +1,175 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/groups"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+// slowValidator blocks until the request is canceled.
+type slowValidator struct {
+	mockValidator
+}
+
+func (v *slowValidator) Validate(ctx context.Context, _ *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	<-ctx.Done()
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+// slowGroups blocks resolving groups until the request is canceled.
+type slowGroups struct{}
+
+func (slowGroups) Groups(ctx context.Context, _ string) ([]string, error) {
+	<-ctx.Done()
+	return nil, ctx.Err() //nolint:wrapcheck // Want the context error.
+}
+
+// slowKeyManager blocks getting the primary version until the request is
+// canceled.
+type slowKeyManager struct {
+	jvscrypto.KeyManager
+}
+
+func (m *slowKeyManager) GetPrimary(ctx context.Context, _ string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err() //nolint:wrapcheck // Want the context error.
+}
+
+// slowSigner signs after a delay, and can't be canceled.
+type slowSigner struct {
+	crypto.Signer
+	delay time.Duration
+}
+
+func (s *slowSigner) Sign(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	time.Sleep(s.delay)
+	return s.Signer.Sign(r, digest, opts) //nolint:wrapcheck // Want the signer's error.
+}
+
+func TestCreateToken_requestDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		deadline   time.Duration
+		validator  jvspb.Validator
+		groups     groups.Resolver
+		keyManager jvscrypto.KeyManager
+		signDelay  time.Duration
+		wantErr    string
+	}{
+		{
+			name:     "within_deadline",
+			deadline: time.Minute,
+		},
+		{
+			name:      "validation",
+			deadline:  50 * time.Millisecond,
+			validator: &slowValidator{},
+			wantErr:   "request deadline exceeded during validation",
+		},
+		{
+			name:     "claims",
+			deadline: 50 * time.Millisecond,
+			groups:   slowGroups{},
+			wantErr:  "request deadline exceeded during claims",
+		},
+		{
+			name:       "signer",
+			deadline:   50 * time.Millisecond,
+			keyManager: &slowKeyManager{},
+			wantErr:    "request deadline exceeded during signer",
+		},
+		{
+			name:      "signing",
+			deadline:  50 * time.Millisecond,
+			signDelay: time.Second,
+			wantErr:   "request deadline exceeded during signing",
+		},
+		{
+			name:      "no_deadline",
+			signDelay: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			validator := tc.validator
+			if validator == nil {
+				validator = &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}}
+			}
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+				RequestDeadline:    tc.deadline,
+			}).WithValidators(map[string]jvspb.Validator{
+				"jira": validator,
+			})
+			if tc.groups != nil {
+				processor.WithGroupsResolver(tc.groups)
+			}
+			if tc.keyManager != nil {
+				processor.WithKeyManager(tc.keyManager)
+			} else {
+				processor.WithLocalSigner(&slowSigner{Signer: privateKey, delay: tc.signDelay}, "test-key")
+			}
+
+			_, err := processor.CreateToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "jira", Value: "JVS-123"},
+				},
+			})
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err == nil {
+				return
+			}
+			if got, want := status.Code(err), codes.DeadlineExceeded; got != want {
+				t.Errorf("expected code %s to be %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/decision"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// Stages of the steps of decision traces.
+	decisionStageDenyList     = "deny_list"
+	decisionStagePreIssueHook = "pre_issue_hook"
+	decisionStageValidation   = "validation"
+	decisionStageQuota        = "quota"
+
+	// DecisionTraceLogMessage is logged with the request ID of every recorded
+	// decision trace.
+	DecisionTraceLogMessage = "recorded decision trace"
+)
+
+// errDecisionTracesDisabled is returned when decision traces are not enabled.
+var errDecisionTracesDisabled = errors.New("decision traces are not enabled")
+
+// WithDecisionTraces records the trace of every denied token request in the
+// store: the checks it went through, and which one denied it. The error of
+// the request has its request ID, to look the trace up with
+// [Processor.DecisionTrace].
+func (p *Processor) WithDecisionTraces(s decision.Store) *Processor {
+	p.decisionTraces = s
+	return p
+}
+
+// DecisionTrace returns the trace of the denied request with the ID.
+func (p *Processor) DecisionTrace(ctx context.Context, requestID string) (*jvspb.DecisionTrace, error) {
+	if p.decisionTraces == nil {
+		return nil, errDecisionTracesDisabled
+	}
+	return p.decisionTraces.Get(ctx, requestID) //nolint:wrapcheck // Want passthrough
+}
+
+// decisionRecorder collects the steps of a token request.
+type decisionRecorder struct {
+	steps []*jvspb.DecisionStep
+}
+
+// decisionRecorderKey is the context key of the [decisionRecorder] of the
+// request.
+type decisionRecorderKey struct{}
+
+// withDecisionRecorder returns a context steps of the request are recorded
+// in.
+func withDecisionRecorder(ctx context.Context) (context.Context, *decisionRecorder) {
+	rec := &decisionRecorder{}
+	return context.WithValue(ctx, decisionRecorderKey{}, rec), rec
+}
+
+// recordDecisionStep records that the request went through the check, which
+// denied it if err is not nil. It does nothing unless decision traces are
+// enabled.
+func recordDecisionStep(ctx context.Context, stage, rule string, err error) {
+	rec, _ := ctx.Value(decisionRecorderKey{}).(*decisionRecorder)
+	if rec == nil {
+		return
+	}
+
+	step := &jvspb.DecisionStep{
+		Stage: stage,
+		Rule:  rule,
+	}
+	if err != nil {
+		step.Denied = true
+		step.Detail = err.Error()
+		if st, ok := status.FromError(err); ok {
+			step.Detail = st.Message()
+		}
+	}
+	rec.steps = append(rec.steps, step)
+}
+
+// recordDecision stores the trace of the denied request, and returns its error
+// with the request ID in its message and details. If the trace can't be
+// stored, the error is returned as is.
+func (p *Processor) recordDecision(ctx context.Context, rec *decisionRecorder, requestor string, req *jvspb.CreateJustificationRequest, err error) error {
+	logger := logging.FromContext(ctx)
+
+	st := status.Convert(err)
+	trace := &jvspb.DecisionTrace{
+		RequestId:   decision.NewRequestID(),
+		Time:        timestamppb.New(time.Now().UTC()),
+		Requestor:   requestor,
+		Categories:  make([]string, 0, len(req.GetJustifications())),
+		InputHashes: inputHashes(req),
+		Steps:       rec.steps,
+		Code:        st.Code().String(),
+		Message:     st.Message(),
+	}
+	for _, j := range req.GetJustifications() {
+		trace.Categories = append(trace.Categories, j.GetCategory())
+	}
+
+	if perr := p.decisionTraces.Put(ctx, trace); perr != nil {
+		logger.ErrorContext(ctx, "failed to record decision trace", "error", perr)
+		return err
+	}
+	logger.InfoContext(ctx, DecisionTraceLogMessage,
+		"request_id", trace.GetRequestId(),
+		"requestor", requestor,
+		"code", trace.GetCode())
+
+	withID := st.Proto()
+	withID.Message = fmt.Sprintf("%s (request ID %s)", st.Message(), trace.GetRequestId())
+	detailed, derr := status.FromProto(withID).WithDetails(&errdetails.RequestInfo{
+		RequestId: trace.GetRequestId(),
+	})
+	if derr != nil {
+		return status.FromProto(withID).Err()
+	}
+	return detailed.Err()
+}
+
+// inputHashes returns the hashes of the inputs of the request that may be
+// sensitive.
+func inputHashes(req *jvspb.CreateJustificationRequest) map[string]string {
+	hashes := make(map[string]string)
+	if subject := req.GetSubject(); subject != "" {
+		hashes["subject"] = decision.HashInput(subject)
+	}
+	for i, j := range req.GetJustifications() {
+		hashes[fmt.Sprintf("justifications[%d].value", i)] = decision.HashInput(j.GetValue())
+	}
+	if aud := req.GetAudiences(); len(aud) > 0 {
+		hashes["audiences"] = decision.HashInput(strings.Join(aud, ","))
+	}
+	if services := req.GetAudienceServices(); len(services) > 0 {
+		hashes["audience_services"] = decision.HashInput(strings.Join(services, ","))
+	}
+	return hashes
+}
+
+// requestIDFromError returns the request ID in the details of the error, or
+// "" if it has none.
+func requestIDFromError(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.RequestInfo); ok {
+			return info.GetRequestId()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/decision"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestProcessor_decisionTraces(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name      string
+		hooks     []PreIssueHook
+		req       *jvspb.CreateJustificationRequest
+		wantCode  codes.Code
+		wantTrace *jvspb.DecisionTrace
+	}{
+		{
+			name: "allowed",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "explanation", Value: "debugging"}},
+			},
+		},
+		{
+			name: "denied_by_validator",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+					{Category: "jira", Value: "ABC-123"},
+				},
+				Subject: "you@example.com",
+			},
+			wantCode: codes.InvalidArgument,
+			wantTrace: &jvspb.DecisionTrace{
+				Requestor:  "me@example.com",
+				Categories: []string{"explanation", "jira"},
+				InputHashes: map[string]string{
+					"subject":                 decision.HashInput("you@example.com"),
+					"justifications[0].value": decision.HashInput("debugging"),
+					"justifications[1].value": decision.HashInput("ABC-123"),
+				},
+				Steps: []*jvspb.DecisionStep{
+					{Stage: "deny_list", Rule: "deny_list"},
+					{Stage: "validation", Rule: "start_time"},
+					{Stage: "validation", Rule: "audiences"},
+					{Stage: "validation", Rule: "validator:explanation"},
+					{
+						Stage:  "validation",
+						Rule:   "validator:jira",
+						Denied: true,
+						Detail: "failed validation criteria with error [unknown ticket] and warning []",
+					},
+					{Stage: "validation", Rule: "size_limits"},
+				},
+				Code:    "InvalidArgument",
+				Message: "failed to validate request: failed validation criteria with error [unknown ticket] and warning []",
+			},
+		},
+		{
+			name: "denied_by_hook",
+			hooks: []PreIssueHook{
+				func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+					return nil
+				},
+				func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+					return errors.New("breakglass requires oncall")
+				},
+			},
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "explanation", Value: "debugging"}},
+				Audiences:      []string{"https://a.example.com", "https://b.example.com"},
+			},
+			wantCode: codes.PermissionDenied,
+			wantTrace: &jvspb.DecisionTrace{
+				Requestor:  "me@example.com",
+				Categories: []string{"explanation"},
+				InputHashes: map[string]string{
+					"justifications[0].value": decision.HashInput("debugging"),
+					"audiences":               decision.HashInput("https://a.example.com,https://b.example.com"),
+				},
+				Steps: []*jvspb.DecisionStep{
+					{Stage: "deny_list", Rule: "deny_list"},
+					{Stage: "pre_issue_hook", Rule: "hook:0"},
+					{Stage: "pre_issue_hook", Rule: "hook:1", Denied: true, Detail: "breakglass requires oncall"},
+				},
+				Code:    "PermissionDenied",
+				Message: "breakglass requires oncall",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  1000,
+			}).WithValidators(map[string]jvspb.Validator{
+				"jira": &mockValidator{
+					resp: &jvspb.ValidateJustificationResponse{Error: []string{"unknown ticket"}},
+				},
+			}).WithLocalSigner(signingKey, "test-key").
+				WithPreIssueHooks(tc.hooks...).
+				WithDecisionTraces(decision.NewMemoryStore(time.Hour))
+
+			_, err = p.CreateJustification(ctx, "me@example.com", tc.req)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Fatalf("expected code %s to be %s: %v", got, want, err)
+			}
+			if tc.wantTrace == nil {
+				return
+			}
+
+			id := requestIDFromError(err)
+			if id == "" {
+				t.Fatalf("expected %v to have a request id", err)
+			}
+			if got, want := status.Convert(err).Message(), "(request ID "+id+")"; !strings.HasSuffix(got, want) {
+				t.Errorf("expected message %q to end with %q", got, want)
+			}
+
+			got, err := p.DecisionTrace(ctx, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantTrace, got, protocmp.Transform(),
+				protocmp.IgnoreFields(&jvspb.DecisionTrace{}, "request_id", "time")); diff != "" {
+				t.Errorf("trace (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessor_decisionTracesDisabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	p := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	})
+
+	_, err := p.CreateJustification(ctx, "me@example.com", &jvspb.CreateJustificationRequest{})
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Fatalf("expected code %s to be %s: %v", got, want, err)
+	}
+	if id := requestIDFromError(err); id != "" {
+		t.Errorf("expected no request id, got %q", id)
+	}
+	if _, err := p.DecisionTrace(ctx, decision.NewRequestID()); !errors.Is(err, errDecisionTracesDisabled) {
+		t.Errorf("expected %v to be %v", err, errDecisionTracesDisabled)
+	}
+}
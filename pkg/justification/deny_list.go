@@ -0,0 +1,220 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// ReasonPrincipalDenied is the reason of the [errdetails.ErrorInfo] of
+	// requests rejected because the requestor or subject is on the deny list.
+	ReasonPrincipalDenied = "PRINCIPAL_DENIED"
+
+	// errorDomain is the domain of the errors of the JVS.
+	errorDomain = "jvs.abcxyz.dev"
+)
+
+// DenyList is a list of principals who may not mint tokens or be named as
+// their subjects, e.g. terminated employees or compromised service accounts.
+// It is loaded from a file with a principal per line, where blank lines and
+// lines starting with "#" are ignored. Principals are matched case
+// insensitively.
+type DenyList struct {
+	path string
+
+	// addMu serializes writes to the file by Add.
+	addMu sync.Mutex
+
+	mu         sync.RWMutex
+	contents   []byte
+	principals map[string]struct{}
+}
+
+// LoadDenyList loads the deny list in the file.
+func LoadDenyList(path string) (*DenyList, error) {
+	d := &DenyList{path: path}
+	if _, err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload reloads the deny list from its file, and reports whether it changed.
+// If the file can't be read, the previous list stays in effect.
+func (d *DenyList) Reload() (bool, error) {
+	b, err := os.ReadFile(d.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read deny list: %w", err)
+	}
+
+	d.mu.RLock()
+	unchanged := d.principals != nil && bytes.Equal(b, d.contents)
+	d.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	principals := make(map[string]struct{})
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		principals[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to parse deny list: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.contents = b
+	d.principals = principals
+	return true, nil
+}
+
+// Add appends the principal to the file of the deny list, after a comment
+// line, and reloads it. It reports whether the principal was already denied,
+// in which case the file is left as is.
+func (d *DenyList) Add(principal, comment string) (bool, error) {
+	d.addMu.Lock()
+	defer d.addMu.Unlock()
+
+	if d.Denied(principal) {
+		return true, nil
+	}
+
+	var b bytes.Buffer
+	d.mu.RLock()
+	if len(d.contents) > 0 && !bytes.HasSuffix(d.contents, []byte("\n")) {
+		b.WriteString("\n")
+	}
+	d.mu.RUnlock()
+	// Keep the comment on one line, so it can't add principals.
+	fmt.Fprintf(&b, "# %s\n%s\n", strings.Join(strings.Fields(comment), " "), strings.TrimSpace(principal))
+
+	f, err := os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to open deny list: %w", err)
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return false, fmt.Errorf("failed to write deny list: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("failed to close deny list: %w", err)
+	}
+
+	if _, err := d.Reload(); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Watch reloads the deny list every interval until the context is done, so
+// principals can be denied without restarting the server. Failing to reload
+// is logged, and the previous list stays in effect.
+func (d *DenyList) Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := d.Reload()
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to reload deny list", "path", d.path, "error", err)
+			continue
+		}
+		if changed {
+			logger.InfoContext(ctx, "reloaded deny list", "path", d.path, "principals", d.Len())
+		}
+	}
+}
+
+// Denied reports whether the principal is on the deny list.
+func (d *DenyList) Denied(principal string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.principals[strings.ToLower(strings.TrimSpace(principal))]
+	return ok
+}
+
+// Len returns the number of principals on the deny list.
+func (d *DenyList) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.principals)
+}
+
+// denied reports whether the principal is on the deny list or revoked.
+func (p *Processor) denied(principal string) bool {
+	if principal == "" {
+		return false
+	}
+	return (p.denyList != nil && p.denyList.Denied(principal)) ||
+		(p.revocations != nil && p.revocations.Revoked(principal))
+}
+
+// checkDenyList returns a PermissionDenied error if the requestor or subject
+// is on the deny list or revoked. The error details have the
+// [ReasonPrincipalDenied] reason, so audit logs can tell these denials apart.
+func (p *Processor) checkDenyList(ctx context.Context, requestor, subject string) error {
+	var role, principal string
+	switch {
+	case p.denied(requestor):
+		role, principal = "requestor", requestor
+	case p.denied(subject):
+		role, principal = "subject", subject
+	default:
+		return nil
+	}
+
+	logging.FromContext(ctx).WarnContext(ctx, "denied principal on the deny list",
+		"role", role,
+		"principal", principal,
+		"requestor", requestor)
+
+	st := status.Newf(codes.PermissionDenied, "%s %q is on the deny list", role, principal)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   ReasonPrincipalDenied,
+		Domain:   errorDomain,
+		Metadata: map[string]string{role: principal},
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
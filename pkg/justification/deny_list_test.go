@@ -0,0 +1,289 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/events"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func writeDenyList(tb testing.TB, path, contents string) {
+	tb.Helper()
+
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestDenyList(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "deny-list")
+	writeDenyList(t, path, `
+# Terminated
+  Former@Example.com
+
+compromised@project.iam.gserviceaccount.com
+`)
+
+	d, err := LoadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Len(), 2; got != want {
+		t.Errorf("expected %d principals to be %d", got, want)
+	}
+	for principal, want := range map[string]bool{
+		"former@example.com":                          true,
+		"FORMER@EXAMPLE.COM":                          true,
+		"compromised@project.iam.gserviceaccount.com": true,
+		"me@example.com":                              false,
+		"# Terminated":                                false,
+		"":                                            false,
+	} {
+		if got := d.Denied(principal); got != want {
+			t.Errorf("expected denied %q to be %t, got %t", principal, want, got)
+		}
+	}
+
+	// Reloading an unchanged file is a no-op.
+	changed, err := d.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("expected unchanged deny list")
+	}
+
+	writeDenyList(t, path, "me@example.com\n")
+	changed, err = d.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected changed deny list")
+	}
+	if !d.Denied("me@example.com") || d.Denied("former@example.com") {
+		t.Error("expected the reloaded deny list to be in effect")
+	}
+
+	// The previous list stays in effect if the file can't be read.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Reload(); err == nil {
+		t.Error("expected an error reloading a missing deny list")
+	}
+	if !d.Denied("me@example.com") {
+		t.Error("expected the previous deny list to stay in effect")
+	}
+}
+
+func TestDenyList_Add(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "deny-list")
+	writeDenyList(t, path, "former@example.com")
+
+	d, err := LoadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	already, err := d.Add("left@example.com", "Deprovisioned\nattacker@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if already {
+		t.Error("expected the principal not to be denied already")
+	}
+	if !d.Denied("left@example.com") {
+		t.Error("expected the added principal to be denied")
+	}
+	if d.Denied("attacker@example.com") {
+		t.Error("expected the comment not to add principals")
+	}
+
+	already, err = d.Add("Left@example.com", "Deprovisioned again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !already {
+		t.Error("expected the principal to be denied already")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "former@example.com\n# Deprovisioned attacker@example.com\nleft@example.com\n"; got != want {
+		t.Errorf("expected deny list %q to be %q", got, want)
+	}
+}
+
+func TestLoadDenyList_missing(t *testing.T) {
+	t.Parallel()
+
+	_, err := LoadDenyList(filepath.Join(t.TempDir(), "missing"))
+	if diff := pkgtestutil.DiffErrString(err, "failed to read deny list"); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestDenyList_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "deny-list")
+	writeDenyList(t, path, "")
+
+	d, err := LoadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Watch(ctx, 10*time.Millisecond)
+	}()
+
+	writeDenyList(t, path, "me@example.com\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for !d.Denied("me@example.com") {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the deny list to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestCreateToken_denyList(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	path := filepath.Join(t.TempDir(), "deny-list")
+	writeDenyList(t, path, "former@example.com\n")
+	denyList, err := LoadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		requestor string
+		subject   string
+		wantErr   string
+	}{
+		{
+			name:      "denied_requestor",
+			requestor: "former@example.com",
+			wantErr:   `requestor "former@example.com" is on the deny list`,
+		},
+		{
+			name:      "denied_subject",
+			requestor: "me@example.com",
+			subject:   "Former@example.com",
+			wantErr:   `subject "Former@example.com" is on the deny list`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			publisher := &fakePublisher{}
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+			}).WithDenyList(denyList).WithEventPublisher(publisher)
+
+			_, err := processor.CreateToken(ctx, tc.requestor, &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+				},
+				Subject: tc.subject,
+			})
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			st := status.Convert(err)
+			if got, want := st.Code(), codes.PermissionDenied; got != want {
+				t.Errorf("expected code %s to be %s", got, want)
+			}
+			if got, want := errorReason(err), ReasonPrincipalDenied; got != want {
+				t.Errorf("expected reason %q to be %q", got, want)
+			}
+			if details := st.Details(); len(details) != 1 {
+				t.Errorf("expected one error detail, got %v", details)
+			} else if info, ok := details[0].(*errdetails.ErrorInfo); !ok || info.GetDomain() != errorDomain {
+				t.Errorf("expected error info in the %s domain, got %v", errorDomain, details[0])
+			}
+
+			if got, want := len(publisher.events), 1; got != want {
+				t.Fatalf("expected %d events to be %d", got, want)
+			}
+			if got, want := publisher.events[0].Type, events.TypeTokenDenied; got != want {
+				t.Errorf("event type: expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSignPayload_denyList(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	path := filepath.Join(t.TempDir(), "deny-list")
+	writeDenyList(t, path, "former@example.com\n")
+	denyList, err := LoadDenyList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	}).WithDenyList(denyList)
+
+	_, err = processor.SignPayload(ctx, "former@example.com", make([]byte, 32))
+	if got, want := status.Code(err), codes.PermissionDenied; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+}
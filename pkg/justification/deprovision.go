@@ -0,0 +1,313 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/abcxyz/jvs/pkg/events"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// DeprovisionWebhookAdmin is the admin recorded for principals
+	// deprovisioned by the webhook.
+	DeprovisionWebhookAdmin = "deprovision-webhook"
+
+	// maxDeprovisionRequestSize is the maximum size of the body of webhook
+	// requests.
+	maxDeprovisionRequestSize = 64 * 1024
+
+	scimUserSchema  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+var (
+	// errDeprovisionDisabled is returned when neither the deny list nor
+	// revocation is enabled.
+	errDeprovisionDisabled = errors.New("neither the deny list nor revocation is enabled")
+
+	// errInvalidPrincipal is returned when the principal is empty, or can't be
+	// a line of the deny list.
+	errInvalidPrincipal = errors.New("principal must be non-empty, without spaces or control characters, and not start with #")
+)
+
+// validatePrincipal returns an error if the principal can't be deprovisioned.
+// Principals are the subjects and requestors of tokens, so they aren't only
+// email addresses, e.g. the subjects of exchanged tokens or service
+// identities. They are stored as a line of the deny list, so they can't have
+// spaces or start a comment.
+func validatePrincipal(principal string) error {
+	if principal == "" || strings.HasPrefix(principal, "#") ||
+		strings.ContainsFunc(principal, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return fmt.Errorf("%w: %q", errInvalidPrincipal, principal)
+	}
+	return nil
+}
+
+// Deprovision denies the principal new tokens, and revokes the tokens already
+// issued to it, in the deny list and the revocations, whichever are enabled.
+// It publishes a principal deprovisioned event, unless the principal was
+// already deprovisioned, which it reports.
+func (p *Processor) Deprovision(ctx context.Context, principal, admin, reason string) (bool, error) {
+	logger := logging.FromContext(ctx)
+
+	if p.denyList == nil && p.revocations == nil {
+		return false, errDeprovisionDisabled
+	}
+	if err := validatePrincipal(principal); err != nil {
+		return false, err
+	}
+
+	alreadyDeprovisioned := true
+	if p.denyList != nil {
+		comment := fmt.Sprintf("Deprovisioned %s by %s", time.Now().UTC().Format(time.RFC3339), admin)
+		if reason != "" {
+			comment += ": " + reason
+		}
+		alreadyDenied, err := p.denyList.Add(principal, comment)
+		if err != nil {
+			return false, fmt.Errorf("failed to update deny list: %w", err)
+		}
+		alreadyDeprovisioned = alreadyDeprovisioned && alreadyDenied
+	}
+	if p.revocations != nil {
+		alreadyRevoked, err := p.revocations.Revoke(ctx, principal, admin, reason)
+		if err != nil {
+			return false, fmt.Errorf("failed to revoke principal: %w", err)
+		}
+		alreadyDeprovisioned = alreadyDeprovisioned && alreadyRevoked
+	}
+
+	logger.InfoContext(ctx, PrincipalDeprovisionedLogMessage,
+		"admin", admin,
+		"principal", principal,
+		"reason", reason,
+		"already_denied", alreadyDeprovisioned)
+	if !alreadyDeprovisioned {
+		p.publish(ctx, events.New(events.TypePrincipalDeprovisioned, principal, &events.PrincipalDeprovisioned{
+			Principal: principal,
+			Admin:     admin,
+			Reason:    reason,
+		}))
+	}
+	return alreadyDeprovisioned, nil
+}
+
+// DeprovisionWebhook receives deprovisioning from an identity provider, and
+// deprovisions principals with [Processor.Deprovision]. Requests must have the
+// shared secret as a bearer token. It handles:
+//
+//   - POST /deprovision, with a JSON body with the "principal" and an
+//     optional "reason", for HR systems and scripts.
+//   - PUT and PATCH /scim/v2/Users/{id}, the SCIM 2.0 user updates identity
+//     providers send when a user is deactivated. Users whose "active" is set
+//     to false are deprovisioned, with their "userName" as the principal, or
+//     the id if the request has no user name. Other updates are acknowledged
+//     and ignored.
+type DeprovisionWebhook struct {
+	processor *Processor
+	secret    []byte
+	mux       *http.ServeMux
+}
+
+// NewDeprovisionWebhook creates a webhook deprovisioning principals of the
+// processor, for requests with the secret.
+func NewDeprovisionWebhook(p *Processor, secret string) *DeprovisionWebhook {
+	w := &DeprovisionWebhook{
+		processor: p,
+		secret:    []byte(secret),
+		mux:       http.NewServeMux(),
+	}
+	w.mux.HandleFunc("POST /deprovision", w.handleDeprovision)
+	w.mux.HandleFunc("PUT /scim/v2/Users/{id}", w.handleSCIMUser)
+	w.mux.HandleFunc("PATCH /scim/v2/Users/{id}", w.handleSCIMUser)
+	return w
+}
+
+// ServeHTTP implements [http.Handler].
+func (w *DeprovisionWebhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(w.secret) == 0 || subtle.ConstantTimeCompare([]byte(token), w.secret) != 1 {
+		logging.FromContext(r.Context()).WarnContext(r.Context(), "unauthenticated deprovision webhook request",
+			"method", r.Method,
+			"path", r.URL.Path)
+		http.Error(rw, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	r.Body = http.MaxBytesReader(rw, r.Body, maxDeprovisionRequestSize)
+	w.mux.ServeHTTP(rw, r)
+}
+
+// deprovisionRequest is the body of POST /deprovision.
+type deprovisionRequest struct {
+	Principal string `json:"principal"`
+	Reason    string `json:"reason"`
+}
+
+// deprovisionResponse is the response to POST /deprovision.
+type deprovisionResponse struct {
+	Principal     string `json:"principal"`
+	AlreadyDenied bool   `json:"already_denied"`
+}
+
+func (w *DeprovisionWebhook) handleDeprovision(rw http.ResponseWriter, r *http.Request) {
+	var req deprovisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	alreadyDenied, err := w.deprovision(r.Context(), req.Principal, req.Reason)
+	if err != nil {
+		http.Error(rw, err.Error(), deprovisionStatus(err))
+		return
+	}
+	writeJSON(rw, http.StatusOK, &deprovisionResponse{
+		Principal:     req.Principal,
+		AlreadyDenied: alreadyDenied,
+	})
+}
+
+// scimUser are the fields of a SCIM user resource the webhook needs.
+type scimUser struct {
+	Schemas  []string `json:"schemas,omitempty"`
+	ID       string   `json:"id,omitempty"`
+	UserName string   `json:"userName,omitempty"`
+	Active   *bool    `json:"active,omitempty"`
+}
+
+// scimPatch is a SCIM PATCH request. Only the operations replacing "active"
+// are read.
+type scimPatch struct {
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+func (w *DeprovisionWebhook) handleSCIMUser(rw http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeSCIMError(rw, http.StatusBadRequest, fmt.Sprintf("failed to read request: %s", err))
+		return
+	}
+
+	user := &scimUser{ID: id}
+	if r.Method == http.MethodPatch {
+		var patch scimPatch
+		if err := json.Unmarshal(b, &patch); err != nil {
+			writeSCIMError(rw, http.StatusBadRequest, fmt.Sprintf("invalid patch: %s", err))
+			return
+		}
+		for _, op := range patch.Operations {
+			if !strings.EqualFold(op.Op, "replace") {
+				continue
+			}
+			// The value is either the new "active", or a partial user.
+			if strings.EqualFold(op.Path, "active") {
+				var active bool
+				if err := json.Unmarshal(op.Value, &active); err != nil {
+					writeSCIMError(rw, http.StatusBadRequest, fmt.Sprintf("invalid active: %s", err))
+					return
+				}
+				user.Active = &active
+				continue
+			}
+			var partial scimUser
+			if op.Path == "" && json.Unmarshal(op.Value, &partial) == nil {
+				if partial.Active != nil {
+					user.Active = partial.Active
+				}
+				if partial.UserName != "" {
+					user.UserName = partial.UserName
+				}
+			}
+		}
+	} else if err := json.Unmarshal(b, user); err != nil {
+		writeSCIMError(rw, http.StatusBadRequest, fmt.Sprintf("invalid user: %s", err))
+		return
+	}
+	user.ID = id
+	user.Schemas = []string{scimUserSchema}
+
+	if user.Active == nil || *user.Active {
+		writeJSON(rw, http.StatusOK, user)
+		return
+	}
+
+	principal := user.UserName
+	if principal == "" {
+		principal = id
+	}
+	if _, err := w.deprovision(r.Context(), principal, "scim user deactivated"); err != nil {
+		writeSCIMError(rw, deprovisionStatus(err), err.Error())
+		return
+	}
+	writeJSON(rw, http.StatusOK, user)
+}
+
+func (w *DeprovisionWebhook) deprovision(ctx context.Context, principal, reason string) (bool, error) {
+	alreadyDenied, err := w.processor.Deprovision(ctx, strings.TrimSpace(principal), DeprovisionWebhookAdmin, reason)
+	if err != nil {
+		if deprovisionStatus(err) == http.StatusInternalServerError {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to deprovision principal", "error", err)
+			return false, errors.New("failed to deprovision principal")
+		}
+		return false, err
+	}
+	return alreadyDenied, nil
+}
+
+// deprovisionStatus returns the HTTP status of the error returned by
+// [Processor.Deprovision].
+func deprovisionStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidPrincipal):
+		return http.StatusBadRequest
+	case errors.Is(err, errDeprovisionDisabled):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	_ = json.NewEncoder(rw).Encode(v)
+}
+
+func writeSCIMError(rw http.ResponseWriter, code int, detail string) {
+	rw.Header().Set("Content-Type", "application/scim+json")
+	rw.WriteHeader(code)
+	_ = json.NewEncoder(rw).Encode(map[string]any{
+		"schemas": []string{scimErrorSchema},
+		"status":  fmt.Sprintf("%d", code),
+		"detail":  detail,
+	})
+}
@@ -0,0 +1,291 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/revocation"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const testWebhookSecret = "0123456789abcdef0123456789abcdef"
+
+// testProcessor creates a processor signing tokens with a local key.
+func testProcessor(tb testing.TB) *Processor {
+	tb.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithLocalSigner(privateKey, "test-key")
+}
+
+func TestDeprovisionWebhook(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		method        string
+		path          string
+		auth          string
+		body          string
+		wantCode      int
+		wantBody      string
+		wantRevoked   string
+		wantNoRevoked bool
+	}{
+		{
+			name:        "deprovision",
+			method:      http.MethodPost,
+			path:        "/deprovision",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"principal":"left@example.com","reason":"terminated"}`,
+			wantCode:    http.StatusOK,
+			wantBody:    `"already_denied":false`,
+			wantRevoked: "left@example.com",
+		},
+		{
+			name:          "no_secret",
+			method:        http.MethodPost,
+			path:          "/deprovision",
+			body:          `{"principal":"left@example.com"}`,
+			wantCode:      http.StatusUnauthorized,
+			wantNoRevoked: true,
+		},
+		{
+			name:          "wrong_secret",
+			method:        http.MethodPost,
+			path:          "/deprovision",
+			auth:          "Bearer " + strings.Repeat("x", len(testWebhookSecret)),
+			body:          `{"principal":"left@example.com"}`,
+			wantCode:      http.StatusUnauthorized,
+			wantNoRevoked: true,
+		},
+		{
+			name:        "exchanged_subject",
+			method:      http.MethodPost,
+			path:        "/deprovision",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"principal":"repo:abcxyz/jvs:ref:refs/heads/main"}`,
+			wantCode:    http.StatusOK,
+			wantRevoked: "repo:abcxyz/jvs:ref:refs/heads/main",
+		},
+		{
+			name:        "service_identity",
+			method:      http.MethodPost,
+			path:        "/deprovision",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"principal":"spiffe://example.com/ns/prod/sa/deployer"}`,
+			wantCode:    http.StatusOK,
+			wantRevoked: "spiffe://example.com/ns/prod/sa/deployer",
+		},
+		{
+			name:     "empty_principal",
+			method:   http.MethodPost,
+			path:     "/deprovision",
+			auth:     "Bearer " + testWebhookSecret,
+			body:     `{"principal":""}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: "principal must be non-empty",
+		},
+		{
+			name:     "invalid_principal",
+			method:   http.MethodPost,
+			path:     "/deprovision",
+			auth:     "Bearer " + testWebhookSecret,
+			body:     `{"principal":"left@example.com\nother@example.com"}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: "without spaces or control characters",
+		},
+		{
+			name:     "malformed",
+			method:   http.MethodPost,
+			path:     "/deprovision",
+			auth:     "Bearer " + testWebhookSecret,
+			body:     `{`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:        "scim_put_inactive",
+			method:      http.MethodPut,
+			path:        "/scim/v2/Users/2819c223",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"userName":"left@example.com","active":false}`,
+			wantCode:    http.StatusOK,
+			wantBody:    `"active":false`,
+			wantRevoked: "left@example.com",
+		},
+		{
+			name:          "scim_put_active",
+			method:        http.MethodPut,
+			path:          "/scim/v2/Users/2819c223",
+			auth:          "Bearer " + testWebhookSecret,
+			body:          `{"userName":"left@example.com","active":true}`,
+			wantCode:      http.StatusOK,
+			wantNoRevoked: true,
+		},
+		{
+			name:        "scim_patch_active_path",
+			method:      http.MethodPatch,
+			path:        "/scim/v2/Users/left@example.com",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"schemas":["urn:ietf:params:scim:api:messages:2.0:PatchOp"],"Operations":[{"op":"replace","path":"active","value":false}]}`,
+			wantCode:    http.StatusOK,
+			wantRevoked: "left@example.com",
+		},
+		{
+			name:        "scim_patch_value",
+			method:      http.MethodPatch,
+			path:        "/scim/v2/Users/2819c223",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"Operations":[{"op":"Replace","value":{"userName":"left@example.com","active":false}}]}`,
+			wantCode:    http.StatusOK,
+			wantRevoked: "left@example.com",
+		},
+		{
+			name:        "scim_patch_no_user_name",
+			method:      http.MethodPatch,
+			path:        "/scim/v2/Users/2819c223",
+			auth:        "Bearer " + testWebhookSecret,
+			body:        `{"Operations":[{"op":"replace","path":"active","value":false}]}`,
+			wantCode:    http.StatusOK,
+			wantRevoked: "2819c223",
+		},
+		{
+			name:     "scim_invalid_principal",
+			method:   http.MethodPut,
+			path:     "/scim/v2/Users/2819c223",
+			auth:     "Bearer " + testWebhookSecret,
+			body:     `{"userName":"Left Person","active":false}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: "urn:ietf:params:scim:api:messages:2.0:Error",
+		},
+		{
+			name:     "not_found",
+			method:   http.MethodGet,
+			path:     "/scim/v2/Users",
+			auth:     "Bearer " + testWebhookSecret,
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			revocations, err := revocation.Load(ctx, revocation.NewFileStore(filepath.Join(t.TempDir(), "revocations.json")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := testProcessor(t).WithRevocations(revocations)
+			webhook := NewDeprovisionWebhook(p, testWebhookSecret)
+
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)).WithContext(ctx)
+			if tc.auth != "" {
+				r.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			webhook.ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Errorf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if got, want := w.Body.String(), tc.wantBody; !strings.Contains(got, want) {
+				t.Errorf("expected body %q to contain %q", got, want)
+			}
+			if tc.wantRevoked != "" && !revocations.Revoked(tc.wantRevoked) {
+				t.Errorf("expected %q to be revoked", tc.wantRevoked)
+			}
+			if tc.wantNoRevoked && revocations.Len() != 0 {
+				t.Errorf("expected no revocations, got %d", revocations.Len())
+			}
+		})
+	}
+}
+
+func TestDeprovisionWebhook_disabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	webhook := NewDeprovisionWebhook(testProcessor(t), testWebhookSecret)
+	r := httptest.NewRequest(http.MethodPost, "/deprovision", strings.NewReader(`{"principal":"left@example.com"}`)).WithContext(ctx)
+	r.Header.Set("Authorization", "Bearer "+testWebhookSecret)
+	w := httptest.NewRecorder()
+	webhook.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusNotImplemented; got != want {
+		t.Errorf("expected status %d to be %d", got, want)
+	}
+}
+
+func TestProcessor_Deprovision_revokes(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	// Replicas sharing the revocations deny the principal, whichever
+	// deprovisioned it.
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	var replicas []*Processor
+	var lists []*revocation.List
+	for range 2 {
+		l, err := revocation.Load(ctx, revocation.NewFileStore(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists = append(lists, l)
+		replicas = append(replicas, testProcessor(t).WithRevocations(l))
+	}
+
+	if _, err := replicas[0].Deprovision(ctx, "left@example.com", "jvs-admin@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := lists[1].Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, p := range replicas {
+		if _, err := p.CreateToken(ctx, "left@example.com", &jvspb.CreateJustificationRequest{
+			Justifications: []*jvspb.Justification{{Category: "explanation", Value: "debugging"}},
+		}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected revoked principal to be denied by replica %d, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// encryptionKeysRefreshInterval is the interval between refreshes of
+// the encryption recipient JWKS.
+const encryptionKeysRefreshInterval = 15 * time.Minute
+
+// NewEncryptionKeys returns the recipient key set served at the given JWKS
+// endpoint, for use with [Processor.WithEncryptionKeys]. Keys are fetched
+// lazily and refreshed periodically, so recipients can rotate their keys.
+func NewEncryptionKeys(ctx context.Context, endpoint string) (jwk.Set, error) {
+	keys, err := jvspb.NewJWKSProvider(ctx, endpoint, jvspb.WithJWKSRefreshInterval(encryptionKeysRefreshInterval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encryption jwks provider: %w", err)
+	}
+	return keys, nil
+}
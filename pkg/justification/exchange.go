@@ -0,0 +1,119 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/idtoken"
+)
+
+// errNotAllowlisted is returned when a subject token doesn't match the token
+// exchange allowlist.
+var errNotAllowlisted = errors.New("subject token is not in the allowlist")
+
+const (
+	// exchangeKeysRefreshInterval is the interval between refreshes of the
+	// third-party JWKS.
+	exchangeKeysRefreshInterval = 15 * time.Minute
+
+	// exchangeMaxTokenAge is the maximum age of subject tokens, so a token of
+	// an identity provider issuing long-lived tokens can't be exchanged long
+	// after it was issued.
+	exchangeMaxTokenAge = time.Hour
+)
+
+// TokenExchanger verifies OIDC tokens issued by a trusted third-party identity
+// provider, such as GitHub Actions, so they can be exchanged for justification
+// tokens.
+type TokenExchanger struct {
+	verifier *idtoken.Verifier
+
+	// allowlist are the patterns tokens must match, keyed by claim.
+	allowlist map[string][]*regexp.Regexp
+}
+
+// NewTokenExchanger creates a new token exchanger from the token exchange
+// options in the config. Keys are fetched lazily on first use.
+func NewTokenExchanger(ctx context.Context, cfg *config.JustificationConfig) (*TokenExchanger, error) {
+	allowlist, err := cfg.TokenExchangeAllowlistRegexps()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token exchange allowlist: %w", err)
+	}
+	if len(allowlist) == 0 {
+		return nil, fmt.Errorf("token exchange allowlist is required")
+	}
+
+	keys, err := jvspb.NewJWKSProvider(ctx, cfg.TokenExchangeJWKSEndpoint, jvspb.WithJWKSRefreshInterval(exchangeKeysRefreshInterval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token exchange jwks provider: %w", err)
+	}
+
+	return &TokenExchanger{
+		verifier: idtoken.NewVerifier(keys, cfg.TokenExchangeIssuer, cfg.TokenExchangeAudience,
+			idtoken.WithMaxAge(exchangeMaxTokenAge)),
+		allowlist: allowlist,
+	}, nil
+}
+
+// Verify verifies the signature, issuer, audience, and lifetime of the given
+// subject token, and that it matches the allowlist. Tokens must have an
+// expiry, and have been issued less than [exchangeMaxTokenAge] ago. It returns
+// the token's subject, which is the identity of the requestor.
+func (e *TokenExchanger) Verify(ctx context.Context, subjectToken string) (string, error) {
+	token, err := e.verifier.Verify(ctx, subjectToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify subject token: %w", err)
+	}
+
+	subject := token.Subject()
+	if subject == "" {
+		return "", fmt.Errorf("subject token has no subject")
+	}
+
+	if !e.allowed(token) {
+		return "", fmt.Errorf("%w: %q", errNotAllowlisted, subject)
+	}
+	return subject, nil
+}
+
+// allowed reports whether any of the token's claims matches the allowlist.
+func (e *TokenExchanger) allowed(token jwt.Token) bool {
+	for claim, patterns := range e.allowlist {
+		var v string
+		if claim == jwt.SubjectKey {
+			v = token.Subject()
+		} else {
+			v, _ = token.PrivateClaims()[claim].(string)
+		}
+		if v == "" {
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/testutil"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+const (
+	testExchangeIssuer   = "https://token.actions.githubusercontent.com"
+	testExchangeAudience = "https://github.com/abcxyz"
+)
+
+var testExchangeAllowlist = []string{"repository=abcxyz/jvs", "sub=repo:abcxyz/pkg:ref:refs/heads/main"}
+
+func TestTokenExchanger_Verify(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	issuer := testutil.NewIDTokenIssuer(t)
+	other := testutil.NewIDTokenIssuer(t)
+
+	cases := []struct {
+		name    string
+		token   string
+		exp     string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs:ref:refs/heads/main", now, map[string]any{
+				"repository":       "abcxyz/jvs",
+				"repository_owner": "abcxyz",
+			}),
+			exp: "repo:abcxyz/jvs:ref:refs/heads/main",
+		},
+		{
+			name:  "allowed_subject",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/pkg:ref:refs/heads/main", now, nil),
+			exp:   "repo:abcxyz/pkg:ref:refs/heads/main",
+		},
+		{
+			name: "foreign_repository",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:evil/jvs:ref:refs/heads/main", now, map[string]any{
+				"repository":       "evil/jvs",
+				"repository_owner": "evil",
+			}),
+			wantErr: `subject token is not in the allowlist: "repo:evil/jvs:ref:refs/heads/main"`,
+		},
+		{
+			name: "partial_match",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs-fork:ref:refs/heads/main", now, map[string]any{
+				"repository":       "abcxyz/jvs-fork",
+				"repository_owner": "abcxyz",
+			}),
+			wantErr: "subject token is not in the allowlist",
+		},
+		{
+			name:    "wrong_issuer",
+			token:   issuer.IDToken(t, "https://evil.example.com", testExchangeAudience, "repo:abcxyz/jvs", now, nil),
+			wantErr: `"iss" not satisfied`,
+		},
+		{
+			name:    "wrong_audience",
+			token:   issuer.IDToken(t, testExchangeIssuer, "https://github.com/other", "repo:abcxyz/jvs", now, nil),
+			wantErr: `"aud" not satisfied`,
+		},
+		{
+			name:    "expired",
+			token:   issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs", now.Add(-1*time.Hour), nil),
+			wantErr: `"exp" not satisfied`,
+		},
+		{
+			name: "missing_expiration",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs", now, map[string]any{
+				jwt.ExpirationKey: nil,
+			}),
+			wantErr: `"exp" not satisfied: required claim not found`,
+		},
+		{
+			name: "missing_issued_at",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs", now, map[string]any{
+				jwt.IssuedAtKey: nil,
+			}),
+			wantErr: `"iat" not satisfied: required claim not found`,
+		},
+		{
+			name: "too_old",
+			token: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs", now, map[string]any{
+				jwt.IssuedAtKey: now.Add(-2 * time.Hour),
+			}),
+			wantErr: "more than the maximum of 1h0m0s",
+		},
+		{
+			name:    "wrong_key",
+			token:   other.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:abcxyz/jvs", now, nil),
+			wantErr: "failed to verify subject token",
+		},
+		{
+			name:    "missing_subject",
+			token:   issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "", now, nil),
+			wantErr: "subject token has no subject",
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-jwt",
+			wantErr: "failed to verify subject token",
+		},
+	}
+
+	exchanger, err := NewTokenExchanger(ctx, &config.JustificationConfig{
+		TokenExchangeIssuer:       testExchangeIssuer,
+		TokenExchangeJWKSEndpoint: issuer.JWKSEndpoint,
+		TokenExchangeAudience:     testExchangeAudience,
+		TokenExchangeAllowlist:    testExchangeAllowlist,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := exchanger.Verify(ctx, tc.token)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := got, tc.exp; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestJVSAgent_ExchangeToken(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	issuer := testutil.NewIDTokenIssuer(t)
+	exchanger, err := NewTokenExchanger(ctx, &config.JustificationConfig{
+		TokenExchangeIssuer:       testExchangeIssuer,
+		TokenExchangeJWKSEndpoint: issuer.JWKSEndpoint,
+		TokenExchangeAudience:     testExchangeAudience,
+		TokenExchangeAllowlist:    testExchangeAllowlist,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := NewJVSAgent(nil)
+	agent.Exchanger = exchanger
+
+	cases := []struct {
+		name     string
+		agent    *JVSAgent
+		req      *jvspb.ExchangeTokenRequest
+		wantCode codes.Code
+	}{
+		{
+			name:     "disabled",
+			agent:    NewJVSAgent(nil),
+			req:      &jvspb.ExchangeTokenRequest{SubjectToken: "foo"},
+			wantCode: codes.Unimplemented,
+		},
+		{
+			name:     "invalid_subject_token",
+			agent:    agent,
+			req:      &jvspb.ExchangeTokenRequest{SubjectToken: "not-a-jwt"},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:  "foreign_repository",
+			agent: agent,
+			req: &jvspb.ExchangeTokenRequest{
+				SubjectToken: issuer.IDToken(t, testExchangeIssuer, testExchangeAudience, "repo:evil/jvs:ref:refs/heads/main", time.Now().UTC(), nil),
+			},
+			wantCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tc.agent.ExchangeToken(ctx, tc.req)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// PolicyExpression is a CEL expression that token requests must satisfy. It
+// is a lighter alternative to a Rego policy, for checks that fit on one line,
+// e.g. `ttl <= duration("1h") || !("breakglass" in categories)`.
+//
+// The expression has these variables:
+//
+//   - request: the CreateJustificationRequest, as a message.
+//   - requestor: the authenticated principal that requested the token.
+//   - subject: the subject of the token, which defaults to the requestor.
+//   - categories: the categories of the justifications.
+//   - justifications: the justifications, as maps with a "category" and
+//     "value".
+//   - ttl: the TTL of the token, the requested TTL or the default TTL.
+//   - audiences: the audiences of the token, with audience services expanded.
+type PolicyExpression struct {
+	source  string
+	program cel.Program
+}
+
+// policyExpressionEnv declares the variables of policy expressions.
+func policyExpressionEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Types(&jvspb.CreateJustificationRequest{}),
+		cel.Variable("request", cel.ObjectType("abcxyz.jvs.CreateJustificationRequest")),
+		cel.Variable("requestor", cel.StringType),
+		cel.Variable("subject", cel.StringType),
+		cel.Variable("categories", cel.ListType(cel.StringType)),
+		cel.Variable("justifications", cel.ListType(cel.MapType(cel.StringType, cel.StringType))),
+		cel.Variable("ttl", cel.DurationType),
+		cel.Variable("audiences", cel.ListType(cel.StringType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel environment: %w", err)
+	}
+	return env, nil
+}
+
+// CompilePolicyExpression compiles the CEL expression. It returns an error if
+// the expression is invalid, or doesn't evaluate to a bool.
+func CompilePolicyExpression(source string) (*PolicyExpression, error) {
+	env, err := policyExpressionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, iss := env.Compile(source)
+	if err := iss.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compile policy expression: %w", err)
+	}
+	if got := ast.OutputType(); got != cel.BoolType {
+		return nil, fmt.Errorf("policy expression must evaluate to a bool, got %s", got)
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy expression program: %w", err)
+	}
+	return &PolicyExpression{
+		source:  source,
+		program: program,
+	}, nil
+}
+
+// String returns the source of the expression.
+func (e *PolicyExpression) String() string {
+	return e.source
+}
+
+// WithPolicyExpression refuses to issue tokens for requests that don't satisfy
+// the CEL expression.
+func (p *Processor) WithPolicyExpression(e *PolicyExpression) *Processor {
+	p.policyExpression = e
+	return p
+}
+
+// EvaluatePolicyExpression reports whether the token request satisfies the
+// policy expression. It is true if there is no policy expression.
+func (p *Processor) EvaluatePolicyExpression(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) (bool, error) {
+	if p.policyExpression == nil {
+		return true, nil
+	}
+
+	justs := req.GetJustifications()
+	categories := make([]string, 0, len(justs))
+	justifications := make([]map[string]string, 0, len(justs))
+	for _, j := range justs {
+		categories = append(categories, j.GetCategory())
+		justifications = append(justifications, map[string]string{
+			"category": j.GetCategory(),
+			"value":    j.GetValue(),
+		})
+	}
+
+	ttl := req.GetTtl().AsDuration()
+	if ttl <= 0 {
+		ttl = min(p.config.DefaultTTL, p.MaxTTL(justs))
+	}
+
+	subject := req.GetSubject()
+	if subject == "" {
+		subject = requestor
+	}
+
+	out, _, err := p.policyExpression.program.ContextEval(ctx, map[string]any{
+		"request":        req,
+		"requestor":      requestor,
+		"subject":        subject,
+		"categories":     categories,
+		"justifications": justifications,
+		"ttl":            ttl,
+		"audiences":      p.audiences(req),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate policy expression: %w", err)
+	}
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("policy expression evaluated to %T, not bool", out.Value())
+	}
+	return allowed, nil
+}
+
+// checkPolicyExpression returns PERMISSION_DENIED if the request doesn't
+// satisfy the policy expression.
+func (p *Processor) checkPolicyExpression(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+	logger := logging.FromContext(ctx)
+
+	allowed, err := p.EvaluatePolicyExpression(ctx, requestor, req)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to evaluate policy expression", "error", err)
+		return status.Error(codes.Internal, "failed to evaluate policy expression")
+	}
+	if !allowed {
+		logger.InfoContext(ctx, "policy expression denied request",
+			"requestor", requestor,
+			"expression", p.policyExpression.String())
+		return status.Errorf(codes.PermissionDenied, "request does not satisfy the policy expression %q", p.policyExpression.String())
+	}
+	return nil
+}
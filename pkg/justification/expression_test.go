@@ -0,0 +1,177 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestCompilePolicyExpression(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		expression string
+		wantErr    string
+	}{
+		{
+			name:       "valid",
+			expression: `ttl <= duration("1h") || !("breakglass" in categories)`,
+		},
+		{
+			name:       "request_fields",
+			expression: `request.ttl <= duration("1h") && request.subject == ""`,
+		},
+		{
+			name:       "syntax_error",
+			expression: `ttl <=`,
+			wantErr:    "failed to compile policy expression",
+		},
+		{
+			name:       "unknown_variable",
+			expression: `role == "admin"`,
+			wantErr:    "undeclared reference to 'role'",
+		},
+		{
+			name:       "not_bool",
+			expression: `ttl`,
+			wantErr:    "policy expression must evaluate to a bool, got google.protobuf.Duration",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := CompilePolicyExpression(tc.expression)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRunValidations_policyExpression(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name       string
+		expression string
+		requestor  string
+		req        *jvspb.CreateJustificationRequest
+		wantCode   codes.Code
+		wantErr    string
+	}{
+		{
+			name:       "allowed",
+			expression: `ttl <= duration("1h") || !("breakglass" in categories)`,
+			requestor:  "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+				Ttl:            durationpb.New(30 * time.Minute),
+			},
+		},
+		{
+			name:       "denied",
+			expression: `ttl <= duration("1h") || !("breakglass" in categories)`,
+			requestor:  "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+				Ttl:            durationpb.New(2 * time.Hour),
+			},
+			wantCode: codes.PermissionDenied,
+			wantErr:  `request does not satisfy the policy expression "ttl <= duration(\"1h\") || !(\"breakglass\" in categories)"`,
+		},
+		{
+			name:       "default_ttl",
+			expression: `ttl == duration("15m")`,
+			requestor:  "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+			},
+		},
+		{
+			name:       "subject_defaults_to_requestor",
+			expression: `subject == requestor && request.subject == ""`,
+			requestor:  "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+			},
+		},
+		{
+			name:       "justifications_and_audiences",
+			expression: `justifications.all(j, j.value.startsWith("INC-")) && audiences == ["dev.abcxyz.jvs"]`,
+			requestor:  "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+			},
+			wantCode: codes.PermissionDenied,
+			wantErr:  "request does not satisfy the policy expression",
+		},
+		{
+			name:       "evaluation_error",
+			expression: `justifications[1].value == ""`,
+			requestor:  "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+			},
+			wantCode: codes.Internal,
+			wantErr:  "failed to evaluate policy expression",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			expression, err := CompilePolicyExpression(tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             4 * time.Hour,
+				MaxAnnotationSize:  1000,
+			}).WithValidators(map[string]jvspb.Validator{
+				"breakglass": &mockValidator{
+					resp: &jvspb.ValidateJustificationResponse{Valid: true},
+				},
+			}).WithPolicyExpression(expression)
+
+			_, err = processor.runValidations(ctx, tc.requestor, tc.req, nil)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
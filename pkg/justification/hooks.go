@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// PreIssueHook is called with every token request, after the deny list check
+// and the renaming of deprecated categories, and before the justifications are
+// validated. It can change the request, or
+// deny it by returning an error. Errors that are not gRPC statuses are
+// returned to the caller as PERMISSION_DENIED.
+type PreIssueHook func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error
+
+// PostIssueHook is called with every issued token and client certificate,
+// after it is signed and its audit entry is logged. It can't fail the request, so errors must be handled
+// by the hook.
+type PostIssueHook func(ctx context.Context, issued *IssuedToken)
+
+// IssuedToken is a token passed to [PostIssueHook]s. Hooks must not modify it.
+type IssuedToken struct {
+	// Requestor is the authenticated principal that requested the token.
+	Requestor string
+
+	// Request is the request the token was issued for.
+	Request *jvspb.CreateJustificationRequest
+
+	// Token is the token's claims, or the claims of a client certificate. It is
+	// already signed.
+	Token jwt.Token
+
+	// Entry is the token's audit entry.
+	Entry *audit.Entry
+}
+
+// WithPreIssueHooks adds hooks called with every token request, in the order
+// they are added.
+func (p *Processor) WithPreIssueHooks(hooks ...PreIssueHook) *Processor {
+	p.preIssueHooks = append(p.preIssueHooks, hooks...)
+	return p
+}
+
+// WithPostIssueHooks adds hooks called with every issued token, in the order
+// they are added.
+func (p *Processor) WithPostIssueHooks(hooks ...PostIssueHook) *Processor {
+	p.postIssueHooks = append(p.postIssueHooks, hooks...)
+	return p
+}
+
+// AuditSinkHook returns a hook that writes the audit entries of issued tokens
+// to the sink. Failing to write an entry is logged.
+func AuditSinkHook(sink audit.Sink) PostIssueHook {
+	return func(ctx context.Context, issued *IssuedToken) {
+		if err := sink.Write(ctx, issued.Entry); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to write audit entry to sink",
+				"jti", issued.Entry.ID,
+				"error", err)
+		}
+	}
+}
+
+// runPreIssueHooks calls the pre-issue hooks in order, and stops at the first
+// one that denies the request.
+func (p *Processor) runPreIssueHooks(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+	for i, hook := range p.preIssueHooks {
+		err := hook(ctx, requestor, req)
+		recordDecisionStep(ctx, decisionStagePreIssueHook, "hook:"+strconv.Itoa(i), err)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return status.Error(codes.PermissionDenied, err.Error())
+		}
+	}
+	return nil
+}
+
+// runPostIssueHooks calls the post-issue hooks in order.
+func (p *Processor) runPostIssueHooks(ctx context.Context, issued *IssuedToken) {
+	for _, hook := range p.postIssueHooks {
+		hook(ctx, issued)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestProcessor_hooks(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addAudience := func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+		req.Audiences = append(req.Audiences, "https://"+requestor)
+		return nil
+	}
+
+	cases := []struct {
+		name        string
+		hooks       []PreIssueHook
+		wantAud     []string
+		wantErr     string
+		wantErrCode codes.Code
+	}{
+		{
+			name:    "no_hooks",
+			wantAud: []string{DefaultAudience},
+		},
+		{
+			name:    "mutate",
+			hooks:   []PreIssueHook{addAudience},
+			wantAud: []string{"https://me@example.com"},
+		},
+		{
+			name: "deny",
+			hooks: []PreIssueHook{
+				func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+					return fmt.Errorf("requestor %s is on call", requestor)
+				},
+				addAudience,
+			},
+			wantErr:     "requestor me@example.com is on call",
+			wantErrCode: codes.PermissionDenied,
+		},
+		{
+			name: "deny_status",
+			hooks: []PreIssueHook{
+				func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+					return status.Error(codes.ResourceExhausted, "too many tokens")
+				},
+			},
+			wantErr:     "too many tokens",
+			wantErrCode: codes.ResourceExhausted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sink, err := audit.NewMemorySink("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var issued []*IssuedToken
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+			}).WithLocalSigner(privateKey, "test-key").
+				WithPreIssueHooks(tc.hooks...).
+				WithPostIssueHooks(func(ctx context.Context, t *IssuedToken) {
+					issued = append(issued, t)
+				}).
+				WithAuditSink(sink)
+
+			b, err := processor.CreateToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+				},
+			})
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				if got, want := status.Code(err), tc.wantErrCode; got != want {
+					t.Errorf("expected code %s to be %s", got, want)
+				}
+				if len(issued) > 0 || len(sink.Entries()) > 0 {
+					t.Errorf("expected denied request not to be recorded")
+				}
+				return
+			}
+
+			token, err := jwt.Parse(b, jwt.WithKey(jwa.ES256, privateKey.Public()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantAud, token.Audience()); diff != "" {
+				t.Errorf("aud (-want, +got):\n%s", diff)
+			}
+
+			if got, want := len(issued), 1; got != want {
+				t.Fatalf("expected %d post-issue hook calls to be %d", got, want)
+			}
+			if got, want := issued[0].Token.JwtID(), token.JwtID(); got != want {
+				t.Errorf("expected hook token %q to be %q", got, want)
+			}
+			if got, want := issued[0].Requestor, "me@example.com"; got != want {
+				t.Errorf("expected hook requestor %q to be %q", got, want)
+			}
+			if got := sink.Entry(token.JwtID()); got == nil {
+				t.Errorf("expected audit entry for %q in the sink", token.JwtID())
+			}
+		})
+	}
+}
+
+func TestProcessor_preIssueHookCategoryAliases(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var categories []string
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  1000,
+	}).WithLocalSigner(privateKey, "test-key").
+		WithValidators(map[string]jvspb.Validator{
+			"freeform": &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}},
+		}).
+		WithCategoryAliases(map[string]string{jvspb.DefaultJustificationCategory: "freeform"}).
+		WithPreIssueHooks(func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+			for _, j := range req.GetJustifications() {
+				categories = append(categories, j.GetCategory())
+			}
+			return nil
+		})
+
+	resp, err := processor.CreateJustification(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: jvspb.DefaultJustificationCategory, Value: "debugging"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hooks see the new categories, and the deprecation is still warned about.
+	if diff := cmp.Diff([]string{"freeform"}, categories); diff != "" {
+		t.Errorf("hook categories (-want, +got):\n%s", diff)
+	}
+	want := []*jvspb.ValidationWarning{
+		{Category: "freeform", Message: `category "explanation" is deprecated, use "freeform" instead`},
+	}
+	if diff := cmp.Diff(want, resp.GetWarnings(), protocmp.Transform()); diff != "" {
+		t.Errorf("warnings (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// RetryAfterMetadataKey is the response header that tells shed callers how
+	// many seconds to wait before retrying.
+	RetryAfterMetadataKey = "retry-after"
+
+	// retryAfter is how long shed callers are asked to wait.
+	retryAfter = 1 * time.Second
+)
+
+// ConcurrencyLimiter limits the number of in-flight JVSService requests. When
+// KMS or plugins back up, requests over the limit fail fast with
+// RESOURCE_EXHAUSTED, instead of piling up goroutines and memory.
+type ConcurrencyLimiter struct {
+	sem chan struct{}
+}
+
+// NewConcurrencyLimiter creates a limiter that allows up to limit in-flight
+// requests.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		sem: make(chan struct{}, limit),
+	}
+}
+
+// UnaryInterceptor is a [grpc.UnaryServerInterceptor] that sheds JVSService
+// requests over the limit. Other services, such as health checks, are not
+// limited.
+func (l *ConcurrencyLimiter) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+jvspb.JVSService_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		defer func() { <-l.sem }()
+		return handler(ctx, req)
+	default:
+	}
+
+	logging.FromContext(ctx).WarnContext(ctx, "shedding request, too many in-flight requests",
+		"method", info.FullMethod,
+		"limit", cap(l.sem))
+
+	seconds := strconv.Itoa(int(retryAfter.Seconds()))
+	if err := grpc.SetHeader(ctx, grpcmetadata.Pairs(RetryAfterMetadataKey, seconds)); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to set retry-after header", "error", err)
+	}
+	return nil, status.Errorf(codes.ResourceExhausted, "too many in-flight requests, retry after %s", retryAfter)
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// fakeServerTransportStream records the headers set by a handler.
+type fakeServerTransportStream struct {
+	grpc.ServerTransportStream
+	header grpcmetadata.MD
+}
+
+func (s *fakeServerTransportStream) SetHeader(md grpcmetadata.MD) error {
+	s.header = grpcmetadata.Join(s.header, md)
+	return nil
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	limiter := NewConcurrencyLimiter(1)
+	createInfo := &grpc.UnaryServerInfo{FullMethod: "/abcxyz.jvs.JVSService/CreateJustification"}
+	healthInfo := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	ok := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+
+	// Hold the only slot until the other requests are done.
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := limiter.UnaryInterceptor(ctx, nil, createInfo, func(ctx context.Context, req any) (any, error) {
+			close(started)
+			<-release
+			return "ok", nil
+		})
+		done <- err
+	}()
+	<-started
+
+	stream := &fakeServerTransportStream{}
+	_, err := limiter.UnaryInterceptor(grpc.NewContextWithServerTransportStream(ctx, stream), nil, createInfo, ok)
+	if got, want := status.Code(err), codes.ResourceExhausted; got != want {
+		t.Errorf("expected code %s to be %s: %v", got, want, err)
+	}
+	if diff := cmp.Diff([]string{"1"}, stream.header.Get(RetryAfterMetadataKey)); diff != "" {
+		t.Errorf("retry-after (-want, +got):\n%s", diff)
+	}
+
+	if _, err := limiter.UnaryInterceptor(ctx, nil, healthInfo, ok); err != nil {
+		t.Errorf("expected health checks not to be limited: %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := limiter.UnaryInterceptor(ctx, nil, createInfo, ok); err != nil {
+		t.Errorf("expected request to be allowed after the slot was released: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ReasonMaintenance is the reason of the [errdetails.ErrorInfo] of requests
+	// rejected because the JVS is in maintenance mode.
+	ReasonMaintenance = "MAINTENANCE"
+
+	// defaultMaintenanceMessage is returned in maintenance mode if no message
+	// is configured.
+	defaultMaintenanceMessage = "the JVS is in maintenance mode and is not issuing tokens, try again later"
+)
+
+// checkMaintenance returns an Unavailable error with the configured message if
+// the JVS is in maintenance mode. The error details have the
+// [ReasonMaintenance] reason, so clients and audit logs can tell it apart from
+// outages.
+func (p *Processor) checkMaintenance() error {
+	if !p.config.MaintenanceMode {
+		return nil
+	}
+
+	msg := p.config.MaintenanceMessage
+	if msg == "" {
+		msg = defaultMaintenanceMessage
+	}
+
+	st := status.New(codes.Unavailable, msg)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: ReasonMaintenance,
+		Domain: errorDomain,
+	}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	t.Parallel()
+
+	caKey, caCert := testCertificateAuthority(t, true)
+	ca, err := NewCertificateAuthority(caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	justReq := &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		message string
+		call    func(ctx context.Context, p *Processor) error
+		wantErr string
+	}{
+		{
+			name:    "create_token",
+			message: "key migration in progress",
+			call: func(ctx context.Context, p *Processor) error {
+				_, err := p.CreateToken(ctx, "me@example.com", justReq)
+				return err
+			},
+			wantErr: "key migration in progress",
+		},
+		{
+			name: "create_token_default_message",
+			call: func(ctx context.Context, p *Processor) error {
+				_, err := p.CreateToken(ctx, "me@example.com", justReq)
+				return err
+			},
+			wantErr: defaultMaintenanceMessage,
+		},
+		{
+			name:    "create_certificate",
+			message: "key migration in progress",
+			call: func(ctx context.Context, p *Processor) error {
+				_, err := p.CreateCertificate(ctx, "me@example.com", &jvspb.CreateCertificateRequest{
+					Request: justReq,
+					Csr:     csr,
+				})
+				return err
+			},
+			wantErr: "key migration in progress",
+		},
+		{
+			name:    "sign_payload",
+			message: "key migration in progress",
+			call: func(ctx context.Context, p *Processor) error {
+				_, err := p.SignPayload(ctx, "me@example.com", make([]byte, 32))
+				return err
+			},
+			wantErr: "key migration in progress",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			publisher := &fakePublisher{}
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+				MaintenanceMode:    true,
+				MaintenanceMessage: tc.message,
+			}).WithCertificateAuthority(ca).WithEventPublisher(publisher)
+
+			err := tc.call(ctx, processor)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := status.Code(err), codes.Unavailable; got != want {
+				t.Errorf("expected code %s to be %s", got, want)
+			}
+			if got, want := errorReason(err), ReasonMaintenance; got != want {
+				t.Errorf("expected reason %q to be %q", got, want)
+			}
+			if got := len(publisher.events); got != 0 {
+				t.Errorf("expected no events, got %d", got)
+			}
+
+			// Categories are still served.
+			if _, err := processor.ListCategories(ctx); err != nil {
+				t.Errorf("failed to list categories: %v", err)
+			}
+		})
+	}
+}
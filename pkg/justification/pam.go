@@ -0,0 +1,129 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/pam"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// AnnotationPAMGrant is the annotation set on justifications of categories
+	// with a PAM entitlement. Its value is the name of the grant filed for the
+	// token.
+	AnnotationPAMGrant = "pam_grant"
+
+	// grantRevokeTimeout is the timeout of revoking the grants of a token that
+	// failed to be issued.
+	grantRevokeTimeout = 10 * time.Second
+)
+
+// WithPAMGrants files a Privileged Access Manager grant of the entitlement of
+// the category, values of the map keyed by category, for every token with a
+// justification of the category. The grant name is set in the annotations of
+// the justification, and the grant is requested for the TTL of the token.
+// Grants of tokens that fail to be issued are revoked.
+func (p *Processor) WithPAMGrants(g pam.Granter, entitlements map[string]string) *Processor {
+	p.pamGranter = g
+	p.pamEntitlements = entitlements
+	return p
+}
+
+// requestGrants files the PAM grants of the justifications with an entitlement
+// and sets their names in the annotations. A grant is filed once per
+// entitlement, and the token ID makes retries idempotent. The names of the
+// filed grants are returned, also on error.
+func (p *Processor) requestGrants(ctx context.Context, subject, jti string, justs []*jvspb.Justification, ttl time.Duration) ([]string, error) {
+	if p.pamGranter == nil {
+		return nil, nil
+	}
+	logger := logging.FromContext(ctx)
+
+	var names []string
+	grants := make(map[string]string, len(p.pamEntitlements))
+	for _, j := range justs {
+		entitlement, ok := p.pamEntitlements[j.GetCategory()]
+		if !ok {
+			continue
+		}
+
+		name, ok := grants[entitlement]
+		if !ok {
+			grant, err := p.pamGranter.CreateGrant(ctx, entitlement, ttl,
+				p.grantJustification(subject, jti, j), jti)
+			if err != nil {
+				return names, fmt.Errorf("failed to request pam grant: %w", err)
+			}
+			logger.InfoContext(ctx, "pam grant requested",
+				"entitlement", entitlement,
+				"grant", grant.Name,
+				"state", grant.State,
+				"jti", jti)
+			name = grant.Name
+			grants[entitlement] = name
+			names = append(names, name)
+		}
+
+		// The annotations may be a cached validation response, so don't modify
+		// them.
+		annotation := make(map[string]string, len(j.GetAnnotation())+1)
+		maps.Copy(annotation, j.GetAnnotation())
+		annotation[AnnotationPAMGrant] = name
+		j.Annotation = annotation
+	}
+	return names, nil
+}
+
+// revokeGrants revokes the PAM grants of a token that failed to be issued. The
+// grants are revoked even if the request was canceled or its deadline exceeded,
+// and failures are only logged.
+func (p *Processor) revokeGrants(ctx context.Context, names []string, jti, reason string) {
+	if len(names) == 0 {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), grantRevokeTimeout)
+	defer cancel()
+
+	for _, name := range names {
+		if err := p.pamGranter.RevokeGrant(ctx, name,
+			fmt.Sprintf("JVS token %s was not issued: %s", jti, reason)); err != nil {
+			logger.ErrorContext(ctx, "failed to revoke pam grant",
+				"grant", name,
+				"jti", jti,
+				"error", err)
+			continue
+		}
+		logger.InfoContext(ctx, "pam grant revoked",
+			"grant", name,
+			"jti", jti)
+	}
+}
+
+// grantJustification returns the justification of the PAM grant of a token.
+// Values of encrypted justifications are left out.
+func (p *Processor) grantJustification(subject, jti string, j *jvspb.Justification) string {
+	if p.encryptionKeys != nil {
+		return fmt.Sprintf("JVS token %s for %s with a %s justification", jti, subject, j.GetCategory())
+	}
+	return fmt.Sprintf("JVS token %s for %s with a %s justification: %s", jti, subject, j.GetCategory(), j.GetValue())
+}
@@ -0,0 +1,265 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/pam"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+type fakeGranter struct {
+	mu       sync.Mutex
+	requests []string
+	grants   map[string]struct{}
+	err      error
+
+	// failEntitlement is an entitlement grants of which fail with err.
+	failEntitlement string
+}
+
+func (g *fakeGranter) CreateGrant(_ context.Context, entitlement string, duration time.Duration, justification, requestID string) (*pam.Grant, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil && (g.failEntitlement == "" || g.failEntitlement == entitlement) {
+		return nil, g.err
+	}
+	g.requests = append(g.requests, fmt.Sprintf("%s %s %s", entitlement, duration, justification))
+	name := entitlement + "/grants/" + requestID
+	if g.grants == nil {
+		g.grants = make(map[string]struct{})
+	}
+	g.grants[name] = struct{}{}
+	return &pam.Grant{
+		Name:  name,
+		State: "APPROVAL_AWAITED",
+	}, nil
+}
+
+func (g *fakeGranter) RevokeGrant(_ context.Context, name, reason string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.grants[name]; !ok {
+		return fmt.Errorf("grant %s not found", name)
+	}
+	delete(g.grants, name)
+	return nil
+}
+
+// remaining returns the names of the grants that weren't revoked.
+func (g *fakeGranter) remaining() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	names := slices.Collect(maps.Keys(g.grants))
+	slices.Sort(names)
+	return names
+}
+
+// failingSigner is a signer that fails to sign.
+type failingSigner struct {
+	crypto.Signer
+}
+
+func (s *failingSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, fmt.Errorf("key is disabled")
+}
+
+func TestProcessor_pamGrants(t *testing.T) {
+	t.Parallel()
+
+	const (
+		entitlement   = "projects/p/locations/global/entitlements/prod-admin"
+		dbEntitlement = "projects/p/locations/global/entitlements/db-admin"
+	)
+
+	cases := []struct {
+		name           string
+		granter        *fakeGranter
+		justifications []*jvspb.Justification
+		wantRequests   []string
+		wantAnnotated  bool
+		wantErr        string
+	}{
+		{
+			name:    "granted",
+			granter: &fakeGranter{},
+			justifications: []*jvspb.Justification{
+				{Category: "breakglass", Value: "prod outage", Annotation: map[string]string{"k": "v"}},
+				{Category: "breakglass", Value: "again"},
+				{Category: "explanation", Value: "debugging"},
+			},
+			wantRequests: []string{
+				entitlement + " 30m0s JVS token {jti} for me@example.com with a breakglass justification: prod outage",
+			},
+			wantAnnotated: true,
+		},
+		{
+			name:    "no_entitlement",
+			granter: &fakeGranter{},
+			justifications: []*jvspb.Justification{
+				{Category: "explanation", Value: "debugging"},
+			},
+		},
+		{
+			name:    "grant_error",
+			granter: &fakeGranter{err: fmt.Errorf("requester is not eligible")},
+			justifications: []*jvspb.Justification{
+				{Category: "breakglass", Value: "prod outage"},
+			},
+			wantErr: "failed to request pam grant: requester is not eligible",
+		},
+		{
+			name: "second_grant_error",
+			granter: &fakeGranter{
+				err:             fmt.Errorf("requester is not eligible"),
+				failEntitlement: dbEntitlement,
+			},
+			justifications: []*jvspb.Justification{
+				{Category: "breakglass", Value: "prod outage"},
+				{Category: "database", Value: "prod outage"},
+			},
+			wantErr: "failed to request pam grant: requester is not eligible",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             time.Hour,
+				Issuer:             "jvs.abcxyz.dev",
+			}).WithPAMGrants(tc.granter, map[string]string{
+				"breakglass": entitlement,
+				"database":   dbEntitlement,
+			})
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: tc.justifications,
+				Ttl:            durationpb.New(30 * time.Minute),
+			}
+			token, grants, err := processor.createToken(ctx, "me@example.com", req, time.Now())
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatalf("Unexpected err: %s", diff)
+			}
+			if err != nil {
+				// Grants filed before the error are revoked.
+				if got := tc.granter.remaining(); len(got) > 0 {
+					t.Errorf("expected grants %q to be revoked", got)
+				}
+				return
+			}
+			if diff := cmp.Diff(tc.granter.remaining(), grants); diff != "" {
+				t.Errorf("grants (-want, +got):\n%s", diff)
+			}
+
+			var wantRequests []string
+			for _, r := range tc.wantRequests {
+				wantRequests = append(wantRequests, strings.ReplaceAll(r, "{jti}", token.JwtID()))
+			}
+			if diff := cmp.Diff(wantRequests, tc.granter.requests); diff != "" {
+				t.Errorf("grant requests (-want, +got):\n%s", diff)
+			}
+
+			got, err := jvspb.GetJustifications(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tc.justifications
+			if tc.wantAnnotated {
+				grant := entitlement + "/grants/" + token.JwtID()
+				want = []*jvspb.Justification{
+					{Category: "breakglass", Value: "prod outage", Annotation: map[string]string{"k": "v", AnnotationPAMGrant: grant}},
+					{Category: "breakglass", Value: "again", Annotation: map[string]string{AnnotationPAMGrant: grant}},
+					{Category: "explanation", Value: "debugging"},
+				}
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("justifications (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateJustification_pamGrantRevoked(t *testing.T) {
+	t.Parallel()
+
+	const entitlement = "projects/p/locations/global/entitlements/prod-admin"
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	granter := &fakeGranter{}
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		Signer:             config.SignerLocal,
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             time.Hour,
+	}).WithLocalSigner(&failingSigner{Signer: privateKey}, "test-key").
+		WithValidators(map[string]jvspb.Validator{
+			"breakglass": &mockValidator{
+				resp: &jvspb.ValidateJustificationResponse{Valid: true},
+			},
+		}).
+		WithPAMGrants(granter, map[string]string{"breakglass": entitlement})
+
+	_, err = processor.CreateJustification(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "breakglass", Value: "prod outage"},
+		},
+	})
+	if diff := testutil.DiffErrString(err, "failed to sign token"); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if got, want := len(granter.requests), 1; got != want {
+		t.Errorf("expected %d grant requests to be %d", got, want)
+	}
+	if got := granter.remaining(); len(got) > 0 {
+		t.Errorf("expected grants %q to be revoked", got)
+	}
+}
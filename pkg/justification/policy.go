@@ -0,0 +1,240 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// PolicyStore is the runtime policy of the JVS, which the [AdminServer]
+// changes without a restart. It is persisted to a JSON file, so changes
+// survive restarts. When the file is on storage shared by all replicas, such
+// as a Cloud Storage volume, [PolicyStore.Watch] picks up the changes made by
+// other replicas.
+type PolicyStore struct {
+	path string
+
+	mu       sync.RWMutex
+	contents []byte
+	policy   *jvspb.Policy
+}
+
+// LoadPolicyStore loads the policy in the file. If the file doesn't exist,
+// the policy is empty, and the file is created on the first change.
+func LoadPolicyStore(path string) (*PolicyStore, error) {
+	s := &PolicyStore{path: path, policy: &jvspb.Policy{}}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reloads the policy from its file, and reports whether it changed. If
+// the file can't be read or parsed, the previous policy stays in effect.
+func (s *PolicyStore) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked()
+}
+
+// reloadLocked is [PolicyStore.Reload], with the lock held.
+func (s *PolicyStore) reloadLocked() (bool, error) {
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		b = nil
+	case err != nil:
+		return false, fmt.Errorf("failed to read policy: %w", err)
+	}
+	if bytes.Equal(b, s.contents) {
+		return false, nil
+	}
+
+	policy := &jvspb.Policy{}
+	if len(b) > 0 {
+		if err := protojson.Unmarshal(b, policy); err != nil {
+			return false, fmt.Errorf("failed to parse policy: %w", err)
+		}
+	}
+	normalizePolicy(policy)
+	s.contents = b
+	s.policy = policy
+	return true, nil
+}
+
+// Watch reloads the policy every interval until the context is done, so every
+// replica sharing the file applies changes made through any of them. Failing
+// to reload is logged, and the previous policy stays in effect.
+func (s *PolicyStore) Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := s.Reload()
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to reload policy", "path", s.path, "error", err)
+			continue
+		}
+		if changed {
+			logger.InfoContext(ctx, "reloaded policy", "path", s.path)
+		}
+	}
+}
+
+// Policy returns a copy of the current policy.
+func (s *PolicyStore) Policy() *jvspb.Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return clonePolicy(s.policy)
+}
+
+// Update reloads the policy, so changes made by other replicas aren't
+// overwritten, changes a copy of it with fn, writes it to the file, and then
+// applies it. If fn or the write fails, the current policy stays in effect.
+func (s *PolicyStore) Update(fn func(p *jvspb.Policy) error) (*jvspb.Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.reloadLocked(); err != nil {
+		return nil, err
+	}
+
+	policy := clonePolicy(s.policy)
+	if err := fn(policy); err != nil {
+		return nil, err
+	}
+	normalizePolicy(policy)
+
+	b, err := s.write(policy)
+	if err != nil {
+		return nil, err
+	}
+	s.contents = b
+	s.policy = policy
+	return clonePolicy(policy), nil
+}
+
+func clonePolicy(p *jvspb.Policy) *jvspb.Policy {
+	return proto.Clone(p).(*jvspb.Policy) //nolint:forcetypeassert // Clone returns the same type.
+}
+
+// normalizePolicy sorts and deduplicates the lists of the policy, so they can
+// be binary searched.
+func normalizePolicy(p *jvspb.Policy) {
+	slices.Sort(p.DisabledCategories)
+	p.DisabledCategories = slices.Compact(p.DisabledCategories)
+	slices.Sort(p.AudienceAllowlist)
+	p.AudienceAllowlist = slices.Compact(p.AudienceAllowlist)
+}
+
+// write writes the policy to a temporary file and renames it over the policy
+// file, so a failed write doesn't leave a partial policy behind. It returns
+// the written contents.
+func (s *PolicyStore) write(policy *jvspb.Policy) ([]byte, error) {
+	b, err := protojson.MarshalOptions{Multiline: true}.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write policy file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close policy file: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return nil, fmt.Errorf("failed to replace policy file: %w", err)
+	}
+	return b, nil
+}
+
+// CategoryDisabled reports whether the category is disabled.
+func (s *PolicyStore) CategoryDisabled(category string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := slices.BinarySearch(s.policy.GetDisabledCategories(), category)
+	return ok
+}
+
+// CategoryMaxTTL returns the TTL cap of the category, or 0 if it has none.
+func (s *PolicyStore) CategoryMaxTTL(category string) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy.GetCategoryMaxTtls()[category].AsDuration()
+}
+
+// AudienceAllowed reports whether tokens may be issued for the audience. Any
+// audience is allowed if the allowlist is empty.
+func (s *PolicyStore) AudienceAllowed(audience string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	allowlist := s.policy.GetAudienceAllowlist()
+	if len(allowlist) == 0 {
+		return true
+	}
+	_, ok := slices.BinarySearch(allowlist, audience)
+	return ok
+}
+
+// categoryDisabled reports whether the category is disabled by the runtime
+// policy.
+func (p *Processor) categoryDisabled(category string) bool {
+	return p.policy != nil && p.policy.CategoryDisabled(category)
+}
+
+// MaxTTL returns the max TTL of tokens with the justifications, which is the
+// lowest of the configured max TTL and the TTL caps of their categories.
+func (p *Processor) MaxTTL(justs []*jvspb.Justification) time.Duration {
+	maxTTL := p.config.MaxTTL
+	if p.policy == nil {
+		return maxTTL
+	}
+	for _, j := range justs {
+		if c := p.policy.CategoryMaxTTL(j.GetCategory()); c > 0 && c < maxTTL {
+			maxTTL = c
+		}
+	}
+	return maxTTL
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// PolicyAttestation signs the issuance policy in force with the token signer,
+// so it can be verified with the JVS public keys. Versions of the policy that
+// weren't attested before are recorded in the audit log.
+func (p *Processor) PolicyAttestation(ctx context.Context) ([]byte, error) {
+	logger := logging.FromContext(ctx)
+
+	policy, err := p.policyAttestation(ctx, time.Now().UTC())
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to build policy attestation", "error", err)
+		return nil, status.Error(codes.Internal, "failed to build policy attestation")
+	}
+
+	signer, err := p.signer(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get token signer", "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to get token signer: %s", err)
+	}
+
+	attestation, err := signPolicyAttestation(signer, policy)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to sign policy attestation", "error", err)
+		return nil, status.Error(codes.Internal, "failed to sign policy attestation")
+	}
+
+	p.recordPolicy(ctx, policy, attestation)
+	return attestation, nil
+}
+
+// policyAttestation returns the issuance policy in force at the time, from the
+// config and the runtime policy.
+func (p *Processor) policyAttestation(ctx context.Context, now time.Time) (*jvspb.PolicyAttestation, error) {
+	categories, err := p.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &jvspb.PolicyAttestation{
+		Issuer:                   p.config.Issuer,
+		IssuedAt:                 now.Truncate(time.Second),
+		DefaultTTL:               p.config.DefaultTTL.String(),
+		MaxTTL:                   p.config.MaxTTL.String(),
+		Categories:               make([]*jvspb.PolicyCategory, 0, len(categories)),
+		DefaultAudiences:         []string{DefaultAudience},
+		AudienceTemplate:         p.config.AudienceTemplate,
+		AudienceTemplateRequired: p.config.AudienceTemplateRequired,
+	}
+	for _, c := range categories {
+		_, shadow := p.shadowCategories[c.GetName()]
+		policy.Categories = append(policy.Categories, &jvspb.PolicyCategory{
+			Name:              c.GetName(),
+			MaxTTL:            p.MaxTTL([]*jvspb.Justification{{Category: c.GetName()}}).String(),
+			ValuePattern:      c.GetValuePattern(),
+			DeprecatedAliases: c.GetDeprecatedAliases(),
+			Shadow:            shadow,
+		})
+	}
+	if p.policy != nil {
+		runtime := p.policy.Policy()
+		policy.DisabledCategories = runtime.GetDisabledCategories()
+		policy.AudienceAllowlist = runtime.GetAudienceAllowlist()
+	}
+
+	digest, err := policy.Digest()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already wrapped.
+	}
+	policy.Version = hex.EncodeToString(digest)
+	return policy, nil
+}
+
+// signPolicyAttestation signs the policy attestation with the token signer.
+func signPolicyAttestation(signer *signerWithID, policy *jvspb.PolicyAttestation) ([]byte, error) {
+	payload, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy attestation: %w", err)
+	}
+
+	headers := jws.NewHeaders()
+	for k, v := range map[string]any{
+		jws.KeyIDKey: signer.id,
+		jws.TypeKey:  jvspb.PolicyAttestationType,
+	} {
+		if err := headers.Set(k, v); err != nil {
+			return nil, fmt.Errorf("failed to set policy attestation header %s: %w", k, err)
+		}
+	}
+
+	b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, signer, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign policy attestation: %w", err)
+	}
+	return b, nil
+}
+
+// recordPolicy logs the policy entry of the attestation, if its version is
+// not the last one this processor recorded.
+func (p *Processor) recordPolicy(ctx context.Context, policy *jvspb.PolicyAttestation, attestation []byte) {
+	p.policyVersionMu.Lock()
+	defer p.policyVersionMu.Unlock()
+
+	if policy.Version == p.policyVersion {
+		return
+	}
+	p.policyVersion = policy.Version
+
+	logging.FromContext(ctx).InfoContext(ctx, audit.PolicyLogMessage, audit.PolicyLogKey, &audit.PolicyEntry{
+		Version:     policy.Version,
+		IssuedAt:    policy.IssuedAt,
+		Policy:      policy,
+		Attestation: string(attestation),
+	})
+}
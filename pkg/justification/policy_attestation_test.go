@@ -0,0 +1,148 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestProcessor_PolicyAttestation(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadPolicyStore(filepath.Join(t.TempDir(), "policy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = []string{"github"}
+		p.CategoryMaxTtls = map[string]*durationpb.Duration{"jira": durationpb.New(30 * time.Minute)}
+		p.AudienceAllowlist = []string{"https://payments.example.com"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout:       5 * time.Minute,
+		Issuer:                   "test-iss",
+		DefaultTTL:               15 * time.Minute,
+		MaxTTL:                   1 * time.Hour,
+		AudienceTemplate:         "https://{service}.example.com",
+		AudienceTemplateRequired: true,
+	}).WithValidators(map[string]jvspb.Validator{
+		"jira": &mockValidator{uiData: &jvspb.UIData{
+			DisplayName:  "Jira",
+			ValuePattern: `^[A-Z]+-[0-9]+$`,
+		}},
+		"github": &mockValidator{uiData: &jvspb.UIData{}},
+	}).WithShadowCategories([]string{"jira"}).
+		WithPolicy(store).
+		WithLocalSigner(privateKey, "test-key")
+
+	b, err := processor.PolicyAttestation(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := jwk.FromRaw(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Set(jwk.KeyIDKey, "test-key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+		t.Fatal(err)
+	}
+	keys := jwk.NewSet()
+	if err := keys.AddKey(publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := jvspb.VerifyPolicyAttestation(string(b), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &jvspb.PolicyAttestation{
+		Issuer:     "test-iss",
+		DefaultTTL: "15m0s",
+		MaxTTL:     "1h0m0s",
+		Categories: []*jvspb.PolicyCategory{
+			{Name: "explanation", MaxTTL: "1h0m0s"},
+			{Name: "jira", MaxTTL: "30m0s", ValuePattern: `^[A-Z]+-[0-9]+$`, Shadow: true},
+		},
+		DisabledCategories:       []string{"github"},
+		DefaultAudiences:         []string{DefaultAudience},
+		AudienceTemplate:         "https://{service}.example.com",
+		AudienceTemplateRequired: true,
+		AudienceAllowlist:        []string{"https://payments.example.com"},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(jvspb.PolicyAttestation{}, "Version", "IssuedAt")); diff != "" {
+		t.Errorf("policy attestation (-want, +got):\n%s", diff)
+	}
+	if got, want := processor.policyVersion, got.Version; got != want {
+		t.Errorf("expected recorded version %q to be %q", got, want)
+	}
+
+	// The version changes with the policy.
+	if _, err := store.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = nil
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b, err = processor.PolicyAttestation(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := jvspb.VerifyPolicyAttestation(string(b), keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Version == got.Version {
+		t.Errorf("expected the version to change with the policy")
+	}
+	if !slices.ContainsFunc(changed.Categories, func(c *jvspb.PolicyCategory) bool { return c.Name == "github" }) {
+		t.Errorf("expected the enabled category to be attested")
+	}
+	if got, want := processor.policyVersion, changed.Version; got != want {
+		t.Errorf("expected recorded version %q to be %q", got, want)
+	}
+}
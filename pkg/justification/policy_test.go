@@ -0,0 +1,315 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestPolicyStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+
+	store, err := LoadPolicyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&jvspb.Policy{}, store.Policy(), protocmp.Transform()); diff != "" {
+		t.Errorf("initial policy (-want, +got):\n%s", diff)
+	}
+
+	want := &jvspb.Policy{
+		DisabledCategories: []string{"github", "jira"},
+		CategoryMaxTtls:    map[string]*durationpb.Duration{"breakglass": durationpb.New(30 * time.Minute)},
+		AudienceAllowlist:  []string{"https://a.example.com", "https://b.example.com"},
+	}
+	if _, err := store.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = []string{"jira", "github", "jira"}
+		p.CategoryMaxTtls = map[string]*durationpb.Duration{"breakglass": durationpb.New(30 * time.Minute)}
+		p.AudienceAllowlist = []string{"https://b.example.com", "https://a.example.com"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, store.Policy(), protocmp.Transform()); diff != "" {
+		t.Errorf("updated policy (-want, +got):\n%s", diff)
+	}
+	if !store.CategoryDisabled("jira") || store.CategoryDisabled("explanation") {
+		t.Errorf("expected only jira and github to be disabled")
+	}
+	if got, want := store.CategoryMaxTTL("breakglass"), 30*time.Minute; got != want {
+		t.Errorf("expected max ttl %s to be %s", got, want)
+	}
+	if store.AudienceAllowed("https://evil.example.com") {
+		t.Errorf("expected audience not on the allowlist to be denied")
+	}
+
+	// A failed update leaves the policy as it was.
+	if _, err := store.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = nil
+		return fmt.Errorf("oops")
+	}); err == nil {
+		t.Errorf("expected error from failed update")
+	}
+	if diff := cmp.Diff(want, store.Policy(), protocmp.Transform()); diff != "" {
+		t.Errorf("policy after failed update (-want, +got):\n%s", diff)
+	}
+
+	// The policy survives a restart.
+	reloaded, err := LoadPolicyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, reloaded.Policy(), protocmp.Transform()); diff != "" {
+		t.Errorf("reloaded policy (-want, +got):\n%s", diff)
+	}
+}
+
+func TestPolicyStore_sharedFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Two replicas share the same file.
+	path := filepath.Join(t.TempDir(), "policy.json")
+	a, err := LoadPolicyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadPolicyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Watch(ctx, 10*time.Millisecond)
+	}()
+
+	if _, err := a.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = []string{"jira"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !b.CategoryDisabled("jira") {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the policy to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// An update through the other replica keeps the first change, even before
+	// it's reloaded.
+	if _, err := a.Update(func(p *jvspb.Policy) error {
+		p.AudienceAllowlist = []string{"https://a.example.com"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = append(p.DisabledCategories, "github")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &jvspb.Policy{
+		DisabledCategories: []string{"github", "jira"},
+		AudienceAllowlist:  []string{"https://a.example.com"},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("policy (-want, +got):\n%s", diff)
+	}
+}
+
+func TestLoadPolicyStore_invalid(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadPolicyStore(path)
+	if diff := pkgtestutil.DiffErrString(err, "failed to parse policy"); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestCreateToken_policy(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	store, err := LoadPolicyStore(filepath.Join(t.TempDir(), "policy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = []string{"jira"}
+		p.CategoryMaxTtls = map[string]*durationpb.Duration{"breakglass": durationpb.New(10 * time.Minute)}
+		p.AudienceAllowlist = []string{DefaultAudience, "https://payments.example.com"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	validator := &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}}
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithValidators(map[string]jvspb.Validator{
+		"breakglass": validator,
+		"jira":       validator,
+	}).WithPolicy(store)
+
+	cases := []struct {
+		name          string
+		category      string
+		ttl           time.Duration
+		audiences     []string
+		wantTTL       time.Duration
+		wantErr       string
+		wantCreateErr string
+	}{
+		{
+			name:     "enabled_category",
+			category: "explanation",
+			wantTTL:  15 * time.Minute,
+		},
+		{
+			name:     "disabled_category",
+			category: "jira",
+			wantErr:  `category "jira" is disabled`,
+		},
+		{
+			name:     "capped_default_ttl",
+			category: "breakglass",
+			wantTTL:  10 * time.Minute,
+		},
+		{
+			name:          "ttl_over_cap",
+			category:      "breakglass",
+			ttl:           30 * time.Minute,
+			wantCreateErr: "requested ttl (30m) cannot be greater than max tll (10m)",
+		},
+		{
+			name:      "allowed_audience",
+			category:  "explanation",
+			audiences: []string{"https://payments.example.com"},
+			wantTTL:   15 * time.Minute,
+		},
+		{
+			name:      "audience_not_allowed",
+			category:  "explanation",
+			audiences: []string{"https://evil.example.com"},
+			wantErr:   `audience "https://evil.example.com" is not allowed`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: tc.category, Value: "debugging"},
+				},
+				Audiences: tc.audiences,
+				Ttl:       durationpb.New(tc.ttl),
+			}
+
+			_, err := processor.runValidations(ctx, "me@example.com", req, nil)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			now := time.Now().UTC().Truncate(time.Second)
+			token, _, err := processor.createToken(ctx, "me@example.com", req, now)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantCreateErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := token.Expiration().Sub(now), tc.wantTTL; got != want {
+				t.Errorf("expected ttl %s to be %s", got, want)
+			}
+		})
+	}
+}
+
+func TestListCategories_disabled(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	store, err := LoadPolicyStore(filepath.Join(t.TempDir(), "policy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Update(func(p *jvspb.Policy) error {
+		p.DisabledCategories = []string{"jira"}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	}).WithValidators(map[string]jvspb.Validator{
+		"jira": &mockValidator{uiData: &jvspb.UIData{DisplayName: "Jira"}},
+	}).WithPolicy(store)
+
+	categories, err := processor.ListCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range categories {
+		got = append(got, c.GetName())
+	}
+	if diff := cmp.Diff([]string{"explanation"}, got); diff != "" {
+		t.Errorf("categories (-want, +got):\n%s", diff)
+	}
+}
@@ -16,22 +16,40 @@ package justification
 
 import (
 	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"github.com/google/uuid"
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
-	"github.com/sethvargo/go-gcpkms/pkg/gcpkms"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/decision"
+	"github.com/abcxyz/jvs/pkg/events"
+	"github.com/abcxyz/jvs/pkg/groups"
 	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/jvs/pkg/pam"
+	"github.com/abcxyz/jvs/pkg/plugin"
+	"github.com/abcxyz/jvs/pkg/quota"
+	"github.com/abcxyz/jvs/pkg/revocation"
+	"github.com/abcxyz/jvs/pkg/transparency"
 	"github.com/abcxyz/pkg/cache"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/timeutil"
@@ -41,14 +59,108 @@ import (
 // mints a token.
 type Processor struct {
 	jvspb.UnimplementedJVSServiceServer
-	kms        *kms.KeyManagementClient
+	keys       jvscrypto.KeyManager
 	config     *config.JustificationConfig
 	cache      *cache.Cache[*signerWithID]
 	validators map[string]jvspb.Validator
+
+	// validationCaches are the caches of successful validation results, keyed
+	// by category. Categories without a cache are validated on every request.
+	validationCaches map[string]*cache.Cache[*jvspb.ValidateJustificationResponse]
+
+	// categoryAliases are the new names of deprecated categories, keyed by the
+	// deprecated name.
+	categoryAliases map[string]string
+
+	// policyExpression is the CEL expression requests must satisfy. If nil,
+	// requests aren't checked against an expression.
+	policyExpression *PolicyExpression
+
+	// claimAliases are the claims duplicated under alternate names in minted
+	// tokens, keyed by the alternate name.
+	claimAliases map[string]string
+
+	// valuePatterns are the patterns justification values must match, keyed by
+	// category. They override the patterns in the UI data of plugins.
+	valuePatterns map[string]*regexp.Regexp
+
+	// requiredAnnotations are the annotations validators must set, keyed by
+	// category.
+	requiredAnnotations map[string][]string
+
+	// pluginPatterns caches the patterns in the UI data of plugins, keyed by
+	// category. A nil pattern means the plugin doesn't declare one.
+	pluginPatternsMu sync.Mutex
+	pluginPatterns   map[string]*regexp.Regexp
+
+	// shadowCategories are the categories whose validation results are only
+	// logged, and never block issuance.
+	shadowCategories map[string]struct{}
+
+	// encryptionKeys are the recipient keys justifications are encrypted to. If
+	// nil, justifications are not encrypted.
+	encryptionKeys jwk.Set
+
+	// publisher publishes token issued and denied events. If nil, events are
+	// not published.
+	publisher events.Publisher
+
+	// preIssueHooks and postIssueHooks are called with every token request and
+	// every issued token.
+	preIssueHooks  []PreIssueHook
+	postIssueHooks []PostIssueHook
+
+	// groups resolves the groups of requestors, which are embedded in tokens.
+	// If nil, tokens have no groups.
+	groups groups.Resolver
+
+	// denyList are the principals who may not mint tokens or be their
+	// subjects. If nil, no principal is denied.
+	denyList *DenyList
+
+	// revocations are the deprovisioned principals, shared by all replicas.
+	// They may not mint tokens, and the tokens they were issued are revoked.
+	// If nil, no principal is revoked.
+	revocations *revocation.List
+
+	// quotas limit how many tokens each requestor can mint per category per
+	// day. If nil, tokens are unlimited.
+	quotas *quota.Quotas
+
+	// pamGranter files the grants of pamEntitlements, keyed by category, for
+	// tokens with justifications of their category. If nil, no grants are
+	// filed.
+	pamGranter      pam.Granter
+	pamEntitlements map[string]string
+
+	// decisionTraces is where the traces of denied token requests are
+	// recorded. If nil, they are not recorded.
+	decisionTraces decision.Store
+
+	// policy is the runtime policy changed with the admin API. If nil, all
+	// categories are enabled with the configured TTLs, and any audience is
+	// allowed.
+	policy *PolicyStore
+
+	// policyVersion is the version of the issuance policy this processor last
+	// recorded in the audit log.
+	policyVersionMu sync.Mutex
+	policyVersion   string
+
+	// ca issues client certificates. If nil, certificate issuance is disabled.
+	ca *CertificateAuthority
+
+	// transparencyLog is where the digests of audit entries are published. If
+	// nil, they are not published.
+	transparencyLog transparency.Log
+
+	// localSigner signs tokens instead of the primary version of the KMS key.
+	// If nil, tokens are signed with KMS.
+	localSigner *signerWithID
 }
 
 type signerWithID struct {
-	*gcpkms.Signer
+	crypto.Signer
 	id string
 }
 
@@ -56,7 +168,7 @@ type signerWithID struct {
 func NewProcessor(kms *kms.KeyManagementClient, config *config.JustificationConfig) *Processor {
 	cache := cache.New[*signerWithID](config.SignerCacheTimeout)
 	return &Processor{
-		kms:    kms,
+		keys:   jvscrypto.NewKMSKeyManager(kms),
 		config: config,
 		cache:  cache,
 		validators: map[string]jvspb.Validator{
@@ -68,11 +180,36 @@ func NewProcessor(kms *kms.KeyManagementClient, config *config.JustificationConf
 const (
 	cacheKey = "signer"
 
+	// AnnotationCategoryWarning is the annotation set on justifications that
+	// used a deprecated category.
+	AnnotationCategoryWarning = "category_warning"
+
 	// DefaultAudience is the default audience used in justification tokens. It
 	// can be overridden with the audiences in the justification request.
 	DefaultAudience = "dev.abcxyz.jvs"
+
+	// MaxJustificationsSize is the maximum total size in bytes of the
+	// categories and values of the justifications of a request.
+	MaxJustificationsSize = 4_000
 )
 
+// WithLocalSigner signs tokens with the local key instead of KMS. The key ID is
+// set as the "kid" header of tokens.
+func (p *Processor) WithLocalSigner(signer crypto.Signer, keyID string) *Processor {
+	p.localSigner = &signerWithID{
+		Signer: signer,
+		id:     keyID,
+	}
+	return p
+}
+
+// WithKeyManager signs tokens with the primary version of the key in the key
+// manager instead of Cloud KMS.
+func (p *Processor) WithKeyManager(m jvscrypto.KeyManager) *Processor {
+	p.keys = m
+	return p
+}
+
 // WithValidators adds validators to the processor.
 func (p *Processor) WithValidators(v map[string]jvspb.Validator) *Processor {
 	for k, validator := range v {
@@ -81,64 +218,588 @@ func (p *Processor) WithValidators(v map[string]jvspb.Validator) *Processor {
 	return p
 }
 
-// Validators returns all the validators allowed by this processor.
+// WithEncryptionKeys encrypts justifications in issued tokens to the given
+// recipient keys.
+func (p *Processor) WithEncryptionKeys(keys jwk.Set) *Processor {
+	p.encryptionKeys = keys
+	return p
+}
+
+// WithValidationCache caches successful validation results of each category
+// for the given TTL. Results are cached per justification value and requestor.
+func (p *Processor) WithValidationCache(ttls map[string]time.Duration) *Processor {
+	if p.validationCaches == nil {
+		p.validationCaches = make(map[string]*cache.Cache[*jvspb.ValidateJustificationResponse], len(ttls))
+	}
+	for category, ttl := range ttls {
+		p.validationCaches[category] = cache.New[*jvspb.ValidateJustificationResponse](ttl)
+	}
+	return p
+}
+
+// WithCategoryAliases accepts justifications with the deprecated categories,
+// keys of the map, as the new categories, values of the map.
+func (p *Processor) WithCategoryAliases(aliases map[string]string) *Processor {
+	p.categoryAliases = aliases
+	return p
+}
+
+// WithClaimAliases duplicates the claims of minted tokens, values of the map,
+// under the alternate names, keys of the map.
+func (p *Processor) WithClaimAliases(aliases map[string]string) *Processor {
+	p.claimAliases = aliases
+	return p
+}
+
+// WithValuePatterns rejects justifications whose values don't match the
+// pattern of their category, without calling the validator. The patterns
+// override those declared in the UI data of plugins.
+func (p *Processor) WithValuePatterns(patterns map[string]*regexp.Regexp) *Processor {
+	p.valuePatterns = patterns
+	return p
+}
+
+// WithRequiredAnnotations refuses to issue tokens with justifications whose
+// validator didn't set the annotations required for their category.
+func (p *Processor) WithRequiredAnnotations(required map[string][]string) *Processor {
+	p.requiredAnnotations = required
+	return p
+}
+
+// WithEventPublisher publishes an event for every issued and denied token.
+func (p *Processor) WithEventPublisher(publisher events.Publisher) *Processor {
+	p.publisher = publisher
+	return p
+}
+
+// WithAuditSink writes the audit entry of every issued token to the sink, in
+// addition to the audit log. It adds an [AuditSinkHook].
+func (p *Processor) WithAuditSink(sink audit.Sink) *Processor {
+	return p.WithPostIssueHooks(AuditSinkHook(sink))
+}
+
+// WithTransparencyLog publishes the digest of the audit entry of every issued
+// token to the transparency log, and records the log's inclusion proof in the
+// entry.
+func (p *Processor) WithTransparencyLog(l transparency.Log) *Processor {
+	p.transparencyLog = l
+	return p
+}
+
+// WithGroupsResolver embeds the groups the requestor is a member of, as
+// resolved by the resolver, in the "groups" claim of tokens.
+func (p *Processor) WithGroupsResolver(r groups.Resolver) *Processor {
+	p.groups = r
+	return p
+}
+
+// WithDenyList rejects requests whose requestor or subject is on the deny
+// list, before validating their justifications.
+func (p *Processor) WithDenyList(d *DenyList) *Processor {
+	p.denyList = d
+	return p
+}
+
+// WithRevocations rejects requests whose requestor or subject is revoked, and
+// lets [Processor.Deprovision] revoke principals.
+func (p *Processor) WithRevocations(l *revocation.List) *Processor {
+	p.revocations = l
+	return p
+}
+
+// WithQuotas rejects requests of requestors who exhausted the daily quota of
+// a category of their justifications, after validating them.
+func (p *Processor) WithQuotas(q *quota.Quotas) *Processor {
+	p.quotas = q
+	return p
+}
+
+// WithPolicy applies the runtime policy to requests: justifications with
+// disabled categories are rejected, TTLs are capped per category, and tokens
+// are only issued for allowlisted audiences. Changes to the policy apply to
+// the next request.
+func (p *Processor) WithPolicy(s *PolicyStore) *Processor {
+	p.policy = s
+	return p
+}
+
+// WithShadowCategories validates the categories in shadow mode. Their
+// validators are called and the results are logged, but justifications are
+// accepted even if they fail validation. That way, a new validator can be
+// rolled out and its false positive rate measured before enforcing it.
+func (p *Processor) WithShadowCategories(categories []string) *Processor {
+	if p.shadowCategories == nil {
+		p.shadowCategories = make(map[string]struct{}, len(categories))
+	}
+	for _, c := range categories {
+		p.shadowCategories[c] = struct{}{}
+	}
+	return p
+}
+
+// Issuer returns the issuer (iss) of the tokens this processor issues.
+func (p *Processor) Issuer() string {
+	return p.config.Issuer
+}
+
+// Validators returns all the validators allowed by this processor. Validators
+// of deprecated categories are excluded, since requests for those categories
+// are validated as the new category.
 func (p *Processor) Validators() map[string]jvspb.Validator {
-	return p.validators
+	if len(p.categoryAliases) == 0 {
+		return p.validators
+	}
+
+	validators := make(map[string]jvspb.Validator, len(p.validators))
+	for k, v := range p.validators {
+		if _, ok := p.categoryAliases[k]; !ok {
+			validators[k] = v
+		}
+	}
+	return validators
+}
+
+// ListCategories returns the categories accepted by this processor, sorted by
+// name, with their deprecated aliases.
+func (p *Processor) ListCategories(ctx context.Context) ([]*jvspb.Category, error) {
+	aliases := make(map[string][]string, len(p.categoryAliases))
+	for alias, target := range p.categoryAliases {
+		aliases[target] = append(aliases[target], alias)
+	}
+
+	validators := p.Validators()
+	categories := make([]*jvspb.Category, 0, len(validators))
+	for name, v := range validators {
+		if p.categoryDisabled(name) {
+			continue
+		}
+
+		uiData, err := v.GetUIData(ctx, &jvspb.GetUIDataRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ui data of category %q: %w", name, err)
+		}
+
+		pattern := uiData.GetValuePattern()
+		if re, ok := p.valuePatterns[name]; ok {
+			pattern = re.String()
+		}
+
+		slices.Sort(aliases[name])
+		categories = append(categories, &jvspb.Category{
+			Name:              name,
+			DisplayName:       uiData.GetDisplayName(),
+			Hint:              uiData.GetHint(),
+			DeprecatedAliases: aliases[name],
+			ValuePattern:      pattern,
+			ValidatorStatus:   validatorStatus(name, v),
+		})
+	}
+	slices.SortFunc(categories, func(a, b *jvspb.Category) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return categories, nil
+}
+
+// ValidatorStatuses returns the status of the validator of every category,
+// including disabled ones, sorted by category.
+func (p *Processor) ValidatorStatuses() []*jvspb.ValidatorStatus {
+	statuses := make([]*jvspb.ValidatorStatus, 0, len(p.validators))
+	for name, v := range p.validators {
+		statuses = append(statuses, validatorStatus(name, v))
+	}
+	slices.SortFunc(statuses, func(a, b *jvspb.ValidatorStatus) int {
+		return strings.Compare(a.GetCategory(), b.GetCategory())
+	})
+	return statuses
+}
+
+// validatorStatus returns the status of the validator of the category.
+// Validators that don't record their calls, such as the built-in "explanation"
+// validator, only report their kind.
+func validatorStatus(category string, v jvspb.Validator) *jvspb.ValidatorStatus {
+	if m, ok := v.(*plugin.Monitor); ok {
+		return m.Status(category)
+	}
+	return &jvspb.ValidatorStatus{
+		Category: category,
+		Kind:     plugin.KindBuiltin,
+	}
 }
 
 // CreateToken implements the create token API which creates and signs a JWT
 // token if the provided justifications are valid.
 func (p *Processor) CreateToken(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) ([]byte, error) {
+	token, _, err := p.CreateTokenWithReceipt(ctx, requestor, req)
+	return token, err
+}
+
+// CreateTokenWithReceipt is like [Processor.CreateToken], but also returns the
+// signed receipt of the token. The receipt is nil unless receipts are enabled.
+func (p *Processor) CreateTokenWithReceipt(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) ([]byte, []byte, error) {
+	resp, err := p.CreateJustification(ctx, requestor, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	var receipt []byte
+	if r := resp.GetReceipt(); r != "" {
+		receipt = []byte(r)
+	}
+	return []byte(resp.GetToken()), receipt, nil
+}
+
+// CreateJustification is like [Processor.CreateTokenWithReceipt], but returns
+// the token, the receipt and the non-fatal warnings of the validators in the
+// API response.
+func (p *Processor) CreateJustification(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) (*jvspb.CreateJustificationResponse, error) {
 	now := time.Now().UTC()
 
 	logger := logging.FromContext(ctx)
 
-	if err := p.runValidations(ctx, req); err != nil {
+	if err := p.checkMaintenance(); err != nil {
 		return nil, err
 	}
 
-	token, err := p.createToken(ctx, requestor, req, now)
+	// The request deadline covers validation, building the claims, getting the
+	// signer and signing.
+	// Recording the issued token isn't cut short by it.
+	recordCtx := ctx
+	if d := p.config.RequestDeadline; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	var stages requestStages
+	var timedOut string
+	defer func() { stages.log(recordCtx, timedOut) }()
+	deadlineExceeded := func(stage string, err error) error {
+		derr := deadlineError(ctx, stage)
+		if derr != nil {
+			logger.ErrorContext(ctx, "request deadline exceeded", "stage", stage, "error", err)
+			timedOut = stage
+		}
+		return derr
+	}
+
+	stages.start(stageValidation)
+	warnings, quotaKeys, err := p.validateRequest(ctx, requestor, req)
+	if err != nil {
+		if derr := deadlineExceeded(stageValidation, err); derr != nil {
+			return nil, derr
+		}
+		return nil, err
+	}
+	stages.end()
+
+	// Quotas are consumed on validation, so concurrent requests can't exceed
+	// them, and refunded if the token isn't issued.
+	var issued bool
+	defer func() {
+		if !issued {
+			p.refundQuotas(recordCtx, quotaKeys)
+		}
+	}()
+
+	stages.start(stageClaims)
+	token, grants, err := p.createToken(ctx, requestor, req, now)
 	if err != nil {
+		if derr := deadlineExceeded(stageClaims, err); derr != nil {
+			return nil, derr
+		}
 		logger.ErrorContext(ctx, "failed to create token", "error", err)
 		return nil, status.Errorf(codes.Internal, "failed to create token: %s", err)
 	}
+	stages.end()
 
-	signer, err := p.cache.WriteThruLookup(cacheKey, func() (*signerWithID, error) {
-		return p.getPrimarySigner(ctx)
-	})
+	// The PAM grants name the token in their justification, so they are filed
+	// before signing and revoked if it fails.
+	defer func() {
+		if !issued {
+			p.revokeGrants(recordCtx, grants, token.JwtID(), "failed to sign token")
+		}
+	}()
+
+	stages.start(stageSigner)
+	signer, err := p.signer(ctx)
 	if err != nil {
+		if derr := deadlineExceeded(stageSigner, err); derr != nil {
+			return nil, derr
+		}
 		logger.ErrorContext(ctx, "failed to get token signer", "error", err)
 		return nil, status.Errorf(codes.Internal, "failed to get token signer: %s", err)
 	}
 
-	// Build custom headers and set the "kid" as the signer ID.
-	headers := jws.NewHeaders()
-	if err := headers.Set(jws.KeyIDKey, signer.id); err != nil {
-		logger.ErrorContext(ctx, "failed to set kid header", "error", err)
+	headers, err := p.tokenHeaders(signer.id)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to set token headers", "error", err)
 		return nil, status.Errorf(codes.Internal, "failed to set token headers: %s", err)
 	}
 
-	// Sign the token.
-	b, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, signer, jws.WithProtectedHeaders(headers)))
-	if err != nil {
+	// Sign the token. Signers don't take a context, so the deadline is enforced
+	// around them.
+	stages.start(stageSigning)
+	var b []byte
+	if err := runWithContext(ctx, func() error {
+		var err error
+		b, err = jwt.Sign(token, jwt.WithKey(jwa.ES256, signer, jws.WithProtectedHeaders(headers)))
+		return err //nolint:wrapcheck // Logged by the caller.
+	}); err != nil {
+		if derr := deadlineExceeded(stageSigning, err); derr != nil {
+			return nil, derr
+		}
 		logger.ErrorContext(ctx, "failed to sign token", "error", err)
 		return nil, status.Error(codes.Internal, "failed to sign token")
 	}
 
+	entry := p.auditEntry(requestor, token, req.GetJustifications())
+	var receipt []byte
+	if p.config.Receipts {
+		if err := runWithContext(ctx, func() error {
+			var err error
+			receipt, err = signReceipt(signer, entry)
+			return err
+		}); err != nil {
+			if derr := deadlineExceeded(stageSigning, err); derr != nil {
+				return nil, derr
+			}
+			logger.ErrorContext(ctx, "failed to sign receipt", "error", err)
+			return nil, status.Error(codes.Internal, "failed to sign receipt")
+		}
+		entry.Receipt = string(receipt)
+	}
+	stages.end()
+	issued = true
+	p.recordIssued(recordCtx, &IssuedToken{
+		Requestor: requestor,
+		Request:   req,
+		Token:     token,
+		Entry:     entry,
+	})
+
+	return &jvspb.CreateJustificationResponse{
+		Token:     string(b),
+		Receipt:   string(receipt),
+		Warnings:  warnings,
+		ExpiresAt: timestamppb.New(token.Expiration().Truncate(time.Second)), // exp has second precision.
+		Jti:       token.JwtID(),
+		Kid:       signer.id,
+		Issuer:    token.Issuer(),
+	}, nil
+}
+
+// validateRequest checks the deny list, runs the pre-issue hooks, validates the
+// request and consumes its quotas, and publishes a token denied event if it's
+// invalid.
+// It returns the warnings of the validators of a valid request, and the keys of
+// the consumed quotas, which the caller must refund if the token isn't issued.
+func (p *Processor) validateRequest(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) ([]*jvspb.ValidationWarning, []quota.Key, error) {
+	var rec *decisionRecorder
+	if p.decisionTraces != nil {
+		ctx, rec = withDecisionRecorder(ctx)
+	}
+
+	var warnings []*jvspb.ValidationWarning
+	var quotaKeys []quota.Key
+	err := p.checkDenyList(ctx, requestor, req.GetSubject())
+	recordDecisionStep(ctx, decisionStageDenyList, "deny_list", err)
+	var categoryWarnings map[*jvspb.Justification]string
+	if err == nil {
+		// Hooks see the categories the justifications are validated as.
+		categoryWarnings = p.aliasCategories(ctx, req)
+		err = p.runPreIssueHooks(ctx, requestor, req)
+	}
+	if err == nil {
+		warnings, err = p.runValidations(ctx, requestor, req, categoryWarnings)
+	}
+	if err == nil && p.quotas != nil {
+		quotaKeys, err = p.consumeQuotas(ctx, requestor, req)
+		recordDecisionStep(ctx, decisionStageQuota, "quota", err)
+	}
+	if err == nil {
+		return warnings, quotaKeys, nil
+	}
+
+	categories := make([]string, 0, len(req.GetJustifications()))
+	for _, j := range req.GetJustifications() {
+		categories = append(categories, j.GetCategory())
+	}
+	p.publish(ctx, events.New(events.TypeTokenDenied, requestor, &events.TokenDenied{
+		Requestor:  requestor,
+		Categories: categories,
+		Reason:     status.Convert(err).Message(),
+	}))
+	if rec != nil {
+		err = p.recordDecision(ctx, rec, requestor, req, err)
+	}
+	return nil, nil, err
+}
+
+// auditEntry returns the audit entry of an issued token.
+func (p *Processor) auditEntry(requestor string, token jwt.Token, justs []*jvspb.Justification) *audit.Entry {
+	entry := audit.NewEntry(requestor, token, justs)
+	if p.encryptionKeys != nil {
+		// Don't leak the justifications we just encrypted into the logs.
+		entry.RedactValues()
+	}
+	return entry
+}
+
+// recordIssued logs the audit entry of an issued token, publishes it, and
+// runs the post-issue hooks.
+func (p *Processor) recordIssued(ctx context.Context, issued *IssuedToken) {
+	logger := logging.FromContext(ctx)
+	entry := issued.Entry
+
+	p.publishTransparency(ctx, entry)
+	logger.InfoContext(ctx, audit.LogMessage, audit.LogKey, entry)
+	p.publish(ctx, events.New(events.TypeTokenIssued, entry.ID, entry))
+	p.runPostIssueHooks(ctx, issued)
+}
+
+// publishTransparency publishes the digest of the audit entry to the
+// transparency log, if there is one, and records the log entry in the audit
+// entry. Failing to publish is logged, but does not fail the request.
+func (p *Processor) publishTransparency(ctx context.Context, entry *audit.Entry) {
+	if p.transparencyLog == nil {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	digest, err := entry.Digest()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to digest audit entry", "jti", entry.ID, "error", err)
+		return
+	}
+	signer, err := p.signer(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get signer", "jti", entry.ID, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.TransparencyLogTimeout)
+	defer cancel()
+
+	t, err := p.transparencyLog.Publish(ctx, digest, signer)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to publish audit entry to transparency log",
+			"jti", entry.ID,
+			"error", err)
+		return
+	}
+	entry.Transparency = t
+}
+
+// publish publishes the event, if there is a publisher. Failing to publish is
+// logged, but does not fail the request.
+func (p *Processor) publish(ctx context.Context, event *events.Event) {
+	if p.publisher == nil {
+		return
+	}
+	if err := p.publisher.Publish(ctx, event); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to publish event",
+			"type", event.Type,
+			"error", err)
+	}
+}
+
+// SignPayload creates a detached JWS over the SHA-256 digest of a payload,
+// signed by the same key as justification tokens. The caller's digest is
+// wrapped in an envelope and signed unencoded, so the signature can't be
+// replayed as a token. The returned signature records the requestor and time
+// in its protected headers.
+func (p *Processor) SignPayload(ctx context.Context, requestor string, digest []byte) ([]byte, error) {
+	now := time.Now().UTC()
+
+	logger := logging.FromContext(ctx)
+
+	if got, want := len(digest), sha256.Size; got != want {
+		return nil, status.Errorf(codes.InvalidArgument, "sha256 digest must be %d bytes, got %d", want, got)
+	}
+
+	if err := p.checkMaintenance(); err != nil {
+		return nil, err
+	}
+
+	if err := p.checkDenyList(ctx, requestor, ""); err != nil {
+		return nil, err
+	}
+
+	signer, err := p.signer(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get token signer", "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to get token signer: %s", err)
+	}
+
+	headers := jws.NewHeaders()
+	for k, v := range map[string]any{
+		jws.KeyIDKey:                   signer.id,
+		jws.TypeKey:                    jvspb.SignatureType,
+		jws.CriticalKey:                []string{"b64"},
+		"b64":                          false,
+		jvspb.SignatureRequestorHeader: requestor,
+		jvspb.SignatureIssuedAtHeader:  now.Unix(),
+	} {
+		if err := headers.Set(k, v); err != nil {
+			logger.ErrorContext(ctx, "failed to set signature header", "header", k, "error", err)
+			return nil, status.Errorf(codes.Internal, "failed to set signature headers: %s", err)
+		}
+	}
+
+	b, err := jws.Sign(nil,
+		jws.WithKey(jwa.ES256, signer, jws.WithProtectedHeaders(headers)),
+		jws.WithDetachedPayload(jvspb.SignatureEnvelope(digest)))
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to sign payload", "error", err)
+		return nil, status.Error(codes.Internal, "failed to sign payload")
+	}
+
+	logger.InfoContext(ctx, "signed payload",
+		"requestor", requestor,
+		"sha256_digest", hex.EncodeToString(digest))
+
 	return b, nil
 }
 
+// Ready returns an error if the processor can't sign tokens. It checks the
+// signing key has an enabled primary version, and loads the signer into the
+// cache so the first request doesn't have to. A local signer is always ready.
+func (p *Processor) Ready(ctx context.Context) error {
+	if p.localSigner != nil {
+		return nil
+	}
+
+	if err := jvscrypto.CheckPrimaryVersion(ctx, p.keys, p.config.KeyName); err != nil {
+		return fmt.Errorf("signing key is not ready: %w", err)
+	}
+	if _, err := p.cache.WriteThruLookup(cacheKey, func() (*signerWithID, error) {
+		return p.getPrimarySigner(ctx)
+	}); err != nil {
+		return fmt.Errorf("failed to load signer: %w", err)
+	}
+	return nil
+}
+
+// signer returns the local signer if set, or the signer of the primary version
+// of the key.
+func (p *Processor) signer(ctx context.Context) (*signerWithID, error) {
+	if p.localSigner != nil {
+		return p.localSigner, nil
+	}
+	return p.cache.WriteThruLookup(cacheKey, func() (*signerWithID, error) {
+		return p.getPrimarySigner(ctx)
+	})
+}
+
 func (p *Processor) getPrimarySigner(ctx context.Context) (*signerWithID, error) {
-	primaryVer, err := jvscrypto.GetPrimary(ctx, p.kms, p.config.KeyName)
+	primaryVer, err := p.keys.GetPrimary(ctx, p.config.KeyName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine primary signing key: %w", err)
 	}
 	if primaryVer == "" {
 		return nil, fmt.Errorf("no primary version found")
 	}
-	sig, err := gcpkms.NewSigner(ctx, p.kms, primaryVer)
+	sig, err := p.keys.Signer(ctx, primaryVer)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create signer: %w", err)
+		return nil, err
 	}
 	return &signerWithID{
 		Signer: sig,
@@ -146,57 +807,187 @@ func (p *Processor) getPrimarySigner(ctx context.Context) (*signerWithID, error)
 	}, nil
 }
 
-// runValidations is an internal helper function that validates requests.
+// aliasCategories renames deprecated categories of the justifications of the
+// request to their new names, and returns the deprecation warnings, keyed by
+// justification.
+func (p *Processor) aliasCategories(ctx context.Context, req *jvspb.CreateJustificationRequest) map[*jvspb.Justification]string {
+	logger := logging.FromContext(ctx)
+
+	var warnings map[*jvspb.Justification]string
+	for _, j := range req.GetJustifications() {
+		target, ok := p.categoryAliases[j.GetCategory()]
+		if !ok {
+			continue
+		}
+		logger.WarnContext(ctx, "deprecated justification category",
+			"category", j.GetCategory(),
+			"new_category", target)
+		if warnings == nil {
+			warnings = make(map[*jvspb.Justification]string)
+		}
+		warnings[j] = fmt.Sprintf("category %q is deprecated, use %q instead", j.GetCategory(), target)
+		j.Category = target
+	}
+	return warnings
+}
+
+// runValidations is an internal helper function that validates requests, the
+// categories of which are already renamed by [Processor.aliasCategories], with
+// its warnings.
 // If any errors occur during validation, it returns a standard internal error message with codes.Internal.
 // If the request fails validation, it returns full error messages with codes.InvalidArgument.
-func (p *Processor) runValidations(ctx context.Context, req *jvspb.CreateJustificationRequest) error {
+func (p *Processor) runValidations(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest, categoryWarnings map[*jvspb.Justification]string) ([]*jvspb.ValidationWarning, error) {
 	logger := logging.FromContext(ctx)
 	if len(req.GetJustifications()) < 1 {
-		return status.Errorf(codes.InvalidArgument, "failed to validate request: no justifications specified")
+		err := status.Errorf(codes.InvalidArgument, "failed to validate request: no justifications specified")
+		recordDecisionStep(ctx, decisionStageValidation, "justifications", err)
+		return nil, err
+	}
+
+	if thumbprint := req.GetJwkThumbprint(); thumbprint != "" {
+		err := jvspb.ValidateJWKThumbprint(thumbprint)
+		recordDecisionStep(ctx, decisionStageValidation, "jwk_thumbprint", err)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to validate request: invalid jwk thumbprint: %v", err)
+		}
+	}
+
+	err := p.validateStartTime(req)
+	recordDecisionStep(ctx, decisionStageValidation, "start_time", err)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to validate request: %v", err)
+	}
+
+	err = p.validateAudiences(req)
+	recordDecisionStep(ctx, decisionStageValidation, "audiences", err)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to validate request: %v", err)
+	}
+
+	if p.policyExpression != nil {
+		err = p.checkPolicyExpression(ctx, requestor, req)
+		recordDecisionStep(ctx, decisionStageValidation, "policy_expression", err)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var validationErr, internalErr error
+	var warnings []*jvspb.ValidationWarning
 
-	var justificationsLength int
+	var justificationsLength, annotationsLength int
 	for _, j := range req.GetJustifications() {
 		justificationsLength += len(j.GetCategory()) + len(j.GetValue())
 
+		categoryWarning := categoryWarnings[j]
+
+		rule := "validator:" + j.GetCategory()
 		v, ok := p.validators[j.GetCategory()]
 		if !ok {
-			validationErr = errors.Join(validationErr, fmt.Errorf("category %q is not supported", j.GetCategory()))
+			err := fmt.Errorf("category %q is not supported", j.GetCategory())
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			validationErr = errors.Join(validationErr, err)
 			continue
 		}
-		resp, verr := v.Validate(ctx, &jvspb.ValidateJustificationRequest{
-			Justification: j,
-		})
+		if p.categoryDisabled(j.GetCategory()) {
+			err := fmt.Errorf("category %q is disabled", j.GetCategory())
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			validationErr = errors.Join(validationErr, err)
+			continue
+		}
+		re, err := p.valuePattern(ctx, j.GetCategory(), v)
+		if err != nil {
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			internalErr = errors.Join(internalErr, err)
+			continue
+		}
+		if re != nil && !re.MatchString(j.GetValue()) {
+			err := fmt.Errorf("justification value for category %q must match %q", j.GetCategory(), re.String())
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			validationErr = errors.Join(validationErr, err)
+			continue
+		}
+
+		resp, verr := p.validate(ctx, v, requestor, j)
+		_, shadow := p.shadowCategories[j.GetCategory()]
+		if shadow {
+			resp, verr = shadowResult(ctx, j, resp, verr)
+		}
 		if verr != nil {
-			internalErr = errors.Join(internalErr, fmt.Errorf("unexpected error from validator %q: %w", j.GetCategory(), verr))
+			err := fmt.Errorf("unexpected error from validator %q: %w", j.GetCategory(), verr)
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			internalErr = errors.Join(internalErr, err)
 			continue
 		}
 
 		if !resp.GetValid() {
-			validationErr = errors.Join(validationErr,
-				fmt.Errorf("failed validation criteria with error %v and warning %v", resp.GetError(), resp.GetWarning()))
+			err := fmt.Errorf("failed validation criteria with error %v and warning %v", resp.GetError(), resp.GetWarning())
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			validationErr = errors.Join(validationErr, err)
+		} else if missing := jvspb.MissingAnnotations(&jvspb.Justification{
+			Category:   j.GetCategory(),
+			Annotation: resp.GetAnnotation(),
+		}, p.requiredAnnotations); len(missing) > 0 && !shadow {
+			// The validator, not the requestor, is at fault.
+			err := fmt.Errorf("validator %q did not set required annotations %q", j.GetCategory(), missing)
+			recordDecisionStep(ctx, decisionStageValidation, rule, err)
+			internalErr = errors.Join(internalErr, err)
+			continue
+		} else {
+			recordDecisionStep(ctx, decisionStageValidation, rule, nil)
+		}
+
+		if categoryWarning != "" {
+			warnings = append(warnings, &jvspb.ValidationWarning{
+				Category: j.GetCategory(),
+				Message:  categoryWarning,
+			})
+		}
+		for _, w := range resp.GetWarning() {
+			warnings = append(warnings, &jvspb.ValidationWarning{
+				Category: j.GetCategory(),
+				Message:  w,
+			})
 		}
 
 		j.Annotation = resp.GetAnnotation()
+		if categoryWarning != "" {
+			// The response may be cached, so don't modify its annotations.
+			annotation := make(map[string]string, len(j.GetAnnotation())+1)
+			maps.Copy(annotation, j.GetAnnotation())
+			annotation[AnnotationCategoryWarning] = categoryWarning
+			j.Annotation = annotation
+		}
+		for k, v := range j.GetAnnotation() {
+			annotationsLength += len(k) + len(v)
+		}
 	}
 
 	// This isn't perfect, but it's the easiest place to get "close" to limiting
 	// the size.
-	if got, maximum := justificationsLength, 4_000; got > maximum {
-		validationErr = errors.Join(validationErr, fmt.Errorf("justification size (%d bytes) must be less than %d bytes",
+	var sizeErr error
+	if got, maximum := justificationsLength, MaxJustificationsSize; got > maximum {
+		sizeErr = errors.Join(sizeErr, fmt.Errorf("justification size (%d bytes) must be less than %d bytes",
+			got, maximum))
+	}
+
+	// Annotations come from plugins rather than the requestor, but end up in the
+	// token all the same.
+	if got, maximum := annotationsLength, p.config.MaxAnnotationSize; got > maximum {
+		sizeErr = errors.Join(sizeErr, fmt.Errorf("annotations size (%d bytes) from validators must be less than %d bytes",
 			got, maximum))
 	}
 
 	var audiencesLength int
-	for _, v := range req.GetAudiences() {
+	for _, v := range p.audiences(req) {
 		audiencesLength += len(v)
 	}
 	if got, maximum := audiencesLength, 1_000; got > maximum {
-		validationErr = errors.Join(validationErr, fmt.Errorf("audiences size (%d bytes) must be less than %d bytes",
+		sizeErr = errors.Join(sizeErr, fmt.Errorf("audiences size (%d bytes) must be less than %d bytes",
 			got, maximum))
 	}
+	recordDecisionStep(ctx, decisionStageValidation, "size_limits", sizeErr)
+	validationErr = errors.Join(validationErr, sizeErr)
 
 	// In case of internal errors, a standard internal error message will be shown to the user,
 	// even if there are validation errors. The complete internal error message will be logged along
@@ -205,34 +996,177 @@ func (p *Processor) runValidations(ctx context.Context, req *jvspb.CreateJustifi
 		logger.ErrorContext(ctx, "internal error during validation",
 			"error", internalErr,
 			"validation_error", validationErr)
-		return status.Errorf(codes.Internal, "unable to validate request")
+		return nil, status.Errorf(codes.Internal, "unable to validate request")
 	}
 
 	if validationErr != nil {
 		logger.WarnContext(ctx, "failed to validate token", "error", validationErr)
-		return status.Errorf(codes.InvalidArgument, "failed to validate request: %v", validationErr)
+		return nil, status.Errorf(codes.InvalidArgument, "failed to validate request: %v", validationErr)
+	}
+	return warnings, nil
+}
+
+// ValuePattern returns the pattern justification values of the category must
+// match, or nil if it has none.
+func (p *Processor) ValuePattern(ctx context.Context, category string) (*regexp.Regexp, error) {
+	v, ok := p.validators[category]
+	if !ok {
+		return nil, fmt.Errorf("category %q is not supported", category)
+	}
+	return p.valuePattern(ctx, category, v)
+}
+
+// valuePattern returns the configured pattern of the category, or else the
+// pattern in the UI data of its validator. Plugin patterns are looked up once,
+// but a failed lookup is retried on the next call, so a plugin that is down
+// doesn't lose its pattern for good.
+func (p *Processor) valuePattern(ctx context.Context, category string, v jvspb.Validator) (*regexp.Regexp, error) {
+	if re, ok := p.valuePatterns[category]; ok {
+		return re, nil
+	}
+
+	p.pluginPatternsMu.Lock()
+	defer p.pluginPatternsMu.Unlock()
+
+	if re, ok := p.pluginPatterns[category]; ok {
+		return re, nil
+	}
+
+	uiData, err := v.GetUIData(ctx, &jvspb.GetUIDataRequest{})
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to get value pattern of category",
+			"category", category,
+			"error", err)
+		return nil, nil
+	}
+
+	var re *regexp.Regexp
+	if pattern := uiData.GetValuePattern(); pattern != "" {
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value pattern from validator %q: %w", category, err)
+		}
+	}
+	if p.pluginPatterns == nil {
+		p.pluginPatterns = make(map[string]*regexp.Regexp)
+	}
+	p.pluginPatterns[category] = re
+	return re, nil
+}
+
+// validate validates the justification, using the category's validation cache
+// if it has one. Only valid results are cached, so a rejected justification can
+// be fixed and retried right away.
+func (p *Processor) validate(ctx context.Context, v jvspb.Validator, requestor string, j *jvspb.Justification) (*jvspb.ValidateJustificationResponse, error) {
+	req := &jvspb.ValidateJustificationRequest{
+		Justification: j,
+		Requestor:     requestor,
+	}
+
+	c, ok := p.validationCaches[j.GetCategory()]
+	if !ok {
+		return v.Validate(ctx, req)
+	}
+
+	key := validationCacheKey(requestor, j.GetValue())
+	if resp, ok := c.Lookup(key); ok {
+		return resp, nil
+	}
+
+	resp, err := v.Validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.GetValid() {
+		c.Set(key, resp)
+	}
+	return resp, nil
+}
+
+// shadowResult logs the validation result of a category in shadow mode and
+// returns a result that never blocks issuance. Annotations are only kept from
+// valid results.
+func shadowResult(ctx context.Context, j *jvspb.Justification, resp *jvspb.ValidateJustificationResponse, err error) (*jvspb.ValidateJustificationResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	switch {
+	case err != nil:
+		logger.WarnContext(ctx, "shadow validation",
+			"category", j.GetCategory(),
+			"valid", false,
+			"error", err)
+	case !resp.GetValid():
+		logger.InfoContext(ctx, "shadow validation",
+			"category", j.GetCategory(),
+			"valid", false,
+			"validation_errors", resp.GetError(),
+			"validation_warnings", resp.GetWarning())
+	default:
+		logger.InfoContext(ctx, "shadow validation",
+			"category", j.GetCategory(),
+			"valid", true)
+		return resp, nil
+	}
+	return &jvspb.ValidateJustificationResponse{Valid: true}, nil
+}
+
+// validationCacheKey returns the validation cache key of a justification value
+// for the requestor.
+func validationCacheKey(requestor, value string) string {
+	sum := sha256.Sum256([]byte(requestor + "\x00" + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateStartTime checks the request's start time, if any, is no further
+// ahead than the configured maximum start delay. Start times in the past are
+// allowed, and are treated as now.
+func (p *Processor) validateStartTime(req *jvspb.CreateJustificationRequest) error {
+	start := req.GetStartTime()
+	if start == nil {
+		return nil
+	}
+	if err := start.CheckValid(); err != nil {
+		return fmt.Errorf("invalid start time: %w", err)
+	}
+
+	delay := time.Until(start.AsTime())
+	if delay <= 0 {
+		return nil
+	}
+	if p.config.MaxStartDelay <= 0 {
+		return fmt.Errorf("scheduling tokens with a start time is not enabled")
+	}
+	if delay > p.config.MaxStartDelay {
+		return fmt.Errorf("start time cannot be more than %s ahead",
+			timeutil.HumanDuration(p.config.MaxStartDelay))
 	}
 	return nil
 }
 
 // createToken is an internal helper for testing that builds an unsigned jwt
-// token from the request.
-func (p *Processor) createToken(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest, now time.Time) (jwt.Token, error) {
-	ttl, err := computeTTL(req.GetTtl().AsDuration(), p.config.DefaultTTL, p.config.MaxTTL)
+// token from the request. It also returns the names of the PAM grants filed
+// for the token, which the caller must revoke if the token isn't issued. On
+// error, the grants are already revoked.
+func (p *Processor) createToken(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest, now time.Time) (_ jwt.Token, _ []string, retErr error) {
+	maxTTL := p.MaxTTL(req.GetJustifications())
+	ttl, err := computeTTL(req.GetTtl().AsDuration(), min(p.config.DefaultTTL, maxTTL), maxTTL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute ttl: %w", err)
+		return nil, nil, fmt.Errorf("failed to compute ttl: %w", err)
+	}
+
+	// Scheduled tokens become valid at their start time, and their ttl is
+	// measured from it.
+	start := now
+	if t := req.GetStartTime(); t != nil && t.AsTime().After(now) {
+		start = t.AsTime().UTC()
 	}
 
 	id := uuid.New().String()
-	exp := now.Add(ttl)
+	exp := start.Add(ttl)
 	justs := req.GetJustifications()
 	iss := p.config.Issuer
 
-	// Use audiences in the request if provided.
-	aud := req.GetAudiences()
-	if len(aud) == 0 {
-		aud = []string{DefaultAudience}
-	}
+	aud := p.audiences(req)
 
 	// If no subject was given, default to the caller's identity.
 	subject := req.GetSubject()
@@ -246,22 +1180,94 @@ func (p *Processor) createToken(ctx context.Context, requestor string, req *jvsp
 		IssuedAt(now).
 		Issuer(iss).
 		JwtID(id).
-		NotBefore(now).
+		NotBefore(start.Add(-p.config.NotBeforeLeeway)).
 		Subject(subject).
 		Build()
 	if err != nil {
-		return nil, fmt.Errorf("failed to build jwt: %w", err)
+		return nil, nil, fmt.Errorf("failed to build jwt: %w", err)
+	}
+
+	if err := jvspb.SetClaimVersion(token, p.config.ClaimVersion); err != nil {
+		return nil, nil, fmt.Errorf("failed to set claim version on jwt: %w", err)
 	}
 
 	if err := jvspb.SetRequestor(token, requestor); err != nil {
-		return nil, fmt.Errorf("failed to set requestor on jwt: %w", err)
+		return nil, nil, fmt.Errorf("failed to set requestor on jwt: %w", err)
+	}
+
+	// Tokens minted without authentication have no requestor to resolve.
+	if p.groups != nil && requestor != "" {
+		memberships, err := p.groups.Groups(ctx, requestor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve requestor groups: %w", err)
+		}
+		if err := jvspb.SetGroups(token, memberships); err != nil {
+			return nil, nil, fmt.Errorf("failed to set groups on jwt: %w", err)
+		}
 	}
 
-	if err := jvspb.SetJustifications(token, justs); err != nil {
-		return nil, fmt.Errorf("failed to set justifications on jwt: %w", err)
+	if p.config.AuditRouteProject != "" {
+		if err := jvspb.SetAuditRoute(token, &jvspb.AuditRoute{
+			Project: p.config.AuditRouteProject,
+			LogID:   p.config.AuditRouteLogID,
+		}); err != nil {
+			return nil, nil, fmt.Errorf("failed to set audit route on jwt: %w", err)
+		}
 	}
 
-	return token, nil
+	if thumbprint := req.GetJwkThumbprint(); thumbprint != "" {
+		if err := jvspb.SetConfirmation(token, thumbprint); err != nil {
+			return nil, nil, fmt.Errorf("failed to set confirmation on jwt: %w", err)
+		}
+	}
+
+	grants, err := p.requestGrants(ctx, subject, id, justs, ttl)
+	defer func() {
+		if retErr != nil {
+			p.revokeGrants(ctx, grants, id, "failed to create token")
+		}
+	}()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if p.encryptionKeys != nil {
+		if err := jvspb.EncryptJustifications(token, justs, p.encryptionKeys); err != nil {
+			return nil, nil, fmt.Errorf("failed to set encrypted justifications on jwt: %w", err)
+		}
+	} else if err := jvspb.SetJustifications(token, justs); err != nil {
+		return nil, nil, fmt.Errorf("failed to set justifications on jwt: %w", err)
+	}
+
+	if err := p.setCompatClaims(token, justs); err != nil {
+		return nil, nil, err
+	}
+
+	// Claim aliases are for verifiers expecting claims by name, so they are
+	// left outside of the namespace.
+	if ns := p.config.ClaimsNamespace; ns != "" {
+		if err := jvspb.NamespaceClaims(token, ns); err != nil {
+			return nil, nil, fmt.Errorf("failed to namespace claims on jwt: %w", err)
+		}
+	}
+
+	return token, grants, nil
+}
+
+// tokenHeaders returns the protected headers of tokens signed by the signer,
+// with the signer ID as the "kid" and the configured token type, if any, as
+// the "typ".
+func (p *Processor) tokenHeaders(kid string) (jws.Headers, error) {
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, kid); err != nil {
+		return nil, fmt.Errorf("failed to set kid header: %w", err)
+	}
+	if typ := p.config.TokenType; typ != "" {
+		if err := headers.Set(jws.TypeKey, typ); err != nil {
+			return nil, fmt.Errorf("failed to set typ header: %w", err)
+		}
+	}
+	return headers, nil
 }
 
 // computeTTL is a helper that computes the best TTL given the requested TTL,
@@ -15,15 +15,21 @@
 package justification
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -39,11 +45,16 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/audit"
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/events"
 	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/jvs/pkg/plugin"
 	"github.com/abcxyz/jvs/pkg/testutil"
 	"github.com/abcxyz/pkg/logging"
 	pkgtestutil "github.com/abcxyz/pkg/testutil"
@@ -77,6 +88,9 @@ func TestCreateToken(t *testing.T) {
 		wantErr            string
 		wantJustifications []*jvspb.Justification
 		serverErr          error
+		encrypt            bool
+		wantJKT            string
+		tokenType          string
 	}{
 		{
 			name: "happy_path",
@@ -98,6 +112,48 @@ func TestCreateToken(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "token_type",
+			request: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{
+						Category: "explanation",
+						Value:    "test",
+					},
+				},
+				Ttl: durationpb.New(3600 * time.Second),
+			},
+			tokenType:     "jvs+jwt",
+			wantTTL:       1 * time.Hour,
+			wantAudiences: []string{DefaultAudience},
+			wantJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "test",
+				},
+			},
+		},
+		{
+			name: "encrypted_justifications",
+			request: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{
+						Category: "explanation",
+						Value:    "sensitive incident details",
+					},
+				},
+				Ttl: durationpb.New(3600 * time.Second),
+			},
+			encrypt:       true,
+			wantTTL:       1 * time.Hour,
+			wantAudiences: []string{DefaultAudience},
+			wantJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "sensitive incident details",
+				},
+			},
+		},
 		{
 			name: "custom_subject",
 			request: &jvspb.CreateJustificationRequest{
@@ -163,6 +219,56 @@ func TestCreateToken(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "bound_to_key",
+			request: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{
+						Category: "explanation",
+						Value:    "test",
+					},
+				},
+				Ttl:           durationpb.New(3600 * time.Second),
+				JwkThumbprint: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+			},
+			wantTTL:       1 * time.Hour,
+			wantAudiences: []string{DefaultAudience},
+			wantJustifications: []*jvspb.Justification{
+				{
+					Category: "explanation",
+					Value:    "test",
+				},
+			},
+			wantJKT: "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs",
+		},
+		{
+			name: "invalid_jwk_thumbprint",
+			request: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{
+						Category: "explanation",
+						Value:    "test",
+					},
+				},
+				Ttl:           durationpb.New(3600 * time.Second),
+				JwkThumbprint: "not-a-thumbprint",
+			},
+			wantErr: "invalid jwk thumbprint: thumbprint must be a 32 byte sha256 digest, got 12 bytes",
+		},
+		{
+			name: "start_time_not_enabled",
+			request: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{
+						Category: "explanation",
+						Value:    "test",
+					},
+				},
+				Ttl:       durationpb.New(3600 * time.Second),
+				StartTime: timestamppb.New(time.Now().Add(time.Hour)),
+			},
+			wantErr: "failed to validate request: scheduling tokens with a start time is not enabled",
+		},
 		{
 			name: "no_justification",
 			request: &jvspb.CreateJustificationRequest{
@@ -280,6 +386,29 @@ func TestCreateToken(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "annotations_too_long",
+			request: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{
+						Category: "jira",
+						Value:    "test",
+					},
+				},
+				Ttl: durationpb.New(3600 * time.Second),
+			},
+			validators: map[string]jvspb.Validator{
+				"jira": &mockValidator{
+					resp: &jvspb.ValidateJustificationResponse{
+						Valid: true,
+						Annotation: map[string]string{
+							"jira_issue_description": strings.Repeat("test", 100),
+						},
+					},
+				},
+			},
+			wantErr: "annotations size (422 bytes) from validators must be less than 100 bytes",
+		},
 		{
 			name: "happy_path_with_unused_validator",
 			request: &jvspb.CreateJustificationRequest{
@@ -452,8 +581,41 @@ func TestCreateToken(t *testing.T) {
 				Issuer:             "test-iss",
 				DefaultTTL:         1 * time.Minute,
 				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+				NotBeforeLeeway:    30 * time.Second,
+				ClaimVersion:       jvspb.ClaimVersion1,
+				TokenType:          tc.tokenType,
 			}).WithValidators(tc.validators)
 
+			publisher := &fakePublisher{}
+			sink := &fakeSink{}
+			processor.WithEventPublisher(publisher).WithAuditSink(sink)
+
+			var encryptionKeys jwk.Set
+			if tc.encrypt {
+				raw, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				privateJWK, err := jwk.FromRaw(raw)
+				if err != nil {
+					t.Fatal(err)
+				}
+				publicJWK, err := privateJWK.PublicKey()
+				if err != nil {
+					t.Fatal(err)
+				}
+				recipients := jwk.NewSet()
+				if err := recipients.AddKey(publicJWK); err != nil {
+					t.Fatal(err)
+				}
+				encryptionKeys = jwk.NewSet()
+				if err := encryptionKeys.AddKey(privateJWK); err != nil {
+					t.Fatal(err)
+				}
+				processor.WithEncryptionKeys(recipients)
+			}
+
 			mockKeyManagement.Reqs = nil
 			mockKeyManagement.Err = tc.serverErr
 
@@ -477,7 +639,11 @@ func TestCreateToken(t *testing.T) {
 				t.Errorf("expected length %d to be %d: %#v", got, want, sigs)
 			} else {
 				headers := sigs[0].ProtectedHeaders()
-				if got, want := headers.Type(), "JWT"; got != want {
+				wantType := jvspb.DefaultTokenType
+				if tc.tokenType != "" {
+					wantType = tc.tokenType
+				}
+				if got, want := headers.Type(), wantType; got != want {
 					t.Errorf("typ: expected %q to be %q", got, want)
 				}
 				if got, want := string(headers.Algorithm()), "ES256"; got != want {
@@ -515,14 +681,22 @@ func TestCreateToken(t *testing.T) {
 			if got, want := len(token.JwtID()), 36; got != want {
 				t.Errorf("jti: expected length %d to be %d: %#v", got, want, token.JwtID())
 			}
-			if got := token.NotBefore(); !got.Before(now) {
-				t.Errorf("nbf: expected %q to be after %q (%q)", got, now, got.Sub(now))
+			if got, want := token.NotBefore(), token.IssuedAt().Add(-30*time.Second); !got.Equal(want) {
+				t.Errorf("nbf: expected %q to be %q", got, want)
 			}
 			if got, want := token.Subject(), tc.wantSubject; got != want {
 				t.Errorf("sub: expected %q to be %q", got, want)
 			}
 
 			// Validate custom claims.
+			gotVersion, err := jvspb.GetClaimVersion(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := gotVersion, jvspb.ClaimVersion1; got != want {
+				t.Errorf("jvs_ver: expected %d to be %d", got, want)
+			}
+
 			gotRequestor, err := jvspb.GetRequestor(token)
 			if err != nil {
 				t.Fatal(err)
@@ -531,7 +705,47 @@ func TestCreateToken(t *testing.T) {
 				t.Errorf("expected %q to be %q", got, want)
 			}
 
-			gotJustifications, err := jvspb.GetJustifications(token)
+			gotJKT, err := jvspb.GetConfirmation(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := gotJKT, tc.wantJKT; got != want {
+				t.Errorf("cnf: expected %q to be %q", got, want)
+			}
+
+			if got, want := len(publisher.events), 1; got != want {
+				t.Fatalf("expected %d events to be %d", got, want)
+			}
+			if got, want := publisher.events[0].Type, events.TypeTokenIssued; got != want {
+				t.Errorf("event type: expected %q to be %q", got, want)
+			}
+			if got, want := publisher.events[0].Subject, token.JwtID(); got != want {
+				t.Errorf("event subject: expected %q to be %q", got, want)
+			}
+
+			if got, want := len(sink.entries), 1; got != want {
+				t.Fatalf("expected %d sink entries to be %d", got, want)
+			}
+			if got, want := sink.entries[0].ID, token.JwtID(); got != want {
+				t.Errorf("sink entry jti: expected %q to be %q", got, want)
+			}
+			if got, want := sink.entries[0].Redacted, tc.encrypt; got != want {
+				t.Errorf("sink entry redacted: expected %t to be %t", got, want)
+			}
+
+			if got, want := jvspb.HasEncryptedJustifications(token), tc.encrypt; got != want {
+				t.Errorf("expected encrypted justifications %t to be %t", got, want)
+			}
+
+			var gotJustifications []*jvspb.Justification
+			if tc.encrypt {
+				if _, ok := token.Get(jvspb.JustificationsKey); ok {
+					t.Errorf("expected no plaintext justifications")
+				}
+				gotJustifications, err = jvspb.DecryptJustifications(token, encryptionKeys)
+			} else {
+				gotJustifications, err = jvspb.GetJustifications(token)
+			}
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -543,44 +757,178 @@ func TestCreateToken(t *testing.T) {
 	}
 }
 
-func TestComputeTTL(t *testing.T) {
+// fakePublisher records the published events.
+type fakePublisher struct {
+	mu     sync.Mutex
+	events []*events.Event
+}
+
+func (p *fakePublisher) Publish(_ context.Context, event *events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// fakeSink records the written audit entries.
+type fakeSink struct {
+	mu      sync.Mutex
+	entries []*audit.Entry
+}
+
+func (s *fakeSink) Write(_ context.Context, entries ...*audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func TestCreateToken_deniedEvent(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	publisher := &fakePublisher{}
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		MaxAnnotationSize:  100,
+	}).WithEventPublisher(publisher)
+
+	if _, err := processor.CreateToken(ctx, "you@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "jira", Value: "ABC-123"},
+		},
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if got, want := len(publisher.events), 1; got != want {
+		t.Fatalf("expected %d events to be %d", got, want)
+	}
+	event := publisher.events[0]
+	if got, want := event.Type, events.TypeTokenDenied; got != want {
+		t.Errorf("event type: expected %q to be %q", got, want)
+	}
+	want := &events.TokenDenied{
+		Requestor:  "you@example.com",
+		Categories: []string{"jira"},
+		Reason:     `failed to validate request: category "jira" is not supported`,
+	}
+	if diff := cmp.Diff(want, event.Data); diff != "" {
+		t.Errorf("event data (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCreateToken_localSigner(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, err := jvscrypto.LocalKeyID(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No KMS client, signing must not call KMS.
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		Signer:             config.SignerLocal,
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithLocalSigner(privateKey, keyID)
+
+	if err := processor.Ready(ctx); err != nil {
+		t.Fatalf("expected local signer to be ready: %s", err)
+	}
+
+	b, err := processor.CreateToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := jwk.FromRaw(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+		t.Fatal(err)
+	}
+	keys := jwk.NewSet()
+	if err := keys.AddKey(publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Parse(b, jwt.WithKeySet(keys, jws.WithRequireKid(true)))
+	if err != nil {
+		t.Fatalf("failed to verify token with the local key: %s", err)
+	}
+	if got, want := token.Issuer(), "test-iss"; got != want {
+		t.Errorf("iss: expected %q to be %q", got, want)
+	}
+	// Without a claim version configured, tokens have the legacy layout.
+	if _, ok := token.Get(jvspb.VersionKey); ok {
+		t.Errorf("expected no %s claim", jvspb.VersionKey)
+	}
+}
+
+// fakeGroupsResolver returns the same groups for every requestor.
+type fakeGroupsResolver struct {
+	groups []string
+	err    error
+}
+
+func (r *fakeGroupsResolver) Groups(_ context.Context, _ string) ([]string, error) {
+	return r.groups, r.err
+}
+
+func TestCreateToken_groups(t *testing.T) {
 	t.Parallel()
 
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
 	cases := []struct {
-		name string
-		req  time.Duration
-		def  time.Duration
-		max  time.Duration
-		exp  time.Duration
-		err  string
+		name       string
+		requestor  string
+		resolver   *fakeGroupsResolver
+		wantGroups []string
+		wantErr    string
 	}{
 		{
-			name: "request_zero_uses_default",
-			req:  0,
-			def:  15 * time.Minute,
-			max:  30 * time.Minute,
-			exp:  15 * time.Minute,
+			name:       "embeds_groups",
+			requestor:  "me@example.com",
+			resolver:   &fakeGroupsResolver{groups: []string{"a@example.com", "b@example.com"}},
+			wantGroups: []string{"a@example.com", "b@example.com"},
 		},
 		{
-			name: "request_negative_uses_default",
-			req:  -10 * time.Second,
-			def:  15 * time.Minute,
-			max:  30 * time.Minute,
-			exp:  15 * time.Minute,
+			name:       "no_groups",
+			requestor:  "me@example.com",
+			resolver:   &fakeGroupsResolver{},
+			wantGroups: []string{},
 		},
 		{
-			name: "request_uses_self_in_bounds",
-			req:  12 * time.Minute,
-			def:  15 * time.Minute,
-			max:  30 * time.Minute,
-			exp:  12 * time.Minute,
+			// Without authentication, there is no requestor to resolve.
+			name:      "no_requestor",
+			requestor: "",
+			resolver:  &fakeGroupsResolver{err: fmt.Errorf("must not be called")},
 		},
 		{
-			name: "request_greater_than_max_errors",
-			req:  1 * time.Hour,
-			def:  15 * time.Minute,
-			max:  30 * time.Minute,
-			err:  "requested ttl (1h) cannot be greater than max tll (30m)",
+			name:      "resolver_error",
+			requestor: "me@example.com",
+			resolver:  &fakeGroupsResolver{err: fmt.Errorf("directory unavailable")},
+			wantErr:   "failed to resolve requestor groups: directory unavailable",
 		},
 	}
 
@@ -588,14 +936,1174 @@ func TestComputeTTL(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := computeTTL(tc.req, tc.def, tc.max)
-			if result := pkgtestutil.DiffErrString(err, tc.err); result != "" {
-				t.Fatal(result)
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+			}).WithGroupsResolver(tc.resolver)
+
+			token, _, err := processor.createToken(ctx, tc.requestor, &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+				},
+			}, time.Now())
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
 			}
 
-			if want := tc.exp; got != want {
-				t.Errorf("expected %q to be %q", got, want)
+			got, err := jvspb.GetGroups(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantGroups, got); diff != "" {
+				t.Errorf("groups: diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateToken_auditRoute(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name      string
+		project   string
+		wantRoute *jvspb.AuditRoute
+	}{
+		{
+			name:    "embeds_route",
+			project: "audit-project",
+			wantRoute: &jvspb.AuditRoute{
+				Project: "audit-project",
+				LogID:   "audit.abcxyz/data_access",
+			},
+		},
+		{
+			name: "no_route",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+				AuditRouteProject:  tc.project,
+				AuditRouteLogID:    "audit.abcxyz/data_access",
+			})
+
+			token, _, err := processor.createToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+				},
+			}, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := jvspb.GetAuditRoute(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantRoute, got); diff != "" {
+				t.Errorf("audit route: diff (-want, +got):\n%s", diff)
 			}
 		})
 	}
 }
+
+// fakeTransparencyLog records the published digests.
+type fakeTransparencyLog struct {
+	mu      sync.Mutex
+	digests [][]byte
+	err     error
+}
+
+func (l *fakeTransparencyLog) Publish(_ context.Context, digest []byte, _ crypto.Signer) (*audit.Transparency, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return nil, l.err
+	}
+	l.digests = append(l.digests, digest)
+	return &audit.Transparency{
+		Digest:   hex.EncodeToString(digest),
+		LogIndex: int64(len(l.digests)),
+	}, nil
+}
+
+func TestCreateToken_transparencyLog(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, err := jvscrypto.LocalKeyID(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		log     *fakeTransparencyLog
+		wantLog bool
+	}{
+		{
+			name:    "published",
+			log:     &fakeTransparencyLog{},
+			wantLog: true,
+		},
+		{
+			name: "publish_error_does_not_fail_issuance",
+			log:  &fakeTransparencyLog{err: fmt.Errorf("log unavailable")},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			sink := &fakeSink{}
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				Signer:                 config.SignerLocal,
+				SignerCacheTimeout:     5 * time.Minute,
+				Issuer:                 "test-iss",
+				DefaultTTL:             15 * time.Minute,
+				MaxTTL:                 1 * time.Hour,
+				MaxAnnotationSize:      100,
+				TransparencyLogTimeout: 5 * time.Second,
+			}).WithLocalSigner(privateKey, keyID).
+				WithAuditSink(sink).
+				WithTransparencyLog(tc.log)
+
+			if _, err := processor.CreateToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "debugging"},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(sink.entries), 1; got != want {
+				t.Fatalf("expected %d sink entries to be %d", got, want)
+			}
+			entry := sink.entries[0]
+
+			if !tc.wantLog {
+				if entry.Transparency != nil {
+					t.Errorf("expected no transparency record, got %#v", entry.Transparency)
+				}
+				return
+			}
+
+			if got, want := len(tc.log.digests), 1; got != want {
+				t.Fatalf("expected %d published digests to be %d", got, want)
+			}
+			digest, err := entry.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := tc.log.digests[0], digest; !bytes.Equal(got, want) {
+				t.Errorf("expected published digest %x to be the entry digest %x", got, want)
+			}
+			if entry.Transparency == nil || entry.Transparency.Digest != hex.EncodeToString(digest) {
+				t.Errorf("expected transparency record of digest %x, got %#v", digest, entry.Transparency)
+			}
+		})
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(`{"image":"gcr.io/my-project/app@sha256:abc"}`)
+	digest := sha256.Sum256(payload)
+
+	cases := []struct {
+		name      string
+		requestor string
+		digest    []byte
+		serverErr error
+		wantErr   string
+	}{
+		{
+			name:      "happy_path",
+			requestor: "me@example.com",
+			digest:    digest[:],
+		},
+		{
+			name:    "invalid_digest",
+			digest:  []byte("not-a-digest"),
+			wantErr: "sha256 digest must be 32 bytes, got 12",
+		},
+		{
+			name:      "kms_failure",
+			digest:    digest[:],
+			serverErr: fmt.Errorf("KMS is down"),
+			wantErr:   "failed to get token signer",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+			now := time.Now().UTC()
+
+			key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+			version := key + "/cryptoKeyVersions/[VERSION]"
+			keyID := key + "/cryptoKeyVersions/[VERSION]-0"
+
+			mockKeyManagement := testutil.NewMockKeyManagementServer(key, version, jvscrypto.PrimaryLabelPrefix+"[VERSION]"+"-0")
+			mockKeyManagement.NumVersions = 1
+
+			privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mockKeyManagement.PrivateKey = privateKey
+			x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			mockKeyManagement.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub}))
+			mockKeyManagement.Err = tc.serverErr
+			mockKeyManagement.Resps = append(mockKeyManagement.Resps[:0], &kmspb.CryptoKeyVersion{})
+
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKeyManagement)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			processor := NewProcessor(c, &config.JustificationConfig{
+				KeyName:            key,
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         1 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+			})
+
+			sig, gotErr := processor.SignPayload(ctx, tc.requestor, tc.digest)
+			if diff := pkgtestutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if gotErr != nil {
+				return
+			}
+
+			if !strings.Contains(string(sig), "..") {
+				t.Errorf("expected %q to have a detached payload", sig)
+			}
+
+			publicKey, err := jwk.FromRaw(privateKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := publicKey.Set(jwk.KeyIDKey, keyID); err != nil {
+				t.Fatal(err)
+			}
+			if err := publicKey.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+				t.Fatal(err)
+			}
+			keys := jwk.NewSet()
+			if err := keys.AddKey(publicKey); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := jvspb.VerifyDetachedSignature(string(sig), bytes.NewReader(payload), keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := got.KeyID, keyID; got != want {
+				t.Errorf("kid: expected %q to be %q", got, want)
+			}
+			if got, want := got.Requestor, tc.requestor; got != want {
+				t.Errorf("requestor: expected %q to be %q", got, want)
+			}
+			if got := got.IssuedAt; got.Before(now.Truncate(time.Second)) {
+				t.Errorf("iat: expected %q to be after %q", got, now)
+			}
+
+			if _, err := jvspb.VerifyDetachedSignature(string(sig), strings.NewReader("tampered"), keys); err == nil {
+				t.Errorf("expected signature over a different payload to fail verification")
+			}
+		})
+	}
+}
+
+func TestProcessor_Ready(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		versionState kmspb.CryptoKeyVersion_CryptoKeyVersionState
+		serverErr    error
+		wantErr      string
+	}{
+		{
+			name: "ready",
+		},
+		{
+			name:         "primary_disabled",
+			versionState: kmspb.CryptoKeyVersion_DISABLED,
+			wantErr:      "signing key is not ready",
+		},
+		{
+			name:      "kms_failure",
+			serverErr: fmt.Errorf("KMS is down"),
+			wantErr:   "KMS is down",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+			version := key + "/cryptoKeyVersions/[VERSION]"
+
+			mockKeyManagement := testutil.NewMockKeyManagementServer(key, version, jvscrypto.PrimaryLabelPrefix+"[VERSION]"+"-0")
+			mockKeyManagement.VersionState = tc.versionState
+			mockKeyManagement.Err = tc.serverErr
+
+			privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			mockKeyManagement.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub}))
+
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKeyManagement)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			processor := NewProcessor(c, &config.JustificationConfig{
+				KeyName:            key,
+				SignerCacheTimeout: 5 * time.Minute,
+			})
+
+			gotErr := processor.Ready(ctx)
+			if diff := pkgtestutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+
+			_, cached := processor.cache.Lookup(cacheKey)
+			if got, want := cached, gotErr == nil; got != want {
+				t.Errorf("expected signer cached to be %t", want)
+			}
+		})
+	}
+}
+
+func TestComputeTTL(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		req  time.Duration
+		def  time.Duration
+		max  time.Duration
+		exp  time.Duration
+		err  string
+	}{
+		{
+			name: "request_zero_uses_default",
+			req:  0,
+			def:  15 * time.Minute,
+			max:  30 * time.Minute,
+			exp:  15 * time.Minute,
+		},
+		{
+			name: "request_negative_uses_default",
+			req:  -10 * time.Second,
+			def:  15 * time.Minute,
+			max:  30 * time.Minute,
+			exp:  15 * time.Minute,
+		},
+		{
+			name: "request_uses_self_in_bounds",
+			req:  12 * time.Minute,
+			def:  15 * time.Minute,
+			max:  30 * time.Minute,
+			exp:  12 * time.Minute,
+		},
+		{
+			name: "request_greater_than_max_errors",
+			req:  1 * time.Hour,
+			def:  15 * time.Minute,
+			max:  30 * time.Minute,
+			err:  "requested ttl (1h) cannot be greater than max tll (30m)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := computeTTL(tc.req, tc.def, tc.max)
+			if result := pkgtestutil.DiffErrString(err, tc.err); result != "" {
+				t.Fatal(result)
+			}
+
+			if want := tc.exp; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCreateToken_startTime(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name          string
+		maxStartDelay time.Duration
+		start         time.Duration
+		noStart       bool
+		wantStart     time.Duration
+		wantErr       string
+	}{
+		{
+			name:    "no_start_time",
+			noStart: true,
+		},
+		{
+			name:  "past_start_time_is_now",
+			start: -time.Hour,
+		},
+		{
+			name:          "scheduled",
+			maxStartDelay: 24 * time.Hour,
+			start:         2 * time.Hour,
+			wantStart:     2 * time.Hour,
+		},
+		{
+			name:    "scheduling_disabled",
+			start:   2 * time.Hour,
+			wantErr: "scheduling tokens with a start time is not enabled",
+		},
+		{
+			name:          "too_far_ahead",
+			maxStartDelay: time.Hour,
+			start:         2 * time.Hour,
+			wantErr:       "start time cannot be more than 1h ahead",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			now := time.Now().UTC().Truncate(time.Second)
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+				NotBeforeLeeway:    30 * time.Second,
+				MaxStartDelay:      tc.maxStartDelay,
+			})
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "explanation", Value: "scheduled change"},
+				},
+				Ttl: durationpb.New(30 * time.Minute),
+			}
+			if !tc.noStart {
+				req.StartTime = timestamppb.New(now.Add(tc.start))
+			}
+
+			err := processor.validateStartTime(req)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			token, _, err := processor.createToken(ctx, "me@example.com", req, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := now.Add(tc.wantStart)
+			if got, want := token.IssuedAt(), now; !got.Equal(want) {
+				t.Errorf("iat: expected %q to be %q", got, want)
+			}
+			if got, want := token.NotBefore(), start.Add(-30*time.Second); !got.Equal(want) {
+				t.Errorf("nbf: expected %q to be %q", got, want)
+			}
+			if got, want := token.Expiration(), start.Add(30*time.Minute); !got.Equal(want) {
+				t.Errorf("exp: expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+type countingValidator struct {
+	mockValidator
+	calls int
+}
+
+func (m *countingValidator) Validate(ctx context.Context, req *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	m.calls++
+	return m.mockValidator.Validate(ctx, req)
+}
+
+func TestRunValidations_cache(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		resp      *jvspb.ValidateJustificationResponse
+		err       error
+		cacheTTLs map[string]time.Duration
+		requests  []struct{ requestor, value string }
+		wantCalls int
+	}{
+		{
+			name: "cached_per_value_and_requestor",
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid:      true,
+				Annotation: map[string]string{"status": "open"},
+			},
+			cacheTTLs: map[string]time.Duration{"jira": 5 * time.Minute},
+			requests: []struct{ requestor, value string }{
+				{"me@example.com", "ABC-123"},
+				{"me@example.com", "ABC-123"},
+				{"you@example.com", "ABC-123"},
+				{"me@example.com", "ABC-456"},
+				{"me@example.com", "ABC-123"},
+			},
+			wantCalls: 3,
+		},
+		{
+			name:      "no_cache_for_category",
+			resp:      &jvspb.ValidateJustificationResponse{Valid: true},
+			cacheTTLs: map[string]time.Duration{"github": 5 * time.Minute},
+			requests: []struct{ requestor, value string }{
+				{"me@example.com", "ABC-123"},
+				{"me@example.com", "ABC-123"},
+			},
+			wantCalls: 2,
+		},
+		{
+			name: "invalid_not_cached",
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid: false,
+				Error: []string{"ticket closed"},
+			},
+			cacheTTLs: map[string]time.Duration{"jira": 5 * time.Minute},
+			requests: []struct{ requestor, value string }{
+				{"me@example.com", "ABC-123"},
+				{"me@example.com", "ABC-123"},
+			},
+			wantCalls: 2,
+		},
+		{
+			name:      "error_not_cached",
+			err:       fmt.Errorf("rate limited"),
+			cacheTTLs: map[string]time.Duration{"jira": 5 * time.Minute},
+			requests: []struct{ requestor, value string }{
+				{"me@example.com", "ABC-123"},
+				{"me@example.com", "ABC-123"},
+			},
+			wantCalls: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			validator := &countingValidator{
+				mockValidator: mockValidator{resp: tc.resp, err: tc.err},
+			}
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				MaxAnnotationSize:  100,
+			}).WithValidators(map[string]jvspb.Validator{
+				"jira": validator,
+			}).WithValidationCache(tc.cacheTTLs)
+
+			for _, r := range tc.requests {
+				req := &jvspb.CreateJustificationRequest{
+					Justifications: []*jvspb.Justification{
+						{Category: "jira", Value: r.value},
+					},
+				}
+				_, err := processor.runValidations(ctx, r.requestor, req, nil)
+				if tc.resp.GetValid() && tc.err == nil {
+					if err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(tc.resp.GetAnnotation(), req.GetJustifications()[0].GetAnnotation()); diff != "" {
+						t.Errorf("annotation (-want, +got):\n%s", diff)
+					}
+				} else if err == nil {
+					t.Error("expected error")
+				}
+			}
+
+			if got, want := validator.calls, tc.wantCalls; got != want {
+				t.Errorf("expected %d validator calls to be %d", got, want)
+			}
+		})
+	}
+}
+
+func TestRunValidations_categoryAliases(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	annotation := map[string]string{"source": "freeform"}
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		MaxAnnotationSize:  1000,
+	}).WithValidators(map[string]jvspb.Validator{
+		"freeform": &mockValidator{
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid:      true,
+				Annotation: annotation,
+			},
+		},
+	}).WithCategoryAliases(map[string]string{
+		jvspb.DefaultJustificationCategory: "freeform",
+	})
+
+	req := &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: jvspb.DefaultJustificationCategory, Value: "test"},
+			{Category: "freeform", Value: "test"},
+		},
+	}
+	if _, err := processor.runValidations(ctx, "me@example.com", req, processor.aliasCategories(ctx, req)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*jvspb.Justification{
+		{
+			Category: "freeform",
+			Value:    "test",
+			Annotation: map[string]string{
+				"source":                  "freeform",
+				AnnotationCategoryWarning: `category "explanation" is deprecated, use "freeform" instead`,
+			},
+		},
+		{
+			Category:   "freeform",
+			Value:      "test",
+			Annotation: map[string]string{"source": "freeform"},
+		},
+	}
+	if diff := cmp.Diff(want, req.GetJustifications(), cmpopts.IgnoreUnexported(jvspb.Justification{})); diff != "" {
+		t.Errorf("justifications (-want, +got):\n%s", diff)
+	}
+
+	// The validator's response must not be modified.
+	if diff := cmp.Diff(map[string]string{"source": "freeform"}, annotation); diff != "" {
+		t.Errorf("validator annotation (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRunValidations_warnings(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		MaxAnnotationSize:  1000,
+	}).WithValidators(map[string]jvspb.Validator{
+		"jira": &mockValidator{
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid:   true,
+				Warning: []string{"ticket closes in 20 minutes"},
+			},
+		},
+		"freeform": &mockValidator{
+			resp: &jvspb.ValidateJustificationResponse{Valid: true},
+		},
+		"shadow": &mockValidator{
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid:   false,
+				Warning: []string{"not reported"},
+			},
+		},
+		"invalid": &mockValidator{
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid:   false,
+				Warning: []string{"not reported"},
+			},
+		},
+	}).WithCategoryAliases(map[string]string{
+		jvspb.DefaultJustificationCategory: "freeform",
+	}).WithShadowCategories([]string{"shadow"})
+
+	cases := []struct {
+		name           string
+		justifications []*jvspb.Justification
+		want           []*jvspb.ValidationWarning
+		wantErr        string
+	}{
+		{
+			name: "validator_warnings",
+			justifications: []*jvspb.Justification{
+				{Category: "jira", Value: "ABC-123"},
+				{Category: "freeform", Value: "test"},
+			},
+			want: []*jvspb.ValidationWarning{
+				{Category: "jira", Message: "ticket closes in 20 minutes"},
+			},
+		},
+		{
+			name: "deprecated_category",
+			justifications: []*jvspb.Justification{
+				{Category: jvspb.DefaultJustificationCategory, Value: "test"},
+			},
+			want: []*jvspb.ValidationWarning{
+				{Category: "freeform", Message: `category "explanation" is deprecated, use "freeform" instead`},
+			},
+		},
+		{
+			name: "no_warnings",
+			justifications: []*jvspb.Justification{
+				{Category: "freeform", Value: "test"},
+				{Category: "shadow", Value: "test"},
+			},
+		},
+		{
+			name: "invalid",
+			justifications: []*jvspb.Justification{
+				{Category: "jira", Value: "ABC-123"},
+				{Category: "invalid", Value: "test"},
+			},
+			wantErr: "failed validation criteria",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: tc.justifications,
+			}
+			got, err := processor.runValidations(ctx, "me@example.com", req, processor.aliasCategories(ctx, req))
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(jvspb.ValidationWarning{})); diff != "" {
+				t.Errorf("warnings (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunValidations_valuePatterns(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		patterns      map[string]*regexp.Regexp
+		uiData        *jvspb.UIData
+		value         string
+		wantErr       string
+		wantValidated bool
+	}{
+		{
+			name:          "no_pattern",
+			value:         "anything",
+			wantValidated: true,
+		},
+		{
+			name:          "config_match",
+			patterns:      map[string]*regexp.Regexp{"jira": regexp.MustCompile(`^issues/\d+$`)},
+			value:         "issues/123",
+			wantValidated: true,
+		},
+		{
+			name:     "config_mismatch",
+			patterns: map[string]*regexp.Regexp{"jira": regexp.MustCompile(`^issues/\d+$`)},
+			value:    "JVS-123",
+			wantErr:  `justification value for category "jira" must match "^issues/\\d+$"`,
+		},
+		{
+			name:          "plugin_match",
+			uiData:        &jvspb.UIData{ValuePattern: `^[A-Z]+-\d+$`},
+			value:         "JVS-123",
+			wantValidated: true,
+		},
+		{
+			name:    "plugin_mismatch",
+			uiData:  &jvspb.UIData{ValuePattern: `^[A-Z]+-\d+$`},
+			value:   "issues/123",
+			wantErr: `justification value for category "jira" must match "^[A-Z]+-\\d+$"`,
+		},
+		{
+			name:          "config_overrides_plugin",
+			patterns:      map[string]*regexp.Regexp{"jira": regexp.MustCompile(`^issues/\d+$`)},
+			uiData:        &jvspb.UIData{ValuePattern: `^[A-Z]+-\d+$`},
+			value:         "issues/123",
+			wantValidated: true,
+		},
+		{
+			name:    "invalid_plugin_pattern",
+			uiData:  &jvspb.UIData{ValuePattern: `^[A-Z`},
+			value:   "JVS-123",
+			wantErr: "unable to validate request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			validator := &countingValidator{
+				mockValidator: mockValidator{
+					resp:   &jvspb.ValidateJustificationResponse{Valid: true},
+					uiData: tc.uiData,
+				},
+			}
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				MaxAnnotationSize:  1000,
+			}).WithValidators(map[string]jvspb.Validator{
+				"jira": validator,
+			}).WithValuePatterns(tc.patterns)
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "jira", Value: tc.value},
+				},
+			}
+			_, err := processor.runValidations(ctx, "me@example.com", req, nil)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if got := validator.calls > 0; got != tc.wantValidated {
+				t.Errorf("expected validator called to be %t, got %t", tc.wantValidated, got)
+			}
+		})
+	}
+}
+
+func TestListCategories(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+	}).WithValidators(map[string]jvspb.Validator{
+		"freeform": &mockValidator{
+			uiData: &jvspb.UIData{
+				DisplayName: "Free-form",
+				Hint:        "Why you need access",
+			},
+		},
+		"jira": plugin.NewMonitor(&mockValidator{
+			uiData: &jvspb.UIData{
+				DisplayName:  "Jira issue key",
+				Hint:         "Jira issue key under JVS project",
+				ValuePattern: `^[A-Z]+-\d+$`,
+			},
+		}, plugin.KindPlugin, "v1.2.3", "grpc/1"),
+	}).WithCategoryAliases(map[string]string{
+		"reason":                           "freeform",
+		jvspb.DefaultJustificationCategory: "freeform",
+	}).WithValuePatterns(map[string]*regexp.Regexp{
+		"jira": regexp.MustCompile(`^JVS-\d+$`),
+	})
+
+	got, err := processor.ListCategories(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*jvspb.Category{
+		{
+			Name:              "freeform",
+			DisplayName:       "Free-form",
+			Hint:              "Why you need access",
+			DeprecatedAliases: []string{jvspb.DefaultJustificationCategory, "reason"},
+			ValidatorStatus: &jvspb.ValidatorStatus{
+				Category: "freeform",
+				Kind:     plugin.KindBuiltin,
+			},
+		},
+		{
+			Name:         "jira",
+			DisplayName:  "Jira issue key",
+			Hint:         "Jira issue key under JVS project",
+			ValuePattern: `^JVS-\d+$`,
+			ValidatorStatus: &jvspb.ValidatorStatus{
+				Category: "jira",
+				Kind:     plugin.KindPlugin,
+				Version:  "v1.2.3",
+				Protocol: "grpc/1",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("categories (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRunValidations_shadow(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name           string
+		validator      *mockValidator
+		shadow         []string
+		wantErr        string
+		wantAnnotation map[string]string
+	}{
+		{
+			name: "shadow_valid",
+			validator: &mockValidator{
+				resp: &jvspb.ValidateJustificationResponse{
+					Valid:      true,
+					Annotation: map[string]string{"change_request": "CHG0000001"},
+				},
+			},
+			shadow:         []string{"servicenow"},
+			wantAnnotation: map[string]string{"change_request": "CHG0000001"},
+		},
+		{
+			name: "shadow_invalid",
+			validator: &mockValidator{
+				resp: &jvspb.ValidateJustificationResponse{
+					Valid:      false,
+					Error:      []string{"change request not found"},
+					Annotation: map[string]string{"change_request": "CHG0000001"},
+				},
+			},
+			shadow: []string{"servicenow"},
+		},
+		{
+			name: "shadow_error",
+			validator: &mockValidator{
+				err: fmt.Errorf("servicenow is down"),
+			},
+			shadow: []string{"servicenow"},
+		},
+		{
+			name: "enforcing_invalid",
+			validator: &mockValidator{
+				resp: &jvspb.ValidateJustificationResponse{
+					Valid: false,
+					Error: []string{"change request not found"},
+				},
+			},
+			shadow:  []string{"jira"},
+			wantErr: "change request not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				MaxAnnotationSize:  1000,
+			}).WithValidators(map[string]jvspb.Validator{
+				"servicenow": tc.validator,
+			}).WithShadowCategories(tc.shadow)
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "servicenow", Value: "CHG0000001"},
+				},
+			}
+			_, err := processor.runValidations(ctx, "me@example.com", req, nil)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantAnnotation, req.GetJustifications()[0].GetAnnotation()); diff != "" {
+				t.Errorf("annotation (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunValidations_requiredAnnotations(t *testing.T) {
+	t.Parallel()
+
+	required := map[string][]string{"jira": {"jira_issue_url"}}
+
+	cases := []struct {
+		name       string
+		required   map[string][]string
+		annotation map[string]string
+		shadow     []string
+		wantErr    string
+	}{
+		{
+			name: "none_required",
+		},
+		{
+			name:       "present",
+			required:   required,
+			annotation: map[string]string{"jira_issue_url": "https://jira.corp/browse/JVS-123"},
+		},
+		{
+			name:     "dropped",
+			required: required,
+			wantErr:  "unable to validate request",
+		},
+		{
+			name:       "empty",
+			required:   required,
+			annotation: map[string]string{"jira_issue_url": ""},
+			wantErr:    "unable to validate request",
+		},
+		{
+			name:     "other_category",
+			required: map[string][]string{"servicenow": {"change_request"}},
+		},
+		{
+			name:     "shadow",
+			required: required,
+			shadow:   []string{"jira"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				SignerCacheTimeout: 5 * time.Minute,
+				MaxAnnotationSize:  1000,
+			}).WithValidators(map[string]jvspb.Validator{
+				"jira": &mockValidator{
+					resp: &jvspb.ValidateJustificationResponse{
+						Valid:      true,
+						Annotation: tc.annotation,
+					},
+				},
+			}).WithRequiredAnnotations(tc.required).WithShadowCategories(tc.shadow)
+
+			req := &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: "jira", Value: "JVS-123"},
+				},
+			}
+			_, err := processor.runValidations(ctx, "me@example.com", req, nil)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestCreateJustification_metadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, err := jvscrypto.LocalKeyID(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		Signer:             config.SignerLocal,
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithLocalSigner(privateKey, keyID).
+		WithValidators(map[string]jvspb.Validator{
+			"explanation": &mockValidator{
+				resp: &jvspb.ValidateJustificationResponse{Valid: true},
+			},
+		})
+
+	resp, err := processor.CreateJustification(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "explanation", Value: "test"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Parse([]byte(resp.GetToken()), jwt.WithKey(jwa.ES256, privateKey.Public()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := resp.GetExpiresAt().AsTime(), token.Expiration(); !got.Equal(want) {
+		t.Errorf("expires_at: expected %s to be %s", got, want)
+	}
+	if got, want := resp.GetJti(), token.JwtID(); got != want || got == "" {
+		t.Errorf("jti: expected %q to be %q", got, want)
+	}
+	if got, want := resp.GetKid(), keyID; got != want {
+		t.Errorf("kid: expected %q to be %q", got, want)
+	}
+	if got, want := resp.GetIssuer(), "test-iss"; got != want {
+		t.Errorf("issuer: expected %q to be %q", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/quota"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// ReasonQuotaExhausted is the reason of the [errdetails.ErrorInfo] of
+	// requests rejected because the requestor exhausted the daily quota of a
+	// category.
+	ReasonQuotaExhausted = "QUOTA_EXHAUSTED"
+
+	// QuotaGrantedLogMessage is logged when an admin grants a principal tokens
+	// over its quota.
+	QuotaGrantedLogMessage = "quota granted"
+
+	// quotaRefundTimeout is the timeout of refunding the quotas of a token that
+	// failed to be issued.
+	quotaRefundTimeout = 10 * time.Second
+)
+
+var (
+	// errQuotaDisabled is returned when quotas are not enabled.
+	errQuotaDisabled = errors.New("quotas are not enabled")
+
+	// errInvalidQuotaGrant is returned when granting a non-positive number of
+	// tokens.
+	errInvalidQuotaGrant = errors.New("tokens must be positive")
+)
+
+// QuotaGrant is the result of granting a principal tokens over its quota.
+type QuotaGrant struct {
+	Usage   *quota.Usage
+	Limit   int
+	ResetAt time.Time
+}
+
+// consumeQuotas counts the token against the quota of each category of the
+// justifications of the request, once per category, and returns the keys it
+// was counted against. The caller must refund them if the token isn't issued.
+// Tokens counted before a quota turns out to be exhausted are refunded.
+func (p *Processor) consumeQuotas(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) ([]quota.Key, error) {
+	if p.quotas == nil {
+		return nil, nil
+	}
+
+	categories := make([]string, 0, len(req.GetJustifications()))
+	for _, j := range req.GetJustifications() {
+		categories = append(categories, j.GetCategory())
+	}
+	slices.Sort(categories)
+
+	logger := logging.FromContext(ctx)
+	var keys []quota.Key
+	for _, category := range slices.Compact(categories) {
+		key, err := p.quotas.Consume(ctx, requestor, category)
+		var exhausted *quota.ExhaustedError
+		switch {
+		case errors.As(err, &exhausted):
+			logger.WarnContext(ctx, "quota exhausted",
+				"requestor", requestor,
+				"category", category,
+				"limit", exhausted.Limit)
+			p.refundQuotas(ctx, keys)
+			return nil, quotaExhaustedError(exhausted)
+		case err != nil:
+			logger.ErrorContext(ctx, "failed to consume quota", "error", err)
+			p.refundQuotas(ctx, keys)
+			return nil, status.Error(codes.Internal, "failed to check quota")
+		case key != nil:
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+// refundQuotas refunds the tokens counted against the keys by a request that
+// failed to issue its token. The tokens are refunded even if the request was
+// canceled or its deadline exceeded, and failures are only logged.
+func (p *Processor) refundQuotas(ctx context.Context, keys []quota.Key) {
+	if len(keys) == 0 {
+		return
+	}
+	logger := logging.FromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), quotaRefundTimeout)
+	defer cancel()
+
+	for _, key := range keys {
+		if err := p.quotas.Refund(ctx, key); err != nil {
+			logger.ErrorContext(ctx, "failed to refund quota",
+				"principal", key.Principal,
+				"category", key.Category,
+				"error", err)
+			continue
+		}
+		logger.InfoContext(ctx, "quota refunded",
+			"principal", key.Principal,
+			"category", key.Category)
+	}
+}
+
+// quotaExhaustedError returns the RESOURCE_EXHAUSTED error of the exhausted
+// quota, with when it resets in its details.
+func quotaExhaustedError(e *quota.ExhaustedError) error {
+	st := status.New(codes.ResourceExhausted, e.Error())
+	if detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason: ReasonQuotaExhausted,
+			Domain: errorDomain,
+			Metadata: map[string]string{
+				"category":   e.Category,
+				"limit":      strconv.Itoa(e.Limit),
+				"reset_time": e.ResetAt.Format(time.RFC3339),
+			},
+		},
+		&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(time.Until(e.ResetAt).Truncate(time.Second)),
+		},
+	); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// GrantQuota allows the principal the number of tokens of the category over
+// its daily quota today, e.g. during an incident.
+func (p *Processor) GrantQuota(ctx context.Context, principal, category string, tokens int, admin, reason string) (*QuotaGrant, error) {
+	if p.quotas == nil {
+		return nil, errQuotaDisabled
+	}
+	if err := validatePrincipal(principal); err != nil {
+		return nil, err
+	}
+	if tokens <= 0 {
+		return nil, fmt.Errorf("%w, got %d", errInvalidQuotaGrant, tokens)
+	}
+
+	usage, resetAt, err := p.quotas.Grant(ctx, principal, category, tokens)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Already wrapped.
+	}
+	limit, _ := p.quotas.Limit(category)
+
+	logging.FromContext(ctx).InfoContext(ctx, QuotaGrantedLogMessage,
+		"admin", admin,
+		"principal", principal,
+		"category", category,
+		"tokens", tokens,
+		"reason", reason)
+	return &QuotaGrant{
+		Usage:   usage,
+		Limit:   limit,
+		ResetAt: resetAt,
+	}, nil
+}
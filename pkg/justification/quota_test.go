@@ -0,0 +1,171 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/quota"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestProcessor_quotas(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		SignerCacheTimeout: 5 * time.Minute,
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithValidators(map[string]jvspb.Validator{
+		"breakglass": &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}},
+		"invalid":    &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: false}},
+	}).WithQuotas(quota.New(quota.NewFileStore(filepath.Join(t.TempDir(), "quota.json")),
+		map[string]int{"breakglass": 2, "invalid": 1}))
+
+	breakglass := &jvspb.Justification{Category: "breakglass", Value: "incident"}
+	invalid := &jvspb.Justification{Category: "invalid", Value: "test"}
+
+	// Invalid requests don't count.
+	if _, _, err := processor.validateRequest(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{breakglass, invalid},
+	}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected invalid request, got %v", err)
+	}
+
+	// A category in several justifications counts once.
+	for range 2 {
+		if _, _, err := processor.validateRequest(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+			Justifications: []*jvspb.Justification{breakglass, breakglass},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, err := processor.validateRequest(ctx, "Me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{breakglass},
+	})
+	st := status.Convert(err)
+	if got, want := st.Code(), codes.ResourceExhausted; got != want {
+		t.Fatalf("expected code %s to be %s: %v", got, want, err)
+	}
+
+	resetAt := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if got, want := st.Message(), `daily quota of 2 tokens of category "breakglass" is exhausted, it resets at `+
+		resetAt.Format(time.RFC3339); got != want {
+		t.Errorf("expected message %q to be %q", got, want)
+	}
+
+	var info *errdetails.ErrorInfo
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.RetryInfo:
+			retry = d
+		}
+	}
+	wantMetadata := map[string]string{
+		"category":   "breakglass",
+		"limit":      "2",
+		"reset_time": resetAt.Format(time.RFC3339),
+	}
+	if got, want := info.GetReason(), ReasonQuotaExhausted; got != want {
+		t.Errorf("expected reason %q to be %q", got, want)
+	}
+	if diff := cmp.Diff(wantMetadata, info.GetMetadata()); diff != "" {
+		t.Errorf("error info metadata (-want, +got):\n%s", diff)
+	}
+	if got := retry.GetRetryDelay().AsDuration(); got <= 0 || got > 24*time.Hour {
+		t.Errorf("expected retry delay %s to be within a day", got)
+	}
+
+	// Other requestors have their own quota.
+	if _, _, err := processor.validateRequest(ctx, "other@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{breakglass},
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProcessor_quotasRefunded(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := NewProcessor(nil, &config.JustificationConfig{
+		Signer:             config.SignerLocal,
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+	}).WithLocalSigner(&failingSigner{Signer: privateKey}, "test-key").
+		WithValidators(map[string]jvspb.Validator{
+			"breakglass": &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}},
+			"database":   &mockValidator{resp: &jvspb.ValidateJustificationResponse{Valid: true}},
+		}).WithQuotas(quota.New(quota.NewFileStore(filepath.Join(t.TempDir(), "quota.json")),
+		map[string]int{"breakglass": 1, "database": 1}))
+
+	breakglass := &jvspb.Justification{Category: "breakglass", Value: "incident"}
+	database := &jvspb.Justification{Category: "database", Value: "incident"}
+
+	// Tokens that fail to be signed don't count.
+	for range 2 {
+		if _, err := processor.CreateJustification(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+			Justifications: []*jvspb.Justification{breakglass},
+		}); status.Code(err) != codes.Internal {
+			t.Fatalf("expected signing to fail, got %v", err)
+		}
+	}
+
+	// Categories counted before another quota is exhausted don't count.
+	if _, _, err := processor.validateRequest(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{database},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := processor.validateRequest(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{breakglass, database},
+	}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected quota to be exhausted, got %v", err)
+	}
+
+	if _, _, err := processor.validateRequest(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{breakglass},
+	}); err != nil {
+		t.Errorf("expected quota to be refunded: %v", err)
+	}
+}
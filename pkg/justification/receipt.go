@@ -0,0 +1,79 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/approver"
+	"github.com/abcxyz/jvs/pkg/audit"
+)
+
+// newReceipt returns the receipt of the audit entry's token. It is built from
+// the entry rather than the token, so annotations redacted from the entry are
+// left out too.
+func newReceipt(entry *audit.Entry) *jvspb.Receipt {
+	receipt := &jvspb.Receipt{
+		ID:             entry.ID,
+		Requestor:      entry.Requestor,
+		Subject:        entry.Subject,
+		Justifications: make([]*jvspb.ReceiptJustification, 0, len(entry.Justifications)),
+		// Match the precision of the token's claims.
+		IssuedAt:  entry.IssuedAt.Truncate(time.Second),
+		ExpiresAt: entry.ExpiresAt.Truncate(time.Second),
+		Redacted:  entry.Redacted,
+	}
+	for _, j := range entry.Justifications {
+		receipt.Justifications = append(receipt.Justifications, &jvspb.ReceiptJustification{
+			Category:   j.Category,
+			Annotation: j.Annotation,
+		})
+		if v := j.Annotation[approver.AnnotationApprover]; v != "" && receipt.Approver == "" {
+			receipt.Approver = v
+		}
+	}
+	return receipt
+}
+
+// signReceipt signs the receipt of the audit entry's token with the token
+// signer, so it can be verified with the JVS public keys.
+func signReceipt(signer *signerWithID, entry *audit.Entry) ([]byte, error) {
+	payload, err := json.Marshal(newReceipt(entry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+
+	headers := jws.NewHeaders()
+	for k, v := range map[string]any{
+		jws.KeyIDKey: signer.id,
+		jws.TypeKey:  jvspb.ReceiptType,
+	} {
+		if err := headers.Set(k, v); err != nil {
+			return nil, fmt.Errorf("failed to set receipt header %s: %w", k, err)
+		}
+	}
+
+	b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, signer, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign receipt: %w", err)
+	}
+	return b, nil
+}
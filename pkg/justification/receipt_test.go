@@ -0,0 +1,186 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package justification
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/approver"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestCreateTokenWithReceipt(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, err := jvscrypto.LocalKeyID(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := jwk.FromRaw(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatal(err)
+	}
+	if err := publicKey.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+		t.Fatal(err)
+	}
+	keys := jwk.NewSet()
+	if err := keys.AddKey(publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	encryptionKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := jwk.FromRaw(encryptionKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipients := jwk.NewSet()
+	if err := recipients.AddKey(recipient); err != nil {
+		t.Fatal(err)
+	}
+
+	annotation := map[string]string{
+		approver.AnnotationApprover: "boss@example.com",
+		approver.AnnotationRelation: approver.RelationManager,
+	}
+	validators := map[string]jvspb.Validator{
+		approver.Category: &mockValidator{
+			resp: &jvspb.ValidateJustificationResponse{
+				Valid:      true,
+				Annotation: annotation,
+			},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		receipts bool
+		encrypt  bool
+		want     *jvspb.ReceiptJustification
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:     "enabled",
+			receipts: true,
+			want: &jvspb.ReceiptJustification{
+				Category:   approver.Category,
+				Annotation: annotation,
+			},
+		},
+		{
+			name:     "encrypted_justifications_are_redacted",
+			receipts: true,
+			encrypt:  true,
+			want: &jvspb.ReceiptJustification{
+				Category: approver.Category,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			sink := &fakeSink{}
+			processor := NewProcessor(nil, &config.JustificationConfig{
+				Signer:             config.SignerLocal,
+				SignerCacheTimeout: 5 * time.Minute,
+				Issuer:             "test-iss",
+				DefaultTTL:         15 * time.Minute,
+				MaxTTL:             1 * time.Hour,
+				MaxAnnotationSize:  100,
+				Receipts:           tc.receipts,
+			}).WithLocalSigner(privateKey, keyID).
+				WithValidators(validators).
+				WithAuditSink(sink)
+			if tc.encrypt {
+				processor.WithEncryptionKeys(recipients)
+			}
+
+			b, receipt, err := processor.CreateTokenWithReceipt(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{
+					{Category: approver.Category, Value: "boss@example.com"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := jwt.Parse(b, jwt.WithKeySet(keys))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := len(sink.entries), 1; got != want {
+				t.Fatalf("expected %d sink entries to be %d", got, want)
+			}
+			if got, want := sink.entries[0].Receipt, string(receipt); got != want {
+				t.Errorf("sink entry receipt: expected %q to be %q", got, want)
+			}
+
+			if !tc.receipts {
+				if receipt != nil {
+					t.Errorf("expected no receipt, got %q", receipt)
+				}
+				return
+			}
+
+			got, err := jvspb.VerifyReceipt(string(receipt), keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &jvspb.Receipt{
+				ID:             token.JwtID(),
+				Requestor:      "me@example.com",
+				Subject:        "me@example.com",
+				Justifications: []*jvspb.ReceiptJustification{tc.want},
+				IssuedAt:       token.IssuedAt().UTC(),
+				ExpiresAt:      token.Expiration().UTC(),
+				Redacted:       tc.encrypt,
+			}
+			if !tc.encrypt {
+				want.Approver = "boss@example.com"
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("receipt (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
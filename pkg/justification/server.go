@@ -16,14 +16,18 @@ package justification
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc/codes"
 	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
 )
 
 // JVSAgent is the implementation of the justification verification server.
@@ -31,6 +35,10 @@ type JVSAgent struct {
 	jvspb.JVSServiceServer
 
 	Processor *Processor
+
+	// Exchanger verifies third-party OIDC tokens for ExchangeToken. Token
+	// exchange is disabled if nil.
+	Exchanger *TokenExchanger
 }
 
 // NewJVSAgent creates a new JVSAgent.
@@ -44,13 +52,87 @@ func (j *JVSAgent) CreateJustification(ctx context.Context, req *jvspb.CreateJus
 		return nil, fmt.Errorf("failed to extract request principal: %w", err)
 	}
 
-	token, err := j.Processor.CreateToken(ctx, requestor, req)
+	return j.Processor.CreateJustification(ctx, requestor, req)
+}
+
+// ExchangeToken verifies the third-party subject token and mints a
+// justification token with the subject token's subject as the requestor.
+func (j *JVSAgent) ExchangeToken(ctx context.Context, req *jvspb.ExchangeTokenRequest) (*jvspb.CreateJustificationResponse, error) {
+	if j.Exchanger == nil {
+		return nil, status.Error(codes.Unimplemented, "token exchange is not enabled")
+	}
+
+	requestor, err := j.Exchanger.Verify(ctx, req.GetSubjectToken())
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "failed to verify subject token", "error", err)
+		if errors.Is(err, errNotAllowlisted) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s", err)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "invalid subject token: %s", err)
+	}
+
+	return j.Processor.CreateJustification(ctx, requestor, req.GetRequest())
+}
+
+// CreateCertificate issues a short-lived client certificate with the
+// justifications for the authenticated caller.
+func (j *JVSAgent) CreateCertificate(ctx context.Context, req *jvspb.CreateCertificateRequest) (*jvspb.CreateCertificateResponse, error) {
+	requestor, err := extractRequestorFromIncomingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract request principal: %w", err)
+	}
+
+	chain, err := j.Processor.CreateCertificate(ctx, requestor, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jvspb.CreateCertificateResponse{
+		CertificateChain: string(chain),
+	}, nil
+}
+
+// SignPayload creates a detached signature over the payload digest for the
+// authenticated caller.
+func (j *JVSAgent) SignPayload(ctx context.Context, req *jvspb.SignPayloadRequest) (*jvspb.SignPayloadResponse, error) {
+	requestor, err := extractRequestorFromIncomingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract request principal: %w", err)
+	}
+
+	sig, err := j.Processor.SignPayload(ctx, requestor, req.GetSha256Digest())
+	if err != nil {
+		return nil, err
+	}
+
+	return &jvspb.SignPayloadResponse{
+		Signature: string(sig),
+	}, nil
+}
+
+// ListCategories lists the justification categories accepted by the processor.
+func (j *JVSAgent) ListCategories(ctx context.Context, _ *jvspb.ListCategoriesRequest) (*jvspb.ListCategoriesResponse, error) {
+	categories, err := j.Processor.ListCategories(ctx)
+	if err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to list categories", "error", err)
+		return nil, status.Error(codes.Internal, "failed to list categories")
+	}
+
+	return &jvspb.ListCategoriesResponse{
+		Categories: categories,
+	}, nil
+}
+
+// GetPolicyAttestation returns the issuance policy in force, signed with the
+// token signer.
+func (j *JVSAgent) GetPolicyAttestation(ctx context.Context, _ *jvspb.GetPolicyAttestationRequest) (*jvspb.GetPolicyAttestationResponse, error) {
+	attestation, err := j.Processor.PolicyAttestation(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &jvspb.CreateJustificationResponse{
-		Token: string(token),
+	return &jvspb.GetPolicyAttestationResponse{
+		Attestation: string(attestation),
 	}, nil
 }
 
@@ -0,0 +1,210 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jvsclient mints justification tokens from the JVS API for services
+// that call other services with them, and keeps them fresh.
+package jvsclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// DefaultRefreshBefore is how long before its expiry a token is refreshed by
+// default.
+const DefaultRefreshBefore = 2 * time.Minute
+
+// JustificationFunc returns the request to mint a token with, e.g. with the
+// justification of the job the service is running. It is called for every
+// token, so it can return a different justification each time.
+type JustificationFunc func(ctx context.Context) (*jvspb.CreateJustificationRequest, error)
+
+// TokenSource mints justification tokens from the JVS API and returns the same
+// token until it is about to expire. It is safe for concurrent use, and
+// concurrent callers of an expired token wait for a single refresh.
+type TokenSource struct {
+	client        jvspb.JVSServiceClient
+	justification JustificationFunc
+	callOpts      []grpc.CallOption
+	refreshBefore time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiry    time.Time
+	refreshAt time.Time
+}
+
+// TokenSourceOption is an option for [NewTokenSource].
+type TokenSourceOption func(s *TokenSource)
+
+// WithCallOptions sets the options of the calls to the JVS API, e.g. the
+// credentials to authenticate with.
+func WithCallOptions(opts ...grpc.CallOption) TokenSourceOption {
+	return func(s *TokenSource) {
+		s.callOpts = append(s.callOpts, opts...)
+	}
+}
+
+// WithRefreshBefore sets how long before its expiry a token is refreshed. Each
+// refresh is jittered earlier by up to 10% of the token's lifetime, so many
+// clients don't refresh in lockstep. The default is [DefaultRefreshBefore].
+// Tokens are always refreshed by half their lifetime.
+func WithRefreshBefore(d time.Duration) TokenSourceOption {
+	return func(s *TokenSource) {
+		s.refreshBefore = d
+	}
+}
+
+// NewTokenSource returns a token source that mints tokens with the JVS client,
+// for the requests the justification function returns. Tokens are minted on
+// first use.
+func NewTokenSource(client jvspb.JVSServiceClient, justification JustificationFunc, opts ...TokenSourceOption) (*TokenSource, error) {
+	if client == nil {
+		return nil, fmt.Errorf("jvs client cannot be nil")
+	}
+	if justification == nil {
+		return nil, fmt.Errorf("justification function cannot be nil")
+	}
+
+	s := &TokenSource{
+		client:        client,
+		justification: justification,
+		refreshBefore: DefaultRefreshBefore,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.refreshBefore < 0 {
+		return nil, fmt.Errorf("refresh before cannot be negative, got %s", s.refreshBefore)
+	}
+	return s, nil
+}
+
+// Token returns a justification token, minting a new one if there is none or
+// the current one is due for a refresh. If refreshing fails, the current token
+// keeps being returned until it expires.
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token != "" && now.Before(s.refreshAt) {
+		return s.token, nil
+	}
+
+	if err := s.refresh(ctx, now); err != nil {
+		if s.token != "" && now.Before(s.expiry) {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to refresh justification token, using current token",
+				"expiry", s.expiry,
+				"error", err)
+			return s.token, nil
+		}
+		return "", err
+	}
+	return s.token, nil
+}
+
+// refresh mints a new token. It must be called with the lock held.
+func (s *TokenSource) refresh(ctx context.Context, now time.Time) error {
+	req, err := s.justification(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get justification: %w", err)
+	}
+
+	resp, err := s.client.CreateJustification(ctx, req, s.callOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to create justification: %w", err)
+	}
+
+	// The token comes straight from the JVS, it only needs parsing for its
+	// expiry.
+	token, err := jwt.ParseInsecure([]byte(resp.GetToken()))
+	if err != nil {
+		return fmt.Errorf("failed to parse justification token: %w", err)
+	}
+	expiry := token.Expiration()
+	if expiry.IsZero() || !expiry.After(now) {
+		return fmt.Errorf("justification token expired at %s", expiry)
+	}
+
+	lifetime := expiry.Sub(now)
+	before := min(s.refreshBefore, lifetime/2)
+	before += time.Duration(rand.Float64() * 0.1 * float64(lifetime)) //nolint:gosec // Jitter doesn't need to be secure.
+
+	s.token = resp.GetToken()
+	s.expiry = expiry
+	s.refreshAt = expiry.Add(-before)
+	return nil
+}
+
+// UnaryClientInterceptor sends a justification token from the token source
+// with every unary call, as [jvspb.TokenUnaryClientInterceptor] does. Calls
+// whose context already carries a token, e.g. one propagated from an incoming
+// request, keep it. Don't install it on the connection to the JVS itself.
+func (s *TokenSource) UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, err := s.withToken(ctx)
+	if err != nil {
+		return err
+	}
+	return jvspb.TokenUnaryClientInterceptor(ctx, method, req, reply, cc, invoker, opts...)
+}
+
+// Transport returns a transport that sends a justification token from the
+// token source with every request, as [jvspb.NewTokenTransport] does. If base
+// is nil, [http.DefaultTransport] is used.
+func (s *TokenSource) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{
+		source: s,
+		base:   jvspb.NewTokenTransport(base),
+	}
+}
+
+type transport struct {
+	source *TokenSource
+	base   http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, err := t.source.withToken(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(r.WithContext(ctx)) //nolint:wrapcheck // Want passthrough
+}
+
+// withToken returns the context with a justification token from the token
+// source, unless it already carries one.
+func (s *TokenSource) withToken(ctx context.Context) (context.Context, error) {
+	if jvspb.JustificationTokenFromContext(ctx) != "" {
+		return ctx, nil
+	}
+	token, err := s.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return jvspb.WithJustificationToken(ctx, token), nil
+}
@@ -0,0 +1,343 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvsclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+// fakeJVSClient mints tokens that expire after the TTL of the request.
+type fakeJVSClient struct {
+	jvspb.JVSServiceClient
+
+	key *ecdsa.PrivateKey
+	now func() time.Time
+
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (c *fakeJVSClient) CreateJustification(_ context.Context, req *jvspb.CreateJustificationRequest, _ ...grpc.CallOption) (*jvspb.CreateJustificationResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	now := c.now()
+	token, err := jwt.NewBuilder().
+		JwtID(fmt.Sprintf("token-%d", c.calls)).
+		IssuedAt(now).
+		Expiration(now.Add(req.GetTtl().AsDuration())).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	b, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, c.key))
+	if err != nil {
+		return nil, err
+	}
+	return &jvspb.CreateJustificationResponse{Token: string(b)}, nil
+}
+
+func (c *fakeJVSClient) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func testJustification(ttl time.Duration) JustificationFunc {
+	return func(_ context.Context) (*jvspb.CreateJustificationRequest, error) {
+		return &jvspb.CreateJustificationRequest{
+			Justifications: []*jvspb.Justification{
+				{Category: "explanation", Value: "nightly backup job"},
+			},
+			Ttl: durationpb.New(ttl),
+		}, nil
+	}
+}
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestTokenSource(tb testing.TB, opts ...TokenSourceOption) (*TokenSource, *fakeJVSClient, *fakeClock) {
+	tb.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Now().Truncate(time.Second)}
+	client := &fakeJVSClient{key: key, now: clock.Now}
+
+	s, err := NewTokenSource(client, testJustification(10*time.Minute), opts...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s.now = clock.Now
+	return s, client, clock
+}
+
+func jti(tb testing.TB, token string) string {
+	tb.Helper()
+
+	t, err := jwt.ParseInsecure([]byte(token))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return t.JwtID()
+}
+
+func TestNewTokenSource(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		client        jvspb.JVSServiceClient
+		justification JustificationFunc
+		opts          []TokenSourceOption
+		wantErr       string
+	}{
+		{
+			name:          "valid",
+			client:        &fakeJVSClient{},
+			justification: testJustification(time.Minute),
+		},
+		{
+			name:          "nil_client",
+			justification: testJustification(time.Minute),
+			wantErr:       "jvs client cannot be nil",
+		},
+		{
+			name:    "nil_justification",
+			client:  &fakeJVSClient{},
+			wantErr: "justification function cannot be nil",
+		},
+		{
+			name:          "negative_refresh_before",
+			client:        &fakeJVSClient{},
+			justification: testJustification(time.Minute),
+			opts:          []TokenSourceOption{WithRefreshBefore(-time.Minute)},
+			wantErr:       "refresh before cannot be negative, got -1m0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewTokenSource(tc.client, tc.justification, tc.opts...)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTokenSource_Token(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	s, client, clock := newTestTokenSource(t, WithRefreshBefore(2*time.Minute))
+
+	first, err := s.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := jti(t, first), "token-1"; got != want {
+		t.Errorf("expected first token %q to be %q", got, want)
+	}
+
+	// Well before the refresh, the same token is returned.
+	clock.Advance(5 * time.Minute)
+	got, err := s.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != first {
+		t.Errorf("expected the token to be reused")
+	}
+
+	// The 10m token is refreshed 2m before expiry, jittered by up to 1m.
+	clock.Advance(4*time.Minute + time.Second)
+	got, err = s.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := jti(t, got), "token-2"; got != want {
+		t.Errorf("expected refreshed token %q to be %q", got, want)
+	}
+
+	// Failing refreshes fall back to the current token until it expires.
+	client.setErr(fmt.Errorf("jvs unavailable"))
+	clock.Advance(8*time.Minute + time.Second)
+	got, err = s.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := jti(t, got), "token-2"; got != want {
+		t.Errorf("expected current token %q to be %q", got, want)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, err := s.Token(ctx); err == nil {
+		t.Errorf("expected error after the current token expired")
+	} else if diff := testutil.DiffErrString(err, "jvs unavailable"); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestTokenSource_Token_concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	s, client, _ := newTestTokenSource(t)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Token(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got, want := client.calls, 1; got != want {
+		t.Errorf("expected %d calls to the jvs to be %d", got, want)
+	}
+}
+
+func TestTokenSource_UnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	s, _, _ := newTestTokenSource(t)
+	want, err := s.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "minted_token",
+			ctx:  ctx,
+			want: want,
+		},
+		{
+			name: "propagated_token",
+			ctx:  jvspb.WithJustificationToken(ctx, "propagated-token"),
+			want: "propagated-token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				got = md.Get(jvspb.TokenHeader)
+				return nil
+			}
+			if err := s.UnaryClientInterceptor(tc.ctx, "/test.Service/Method", nil, nil, nil, invoker); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0] != tc.want {
+				t.Errorf("expected outgoing tokens %q to be [%q]", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTokenSource_Transport(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	s, _, _ := newTestTokenSource(t)
+	want, err := s.Token(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(jvspb.TokenHeader)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: s.Transport(srv.Client().Transport)}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got != want {
+		t.Errorf("expected server token %q to be %q", got, want)
+	}
+}
@@ -16,63 +16,254 @@ package jvscrypto
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
 )
 
+// versionNameRegexp matches crypto key version resource names, capturing the
+// crypto key resource name.
+var versionNameRegexp = regexp.MustCompile(
+	`^(projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+)/cryptoKeyVersions/[^/]+$`)
+
+// keyNameRegexp matches crypto key resource names.
+var keyNameRegexp = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
 // CertificateActionService allows for performing manual actions on certificate
 // versions.
 type CertificateActionService struct {
 	jvspb.CertificateActionServiceServer
 	Handler   *RotationHandler
 	KMSClient *kms.KeyManagementClient
+
+	// KeyNames, if set, are the only crypto keys actions can be performed on.
+	KeyNames []string
+
+	// ConfirmationKey is the HMAC key dry run confirmation tokens are signed
+	// with. If unset, a random key is generated, and tokens can only be used on
+	// the same instance that issued them.
+	ConfirmationKey []byte
+
+	confirmationKeyOnce sync.Once
+	confirmationKey     []byte
+	confirmationKeyErr  error
 }
 
 // CertificateAction implements the certificate action API which performs manual
-// actions on cert versions. this wraps certificateAction and adds a blank
-// response.
+// actions on cert versions. Each action is validated and performed
+// independently, and its outcome is reported in the response rather than
+// failing the whole request.
+//
+// Dry runs only plan the actions and return a confirmation token. Performing
+// actions that include FORCE_DESTROY requires that token, so the destroyed
+// versions are exactly the ones that were reviewed.
 func (p *CertificateActionService) CertificateAction(ctx context.Context, request *jvspb.CertificateActionRequest) (*jvspb.CertificateActionResponse, error) {
-	return &jvspb.CertificateActionResponse{}, p.certificateAction(ctx, request)
-}
+	logger := logging.FromContext(ctx)
+	now := time.Now().UTC()
+
+	actions := request.GetActions()
+
+	confirmationKey, err := p.getConfirmationKey()
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get confirmation key", "error", err)
+		return nil, status.Error(codes.Internal, "failed to get confirmation key")
+	}
+	digest, err := actionsDigest(actions)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid actions: %s", err)
+	}
+
+	if !request.GetDryRun() {
+		if token := request.GetConfirmationToken(); token != "" {
+			if err := verifyConfirmation(confirmationKey, token, digest, now); err != nil {
+				return nil, status.Errorf(codes.FailedPrecondition, "invalid confirmation token: %s", err)
+			}
+		} else if slices.ContainsFunc(actions, func(a *jvspb.Action) bool {
+			return a.GetAction() == jvspb.Action_FORCE_DESTROY
+		}) {
+			return nil, status.Error(codes.FailedPrecondition,
+				"FORCE_DESTROY requires the confirmation token from a dry run of the same actions")
+		}
+	}
+
+	resp := &jvspb.CertificateActionResponse{
+		Results: make([]*jvspb.ActionResult, 0, len(actions)),
+	}
 
-func (p *CertificateActionService) certificateAction(ctx context.Context, request *jvspb.CertificateActionRequest) error {
-	// create map of key -> version actions list
-	actions := make(map[string][]*actionTuple)
-	for _, action := range request.GetActions() {
-		key, err := getKeyNameFromVersion(action.GetVersion())
+	// Plan every action before performing any, so all actions are planned
+	// against the key state before this request, e.g. the same primary.
+	planned := make([][]*actionTuple, len(actions))
+	valid := true
+	for i, action := range actions {
+		key, tuples, err := p.planAction(ctx, action)
+		result := &jvspb.ActionResult{
+			Key:     key,
+			Version: action.GetVersion(),
+			Action:  action.GetAction(),
+		}
 		if err != nil {
-			return fmt.Errorf("couldn't determine key name from version %s: %w", action.GetVersion(), err)
+			result.Error = err.Error()
+			valid = false
 		}
-		keyActions, ok := actions[key]
-		if !ok {
-			keyActions = make([]*actionTuple, 0)
+		for _, t := range tuples {
+			result.Plan = append(result.Plan, t.String())
 		}
+		resp.Results = append(resp.Results, result)
+		planned[i] = tuples
+	}
 
-		ver, err := p.KMSClient.GetCryptoKeyVersion(ctx, &kmspb.GetCryptoKeyVersionRequest{Name: action.GetVersion()})
-		if err != nil {
-			return fmt.Errorf("couldn't get key version %s: %w", action.GetVersion(), err)
+	if request.GetDryRun() {
+		if valid {
+			token, err := signConfirmation(confirmationKey, digest, now.Add(confirmationTokenTTL))
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to sign confirmation token", "error", err)
+				return nil, status.Error(codes.Internal, "failed to sign confirmation token")
+			}
+			resp.ConfirmationToken = token
 		}
+		return resp, nil
+	}
 
-		primary, err := GetPrimary(ctx, p.KMSClient, key)
-		if err != nil {
-			return fmt.Errorf("couldn't determine current primary: %w", err)
+	for i, result := range resp.Results {
+		if result.GetError() != "" {
+			continue
+		}
+		if err := p.Handler.performActions(ctx, result.GetKey(), planned[i]); err != nil {
+			logger.ErrorContext(ctx, "failed to perform certificate action",
+				"version", result.GetVersion(),
+				"action", result.GetAction().String(),
+				"error", err)
+			result.Error = fmt.Sprintf("couldn't perform actions %v on key %s: %s", planned[i], result.GetKey(), err)
+			continue
+		}
+		result.Success = true
+	}
+	return resp, nil
+}
+
+// KeyStatus implements the key status API, which describes the versions of a
+// key and when their key material is destroyed, so the actual destruction
+// timeline can be audited.
+func (p *CertificateActionService) KeyStatus(ctx context.Context, request *jvspb.KeyStatusRequest) (*jvspb.KeyStatusResponse, error) {
+	logger := logging.FromContext(ctx)
+
+	key := request.GetKey()
+	if !keyNameRegexp.MatchString(key) {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"key %q is not a crypto key resource name (projects/*/locations/*/keyRings/*/cryptoKeys/*)", key)
+	}
+	if len(p.KeyNames) > 0 && !slices.Contains(p.KeyNames, key) {
+		return nil, status.Errorf(codes.InvalidArgument, "key %q is not managed by the jvs", key)
+	}
+
+	cryptoKey, err := p.KMSClient.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: key})
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to get key", "key", key, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to get key %s", key)
+	}
+	vers, err := NewKMSKeyManager(p.KMSClient).ListVersions(ctx, key)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to list key versions", "key", key, "error", err)
+		return nil, status.Errorf(codes.Internal, "failed to list versions of key %s", key)
+	}
+
+	scheduled := DefaultDestroyScheduledDuration
+	if d := cryptoKey.GetDestroyScheduledDuration(); d != nil {
+		scheduled = d.AsDuration()
+	}
+
+	resp := &jvspb.KeyStatusResponse{
+		Key:                      key,
+		Primary:                  primaryFromLabels(key, cryptoKey.GetLabels()),
+		DestroyScheduledDuration: durationpb.New(scheduled),
+		Versions:                 make([]*jvspb.KeyVersionStatus, 0, len(vers)),
+	}
+	for _, ver := range vers {
+		versionStatus := &jvspb.KeyVersionStatus{
+			Version:    ver.GetName(),
+			State:      ver.GetState().String(),
+			CreateTime: ver.GetCreateTime(),
 		}
 
-		keyActions = append(keyActions, determineActions(ver, action.GetAction(), primary)...)
-		actions[key] = keyActions
+		//nolint:exhaustive // Other states are never destroyed by the jvs.
+		switch ver.GetState() {
+		case kmspb.CryptoKeyVersion_ENABLED, kmspb.CryptoKeyVersion_DISABLED:
+			// Rotation destroys versions once they reach the destroy request age,
+			// and KMS destroys their key material after the destroy schedule.
+			destroyTime := ver.GetCreateTime().AsTime().Add(p.Handler.config.DestroyRequestAge() + scheduled)
+			versionStatus.DestroyTime = timestamppb.New(destroyTime)
+			versionStatus.ExpectedDestroyTime = true
+		case kmspb.CryptoKeyVersion_DESTROY_SCHEDULED:
+			versionStatus.DestroyTime = ver.GetDestroyTime()
+		case kmspb.CryptoKeyVersion_DESTROYED:
+			versionStatus.DestroyTime = ver.GetDestroyEventTime()
+		}
+		resp.Versions = append(resp.Versions, versionStatus)
+	}
+	return resp, nil
+}
+
+// getConfirmationKey returns the configured confirmation key, or a random key
+// generated on first use.
+func (p *CertificateActionService) getConfirmationKey() ([]byte, error) {
+	if len(p.ConfirmationKey) > 0 {
+		return p.ConfirmationKey, nil
 	}
 
-	for key, actionTuples := range actions {
-		if err := p.Handler.performActions(ctx, key, actionTuples); err != nil {
-			// If any actions fail, short circuit.
-			return fmt.Errorf("couldn't perform actions %v on key %s: %w", actionTuples, key, err)
+	p.confirmationKeyOnce.Do(func() {
+		p.confirmationKey = make([]byte, 32)
+		if _, err := rand.Read(p.confirmationKey); err != nil {
+			p.confirmationKeyErr = fmt.Errorf("failed to generate confirmation key: %w", err)
 		}
+	})
+	return p.confirmationKey, p.confirmationKeyErr
+}
+
+// planAction validates the action and determines the changes needed to perform
+// it. It returns the crypto key the action applies to.
+func (p *CertificateActionService) planAction(ctx context.Context, action *jvspb.Action) (string, []*actionTuple, error) {
+	if _, ok := jvspb.Action_ACTION_name[int32(action.GetAction())]; !ok {
+		return "", nil, fmt.Errorf("unknown action %d", action.GetAction())
+	}
+
+	matches := versionNameRegexp.FindStringSubmatch(action.GetVersion())
+	if matches == nil {
+		return "", nil, fmt.Errorf("version %q is not a crypto key version resource name "+
+			"(projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*)", action.GetVersion())
+	}
+	key := matches[1]
+
+	if want := action.GetKey(); want != "" && want != key {
+		return key, nil, fmt.Errorf("version %q does not belong to key %q", action.GetVersion(), want)
 	}
-	return nil
+	if len(p.KeyNames) > 0 && !slices.Contains(p.KeyNames, key) {
+		return key, nil, fmt.Errorf("key %q is not managed by the jvs", key)
+	}
+
+	ver, err := p.KMSClient.GetCryptoKeyVersion(ctx, &kmspb.GetCryptoKeyVersionRequest{Name: action.GetVersion()})
+	if err != nil {
+		return key, nil, fmt.Errorf("couldn't get key version %s: %w", action.GetVersion(), err)
+	}
+
+	primary, err := GetPrimary(ctx, p.KMSClient, key)
+	if err != nil {
+		return key, nil, fmt.Errorf("couldn't determine current primary: %w", err)
+	}
+
+	return key, determineActions(ver, action.GetAction(), primary), nil
 }
 
 // determineActions decides which changes we should make based on the asked for
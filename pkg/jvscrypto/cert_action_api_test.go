@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"cloud.google.com/go/kms/apiv1/kmspb"
@@ -26,7 +27,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/pkg/config"
@@ -45,6 +48,7 @@ func TestCertificateAction(t *testing.T) {
 	cases := []struct {
 		name             string
 		request          *jvspb.CertificateActionRequest
+		confirmed        bool
 		priorPrimary     string
 		expectedRequests []proto.Message
 		expectedPrimary  string
@@ -202,6 +206,7 @@ func TestCertificateAction(t *testing.T) {
 					},
 				},
 			},
+			confirmed:    true,
 			priorPrimary: PrimaryLabelPrefix + versionSuffix,
 			expectedRequests: []proto.Message{
 				// Look up the existing key version
@@ -346,10 +351,20 @@ func TestCertificateAction(t *testing.T) {
 				KMSClient: c,
 			}
 
-			_, gotErr := service.CertificateAction(ctx, tc.request)
+			request := proto.Clone(tc.request).(*jvspb.CertificateActionRequest)
+			if tc.confirmed {
+				request.ConfirmationToken = testConfirmationToken(t, service, request.GetActions())
+			}
+
+			resp, gotErr := service.CertificateAction(ctx, request)
 			if diff := pkgtestutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
 				t.Errorf("Unexpected err: %s", diff)
 			}
+			for _, result := range resp.GetResults() {
+				if !result.GetSuccess() {
+					t.Errorf("expected action on %s to succeed: %s", result.GetVersion(), result.GetError())
+				}
+			}
 			if diff := cmp.Diff(tc.expectedRequests, mockKMS.Reqs, protocmp.Transform()); diff != "" {
 				t.Errorf("wrong requests: diff (-want, +got): %s", diff)
 			}
@@ -359,3 +374,370 @@ func TestCertificateAction(t *testing.T) {
 		})
 	}
 }
+
+func TestCertificateAction_results(t *testing.T) {
+	t.Parallel()
+
+	parent := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	otherKey := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[OTHER_KEY]"
+	versionName := parent + "/cryptoKeyVersions/[VERSION]"
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	// The version is not primary, so rotating it is a no-op.
+	mockKMS := testutil.NewMockKeyManagementServer(parent, versionName, PrimaryLabelPrefix+"[VERSION]-2")
+
+	_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, mockKMS)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := &CertificateActionService{
+		Handler:   NewRotationHandler(ctx, c, &config.CertRotationConfig{}),
+		KMSClient: c,
+		KeyNames:  []string{parent},
+	}
+
+	resp, err := service.CertificateAction(ctx, &jvspb.CertificateActionRequest{
+		Actions: []*jvspb.Action{
+			{
+				Version: "1",
+				Action:  jvspb.Action_FORCE_DISABLE,
+			},
+			{
+				Version: versionName,
+				Key:     otherKey,
+				Action:  jvspb.Action_FORCE_DISABLE,
+			},
+			{
+				Version: otherKey + "/cryptoKeyVersions/1",
+				Action:  jvspb.Action_FORCE_DISABLE,
+			},
+			{
+				Version: versionName,
+				Action:  jvspb.Action_ACTION(42),
+			},
+			{
+				Version: versionName,
+				Key:     parent,
+				Action:  jvspb.Action_ROTATE,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*jvspb.ActionResult{
+		{
+			Version: "1",
+			Action:  jvspb.Action_FORCE_DISABLE,
+			Error: `version "1" is not a crypto key version resource name ` +
+				`(projects/*/locations/*/keyRings/*/cryptoKeys/*/cryptoKeyVersions/*)`,
+		},
+		{
+			Key:     parent,
+			Version: versionName,
+			Action:  jvspb.Action_FORCE_DISABLE,
+			Error:   fmt.Sprintf("version %q does not belong to key %q", versionName, otherKey),
+		},
+		{
+			Key:     otherKey,
+			Version: otherKey + "/cryptoKeyVersions/1",
+			Action:  jvspb.Action_FORCE_DISABLE,
+			Error:   fmt.Sprintf("key %q is not managed by the jvs", otherKey),
+		},
+		{
+			Version: versionName,
+			Action:  jvspb.Action_ACTION(42),
+			Error:   "unknown action 42",
+		},
+		{
+			Key:     parent,
+			Version: versionName,
+			Action:  jvspb.Action_ROTATE,
+			Success: true,
+		},
+	}
+	if diff := cmp.Diff(want, resp.GetResults(), protocmp.Transform()); diff != "" {
+		t.Errorf("results: diff (-want, +got): %s", diff)
+	}
+
+	// Only the valid action should have reached KMS.
+	wantRequests := []proto.Message{
+		&kmspb.GetCryptoKeyVersionRequest{
+			Name: versionName,
+		},
+		&kmspb.GetCryptoKeyRequest{
+			Name: parent,
+		},
+	}
+	if diff := cmp.Diff(wantRequests, mockKMS.Reqs, protocmp.Transform()); diff != "" {
+		t.Errorf("wrong requests: diff (-want, +got): %s", diff)
+	}
+}
+
+func TestCertificateAction_dryRun(t *testing.T) {
+	t.Parallel()
+
+	parent := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	versionName := parent + "/cryptoKeyVersions/[VERSION]"
+	otherVersionName := parent + "/cryptoKeyVersions/[OTHER_VERSION]"
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	mockKMS := testutil.NewMockKeyManagementServer(parent, versionName, PrimaryLabelPrefix+"[VERSION]")
+
+	_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, mockKMS)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := &CertificateActionService{
+		Handler:   NewRotationHandler(ctx, c, &config.CertRotationConfig{}),
+		KMSClient: c,
+	}
+
+	actions := []*jvspb.Action{
+		{
+			Version: versionName,
+			Action:  jvspb.Action_FORCE_DESTROY,
+		},
+	}
+
+	// Performing a destroy without confirmation is refused before touching KMS.
+	_, err = service.CertificateAction(ctx, &jvspb.CertificateActionRequest{
+		Actions: actions,
+	})
+	if diff := pkgtestutil.DiffErrString(err, "FORCE_DESTROY requires the confirmation token"); diff != "" {
+		t.Error(diff)
+	}
+
+	// The dry run plans the actions without changing anything.
+	plan, err := service.CertificateAction(ctx, &jvspb.CertificateActionRequest{
+		Actions: actions,
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan := []*jvspb.ActionResult{
+		{
+			Key:     parent,
+			Version: versionName,
+			Action:  jvspb.Action_FORCE_DESTROY,
+			Plan: []string{
+				"create new version and promote to primary",
+				"destroy " + versionName,
+			},
+		},
+	}
+	if diff := cmp.Diff(wantPlan, plan.GetResults(), protocmp.Transform()); diff != "" {
+		t.Errorf("plan: diff (-want, +got): %s", diff)
+	}
+	if plan.GetConfirmationToken() == "" {
+		t.Fatalf("expected a confirmation token")
+	}
+	for _, req := range mockKMS.Reqs {
+		switch req.(type) {
+		case *kmspb.GetCryptoKeyVersionRequest, *kmspb.GetCryptoKeyRequest:
+		default:
+			t.Errorf("expected dry run to only read from kms, got %T", req)
+		}
+	}
+
+	// The token can't be used for different actions.
+	_, err = service.CertificateAction(ctx, &jvspb.CertificateActionRequest{
+		Actions: []*jvspb.Action{
+			{
+				Version: otherVersionName,
+				Action:  jvspb.Action_FORCE_DESTROY,
+			},
+		},
+		ConfirmationToken: plan.GetConfirmationToken(),
+	})
+	if diff := pkgtestutil.DiffErrString(err, "confirmation token was issued for different actions"); diff != "" {
+		t.Error(diff)
+	}
+
+	// Re-submitting with the token performs the actions.
+	resp, err := service.CertificateAction(ctx, &jvspb.CertificateActionRequest{
+		Actions:           actions,
+		ConfirmationToken: plan.GetConfirmationToken(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(resp.GetResults()), 1; got != want {
+		t.Fatalf("expected %d results to be %d", got, want)
+	}
+	if result := resp.GetResults()[0]; !result.GetSuccess() {
+		t.Errorf("expected action to succeed: %s", result.GetError())
+	}
+	if _, ok := mockKMS.Reqs[len(mockKMS.Reqs)-1].(*kmspb.DestroyCryptoKeyVersionRequest); !ok {
+		t.Errorf("expected version to be destroyed, got %T", mockKMS.Reqs[len(mockKMS.Reqs)-1])
+	}
+}
+
+func TestKeyStatus(t *testing.T) {
+	t.Parallel()
+
+	parent := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	otherKey := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[OTHER_KEY]"
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	destroyTime := time.Date(2023, 3, 5, 0, 0, 0, 0, time.UTC)
+	destroyedTime := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	mockKMS := testutil.NewMockKeyManagementServer(parent, parent+"/cryptoKeyVersions/1", PrimaryLabelPrefix+"1")
+	mockKMS.DestroyScheduledDuration = durationpb.New(24 * time.Hour)
+	mockKMS.Versions = []*kmspb.CryptoKeyVersion{
+		{
+			Name:       parent + "/cryptoKeyVersions/1",
+			State:      kmspb.CryptoKeyVersion_ENABLED,
+			CreateTime: timestamppb.New(created),
+		},
+		{
+			Name:        parent + "/cryptoKeyVersions/2",
+			State:       kmspb.CryptoKeyVersion_DESTROY_SCHEDULED,
+			CreateTime:  timestamppb.New(created),
+			DestroyTime: timestamppb.New(destroyTime),
+		},
+		{
+			Name:             parent + "/cryptoKeyVersions/3",
+			State:            kmspb.CryptoKeyVersion_DESTROYED,
+			CreateTime:       timestamppb.New(created),
+			DestroyEventTime: timestamppb.New(destroyedTime),
+		},
+		{
+			Name:       parent + "/cryptoKeyVersions/4",
+			State:      kmspb.CryptoKeyVersion_PENDING_GENERATION,
+			CreateTime: timestamppb.New(created),
+		},
+	}
+
+	_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, mockKMS)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := &CertificateActionService{
+		Handler: NewRotationHandler(ctx, c, &config.CertRotationConfig{
+			KeyTTL:                   10 * 24 * time.Hour,
+			DisabledPeriod:           20 * 24 * time.Hour,
+			DestroyScheduledDuration: 24 * time.Hour,
+		}),
+		KMSClient: c,
+		KeyNames:  []string{parent},
+	}
+
+	cases := []struct {
+		name    string
+		key     string
+		want    *jvspb.KeyStatusResponse
+		wantErr string
+	}{
+		{
+			name: "success",
+			key:  parent,
+			want: &jvspb.KeyStatusResponse{
+				Key:                      parent,
+				Primary:                  parent + "/cryptoKeyVersions/1",
+				DestroyScheduledDuration: durationpb.New(24 * time.Hour),
+				Versions: []*jvspb.KeyVersionStatus{
+					{
+						Version:    parent + "/cryptoKeyVersions/1",
+						State:      "ENABLED",
+						CreateTime: timestamppb.New(created),
+						// The key material is destroyed 30 days after creation: the
+						// version is destroyed after 29 days, and its key material a day later.
+						DestroyTime:         timestamppb.New(created.Add(30 * 24 * time.Hour)),
+						ExpectedDestroyTime: true,
+					},
+					{
+						Version:     parent + "/cryptoKeyVersions/2",
+						State:       "DESTROY_SCHEDULED",
+						CreateTime:  timestamppb.New(created),
+						DestroyTime: timestamppb.New(destroyTime),
+					},
+					{
+						Version:     parent + "/cryptoKeyVersions/3",
+						State:       "DESTROYED",
+						CreateTime:  timestamppb.New(created),
+						DestroyTime: timestamppb.New(destroyedTime),
+					},
+					{
+						Version:    parent + "/cryptoKeyVersions/4",
+						State:      "PENDING_GENERATION",
+						CreateTime: timestamppb.New(created),
+					},
+				},
+			},
+		},
+		{
+			name:    "invalid_key",
+			key:     parent + "/cryptoKeyVersions/1",
+			wantErr: "is not a crypto key resource name",
+		},
+		{
+			name:    "unmanaged_key",
+			key:     otherKey,
+			wantErr: "is not managed by the jvs",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := service.KeyStatus(ctx, &jvspb.KeyStatusRequest{Key: tc.key})
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("diff (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func testConfirmationToken(tb testing.TB, service *CertificateActionService, actions []*jvspb.Action) string {
+	tb.Helper()
+
+	key, err := service.getConfirmationKey()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	digest, err := actionsDigest(actions)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	token, err := signConfirmation(key, digest, time.Now().Add(time.Minute))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return token
+}
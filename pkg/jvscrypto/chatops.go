@@ -0,0 +1,312 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/status"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// maxChatOpsRequestSize is the maximum size of the body of slash command
+	// requests.
+	maxChatOpsRequestSize = 64 * 1024
+
+	// chatOpsMaxRequestAge is how old the timestamp of a slash command request
+	// can be, so captured requests can't be replayed.
+	chatOpsMaxRequestAge = 5 * time.Minute
+
+	chatOpsUsage = "Usage: `rotate [version]`, `status [key]` or `disable <version>`"
+)
+
+// ChatOpsHandler serves Slack compatible slash commands for key rotation. It
+// lets authorized users rotate the primary key version, describe the keys
+// and disable key versions through the [CertificateActionService]:
+//
+//	/jvs-keys rotate [version]
+//	/jvs-keys status [key]
+//	/jvs-keys disable <version>
+//
+// Versions can be given as their number if a single key is managed. Requests
+// must be signed with the Slack signing secret, and every command is logged
+// with the user who ran it.
+type ChatOpsHandler struct {
+	service       *CertificateActionService
+	signingSecret []byte
+	allowedUsers  []string
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// NewChatOpsHandler returns a handler that performs the commands of the
+// allowed Slack user IDs through the service, after verifying the requests
+// are signed with the signing secret.
+func NewChatOpsHandler(service *CertificateActionService, signingSecret []byte, allowedUsers []string) *ChatOpsHandler {
+	return &ChatOpsHandler{
+		service:       service,
+		signingSecret: signingSecret,
+		allowedUsers:  allowedUsers,
+		now:           time.Now,
+	}
+}
+
+// chatOpsResponse is the response to a slash command.
+type chatOpsResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *ChatOpsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxChatOpsRequestSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if err := h.verifySignature(r.Header, body); err != nil {
+		logger.WarnContext(ctx, "unauthenticated chatops request", "error", err)
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	userID, text := form.Get("user_id"), strings.TrimSpace(form.Get("text"))
+	logger = logger.With(
+		"user_id", userID,
+		"user_name", form.Get("user_name"),
+		"team_id", form.Get("team_id"),
+		"channel_id", form.Get("channel_id"),
+		"command", form.Get("command"),
+		"text", text)
+
+	if !slices.Contains(h.allowedUsers, userID) {
+		logger.WarnContext(ctx, "unauthorized chatops command")
+		h.respond(w, "ephemeral", "You are not authorized to manage the JVS keys.")
+		return
+	}
+
+	resp, err := h.run(ctx, text)
+	if err != nil {
+		logger.WarnContext(ctx, "failed chatops command", "error", err)
+		h.respond(w, "ephemeral", err.Error())
+		return
+	}
+	logger.InfoContext(ctx, "ran chatops command", "result", resp)
+	h.respond(w, "in_channel", resp)
+}
+
+// verifySignature checks the request was signed with the signing secret
+// recently, as described in
+// https://api.slack.com/authentication/verifying-requests-from-slack.
+func (h *ChatOpsHandler) verifySignature(header http.Header, body []byte) error {
+	if len(h.signingSecret) == 0 {
+		return fmt.Errorf("no signing secret is configured")
+	}
+
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp %q", ts)
+	}
+	if age := h.now().Sub(time.Unix(sec, 0)); age > chatOpsMaxRequestAge || age < -chatOpsMaxRequestAge {
+		return fmt.Errorf("request timestamp is %s off", age.Round(time.Second))
+	}
+
+	got, ok := strings.CutPrefix(header.Get("X-Slack-Signature"), "v0=")
+	if !ok {
+		return fmt.Errorf("missing v0 signature")
+	}
+	gotMAC, err := hex.DecodeString(got)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if !hmac.Equal(gotMAC, ChatOpsSignature(h.signingSecret, ts, body)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// ChatOpsSignature returns the Slack v0 signature of a request body sent at
+// the timestamp, in Unix seconds.
+func ChatOpsSignature(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// run performs the command and returns the text to respond with.
+func (h *ChatOpsHandler) run(ctx context.Context, text string) (string, error) {
+	command, args, _ := strings.Cut(text, " ")
+	args = strings.TrimSpace(args)
+
+	switch command {
+	case "rotate":
+		if args == "" {
+			return h.rotatePrimaries(ctx)
+		}
+		return h.act(ctx, args, jvspb.Action_ROTATE)
+	case "disable":
+		if args == "" {
+			return "", fmt.Errorf("disable requires a version. %s", chatOpsUsage)
+		}
+		return h.act(ctx, args, jvspb.Action_FORCE_DISABLE)
+	case "status":
+		return h.status(ctx, args)
+	default:
+		return "", fmt.Errorf("unknown command %q. %s", command, chatOpsUsage)
+	}
+}
+
+// rotatePrimaries rotates the primary version of every managed key.
+func (h *ChatOpsHandler) rotatePrimaries(ctx context.Context) (string, error) {
+	if len(h.service.KeyNames) == 0 {
+		return "", fmt.Errorf("no keys are managed, give the version to rotate")
+	}
+
+	actions := make([]*jvspb.Action, 0, len(h.service.KeyNames))
+	for _, key := range h.service.KeyNames {
+		primary, err := GetPrimary(ctx, h.service.KMSClient, key)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the primary version of key %s", key)
+		}
+		if primary == "" {
+			return "", fmt.Errorf("key %s has no primary version", key)
+		}
+		actions = append(actions, &jvspb.Action{
+			Version: primary,
+			Action:  jvspb.Action_ROTATE,
+		})
+	}
+	return h.perform(ctx, actions)
+}
+
+// act performs the action on the version.
+func (h *ChatOpsHandler) act(ctx context.Context, version string, action jvspb.Action_ACTION) (string, error) {
+	return h.perform(ctx, []*jvspb.Action{
+		{
+			Version: h.versionName(version),
+			Action:  action,
+		},
+	})
+}
+
+func (h *ChatOpsHandler) perform(ctx context.Context, actions []*jvspb.Action) (string, error) {
+	resp, err := h.service.CertificateAction(ctx, &jvspb.CertificateActionRequest{Actions: actions})
+	if err != nil {
+		return "", errors.New(status.Convert(err).Message())
+	}
+
+	var b strings.Builder
+	for _, result := range resp.GetResults() {
+		if result.GetSuccess() {
+			fmt.Fprintf(&b, ":white_check_mark: %s `%s`", result.GetAction(), result.GetVersion())
+			if len(result.GetPlan()) > 0 {
+				fmt.Fprintf(&b, ": %s", strings.Join(result.GetPlan(), ", "))
+			}
+		} else {
+			fmt.Fprintf(&b, ":x: %s `%s`: %s", result.GetAction(), result.GetVersion(), result.GetError())
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// status describes the versions of the key, or of every managed key.
+func (h *ChatOpsHandler) status(ctx context.Context, key string) (string, error) {
+	keys := h.service.KeyNames
+	if key != "" {
+		keys = []string{key}
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("no keys are managed, give the key to describe")
+	}
+
+	var b strings.Builder
+	for _, key := range keys {
+		resp, err := h.service.KeyStatus(ctx, &jvspb.KeyStatusRequest{Key: key})
+		if err != nil {
+			return "", errors.New(status.Convert(err).Message())
+		}
+		fmt.Fprintf(&b, "*%s*\nPrimary: `%s`\n", resp.GetKey(), resp.GetPrimary())
+		for _, v := range resp.GetVersions() {
+			fmt.Fprintf(&b, "• `%s` %s, created %s", v.GetVersion(), v.GetState(),
+				v.GetCreateTime().AsTime().Format(time.RFC3339))
+			if v.GetDestroyTime() != nil {
+				verb := "destroyed"
+				if v.GetExpectedDestroyTime() {
+					verb = "destroyed by"
+				}
+				fmt.Fprintf(&b, ", %s %s", verb, v.GetDestroyTime().AsTime().Format(time.RFC3339))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// versionName returns the resource name of the version, which can be given as
+// its number if a single key is managed.
+func (h *ChatOpsHandler) versionName(version string) string {
+	if _, err := strconv.ParseUint(version, 10, 64); err == nil && len(h.service.KeyNames) == 1 {
+		return h.service.KeyNames[0] + "/cryptoKeyVersions/" + version
+	}
+	return version
+}
+
+func (h *ChatOpsHandler) respond(w http.ResponseWriter, responseType, text string) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(&chatOpsResponse{
+		ResponseType: responseType,
+		Text:         text,
+	}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b.Bytes())
+}
@@ -0,0 +1,233 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/testutil"
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestChatOpsHandler(t *testing.T) {
+	t.Parallel()
+
+	parent := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	secret := []byte("slack-signing-secret")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		method    string
+		user      string
+		text      string
+		timestamp time.Time
+		secret    []byte
+		wantCode  int
+		wantType  string
+		wantText  []string
+	}{
+		{
+			name:     "rotate",
+			text:     "rotate",
+			wantCode: http.StatusOK,
+			wantType: "in_channel",
+			wantText: []string{
+				":white_check_mark: ROTATE `" + parent + "/cryptoKeyVersions/1`: create new version and promote to primary",
+			},
+		},
+		{
+			name:     "disable_version_number",
+			text:     "disable 2",
+			wantCode: http.StatusOK,
+			wantType: "in_channel",
+			wantText: []string{
+				":white_check_mark: FORCE_DISABLE `" + parent + "/cryptoKeyVersions/2`: disable " + parent + "/cryptoKeyVersions/2",
+			},
+		},
+		{
+			name:     "disable_unmanaged_key",
+			text:     "disable projects/p/locations/l/keyRings/r/cryptoKeys/other/cryptoKeyVersions/1",
+			wantCode: http.StatusOK,
+			wantType: "in_channel",
+			wantText: []string{
+				`:x: FORCE_DISABLE ` + "`projects/p/locations/l/keyRings/r/cryptoKeys/other/cryptoKeyVersions/1`" +
+					`: key "projects/p/locations/l/keyRings/r/cryptoKeys/other" is not managed by the jvs`,
+			},
+		},
+		{
+			name:     "disable_without_version",
+			text:     "disable",
+			wantCode: http.StatusOK,
+			wantType: "ephemeral",
+			wantText: []string{"disable requires a version"},
+		},
+		{
+			name:     "status",
+			text:     "status",
+			wantCode: http.StatusOK,
+			wantType: "in_channel",
+			wantText: []string{
+				"*" + parent + "*",
+				"Primary: `" + parent + "/cryptoKeyVersions/1`",
+				"• `" + parent + "/cryptoKeyVersions/1` ENABLED, created 2025-12-01T00:00:00Z",
+			},
+		},
+		{
+			name:     "status_unmanaged_key",
+			text:     "status projects/p/locations/l/keyRings/r/cryptoKeys/other",
+			wantCode: http.StatusOK,
+			wantType: "ephemeral",
+			wantText: []string{`key "projects/p/locations/l/keyRings/r/cryptoKeys/other" is not managed by the jvs`},
+		},
+		{
+			name:     "unknown_command",
+			text:     "destroy 1",
+			wantCode: http.StatusOK,
+			wantType: "ephemeral",
+			wantText: []string{`unknown command "destroy"`, chatOpsUsage},
+		},
+		{
+			name:     "unauthorized_user",
+			user:     "U999",
+			text:     "rotate",
+			wantCode: http.StatusOK,
+			wantType: "ephemeral",
+			wantText: []string{"You are not authorized to manage the JVS keys."},
+		},
+		{
+			name:     "invalid_signature",
+			text:     "rotate",
+			secret:   []byte("other-secret"),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "stale_timestamp",
+			text:      "rotate",
+			timestamp: now.Add(-10 * time.Minute),
+			wantCode:  http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong_method",
+			method:   http.MethodGet,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			mockKMS := testutil.NewMockKeyManagementServer(parent, parent+"/cryptoKeyVersions/1", PrimaryLabelPrefix+"1")
+			mockKMS.Versions = []*kmspb.CryptoKeyVersion{
+				{
+					Name:       parent + "/cryptoKeyVersions/1",
+					State:      kmspb.CryptoKeyVersion_ENABLED,
+					CreateTime: timestamppb.New(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)),
+				},
+			}
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKMS)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+			c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := NewChatOpsHandler(&CertificateActionService{
+				Handler:   NewRotationHandler(ctx, c, &config.CertRotationConfig{}),
+				KMSClient: c,
+				KeyNames:  []string{parent},
+			}, secret, []string{"U012AB3CD"})
+			handler.now = func() time.Time { return now }
+
+			user := tc.user
+			if user == "" {
+				user = "U012AB3CD"
+			}
+			body := url.Values{
+				"command":   {"/jvs-keys"},
+				"text":      {tc.text},
+				"user_id":   {user},
+				"user_name": {"jane"},
+				"team_id":   {"T0001"},
+			}.Encode()
+
+			timestamp := tc.timestamp
+			if timestamp.IsZero() {
+				timestamp = now
+			}
+			signingSecret := tc.secret
+			if signingSecret == nil {
+				signingSecret = secret
+			}
+			ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/chatops/slack", strings.NewReader(body)).WithContext(ctx)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Slack-Request-Timestamp", ts)
+			req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(ChatOpsSignature(signingSecret, ts, []byte(body))))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Fatalf("expected status %d, got %d: %s", want, got, w.Body.String())
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var resp chatOpsResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := resp.ResponseType, tc.wantType; got != want {
+				t.Errorf("expected response type %q, got %q", want, got)
+			}
+			for _, want := range tc.wantText {
+				if !strings.Contains(resp.Text, want) {
+					t.Errorf("expected response to contain %q, got %q", want, resp.Text)
+				}
+			}
+		})
+	}
+}
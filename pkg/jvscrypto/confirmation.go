@@ -0,0 +1,109 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// confirmationTokenTTL is how long a dry run's confirmation token can be used.
+const confirmationTokenTTL = 10 * time.Minute
+
+// confirmationClaims are the contents of a confirmation token.
+type confirmationClaims struct {
+	// Digest is the base64 encoded SHA-256 digest of the confirmed actions.
+	Digest string `json:"digest"`
+
+	// Expiry is when the token expires, in seconds since the Unix epoch.
+	Expiry int64 `json:"exp"`
+}
+
+// actionsDigest returns a digest that identifies the actions, including their
+// order.
+func actionsDigest(actions []*jvspb.Action) ([]byte, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(&jvspb.CertificateActionRequest{
+		Actions: actions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal actions: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// signConfirmation creates a confirmation token for the actions digest, in the
+// format base64(claims).base64(hmac).
+func signConfirmation(key, digest []byte, expiry time.Time) (string, error) {
+	b, err := json.Marshal(&confirmationClaims{
+		Digest: base64.RawURLEncoding.EncodeToString(digest),
+		Expiry: expiry.Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal confirmation: %w", err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(confirmationMAC(key, payload)), nil
+}
+
+// verifyConfirmation verifies the confirmation token was created for the
+// actions digest and has not expired.
+func verifyConfirmation(key []byte, token string, digest []byte, now time.Time) error {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("malformed confirmation token")
+	}
+
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("malformed confirmation token signature: %w", err)
+	}
+	if !hmac.Equal(gotMAC, confirmationMAC(key, payload)) {
+		return fmt.Errorf("invalid confirmation token signature")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("malformed confirmation token payload: %w", err)
+	}
+	var claims confirmationClaims
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return fmt.Errorf("malformed confirmation token payload: %w", err)
+	}
+
+	if !now.Before(time.Unix(claims.Expiry, 0)) {
+		return fmt.Errorf("confirmation token expired")
+	}
+	if claims.Digest != base64.RawURLEncoding.EncodeToString(digest) {
+		return fmt.Errorf("confirmation token was issued for different actions")
+	}
+	return nil
+}
+
+func confirmationMAC(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"testing"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestVerifyConfirmation(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("confirmation-key")
+	now := time.Unix(1700000000, 0)
+
+	digest, err := actionsDigest([]*jvspb.Action{
+		{Version: "projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1", Action: jvspb.Action_FORCE_DESTROY},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDigest, err := actionsDigest([]*jvspb.Action{
+		{Version: "projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/2", Action: jvspb.Action_FORCE_DESTROY},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(tb testing.TB, key []byte, expiry time.Time) string {
+		tb.Helper()
+		token, err := signConfirmation(key, digest, expiry)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		return token
+	}
+
+	cases := []struct {
+		name   string
+		token  string
+		digest []byte
+		expErr string
+	}{
+		{
+			name:   "valid",
+			token:  sign(t, key, now.Add(time.Minute)),
+			digest: digest,
+		},
+		{
+			name:   "expired",
+			token:  sign(t, key, now),
+			digest: digest,
+			expErr: "confirmation token expired",
+		},
+		{
+			name:   "different_actions",
+			token:  sign(t, key, now.Add(time.Minute)),
+			digest: otherDigest,
+			expErr: "confirmation token was issued for different actions",
+		},
+		{
+			name:   "wrong_key",
+			token:  sign(t, []byte("other-key"), now.Add(time.Minute)),
+			digest: digest,
+			expErr: "invalid confirmation token signature",
+		},
+		{
+			name:   "malformed",
+			token:  "not-a-token",
+			digest: digest,
+			expErr: "malformed confirmation token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := verifyConfirmation(key, tc.token, tc.digest, now)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
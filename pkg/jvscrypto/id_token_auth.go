@@ -0,0 +1,98 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/jvs/pkg/idtoken"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+// IDTokenAuthenticator verifies the OIDC ID tokens of the callers of an
+// endpoint, such as the Cloud Scheduler jobs and Pub/Sub push subscriptions
+// calling the rotation trigger, or the verifiers reporting key usage, so the
+// endpoint doesn't rely solely on the IAM of the platform it runs on. Tokens
+// must be verified by the verifier, and their email must be one of the
+// principals, typically the service accounts of the callers.
+type IDTokenAuthenticator struct {
+	verifier   *idtoken.Verifier
+	principals idtoken.Principals
+	name       string
+	h          *renderer.Renderer
+}
+
+// NewIDTokenAuthenticator returns an authenticator of the ID tokens verified by
+// the verifier, for the principals allowed to call the endpoint. The name of
+// the endpoint, e.g. "rotation trigger", is used in logs and errors. See
+// [IDTokenAuthenticator].
+func NewIDTokenAuthenticator(verifier *idtoken.Verifier, principals []string, name string, h *renderer.Renderer) *IDTokenAuthenticator {
+	return &IDTokenAuthenticator{
+		verifier:   verifier,
+		principals: idtoken.NewPrincipals(principals),
+		name:       name,
+		h:          h,
+	}
+}
+
+// Handler returns a handler that serves the requests with an ID token of one
+// of the principals with next. Other requests get a 401, or a 403 if the token
+// is valid but not of one of the principals.
+func (a *IDTokenAuthenticator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx)
+
+		token, err := a.verify(r)
+		if err != nil {
+			logger.WarnContext(ctx, "unauthenticated "+a.name, "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			a.h.RenderJSON(w, http.StatusUnauthorized, fmt.Errorf("unauthenticated"))
+			return
+		}
+
+		email, err := idtoken.VerifiedEmail(token)
+		if err != nil {
+			logger.WarnContext(ctx, "denied "+a.name, "principal", email, "subject", token.Subject(), "error", err)
+			a.h.RenderJSON(w, http.StatusForbidden, err)
+			return
+		}
+		if !a.principals.Contains(email) {
+			logger.WarnContext(ctx, "denied "+a.name, "principal", email, "subject", token.Subject())
+			a.h.RenderJSON(w, http.StatusForbidden, fmt.Errorf("%q is not allowed", email))
+			return
+		}
+
+		logger.InfoContext(ctx, "authenticated "+a.name, "principal", email)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verify verifies the bearer ID token of the request.
+func (a *IDTokenAuthenticator) verify(r *http.Request) (jwt.Token, error) {
+	raw, err := idtoken.BearerToken(r.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Want passthrough
+	}
+	token, err := a.verifier.Verify(r.Context(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/jvs/pkg/idtoken"
+	"github.com/abcxyz/jvs/pkg/testutil"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+const (
+	testTriggerIssuer    = "https://accounts.google.com"
+	testTriggerAudience  = "https://jvs-cert-rotator.example.com"
+	testTriggerPrincipal = "scheduler@example-project.iam.gserviceaccount.com"
+)
+
+func TestIDTokenAuthenticator_Handler(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+	now := time.Now().UTC()
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := testutil.NewIDTokenIssuer(t)
+	other := testutil.NewIDTokenIssuer(t)
+
+	verifier := idtoken.NewVerifier(issuer.Keys, testTriggerIssuer, testTriggerAudience)
+	a := NewIDTokenAuthenticator(verifier, []string{"Scheduler@example-project.iam.gserviceaccount.com "}, "rotation trigger", h)
+
+	email := map[string]any{"email": testTriggerPrincipal}
+
+	cases := []struct {
+		name     string
+		auth     string
+		wantCode int
+	}{
+		{
+			name:     "valid",
+			auth:     "Bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, email),
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "valid_verified_email",
+			auth: "bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, map[string]any{
+				"email":          testTriggerPrincipal,
+				"email_verified": true,
+			}),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "missing_authorization",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "not_bearer",
+			auth:     "Basic dXNlcjpwYXNz",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "invalid_token",
+			auth:     "Bearer not-a-jwt",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong_key",
+			auth:     "Bearer " + other.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, email),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong_audience",
+			auth:     "Bearer " + issuer.IDToken(t, testTriggerIssuer, "https://other.example.com", "1234567890", now, email),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong_issuer",
+			auth:     "Bearer " + issuer.IDToken(t, "https://other.example.com", testTriggerAudience, "1234567890", now, email),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "expired",
+			auth:     "Bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now.Add(-time.Hour), email),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "missing_expiration",
+			auth: "Bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, map[string]any{
+				"email":           testTriggerPrincipal,
+				jwt.ExpirationKey: nil,
+			}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "not_principal",
+			auth:     "Bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, map[string]any{"email": "me@example.com"}),
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "missing_email",
+			auth:     "Bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, nil),
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "unverified_email",
+			auth: "Bearer " + issuer.IDToken(t, testTriggerIssuer, testTriggerAudience, "1234567890", now, map[string]any{
+				"email":          testTriggerPrincipal,
+				"email_verified": false,
+			}),
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var called bool
+			handler := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+			if tc.auth != "" {
+				r.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Errorf("expected code %d to be %d: %s", got, want, w.Body.String())
+			}
+			if got, want := called, tc.wantCode == http.StatusOK; got != want {
+				t.Errorf("expected next called %t to be %t", got, want)
+			}
+			if got, want := w.Header().Get("WWW-Authenticate") != "", tc.wantCode == http.StatusUnauthorized; got != want {
+				t.Errorf("expected www-authenticate %t to be %t", got, want)
+			}
+		})
+	}
+}
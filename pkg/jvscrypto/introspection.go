@@ -0,0 +1,290 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+// maxIntrospectionRequestSize is the maximum size of an introspection request
+// body, which is far larger than any JVS token.
+const maxIntrospectionRequestSize = 64 * 1024
+
+// RevocationChecker returns when principals were revoked, such as the list of
+// deprovisioned principals served at the revocation list endpoint.
+type RevocationChecker interface {
+	// RevokedAt returns when the principal was revoked, and whether it is.
+	RevokedAt(principal string) (time.Time, bool)
+}
+
+// IntrospectionServer introspects JVS tokens per RFC 7662, for API gateways
+// that gate requests on OAuth token introspection. Clients authenticate with
+// HTTP basic authentication or the client_id and client_secret form
+// parameters.
+//
+// A token is active if it is a JVS token signed by one of the served keys,
+// with the checks of the client library: it has an accepted "typ" header and
+// issuer, an expiry that hasn't passed, and justifications. With revocations,
+// neither its subject nor its requestor may have been revoked at or after it
+// was issued. Active tokens are returned with their claims.
+type IntrospectionServer struct {
+	keys        *KeyServer
+	revocations RevocationChecker
+	namespace   string
+	issuers     []string
+	tokenTypes  []string
+	h           *renderer.Renderer
+
+	// secrets are the SHA-256 digests of the client secrets, keyed by client
+	// ID, so they are compared in constant time regardless of their length.
+	secrets map[string][sha256.Size]byte
+}
+
+// NewIntrospectionServer creates a new server that lets the clients, keyed by
+// client ID, introspect tokens signed by the keys. See [IntrospectionServer]
+// for more information.
+func NewIntrospectionServer(keys *KeyServer, clientSecrets map[string]string, h *renderer.Renderer) *IntrospectionServer {
+	secrets := make(map[string][sha256.Size]byte, len(clientSecrets))
+	for id, secret := range clientSecrets {
+		secrets[id] = sha256.Sum256([]byte(secret))
+	}
+	return &IntrospectionServer{
+		keys:    keys,
+		h:       h,
+		secrets: secrets,
+	}
+}
+
+// WithRevocations reports tokens of revoked principals as inactive.
+func (s *IntrospectionServer) WithRevocations(r RevocationChecker) *IntrospectionServer {
+	s.revocations = r
+	return s
+}
+
+// WithClaimsNamespace reads the JVS claims of tokens under the namespace, to
+// check their justifications and requestor. The claims are returned as they
+// are.
+func (s *IntrospectionServer) WithClaimsNamespace(namespace string) *IntrospectionServer {
+	s.namespace = namespace
+	return s
+}
+
+// WithIssuers reports tokens of other issuers (iss) as inactive. Without
+// issuers, tokens of any issuer are active.
+func (s *IntrospectionServer) WithIssuers(issuers ...string) *IntrospectionServer {
+	s.issuers = issuers
+	return s
+}
+
+// WithTokenTypes reports tokens with other "typ" headers as inactive. Without
+// types, tokens must be untyped or of [jvspb.DefaultTokenType].
+func (s *IntrospectionServer) WithTokenTypes(types ...string) *IntrospectionServer {
+	s.tokenTypes = types
+	return s
+}
+
+// ClientIDs returns the IDs of the clients allowed to introspect tokens,
+// sorted.
+func (s *IntrospectionServer) ClientIDs() []string {
+	ids := make([]string, 0, len(s.secrets))
+	for id := range s.secrets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ServeHTTP introspects the token in the request.
+func (s *IntrospectionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.h.RenderJSON(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	// Responses are about a token, they must not be cached by intermediaries.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxIntrospectionRequestSize)
+	if err := r.ParseForm(); err != nil {
+		s.renderOAuthError(w, http.StatusBadRequest, "invalid_request", "failed to parse form")
+		return
+	}
+
+	clientID, ok := s.authenticate(r)
+	if !ok {
+		logger.WarnContext(ctx, "rejected introspection client", "client_id", clientID)
+		w.Header().Set("WWW-Authenticate", `Basic realm="jvs"`)
+		s.renderOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	raw := r.PostForm.Get("token")
+	if raw == "" {
+		s.renderOAuthError(w, http.StatusBadRequest, "invalid_request", "missing token")
+		return
+	}
+
+	resp, err := s.introspect(r, raw)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to introspect token", "error", err)
+		s.renderOAuthError(w, http.StatusInternalServerError, "server_error", "failed to introspect token")
+		return
+	}
+	logger.InfoContext(ctx, "introspected token",
+		"client_id", clientID,
+		"active", resp["active"],
+		"jti", resp["jti"])
+
+	s.h.RenderJSON(w, http.StatusOK, resp)
+}
+
+// authenticate returns the ID of the client, and whether the client presented
+// its secret.
+func (s *IntrospectionServer) authenticate(r *http.Request) (string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	want, ok := s.secrets[id]
+	if !ok || id == "" {
+		return id, false
+	}
+	got := sha256.Sum256([]byte(secret))
+	return id, subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+// introspect returns the introspection response for the token. Tokens that
+// fail to verify are inactive, only failing to load the keys is an error.
+func (s *IntrospectionServer) introspect(r *http.Request, raw string) (map[string]any, error) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	keys, err := s.keys.KeySet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := map[string]any{"active": false}
+	// Receipts and other objects signed by the keys aren't tokens.
+	if err := jvspb.CheckTokenType(raw, s.tokenTypes); err != nil {
+		logger.DebugContext(ctx, "introspected invalid token", "error", err)
+		return inactive, nil
+	}
+	token, err := jwt.Parse([]byte(raw),
+		jwt.WithContext(ctx),
+		jwt.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithAcceptableSkew(5*time.Second),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+	)
+	if err != nil {
+		logger.DebugContext(ctx, "introspected invalid token", "error", err)
+		return inactive, nil
+	}
+	if err := s.checkClaims(token); err != nil {
+		logger.DebugContext(ctx, "introspected invalid token", "jti", token.JwtID(), "error", err)
+		return inactive, nil
+	}
+	if s.revoked(token) {
+		logger.DebugContext(ctx, "introspected revoked token", "jti", token.JwtID())
+		return inactive, nil
+	}
+
+	b, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	resp := make(map[string]any)
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal claims: %w", err)
+	}
+	resp["active"] = true
+	resp["token_type"] = "Bearer"
+	return resp, nil
+}
+
+// checkClaims returns an error if the token's issuer isn't accepted, or it has
+// no justifications under the claims namespace.
+func (s *IntrospectionServer) checkClaims(token jwt.Token) error {
+	if len(s.issuers) > 0 && !slices.Contains(s.issuers, token.Issuer()) {
+		return fmt.Errorf("issuer %q is not one of the accepted issuers %q", token.Issuer(), s.issuers)
+	}
+	_, ok := token.Get(s.namespace + jvspb.JustificationsKey)
+	if !ok {
+		_, ok = token.Get(s.namespace + jvspb.EncryptedJustificationsKey)
+	}
+	if !ok {
+		return fmt.Errorf("jwt has no %s or %s claim",
+			s.namespace+jvspb.JustificationsKey, s.namespace+jvspb.EncryptedJustificationsKey)
+	}
+	return nil
+}
+
+// revoked returns whether the subject or requestor of the token was revoked at
+// or after the token was issued.
+func (s *IntrospectionServer) revoked(token jwt.Token) bool {
+	if s.revocations == nil {
+		return false
+	}
+
+	principals := []string{token.Subject()}
+	if s.namespace != "" {
+		if raw, ok := token.Get(s.namespace + jvspb.RequestorKey); ok {
+			if requestor, ok := raw.(string); ok {
+				principals = append(principals, requestor)
+			}
+		}
+	}
+	if requestor, err := jvspb.GetRequestor(token); err == nil && requestor != "" {
+		principals = append(principals, requestor)
+	}
+	for _, principal := range principals {
+		if principal == "" {
+			continue
+		}
+		if revokedAt, ok := s.revocations.RevokedAt(principal); ok && !token.IssuedAt().After(revokedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderOAuthError renders an RFC 6749 error response, which gateways expect
+// instead of the renderer's errors.
+func (s *IntrospectionServer) renderOAuthError(w http.ResponseWriter, code int, errCode, desc string) {
+	s.h.RenderJSON(w, code, map[string]string{
+		"error":             errCode,
+		"error_description": desc,
+	})
+}
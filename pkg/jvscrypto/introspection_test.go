@@ -0,0 +1,407 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+const (
+	testIntrospectionClientID     = "gateway"
+	testIntrospectionClientSecret = "0123456789abcdef0123456789abcdef"
+)
+
+type fakeRevocations map[string]time.Time
+
+func (f fakeRevocations) RevokedAt(principal string) (time.Time, bool) {
+	t, ok := f[principal]
+	return t, ok
+}
+
+func TestIntrospectionServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	dir := t.TempDir()
+	signingKey, signingKeyPath := writeTestKey(t, dir, "signing-key.pem", true)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:       config.SignerLocal,
+		KeyPaths:     []string{signingKeyPath},
+		CacheTimeout: 5 * time.Minute,
+	}, h).WithLocalKeys([]string{signingKeyPath})
+
+	issuedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	revocations := fakeRevocations{
+		"revoked@example.com":  issuedAt,
+		"returned@example.com": issuedAt.Add(-time.Hour),
+	}
+	server := NewIntrospectionServer(keyServer, map[string]string{
+		testIntrospectionClientID: testIntrospectionClientSecret,
+	}, h).WithRevocations(revocations).
+		WithIssuers("jvs.abcxyz.dev")
+
+	validToken := testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour)
+
+	cases := []struct {
+		name       string
+		method     string
+		form       url.Values
+		basicAuth  bool
+		wantStatus int
+		wantBody   map[string]any
+	}{
+		{
+			name:       "active",
+			form:       url.Values{"token": {validToken}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody: map[string]any{
+				"active":     true,
+				"token_type": "Bearer",
+				"aud":        []any{"dev.abcxyz.jvs"},
+				"exp":        float64(issuedAt.Add(time.Hour).Unix()),
+				"iat":        float64(issuedAt.Unix()),
+				"iss":        "jvs.abcxyz.dev",
+				"jti":        "test-jti",
+				"sub":        "me@example.com",
+				"req":        "me@example.com",
+				"justs": []any{
+					map[string]any{"category": "explanation", "value": "debugging"},
+				},
+			},
+		},
+		{
+			name: "form_credentials",
+			form: url.Values{
+				"token":         {validToken},
+				"client_id":     {testIntrospectionClientID},
+				"client_secret": {testIntrospectionClientSecret},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "expired",
+			form:       url.Values{"token": {testIntrospectionToken(t, signingKey, "me@example.com", issuedAt.Add(-2*time.Hour), time.Hour)}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name:       "unknown_key",
+			form:       url.Values{"token": {testIntrospectionToken(t, otherKey, "me@example.com", issuedAt, time.Hour)}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name: "wrong_issuer",
+			form: url.Values{"token": {testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour,
+				func(token jwt.Token, _ jws.Headers) error {
+					return token.Set(jwt.IssuerKey, "other.example.com")
+				})}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name: "no_expiration",
+			form: url.Values{"token": {testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour,
+				func(token jwt.Token, _ jws.Headers) error {
+					return token.Remove(jwt.ExpirationKey)
+				})}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name: "no_justifications",
+			form: url.Values{"token": {testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour,
+				func(token jwt.Token, _ jws.Headers) error {
+					return token.Remove(jvspb.JustificationsKey)
+				})}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name: "not_a_token_type",
+			form: url.Values{"token": {testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour,
+				func(_ jwt.Token, headers jws.Headers) error {
+					return headers.Set(jws.TypeKey, jvspb.ReceiptType)
+				})}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name:       "malformed",
+			form:       url.Values{"token": {"not-a-jwt"}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name:       "revoked",
+			form:       url.Values{"token": {testIntrospectionToken(t, signingKey, "revoked@example.com", issuedAt, time.Hour)}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+			wantBody:   map[string]any{"active": false},
+		},
+		{
+			name:       "issued_after_revocation",
+			form:       url.Values{"token": {testIntrospectionToken(t, signingKey, "returned@example.com", issuedAt, time.Hour)}},
+			basicAuth:  true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing_credentials",
+			form:       url.Values{"token": {validToken}},
+			wantStatus: http.StatusUnauthorized,
+			wantBody: map[string]any{
+				"error":             "invalid_client",
+				"error_description": "client authentication failed",
+			},
+		},
+		{
+			name: "wrong_secret",
+			form: url.Values{
+				"token":         {validToken},
+				"client_id":     {testIntrospectionClientID},
+				"client_secret": {"wrong"},
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing_token",
+			form:       url.Values{},
+			basicAuth:  true,
+			wantStatus: http.StatusBadRequest,
+			wantBody: map[string]any{
+				"error":             "invalid_request",
+				"error_description": "missing token",
+			},
+		},
+		{
+			name:       "wrong_method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, "/introspect", strings.NewReader(tc.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				r.SetBasicAuth(testIntrospectionClientID, testIntrospectionClientSecret)
+			}
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.wantStatus; got != want {
+				t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK {
+				if got, want := w.Header().Get("Cache-Control"), "no-store"; got != want {
+					t.Errorf("expected Cache-Control %q to be %q", got, want)
+				}
+			}
+			if tc.wantBody == nil {
+				return
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantBody, got); diff != "" {
+				t.Errorf("body (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIntrospectionServer_namespaceAndTokenType(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	const namespace = "https://jvs.example.com/"
+
+	signingKey, signingKeyPath := writeTestKey(t, t.TempDir(), "signing-key.pem", true)
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:       config.SignerLocal,
+		KeyPaths:     []string{signingKeyPath},
+		CacheTimeout: 5 * time.Minute,
+	}, h).WithLocalKeys([]string{signingKeyPath})
+
+	server := NewIntrospectionServer(keyServer, map[string]string{
+		testIntrospectionClientID: testIntrospectionClientSecret,
+	}, h).WithClaimsNamespace(namespace).
+		WithTokenTypes("jvs+jwt")
+
+	issuedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	namespaced := func(token jwt.Token, headers jws.Headers) error {
+		if err := headers.Set(jws.TypeKey, "jvs+jwt"); err != nil {
+			return err
+		}
+		return jvspb.NamespaceClaims(token, namespace)
+	}
+	typed := func(_ jwt.Token, headers jws.Headers) error {
+		return headers.Set(jws.TypeKey, "jvs+jwt")
+	}
+
+	cases := []struct {
+		name       string
+		token      string
+		wantActive bool
+	}{
+		{
+			name:       "namespaced",
+			token:      testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour, namespaced),
+			wantActive: true,
+		},
+		{
+			name:  "not_namespaced",
+			token: testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour, typed),
+		},
+		{
+			name:  "untyped",
+			token: testIntrospectionToken(t, signingKey, "me@example.com", issuedAt, time.Hour),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/introspect",
+				strings.NewReader(url.Values{"token": {tc.token}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.SetBasicAuth(testIntrospectionClientID, testIntrospectionClientSecret)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+
+			var got struct {
+				Active bool `json:"active"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := got.Active, tc.wantActive; got != want {
+				t.Errorf("expected active %t to be %t: %s", got, want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIntrospectionServer_ClientIDs(t *testing.T) {
+	t.Parallel()
+
+	server := NewIntrospectionServer(nil, map[string]string{
+		"mesh":    testIntrospectionClientSecret,
+		"gateway": testIntrospectionClientSecret,
+	}, nil)
+	if diff := cmp.Diff([]string{"gateway", "mesh"}, server.ClientIDs()); diff != "" {
+		t.Errorf("client IDs (-want, +got):\n%s", diff)
+	}
+}
+
+// testIntrospectionToken returns a token for the principal signed by the key,
+// with the key's local key ID. The edits change its claims and headers before
+// it is signed.
+func testIntrospectionToken(tb testing.TB, privateKey *ecdsa.PrivateKey, principal string, issuedAt time.Time, ttl time.Duration, edits ...func(jwt.Token, jws.Headers) error) string {
+	tb.Helper()
+
+	token, err := jwt.NewBuilder().
+		Audience([]string{"dev.abcxyz.jvs"}).
+		Expiration(issuedAt.Add(ttl)).
+		IssuedAt(issuedAt).
+		Issuer("jvs.abcxyz.dev").
+		JwtID("test-jti").
+		Subject(principal).
+		Build()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := jvspb.SetRequestor(token, principal); err != nil {
+		tb.Fatal(err)
+	}
+	if err := jvspb.SetJustifications(token, []*jvspb.Justification{
+		{Category: "explanation", Value: "debugging"},
+	}); err != nil {
+		tb.Fatal(err)
+	}
+
+	kid, err := LocalKeyID(&privateKey.PublicKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, kid); err != nil {
+		tb.Fatal(err)
+	}
+	for _, edit := range edits {
+		if err := edit(token, headers); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	b, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, privateKey, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(b)
+}
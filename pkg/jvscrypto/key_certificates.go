@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha1" //nolint:gosec // x5t is defined as a SHA-1 thumbprint.
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/cert"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// certificateChains reads the PEM encoded certificate chains in the ".pem"
+// files of the directory, and returns them keyed by the key ID of the public
+// key of their first certificate. Chains for other keys, and chains whose
+// certificate isn't valid at the time, are skipped. If several chains are for
+// the same key, the one whose certificate expires last is used.
+//
+// Files that can't be parsed are logged and skipped, so one bad certificate
+// doesn't stop the public keys from being served.
+func certificateChains(ctx context.Context, dir string, publicKeys map[string]crypto.PublicKey, now time.Time) (map[string][]*x509.Certificate, error) {
+	logger := logging.FromContext(ctx)
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to read certificate directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certificates: %w", err)
+	}
+
+	chains := make(map[string][]*x509.Certificate)
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate %s: %w", path, err)
+		}
+		chain, err := jvspb.ParseCertificateChain(b)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to parse certificate chain, skipping",
+				"path", path,
+				"error", err)
+			continue
+		}
+
+		leaf := chain[0]
+		if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+			logger.WarnContext(ctx, "certificate is not valid, skipping",
+				"path", path,
+				"not_before", leaf.NotBefore,
+				"not_after", leaf.NotAfter)
+			continue
+		}
+
+		kid := keyIDFor(leaf.PublicKey, publicKeys)
+		if kid == "" {
+			logger.DebugContext(ctx, "certificate is not for a served key, skipping", "path", path)
+			continue
+		}
+		if current, ok := chains[kid]; ok && !leaf.NotAfter.After(current[0].NotAfter) {
+			continue
+		}
+		chains[kid] = chain
+	}
+	return chains, nil
+}
+
+// keyIDFor returns the key ID of the public key, or "" if it is not one of the
+// public keys.
+func keyIDFor(pub crypto.PublicKey, publicKeys map[string]crypto.PublicKey) string {
+	key, ok := pub.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return ""
+	}
+	for kid, publicKey := range publicKeys {
+		if key.Equal(publicKey) {
+			return kid
+		}
+	}
+	return ""
+}
+
+// addCertificateChains sets the x5c, x5t and x5t#S256 fields of the keys that
+// have a certificate chain, keyed by key ID.
+func (s *JWKSet) addCertificateChains(chains map[string][]*x509.Certificate) error {
+	for _, key := range s.Keys {
+		chain, ok := chains[key.KeyID()]
+		if !ok {
+			continue
+		}
+
+		var x5c cert.Chain
+		for _, c := range chain {
+			if err := x5c.AddString(base64.StdEncoding.EncodeToString(c.Raw)); err != nil {
+				return fmt.Errorf("failed to add certificate to chain: %w", err)
+			}
+		}
+		if err := key.Set(jwk.X509CertChainKey, &x5c); err != nil {
+			return fmt.Errorf("failed to set x5c on jwk %s: %w", key.KeyID(), err)
+		}
+
+		sha1Sum := sha1.Sum(chain[0].Raw) //nolint:gosec // x5t is defined as a SHA-1 thumbprint.
+		if err := key.Set(jwk.X509CertThumbprintKey, base64.RawURLEncoding.EncodeToString(sha1Sum[:])); err != nil {
+			return fmt.Errorf("failed to set x5t on jwk %s: %w", key.KeyID(), err)
+		}
+		sha256Sum := sha256.Sum256(chain[0].Raw)
+		if err := key.Set(jwk.X509CertThumbprintS256Key, base64.RawURLEncoding.EncodeToString(sha256Sum[:])); err != nil {
+			return fmt.Errorf("failed to set x5t#S256 on jwk %s: %w", key.KeyID(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+func TestKeyServer_certificateChains(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	keyDir, certDir := t.TempDir(), t.TempDir()
+	signingKey, signingKeyPath := writeTestKey(t, keyDir, "signing-key.pem", false)
+	oldKey, oldKeyPath := writeTestKey(t, keyDir, "old-key.pem", false)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	ca := testCertificate(t, nil, caKey, caKey.Public(), now.Add(24*time.Hour))
+	signingCert := testCertificate(t, ca, caKey, signingKey.Public(), now.Add(time.Hour))
+	renewedSigningCert := testCertificate(t, ca, caKey, signingKey.Public(), now.Add(2*time.Hour))
+	expiredOldCert := testCertificate(t, ca, caKey, oldKey.Public(), now.Add(-time.Hour))
+	otherCert := testCertificate(t, ca, caKey, otherKey.Public(), now.Add(time.Hour))
+
+	writeTestChain(t, certDir, "signing.pem", signingCert, ca)
+	writeTestChain(t, certDir, "signing-renewed.pem", renewedSigningCert, ca)
+	writeTestChain(t, certDir, "old-expired.pem", expiredOldCert, ca)
+	writeTestChain(t, certDir, "other.pem", otherCert, ca)
+	writeTestChain(t, certDir, "signing.crt", signingCert)
+	if err := os.WriteFile(filepath.Join(certDir, "invalid.pem"), []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:         config.SignerLocal,
+		KeyPaths:       []string{signingKeyPath, oldKeyPath},
+		CacheTimeout:   5 * time.Minute,
+		CertificateDir: certDir,
+	}, h).WithLocalKeys([]string{signingKeyPath, oldKeyPath})
+
+	w := httptest.NewRecorder()
+	keyServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	set, err := jwk.Parse(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The signing key is served with the chain of its certificate that expires
+	// last.
+	kid, err := LocalKeyID(&signingKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := set.LookupKeyID(kid)
+	if !ok {
+		t.Fatalf("expected key %q in the jwks", kid)
+	}
+	x5c := key.X509CertChain()
+	if got, want := x5c.Len(), 2; got != want {
+		t.Fatalf("expected %d certificates in x5c to be %d", got, want)
+	}
+	for i, want := range []*x509.Certificate{renewedSigningCert, ca} {
+		got, _ := x5c.Get(i)
+		if string(got) != base64.StdEncoding.EncodeToString(want.Raw) {
+			t.Errorf("expected certificate %d in x5c to be %q", i, want.Subject)
+		}
+	}
+	sum := sha256.Sum256(renewedSigningCert.Raw)
+	if got, want := key.X509CertThumbprintS256(), base64.RawURLEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("expected x5t#S256 %q to be %q", got, want)
+	}
+	if key.X509CertThumbprint() == "" {
+		t.Errorf("expected x5t to be set")
+	}
+
+	// The old key's certificate expired, so it is served without a chain.
+	kid, err = LocalKeyID(&oldKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok = set.LookupKeyID(kid)
+	if !ok {
+		t.Fatalf("expected key %q in the jwks", kid)
+	}
+	if got := key.X509CertChain(); got != nil && got.Len() > 0 {
+		t.Errorf("expected no x5c for the expired certificate, got %d certificates", got.Len())
+	}
+}
+
+func TestKeyServer_certificateChains_missingDir(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	_, keyPath := writeTestKey(t, t.TempDir(), "signing-key.pem", false)
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:         config.SignerLocal,
+		KeyPaths:       []string{keyPath},
+		CacheTimeout:   5 * time.Minute,
+		CertificateDir: filepath.Join(t.TempDir(), "missing"),
+	}, h).WithLocalKeys([]string{keyPath})
+
+	w := httptest.NewRecorder()
+	keyServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil))
+	if got, want := w.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+}
+
+// testCertificate creates a certificate for the public key that expires at
+// notAfter, signed by the parent. If parent is nil, it creates a self-signed
+// CA certificate.
+func testCertificate(tb testing.TB, parent *x509.Certificate, parentKey crypto.Signer, pub crypto.PublicKey, notAfter time.Time) *x509.Certificate {
+	tb.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "jvs-test"},
+		NotBefore:    notAfter.Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	if parent == nil {
+		template.Subject.CommonName = "jvs-test-ca"
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, parentKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return cert
+}
+
+// writeTestChain writes the PEM encoded certificates to the directory.
+func writeTestChain(tb testing.TB, dir, name string, chain ...*x509.Certificate) {
+	tb.Helper()
+
+	var b []byte
+	for _, c := range chain {
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), b, 0o600); err != nil {
+		tb.Fatal(err)
+	}
+}
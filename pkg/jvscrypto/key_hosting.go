@@ -16,12 +16,22 @@ package jvscrypto
 
 import (
 	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	kms "cloud.google.com/go/kms/apiv1"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 
+	jvspb "github.com/abcxyz/jvs/apis/v0"
 	"github.com/abcxyz/jvs/pkg/config"
 	"github.com/abcxyz/pkg/cache"
 	"github.com/abcxyz/pkg/logging"
@@ -32,13 +42,24 @@ import (
 type KeyServer struct {
 	kmsClient *kms.KeyManagementClient
 	config    *config.PublicKeyConfig
-	cache     *cache.Cache[string]
+	cache     *cache.Cache[*jwksDocument]
 	h         *renderer.Renderer
+
+	// localKeyPaths are the files of the local public keys to serve instead of
+	// the KMS keys. They are read again whenever the cache expires.
+	localKeyPaths []string
+
+	// keys is the key manager to serve the public keys of instead of the KMS
+	// keys. If nil, keys are read from KMS.
+	keys KeyManager
+
+	// keySetSigner signs the JWKS with the root key, if set.
+	keySetSigner *keySetSigner
 }
 
 // NewKeyServer creates a new server. See [KeyServer] for more information.
 func NewKeyServer(ctx context.Context, kmsClient *kms.KeyManagementClient, cfg *config.PublicKeyConfig, h *renderer.Renderer) *KeyServer {
-	cache := cache.New[string](cfg.CacheTimeout)
+	cache := cache.New[*jwksDocument](cfg.CacheTimeout)
 
 	return &KeyServer{
 		kmsClient: kmsClient,
@@ -48,15 +69,96 @@ func NewKeyServer(ctx context.Context, kmsClient *kms.KeyManagementClient, cfg *
 	}
 }
 
+// WithLocalKeys serves the PEM encoded public keys at the paths instead of the
+// KMS keys. See [LoadPublicKeys] for the supported keys.
+func (k *KeyServer) WithLocalKeys(paths []string) *KeyServer {
+	k.localKeyPaths = paths
+	return k
+}
+
+// WithKeyManager serves the public keys of the enabled versions of the keys in
+// the key manager instead of the KMS keys.
+func (k *KeyServer) WithKeyManager(m KeyManager) *KeyServer {
+	k.keys = m
+	return k
+}
+
+// WithKeySetSigner signs the JWKS with the root key, with the key ID, for
+// requests that accept [jvspb.SignedJWKSContentType]. The root key must be an
+// ECDSA P-256 key, and signed key sets expire after the TTL, which must be
+// longer than the cache timeout.
+func (k *KeyServer) WithKeySetSigner(signer crypto.Signer, kid string, ttl time.Duration) *KeyServer {
+	k.keySetSigner = &keySetSigner{
+		signer: signer,
+		kid:    kid,
+		ttl:    ttl,
+	}
+	return k
+}
+
 const cacheKey = "jwks"
 
-// ServeHTTP returns the public keys in JWK format.
+// audienceQueryRegexp matches the audiences of the "aud" query parameter. They
+// are the names in the audience labels of the keys, see
+// [AudienceLabelPrefix].
+var audienceQueryRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,59}$`)
+
+// jwksDocument is the JWKS of all the public keys, and the audiences of the
+// keys, to serve partial JWKS from.
+type jwksDocument struct {
+	// json is the JSON encoded JWKS.
+	json string
+	set  *JWKSet
+
+	// audiences are the audiences in the labels of the keys, keyed by key ID.
+	// Keys without audience labels may sign for any audience.
+	audiences map[string][]string
+
+	// signedSets are the signed key sets of the JWKS served from the document,
+	// keyed by the JSON encoded JWKS.
+	signedMu   sync.Mutex
+	signedSets map[string]string
+}
+
+// forAudiences returns the JSON encoded JWKS of the keys that sign for any of
+// the audiences, and the keys without audience labels.
+func (d *jwksDocument) forAudiences(audiences []string) (string, error) {
+	set := &JWKSet{Keys: make([]jwk.Key, 0, len(d.set.Keys))}
+	for _, key := range d.set.Keys {
+		auds, ok := d.audiences[key.KeyID()]
+		if !ok || slices.ContainsFunc(audiences, func(aud string) bool {
+			return slices.Contains(auds, aud)
+		}) {
+			set.Keys = append(set.Keys, key)
+		}
+	}
+
+	b, err := json.Marshal(set)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jwks as json: %w", err)
+	}
+	return string(b), nil
+}
+
+// ServeHTTP returns the public keys in JWK format. If the request has "aud"
+// query parameters, only the keys that sign for any of those audiences, and
+// the keys without audience labels, are returned. With a key set signer, the
+// keys are returned as a signed key set if the request accepts one.
 func (k *KeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := logging.FromContext(ctx)
 
-	val, err := k.cache.WriteThruLookup(cacheKey, func() (string, error) {
-		return k.generateJWKString(r.Context())
+	audiences := r.URL.Query()["aud"]
+	for _, aud := range audiences {
+		if !audienceQueryRegexp.MatchString(aud) {
+			k.h.RenderJSON(w, http.StatusBadRequest,
+				fmt.Errorf("audience %q must be lowercase letters, digits, underscores or dashes", aud))
+			return
+		}
+	}
+
+	doc, err := k.cache.WriteThruLookup(cacheKey, func() (*jwksDocument, error) {
+		return k.generateJWKS(r.Context())
 	})
 	if err != nil {
 		logger.ErrorContext(ctx, "error generating jwk string", "error", err)
@@ -64,29 +166,250 @@ func (k *KeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("content-type", "application/json")
+	val := doc.json
+	if len(audiences) > 0 {
+		if val, err = doc.forAudiences(audiences); err != nil {
+			logger.ErrorContext(ctx, "error generating partial jwks", "error", err)
+			k.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to generate jwks"))
+			return
+		}
+	}
+
+	contentType := "application/json"
+	if k.keySetSigner != nil {
+		// Caches in front of the server must not serve one representation for
+		// the other.
+		w.Header().Set("vary", "Accept")
+		if acceptsSignedKeySet(r) {
+			if val, err = doc.signed(val, k.keySetSigner); err != nil {
+				logger.ErrorContext(ctx, "error signing jwks", "error", err)
+				k.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to sign jwks"))
+				return
+			}
+			contentType = jvspb.SignedJWKSContentType
+		}
+	}
+
+	// The JWKS only changes when the cache is refreshed, so clients can poll
+	// with conditional requests and get a 304 until then.
+	etag := jwksETag(val)
+	w.Header().Set("etag", etag)
+	if etagMatches(r.Header.Get("if-none-match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("content-type", contentType)
 	fmt.Fprint(w, val)
 }
 
-func (k *KeyServer) generateJWKString(ctx context.Context) (string, error) {
-	keyVersions, err := CryptoKeyVersionsFor(ctx, k.kmsClient, k.config.KeyNames)
+// jwksETag returns a strong ETag for the JWKS.
+func jwksETag(jwks string) string {
+	sum := sha256.Sum256([]byte(jwks))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header matches the ETag. Weak
+// comparison is used, as the header may list weak ETags.
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeKey returns a single public key, for tooling that can't consume a JWKS.
+// The path must end in the key ID, followed by ".pem" for a PEM encoded public
+// key or ".jwk" for a JWK, e.g.
+// "/keys/projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1.pem".
+// It expects the key ID as the "kid" path value.
+func (k *KeyServer) ServeKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	kid, format := r.PathValue("kid"), ""
+	for _, ext := range []string{".pem", ".jwk"} {
+		if v, ok := strings.CutSuffix(kid, ext); ok {
+			kid, format = v, ext
+			break
+		}
+	}
+	if format == "" {
+		k.h.RenderJSON(w, http.StatusNotFound, fmt.Errorf("unknown key format, must be .pem or .jwk"))
+		return
+	}
+
+	doc, err := k.cache.WriteThruLookup(cacheKey, func() (*jwksDocument, error) {
+		return k.generateJWKS(ctx)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to list crypto keys: %w", err)
+		logger.ErrorContext(ctx, "error generating jwk string", "error", err)
+		k.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to generate jwks"))
+		return
 	}
 
-	publicKeys, err := PublicKeysFor(ctx, k.kmsClient, keyVersions)
+	i := slices.IndexFunc(doc.set.Keys, func(key jwk.Key) bool {
+		return key.KeyID() == kid
+	})
+	if i < 0 {
+		k.h.RenderJSON(w, http.StatusNotFound, fmt.Errorf("key %q not found", kid))
+		return
+	}
+	key := doc.set.Keys[i]
+
+	var b []byte
+	var contentType string
+	switch format {
+	case ".pem":
+		b, err = jwk.EncodePEM(key)
+		contentType = "application/x-pem-file"
+	case ".jwk":
+		b, err = json.Marshal(key)
+		contentType = "application/jwk+json"
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get public keys: %w", err)
+		logger.ErrorContext(ctx, "error encoding key", "kid", kid, "format", format, "error", err)
+		k.h.RenderJSON(w, http.StatusInternalServerError, fmt.Errorf("failed to encode key"))
+		return
+	}
+
+	// Keys are only refreshed from KMS once the cache expires, so clients can
+	// cache them for as long.
+	w.Header().Set("cache-control", fmt.Sprintf("public, max-age=%d", int(k.config.CacheTimeout.Seconds())))
+	w.Header().Set("content-type", contentType)
+	fmt.Fprintf(w, "%s", b)
+}
+
+// KeySet returns the public keys the server serves, from its cache.
+func (k *KeyServer) KeySet(ctx context.Context) (jwk.Set, error) {
+	d, err := k.cache.WriteThruLookup(cacheKey, func() (*jwksDocument, error) {
+		return k.generateJWKS(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jwks: %w", err)
+	}
+
+	set := jwk.NewSet()
+	for _, key := range d.set.Keys {
+		if err := set.AddKey(key); err != nil {
+			return nil, fmt.Errorf("failed to add key %q: %w", key.KeyID(), err)
+		}
+	}
+	return set, nil
+}
+
+// Ready returns an error if the server can't serve the public keys. It checks
+// every key has an enabled primary version, and loads the JWKS into the
+// cache.
+func (k *KeyServer) Ready(ctx context.Context) error {
+	if k.localKeyPaths == nil {
+		keys := k.keys
+		if keys == nil {
+			keys = NewKMSKeyManager(k.kmsClient)
+		}
+		for _, key := range k.config.KeyNames {
+			if err := CheckPrimaryVersion(ctx, keys, key); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := k.cache.WriteThruLookup(cacheKey, func() (*jwksDocument, error) {
+		return k.generateJWKS(ctx)
+	}); err != nil {
+		return fmt.Errorf("failed to generate jwks: %w", err)
+	}
+	return nil
+}
+
+func (k *KeyServer) generateJWKS(ctx context.Context) (*jwksDocument, error) {
+	publicKeys, err := k.publicKeys(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	jwks, err := JWKSFromPublicKeys(publicKeys)
 	if err != nil {
-		return "", fmt.Errorf("failed to create jwks: %w", err)
+		return nil, fmt.Errorf("failed to create jwks: %w", err)
+	}
+
+	if dir := k.config.CertificateDir; dir != "" {
+		chains, err := certificateChains(ctx, dir, publicKeys, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate chains: %w", err)
+		}
+		if err := jwks.addCertificateChains(chains); err != nil {
+			return nil, fmt.Errorf("failed to add certificate chains: %w", err)
+		}
+	}
+
+	audiences, err := k.keyAudiences(ctx, publicKeys)
+	if err != nil {
+		return nil, err
 	}
 
 	b, err := json.Marshal(jwks)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal jwks as json: %w", err)
+		return nil, fmt.Errorf("failed to marshal jwks as json: %w", err)
 	}
-	return string(b), nil
+	return &jwksDocument{
+		json:      string(b),
+		set:       jwks,
+		audiences: audiences,
+	}, nil
+}
+
+// keyAudiences returns the audiences of the public keys, keyed by key ID, from
+// the labels of their KMS keys. Local keys and keys of other key managers have
+// no labels, so they may sign for any audience.
+func (k *KeyServer) keyAudiences(ctx context.Context, publicKeys map[string]crypto.PublicKey) (map[string][]string, error) {
+	if k.localKeyPaths != nil || k.keys != nil {
+		return nil, nil
+	}
+
+	keyAudiences, err := KeyAudiences(ctx, k.kmsClient, k.config.KeyNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key audiences: %w", err)
+	}
+
+	audiences := make(map[string][]string, len(publicKeys))
+	for kid := range publicKeys {
+		key, _, _ := strings.Cut(kid, "/cryptoKeyVersions/")
+		if auds, ok := keyAudiences[key]; ok {
+			audiences[kid] = auds
+		}
+	}
+	return audiences, nil
+}
+
+// publicKeys returns the public keys to serve, keyed by key ID.
+func (k *KeyServer) publicKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if k.localKeyPaths != nil {
+		publicKeys, err := LoadPublicKeys(k.localKeyPaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local public keys: %w", err)
+		}
+		return publicKeys, nil
+	}
+
+	if k.keys != nil {
+		publicKeys, err := EnabledPublicKeys(ctx, k.keys, k.config.KeyNames)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get public keys: %w", err)
+		}
+		return publicKeys, nil
+	}
+
+	keyVersions, err := CryptoKeyVersionsFor(ctx, k.kmsClient, k.config.KeyNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crypto keys: %w", err)
+	}
+
+	publicKeys, err := PublicKeysFor(ctx, k.kmsClient, keyVersions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys: %w", err)
+	}
+	return publicKeys, nil
 }
@@ -23,6 +23,8 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -127,7 +129,7 @@ func TestGenerateJWKString(t *testing.T) {
 
 			keyServer := NewKeyServer(ctx, kmsClient, cfg, h)
 
-			got, err := keyServer.generateJWKString(ctx)
+			doc, err := keyServer.generateJWKS(ctx)
 			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
 				t.Errorf("Unexpected err: %s", diff)
 			}
@@ -135,9 +137,412 @@ func TestGenerateJWKString(t *testing.T) {
 				return
 			}
 
-			if diff := cmp.Diff(tc.wantOutput, got); diff != "" {
+			if diff := cmp.Diff(tc.wantOutput, doc.json); diff != "" {
 				t.Errorf("Got diff (-want, +got): %s", diff)
 			}
 		})
 	}
 }
+
+func TestKeyServer_ServeKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemEncodedPub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub})
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	kid := key + "/cryptoKeyVersions/[VERSION]-0"
+
+	mockKMSServer := testutil.NewMockKeyManagementServer(key, key+"/cryptoKeyVersions/[VERSION]", PrimaryLabelPrefix+"[VERSION]")
+	mockKMSServer.PrivateKey = privateKey
+	mockKMSServer.PublicKey = string(pemEncodedPub)
+	mockKMSServer.NumVersions = 1
+
+	_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, mockKMSServer)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	kmsClient, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyServer := NewKeyServer(ctx, kmsClient, &config.PublicKeyConfig{
+		KeyNames:     []string{key},
+		CacheTimeout: 5 * time.Minute,
+	}, h)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys/{kid...}", keyServer.ServeKey)
+
+	cases := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "pem",
+			path:            "/keys/" + kid + ".pem",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-pem-file",
+			wantBody:        string(pemEncodedPub),
+		},
+		{
+			name:            "jwk",
+			path:            "/keys/" + kid + ".jwk",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/jwk+json",
+			// JWK coordinates are always 32 bytes, unlike big.Int.Bytes.
+			wantBody: fmt.Sprintf(`{"crv":"P-256","kid":"%s","kty":"EC","x":"%s","y":"%s"}`,
+				kid,
+				base64.RawURLEncoding.EncodeToString(privateKey.X.FillBytes(make([]byte, 32))),
+				base64.RawURLEncoding.EncodeToString(privateKey.Y.FillBytes(make([]byte, 32)))),
+		},
+		{
+			name:       "unknown_kid",
+			path:       "/keys/" + key + "/cryptoKeyVersions/other.pem",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown_format",
+			path:       "/keys/" + kid + ".der",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantStatus; got != want {
+				t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			if got, want := w.Header().Get("content-type"), tc.wantContentType; got != want {
+				t.Errorf("expected content-type %q to be %q", got, want)
+			}
+			if got, want := w.Header().Get("cache-control"), "public, max-age=300"; got != want {
+				t.Errorf("expected cache-control %q to be %q", got, want)
+			}
+			if diff := cmp.Diff(tc.wantBody, w.Body.String()); diff != "" {
+				t.Errorf("body (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKeyServer_ServeHTTP_ETag(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemEncodedPub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub})
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+
+	mockKMSServer := testutil.NewMockKeyManagementServer(key, key+"/cryptoKeyVersions/[VERSION]", PrimaryLabelPrefix+"[VERSION]")
+	mockKMSServer.PrivateKey = privateKey
+	mockKMSServer.PublicKey = string(pemEncodedPub)
+	mockKMSServer.NumVersions = 1
+
+	_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, mockKMSServer)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	kmsClient, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyServer := NewKeyServer(ctx, kmsClient, &config.PublicKeyConfig{
+		KeyNames:     []string{key},
+		CacheTimeout: 5 * time.Minute,
+	}, h)
+
+	w := httptest.NewRecorder()
+	keyServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil).WithContext(ctx))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+	etag := w.Header().Get("etag")
+	if etag == "" {
+		t.Fatal("expected an etag")
+	}
+
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{
+			name:        "match",
+			ifNoneMatch: etag,
+			wantStatus:  http.StatusNotModified,
+		},
+		{
+			name:        "weak_match_in_list",
+			ifNoneMatch: `"other", W/` + etag,
+			wantStatus:  http.StatusNotModified,
+		},
+		{
+			name:        "no_match",
+			ifNoneMatch: `"other"`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "unconditional",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil).WithContext(ctx)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			keyServer.ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantStatus; got != want {
+				t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if got, want := w.Header().Get("etag"), etag; got != want {
+				t.Errorf("expected etag %q to be %q", got, want)
+			}
+			if tc.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("expected no body, got %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestKeyServer_ServeHTTP_audiences(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemEncodedPub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub})
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	kid := key + "/cryptoKeyVersions/[VERSION]-0"
+
+	mockKMSServer := testutil.NewMockKeyManagementServer(key, key+"/cryptoKeyVersions/[VERSION]", PrimaryLabelPrefix+"[VERSION]")
+	mockKMSServer.PrivateKey = privateKey
+	mockKMSServer.PublicKey = string(pemEncodedPub)
+	mockKMSServer.NumVersions = 1
+	mockKMSServer.Labels[AudienceLabelPrefix+"payments"] = ""
+	mockKMSServer.Labels[AudienceLabelPrefix+"billing-api"] = ""
+
+	_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		kmspb.RegisterKeyManagementServiceServer(s, mockKMSServer)
+	})
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	kmsClient, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyServer := NewKeyServer(ctx, kmsClient, &config.PublicKeyConfig{
+		KeyNames:     []string{key},
+		CacheTimeout: 5 * time.Minute,
+	}, h)
+
+	// JWK coordinates are always 32 bytes, unlike big.Int.Bytes.
+	fullJWKS := fmt.Sprintf(`{"keys":[{"crv":"P-256","kid":"%s","kty":"EC","x":"%s","y":"%s"}]}`,
+		kid,
+		base64.RawURLEncoding.EncodeToString(privateKey.X.FillBytes(make([]byte, 32))),
+		base64.RawURLEncoding.EncodeToString(privateKey.Y.FillBytes(make([]byte, 32))))
+
+	cases := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "all_keys",
+			wantStatus: http.StatusOK,
+			wantBody:   fullJWKS,
+		},
+		{
+			name:       "matching_audience",
+			query:      "?aud=payments",
+			wantStatus: http.StatusOK,
+			wantBody:   fullJWKS,
+		},
+		{
+			name:       "any_matching_audience",
+			query:      "?aud=other&aud=billing-api",
+			wantStatus: http.StatusOK,
+			wantBody:   fullJWKS,
+		},
+		{
+			name:       "other_audience",
+			query:      "?aud=other",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"keys":[]}`,
+		},
+		{
+			name:       "invalid_audience",
+			query:      "?aud=Payments",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks"+tc.query, nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			keyServer.ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantStatus; got != want {
+				t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			if diff := cmp.Diff(tc.wantBody, w.Body.String()); diff != "" {
+				t.Errorf("body (-want, +got):\n%s", diff)
+			}
+			if got, want := w.Header().Get("etag"), jwksETag(tc.wantBody); got != want {
+				t.Errorf("expected etag %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyServer_Ready(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemEncodedPub := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub})
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+
+	cases := []struct {
+		name    string
+		primary string
+		wantErr string
+	}{
+		{
+			name:    "ready",
+			primary: PrimaryLabelPrefix + "[VERSION]-0",
+		},
+		{
+			name:    "no_primary",
+			wantErr: "has no primary version",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			mockKMSServer := testutil.NewMockKeyManagementServer(key, key+"/cryptoKeyVersions/[VERSION]", tc.primary)
+			if tc.primary == "" {
+				mockKMSServer.Labels = nil
+			}
+			mockKMSServer.PrivateKey = privateKey
+			mockKMSServer.PublicKey = string(pemEncodedPub)
+			mockKMSServer.NumVersions = 1
+
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKMSServer)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			kmsClient, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h, err := renderer.New(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			keyServer := NewKeyServer(ctx, kmsClient, &config.PublicKeyConfig{
+				KeyNames:     []string{key},
+				CacheTimeout: 5 * time.Minute,
+			}, h)
+
+			err = keyServer.Ready(ctx)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
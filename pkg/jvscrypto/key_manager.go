@@ -0,0 +1,341 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/sethvargo/go-gcpkms/pkg/gcpkms"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// KeyManager manages the versions of signing keys in a key store, such as
+// Cloud KMS or an HSM. Versions are described with the Cloud KMS model: a name,
+// a state and a create time. Only the ENABLED and DISABLED states are expected
+// from stores other than KMS.
+type KeyManager interface {
+	// ListVersions returns all the versions of the key.
+	ListVersions(ctx context.Context, key string) ([]*kmspb.CryptoKeyVersion, error)
+
+	// GetVersion returns the version with the name.
+	GetVersion(ctx context.Context, version string) (*kmspb.CryptoKeyVersion, error)
+
+	// GetPrimary returns the name of the primary version of the key, or "" if
+	// it has none.
+	GetPrimary(ctx context.Context, key string) (string, error)
+
+	// SetPrimary marks the version as the primary version of the key.
+	SetPrimary(ctx context.Context, key, version string) error
+
+	// CreateVersion creates a new version of the key, and returns it once it is
+	// enabled.
+	CreateVersion(ctx context.Context, key string) (*kmspb.CryptoKeyVersion, error)
+
+	// DisableVersion disables the version, so it can't sign anymore.
+	DisableVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error
+
+	// DestroyVersion destroys the key material of the version.
+	DestroyVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error
+
+	// PublicKey returns the public key of the version.
+	PublicKey(ctx context.Context, version string) (crypto.PublicKey, error)
+
+	// Signer returns a signer for the version.
+	Signer(ctx context.Context, version string) (crypto.Signer, error)
+}
+
+// DefaultDestroyScheduledDuration is how long Cloud KMS schedules versions for
+// destruction if the key doesn't set a destroy_scheduled_duration.
+const DefaultDestroyScheduledDuration = 30 * 24 * time.Hour
+
+// DestroyScheduler is implemented by key managers that schedule destroyed
+// versions for destruction, instead of destroying their key material
+// immediately, like Cloud KMS.
+type DestroyScheduler interface {
+	// DestroyScheduledDuration returns how long destroyed versions of the key
+	// stay scheduled for destruction before their key material is destroyed.
+	DestroyScheduledDuration(ctx context.Context, key string) (time.Duration, error)
+}
+
+// CheckPrimaryVersion checks that the key has a primary version, and that the
+// primary version is enabled, so it can sign.
+func CheckPrimaryVersion(ctx context.Context, m KeyManager, key string) error {
+	primary, err := m.GetPrimary(ctx, key)
+	if err != nil {
+		return err
+	}
+	if primary == "" {
+		return fmt.Errorf("key %s has no primary version", key)
+	}
+
+	ver, err := m.GetVersion(ctx, primary)
+	if err != nil {
+		return fmt.Errorf("failed to get primary version %s: %w", primary, err)
+	}
+	if got, want := ver.GetState(), kmspb.CryptoKeyVersion_ENABLED; got != want {
+		return fmt.Errorf("primary version %s is %s, not %s", primary, got, want)
+	}
+	return nil
+}
+
+// EnabledPublicKeys returns the public keys of the enabled versions of all the
+// keys, keyed by version name.
+func EnabledPublicKeys(ctx context.Context, m KeyManager, keys []string) (map[string]crypto.PublicKey, error) {
+	publicKeys := make(map[string]crypto.PublicKey)
+	for _, key := range keys {
+		vers, err := m.ListVersions(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions for %s: %w", key, err)
+		}
+		for _, ver := range vers {
+			if ver.GetState() != kmspb.CryptoKeyVersion_ENABLED {
+				continue
+			}
+			pub, err := m.PublicKey(ctx, ver.GetName())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get public key for key version %s: %w", ver.GetName(), err)
+			}
+			publicKeys[ver.GetName()] = pub
+		}
+	}
+	return publicKeys, nil
+}
+
+// kmsKeyManager manages keys in Cloud KMS. The primary version is stored in the
+// key labels, see [SetPrimary].
+type kmsKeyManager struct {
+	client *kms.KeyManagementClient
+
+	// versionTemplate, if set, overrides the version template of the key when
+	// creating versions. Unspecified fields keep the key's setting.
+	versionTemplate *kmspb.CryptoKeyVersionTemplate
+}
+
+// NewKMSKeyManager returns a [KeyManager] for Cloud KMS keys, in the format
+// `projects/*/locations/*/keyRings/*/cryptoKeys/*`.
+func NewKMSKeyManager(client *kms.KeyManagementClient) KeyManager {
+	return &kmsKeyManager{client: client}
+}
+
+func (m *kmsKeyManager) ListVersions(ctx context.Context, key string) ([]*kmspb.CryptoKeyVersion, error) {
+	it := m.client.ListCryptoKeyVersions(ctx, &kmspb.ListCryptoKeyVersionsRequest{
+		Parent: key,
+	})
+	vers := make([]*kmspb.CryptoKeyVersion, 0)
+	for {
+		ver, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("err while reading crypto key version list: %w", err)
+		}
+		vers = append(vers, ver)
+	}
+	return vers, nil
+}
+
+func (m *kmsKeyManager) GetVersion(ctx context.Context, version string) (*kmspb.CryptoKeyVersion, error) {
+	ver, err := m.client.GetCryptoKeyVersion(ctx, &kmspb.GetCryptoKeyVersionRequest{Name: version})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crypto key version: %w", err)
+	}
+	return ver, nil
+}
+
+func (m *kmsKeyManager) GetPrimary(ctx context.Context, key string) (string, error) {
+	return GetPrimary(ctx, m.client, key)
+}
+
+func (m *kmsKeyManager) SetPrimary(ctx context.Context, key, version string) error {
+	return SetPrimary(ctx, m.client, key, version)
+}
+
+func (m *kmsKeyManager) CreateVersion(ctx context.Context, key string) (*kmspb.CryptoKeyVersion, error) {
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "creating new key version")
+
+	if m.versionTemplate != nil {
+		if err := m.updateVersionTemplate(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	createReq := &kmspb.CreateCryptoKeyVersionRequest{
+		Parent:           key,
+		CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
+	}
+	resp, err := m.client.CreateCryptoKeyVersion(ctx, createReq)
+	if err != nil {
+		return nil, fmt.Errorf("key creation failed: %w", err)
+	}
+
+	// Wait for a key version to be created and enabled.
+	var ckv *kmspb.CryptoKeyVersion
+	b := retry.WithMaxRetries(5, retry.NewFibonacci(500*time.Millisecond))
+	if err := retry.Do(ctx, b, func(ctx context.Context) error {
+		var err error
+		ckv, err = m.GetVersion(ctx, resp.GetName())
+		if err != nil {
+			return err
+		}
+		if got, want := ckv.GetState(), kmspb.CryptoKeyVersion_ENABLED; got != want {
+			return retry.RetryableError(fmt.Errorf("expected %s to be %s", got.String(), want.String()))
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("key did not enter ready state: %w", err)
+	}
+
+	if err := m.checkVersionTemplate(ckv); err != nil {
+		return nil, err
+	}
+	return ckv, nil
+}
+
+// updateVersionTemplate updates the version template of the key to the
+// configured one, if it differs, so new versions are created with it.
+func (m *kmsKeyManager) updateVersionTemplate(ctx context.Context, key string) error {
+	cryptoKey, err := m.client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: key})
+	if err != nil {
+		return fmt.Errorf("issue while getting key from KMS: %w", err)
+	}
+
+	template := &kmspb.CryptoKeyVersionTemplate{
+		Algorithm:       cryptoKey.GetVersionTemplate().GetAlgorithm(),
+		ProtectionLevel: cryptoKey.GetVersionTemplate().GetProtectionLevel(),
+	}
+	if a := m.versionTemplate.GetAlgorithm(); a != kmspb.CryptoKeyVersion_CRYPTO_KEY_VERSION_ALGORITHM_UNSPECIFIED {
+		template.Algorithm = a
+	}
+	if p := m.versionTemplate.GetProtectionLevel(); p != kmspb.ProtectionLevel_PROTECTION_LEVEL_UNSPECIFIED {
+		template.ProtectionLevel = p
+	}
+	if proto.Equal(template, cryptoKey.GetVersionTemplate()) {
+		return nil
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "updating key version template",
+		"key", key,
+		"algorithm", template.GetAlgorithm().String(),
+		"protection_level", template.GetProtectionLevel().String())
+	var messageType *kmspb.CryptoKey
+	mask, err := fieldmaskpb.New(messageType, "version_template")
+	if err != nil {
+		return fmt.Errorf("failed to create fieldmask: %w", err)
+	}
+	if _, err := m.client.UpdateCryptoKey(ctx, &kmspb.UpdateCryptoKeyRequest{
+		CryptoKey: &kmspb.CryptoKey{
+			Name:            key,
+			VersionTemplate: template,
+		},
+		UpdateMask: mask,
+	}); err != nil {
+		return fmt.Errorf("failed to update key version template: %w", err)
+	}
+	return nil
+}
+
+// checkVersionTemplate checks the version was created with the configured
+// version template.
+func (m *kmsKeyManager) checkVersionTemplate(ver *kmspb.CryptoKeyVersion) error {
+	if want := m.versionTemplate.GetAlgorithm(); want != kmspb.CryptoKeyVersion_CRYPTO_KEY_VERSION_ALGORITHM_UNSPECIFIED &&
+		ver.GetAlgorithm() != want {
+		return fmt.Errorf("created version %s has algorithm %s, expected %s", ver.GetName(), ver.GetAlgorithm(), want)
+	}
+	if want := m.versionTemplate.GetProtectionLevel(); want != kmspb.ProtectionLevel_PROTECTION_LEVEL_UNSPECIFIED &&
+		ver.GetProtectionLevel() != want {
+		return fmt.Errorf("created version %s has protection level %s, expected %s", ver.GetName(), ver.GetProtectionLevel(), want)
+	}
+	return nil
+}
+
+func (m *kmsKeyManager) DisableVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
+	logger := logging.FromContext(ctx)
+
+	// Make a copy to modify
+	newVerState := ver
+
+	logger.InfoContext(ctx, "disabling key version", "version_name", ver.GetName())
+	newVerState.State = kmspb.CryptoKeyVersion_DISABLED
+	var messageType *kmspb.CryptoKeyVersion
+	mask, err := fieldmaskpb.New(messageType, "state")
+	if err != nil {
+		return fmt.Errorf("failed to create fieldmask: %w", err)
+	}
+	updateReq := &kmspb.UpdateCryptoKeyVersionRequest{
+		CryptoKeyVersion: newVerState,
+		UpdateMask:       mask,
+	}
+	if _, err := m.client.UpdateCryptoKeyVersion(ctx, updateReq); err != nil {
+		return fmt.Errorf("key disable failed: %w", err)
+	}
+	return nil
+}
+
+func (m *kmsKeyManager) DestroyVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "destroying key version", "version_name", ver.GetName())
+	destroyReq := &kmspb.DestroyCryptoKeyVersionRequest{
+		Name: ver.GetName(),
+	}
+	if _, err := m.client.DestroyCryptoKeyVersion(ctx, destroyReq); err != nil {
+		return fmt.Errorf("key destroy failed: %w", err)
+	}
+	return nil
+}
+
+func (m *kmsKeyManager) DestroyScheduledDuration(ctx context.Context, key string) (time.Duration, error) {
+	response, err := m.client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: key})
+	if err != nil {
+		return 0, fmt.Errorf("issue while getting key from KMS: %w", err)
+	}
+	if d := response.GetDestroyScheduledDuration(); d != nil {
+		return d.AsDuration(), nil
+	}
+	return DefaultDestroyScheduledDuration, nil
+}
+
+func (m *kmsKeyManager) PublicKey(ctx context.Context, version string) (crypto.PublicKey, error) {
+	resp, err := m.client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: version})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key: %w", err)
+	}
+	pub, _, err := jwk.DecodePEM([]byte(resp.GetPem()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pem: %w", err)
+	}
+	return pub, nil
+}
+
+func (m *kmsKeyManager) Signer(ctx context.Context, version string) (crypto.Signer, error) {
+	signer, err := gcpkms.NewSigner(ctx, m.client, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	return signer, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// maxSignedKeySets is the maximum number of signed key sets cached per JWKS
+// document. There is one per combination of audiences requested, so the cache
+// is bounded; key sets beyond it are signed for every request.
+const maxSignedKeySets = 64
+
+// keySetSigner signs key sets with the root key.
+type keySetSigner struct {
+	signer crypto.Signer
+	kid    string
+	ttl    time.Duration
+}
+
+// sign returns the JSON encoded JWKS as a signed key set, a JWT of type
+// [jvspb.SignedJWKSType] with the keys in its "keys" claim. The root public
+// key is in the "jwk" header, so verifiers can check it against the root keys
+// they pin. See [jvspb.VerifySignedJWKS].
+func (s *keySetSigner) sign(jwks string, now time.Time) (string, error) {
+	var set struct {
+		Keys json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(jwks), &set); err != nil {
+		return "", fmt.Errorf("failed to parse jwks: %w", err)
+	}
+	payload, err := json.Marshal(map[string]any{
+		"keys": set.Keys,
+		"iat":  now.Unix(),
+		"exp":  now.Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal signed jwks payload: %w", err)
+	}
+
+	pub, err := jwk.FromRaw(s.signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to create jwk from root key: %w", err)
+	}
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.TypeKey, jvspb.SignedJWKSType); err != nil {
+		return "", fmt.Errorf("failed to set typ header: %w", err)
+	}
+	if err := headers.Set(jws.KeyIDKey, s.kid); err != nil {
+		return "", fmt.Errorf("failed to set kid header: %w", err)
+	}
+	if err := headers.Set(jws.JWKKey, pub); err != nil {
+		return "", fmt.Errorf("failed to set jwk header: %w", err)
+	}
+
+	b, err := jws.Sign(payload, jws.WithKey(jwa.ES256, s.signer, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwks: %w", err)
+	}
+	return string(b), nil
+}
+
+// signed returns the signed key set of the JSON encoded JWKS, which must be
+// one the document serves. Signed key sets are cached with the document, so
+// the root key signs each of them once per cache refresh.
+func (d *jwksDocument) signed(jwks string, s *keySetSigner) (string, error) {
+	d.signedMu.Lock()
+	defer d.signedMu.Unlock()
+
+	if v, ok := d.signedSets[jwks]; ok {
+		return v, nil
+	}
+	v, err := s.sign(jwks, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if d.signedSets == nil {
+		d.signedSets = make(map[string]string, 1)
+	}
+	if len(d.signedSets) < maxSignedKeySets {
+		d.signedSets[jwks] = v
+	}
+	return v, nil
+}
+
+// acceptsSignedKeySet reports whether the Accept header of the request lists
+// [jvspb.SignedJWKSContentType].
+func acceptsSignedKeySet(r *http.Request) bool {
+	for _, header := range r.Header.Values("accept") {
+		for _, v := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(v)
+			if err == nil && mediaType == jvspb.SignedJWKSContentType && params["q"] != "0" {
+				return true
+			}
+		}
+	}
+	return false
+}
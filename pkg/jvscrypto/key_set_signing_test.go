@@ -0,0 +1,189 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+func TestKeyServer_ServeHTTP_signed(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	dir := t.TempDir()
+	key, path := writeTestKey(t, dir, "key.pem", false)
+	kid, err := LocalKeyID(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootThumbprint, err := LocalKeyID(rootKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.PublicKeyConfig{CacheTimeout: 5 * time.Minute}
+
+	signed := NewKeyServer(ctx, nil, cfg, h).
+		WithLocalKeys([]string{path}).
+		WithKeySetSigner(rootKey, "root", time.Hour)
+	unsigned := NewKeyServer(ctx, nil, cfg, h).
+		WithLocalKeys([]string{path})
+
+	cases := []struct {
+		name            string
+		server          *KeyServer
+		accept          string
+		wantContentType string
+		wantVary        string
+	}{
+		{
+			name:            "signed",
+			server:          signed,
+			accept:          jvspb.SignedJWKSContentType,
+			wantContentType: jvspb.SignedJWKSContentType,
+			wantVary:        "Accept",
+		},
+		{
+			name:            "signed_in_list",
+			server:          signed,
+			accept:          "application/json;q=0.5, " + jvspb.SignedJWKSContentType,
+			wantContentType: jvspb.SignedJWKSContentType,
+			wantVary:        "Accept",
+		},
+		{
+			name:            "signed_refused",
+			server:          signed,
+			accept:          jvspb.SignedJWKSContentType + ";q=0, application/json",
+			wantContentType: "application/json",
+			wantVary:        "Accept",
+		},
+		{
+			name:            "json",
+			server:          signed,
+			accept:          "application/json",
+			wantContentType: "application/json",
+			wantVary:        "Accept",
+		},
+		{
+			name:            "no_signer",
+			server:          unsigned,
+			accept:          jvspb.SignedJWKSContentType,
+			wantContentType: "application/json",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil).WithContext(ctx)
+			req.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+			tc.server.ServeHTTP(w, req)
+
+			if got, want := w.Code, http.StatusOK; got != want {
+				t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if got, want := w.Header().Get("content-type"), tc.wantContentType; got != want {
+				t.Errorf("expected content type %q to be %q", got, want)
+			}
+			if got, want := w.Header().Get("vary"), tc.wantVary; got != want {
+				t.Errorf("expected vary %q to be %q", got, want)
+			}
+			if tc.wantContentType != jvspb.SignedJWKSContentType {
+				return
+			}
+
+			got, err := jvspb.VerifySignedJWKS(w.Body.Bytes(), []string{rootThumbprint}, time.Now(), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := got.Keys.LookupKeyID(kid); !ok {
+				t.Errorf("expected key %q in the signed jwks", kid)
+			}
+			if got, want := got.ExpiresAt.Sub(got.IssuedAt), time.Hour; got != want {
+				t.Errorf("expected signed jwks lifetime %s to be %s", got, want)
+			}
+		})
+	}
+}
+
+func TestKeyServer_ServeHTTP_signedCached(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	_, path := writeTestKey(t, t.TempDir(), "key.pem", false)
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := NewKeyServer(ctx, nil, &config.PublicKeyConfig{CacheTimeout: 5 * time.Minute}, h).
+		WithLocalKeys([]string{path}).
+		WithKeySetSigner(rootKey, "root", time.Hour)
+
+	serve := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil).WithContext(ctx)
+		req.Header.Set("Accept", jvspb.SignedJWKSContentType)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		keyServer.ServeHTTP(w, req)
+		return w
+	}
+
+	first := serve("")
+	if got, want := first.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, first.Body.String())
+	}
+
+	// The key set is signed once per cache refresh, so it is revalidated with
+	// its ETag.
+	second := serve(first.Header().Get("etag"))
+	if got, want := second.Code, http.StatusNotModified; got != want {
+		t.Errorf("expected status %d to be %d: %s", got, want, second.Body.String())
+	}
+	if got, want := serve("").Body.String(), first.Body.String(); got != want {
+		t.Errorf("expected signed jwks %q to be %q", got, want)
+	}
+}
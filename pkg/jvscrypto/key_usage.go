@@ -0,0 +1,177 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+const (
+	// maxKeyUsageReportSize is the maximum size of a key usage report body.
+	maxKeyUsageReportSize = 4096
+
+	// maxTrackedKeyVersions bounds the memory used by bogus reports.
+	maxTrackedKeyVersions = 1024
+)
+
+// KeyUsage is the aggregated usage of a key version.
+type KeyUsage struct {
+	// KeyID is the "kid" of the key version.
+	KeyID string `json:"kid"`
+
+	// Reports is the number of reports received by this replica.
+	// EstimatedVerifications accounts for the verifiers' sample rates.
+	Reports                int64 `json:"reports"`
+	EstimatedVerifications int64 `json:"estimated_verifications"`
+
+	FirstReported time.Time `json:"first_reported"`
+	LastReported  time.Time `json:"last_reported"`
+}
+
+// KeyUsageServer aggregates the key versions verifiers report using, so
+// operators know when an old version is no longer verifying tokens and safe to
+// destroy. POST accepts a [jvspb.KeyUsageReport] and GET returns the usage of
+// every reported version.
+//
+// Usage is aggregated in memory since the server started, so with several
+// replicas, each one only counts the reports it received. Each report is also
+// logged, for aggregating across replicas.
+type KeyUsageServer struct {
+	config *config.PublicKeyConfig
+	h      *renderer.Renderer
+	now    func() time.Time
+
+	mu    sync.Mutex
+	usage map[string]*KeyUsage
+}
+
+// NewKeyUsageServer creates a new server. See [KeyUsageServer] for more
+// information.
+func NewKeyUsageServer(cfg *config.PublicKeyConfig, h *renderer.Renderer) *KeyUsageServer {
+	return &KeyUsageServer{
+		config: cfg,
+		h:      h,
+		now:    time.Now,
+		usage:  make(map[string]*KeyUsage),
+	}
+}
+
+// ServeHTTP records or returns key usage.
+func (s *KeyUsageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handleReport(w, r)
+	case http.MethodGet:
+		s.h.RenderJSON(w, http.StatusOK, s.Usage())
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		s.h.RenderJSON(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	}
+}
+
+func (s *KeyUsageServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	var report jvspb.KeyUsageReport
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxKeyUsageReportSize)).Decode(&report); err != nil {
+		s.h.RenderJSON(w, http.StatusBadRequest, fmt.Errorf("failed to parse report: %w", err))
+		return
+	}
+	if err := s.validateReport(&report); err != nil {
+		s.h.RenderJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !s.record(&report) {
+		s.h.RenderJSON(w, http.StatusTooManyRequests, fmt.Errorf("too many key versions tracked"))
+		return
+	}
+	logger.InfoContext(ctx, "key usage reported",
+		"kid", report.KeyID,
+		"sample_rate", report.SampleRate)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validateReport ensures the report is for a version of a key this server
+// serves, which also bounds how many versions are tracked.
+func (s *KeyUsageServer) validateReport(report *jvspb.KeyUsageReport) error {
+	// Tiny rates would overflow the estimated verifications.
+	if report.SampleRate < jvspb.MinUsageReportSampleRate || report.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be in [%v, 1], got %v", jvspb.MinUsageReportSampleRate, report.SampleRate)
+	}
+
+	// Key IDs are key version resource names.
+	matches := versionNameRegexp.FindStringSubmatch(report.KeyID)
+	if matches == nil || !slices.Contains(s.config.KeyNames, matches[1]) {
+		return fmt.Errorf("kid %q is not a version of a jvs key", report.KeyID)
+	}
+	return nil
+}
+
+// record adds the report to the usage. It returns false if the report is for a
+// new key version and too many versions are already tracked.
+func (s *KeyUsageServer) record(report *jvspb.KeyUsageReport) bool {
+	now := s.now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.usage[report.KeyID]
+	if !ok {
+		if len(s.usage) >= maxTrackedKeyVersions {
+			return false
+		}
+		u = &KeyUsage{
+			KeyID:         report.KeyID,
+			FirstReported: now,
+		}
+		s.usage[report.KeyID] = u
+	}
+	u.Reports++
+	u.EstimatedVerifications += int64(math.Round(1 / report.SampleRate))
+	u.LastReported = now
+	return true
+}
+
+// Usage returns the usage of every reported key version, sorted by key ID.
+func (s *KeyUsageServer) Usage() []*KeyUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]*KeyUsage, 0, len(s.usage))
+	for _, u := range s.usage {
+		cp := *u
+		out = append(out, &cp)
+	}
+	slices.SortFunc(out, func(a, b *KeyUsage) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+	return out
+}
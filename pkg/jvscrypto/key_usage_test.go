@@ -0,0 +1,135 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+)
+
+func TestKeyUsageServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	versionOne := key + "/cryptoKeyVersions/1"
+	versionTwo := key + "/cryptoKeyVersions/2"
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewKeyUsageServer(&config.PublicKeyConfig{KeyNames: []string{key}}, h)
+	server.now = func() time.Time { return now }
+
+	cases := []struct {
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{
+			method:     http.MethodPost,
+			body:       `{"kid":"` + versionOne + `","sample_rate":0.01}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			method:     http.MethodPost,
+			body:       `{"kid":"` + versionOne + `","sample_rate":0.5}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			method:     http.MethodPost,
+			body:       `{"kid":"` + versionTwo + `","sample_rate":1}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			method:     http.MethodPost,
+			body:       `{"kid":"projects/p/locations/l/keyRings/r/cryptoKeys/other/cryptoKeyVersions/1","sample_rate":1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			method:     http.MethodPost,
+			body:       `{"kid":"` + versionOne + `","sample_rate":0}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			method:     http.MethodPost,
+			body:       `{"kid":"` + versionOne + `","sample_rate":1e-300}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			method:     http.MethodPost,
+			body:       `not json`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			method:     http.MethodDelete,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/key-usage", strings.NewReader(tc.body)).WithContext(ctx)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if got, want := w.Code, tc.wantStatus; got != want {
+			t.Errorf("%s %s: expected status %d to be %d: %s", tc.method, tc.body, got, want, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/key-usage", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	var got []*KeyUsage
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []*KeyUsage{
+		{
+			KeyID:                  versionOne,
+			Reports:                2,
+			EstimatedVerifications: 102,
+			FirstReported:          now,
+			LastReported:           now,
+		},
+		{
+			KeyID:                  versionTwo,
+			Reports:                1,
+			EstimatedVerifications: 1,
+			FirstReported:          now,
+			LastReported:           now,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+}
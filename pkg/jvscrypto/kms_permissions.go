@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrKMSPermissionsUnknown is wrapped by the errors of
+// [CheckKMSPermissions] when the permissions could not be tested at all, e.g.
+// because KMS is unreachable or doesn't implement IAM, as KMS emulators don't.
+var ErrKMSPermissionsUnknown = errors.New("kms permissions could not be tested")
+
+var (
+	// SignerPermissions are the permissions on the key the servers that sign
+	// tokens with KMS need.
+	SignerPermissions = []string{
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeyVersions.get",
+		"cloudkms.cryptoKeyVersions.useToSign",
+		"cloudkms.cryptoKeyVersions.viewPublicKey",
+	}
+
+	// PublicKeyPermissions are the permissions on the keys the public key
+	// server needs.
+	PublicKeyPermissions = []string{
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeyVersions.list",
+		"cloudkms.cryptoKeyVersions.viewPublicKey",
+	}
+
+	// RotationPermissions are the permissions on the keys the cert rotator
+	// needs.
+	RotationPermissions = []string{
+		"cloudkms.cryptoKeys.get",
+		"cloudkms.cryptoKeys.update",
+		"cloudkms.cryptoKeyVersions.create",
+		"cloudkms.cryptoKeyVersions.destroy",
+		"cloudkms.cryptoKeyVersions.list",
+		"cloudkms.cryptoKeyVersions.update",
+	}
+)
+
+// permissionRoles are the predefined roles that grant permissions, so missing
+// permissions can be reported with the role to grant, consistent with the
+// roles granted by `jvsctl bootstrap`.
+var permissionRoles = map[string]string{
+	"cloudkms.cryptoKeys.get":                  "roles/cloudkms.viewer",
+	"cloudkms.cryptoKeyVersions.get":           "roles/cloudkms.viewer",
+	"cloudkms.cryptoKeyVersions.list":          "roles/cloudkms.viewer",
+	"cloudkms.cryptoKeyVersions.useToSign":     "roles/cloudkms.cryptoOperator",
+	"cloudkms.cryptoKeyVersions.viewPublicKey": "roles/cloudkms.publicKeyViewer",
+	"cloudkms.cryptoKeys.update":               "roles/cloudkms.admin",
+	"cloudkms.cryptoKeyVersions.create":        "roles/cloudkms.admin",
+	"cloudkms.cryptoKeyVersions.destroy":       "roles/cloudkms.admin",
+	"cloudkms.cryptoKeyVersions.update":        "roles/cloudkms.admin",
+}
+
+// CheckKMSPermissions checks the caller has the permissions on each of the
+// keys. The error lists the missing permissions of each key, and the roles
+// that grant them. If the permissions can't be tested, it stops and returns an
+// error wrapping [ErrKMSPermissionsUnknown].
+func CheckKMSPermissions(ctx context.Context, client *kms.KeyManagementClient, keys, permissions []string) error {
+	var merr error
+	for _, key := range keys {
+		granted, err := client.ResourceIAM(key).TestPermissions(ctx, permissions)
+		if err != nil {
+			switch status.Code(err) {
+			case codes.Unimplemented, codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+				return fmt.Errorf("%w on %s: %w", ErrKMSPermissionsUnknown, key, err)
+			}
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w on %s: %w", ErrKMSPermissionsUnknown, key, err)
+			}
+			merr = errors.Join(merr, fmt.Errorf("failed to test permissions on %s: %w", key, err))
+			continue
+		}
+
+		var missing []string
+		roles := make(map[string]struct{})
+		for _, p := range permissions {
+			if slices.Contains(granted, p) {
+				continue
+			}
+			missing = append(missing, p)
+			if role, ok := permissionRoles[p]; ok {
+				roles[role] = struct{}{}
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		err = fmt.Errorf("missing permissions %s on %s", strings.Join(missing, ", "), key)
+		if len(roles) > 0 {
+			names := make([]string, 0, len(roles))
+			for role := range roles {
+				names = append(names, role)
+			}
+			slices.Sort(names)
+			err = fmt.Errorf("%w, grant %s", err, strings.Join(names, ", "))
+		}
+		merr = errors.Join(merr, err)
+	}
+	return merr
+}
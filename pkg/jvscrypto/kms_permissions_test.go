@@ -0,0 +1,138 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"cloud.google.com/go/iam/apiv1/iampb"
+	kms "cloud.google.com/go/kms/apiv1"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestCheckKMSPermissions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const key = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	const otherKey = "projects/p/locations/global/keyRings/r/cryptoKeys/other"
+
+	cases := []struct {
+		name        string
+		keys        []string
+		permissions []string
+		granted     map[string][]string
+		noIAM       bool
+		wantErr     string
+		wantUnknown bool
+	}{
+		{
+			name:        "granted",
+			keys:        []string{key},
+			permissions: SignerPermissions,
+			granted:     map[string][]string{key: SignerPermissions},
+		},
+		{
+			name:        "missing_sign",
+			keys:        []string{key},
+			permissions: SignerPermissions,
+			granted: map[string][]string{key: {
+				"cloudkms.cryptoKeys.get",
+				"cloudkms.cryptoKeyVersions.get",
+				"cloudkms.cryptoKeyVersions.viewPublicKey",
+			}},
+			wantErr: "missing permissions cloudkms.cryptoKeyVersions.useToSign on " + key +
+				", grant roles/cloudkms.cryptoOperator",
+		},
+		{
+			name:        "missing_all",
+			keys:        []string{key},
+			permissions: PublicKeyPermissions,
+			wantErr: "missing permissions cloudkms.cryptoKeys.get, cloudkms.cryptoKeyVersions.list, " +
+				"cloudkms.cryptoKeyVersions.viewPublicKey on " + key +
+				", grant roles/cloudkms.publicKeyViewer, roles/cloudkms.viewer",
+		},
+		{
+			name:        "one_of_several_keys",
+			keys:        []string{key, otherKey},
+			permissions: RotationPermissions,
+			granted: map[string][]string{
+				key:      RotationPermissions,
+				otherKey: RotationPermissions[:1],
+			},
+			wantErr: "on " + otherKey + ", grant roles/cloudkms.admin, roles/cloudkms.viewer",
+		},
+		{
+			name:        "iam_not_implemented",
+			keys:        []string{key},
+			permissions: SignerPermissions,
+			noIAM:       true,
+			wantErr:     "kms permissions could not be tested on " + key,
+			wantUnknown: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				if !tc.noIAM {
+					iampb.RegisterIAMPolicyServer(s, &fakeIAMServer{granted: tc.granted})
+				}
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			client, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = CheckKMSPermissions(ctx, client, tc.keys, tc.permissions)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if got, want := errors.Is(err, ErrKMSPermissionsUnknown), tc.wantUnknown; got != want {
+				t.Errorf("expected errors.Is(err, ErrKMSPermissionsUnknown) %t to be %t", got, want)
+			}
+		})
+	}
+}
+
+// fakeIAMServer grants the permissions in granted, keyed by resource.
+type fakeIAMServer struct {
+	iampb.UnimplementedIAMPolicyServer
+
+	granted map[string][]string
+}
+
+func (s *fakeIAMServer) TestIamPermissions(ctx context.Context, req *iampb.TestIamPermissionsRequest) (*iampb.TestIamPermissionsResponse, error) {
+	var permissions []string
+	for _, p := range req.GetPermissions() {
+		if slices.Contains(s.granted[req.GetResource()], p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return &iampb.TestIamPermissionsResponse{Permissions: permissions}, nil
+}
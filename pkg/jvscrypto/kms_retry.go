@@ -0,0 +1,156 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"github.com/sethvargo/go-retry"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// KMSRetryPolicy is how calls to Cloud KMS are retried. Clients created with
+// [NewKMSClient] retry all their calls with it, instead of the default retries
+// of the KMS client, so the processor, key server and rotation handler retry
+// transient KMS errors the same way.
+type KMSRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the
+	// first one.
+	MaxAttempts uint64
+
+	// InitialBackoff is the pause before the first retry. It doubles with each
+	// retry, up to MaxBackoff, and is jittered by up to JitterPercent percent so
+	// that replicas don't retry in lockstep.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	JitterPercent  uint64
+
+	// Budget is the maximum time of a call, including its retries. It is
+	// overridden for some methods by MethodBudgets, keyed by method name, e.g.
+	// "AsymmetricSign".
+	Budget        time.Duration
+	MethodBudgets map[string]time.Duration
+}
+
+// DefaultKMSRetryPolicy returns the retry policy of the servers. Signing is on
+// the path of users' requests, so it has a shorter budget than the other calls.
+func DefaultKMSRetryPolicy() *KMSRetryPolicy {
+	return &KMSRetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		JitterPercent:  20,
+		Budget:         30 * time.Second,
+		MethodBudgets: map[string]time.Duration{
+			"AsymmetricSign": 10 * time.Second,
+		},
+	}
+}
+
+// NewKMSClient creates a KMS client whose calls are retried with the policy.
+// The policy is installed as a gRPC interceptor of the client's connection, so
+// it doesn't apply if the options give an existing connection.
+func NewKMSClient(ctx context.Context, policy *KMSRetryPolicy, opts ...option.ClientOption) (*kms.KeyManagementClient, error) {
+	client, err := kms.NewKeyManagementClient(ctx, append(opts,
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(policy.UnaryClientInterceptor)))...)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Callers add their own context.
+	}
+
+	// Disable the client's own retries and timeouts, which would multiply the
+	// attempts of the policy.
+	client.CallOptions = &kms.KeyManagementCallOptions{}
+	return client, nil
+}
+
+// UnaryClientInterceptor retries failed KMS calls with retryable codes, with
+// exponential backoff, until the call succeeds, runs out of attempts or its
+// budget expires.
+func (p *KMSRetryPolicy) UnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	logger := logging.FromContext(ctx)
+
+	name := path.Base(method)
+	ctx, cancel := context.WithTimeout(ctx, p.budget(name))
+	defer cancel()
+
+	var attempts uint64
+	var lastErr error
+	if err := retry.Do(ctx, p.backoff(), func(ctx context.Context) error {
+		attempts++
+		lastErr = invoker(ctx, method, req, reply, cc, opts...)
+		if lastErr != nil && retryableKMSError(name, lastErr) {
+			logger.WarnContext(ctx, "kms call failed with a retryable error",
+				"method", name,
+				"attempt", attempts,
+				"code", status.Code(lastErr).String(),
+				"error", lastErr)
+			return retry.RetryableError(lastErr)
+		}
+		return lastErr
+	}); err != nil {
+		// Report why the last attempt failed, rather than the budget expiring,
+		// so callers still get the status error of the call.
+		if lastErr != nil {
+			return lastErr
+		}
+		return err //nolint:wrapcheck // Only a context error, before any attempt.
+	}
+	return nil
+}
+
+// budget returns the budget of calls to the method.
+func (p *KMSRetryPolicy) budget(method string) time.Duration {
+	if d, ok := p.MethodBudgets[method]; ok {
+		return d
+	}
+	return p.Budget
+}
+
+// backoff returns the backoff between the attempts of a call.
+func (p *KMSRetryPolicy) backoff() retry.Backoff {
+	var retries uint64
+	if p.MaxAttempts > 1 {
+		retries = p.MaxAttempts - 1
+	}
+
+	b := retry.NewExponential(p.InitialBackoff)
+	b = retry.WithCappedDuration(p.MaxBackoff, b)
+	b = retry.WithJitterPercent(p.JitterPercent, b)
+	return retry.WithMaxRetries(retries, b)
+}
+
+// retryableKMSError returns true if the KMS call to the method failed with a
+// transient error, and it is safe to call it again. Calls that create
+// resources aren't safe to repeat if the first one may have been applied, so
+// they are only retried when KMS rejected them for quota.
+func retryableKMSError(method string, err error) bool {
+	switch status.Code(err) {
+	case codes.ResourceExhausted:
+		return true
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+		return !strings.HasPrefix(method, "Create")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/pkg/logging"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+// flakyKMS fails calls with its errors, in order, then succeeds.
+type flakyKMS struct {
+	kmspb.UnimplementedKeyManagementServiceServer
+
+	mu    sync.Mutex
+	errs  []error
+	calls int
+	delay time.Duration
+}
+
+func (s *flakyKMS) nextErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func (s *flakyKMS) GetCryptoKey(ctx context.Context, req *kmspb.GetCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	if err := s.nextErr(); err != nil {
+		return nil, err
+	}
+	return &kmspb.CryptoKey{Name: req.GetName()}, nil
+}
+
+func (s *flakyKMS) CreateCryptoKeyVersion(ctx context.Context, req *kmspb.CreateCryptoKeyVersionRequest) (*kmspb.CryptoKeyVersion, error) {
+	if err := s.nextErr(); err != nil {
+		return nil, err
+	}
+	return &kmspb.CryptoKeyVersion{Name: req.GetParent() + "/cryptoKeyVersions/1"}, nil
+}
+
+func (s *flakyKMS) AsymmetricSign(ctx context.Context, req *kmspb.AsymmetricSignRequest) (*kmspb.AsymmetricSignResponse, error) {
+	if err := s.nextErr(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case <-time.After(s.delay):
+	}
+	return &kmspb.AsymmetricSignResponse{Name: req.GetName()}, nil
+}
+
+func TestNewKMSClient_retries(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	unavailable := status.Error(codes.Unavailable, "try again")
+	exhausted := status.Error(codes.ResourceExhausted, "quota exceeded")
+	denied := status.Error(codes.PermissionDenied, "denied")
+
+	cases := []struct {
+		name      string
+		method    string
+		errs      []error
+		delay     time.Duration
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{
+			name:      "success",
+			method:    "GetCryptoKey",
+			wantCalls: 1,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "transient_errors",
+			method:    "GetCryptoKey",
+			errs:      []error{unavailable, exhausted},
+			wantCalls: 3,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "not_retryable",
+			method:    "GetCryptoKey",
+			errs:      []error{denied},
+			wantCalls: 1,
+			wantCode:  codes.PermissionDenied,
+		},
+		{
+			name:      "out_of_attempts",
+			method:    "GetCryptoKey",
+			errs:      []error{unavailable, unavailable, unavailable, unavailable},
+			wantCalls: 3,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "create_not_retried_if_unavailable",
+			method:    "CreateCryptoKeyVersion",
+			errs:      []error{unavailable},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "create_retried_if_exhausted",
+			method:    "CreateCryptoKeyVersion",
+			errs:      []error{exhausted},
+			wantCalls: 2,
+			wantCode:  codes.OK,
+		},
+		{
+			name:      "method_budget",
+			method:    "AsymmetricSign",
+			delay:     time.Minute,
+			wantCalls: 1,
+			wantCode:  codes.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := &flakyKMS{errs: tc.errs, delay: tc.delay}
+			addr, _ := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, server)
+			})
+
+			client, err := NewKMSClient(ctx, &KMSRetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     10 * time.Millisecond,
+				JitterPercent:  20,
+				Budget:         time.Minute,
+				MethodBudgets: map[string]time.Duration{
+					"AsymmetricSign": 100 * time.Millisecond,
+				},
+			},
+				option.WithEndpoint(addr),
+				option.WithoutAuthentication(),
+				option.WithGRPCDialOption(grpc.WithTransportCredentials(grpcinsecure.NewCredentials())),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := client.Close(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			switch tc.method {
+			case "GetCryptoKey":
+				_, err = client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: "key"})
+			case "CreateCryptoKeyVersion":
+				_, err = client.CreateCryptoKeyVersion(ctx, &kmspb.CreateCryptoKeyVersionRequest{Parent: "key"})
+			case "AsymmetricSign":
+				_, err = client.AsymmetricSign(ctx, &kmspb.AsymmetricSignRequest{Name: "key"})
+			default:
+				panic(fmt.Sprintf("unknown method %q", tc.method))
+			}
+
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if got, want := server.calls, tc.wantCalls; got != want {
+				t.Errorf("expected %d calls to be %d", got, want)
+			}
+		})
+	}
+}
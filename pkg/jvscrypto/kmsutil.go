@@ -34,6 +34,11 @@ import (
 const (
 	PrimaryKey         = "primary"
 	PrimaryLabelPrefix = "ver_"
+
+	// AudienceLabelPrefix is the prefix of the labels of a key that name the
+	// audiences it signs for, e.g. "aud_payments" for the "payments" audience.
+	// The values of the labels are ignored.
+	AudienceLabelPrefix = "aud_"
 )
 
 // GetPrimary gets the key version name marked as primary in the key labels.
@@ -42,12 +47,24 @@ func GetPrimary(ctx context.Context, kms *kms.KeyManagementClient, key string) (
 	if err != nil {
 		return "", fmt.Errorf("issue while getting key from KMS: %w", err)
 	}
-	if primary, ok := response.GetLabels()[PrimaryKey]; ok {
+	return primaryFromLabels(key, response.GetLabels()), nil
+}
+
+// primaryFromLabels returns the name of the primary version in the key labels,
+// or "" if there is none.
+func primaryFromLabels(key string, labels map[string]string) string {
+	if primary, ok := labels[PrimaryKey]; ok {
 		primary = strings.TrimPrefix(primary, PrimaryLabelPrefix)
-		return fmt.Sprintf("%s/cryptoKeyVersions/%s", key, primary), nil
+		return fmt.Sprintf("%s/cryptoKeyVersions/%s", key, primary)
 	}
 	// no primary found
-	return "", nil
+	return ""
+}
+
+// CheckPrimary checks that the KMS key has a primary version, and that the
+// primary version is enabled, so it can sign.
+func CheckPrimary(ctx context.Context, kms *kms.KeyManagementClient, key string) error {
+	return CheckPrimaryVersion(ctx, NewKMSKeyManager(kms), key)
 }
 
 // SetPrimary sets the key version name as primary in the key labels.
@@ -97,6 +114,34 @@ func getLabelValue(versionName string) (string, error) {
 	return versionValue, nil
 }
 
+// KeyAudiences returns the audiences in the labels of the keys, keyed by key.
+// Keys without audience labels are omitted.
+func KeyAudiences(ctx context.Context, client *kms.KeyManagementClient, keys []string) (map[string][]string, error) {
+	audiences := make(map[string][]string)
+	for _, key := range keys {
+		response, err := client.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: key})
+		if err != nil {
+			return nil, fmt.Errorf("issue while getting key from KMS: %w", err)
+		}
+		if auds := audiencesFromLabels(response.GetLabels()); len(auds) > 0 {
+			audiences[key] = auds
+		}
+	}
+	return audiences, nil
+}
+
+// audiencesFromLabels returns the sorted audiences in the key labels.
+func audiencesFromLabels(labels map[string]string) []string {
+	var audiences []string
+	for label := range labels {
+		if aud, ok := strings.CutPrefix(label, AudienceLabelPrefix); ok && aud != "" {
+			audiences = append(audiences, aud)
+		}
+	}
+	sort.Strings(audiences)
+	return audiences
+}
+
 // CryptoKeyVersionsFor returns the list of cryptoKeyVersions for all the given
 // parent keys.
 func CryptoKeyVersionsFor(ctx context.Context, client *kms.KeyManagementClient, parentKeys []string) ([]string, error) {
@@ -0,0 +1,96 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"testing"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/abcxyz/jvs/pkg/testutil"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestCheckPrimary(t *testing.T) {
+	t.Parallel()
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+
+	cases := []struct {
+		name         string
+		labels       map[string]string
+		versionState kmspb.CryptoKeyVersion_CryptoKeyVersionState
+		kmsErr       error
+		wantErr      string
+	}{
+		{
+			name:   "enabled",
+			labels: map[string]string{PrimaryKey: PrimaryLabelPrefix + "1"},
+		},
+		{
+			name:    "no_primary",
+			wantErr: "has no primary version",
+		},
+		{
+			name:         "disabled",
+			labels:       map[string]string{PrimaryKey: PrimaryLabelPrefix + "1"},
+			versionState: kmspb.CryptoKeyVersion_DISABLED,
+			wantErr:      "primary version " + key + "/cryptoKeyVersions/1 is DISABLED, not ENABLED",
+		},
+		{
+			name:    "kms_error",
+			labels:  map[string]string{PrimaryKey: PrimaryLabelPrefix + "1"},
+			kmsErr:  status.Error(codes.PermissionDenied, "denied"),
+			wantErr: "issue while getting key from KMS",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+
+			mockKMSServer := &testutil.MockKeyManagementServer{
+				KeyName:      key,
+				Labels:       tc.labels,
+				VersionState: tc.versionState,
+				Err:          tc.kmsErr,
+			}
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKMSServer)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			kmsClient, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = CheckPrimary(ctx, kmsClient, key)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// LoadSigningKey loads the PEM encoded ECDSA P-256 private key at the path, for
+// signing tokens without KMS. It returns the key and its key ID, which is the
+// JWK thumbprint of its public key.
+func LoadSigningKey(path string) (crypto.Signer, string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	key, err := parsePEMKey(b)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	signer, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, "", fmt.Errorf("signing key %s is not a private key", path)
+	}
+
+	kid, err := LocalKeyID(signer.Public())
+	if err != nil {
+		return nil, "", err
+	}
+	return signer, kid, nil
+}
+
+// GenerateSigningKey writes a new PEM encoded ECDSA P-256 private key to the
+// path, readable only by the owner. It fails if the file already exists.
+func GenerateSigningKey(path string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signing key: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	return nil
+}
+
+// LoadPublicKeys loads the PEM encoded ECDSA P-256 keys at the paths, keyed by
+// their key ID. Each file can hold a public or a private key, so the signing
+// keys of the API can be served as is.
+func LoadPublicKeys(paths []string) (map[string]crypto.PublicKey, error) {
+	publicKeys := make(map[string]crypto.PublicKey, len(paths))
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+
+		key, err := parsePEMKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		pub := key
+		if priv, ok := key.(*ecdsa.PrivateKey); ok {
+			pub = priv.Public()
+		}
+
+		kid, err := LocalKeyID(pub)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys[kid] = pub
+	}
+	return publicKeys, nil
+}
+
+// LocalKeyID returns the key ID of a local key, the base64url-encoded SHA-256
+// JWK thumbprint of its public key.
+func LocalKeyID(pub crypto.PublicKey) (string, error) {
+	key, err := jwk.FromRaw(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to create jwk from public key: %w", err)
+	}
+	kid, err := jvspb.JWKThumbprint(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute key id: %w", err)
+	}
+	return kid, nil
+}
+
+// parsePEMKey parses the first PEM block of b as an ECDSA P-256 public or
+// private key, since tokens are signed with ES256.
+func parsePEMKey(b []byte) (any, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no pem block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", block.Type, err)
+	}
+
+	var curve elliptic.Curve
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		curve = k.Curve
+	case *ecdsa.PublicKey:
+		curve = k.Curve
+	default:
+		return nil, fmt.Errorf("key must be an ecdsa key, got %T", key)
+	}
+	if curve != elliptic.P256() {
+		return nil, fmt.Errorf("key must be on the P-256 curve, got %s", curve.Params().Name)
+	}
+	return key, nil
+}
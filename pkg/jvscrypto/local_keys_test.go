@@ -0,0 +1,250 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+func TestLoadSigningKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKID, err := LocalKeyID(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec1, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Bytes, err := x509.MarshalECPrivateKey(p384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaBytes, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		pem     []byte
+		wantErr string
+	}{
+		{
+			name: "ec_private_key",
+			pem:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}),
+		},
+		{
+			name: "pkcs8_private_key",
+			pem:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			name:    "public_key",
+			pem:     pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}),
+			wantErr: "is not a private key",
+		},
+		{
+			name:    "wrong_curve",
+			pem:     pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p384Bytes}),
+			wantErr: "key must be on the P-256 curve, got P-384",
+		},
+		{
+			name:    "rsa_key",
+			pem:     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaBytes}),
+			wantErr: "key must be an ecdsa key",
+		},
+		{
+			name:    "not_pem",
+			pem:     []byte("not a key"),
+			wantErr: "no pem block found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "key.pem")
+			if err := os.WriteFile(path, tc.pem, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			signer, kid, err := LoadSigningKey(path)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+
+			if !key.Equal(signer) {
+				t.Errorf("expected the loaded key to be the written key")
+			}
+			if kid != wantKID {
+				t.Errorf("kid: expected %q to be %q", kid, wantKID)
+			}
+		})
+	}
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "signing-key.pem")
+	if err := GenerateSigningKey(path); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.Mode().Perm(), os.FileMode(0o600); got != want {
+		t.Errorf("expected mode %s to be %s", got, want)
+	}
+	if _, _, err := LoadSigningKey(path); err != nil {
+		t.Errorf("failed to load generated key: %v", err)
+	}
+
+	// Existing keys are never overwritten.
+	err = GenerateSigningKey(path)
+	if diff := pkgtestutil.DiffErrString(err, "file exists"); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestKeyServer_localKeys(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	dir := t.TempDir()
+	signingKey, signingKeyPath := writeTestKey(t, dir, "signing-key.pem", true)
+	oldKey, oldKeyPath := writeTestKey(t, dir, "old-key.pem", false)
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:       config.SignerLocal,
+		KeyPaths:     []string{signingKeyPath, oldKeyPath},
+		CacheTimeout: 5 * time.Minute,
+	}, h).WithLocalKeys([]string{signingKeyPath, oldKeyPath})
+
+	if err := keyServer.Ready(ctx); err != nil {
+		t.Fatalf("expected local keys to be ready: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	keyServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	set, err := jwk.Parse(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := set.Len(), 2; got != want {
+		t.Fatalf("expected %d keys to be %d", got, want)
+	}
+	for _, pub := range []*ecdsa.PublicKey{&signingKey.PublicKey, &oldKey.PublicKey} {
+		kid, err := LocalKeyID(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, ok := set.LookupKeyID(kid)
+		if !ok {
+			t.Fatalf("expected key %q in the jwks", kid)
+		}
+		var got ecdsa.PublicKey
+		if err := key.Raw(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(&got) {
+			t.Errorf("expected key %q to be the public key on disk", kid)
+		}
+	}
+}
+
+// writeTestKey writes a new P-256 key to the directory, as a private key if
+// private is true, or as a public key otherwise.
+func writeTestKey(tb testing.TB, dir, name string, private bool) (*ecdsa.PrivateKey, string) {
+	tb.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	block := &pem.Block{Type: "EC PRIVATE KEY"}
+	if private {
+		block.Bytes, err = x509.MarshalECPrivateKey(key)
+	} else {
+		block.Type = "PUBLIC KEY"
+		block.Bytes, err = x509.MarshalPKIXPublicKey(key.Public())
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	return key, path
+}
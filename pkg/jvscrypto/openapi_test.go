@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jvscrypto
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	t.Parallel()
+
+	var spec struct {
+		OpenAPI    string                     `json:"openapi"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(OpenAPISpec, &spec); err != nil {
+		t.Fatalf("failed to parse spec: %v", err)
+	}
+
+	if got, want := spec.OpenAPI, "3.0.3"; got != want {
+		t.Errorf("expected openapi version %q to be %q", got, want)
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	wantPaths := []string{"/.well-known/jwks", "/introspect", "/key-usage", "/keys/{kid}", "/revocations"}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("paths (-want, +got):\n%s", diff)
+	}
+
+	// The schemas must match the JSON encoding of the types served.
+	for name, v := range map[string]any{
+		"KeyUsage":       KeyUsage{},
+		"KeyUsageReport": jvspb.KeyUsageReport{},
+		"Revocation":     jvspb.Revocation{},
+		"RevocationList": jvspb.RevocationList{},
+	} {
+		var want []string
+		typ := reflect.TypeOf(v)
+		for i := range typ.NumField() {
+			tag, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			want = append(want, tag)
+		}
+		slices.Sort(want)
+
+		var got []string
+		for p := range spec.Components.Schemas[name].Properties {
+			got = append(got, p)
+		}
+		slices.Sort(got)
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("properties of schema %s (-want, +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	HandleOpenAPI().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Errorf("expected status %d to be %d", got, want)
+	}
+	if got, want := w.Header().Get("content-type"), "application/json"; got != want {
+		t.Errorf("expected content type %q to be %q", got, want)
+	}
+	if got := w.Body.Bytes(); !slices.Equal(got, OpenAPISpec) {
+		t.Errorf("expected body to be the spec, got %q", got)
+	}
+}
@@ -23,19 +23,21 @@ import (
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"cloud.google.com/go/kms/apiv1/kmspb"
-	"github.com/sethvargo/go-retry"
-	"google.golang.org/api/iterator"
-	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/events"
 	"github.com/abcxyz/pkg/logging"
 )
 
 // RotationHandler handles all necessary rotation actions for asymmetric keys
 // based off a provided configuration.
 type RotationHandler struct {
-	kmsClient *kms.KeyManagementClient
-	config    *config.CertRotationConfig
+	keys   KeyManager
+	config *config.CertRotationConfig
+
+	// publisher publishes key rotated, disabled and destroyed events. If nil,
+	// events are not published.
+	publisher events.Publisher
 }
 
 // NewRotationHandler creates a handler for rotating keys.
@@ -45,9 +47,38 @@ func NewRotationHandler(ctx context.Context, kmsClient *kms.KeyManagementClient,
 	}
 
 	return &RotationHandler{
-		kmsClient: kmsClient,
-		config:    cfg,
+		keys: &kmsKeyManager{
+			client:          kmsClient,
+			versionTemplate: versionTemplate(cfg),
+		},
+		config: cfg,
+	}
+}
+
+// versionTemplate returns the version template new versions are created with
+// for the config, or nil to use the key's.
+func versionTemplate(cfg *config.CertRotationConfig) *kmspb.CryptoKeyVersionTemplate {
+	if cfg.KeyAlgorithm == "" && cfg.KeyProtectionLevel == "" {
+		return nil
 	}
+	return &kmspb.CryptoKeyVersionTemplate{
+		Algorithm: kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm(
+			kmspb.CryptoKeyVersion_CryptoKeyVersionAlgorithm_value[cfg.KeyAlgorithm]),
+		ProtectionLevel: kmspb.ProtectionLevel(kmspb.ProtectionLevel_value[cfg.KeyProtectionLevel]),
+	}
+}
+
+// WithKeyManager rotates the keys in the key manager instead of Cloud KMS.
+func (h *RotationHandler) WithKeyManager(m KeyManager) *RotationHandler {
+	h.keys = m
+	return h
+}
+
+// WithEventPublisher publishes an event for every key version that is
+// promoted to primary, disabled or destroyed.
+func (h *RotationHandler) WithEventPublisher(publisher events.Publisher) *RotationHandler {
+	h.publisher = publisher
+	return h
 }
 
 // RotateKeys rotates all keys.
@@ -66,32 +97,35 @@ func (h *RotationHandler) RotateKeys(ctx context.Context) (merr error) {
 	return
 }
 
+// Ready returns an error if any of the keys can't be read from the key
+// manager. Unlike the signing services, it doesn't require a primary version,
+// since rotation is what creates the first one.
+func (h *RotationHandler) Ready(ctx context.Context) error {
+	for _, key := range h.config.KeyNames {
+		if _, err := h.keys.GetPrimary(ctx, key); err != nil {
+			return fmt.Errorf("failed to get key %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
 // RotateKey is called to determine and perform rotation actions on versions for a key.
 // key is the full resource name: `projects/*/locations/*/keyRings/*/cryptoKeys/*`
 // https://pkg.go.dev/google.golang.org/genproto/googleapis/cloud/kms/v1#CryptoKey
 func (h *RotationHandler) RotateKey(ctx context.Context, key string) error {
 	curTime := time.Now().UTC()
-	// Create the request to list Keys.
-	listKeysReq := &kmspb.ListCryptoKeyVersionsRequest{
-		Parent: key,
+	// List the Key Versions in the Key
+	vers, err := h.keys.ListVersions(ctx, key)
+	if err != nil {
+		return err
 	}
 
-	// List the Key Versions in the Key
-	it := h.kmsClient.ListCryptoKeyVersions(ctx, listKeysReq)
-	vers := make([]*kmspb.CryptoKeyVersion, 0)
-	for {
-		ver, err := it.Next()
-		if errors.Is(err, iterator.Done) {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("err while reading crypto key version list: %w", err)
-		}
-		vers = append(vers, ver)
+	if err := h.verifyDestroySchedule(ctx, key); err != nil {
+		return fmt.Errorf("failed to verify destroy schedule: %w", err)
 	}
 
 	// Get any relevant Key Version information from the StateStore
-	primaryName, err := GetPrimary(ctx, h.kmsClient, key)
+	primaryName, err := h.keys.GetPrimary(ctx, key)
 	if err != nil {
 		return fmt.Errorf("failed to determine primary: %w", err)
 	}
@@ -106,6 +140,28 @@ func (h *RotationHandler) RotateKey(ctx context.Context, key string) error {
 	return nil
 }
 
+// verifyDestroySchedule checks that the key schedules destroyed versions for
+// destruction for as long as the config expects, so versions are destroyed on
+// time. It is a no-op if the config doesn't set a destroy scheduled duration.
+func (h *RotationHandler) verifyDestroySchedule(ctx context.Context, key string) error {
+	want := h.config.DestroyScheduledDuration
+	if want <= 0 {
+		return nil
+	}
+	scheduler, ok := h.keys.(DestroyScheduler)
+	if !ok {
+		return fmt.Errorf("key manager does not schedule destruction")
+	}
+	got, err := scheduler.DestroyScheduledDuration(ctx, key)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("key %s has a destroy scheduled duration of %s, expected %s", key, got, want)
+	}
+	return nil
+}
+
 type Action int8
 
 const (
@@ -121,6 +177,24 @@ type actionTuple struct {
 	Version *kmspb.CryptoKeyVersion
 }
 
+// String describes the step the action takes.
+func (a *actionTuple) String() string {
+	switch a.Action {
+	case ActionCreateNew:
+		return "create new version"
+	case ActionCreateNewAndPromote:
+		return "create new version and promote to primary"
+	case ActionPromote:
+		return "promote " + a.Version.GetName() + " to primary"
+	case ActionDisable:
+		return "disable " + a.Version.GetName()
+	case ActionDestroy:
+		return "destroy " + a.Version.GetName()
+	default:
+		return fmt.Sprintf("unknown action %d", a.Action)
+	}
+}
+
 func (h *RotationHandler) determineActions(ctx context.Context, vers []*kmspb.CryptoKeyVersion, primaryName string, curTime time.Time) ([]*actionTuple, error) {
 	logger := logging.FromContext(ctx)
 	var primary *kmspb.CryptoKeyVersion
@@ -158,6 +232,9 @@ func (h *RotationHandler) determineActions(ctx context.Context, vers []*kmspb.Cr
 	newActions := h.actionsForNewVersions(ctx, newerVers, primary, curTime)
 	actions = append(actions, newActions...)
 
+	orphanActions := h.actionsForOrphanedVersions(ctx, newerVers, curTime)
+	actions = append(actions, orphanActions...)
+
 	return actions, nil
 }
 
@@ -207,6 +284,40 @@ func newestEnabledVer(vers []*kmspb.CryptoKeyVersion) *kmspb.CryptoKeyVersion {
 	return newest
 }
 
+// OrphanedVersionLogMessage is the message of the log entry for each orphaned
+// key version, for log-based metrics and alerts.
+const OrphanedVersionLogMessage = "orphaned key version"
+
+// Determine actions for orphaned versions: enabled versions newer than the
+// primary, other than the newest one that's up for promotion, that are older
+// than the destroy age. They were never promoted, e.g. because they were
+// created by a failed run, and would otherwise stay enabled forever. They are
+// logged, and disabled if the config says so.
+func (h *RotationHandler) actionsForOrphanedVersions(ctx context.Context, vers []*kmspb.CryptoKeyVersion, curTime time.Time) []*actionTuple {
+	logger := logging.FromContext(ctx)
+	actions := make([]*actionTuple, 0)
+	newest := newestEnabledVer(vers)
+	cutoff := curTime.Add(-h.config.DestroyAge())
+
+	for _, ver := range vers {
+		if ver == newest || ver.GetState() != kmspb.CryptoKeyVersion_ENABLED {
+			continue
+		}
+		if !ver.GetCreateTime().AsTime().Before(cutoff) {
+			continue
+		}
+		logger.WarnContext(ctx, OrphanedVersionLogMessage,
+			"version", ver.GetName(),
+			"create_time", ver.GetCreateTime().AsTime(),
+			"cutoff", cutoff,
+			"disable", h.config.DisableOrphanedVersions)
+		if h.config.DisableOrphanedVersions {
+			actions = append(actions, &actionTuple{ActionDisable, ver})
+		}
+	}
+	return actions
+}
+
 // Determine actions for disabled versions.
 func (h *RotationHandler) actionsForOlderVersions(ctx context.Context, vers []*kmspb.CryptoKeyVersion, curTime time.Time) []*actionTuple {
 	logger := logging.FromContext(ctx)
@@ -223,6 +334,10 @@ func (h *RotationHandler) actionsForOlderVersions(ctx context.Context, vers []*k
 			if h.shouldDestroy(ctx, ver, curTime) {
 				actions = append(actions, &actionTuple{ActionDestroy, ver})
 			}
+		case kmspb.CryptoKeyVersion_DESTROY_SCHEDULED:
+			logger.InfoContext(ctx, "key version scheduled for destruction",
+				"version", ver,
+				"destroy_time", ver.GetDestroyTime().AsTime())
 		default:
 			logger.InfoContext(ctx, "no action needed for key version in current state",
 				"version", ver,
@@ -234,7 +349,9 @@ func (h *RotationHandler) actionsForOlderVersions(ctx context.Context, vers []*k
 
 func (h *RotationHandler) shouldDestroy(ctx context.Context, ver *kmspb.CryptoKeyVersion, curTime time.Time) bool {
 	logger := logging.FromContext(ctx)
-	cutoff := curTime.Add(-h.config.DestroyAge())
+	// With KMS, the key material is only destroyed after the destroy schedule,
+	// so account for it if configured.
+	cutoff := curTime.Add(-h.config.DestroyRequestAge())
 	shouldDestroy := ver.GetCreateTime().AsTime().Before(cutoff)
 	if shouldDestroy {
 		logger.InfoContext(ctx, "version created before cutoff date, should destroy",
@@ -324,107 +441,62 @@ func (h *RotationHandler) performActions(ctx context.Context, keyName string, ac
 	for _, action := range actions {
 		switch action.Action {
 		case ActionCreateNew:
-			_, err := h.performCreateNew(ctx, keyName)
+			_, err := h.keys.CreateVersion(ctx, keyName)
 			if err != nil {
 				merr = errors.Join(merr, err)
 			}
 		case ActionPromote:
-			if err := SetPrimary(ctx, h.kmsClient, keyName, action.Version.GetName()); err != nil {
+			if err := h.keys.SetPrimary(ctx, keyName, action.Version.GetName()); err != nil {
 				merr = errors.Join(merr, err)
+				continue
 			}
+			h.publish(ctx, events.TypeKeyRotated, keyName, action.Version.GetName())
 		case ActionCreateNewAndPromote:
-			newVer, err := h.performCreateNew(ctx, keyName)
+			newVer, err := h.keys.CreateVersion(ctx, keyName)
 			if err != nil {
 				merr = errors.Join(merr, err)
 				continue
 			}
 			logger.InfoContext(ctx, "promoting immediately")
-			if err := SetPrimary(ctx, h.kmsClient, keyName, newVer.GetName()); err != nil {
+			if err := h.keys.SetPrimary(ctx, keyName, newVer.GetName()); err != nil {
 				merr = errors.Join(merr, err)
+				continue
 			}
+			h.publish(ctx, events.TypeKeyRotated, keyName, newVer.GetName())
 		case ActionDisable:
-			if err := h.performDisable(ctx, action.Version); err != nil {
+			if err := h.keys.DisableVersion(ctx, action.Version); err != nil {
 				merr = errors.Join(merr, err)
 				continue
 			}
+			h.publish(ctx, events.TypeKeyDisabled, keyName, action.Version.GetName())
 		case ActionDestroy:
-			if err := h.performDestroy(ctx, action.Version); err != nil {
+			if err := h.keys.DestroyVersion(ctx, action.Version); err != nil {
 				merr = errors.Join(merr, err)
+				continue
 			}
+			h.publish(ctx, events.TypeKeyDestroyed, keyName, action.Version.GetName())
 		}
 	}
 
 	return
 }
 
-func (h *RotationHandler) performDisable(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
-	logger := logging.FromContext(ctx)
-
-	// Make a copy to modify
-	newVerState := ver
-
-	logger.InfoContext(ctx, "disabling key version", "version_name", ver.GetName())
-	newVerState.State = kmspb.CryptoKeyVersion_DISABLED
-	var messageType *kmspb.CryptoKeyVersion
-	mask, err := fieldmaskpb.New(messageType, "state")
-	if err != nil {
-		return fmt.Errorf("failed to create fieldmask: %w", err)
-	}
-	updateReq := &kmspb.UpdateCryptoKeyVersionRequest{
-		CryptoKeyVersion: newVerState,
-		UpdateMask:       mask,
-	}
-	if _, err := h.kmsClient.UpdateCryptoKeyVersion(ctx, updateReq); err != nil {
-		return fmt.Errorf("key disable failed: %w", err)
-	}
-	return nil
-}
-
-func (h *RotationHandler) performDestroy(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
-	logger := logging.FromContext(ctx)
-	logger.InfoContext(ctx, "destroying key version", "version_name", ver.GetName())
-	destroyReq := &kmspb.DestroyCryptoKeyVersionRequest{
-		Name: ver.GetName(),
-	}
-	if _, err := h.kmsClient.DestroyCryptoKeyVersion(ctx, destroyReq); err != nil {
-		return fmt.Errorf("key destroy failed: %w", err)
-	}
-	return nil
-}
-
-func (h *RotationHandler) performCreateNew(ctx context.Context, keyName string) (*kmspb.CryptoKeyVersion, error) {
-	logger := logging.FromContext(ctx)
-	logger.InfoContext(ctx, "creating new key version")
-
-	createReq := &kmspb.CreateCryptoKeyVersionRequest{
-		Parent:           keyName,
-		CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
-	}
-	resp, err := h.kmsClient.CreateCryptoKeyVersion(ctx, createReq)
-	if err != nil {
-		return nil, fmt.Errorf("key creation failed: %w", err)
-	}
-
-	// Wait for a key version to be created and enabled.
-	var ckv *kmspb.CryptoKeyVersion
-	b := retry.WithMaxRetries(5, retry.NewFibonacci(500*time.Millisecond))
-	if err := retry.Do(ctx, b, func(ctx context.Context) error {
-		var err error
-		ckv, err = h.kmsClient.GetCryptoKeyVersion(ctx, &kmspb.GetCryptoKeyVersionRequest{
-			Name: resp.GetName(),
-		})
-		if err != nil {
-			return fmt.Errorf("failed to get crypto key version: %w", err)
-		}
-		if got, want := ckv.GetState(), kmspb.CryptoKeyVersion_ENABLED; got != want {
-			return retry.RetryableError(fmt.Errorf("expected %s to be %s", got.String(), want.String()))
-		}
-		return nil
-	}); err != nil {
-		return nil, fmt.Errorf("key did not enter ready state: %w", err)
+// publish publishes a key event for the version, if there is a publisher.
+// Failing to publish is logged, but does not fail the action.
+func (h *RotationHandler) publish(ctx context.Context, typ, keyName, version string) {
+	if h.publisher == nil {
+		return
+	}
+	event := events.New(typ, version, &events.KeyVersion{
+		Key:     keyName,
+		Version: version,
+	})
+	if err := h.publisher.Publish(ctx, event); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to publish event",
+			"type", typ,
+			"version", version,
+			"error", err)
 	}
-
-	return ckv, nil
 }
 
 // GetKeyNameFromVersion converts a key version name to a key name.
@@ -29,10 +29,12 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/events"
 	"github.com/abcxyz/jvs/pkg/testutil"
 	"github.com/abcxyz/pkg/logging"
 	pkgtestutil "github.com/abcxyz/pkg/testutil"
@@ -297,6 +299,369 @@ func TestDetermineActions(t *testing.T) {
 	}
 }
 
+func TestDetermineActions_orphanedVersions(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cfg := &config.CertRotationConfig{
+		KeyTTL:           240 * time.Hour, // 10 days
+		GracePeriod:      60 * time.Minute,
+		DisabledPeriod:   480 * time.Hour, // 20 days
+		PropagationDelay: 12 * time.Hour,
+	}
+
+	curTime := time.Unix(100*60*60*24, 0) // 100 days after start
+
+	primaryKey := &kmspb.CryptoKeyVersion{
+		CreateTime: &timestamppb.Timestamp{Seconds: 40 * 60 * 60 * 24}, // 60 days old
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		Name:       "primaryKey",
+	}
+	orphanedKey := &kmspb.CryptoKeyVersion{
+		CreateTime: &timestamppb.Timestamp{Seconds: 55 * 60 * 60 * 24}, // 45 days old
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		Name:       "orphanedKey",
+	}
+	recentKey := &kmspb.CryptoKeyVersion{
+		CreateTime: &timestamppb.Timestamp{Seconds: 80 * 60 * 60 * 24}, // 20 days old
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		Name:       "recentKey",
+	}
+	newKey := &kmspb.CryptoKeyVersion{
+		CreateTime: &timestamppb.Timestamp{Seconds: 99 * 60 * 60 * 24}, // 1 day old
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		Name:       "newKey",
+	}
+
+	cases := []struct {
+		name        string
+		disable     bool
+		versions    []*kmspb.CryptoKeyVersion
+		primary     string
+		wantActions []*actionTuple
+	}{
+		{
+			name:     "orphan_logged",
+			versions: []*kmspb.CryptoKeyVersion{primaryKey, orphanedKey, newKey},
+			primary:  primaryKey.GetName(),
+			wantActions: []*actionTuple{
+				{ActionPromote, newKey},
+			},
+		},
+		{
+			name:     "orphan_disabled",
+			disable:  true,
+			versions: []*kmspb.CryptoKeyVersion{primaryKey, orphanedKey, newKey},
+			primary:  primaryKey.GetName(),
+			wantActions: []*actionTuple{
+				{ActionPromote, newKey},
+				{ActionDisable, orphanedKey},
+			},
+		},
+		{
+			name:     "orphan_without_primary_disabled",
+			disable:  true,
+			versions: []*kmspb.CryptoKeyVersion{orphanedKey, newKey},
+			wantActions: []*actionTuple{
+				{ActionPromote, newKey},
+				{ActionDisable, orphanedKey},
+			},
+		},
+		{
+			name:     "newest_not_orphaned",
+			disable:  true,
+			versions: []*kmspb.CryptoKeyVersion{primaryKey, orphanedKey},
+			primary:  primaryKey.GetName(),
+			wantActions: []*actionTuple{
+				{ActionPromote, orphanedKey},
+			},
+		},
+		{
+			name:     "younger_than_destroy_age",
+			disable:  true,
+			versions: []*kmspb.CryptoKeyVersion{primaryKey, recentKey, newKey},
+			primary:  primaryKey.GetName(),
+			wantActions: []*actionTuple{
+				{ActionPromote, newKey},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := *cfg
+			cfg.DisableOrphanedVersions = tc.disable
+			handler := NewRotationHandler(ctx, nil, &cfg)
+
+			output, err := handler.determineActions(ctx, tc.versions, tc.primary, curTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantActions, output, protocmp.Transform()); diff != "" {
+				t.Errorf("Got diff (-want, +got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestDetermineActions_destroyScheduledDuration(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	curTime := time.Unix(100*60*60*24, 0) // 100 days after start
+
+	primaryKey := &kmspb.CryptoKeyVersion{
+		CreateTime: &timestamppb.Timestamp{Seconds: 99 * 60 * 60 * 24}, // 1 day old
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		Name:       "primaryKey",
+	}
+	disabledKey := &kmspb.CryptoKeyVersion{
+		CreateTime: &timestamppb.Timestamp{Seconds: 73 * 60 * 60 * 24}, // 27 days old
+		State:      kmspb.CryptoKeyVersion_DISABLED,
+		Name:       "disabledKey",
+	}
+	scheduledKey := &kmspb.CryptoKeyVersion{
+		CreateTime:  &timestamppb.Timestamp{Seconds: 60 * 60 * 60 * 24}, // 40 days old
+		State:       kmspb.CryptoKeyVersion_DESTROY_SCHEDULED,
+		Name:        "scheduledKey",
+		DestroyTime: &timestamppb.Timestamp{Seconds: 101 * 60 * 60 * 24},
+	}
+
+	cases := []struct {
+		name        string
+		scheduled   time.Duration
+		wantActions []*actionTuple
+	}{
+		{
+			name:        "not_configured",
+			wantActions: []*actionTuple{},
+		},
+		{
+			// Destroyed at 25 days, so the key material is destroyed at 30 days.
+			name:      "configured",
+			scheduled: 5 * 24 * time.Hour,
+			wantActions: []*actionTuple{
+				{ActionDestroy, disabledKey},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewRotationHandler(ctx, nil, &config.CertRotationConfig{
+				KeyTTL:                   240 * time.Hour, // 10 days
+				GracePeriod:              60 * time.Minute,
+				DisabledPeriod:           480 * time.Hour, // 20 days
+				PropagationDelay:         12 * time.Hour,
+				DestroyScheduledDuration: tc.scheduled,
+			})
+
+			versions := []*kmspb.CryptoKeyVersion{primaryKey, disabledKey, scheduledKey}
+			output, err := handler.determineActions(ctx, versions, primaryKey.GetName(), curTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantActions, output, protocmp.Transform()); diff != "" {
+				t.Errorf("Got diff (-want, +got): %v", diff)
+			}
+		})
+	}
+}
+
+func TestVerifyDestroySchedule(t *testing.T) {
+	t.Parallel()
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+
+	cases := []struct {
+		name         string
+		want         time.Duration
+		keyScheduled *durationpb.Duration
+		wantRequests int
+		wantErr      string
+	}{
+		{
+			name: "not_configured",
+		},
+		{
+			name:         "matches",
+			want:         24 * time.Hour,
+			keyScheduled: durationpb.New(24 * time.Hour),
+			wantRequests: 1,
+		},
+		{
+			name:         "default",
+			want:         DefaultDestroyScheduledDuration,
+			wantRequests: 1,
+		},
+		{
+			name:         "mismatch",
+			want:         24 * time.Hour,
+			keyScheduled: durationpb.New(48 * time.Hour),
+			wantRequests: 1,
+			wantErr:      "has a destroy scheduled duration of 48h0m0s, expected 24h0m0s",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			mockKMS := testutil.NewMockKeyManagementServer(key, key+"/cryptoKeyVersions/1", "")
+			mockKMS.DestroyScheduledDuration = tc.keyScheduled
+
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKMS)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := NewRotationHandler(ctx, c, &config.CertRotationConfig{
+				DestroyScheduledDuration: tc.want,
+			})
+			err = handler.verifyDestroySchedule(ctx, key)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if got, want := len(mockKMS.Reqs), tc.wantRequests; got != want {
+				t.Errorf("expected %d kms requests to be %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPerformActions_versionTemplate(t *testing.T) {
+	t.Parallel()
+
+	key := "projects/[PROJECT]/locations/[LOCATION]/keyRings/[KEY_RING]/cryptoKeys/[CRYPTO_KEY]"
+	versionName := key + "/cryptoKeyVersions/[VERSION]"
+
+	softwareTemplate := &kmspb.CryptoKeyVersionTemplate{
+		Algorithm:       kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256,
+		ProtectionLevel: kmspb.ProtectionLevel_SOFTWARE,
+	}
+	hsmTemplate := &kmspb.CryptoKeyVersionTemplate{
+		Algorithm:       kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256,
+		ProtectionLevel: kmspb.ProtectionLevel_HSM,
+	}
+
+	cases := []struct {
+		name             string
+		cfg              *config.CertRotationConfig
+		expectedRequests []proto.Message
+		expectedTemplate *kmspb.CryptoKeyVersionTemplate
+	}{
+		{
+			name: "key_template",
+			cfg:  &config.CertRotationConfig{},
+			expectedRequests: []proto.Message{
+				&kmspb.CreateCryptoKeyVersionRequest{
+					Parent:           key,
+					CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
+				},
+				&kmspb.GetCryptoKeyVersionRequest{
+					Name: versionName + "-new",
+				},
+			},
+			expectedTemplate: softwareTemplate,
+		},
+		{
+			name: "updates_template",
+			cfg: &config.CertRotationConfig{
+				KeyProtectionLevel: config.ProtectionLevelHSM,
+			},
+			expectedRequests: []proto.Message{
+				&kmspb.GetCryptoKeyRequest{
+					Name: key,
+				},
+				&kmspb.UpdateCryptoKeyRequest{
+					CryptoKey: &kmspb.CryptoKey{
+						Name:            key,
+						VersionTemplate: hsmTemplate,
+					},
+					UpdateMask: &fieldmaskpb.FieldMask{
+						Paths: []string{"version_template"},
+					},
+				},
+				&kmspb.CreateCryptoKeyVersionRequest{
+					Parent:           key,
+					CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
+				},
+				&kmspb.GetCryptoKeyVersionRequest{
+					Name: versionName + "-new",
+				},
+			},
+			expectedTemplate: hsmTemplate,
+		},
+		{
+			name: "template_matches",
+			cfg: &config.CertRotationConfig{
+				KeyAlgorithm:       config.KeyAlgorithmECP256,
+				KeyProtectionLevel: config.ProtectionLevelSoftware,
+			},
+			expectedRequests: []proto.Message{
+				&kmspb.GetCryptoKeyRequest{
+					Name: key,
+				},
+				&kmspb.CreateCryptoKeyVersionRequest{
+					Parent:           key,
+					CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
+				},
+				&kmspb.GetCryptoKeyVersionRequest{
+					Name: versionName + "-new",
+				},
+			},
+			expectedTemplate: softwareTemplate,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			mockKMS := testutil.NewMockKeyManagementServer(key, versionName, "")
+			mockKMS.VersionTemplate = softwareTemplate
+
+			_, conn := pkgtestutil.FakeGRPCServer(t, func(s *grpc.Server) {
+				kmspb.RegisterKeyManagementServiceServer(s, mockKMS)
+			})
+			t.Cleanup(func() {
+				conn.Close()
+			})
+
+			c, err := kms.NewKeyManagementClient(ctx, option.WithGRPCConn(conn))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := NewRotationHandler(ctx, c, tc.cfg)
+			if err := handler.performActions(ctx, key, []*actionTuple{{ActionCreateNew, nil}}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expectedRequests, mockKMS.Reqs, protocmp.Transform()); diff != "" {
+				t.Errorf("wrong requests: diff (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tc.expectedTemplate, mockKMS.VersionTemplate, protocmp.Transform()); diff != "" {
+				t.Errorf("wrong version template: diff (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
 func TestPerformActions(t *testing.T) {
 	t.Parallel()
 
@@ -310,6 +675,7 @@ func TestPerformActions(t *testing.T) {
 		priorPrimary     string
 		expectedRequests []proto.Message
 		expectedPrimary  string
+		expectedEvents   []string
 		wantErr          string
 		serverErr        error
 	}{
@@ -336,6 +702,7 @@ func TestPerformActions(t *testing.T) {
 					},
 				},
 			},
+			expectedEvents: []string{events.TypeKeyDisabled + " " + versionName},
 		},
 		{
 			name: "create_new_and_promote",
@@ -371,6 +738,7 @@ func TestPerformActions(t *testing.T) {
 				},
 			},
 			expectedPrimary: PrimaryLabelPrefix + versionSuffix + "-new",
+			expectedEvents:  []string{events.TypeKeyRotated + " " + versionName + "-new"},
 		},
 		{
 			name: "create_new_and_promote_with_failure",
@@ -411,6 +779,7 @@ func TestPerformActions(t *testing.T) {
 					Name: versionName,
 				},
 			},
+			expectedEvents: []string{events.TypeKeyDestroyed + " " + versionName},
 		},
 		{
 			name: "multi_action",
@@ -439,6 +808,7 @@ func TestPerformActions(t *testing.T) {
 					Name: versionName + "2",
 				},
 			},
+			expectedEvents: []string{events.TypeKeyDestroyed + " " + versionName + "2"},
 		},
 		{
 			name: "test_err",
@@ -487,7 +857,8 @@ func TestPerformActions(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			handler := NewRotationHandler(ctx, c, nil)
+			publisher := &fakePublisher{}
+			handler := NewRotationHandler(ctx, c, nil).WithEventPublisher(publisher)
 
 			gotErr := handler.performActions(ctx, parent, tc.actions)
 			if diff := pkgtestutil.DiffErrString(gotErr, tc.wantErr); diff != "" {
@@ -499,6 +870,24 @@ func TestPerformActions(t *testing.T) {
 			if diff := cmp.Diff(tc.expectedPrimary, mockKeyManagement.Labels["primary"]); diff != "" {
 				t.Errorf("wrong primary: diff (-want, +got): %s", diff)
 			}
+
+			var gotEvents []string
+			for _, e := range publisher.events {
+				gotEvents = append(gotEvents, e.Type+" "+e.Subject)
+			}
+			if diff := cmp.Diff(tc.expectedEvents, gotEvents); diff != "" {
+				t.Errorf("wrong events: diff (-want, +got): %s", diff)
+			}
 		})
 	}
 }
+
+// fakePublisher records the published events.
+type fakePublisher struct {
+	events []*events.Event
+}
+
+func (p *fakePublisher) Publish(_ context.Context, event *events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
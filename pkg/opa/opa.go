@@ -0,0 +1,273 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package opa evaluates Rego policies of the Open Policy Agent (OPA) with
+// every token request, so security teams can deny requests, or change their
+// TTL and audiences, without writing a plugin.
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Query is the query of the decision of a policy. Policies declare the
+// "jvs.issuance" package, and the rules of [Decision] in it.
+const Query = "data.jvs.issuance"
+
+// Input is the input of a policy, the token request.
+type Input struct {
+	// Requestor is the authenticated principal that requested the token.
+	Requestor string `json:"requestor"`
+
+	// Subject is the requested subject of the token. It is empty if the
+	// subject is the requestor.
+	Subject string `json:"subject"`
+
+	// Justifications are the justifications of the request.
+	Justifications []*InputJustification `json:"justifications"`
+
+	// Categories are the categories of the justifications.
+	Categories []string `json:"categories"`
+
+	// TTLSeconds is the requested TTL of the token, in seconds. It is 0 if the
+	// request has none, and gets the default TTL.
+	TTLSeconds float64 `json:"ttl_seconds"`
+
+	// Audiences and AudienceServices are the requested audiences and audience
+	// services of the token. If both are empty, the token gets the default
+	// audience.
+	Audiences        []string `json:"audiences"`
+	AudienceServices []string `json:"audience_services"`
+}
+
+// InputJustification is a justification of the input of a policy.
+type InputJustification struct {
+	Category string `json:"category"`
+	Value    string `json:"value"`
+}
+
+// Decision is the decision of a policy. Rules the policy doesn't define leave
+// the request as it is.
+type Decision struct {
+	// Deny are the reasons to deny the request. The request is allowed if it
+	// is empty.
+	Deny []string `json:"deny"`
+
+	// TTLSeconds, if set, replaces the TTL of the token, in seconds. TTLs over
+	// the max TTL of the token are lowered to it.
+	TTLSeconds *float64 `json:"ttl_seconds"`
+
+	// Audiences, if set, replace the audiences and audience services of the
+	// token.
+	Audiences []string `json:"audiences"`
+}
+
+// Policy is a Rego policy loaded from a local file. Policies in Cloud Storage
+// must be mounted as a volume, gs:// URLs aren't read.
+type Policy struct {
+	path string
+
+	mu       sync.RWMutex
+	contents []byte
+	query    rego.PreparedEvalQuery
+}
+
+// Load loads and compiles the Rego policy in the file.
+func Load(ctx context.Context, path string) (*Policy, error) {
+	p := &Policy{path: path}
+	if _, err := p.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reloads the policy from its file, and reports whether it changed. If
+// the file can't be read or compiled, the previous policy stays in effect.
+func (p *Policy) Reload(ctx context.Context) (bool, error) {
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read rego policy: %w", err)
+	}
+
+	p.mu.RLock()
+	unchanged := p.contents != nil && bytes.Equal(b, p.contents)
+	p.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	query, err := rego.New(
+		rego.Query(Query),
+		rego.Module(p.path, string(b)),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to compile rego policy: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.contents = b
+	p.query = query
+	return true, nil
+}
+
+// Watch reloads the policy every interval until the context is done. Failed
+// reloads are logged.
+func (p *Policy) Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := p.Reload(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to reload rego policy", "path", p.path, "error", err)
+			continue
+		}
+		if changed {
+			logger.InfoContext(ctx, "reloaded rego policy", "path", p.path)
+		}
+	}
+}
+
+// Evaluate evaluates the policy with the input, and returns its decision.
+func (p *Policy) Evaluate(ctx context.Context, input *Input) (*Decision, error) {
+	p.mu.RLock()
+	query := p.query
+	p.mu.RUnlock()
+
+	rs, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate rego policy: %w", err)
+	}
+
+	// The query is undefined if the policy has no rules in the package.
+	var decision Decision
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return &decision, nil
+	}
+
+	b, err := json.Marshal(rs[0].Expressions[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rego policy decision: %w", err)
+	}
+	if err := json.Unmarshal(b, &decision); err != nil {
+		return nil, fmt.Errorf("invalid rego policy decision: %w", err)
+	}
+	if err := decision.validate(); err != nil {
+		return nil, fmt.Errorf("invalid rego policy decision: %w", err)
+	}
+	return &decision, nil
+}
+
+// validate checks the decision can be applied to a request.
+func (d *Decision) validate() error {
+	var merr error
+	if d.TTLSeconds != nil && *d.TTLSeconds <= 0 {
+		merr = errors.Join(merr, fmt.Errorf("ttl_seconds must be positive, got %v", *d.TTLSeconds))
+	}
+	for _, aud := range d.Audiences {
+		if aud == "" {
+			merr = errors.Join(merr, fmt.Errorf("audiences must not be empty"))
+		}
+	}
+	return merr
+}
+
+// NewInput returns the input of a policy for the token request.
+func NewInput(requestor string, req *jvspb.CreateJustificationRequest) *Input {
+	input := &Input{
+		Requestor:        requestor,
+		Subject:          req.GetSubject(),
+		Justifications:   make([]*InputJustification, 0, len(req.GetJustifications())),
+		Categories:       make([]string, 0, len(req.GetJustifications())),
+		TTLSeconds:       req.GetTtl().AsDuration().Seconds(),
+		Audiences:        req.GetAudiences(),
+		AudienceServices: req.GetAudienceServices(),
+	}
+	for _, j := range req.GetJustifications() {
+		input.Justifications = append(input.Justifications, &InputJustification{
+			Category: j.GetCategory(),
+			Value:    j.GetValue(),
+		})
+		input.Categories = append(input.Categories, j.GetCategory())
+	}
+	return input
+}
+
+// PreIssueHook returns a hook that evaluates the policy with every token
+// request. It denies requests the policy denies with PERMISSION_DENIED, and
+// applies the TTL and audiences of the decision to the others. TTLs are capped
+// at the max TTL of the justifications of the request, e.g.
+// [justification.Processor.MaxTTL]. Requests fail with INTERNAL if the policy
+// can't be evaluated.
+func (p *Policy) PreIssueHook(maxTTL func(justs []*jvspb.Justification) time.Duration) justification.PreIssueHook {
+	return func(ctx context.Context, requestor string, req *jvspb.CreateJustificationRequest) error {
+		logger := logging.FromContext(ctx)
+
+		decision, err := p.Evaluate(ctx, NewInput(requestor, req))
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to evaluate rego policy", "path", p.path, "error", err)
+			return status.Error(codes.Internal, "failed to evaluate rego policy")
+		}
+
+		if len(decision.Deny) > 0 {
+			logger.InfoContext(ctx, "rego policy denied request",
+				"requestor", requestor,
+				"reasons", decision.Deny)
+			return status.Errorf(codes.PermissionDenied, "denied by policy: %s", strings.Join(decision.Deny, "; "))
+		}
+
+		if decision.TTLSeconds != nil {
+			ttl := time.Duration(*decision.TTLSeconds * float64(time.Second))
+			if limit := maxTTL(req.GetJustifications()); ttl > limit {
+				logger.WarnContext(ctx, "rego policy ttl exceeds max ttl",
+					"path", p.path,
+					"ttl", ttl,
+					"max_ttl", limit)
+				ttl = limit
+			}
+			req.Ttl = durationpb.New(ttl)
+		}
+		if decision.Audiences != nil {
+			req.Audiences = decision.Audiences
+			req.AudienceServices = nil
+		}
+		return nil
+	}
+}
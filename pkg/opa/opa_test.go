@@ -0,0 +1,245 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opa
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+const testPolicy = `
+package jvs.issuance
+
+deny contains "breakglass requires a justification from the oncall group" if {
+	"breakglass" in input.categories
+	not endswith(input.requestor, "@oncall.example.com")
+}
+
+ttl_seconds := 600 if {
+	"breakglass" in input.categories
+}
+
+audiences := ["https://prod.example.com"] if {
+	some j in input.justifications
+	startswith(j.value, "PROD-")
+}
+`
+
+func TestPolicy_PreIssueHook(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	cases := []struct {
+		name      string
+		policy    string
+		requestor string
+		req       *jvspb.CreateJustificationRequest
+		wantReq   *jvspb.CreateJustificationRequest
+		wantCode  codes.Code
+		wantErr   string
+	}{
+		{
+			name:      "allowed",
+			policy:    testPolicy,
+			requestor: "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+				Ttl:            durationpb.New(time.Hour),
+				Audiences:      []string{"https://dev.example.com"},
+			},
+			wantReq: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+				Ttl:            durationpb.New(time.Hour),
+				Audiences:      []string{"https://dev.example.com"},
+			},
+		},
+		{
+			name:      "denied",
+			policy:    testPolicy,
+			requestor: "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+			},
+			wantCode: codes.PermissionDenied,
+			wantErr:  "denied by policy: breakglass requires a justification from the oncall group",
+		},
+		{
+			name:      "adjusts_ttl",
+			policy:    testPolicy,
+			requestor: "me@oncall.example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+				Ttl:            durationpb.New(time.Hour),
+			},
+			wantReq: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "breakglass", Value: "outage"}},
+				Ttl:            durationpb.New(10 * time.Minute),
+			},
+		},
+		{
+			name:      "caps_ttl",
+			policy:    "package jvs.issuance\n\nttl_seconds := 7200\n",
+			requestor: "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+				Ttl:            durationpb.New(10 * time.Minute),
+			},
+			wantReq: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+				Ttl:            durationpb.New(time.Hour),
+			},
+		},
+		{
+			name:      "adjusts_audiences",
+			policy:    testPolicy,
+			requestor: "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications:   []*jvspb.Justification{{Category: "jira", Value: "PROD-1"}},
+				AudienceServices: []string{"payments"},
+			},
+			wantReq: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "PROD-1"}},
+				Audiences:      []string{"https://prod.example.com"},
+			},
+		},
+		{
+			name:      "undefined_package",
+			policy:    "package other\n\nallow := true\n",
+			requestor: "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+			},
+			wantReq: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+			},
+		},
+		{
+			name:      "invalid_decision",
+			policy:    "package jvs.issuance\n\nttl_seconds := -1\n",
+			requestor: "me@example.com",
+			req: &jvspb.CreateJustificationRequest{
+				Justifications: []*jvspb.Justification{{Category: "jira", Value: "ABC-123"}},
+			},
+			wantCode: codes.Internal,
+			wantErr:  "failed to evaluate rego policy",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "policy.rego")
+			if err := os.WriteFile(path, []byte(tc.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			policy, err := Load(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			maxTTL := func([]*jvspb.Justification) time.Duration { return time.Hour }
+			err = policy.PreIssueHook(maxTTL)(ctx, tc.requestor, tc.req)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Errorf("expected code %s to be %s: %v", got, want, err)
+			}
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantReq, tc.req, protocmp.Transform()); diff != "" {
+				t.Errorf("request (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPolicy_Reload(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	if err := os.WriteFile(path, []byte(testPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(ctx, filepath.Join(t.TempDir(), "missing.rego")); err == nil {
+		t.Errorf("expected missing policy to fail to load")
+	}
+
+	policy, err := Load(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := policy.Reload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Errorf("expected unchanged policy")
+	}
+
+	// A policy that doesn't compile keeps the previous one in effect.
+	if err := os.WriteFile(path, []byte("package jvs.issuance\n\ndeny contains"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := policy.Reload(ctx); err == nil {
+		t.Errorf("expected invalid policy to fail to reload")
+	}
+	input := &Input{Requestor: "me@example.com", Categories: []string{"breakglass"}}
+	decision, err := policy.Evaluate(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(decision.Deny), 1; got != want {
+		t.Errorf("expected %d deny reasons, got %d", want, got)
+	}
+
+	if err := os.WriteFile(path, []byte("package jvs.issuance\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = policy.Reload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Errorf("expected changed policy")
+	}
+	decision, err = policy.Evaluate(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Decision{}, decision); diff != "" {
+		t.Errorf("decision (-want, +got):\n%s", diff)
+	}
+}
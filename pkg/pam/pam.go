@@ -0,0 +1,195 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pam files grants with Google Cloud Privileged Access Manager (PAM).
+package pam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	// DefaultEndpoint is the endpoint of the Privileged Access Manager API.
+	DefaultEndpoint = "https://privilegedaccessmanager.googleapis.com/"
+
+	// scope is the OAuth scope of the Privileged Access Manager API.
+	scope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// maxResponseSize is the maximum size of a PAM response body.
+	maxResponseSize = 1 << 20
+)
+
+// entitlementRegexp matches the resource names of entitlements.
+var entitlementRegexp = regexp.MustCompile(
+	`^(?:projects|folders|organizations)/[^/]+/locations/[^/]+/entitlements/[^/]+$`)
+
+// grantRegexp matches the resource names of grants.
+var grantRegexp = regexp.MustCompile(
+	`^(?:projects|folders|organizations)/[^/]+/locations/[^/]+/entitlements/[^/]+/grants/[^/]+$`)
+
+// Granter files grants of entitlements.
+type Granter interface {
+	// CreateGrant requests a grant of the entitlement for the duration, with
+	// the justification. The request ID makes retries idempotent, it must be a
+	// UUID.
+	CreateGrant(ctx context.Context, entitlement string, duration time.Duration, justification, requestID string) (*Grant, error)
+
+	// RevokeGrant revokes the grant with the name, for the reason.
+	RevokeGrant(ctx context.Context, name, reason string) error
+}
+
+// Grant is a PAM grant.
+type Grant struct {
+	// Name is the resource name of the grant, e.g.
+	// "projects/p/locations/global/entitlements/e/grants/g".
+	Name string `json:"name"`
+
+	// State is the state of the grant, e.g. "APPROVAL_AWAITED" or "ACTIVE".
+	State string `json:"state"`
+}
+
+var _ Granter = (*Client)(nil)
+
+// Client is a [Granter] calling the Privileged Access Manager API. The grants
+// are requested by the principal of the client's credentials, which must be
+// an eligible principal of the entitlements.
+type Client struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewClient creates a client of the API at the endpoint, or at
+// [DefaultEndpoint] if empty, with the client options.
+func NewClient(ctx context.Context, endpoint string, opts ...option.ClientOption) (*Client, error) {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	opts = append([]option.ClientOption{option.WithScopes(scope)}, opts...)
+	client, _, err := htransport.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pam client: %w", err)
+	}
+	return &Client{
+		endpoint: endpoint,
+		client:   client,
+	}, nil
+}
+
+// CreateGrant implements [Granter].
+func (c *Client) CreateGrant(ctx context.Context, entitlement string, duration time.Duration, justification, requestID string) (*Grant, error) {
+	if !entitlementRegexp.MatchString(entitlement) {
+		return nil, fmt.Errorf("%q is not an entitlement name", entitlement)
+	}
+
+	u, err := url.JoinPath(c.endpoint, "v1", entitlement, "grants")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build grant url: %w", err)
+	}
+	if requestID != "" {
+		u += "?" + url.Values{"requestId": {requestID}}.Encode()
+	}
+
+	body, err := json.Marshal(map[string]any{
+		// Durations are encoded as seconds in JSON.
+		"requestedDuration": fmt.Sprintf("%ds", int64(duration.Round(time.Second).Seconds())),
+		"justification": map[string]string{
+			"unstructuredJustification": justification,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal grant: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build grant request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grant of %s: %w", entitlement, err)
+	}
+	defer resp.Body.Close()
+
+	r := io.LimitReader(resp.Body, maxResponseSize)
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(r)
+		return nil, fmt.Errorf("failed to create grant of %s: status %d: %s",
+			entitlement, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var grant Grant
+	if err := json.NewDecoder(r).Decode(&grant); err != nil {
+		return nil, fmt.Errorf("failed to parse grant of %s: %w", entitlement, err)
+	}
+	if grant.Name == "" {
+		return nil, fmt.Errorf("grant of %s has no name", entitlement)
+	}
+	return &grant, nil
+}
+
+// RevokeGrant implements [Granter].
+func (c *Client) RevokeGrant(ctx context.Context, name, reason string) error {
+	if !grantRegexp.MatchString(name) {
+		return fmt.Errorf("%q is not a grant name", name)
+	}
+
+	u, err := url.JoinPath(c.endpoint, "v1", name+":revoke")
+	if err != nil {
+		return fmt.Errorf("failed to build revoke url: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"reason": reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal revocation: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build revoke request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke grant %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	// The response is a long-running operation, the revocation completes
+	// asynchronously.
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to revoke grant %s: status %d: %s",
+			name, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pam
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestClient_CreateGrant(t *testing.T) {
+	t.Parallel()
+
+	const entitlement = "projects/p/locations/global/entitlements/prod-admin"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Method, http.MethodPost; got != want {
+			t.Errorf("expected method %q to be %q", got, want)
+		}
+		if r.URL.Path == "/v1/projects/p/locations/global/entitlements/broken/grants" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error": {"message": "requester is not eligible"}}`))
+			return
+		}
+		if got, want := r.URL.Path, "/v1/"+entitlement+"/grants"; got != want {
+			t.Errorf("expected path %q to be %q", got, want)
+		}
+		if got, want := r.URL.Query().Get("requestId"), "0b0a8c3c-3f0a-4e7e-9b4b-7f5e0f6b8a11"; got != want {
+			t.Errorf("expected request id %q to be %q", got, want)
+		}
+
+		var body struct {
+			RequestedDuration string `json:"requestedDuration"`
+			Justification     struct {
+				UnstructuredJustification string `json:"unstructuredJustification"`
+			} `json:"justification"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if got, want := body.RequestedDuration, "3600s"; got != want {
+			t.Errorf("expected requested duration %q to be %q", got, want)
+		}
+		if got, want := body.Justification.UnstructuredJustification, "breakglass: prod outage"; got != want {
+			t.Errorf("expected justification %q to be %q", got, want)
+		}
+
+		if err := json.NewEncoder(w).Encode(map[string]any{
+			"name":  entitlement + "/grants/g1",
+			"state": "APPROVAL_AWAITED",
+		}); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name        string
+		entitlement string
+		want        *Grant
+		wantErr     string
+	}{
+		{
+			name:        "success",
+			entitlement: entitlement,
+			want: &Grant{
+				Name:  entitlement + "/grants/g1",
+				State: "APPROVAL_AWAITED",
+			},
+		},
+		{
+			name:        "invalid_entitlement",
+			entitlement: "prod-admin",
+			wantErr:     `"prod-admin" is not an entitlement name`,
+		},
+		{
+			name:        "denied",
+			entitlement: "projects/p/locations/global/entitlements/broken",
+			wantErr:     "status 403: {\"error\": {\"message\": \"requester is not eligible\"}}",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c, err := NewClient(ctx, srv.URL, option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.CreateGrant(ctx, tc.entitlement, time.Hour, "breakglass: prod outage",
+				"0b0a8c3c-3f0a-4e7e-9b4b-7f5e0f6b8a11")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("grant (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClient_RevokeGrant(t *testing.T) {
+	t.Parallel()
+
+	const grant = "projects/p/locations/global/entitlements/prod-admin/grants/g1"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Method, http.MethodPost; got != want {
+			t.Errorf("expected method %q to be %q", got, want)
+		}
+		if r.URL.Path == "/v1/projects/p/locations/global/entitlements/prod-admin/grants/gone:revoke" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"message": "grant not found"}}`))
+			return
+		}
+		if got, want := r.URL.Path, "/v1/"+grant+":revoke"; got != want {
+			t.Errorf("expected path %q to be %q", got, want)
+		}
+
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		if got, want := body.Reason, "token was not issued"; got != want {
+			t.Errorf("expected reason %q to be %q", got, want)
+		}
+
+		_, _ = w.Write([]byte(`{"name": "operations/op1"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name    string
+		grant   string
+		wantErr string
+	}{
+		{
+			name:  "success",
+			grant: grant,
+		},
+		{
+			name:    "invalid_grant",
+			grant:   "projects/p/locations/global/entitlements/prod-admin",
+			wantErr: "is not a grant name",
+		},
+		{
+			name:    "not_found",
+			grant:   "projects/p/locations/global/entitlements/prod-admin/grants/gone",
+			wantErr: "status 404: {\"error\": {\"message\": \"grant not found\"}}",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c, err := NewClient(ctx, srv.URL, option.WithoutAuthentication())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.RevokeGrant(ctx, tc.grant, "token was not issued")
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Errorf("Unexpected err: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"debug/buildinfo"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+const (
+	// KindBuiltin, KindPlugin and KindRemote are the kinds of validators:
+	// built into the JVS, loaded from the plugin directory, or served over gRPC
+	// by another service.
+	KindBuiltin = "builtin"
+	KindPlugin  = "plugin"
+	KindRemote  = "remote"
+
+	// errorRateWindow is the number of recent Validate calls the error rate is
+	// computed over.
+	errorRateWindow = 100
+)
+
+var _ jvspb.Validator = (*Monitor)(nil)
+
+// Monitor is a [jvspb.Validator] that records the outcome of the Validate calls
+// of the validator it wraps, so operators can spot a broken validator before
+// its users do.
+type Monitor struct {
+	jvspb.Validator
+
+	kind     string
+	version  string
+	protocol string
+
+	// now is the clock, for testing.
+	now func() time.Time
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	count       int64
+	errorCount  int64
+	lastError   string
+
+	// recent are the outcomes of the last errorRateWindow calls, true if the
+	// call failed. next is the index of the oldest outcome once it is full.
+	recent []bool
+	next   int
+}
+
+// NewMonitor wraps the validator of the given kind. version and protocol are
+// empty if they are unknown.
+func NewMonitor(v jvspb.Validator, kind, version, protocol string) *Monitor {
+	return &Monitor{
+		Validator: v,
+		kind:      kind,
+		version:   version,
+		protocol:  protocol,
+		now:       time.Now,
+	}
+}
+
+// Validate calls the wrapped validator and records whether the call failed.
+// Invalid justifications are successful calls.
+func (m *Monitor) Validate(ctx context.Context, req *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	resp, err := m.Validator.Validate(ctx, req)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.count++
+	if err != nil {
+		m.errorCount++
+		m.lastError = err.Error()
+	} else {
+		m.lastSuccess = m.now()
+	}
+
+	if len(m.recent) < errorRateWindow {
+		m.recent = append(m.recent, err != nil)
+	} else {
+		m.recent[m.next] = err != nil
+		m.next = (m.next + 1) % errorRateWindow
+	}
+
+	return resp, err //nolint:wrapcheck // Want passthrough
+}
+
+// Status returns the status of the validator of the category.
+func (m *Monitor) Status(category string) *jvspb.ValidatorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &jvspb.ValidatorStatus{
+		Category:           category,
+		Kind:               m.kind,
+		Version:            m.version,
+		Protocol:           m.protocol,
+		ValidateCount:      m.count,
+		ValidateErrorCount: m.errorCount,
+		LastError:          m.lastError,
+	}
+	if !m.lastSuccess.IsZero() {
+		status.LastSuccessTime = timestamppb.New(m.lastSuccess)
+	}
+	if len(m.recent) > 0 {
+		var failed int
+		for _, f := range m.recent {
+			if f {
+				failed++
+			}
+		}
+		status.ErrorRate = float64(failed) / float64(len(m.recent))
+	}
+	return status
+}
+
+// binaryVersion returns the version of the Go binary at path, from its build
+// info: the version of its main module, or else its VCS revision. It returns
+// an empty string if the binary has no build info, e.g. it isn't written in Go.
+func binaryVersion(path string) string {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision == "" {
+		return ""
+	}
+	if modified == "true" {
+		return fmt.Sprintf("%s-dirty", revision)
+	}
+	return revision
+}
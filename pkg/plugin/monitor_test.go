@@ -0,0 +1,162 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+)
+
+// failingValidator fails the Validate calls of justifications with the value
+// "fail".
+type failingValidator struct {
+	jvspb.Validator
+}
+
+func (v *failingValidator) Validate(ctx context.Context, req *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	if req.GetJustification().GetValue() == "fail" {
+		return nil, fmt.Errorf("plugin crashed")
+	}
+	return &jvspb.ValidateJustificationResponse{Valid: req.GetJustification().GetValue() == "valid"}, nil
+}
+
+func TestMonitor(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		values []string
+		want   *jvspb.ValidatorStatus
+	}{
+		{
+			name: "no_calls",
+			want: &jvspb.ValidatorStatus{
+				Category: "jira",
+				Kind:     KindPlugin,
+				Version:  "v1.2.3",
+				Protocol: "grpc/1",
+			},
+		},
+		{
+			name:   "invalid_is_success",
+			values: []string{"valid", "invalid"},
+			want: &jvspb.ValidatorStatus{
+				Category:        "jira",
+				Kind:            KindPlugin,
+				Version:         "v1.2.3",
+				Protocol:        "grpc/1",
+				LastSuccessTime: timestamppb.New(now),
+				ValidateCount:   2,
+			},
+		},
+		{
+			name:   "errors",
+			values: []string{"valid", "fail", "fail", "invalid"},
+			want: &jvspb.ValidatorStatus{
+				Category:           "jira",
+				Kind:               KindPlugin,
+				Version:            "v1.2.3",
+				Protocol:           "grpc/1",
+				LastSuccessTime:    timestamppb.New(now),
+				ValidateCount:      4,
+				ValidateErrorCount: 2,
+				ErrorRate:          0.5,
+				LastError:          "plugin crashed",
+			},
+		},
+		{
+			name:   "only_errors",
+			values: []string{"fail"},
+			want: &jvspb.ValidatorStatus{
+				Category:           "jira",
+				Kind:               KindPlugin,
+				Version:            "v1.2.3",
+				Protocol:           "grpc/1",
+				ValidateCount:      1,
+				ValidateErrorCount: 1,
+				ErrorRate:          1,
+				LastError:          "plugin crashed",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := NewMonitor(&failingValidator{}, KindPlugin, "v1.2.3", "grpc/1")
+			m.now = func() time.Time { return now }
+
+			for _, v := range tc.values {
+				_, _ = m.Validate(ctx, &jvspb.ValidateJustificationRequest{
+					Justification: &jvspb.Justification{Category: "jira", Value: v},
+				})
+			}
+
+			if diff := cmp.Diff(tc.want, m.Status("jira"), protocmp.Transform()); diff != "" {
+				t.Errorf("status (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMonitor_errorRateWindow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	m := NewMonitor(&failingValidator{}, KindRemote, "", "grpc")
+
+	// Old failures leave the window as newer calls succeed.
+	for _, v := range []string{"fail", "valid"} {
+		for i := 0; i < errorRateWindow; i++ {
+			_, _ = m.Validate(ctx, &jvspb.ValidateJustificationRequest{
+				Justification: &jvspb.Justification{Category: "jira", Value: v},
+			})
+		}
+	}
+
+	got := m.Status("jira")
+	if got, want := got.GetErrorRate(), 0.0; got != want {
+		t.Errorf("error rate got %v, want %v", got, want)
+	}
+	if got, want := got.GetValidateErrorCount(), int64(errorRateWindow); got != want {
+		t.Errorf("error count got %d, want %d", got, want)
+	}
+}
+
+func TestBinaryVersion(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "jvs-plugin-script")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := binaryVersion(path); got != "" {
+		t.Errorf("version of a script got %q, want empty", got)
+	}
+}
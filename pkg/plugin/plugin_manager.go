@@ -16,10 +16,13 @@
 package plugin
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 
 	"github.com/hashicorp/go-plugin"
 
@@ -32,8 +35,22 @@ const (
 	PluginGlob = "jvs-plugin-*"
 )
 
+// SealedConfigs are the configs of local plugins, encrypted with a KMS key.
+// Each plugin gets its own config and decrypts it with
+// [jvspb.LoadPluginConfig], so their secrets are never in plaintext outside of
+// the plugin process.
+type SealedConfigs struct {
+	// Key is the KMS key that decrypts the configs.
+	Key string
+
+	// Ciphertexts are the encrypted configs, keyed by plugin name.
+	Ciphertexts map[string][]byte
+}
+
 // LoadPlugins loads plugins in the dir and put them into the Validator interface.
-func LoadPlugins(dir string) (map[string]jvspb.Validator, *multicloser.Closer, error) {
+// Plugins with a sealed config are passed it in their environment. sealed may
+// be nil.
+func LoadPlugins(dir string, sealed *SealedConfigs) (map[string]jvspb.Validator, *multicloser.Closer, error) {
 	validators := make(map[string]jvspb.Validator)
 	var merr error
 	var closer *multicloser.Closer
@@ -45,10 +62,12 @@ func LoadPlugins(dir string) (map[string]jvspb.Validator, *multicloser.Closer, e
 			"error discovering plugins in %s: %w", dir, err)
 	}
 
+	names := make([]string, 0, len(paths))
 	for _, path := range paths {
 		// PluginGlob prefix won't be part of the name.
 		prefix := len(PluginGlob) - 1
 		name := filepath.Base(path)[prefix:]
+		names = append(names, name)
 
 		// Enable the plugin.
 		pluginClient := plugin.NewClient(&plugin.ClientConfig{
@@ -58,7 +77,7 @@ func LoadPlugins(dir string) (map[string]jvspb.Validator, *multicloser.Closer, e
 			Plugins: map[string]plugin.Plugin{
 				name: &jvspb.ValidatorPlugin{},
 			},
-			Cmd:              exec.Command(path),
+			Cmd:              pluginCommand(path, name, sealed),
 			AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
 		})
 		closer = multicloser.Append(closer, pluginClient.Kill)
@@ -82,7 +101,31 @@ func LoadPlugins(dir string) (map[string]jvspb.Validator, *multicloser.Closer, e
 			merr = errors.Join(merr, fmt.Errorf("failed to cast plugin %s to Validator interface", name))
 			continue
 		}
-		validators[name] = v
+		protocol := fmt.Sprintf("%s/%d", pluginClient.Protocol(), pluginClient.NegotiatedVersion())
+		validators[name] = NewMonitor(v, KindPlugin, binaryVersion(path), protocol)
+	}
+
+	if sealed != nil {
+		for name := range sealed.Ciphertexts {
+			if !slices.Contains(names, name) {
+				merr = errors.Join(merr, fmt.Errorf("sealed config of plugin %s has no plugin in %s", name, dir))
+			}
+		}
 	}
 	return validators, closer, merr
 }
+
+// pluginCommand returns the command that starts the plugin at path. It inherits
+// the environment of the host, and has the plugin's sealed config, if any.
+func pluginCommand(path, name string, sealed *SealedConfigs) *exec.Cmd {
+	cmd := exec.Command(path)
+	if sealed == nil {
+		return cmd
+	}
+	if ciphertext, ok := sealed.Ciphertexts[name]; ok {
+		cmd.Env = append(os.Environ(),
+			jvspb.PluginConfigKeyEnv+"="+sealed.Key,
+			jvspb.PluginSealedConfigEnv+"="+base64.StdEncoding.EncodeToString(ciphertext))
+	}
+	return cmd
+}
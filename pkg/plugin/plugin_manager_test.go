@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestPluginCommand(t *testing.T) {
+	t.Parallel()
+
+	sealed := &SealedConfigs{
+		Key:         "projects/p/locations/global/keyRings/r/cryptoKeys/plugins",
+		Ciphertexts: map[string][]byte{"jira": []byte("ciphertext")},
+	}
+
+	cases := []struct {
+		name    string
+		plugin  string
+		sealed  *SealedConfigs
+		wantEnv []string
+	}{
+		{
+			name:   "sealed_config",
+			plugin: "jira",
+			sealed: sealed,
+			wantEnv: []string{
+				jvspb.PluginConfigKeyEnv + "=" + sealed.Key,
+				jvspb.PluginSealedConfigEnv + "=" + base64.StdEncoding.EncodeToString([]byte("ciphertext")),
+			},
+		},
+		{
+			name:   "other_plugin",
+			plugin: "github",
+			sealed: sealed,
+		},
+		{
+			name:   "no_sealed_configs",
+			plugin: "jira",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := pluginCommand("/var/jvs/plugins/jvs-plugin-"+tc.plugin, tc.plugin, tc.sealed)
+			if tc.wantEnv == nil {
+				// A nil Env inherits the environment of the host.
+				if cmd.Env != nil {
+					t.Errorf("got env %q, want the host's", cmd.Env)
+				}
+				return
+			}
+			for _, want := range tc.wantEnv {
+				if !slices.Contains(cmd.Env, want) {
+					t.Errorf("env %q is missing %q", cmd.Env, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPlugins_unknownSealedConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, _, err := LoadPlugins(dir, &SealedConfigs{
+		Key:         "projects/p/locations/global/keyRings/r/cryptoKeys/plugins",
+		Ciphertexts: map[string][]byte{"jira": []byte("ciphertext")},
+	})
+	if diff := testutil.DiffErrString(err, "sealed config of plugin jira has no plugin in "+dir); diff != "" {
+		t.Error(diff)
+	}
+}
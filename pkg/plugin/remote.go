@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/multicloser"
+)
+
+// RemoteTransportCredentials returns the credentials to connect to remote
+// plugins with. The CA file verifies the plugin servers, defaulting to the
+// system roots, and the optional certificate and key authenticate the JVS to
+// them (mTLS). If plaintext is true, TLS is disabled.
+func RemoteTransportCredentials(caFile, certFile, keyFile string, plaintext bool) (credentials.TransportCredentials, error) {
+	if plaintext {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote plugin ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in remote plugin ca file %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load remote plugin client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// LoadRemotePlugins connects to the validator plugins served over gRPC at the
+// given addresses, keyed by category. Connections are established lazily, so an
+// unavailable plugin only fails the requests for its category.
+func LoadRemotePlugins(addrs map[string]string, creds credentials.TransportCredentials) (map[string]jvspb.Validator, *multicloser.Closer, error) {
+	validators := make(map[string]jvspb.Validator, len(addrs))
+	var merr error
+	var closer *multicloser.Closer
+
+	// Sort for deterministic errors.
+	for _, name := range slices.Sorted(maps.Keys(addrs)) {
+		addr := addrs[name]
+
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			merr = errors.Join(merr, fmt.Errorf("failed to create client for remote plugin %s at %s: %w", name, addr, err))
+			continue
+		}
+		closer = multicloser.Append(closer, conn.Close)
+
+		validators[name] = NewMonitor(jvspb.NewPluginClient(conn), KindRemote, "", "grpc")
+	}
+	return validators, closer, merr
+}
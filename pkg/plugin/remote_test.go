@@ -0,0 +1,117 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestLoadRemotePlugins(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	addr, _ := testutil.FakeGRPCServer(t, func(s *grpc.Server) {
+		jvspb.RegisterJVSPluginServer(s, &jvspb.PluginServer{Impl: jvspb.DefaultJustificationValidator})
+	})
+
+	creds, err := RemoteTransportCredentials("", "", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validators, closer, err := LoadRemotePlugins(map[string]string{"remote": addr}, creds)
+	t.Cleanup(func() {
+		if err := closer.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok := validators["remote"]
+	if !ok {
+		t.Fatalf("expected remote validator, got %v", validators)
+	}
+
+	resp, err := v.Validate(ctx, &jvspb.ValidateJustificationRequest{
+		Justification: &jvspb.Justification{Category: "remote", Value: ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetValid() {
+		t.Errorf("expected empty justification to be invalid")
+	}
+
+	uiData, err := v.GetUIData(ctx, &jvspb.GetUIDataRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := uiData.GetDisplayName(), jvspb.DefaultJustificationDisplayName; got != want {
+		t.Errorf("expected display name %q to be %q", got, want)
+	}
+}
+
+func TestRemoteTransportCredentials(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		caFile   string
+		certFile string
+		keyFile  string
+		expErr   string
+	}{
+		{
+			name: "system_roots",
+		},
+		{
+			name:   "missing_ca_file",
+			caFile: "/does/not/exist.pem",
+			expErr: "failed to read remote plugin ca file",
+		},
+		{
+			name:     "missing_client_cert",
+			certFile: "/does/not/exist.pem",
+			keyFile:  "/does/not/exist-key.pem",
+			expErr:   "failed to load remote plugin client certificate",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			creds, err := RemoteTransportCredentials(tc.caFile, tc.certFile, tc.keyFile, false)
+			if diff := testutil.DiffErrString(err, tc.expErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := creds.Info().SecurityProtocol, "tls"; got != want {
+				t.Errorf("expected security protocol %q to be %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore stores usage in a JSON file. With several replicas, the file must
+// be on a volume they all share. Each change rereads the file and replaces it
+// atomically, so tokens counted by other replicas are kept. Usage of earlier
+// days is dropped on change.
+type FileStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileStore creates a store in the file, which is created on the first
+// change.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Consume implements [Store].
+func (s *FileStore) Consume(ctx context.Context, key Key, limit int) (bool, error) {
+	var consumed bool
+	if _, err := s.update(key, func(u *Usage) {
+		if u.Used < limit+u.Granted {
+			u.Used++
+			consumed = true
+		}
+	}); err != nil {
+		return false, err
+	}
+	return consumed, nil
+}
+
+// Refund implements [Store].
+func (s *FileStore) Refund(ctx context.Context, key Key) error {
+	_, err := s.update(key, func(u *Usage) {
+		if u.Used > 0 {
+			u.Used--
+		}
+	})
+	return err
+}
+
+// Grant implements [Store].
+func (s *FileStore) Grant(ctx context.Context, key Key, n int) (*Usage, error) {
+	return s.update(key, func(u *Usage) {
+		u.Granted += n
+	})
+}
+
+// update applies fn to the usage of the key, and writes the usage of its day.
+func (s *FileStore) update(key Key, fn func(u *Usage)) (*Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var target *Usage
+	usage := make([]*Usage, 0, len(all)+1)
+	for _, u := range all {
+		if u.Day < key.Day {
+			continue
+		}
+		if u.Key == key {
+			target = u
+		}
+		usage = append(usage, u)
+	}
+	if target == nil {
+		target = &Usage{Key: key}
+		usage = append(usage, target)
+	}
+
+	fn(target)
+	if err := s.write(usage); err != nil {
+		return nil, err
+	}
+	result := *target
+	return &result, nil
+}
+
+func (s *FileStore) read() ([]*Usage, error) {
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+
+	var usage []*Usage
+	if err := json.Unmarshal(b, &usage); err != nil {
+		return nil, fmt.Errorf("failed to parse quota usage: %w", err)
+	}
+	return usage, nil
+}
+
+// write writes the usage to a temporary file and renames it over the file, so
+// a failed write doesn't leave partial usage behind.
+func (s *FileStore) write(usage []*Usage) error {
+	b, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quota usage: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create quota usage file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write quota usage file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close quota usage file: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace quota usage file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+var _ Store = (*PostgresStore)(nil)
+
+// PostgresStore stores usage in the "jvs_quota_usage" table of a Postgres
+// database, such as Cloud SQL. The database is opened by the caller, so any
+// driver for database/sql can be used.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a store for the database. The table must have been
+// created with [PostgresStore.Migrate].
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Migrate creates the usage table if it doesn't exist. Migrating twice is a
+// no-op.
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS jvs_quota_usage (
+  principal TEXT NOT NULL,
+  category  TEXT NOT NULL,
+  day       DATE NOT NULL,
+  used      INTEGER NOT NULL DEFAULT 0,
+  granted   INTEGER NOT NULL DEFAULT 0,
+  PRIMARY KEY (principal, category, day)
+)`); err != nil {
+		return fmt.Errorf("failed to create quota usage table: %w", err)
+	}
+	return nil
+}
+
+// Consume implements [Store]. The count is checked and incremented in a single
+// statement, so concurrent requests on different replicas can't both take the
+// last token.
+func (s *PostgresStore) Consume(ctx context.Context, key Key, limit int) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO jvs_quota_usage (principal, category, day, used)
+VALUES ($1, $2, $3, 1)
+ON CONFLICT (principal, category, day) DO UPDATE SET used = jvs_quota_usage.used + 1
+WHERE jvs_quota_usage.used < $4 + jvs_quota_usage.granted`, key.Principal, key.Category, key.Day, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume quota: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume quota: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Refund implements [Store].
+func (s *PostgresStore) Refund(ctx context.Context, key Key) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE jvs_quota_usage SET used = used - 1
+WHERE principal = $1 AND category = $2 AND day = $3 AND used > 0`, key.Principal, key.Category, key.Day); err != nil {
+		return fmt.Errorf("failed to refund quota: %w", err)
+	}
+	return nil
+}
+
+// Grant implements [Store].
+func (s *PostgresStore) Grant(ctx context.Context, key Key, n int) (*Usage, error) {
+	u := &Usage{Key: key}
+	if err := s.db.QueryRowContext(ctx, `INSERT INTO jvs_quota_usage (principal, category, day, granted)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (principal, category, day) DO UPDATE SET granted = jvs_quota_usage.granted + $4
+RETURNING used, granted`, key.Principal, key.Category, key.Day, n).Scan(&u.Used, &u.Granted); err != nil {
+		return nil, fmt.Errorf("failed to grant quota: %w", err)
+	}
+	return u, nil
+}
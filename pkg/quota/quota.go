@@ -0,0 +1,172 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota limits how many tokens each principal can mint per category
+// per day, in storage shared by all JVS replicas, with overrides granted by
+// admins.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNoQuota is returned when granting tokens of a category without a quota.
+var ErrNoQuota = errors.New("category has no quota")
+
+// Key identifies the tokens of a category a principal minted on a day.
+type Key struct {
+	// Principal is the email of the requestor, lowercased.
+	Principal string `json:"principal"`
+
+	// Category is the justification category.
+	Category string `json:"category"`
+
+	// Day is the UTC date, in the time.DateOnly format.
+	Day string `json:"day"`
+}
+
+// Usage is the number of tokens counted against a key, and granted over its
+// limit.
+type Usage struct {
+	Key
+
+	Used    int `json:"used"`
+	Granted int `json:"granted,omitempty"`
+}
+
+// Store is where usage is stored. It must be shared by all replicas.
+type Store interface {
+	// Consume counts a token against the key if fewer than the limit plus the
+	// granted tokens are counted, and reports whether it was counted. The
+	// limit is positive.
+	Consume(ctx context.Context, key Key, limit int) (bool, error)
+
+	// Refund uncounts a token counted against the key. Refunding a key without
+	// counted tokens is a no-op.
+	Refund(ctx context.Context, key Key) error
+
+	// Grant allows n more tokens for the key than its limit, and returns its
+	// usage.
+	Grant(ctx context.Context, key Key, n int) (*Usage, error)
+}
+
+// ExhaustedError is returned when the quota of a category is exhausted for the
+// day.
+type ExhaustedError struct {
+	Category string
+	Limit    int
+
+	// ResetAt is when the quota resets, at the next UTC midnight.
+	ResetAt time.Time
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("daily quota of %d tokens of category %q is exhausted, it resets at %s",
+		e.Limit, e.Category, e.ResetAt.Format(time.RFC3339))
+}
+
+// Quotas enforces the daily token limits of the categories, counted per
+// principal in a [Store]. Days start at midnight UTC.
+type Quotas struct {
+	store  Store
+	limits map[string]int
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// New creates quotas with the daily limits of the categories, which must be
+// positive, stored in the store. Categories without a limit are unlimited.
+func New(store Store, limits map[string]int) *Quotas {
+	return &Quotas{
+		store:  store,
+		limits: limits,
+		now:    time.Now,
+	}
+}
+
+// Limit returns the daily limit of the category, and whether it has one.
+func (q *Quotas) Limit(category string) (int, bool) {
+	limit, ok := q.limits[category]
+	return limit, ok
+}
+
+// Consume counts a token of the category for the principal, and returns the
+// key it was counted against, or nil if the category is unlimited. It returns
+// an [*ExhaustedError] if the principal already minted its quota today.
+func (q *Quotas) Consume(ctx context.Context, principal, category string) (*Key, error) {
+	limit, ok := q.limits[category]
+	if !ok {
+		return nil, nil
+	}
+
+	key, resetAt := q.key(principal, category)
+	consumed, err := q.store.Consume(ctx, key, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume quota: %w", err)
+	}
+	if !consumed {
+		return nil, &ExhaustedError{
+			Category: category,
+			Limit:    limit,
+			ResetAt:  resetAt,
+		}
+	}
+	return &key, nil
+}
+
+// Refund uncounts a token returned by [Quotas.Consume], e.g. if it failed to be
+// issued. The token is refunded on the day it was counted.
+func (q *Quotas) Refund(ctx context.Context, key Key) error {
+	if err := q.store.Refund(ctx, key); err != nil {
+		return fmt.Errorf("failed to refund quota: %w", err)
+	}
+	return nil
+}
+
+// Grant allows the principal n more tokens of the category than its limit
+// today, e.g. during an incident. It returns the usage of the principal and
+// when it resets.
+func (q *Quotas) Grant(ctx context.Context, principal, category string, n int) (*Usage, time.Time, error) {
+	if _, ok := q.limits[category]; !ok {
+		return nil, time.Time{}, fmt.Errorf("%w: %q", ErrNoQuota, category)
+	}
+
+	key, resetAt := q.key(principal, category)
+	usage, err := q.store.Grant(ctx, key, n)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to grant quota: %w", err)
+	}
+	return usage, resetAt, nil
+}
+
+// key returns the key of the principal and category today, and when it
+// resets.
+func (q *Quotas) key(principal, category string) (Key, time.Time) {
+	now := q.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return Key{
+		Principal: normalize(principal),
+		Category:  category,
+		Day:       day.Format(time.DateOnly),
+	}, day.AddDate(0, 0, 1)
+}
+
+func normalize(principal string) string {
+	return strings.ToLower(strings.TrimSpace(principal))
+}
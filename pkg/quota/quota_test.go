@@ -0,0 +1,203 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+func TestQuotas(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "quota.json")
+	now := time.Date(2026, 1, 2, 23, 0, 0, 0, time.UTC)
+	replica1 := New(NewFileStore(path), map[string]int{"breakglass": 2})
+	replica1.now = func() time.Time { return now }
+	replica2 := New(NewFileStore(path), map[string]int{"breakglass": 2})
+	replica2.now = func() time.Time { return now }
+
+	// Tokens minted through either replica count.
+	key, err := replica1.Consume(ctx, "Me@Example.com", "breakglass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Key{Principal: "me@example.com", Category: "breakglass", Day: "2026-01-02"}, key); diff != "" {
+		t.Errorf("key (-want, +got):\n%s", diff)
+	}
+	if _, err := replica2.Consume(ctx, "me@example.com", "breakglass"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = replica1.Consume(ctx, "me@example.com", "breakglass")
+	var exhausted *ExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("expected %v to be an exhausted error", err)
+	}
+	want := &ExhaustedError{
+		Category: "breakglass",
+		Limit:    2,
+		ResetAt:  time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+	if diff := cmp.Diff(want, exhausted); diff != "" {
+		t.Errorf("exhausted error (-want, +got):\n%s", diff)
+	}
+	if got, want := err.Error(), `daily quota of 2 tokens of category "breakglass" is exhausted, it resets at 2026-01-03T00:00:00Z`; got != want {
+		t.Errorf("expected error %q to be %q", got, want)
+	}
+
+	// Refunded tokens can be minted again.
+	if err := replica2.Refund(ctx, *key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replica1.Consume(ctx, "me@example.com", "breakglass"); err != nil {
+		t.Error(err)
+	}
+
+	// Other principals and categories aren't affected.
+	if _, err := replica1.Consume(ctx, "other@example.com", "breakglass"); err != nil {
+		t.Error(err)
+	}
+	for range 3 {
+		key, err := replica1.Consume(ctx, "me@example.com", "explanation")
+		if err != nil {
+			t.Error(err)
+		}
+		if key != nil {
+			t.Errorf("expected no key for an unlimited category, got %v", key)
+		}
+	}
+
+	// Granted tokens can be minted over the limit.
+	usage, resetAt, err := replica2.Grant(ctx, "me@example.com", "breakglass", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUsage := &Usage{
+		Key:     Key{Principal: "me@example.com", Category: "breakglass", Day: "2026-01-02"},
+		Used:    2,
+		Granted: 1,
+	}
+	if diff := cmp.Diff(wantUsage, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+	if got, want := resetAt, want.ResetAt; !got.Equal(want) {
+		t.Errorf("expected reset at %s to be %s", got, want)
+	}
+	if _, err := replica1.Consume(ctx, "me@example.com", "breakglass"); err != nil {
+		t.Error(err)
+	}
+	if _, err := replica1.Consume(ctx, "me@example.com", "breakglass"); !errors.As(err, &exhausted) {
+		t.Errorf("expected %v to be an exhausted error", err)
+	}
+
+	if _, _, err := replica1.Grant(ctx, "me@example.com", "explanation", 1); !errors.Is(err, ErrNoQuota) {
+		t.Errorf("expected %v to be %v", err, ErrNoQuota)
+	}
+
+	// The quota resets the next day, and usage of earlier days is dropped.
+	now = now.Add(2 * time.Hour)
+	if _, err := replica1.Consume(ctx, "me@example.com", "breakglass"); err != nil {
+		t.Error(err)
+	}
+	got, err := NewFileStore(path).read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStored := []*Usage{
+		{
+			Key:  Key{Principal: "me@example.com", Category: "breakglass", Day: "2026-01-03"},
+			Used: 1,
+		},
+	}
+	if diff := cmp.Diff(wantStored, got); diff != "" {
+		t.Errorf("stored usage (-want, +got):\n%s", diff)
+	}
+}
+
+// TestPostgresStore runs against a real database, and is skipped unless
+// JVS_TEST_POSTGRES_URL is set, e.g. to a local instance started with
+// `docker run -e POSTGRES_PASSWORD=jvs -p 5432:5432 postgres`.
+func TestPostgresStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dbURL := os.Getenv("JVS_TEST_POSTGRES_URL")
+	if dbURL == "" {
+		t.Skip("Skip postgres test; set env var JVS_TEST_POSTGRES_URL to enable")
+	}
+
+	db, err := sql.Open("pgx", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	store := NewPostgresStore(db)
+	// Migrating twice is a no-op.
+	for range 2 {
+		if err := store.Migrate(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	key := Key{
+		Principal: "test-" + time.Now().Format("20060102150405.000000000") + "@example.com",
+		Category:  "breakglass",
+		Day:       time.Now().UTC().Format(time.DateOnly),
+	}
+	for _, want := range []bool{true, false} {
+		consumed, err := store.Consume(ctx, key, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := consumed; got != want {
+			t.Errorf("expected consumed %t to be %t", got, want)
+		}
+	}
+
+	usage, err := store.Grant(ctx, key, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Usage{Key: key, Used: 1, Granted: 1}, usage); diff != "" {
+		t.Errorf("usage (-want, +got):\n%s", diff)
+	}
+	if consumed, err := store.Consume(ctx, key, 1); err != nil || !consumed {
+		t.Errorf("expected granted token to be consumed, got %t, %v", consumed, err)
+	}
+
+	if err := store.Refund(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if consumed, err := store.Consume(ctx, key, 1); err != nil || !consumed {
+		t.Errorf("expected refunded token to be consumed, got %t, %v", consumed, err)
+	}
+}
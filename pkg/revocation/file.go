@@ -0,0 +1,111 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore stores revocations in a JSON file. With several replicas, the file
+// must be on a volume they all share. Each revocation rereads the file and
+// replaces it atomically, so revocations made through other replicas are kept.
+type FileStore struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// NewFileStore creates a store in the file, which is created on the first
+// revocation.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Revoke implements [Store].
+func (s *FileStore) Revoke(ctx context.Context, r *Revocation) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rs, err := s.read()
+	if err != nil {
+		return false, err
+	}
+	for _, existing := range rs {
+		if normalize(existing.Principal) == normalize(r.Principal) {
+			return true, nil
+		}
+	}
+	if err := s.write(append(rs, r)); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// List implements [Store].
+func (s *FileStore) List(ctx context.Context) ([]*Revocation, error) {
+	return s.read()
+}
+
+func (s *FileStore) read() ([]*Revocation, error) {
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read revocations: %w", err)
+	}
+
+	var rs []*Revocation
+	if err := json.Unmarshal(b, &rs); err != nil {
+		return nil, fmt.Errorf("failed to parse revocations: %w", err)
+	}
+	return rs, nil
+}
+
+// write writes the revocations to a temporary file and renames it over the
+// file, so a failed write doesn't leave partial revocations behind.
+func (s *FileStore) write(rs []*Revocation) error {
+	b, err := json.MarshalIndent(rs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal revocations: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create revocations file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write revocations file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close revocations file: %w", err)
+	}
+	if err := os.Rename(f.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace revocations file: %w", err)
+	}
+	return nil
+}
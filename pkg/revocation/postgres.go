@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+var _ Store = (*PostgresStore)(nil)
+
+// PostgresStore stores revocations in the "jvs_revocations" table of a
+// Postgres database, such as Cloud SQL. The database is opened by the caller,
+// so any driver for database/sql can be used.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a store for the database. The table must have been
+// created with [PostgresStore.Migrate].
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Migrate creates the revocations table if it doesn't exist. Migrating twice is
+// a no-op.
+func (s *PostgresStore) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS jvs_revocations (
+  principal  TEXT PRIMARY KEY,
+  revoked_at TIMESTAMPTZ NOT NULL,
+  admin      TEXT NOT NULL DEFAULT '',
+  reason     TEXT NOT NULL DEFAULT ''
+)`); err != nil {
+		return fmt.Errorf("failed to create revocations table: %w", err)
+	}
+	return nil
+}
+
+// Revoke implements [Store].
+func (s *PostgresStore) Revoke(ctx context.Context, r *Revocation) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO jvs_revocations (principal, revoked_at, admin, reason)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (principal) DO NOTHING`, normalize(r.Principal), r.RevokedAt, r.Admin, r.Reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert revocation: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to insert revocation: %w", err)
+	}
+	return n == 0, nil
+}
+
+// List implements [Store].
+func (s *PostgresStore) List(ctx context.Context) ([]*Revocation, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT principal, revoked_at, admin, reason FROM jvs_revocations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revocations: %w", err)
+	}
+	defer rows.Close()
+
+	var rs []*Revocation
+	for rows.Next() {
+		var r Revocation
+		if err := rows.Scan(&r.Principal, &r.RevokedAt, &r.Admin, &r.Reason); err != nil {
+			return nil, fmt.Errorf("failed to read revocation: %w", err)
+		}
+		r.RevokedAt = r.RevokedAt.UTC()
+		rs = append(rs, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list revocations: %w", err)
+	}
+	return rs, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revocation stores the principals whose tokens are revoked, in
+// storage shared by all JVS replicas, and serves the revocation list verifiers
+// check tokens against.
+package revocation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Revocation revokes the tokens of a principal issued at or before RevokedAt,
+// and denies it new ones.
+type Revocation struct {
+	// Principal is the email of the revoked principal, lowercased.
+	Principal string `json:"principal"`
+
+	// RevokedAt is the time the principal was revoked.
+	RevokedAt time.Time `json:"revoked_at"`
+
+	// Admin is who revoked the principal, and Reason why.
+	Admin  string `json:"admin,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Store is where revocations are stored. It must be shared by all replicas.
+type Store interface {
+	// Revoke stores the revocation. It reports whether the principal was
+	// already revoked, in which case the earlier revocation is kept.
+	Revoke(ctx context.Context, r *Revocation) (bool, error)
+
+	// List returns all revocations.
+	List(ctx context.Context) ([]*Revocation, error)
+}
+
+// List is a cache of the revocations in a [Store], reloaded with
+// [List.Watch].
+type List struct {
+	store Store
+
+	mu      sync.RWMutex
+	revoked map[string]*Revocation
+}
+
+// Load loads the revocations in the store.
+func Load(ctx context.Context, store Store) (*List, error) {
+	l := &List{store: store}
+	if err := l.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload reloads the revocations from the store. If they can't be read, the
+// previous ones stay in effect.
+func (l *List) Reload(ctx context.Context) error {
+	rs, err := l.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load revocations: %w", err)
+	}
+
+	revoked := make(map[string]*Revocation, len(rs))
+	for _, r := range rs {
+		revoked[normalize(r.Principal)] = r
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.revoked = revoked
+	return nil
+}
+
+// Watch reloads the revocations every interval until the context is done, so
+// revocations made through other replicas apply to this one. Failing to
+// reload is logged, and the previous revocations stay in effect.
+func (l *List) Watch(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := l.Reload(ctx); err != nil {
+			logger.ErrorContext(ctx, "failed to reload revocations", "error", err)
+		}
+	}
+}
+
+// Revoke revokes the principal now, in the store and in the cache. It reports
+// whether the principal was already revoked.
+func (l *List) Revoke(ctx context.Context, principal, admin, reason string) (bool, error) {
+	r := &Revocation{
+		Principal: normalize(principal),
+		RevokedAt: time.Now().UTC().Truncate(time.Second),
+		Admin:     admin,
+		Reason:    strings.Join(strings.Fields(reason), " "),
+	}
+	alreadyRevoked, err := l.store.Revoke(ctx, r)
+	if err != nil {
+		return false, fmt.Errorf("failed to store revocation: %w", err)
+	}
+
+	// Reload, so an earlier revocation from another replica is cached as is.
+	if err := l.Reload(ctx); err != nil {
+		return false, err
+	}
+	return alreadyRevoked, nil
+}
+
+// Revoked reports whether the principal is revoked.
+func (l *List) Revoked(principal string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.revoked[normalize(principal)]
+	return ok
+}
+
+// RevokedAt returns when the principal was revoked, and whether it is.
+func (l *List) RevokedAt(principal string) (time.Time, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	r, ok := l.revoked[normalize(principal)]
+	if !ok {
+		return time.Time{}, false
+	}
+	return r.RevokedAt, true
+}
+
+// Len returns the number of revoked principals.
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.revoked)
+}
+
+// RevocationList returns the revocation list served to verifiers, sorted by
+// revocation time. It only has the digests of the principals.
+func (l *List) RevocationList() *jvspb.RevocationList {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	out := &jvspb.RevocationList{Revocations: make([]*jvspb.Revocation, 0, len(l.revoked))}
+	for principal, r := range l.revoked {
+		out.Revocations = append(out.Revocations, &jvspb.Revocation{
+			PrincipalSHA256: jvspb.RevocationPrincipalDigest(principal),
+			RevokedAt:       r.RevokedAt,
+		})
+	}
+	slices.SortFunc(out.Revocations, func(a, b *jvspb.Revocation) int {
+		if c := a.RevokedAt.Compare(b.RevokedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.PrincipalSHA256, b.PrincipalSHA256)
+	})
+	return out
+}
+
+// ServeHTTP serves the revocation list as JSON.
+func (l *List) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(l.RevocationList())
+	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to marshal revocation list", "error", err)
+		http.Error(w, "failed to marshal revocation list", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// normalize returns the principal as it is stored.
+func normalize(principal string) string {
+	return strings.ToLower(strings.TrimSpace(principal))
+}
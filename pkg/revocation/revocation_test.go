@@ -0,0 +1,244 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/pkg/logging"
+)
+
+func TestList_Revoke(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	replica1, err := Load(ctx, NewFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica2, err := Load(ctx, NewFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []bool{false, true} {
+		alreadyRevoked, err := replica1.Revoke(ctx, " Left@Example.com", "admin@example.com", "left\nthe company")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := alreadyRevoked; got != want {
+			t.Errorf("expected already revoked %t to be %t", got, want)
+		}
+	}
+	if !replica1.Revoked("left@example.com") {
+		t.Error("expected principal to be revoked on the replica revoking it")
+	}
+	if revokedAt, ok := replica1.RevokedAt("LEFT@example.com"); !ok || revokedAt.IsZero() {
+		t.Errorf("expected revocation time %s to be set", revokedAt)
+	}
+	if _, ok := replica1.RevokedAt("stayed@example.com"); ok {
+		t.Error("expected other principal not to be revoked")
+	}
+
+	// Revocations through the other replica apply after it reloads, and are
+	// kept when it revokes another principal.
+	if replica2.Revoked("left@example.com") {
+		t.Error("expected principal not to be revoked before reloading")
+	}
+	if err := replica2.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !replica2.Revoked("LEFT@example.com") {
+		t.Error("expected principal to be revoked after reloading")
+	}
+	if _, err := replica2.Revoke(ctx, "compromised@example.com", "admin@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := replica1.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := replica1.Len(), 2; got != want {
+		t.Errorf("expected %d revocations to be %d", got, want)
+	}
+
+	rs, err := NewFileStore(path).List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rs[0].Reason, "left the company"; got != want {
+		t.Errorf("expected reason %q to be %q", got, want)
+	}
+}
+
+func TestList_Reload_invalid(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	l, err := Load(ctx, NewFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Revoke(ctx, "left@example.com", "admin@example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The previous revocations stay in effect.
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reload(ctx); err == nil {
+		t.Error("expected error reloading invalid revocations")
+	}
+	if !l.Revoked("left@example.com") {
+		t.Error("expected principal to stay revoked")
+	}
+}
+
+func TestList_Watch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(logging.WithLogger(context.Background(), logging.TestLogger(t)))
+	t.Cleanup(cancel)
+
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	l, err := Load(ctx, NewFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.Watch(ctx, 10*time.Millisecond)
+
+	if _, err := NewFileStore(path).Revoke(ctx, &Revocation{Principal: "left@example.com", RevokedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !l.Revoked("left@example.com") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected principal to be revoked after reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestList_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	revokedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := NewFileStore(filepath.Join(t.TempDir(), "revocations.json"))
+	for _, principal := range []string{"second@example.com", "first@example.com"} {
+		if _, err := store.Revoke(ctx, &Revocation{Principal: principal, RevokedAt: revokedAt}); err != nil {
+			t.Fatal(err)
+		}
+		revokedAt = revokedAt.Add(-time.Hour)
+	}
+	l, err := Load(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/revocations", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d", got, want)
+	}
+
+	var got jvspb.RevocationList
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	// Only the digests of the principals are served, oldest first.
+	want := jvspb.RevocationList{Revocations: []*jvspb.Revocation{
+		{
+			PrincipalSHA256: jvspb.RevocationPrincipalDigest("first@example.com"),
+			RevokedAt:       time.Date(2026, 1, 2, 2, 4, 5, 0, time.UTC),
+		},
+		{
+			PrincipalSHA256: jvspb.RevocationPrincipalDigest("second@example.com"),
+			RevokedAt:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("revocation list (-want, +got):\n%s", diff)
+	}
+}
+
+// TestPostgresStore runs against a real database, and is skipped unless
+// JVS_TEST_POSTGRES_URL is set, e.g. to a local instance started with
+// `docker run -e POSTGRES_PASSWORD=jvs -p 5432:5432 postgres`.
+func TestPostgresStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	dbURL := os.Getenv("JVS_TEST_POSTGRES_URL")
+	if dbURL == "" {
+		t.Skip("Skip postgres test; set env var JVS_TEST_POSTGRES_URL to enable")
+	}
+
+	db, err := sql.Open("pgx", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	store := NewPostgresStore(db)
+	// Migrating twice is a no-op.
+	for range 2 {
+		if err := store.Migrate(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	principal := "test-" + time.Now().Format("20060102150405.000000000") + "@example.com"
+	for _, want := range []bool{false, true} {
+		alreadyRevoked, err := store.Revoke(ctx, &Revocation{Principal: principal, RevokedAt: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := alreadyRevoked; got != want {
+			t.Errorf("expected already revoked %t to be %t", got, want)
+		}
+	}
+
+	l, err := Load(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Revoked(principal) {
+		t.Errorf("expected %q to be revoked", principal)
+	}
+}
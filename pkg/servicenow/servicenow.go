@@ -0,0 +1,181 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package servicenow provides a justification validator that checks ServiceNow
+// change requests.
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+)
+
+const (
+	DisplayName = "ServiceNow change request"
+	Hint        = "The number of an approved change request in the Implement state, e.g. CHG0030001."
+
+	// Annotations set on valid justifications.
+	AnnotationChangeRequest = "change_request"
+	AnnotationWindowEnd     = "change_window_end"
+
+	// timeLayout is the layout of ServiceNow date-time fields, which are in UTC
+	// when not requesting display values.
+	timeLayout = "2006-01-02 15:04:05"
+
+	// maxResponseSize is the maximum size of a ServiceNow response body.
+	maxResponseSize = 1 << 20
+)
+
+// changeNumberRegexp matches change request numbers. It also prevents
+// injecting into the encoded query.
+var changeNumberRegexp = regexp.MustCompile(`^[A-Z]+[0-9]+$`)
+
+var _ jvspb.Validator = (*Validator)(nil)
+
+// Validator validates that the justification is the number of a ServiceNow
+// change request that is in the Implement state, and that the current time is
+// within its planned change window.
+type Validator struct {
+	config *config.ServiceNowConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewValidator creates a new validator for the ServiceNow instance.
+func NewValidator(cfg *config.ServiceNowConfig) *Validator {
+	return &Validator{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// changeRequest is a change request record from the Table API.
+type changeRequest struct {
+	Number    string `json:"number"`
+	State     string `json:"state"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// Validate implements [jvspb.Validator].
+func (v *Validator) Validate(ctx context.Context, req *jvspb.ValidateJustificationRequest) (*jvspb.ValidateJustificationResponse, error) {
+	number := strings.ToUpper(strings.TrimSpace(req.GetJustification().GetValue()))
+	if !changeNumberRegexp.MatchString(number) {
+		return invalid(fmt.Sprintf("%q is not a change request number", number)), nil
+	}
+
+	cr, err := v.lookup(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if cr == nil {
+		return invalid(fmt.Sprintf("change request %s not found", number)), nil
+	}
+
+	if cr.State != v.config.ImplementState {
+		return invalid(fmt.Sprintf("change request %s is not in the Implement state", number)), nil
+	}
+
+	start, startErr := time.Parse(timeLayout, cr.StartDate)
+	end, endErr := time.Parse(timeLayout, cr.EndDate)
+	if startErr != nil || endErr != nil {
+		return invalid(fmt.Sprintf("change request %s has no planned change window", number)), nil
+	}
+
+	now := v.now().UTC()
+	if now.Before(start) {
+		return invalid(fmt.Sprintf("change window of %s starts at %s", number, start.Format(time.RFC3339))), nil
+	}
+	if !now.Before(end) {
+		return invalid(fmt.Sprintf("change window of %s ended at %s", number, end.Format(time.RFC3339))), nil
+	}
+
+	return &jvspb.ValidateJustificationResponse{
+		Valid: true,
+		Annotation: map[string]string{
+			AnnotationChangeRequest: cr.Number,
+			AnnotationWindowEnd:     end.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// GetUIData implements [jvspb.Validator].
+func (v *Validator) GetUIData(_ context.Context, _ *jvspb.GetUIDataRequest) (*jvspb.UIData, error) {
+	return &jvspb.UIData{
+		DisplayName: DisplayName,
+		Hint:        Hint,
+	}, nil
+}
+
+// lookup returns the change request with the number, or nil if it does not
+// exist.
+func (v *Validator) lookup(ctx context.Context, number string) (*changeRequest, error) {
+	u, err := url.JoinPath(v.config.InstanceURL, "api/now/table/change_request")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build change request url: %w", err)
+	}
+	q := url.Values{
+		"sysparm_query":  {"number=" + number},
+		"sysparm_fields": {"number,state,start_date,end_date"},
+		"sysparm_limit":  {"1"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build change request lookup: %w", err)
+	}
+	req.SetBasicAuth(v.config.Username, v.config.Password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up change request %s: %w", number, err)
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxResponseSize)
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(body)
+		return nil, fmt.Errorf("failed to look up change request %s: status %d: %s", number, resp.StatusCode, b)
+	}
+
+	var result struct {
+		Result []*changeRequest `json:"result"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse change request %s: %w", number, err)
+	}
+	if len(result.Result) == 0 {
+		return nil, nil
+	}
+	return result.Result[0], nil
+}
+
+func invalid(msg string) *jvspb.ValidateJustificationResponse {
+	return &jvspb.ValidateJustificationResponse{
+		Valid: false,
+		Error: []string{msg},
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicenow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/apis/v0/jvsplugintest"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	t.Parallel()
+
+	changes := map[string]*changeRequest{
+		"CHG0000001": {
+			Number:    "CHG0000001",
+			State:     "-1",
+			StartDate: "2023-06-01 10:00:00",
+			EndDate:   "2023-06-01 14:00:00",
+		},
+		"CHG0000002": {
+			Number:    "CHG0000002",
+			State:     "-2",
+			StartDate: "2023-06-01 10:00:00",
+			EndDate:   "2023-06-01 14:00:00",
+		},
+		"CHG0000003": {
+			Number:    "CHG0000003",
+			State:     "-1",
+			StartDate: "2023-06-01 13:00:00",
+			EndDate:   "2023-06-01 14:00:00",
+		},
+		"CHG0000004": {
+			Number:    "CHG0000004",
+			State:     "-1",
+			StartDate: "2023-06-01 08:00:00",
+			EndDate:   "2023-06-01 11:00:00",
+		},
+		"CHG0000005": {
+			Number: "CHG0000005",
+			State:  "-1",
+		},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "jvs" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got, want := r.URL.Path, "/api/now/table/change_request"; got != want {
+			t.Errorf("expected path %q to be %q", got, want)
+		}
+
+		var result []*changeRequest
+		switch q := r.URL.Query().Get("sysparm_query"); q {
+		case "number=CHG9999999":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		default:
+			if cr, ok := changes[q[len("number="):]]; ok {
+				result = append(result, cr)
+			}
+		}
+		if err := json.NewEncoder(w).Encode(map[string]any{"result": result}); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cases := []struct {
+		name     string
+		value    string
+		wantResp *jvspb.ValidateJustificationResponse
+		wantErr  string
+	}{
+		{
+			name:  "in_change_window",
+			value: "chg0000001",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Valid: true,
+				Annotation: map[string]string{
+					AnnotationChangeRequest: "CHG0000001",
+					AnnotationWindowEnd:     "2023-06-01T14:00:00Z",
+				},
+			},
+		},
+		{
+			name:  "not_implementing",
+			value: "CHG0000002",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"change request CHG0000002 is not in the Implement state"},
+			},
+		},
+		{
+			name:  "before_change_window",
+			value: "CHG0000003",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"change window of CHG0000003 starts at 2023-06-01T13:00:00Z"},
+			},
+		},
+		{
+			name:  "after_change_window",
+			value: "CHG0000004",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"change window of CHG0000004 ended at 2023-06-01T11:00:00Z"},
+			},
+		},
+		{
+			name:  "no_change_window",
+			value: "CHG0000005",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"change request CHG0000005 has no planned change window"},
+			},
+		},
+		{
+			name:  "not_found",
+			value: "CHG0000006",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{"change request CHG0000006 not found"},
+			},
+		},
+		{
+			name:  "invalid_number",
+			value: "CHG1^ORnumber!=x",
+			wantResp: &jvspb.ValidateJustificationResponse{
+				Error: []string{`"CHG1^ORNUMBER!=X" is not a change request number`},
+			},
+		},
+		{
+			name:    "server_error",
+			value:   "CHG9999999",
+			wantErr: "status 500",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := NewValidator(&config.ServiceNowConfig{
+				InstanceURL:    srv.URL,
+				Username:       "jvs",
+				Password:       "secret",
+				ImplementState: "-1",
+				Timeout:        5 * time.Second,
+			})
+			v.now = func() time.Time {
+				return time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+			}
+
+			gotResp, err := v.Validate(context.Background(), &jvspb.ValidateJustificationRequest{
+				Justification: &jvspb.Justification{
+					Category: "servicenow",
+					Value:    tc.value,
+				},
+			})
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if diff := cmp.Diff(tc.wantResp, gotResp, protocmp.Transform()); diff != "" {
+				t.Errorf("response (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidator_conformance(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result := []*changeRequest{}
+		if r.URL.Query().Get("sysparm_query") == "number=CHG0000001" {
+			result = append(result, &changeRequest{
+				Number:    "CHG0000001",
+				State:     "-1",
+				StartDate: "2023-06-01 10:00:00",
+				EndDate:   "2023-06-01 14:00:00",
+			})
+		}
+		if err := json.NewEncoder(w).Encode(map[string]any{"result": result}); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	v := NewValidator(&config.ServiceNowConfig{
+		InstanceURL:    srv.URL,
+		Username:       "jvs",
+		Password:       "secret",
+		ImplementState: "-1",
+		Timeout:        5 * time.Second,
+	})
+	v.now = func() time.Time {
+		return time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	jvsplugintest.RunConformanceTests(t, v,
+		jvsplugintest.WithCategory("servicenow"),
+		jvsplugintest.WithValidValue("CHG0000001"))
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serving
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// ReadinessService is the gRPC health service name to check for readiness,
+// as opposed to the empty service name for liveness.
+const ReadinessService = "ready"
+
+// ReadinessCheck returns an error if the server can't serve requests yet, or
+// anymore.
+type ReadinessCheck func(ctx context.Context) error
+
+// HandleHTTPReadiness responds 200 if the check passes, and 503 otherwise. The
+// reason is logged, but not returned, since readiness endpoints are
+// unauthenticated.
+func HandleHTTPReadiness(check ReadinessCheck) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		w.Header().Set("content-type", "application/json")
+		if err := check(ctx); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "readiness check failed", "error", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"status":"unavailable"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"ok"}`)
+	})
+}
+
+// HealthServer is a gRPC health server that is always SERVING for liveness,
+// and runs the readiness check for [ReadinessService].
+type HealthServer struct {
+	*health.Server
+
+	check ReadinessCheck
+}
+
+// NewHealthServer creates a health server using the readiness check.
+func NewHealthServer(check ReadinessCheck) *HealthServer {
+	return &HealthServer{
+		Server: health.NewServer(),
+		check:  check,
+	}
+}
+
+// Check implements [grpc_health_v1.HealthServer].
+func (s *HealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if req.GetService() != ReadinessService {
+		return s.Server.Check(ctx, req)
+	}
+
+	if err := s.check(ctx); err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "readiness check failed", "error", err)
+		return &grpc_health_v1.HealthCheckResponse{
+			Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		}, nil
+	}
+	return &grpc_health_v1.HealthCheckResponse{
+		Status: grpc_health_v1.HealthCheckResponse_SERVING,
+	}, nil
+}
+
+var _ grpc_health_v1.HealthServer = (*HealthServer)(nil)
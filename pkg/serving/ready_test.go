@@ -0,0 +1,126 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serving
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestHandleHTTPReadiness(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "ready",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"status":"ok"}`,
+		},
+		{
+			name:       "not_ready",
+			checkErr:   fmt.Errorf("key has no primary version"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"status":"unavailable"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			handler := HandleHTTPReadiness(func(ctx context.Context) error {
+				return tc.checkErr
+			})
+			req := httptest.NewRequest(http.MethodGet, "/ready", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if got, want := w.Code, tc.wantStatus; got != want {
+				t.Errorf("expected status %d to be %d", got, want)
+			}
+			if got, want := w.Body.String(), tc.wantBody; got != want {
+				t.Errorf("expected body %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHealthServer_Check(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		service    string
+		checkErr   error
+		wantStatus grpc_health_v1.HealthCheckResponse_ServingStatus
+		wantErr    string
+	}{
+		{
+			name:       "liveness",
+			checkErr:   fmt.Errorf("not ready"),
+			wantStatus: grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:       "ready",
+			service:    ReadinessService,
+			wantStatus: grpc_health_v1.HealthCheckResponse_SERVING,
+		},
+		{
+			name:       "not_ready",
+			service:    ReadinessService,
+			checkErr:   fmt.Errorf("not ready"),
+			wantStatus: grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+		},
+		{
+			name:    "unknown_service",
+			service: "other",
+			wantErr: "unknown service",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			server := NewHealthServer(func(ctx context.Context) error {
+				return tc.checkErr
+			})
+			resp, err := server.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: tc.service})
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if got, want := resp.GetStatus(), tc.wantStatus; got != want {
+				t.Errorf("expected status %s to be %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package serving runs the JVS servers, and drains them on shutdown: they stop
+// accepting requests, and wait for in-flight requests to finish, up to a
+// timeout.
+package serving
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// Server serves on a listener until its context is done, then drains.
+type Server struct {
+	listener net.Listener
+	ip       string
+	port     string
+	timeout  time.Duration
+	tracker  *Tracker
+
+	warmup        ReadinessCheck
+	warmupTimeout time.Duration
+}
+
+// New creates a server listening on the port. On shutdown, it waits up to the
+// timeout for in-flight requests to finish, or stops right away if the timeout
+// is 0.
+func New(port string, timeout time.Duration) (*Server, error) {
+	// Create the listener first, so the server accepts requests as soon as it
+	// is returned.
+	addr := ":" + port
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listener on %s: %w", addr, err)
+	}
+
+	tcpAddr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		listener.Close()
+		return nil, fmt.Errorf("listener is not tcp (got %T)", listener.Addr())
+	}
+
+	return &Server{
+		listener: listener,
+		ip:       tcpAddr.IP.String(),
+		port:     strconv.Itoa(tcpAddr.Port),
+		timeout:  timeout,
+		tracker:  NewTracker(),
+	}, nil
+}
+
+// WithTracker reports the in-flight requests of the tracker on shutdown. gRPC
+// servers must install [Tracker.UnaryInterceptor] of the same tracker, HTTP
+// handlers are tracked by the server.
+func (s *Server) WithTracker(tracker *Tracker) *Server {
+	s.tracker = tracker
+	return s
+}
+
+// WithWarmup retries the check before serving, up to the timeout, so caches
+// are loaded before the first request. If it doesn't pass in time, the server
+// serves anyway.
+func (s *Server) WithWarmup(timeout time.Duration, check ReadinessCheck) *Server {
+	s.warmup = check
+	s.warmupTimeout = timeout
+	return s
+}
+
+// Addr returns the server's listening address, "ip:port".
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.ip, s.port)
+}
+
+// IP returns the server's listening IP.
+func (s *Server) IP() string {
+	return s.ip
+}
+
+// Port returns the server's listening port.
+func (s *Server) Port() string {
+	return s.port
+}
+
+// StartHTTPHandler serves the handler until the context is done, then drains.
+func (s *Server) StartHTTPHandler(ctx context.Context, handler http.Handler) error {
+	srv := &http.Server{
+		// Allow custom responses to OPTIONS.
+		DisableGeneralOptionsHandler: true,
+
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+
+		Handler: s.tracker.Handler(handler),
+	}
+
+	return s.start(ctx,
+		func() error {
+			if err := srv.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err //nolint:wrapcheck // Wrapped by start
+			}
+			return nil
+		},
+		func(ctx context.Context) {
+			// Shutdown only fails if the context is done, in which case drain
+			// forces the server to close.
+			_ = srv.Shutdown(ctx)
+		},
+		func() {
+			_ = srv.Close()
+		})
+}
+
+// StartGRPC serves the gRPC server until the context is done, then drains.
+func (s *Server) StartGRPC(ctx context.Context, srv *grpc.Server) error {
+	return s.start(ctx,
+		func() error {
+			if err := srv.Serve(s.listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				return err //nolint:wrapcheck // Wrapped by start
+			}
+			return nil
+		},
+		func(context.Context) {
+			srv.GracefulStop()
+		},
+		srv.Stop)
+}
+
+// start runs serve until the context is done, then calls shutdown, which must
+// stop accepting requests and wait for in-flight requests to finish. If they
+// don't finish in time, stop is called to abandon them.
+func (s *Server) start(ctx context.Context, serve func() error, shutdown func(context.Context), stop func()) error {
+	logger := logging.FromContext(ctx)
+
+	if err := s.warmUp(ctx); err != nil {
+		logger.WarnContext(ctx, "server is starting before warming up", "error", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.InfoContext(ctx, "server is starting",
+			"ip", s.ip,
+			"port", s.port)
+		errCh <- serve()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.InfoContext(ctx, "server is draining",
+		"timeout", s.timeout.String(),
+		"in_flight", s.tracker.InFlight())
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.timeout)
+	defer cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		shutdown(shutdownCtx)
+	}()
+
+	var unfinished map[string]int
+	select {
+	case <-drained:
+	case <-shutdownCtx.Done():
+		unfinished = s.tracker.InFlight()
+		stop()
+		<-drained
+	}
+
+	if err := <-errCh; err != nil {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+
+	if len(unfinished) > 0 {
+		logger.ErrorContext(ctx, "server stopped with unfinished requests",
+			"timeout", s.timeout.String(),
+			"unfinished", unfinished)
+		return fmt.Errorf("shutdown timed out after %s with unfinished requests: %s",
+			s.timeout, formatInFlight(unfinished))
+	}
+	logger.InfoContext(ctx, "server is stopped")
+	return nil
+}
+
+// formatInFlight formats in-flight request counts, sorted by name.
+func formatInFlight(inFlight map[string]int) string {
+	names := make([]string, 0, len(inFlight))
+	for name := range inFlight {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, inFlight[name]))
+	}
+	return strings.Join(parts, ", ")
+}
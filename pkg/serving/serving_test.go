@@ -0,0 +1,202 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serving
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestServer_StartHTTPHandler(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		timeout time.Duration
+		release time.Duration
+		wantErr string
+	}{
+		{
+			name:    "drained",
+			timeout: 5 * time.Second,
+			release: 100 * time.Millisecond,
+		},
+		{
+			name:    "timed_out",
+			timeout: 100 * time.Millisecond,
+			release: 5 * time.Second,
+			wantErr: "shutdown timed out after 100ms with unfinished requests: GET /slow (1)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(logging.WithLogger(context.Background(), logging.TestLogger(t)))
+			defer cancel()
+
+			started := make(chan struct{})
+			mux := http.NewServeMux()
+			mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tc.release):
+				case <-r.Context().Done():
+				}
+			})
+
+			server, err := New("0", tc.timeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- server.StartHTTPHandler(ctx, mux)
+			}()
+
+			go func() {
+				resp, err := http.Get("http://" + server.Addr() + "/slow")
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+			<-started
+			cancel()
+
+			if diff := testutil.DiffErrString(<-errCh, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// slowHealthServer is a health server whose checks block until released or
+// canceled.
+type slowHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+
+	started chan struct{}
+	release time.Duration
+}
+
+func (s *slowHealthServer) Check(ctx context.Context, _ *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	close(s.started)
+	select {
+	case <-time.After(s.release):
+	case <-ctx.Done():
+	}
+	return &grpc_health_v1.HealthCheckResponse{}, nil
+}
+
+func TestServer_StartGRPC(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		timeout time.Duration
+		release time.Duration
+		wantErr string
+	}{
+		{
+			name:    "drained",
+			timeout: 5 * time.Second,
+			release: 100 * time.Millisecond,
+		},
+		{
+			name:    "timed_out",
+			timeout: 100 * time.Millisecond,
+			release: 5 * time.Second,
+			wantErr: "shutdown timed out after 100ms with unfinished requests: /grpc.health.v1.Health/Check (1)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(logging.WithLogger(context.Background(), logging.TestLogger(t)))
+			defer cancel()
+
+			tracker := NewTracker()
+			grpcServer := grpc.NewServer(grpc.UnaryInterceptor(tracker.UnaryInterceptor))
+			health := &slowHealthServer{started: make(chan struct{}), release: tc.release}
+			grpc_health_v1.RegisterHealthServer(grpcServer, health)
+
+			server, err := New("0", tc.timeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.WithTracker(tracker)
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- server.StartGRPC(ctx, grpcServer)
+			}()
+
+			conn, err := grpc.NewClient(server.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+
+			go func() {
+				_, _ = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			}()
+			<-health.started
+			cancel()
+
+			if diff := testutil.DiffErrString(<-errCh, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := NewTracker()
+	doneA1 := tracker.Start("a")
+	doneA2 := tracker.Start("a")
+	doneB := tracker.Start("b")
+
+	if diff := cmp.Diff(map[string]int{"a": 2, "b": 1}, tracker.InFlight()); diff != "" {
+		t.Errorf("in flight (-want, +got):\n%s", diff)
+	}
+
+	doneA1()
+	doneA1() // Calling done again is a no-op.
+	doneB()
+	if diff := cmp.Diff(map[string]int{"a": 1}, tracker.InFlight()); diff != "" {
+		t.Errorf("in flight (-want, +got):\n%s", diff)
+	}
+
+	doneA2()
+	if diff := cmp.Diff(map[string]int{}, tracker.InFlight()); diff != "" {
+		t.Errorf("in flight (-want, +got):\n%s", diff)
+	}
+}
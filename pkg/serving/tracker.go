@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serving
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Tracker counts in-flight requests by name, so a draining server can report
+// the requests it is waiting for, or abandoned.
+type Tracker struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// NewTracker creates a tracker with no in-flight requests.
+func NewTracker() *Tracker {
+	return &Tracker{
+		inFlight: make(map[string]int),
+	}
+}
+
+// Start records the start of a request, and returns the function to call once
+// it finishes.
+func (t *Tracker) Start(name string) func() {
+	t.mu.Lock()
+	t.inFlight[name]++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.inFlight[name]--; t.inFlight[name] <= 0 {
+				delete(t.inFlight, name)
+			}
+		})
+	}
+}
+
+// InFlight returns the number of in-flight requests by name.
+func (t *Tracker) InFlight() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.inFlight)
+}
+
+// UnaryInterceptor is a [grpc.UnaryServerInterceptor] that tracks requests by
+// their full method name.
+func (t *Tracker) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	defer t.Start(info.FullMethod)()
+	return handler(ctx, req)
+}
+
+// Handler tracks the requests of the handler by method and path, e.g.
+// "POST /".
+func (t *Tracker) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer t.Start(r.Method + " " + r.URL.Path)()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serving
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sethvargo/go-retry"
+
+	"github.com/abcxyz/pkg/logging"
+)
+
+// warmUp retries the warmup check with backoff until it passes, or the warmup
+// timeout expires. It's a no-op if there is no check, or the timeout is 0.
+func (s *Server) warmUp(ctx context.Context) error {
+	if s.warmup == nil || s.warmupTimeout <= 0 {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "server is warming up",
+		"timeout", s.warmupTimeout.String())
+
+	ctx, cancel := context.WithTimeout(ctx, s.warmupTimeout)
+	defer cancel()
+
+	var attempts int
+	var lastErr error
+	b := retry.WithCappedDuration(5*time.Second, retry.NewFibonacci(500*time.Millisecond))
+	if err := retry.Do(ctx, b, func(ctx context.Context) error {
+		attempts++
+		if lastErr = s.warmup(ctx); lastErr != nil {
+			logger.WarnContext(ctx, "failed to warm up, retrying",
+				"attempt", attempts,
+				"error", lastErr)
+			return retry.RetryableError(lastErr)
+		}
+		return nil
+	}); err != nil {
+		// Report why the last attempt failed, rather than the timeout.
+		if lastErr != nil {
+			err = lastErr
+		}
+		return fmt.Errorf("failed to warm up after %d attempts: %w", attempts, err)
+	}
+
+	logger.InfoContext(ctx, "server is warmed up", "attempts", attempts)
+	return nil
+}
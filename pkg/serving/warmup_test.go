@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serving
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestServer_warmUp(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		timeout      time.Duration
+		failures     int
+		wantAttempts int
+		wantErr      string
+	}{
+		{
+			name:         "warm",
+			timeout:      5 * time.Second,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried",
+			timeout:      5 * time.Second,
+			failures:     1,
+			wantAttempts: 2,
+		},
+		{
+			name:         "timed_out",
+			timeout:      100 * time.Millisecond,
+			failures:     100,
+			wantAttempts: 1,
+			wantErr:      "failed to warm up after 1 attempts: not ready",
+		},
+		{
+			name:     "disabled",
+			failures: 100,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+			var attempts int
+			server := &Server{}
+			server.WithWarmup(tc.timeout, func(ctx context.Context) error {
+				attempts++
+				if attempts <= tc.failures {
+					return fmt.Errorf("not ready")
+				}
+				return nil
+			})
+
+			err := server.warmUp(ctx)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+			if got, want := attempts, tc.wantAttempts; got != want {
+				t.Errorf("expected %d attempts to be %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// IDTokenKeyID is the key ID of the keys of [NewIDTokenIssuer].
+const IDTokenKeyID = "test-key"
+
+// IDTokenIssuer signs ID tokens with a new ES256 key, and serves its public key
+// at a JWKS endpoint.
+type IDTokenIssuer struct {
+	// PrivateKey signs the ID tokens.
+	PrivateKey *ecdsa.PrivateKey
+
+	// Keys is the key set of the public key.
+	Keys jwk.Set
+
+	// JWKSEndpoint is the URL of the JWKS endpoint serving Keys.
+	JWKSEndpoint string
+}
+
+// NewIDTokenIssuer returns an ID token issuer with a new key. Its JWKS endpoint
+// is closed at the end of the test.
+func NewIDTokenIssuer(tb testing.TB) *IDTokenIssuer {
+	tb.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	key, err := jwk.FromRaw(privateKey.Public())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := key.Set(jwk.KeyIDKey, IDTokenKeyID); err != nil {
+		tb.Fatal(err)
+	}
+	if err := key.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+		tb.Fatal(err)
+	}
+	keys := jwk.NewSet()
+	if err := keys.AddKey(key); err != nil {
+		tb.Fatal(err)
+	}
+	b, err := json.Marshal(keys)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}))
+	tb.Cleanup(srv.Close)
+
+	return &IDTokenIssuer{
+		PrivateKey:   privateKey,
+		Keys:         keys,
+		JWKSEndpoint: srv.URL,
+	}
+}
+
+// IDToken returns an ID token of the issuer iss for the audience, with the
+// subject, issued at now and valid for 5 minutes. Claims, e.g. the email, are added to
+// the token, or removed from it if their value is nil.
+func (i *IDTokenIssuer) IDToken(tb testing.TB, iss, aud, sub string, now time.Time, claims map[string]any) string {
+	tb.Helper()
+
+	token, err := jwt.NewBuilder().
+		Audience([]string{aud}).
+		Expiration(now.Add(5 * time.Minute)).
+		IssuedAt(now).
+		Issuer(iss).
+		NotBefore(now).
+		Subject(sub).
+		Build()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for k, v := range claims {
+		if v == nil {
+			if err := token.Remove(k); err != nil {
+				tb.Fatal(err)
+			}
+			continue
+		}
+		if err := token.Set(k, v); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return SignToken(tb, i.PrivateKey, IDTokenKeyID, token)
+}
+
+// SignToken signs the token with ES256 with the private key, with the key ID.
+func SignToken(tb testing.TB, privateKey *ecdsa.PrivateKey, kid string, token jwt.Token) string {
+	tb.Helper()
+
+	key, err := jwk.FromRaw(privateKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+		tb.Fatal(err)
+	}
+
+	b, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return string(b)
+}
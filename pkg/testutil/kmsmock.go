@@ -25,6 +25,7 @@ import (
 
 	"cloud.google.com/go/kms/apiv1/kmspb"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 type MockKeyManagementServer struct {
@@ -44,6 +45,23 @@ type MockKeyManagementServer struct {
 
 	Labels map[string]string
 
+	// VersionState is the state of the key versions returned by
+	// GetCryptoKeyVersion, ENABLED if unset.
+	VersionState kmspb.CryptoKeyVersion_CryptoKeyVersionState
+
+	// Versions, if set, are returned by ListCryptoKeyVersions instead of
+	// NumVersions generated versions.
+	Versions []*kmspb.CryptoKeyVersion
+
+	// DestroyScheduledDuration is the destroy_scheduled_duration of the key
+	// returned by GetCryptoKey.
+	DestroyScheduledDuration *durationpb.Duration
+
+	// VersionTemplate is the version template of the key returned by
+	// GetCryptoKey, and updated by UpdateCryptoKey. Versions returned by
+	// GetCryptoKeyVersion have its algorithm and protection level.
+	VersionTemplate *kmspb.CryptoKeyVersionTemplate
+
 	PrivateKey  *ecdsa.PrivateKey
 	PublicKey   string
 	KeyName     string
@@ -68,6 +86,11 @@ func (s *MockKeyManagementServer) ListCryptoKeyVersions(ctx context.Context, req
 	if s.Err != nil {
 		return nil, s.Err
 	}
+	if s.Versions != nil {
+		return &kmspb.ListCryptoKeyVersionsResponse{
+			CryptoKeyVersions: s.Versions,
+		}, nil
+	}
 	list := make([]*kmspb.CryptoKeyVersion, 0)
 	for i := 0; i < s.NumVersions; i++ {
 		list = append(list, &kmspb.CryptoKeyVersion{
@@ -88,8 +111,10 @@ func (s *MockKeyManagementServer) GetCryptoKey(ctx context.Context, req *kmspb.G
 		return nil, s.Err
 	}
 	return &kmspb.CryptoKey{
-		Name:   s.KeyName,
-		Labels: s.Labels,
+		Name:                     s.KeyName,
+		Labels:                   s.Labels,
+		DestroyScheduledDuration: s.DestroyScheduledDuration,
+		VersionTemplate:          s.VersionTemplate,
 	}, nil
 }
 
@@ -100,9 +125,15 @@ func (s *MockKeyManagementServer) GetCryptoKeyVersion(ctx context.Context, req *
 	if s.Err != nil {
 		return nil, s.Err
 	}
+	state := s.VersionState
+	if state == kmspb.CryptoKeyVersion_CRYPTO_KEY_VERSION_STATE_UNSPECIFIED {
+		state = kmspb.CryptoKeyVersion_ENABLED
+	}
 	return &kmspb.CryptoKeyVersion{
-		Name:  req.GetName(),
-		State: kmspb.CryptoKeyVersion_ENABLED,
+		Name:            req.GetName(),
+		State:           state,
+		Algorithm:       s.VersionTemplate.GetAlgorithm(),
+		ProtectionLevel: s.VersionTemplate.GetProtectionLevel(),
 	}, nil
 }
 
@@ -158,7 +189,14 @@ func (s *MockKeyManagementServer) UpdateCryptoKey(ctx context.Context, req *kmsp
 	s.reqMu.Lock()
 	defer s.reqMu.Unlock()
 	s.Reqs = append(s.Reqs, req)
-	s.Labels = req.GetCryptoKey().GetLabels()
+	for _, path := range req.GetUpdateMask().GetPaths() {
+		switch path {
+		case "labels":
+			s.Labels = req.GetCryptoKey().GetLabels()
+		case "version_template":
+			s.VersionTemplate = req.GetCryptoKey().GetVersionTemplate()
+		}
+	}
 
 	return &kmspb.CryptoKey{}, nil
 }
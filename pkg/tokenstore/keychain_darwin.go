@@ -0,0 +1,85 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin
+
+package tokenstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errSecItemNotFound is the exit code of the security tool when the item does
+// not exist.
+const errSecItemNotFound = 44
+
+// keychainStore stores values as generic passwords in the login keychain using
+// the security tool.
+type keychainStore struct {
+	service string
+}
+
+// NewKeychainStore returns a store backed by the macOS Keychain.
+func NewKeychainStore(service string) (Store, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return &keychainStore{service: service}, nil
+}
+
+func (s *keychainStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "security", "find-generic-password", "-s", s.service, "-a", key, "-w")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read from keychain: %w", err)
+	}
+	return bytes.TrimSuffix(stdout.Bytes(), []byte("\n")), nil
+}
+
+func (s *keychainStore) Set(ctx context.Context, key string, value []byte) error {
+	if bytes.ContainsAny(value, "\"\\\n") {
+		return fmt.Errorf("value contains characters that cannot be stored in the keychain")
+	}
+
+	// The value is passed over stdin in interactive mode, so it does not show up
+	// in the process list.
+	cmd := exec.CommandContext(ctx, "security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -w \"%s\"\n", s.service, key, value))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write to keychain: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (s *keychainStore) Delete(ctx context.Context, key string) error {
+	cmd := exec.CommandContext(ctx, "security", "delete-generic-password", "-s", s.service, "-a", key)
+	if err := cmd.Run(); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete from keychain: %w", err)
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package tokenstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// keychainStore stores values in the Secret Service (e.g. GNOME Keyring or
+// KWallet) using libsecret's secret-tool.
+type keychainStore struct {
+	service string
+}
+
+// NewKeychainStore returns a store backed by libsecret.
+func NewKeychainStore(service string) (Store, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return &keychainStore{service: service}, nil
+}
+
+func (s *keychainStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "secret-tool", "lookup", "service", s.service, "account", key)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits non-zero without output when there is no match.
+		if stdout.Len() == 0 && stderr.Len() == 0 {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read from secret service: %w: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s *keychainStore) Set(ctx context.Context, key string, value []byte) error {
+	// secret-tool reads the secret from stdin, so it does not show up in the
+	// process list.
+	cmd := exec.CommandContext(ctx, "secret-tool", "store", "--label", s.service+" token",
+		"service", s.service, "account", key)
+	cmd.Stdin = bytes.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write to secret service: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (s *keychainStore) Delete(ctx context.Context, key string) error {
+	cmd := exec.CommandContext(ctx, "secret-tool", "clear", "service", s.service, "account", key)
+	if out, err := cmd.CombinedOutput(); err != nil && len(out) > 0 {
+		return fmt.Errorf("failed to delete from secret service: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package tokenstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// keychainStore stores values as files encrypted with DPAPI, so they can only
+// be decrypted by the current Windows user.
+type keychainStore struct {
+	files *FileStore
+}
+
+// NewKeychainStore returns a store backed by DPAPI-encrypted files in the
+// user's local app data directory.
+func NewKeychainStore(service string) (Store, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	return &keychainStore{files: NewFileStore(filepath.Join(dir, service, "dpapi"))}, nil
+}
+
+func (s *keychainStore) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := s.files.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := dpapi(b, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+	return out, nil
+}
+
+func (s *keychainStore) Set(ctx context.Context, key string, value []byte) error {
+	b, err := dpapi(value, true)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	return s.files.Set(ctx, key, b)
+}
+
+func (s *keychainStore) Delete(ctx context.Context, key string) error {
+	return s.files.Delete(ctx, key)
+}
+
+// dpapi encrypts or decrypts b for the current user.
+func dpapi(b []byte, encrypt bool) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	in := windows.DataBlob{Size: uint32(len(b)), Data: &b[0]}
+	var out windows.DataBlob
+
+	var err error
+	if encrypt {
+		err = windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	} else {
+		err = windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+
+	return append([]byte(nil), unsafe.Slice(out.Data, out.Size)...), nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tokenstore stores cached justification tokens on developer machines,
+// preferring the OS keychain (macOS Keychain, Windows DPAPI, or libsecret on
+// Linux) over plaintext files.
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Service is the keychain service name that tokens are stored under.
+const Service = "jvsctl"
+
+var (
+	// ErrNotFound is returned when there is no value for a key.
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnsupported is returned when the OS keychain is not available on this
+	// machine.
+	ErrUnsupported = errors.New("os keychain is not supported on this machine")
+)
+
+// Store is a key-value store for secrets. Keys must be safe to use as file
+// names.
+type Store interface {
+	// Get returns the value for the key, or [ErrNotFound].
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores the value for the key, overwriting any existing value.
+	Set(ctx context.Context, key string, value []byte) error
+
+	// Delete removes the value for the key. It is not an error if there is no
+	// value.
+	Delete(ctx context.Context, key string) error
+}
+
+// Storage backends for [New].
+const (
+	StorageAuto     = "auto"
+	StorageKeychain = "keychain"
+	StorageFile     = "file"
+)
+
+// New returns the store for the given storage backend. "keychain" uses the OS
+// keychain and fails if it is unavailable, "file" uses plaintext files in dir,
+// and "auto" uses the OS keychain and falls back to files in dir when the
+// keychain is unavailable or fails.
+func New(storage, dir string) (Store, error) {
+	switch storage {
+	case StorageFile:
+		return NewFileStore(dir), nil
+	case StorageKeychain:
+		return NewKeychainStore(Service)
+	case StorageAuto, "":
+		if keychain, err := NewKeychainStore(Service); err == nil {
+			return &fallbackStore{primary: keychain, fallback: NewFileStore(dir)}, nil
+		}
+		return NewFileStore(dir), nil
+	default:
+		return nil, fmt.Errorf("unknown token storage %q, must be one of %q, %q, or %q",
+			storage, StorageAuto, StorageKeychain, StorageFile)
+	}
+}
+
+// fallbackStore uses the primary store, and the fallback store when the
+// primary fails, e.g. because there is no keyring daemon running.
+type fallbackStore struct {
+	primary  Store
+	fallback Store
+}
+
+func (s *fallbackStore) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := s.primary.Get(ctx, key)
+	if err == nil {
+		return v, nil
+	}
+	return s.fallback.Get(ctx, key)
+}
+
+func (s *fallbackStore) Set(ctx context.Context, key string, value []byte) error {
+	if err := s.primary.Set(ctx, key, value); err != nil {
+		return s.fallback.Set(ctx, key, value)
+	}
+	// Don't leave a stale plaintext copy behind.
+	return s.fallback.Delete(ctx, key)
+}
+
+func (s *fallbackStore) Delete(ctx context.Context, key string) error {
+	return errors.Join(s.primary.Delete(ctx, key), s.fallback.Delete(ctx, key))
+}
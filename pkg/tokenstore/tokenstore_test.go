@@ -0,0 +1,171 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "tokens")
+	s := NewFileStore(dir)
+
+	if _, err := s.Get(ctx, "foo"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %v to be %v", err, ErrNotFound)
+	}
+
+	if err := s.Set(ctx, "foo", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, "foo", []byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(got), "baz"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(dir, "foo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := info.Mode().Perm(), os.FileMode(0o600); got != want {
+			t.Errorf("expected mode %o to be %o", got, want)
+		}
+	}
+
+	if err := s.Delete(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "foo"); err != nil {
+		t.Errorf("expected deleting a missing key to succeed: %v", err)
+	}
+	if _, err := s.Get(ctx, "foo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v to be %v", err, ErrNotFound)
+	}
+}
+
+func TestFallbackStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cases := []struct {
+		name         string
+		primary      Store
+		wantFallback bool
+	}{
+		{
+			name:    "primary_works",
+			primary: NewFileStore(t.TempDir()),
+		},
+		{
+			name:         "primary_fails",
+			primary:      &brokenStore{},
+			wantFallback: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fallback := NewFileStore(t.TempDir())
+			s := &fallbackStore{primary: tc.primary, fallback: fallback}
+
+			if err := s.Set(ctx, "foo", []byte("bar")); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.Get(ctx, "foo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(got), "bar"; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+
+			_, err = fallback.Get(ctx, "foo")
+			if got, want := err == nil, tc.wantFallback; got != want {
+				t.Errorf("expected value in fallback %t to be %t", got, want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		storage string
+		wantErr string
+	}{
+		{
+			name:    "file",
+			storage: StorageFile,
+		},
+		{
+			name:    "auto",
+			storage: StorageAuto,
+		},
+		{
+			name:    "unknown",
+			storage: "cloud",
+			wantErr: `unknown token storage "cloud"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tc.storage, t.TempDir())
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+type brokenStore struct{}
+
+func (s *brokenStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, fmt.Errorf("broken")
+}
+
+func (s *brokenStore) Set(ctx context.Context, key string, value []byte) error {
+	return fmt.Errorf("broken")
+}
+
+func (s *brokenStore) Delete(ctx context.Context, key string) error {
+	return fmt.Errorf("broken")
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/abcxyz/jvs/pkg/audit"
+)
+
+const (
+	// rekorEntriesPath is the path of the Rekor API that creates log entries.
+	rekorEntriesPath = "api/v1/log/entries"
+
+	// maxResponseSize is the maximum size of a Rekor response body.
+	maxResponseSize = 1 << 20
+)
+
+var _ Log = (*Rekor)(nil)
+
+// Rekor is a [Log] backed by a Rekor transparency log, e.g. the public
+// Sigstore instance at https://rekor.sigstore.dev. Digests are published as
+// "hashedrekord" entries, which Rekor verifies against the signature and
+// public key, so anyone can look up an entry by its digest and check it was
+// signed by the JVS.
+type Rekor struct {
+	client *http.Client
+	url    string
+}
+
+// NewRekor creates a new log for the Rekor instance at the URL.
+func NewRekor(client *http.Client, url string) *Rekor {
+	return &Rekor{
+		client: client,
+		url:    url,
+	}
+}
+
+// hashedRekord is the request body of a "hashedrekord" log entry.
+type hashedRekord struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Spec       hashedRekordSpec `json:"spec"`
+}
+
+type hashedRekordSpec struct {
+	Signature struct {
+		Content   string `json:"content"`
+		PublicKey struct {
+			Content string `json:"content"`
+		} `json:"publicKey"`
+	} `json:"signature"`
+	Data struct {
+		Hash struct {
+			Algorithm string `json:"algorithm"`
+			Value     string `json:"value"`
+		} `json:"hash"`
+	} `json:"data"`
+}
+
+// rekorLogEntry is a log entry in a Rekor response.
+type rekorLogEntry struct {
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+		InclusionProof       *struct {
+			LogIndex   int64    `json:"logIndex"`
+			TreeSize   int64    `json:"treeSize"`
+			RootHash   string   `json:"rootHash"`
+			Hashes     []string `json:"hashes"`
+			Checkpoint string   `json:"checkpoint"`
+		} `json:"inclusionProof"`
+	} `json:"verification"`
+}
+
+// Publish implements [Log].
+func (r *Rekor) Publish(ctx context.Context, digest []byte, signer crypto.Signer) (*audit.Transparency, error) {
+	if got, want := len(digest), sha256.Size; got != want {
+		return nil, fmt.Errorf("expected a %d byte sha256 digest, got %d bytes", want, got)
+	}
+
+	sig, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign digest: %w", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	var entry hashedRekord
+	entry.APIVersion = "0.0.1"
+	entry.Kind = "hashedrekord"
+	entry.Spec.Signature.Content = base64.StdEncoding.EncodeToString(sig)
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(publicKey)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(digest)
+
+	body, err := json.Marshal(&entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal log entry: %w", err)
+	}
+
+	u, err := url.JoinPath(r.url, rekorEntriesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build log entries url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build log entry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log entry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody := io.LimitReader(resp.Body, maxResponseSize)
+	if resp.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(respBody)
+		return nil, fmt.Errorf("failed to create log entry: status %d: %s", resp.StatusCode, b)
+	}
+
+	// The response maps the UUID of the created entry to the entry.
+	var result map[string]*rekorLogEntry
+	if err := json.NewDecoder(respBody).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse log entry: %w", err)
+	}
+	if got, want := len(result), 1; got != want {
+		return nil, fmt.Errorf("expected %d log entry, got %d", want, got)
+	}
+	var uuid string
+	var e *rekorLogEntry
+	for k, v := range result {
+		uuid, e = k, v
+	}
+	if e == nil {
+		return nil, fmt.Errorf("log entry %s is empty", uuid)
+	}
+
+	t := &audit.Transparency{
+		Digest:               entry.Spec.Data.Hash.Value,
+		LogURL:               r.url,
+		UUID:                 uuid,
+		LogID:                e.LogID,
+		LogIndex:             e.LogIndex,
+		IntegratedTime:       time.Unix(e.IntegratedTime, 0).UTC(),
+		SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+	}
+	if p := e.Verification.InclusionProof; p != nil {
+		t.InclusionProof = &audit.InclusionProof{
+			LogIndex:   p.LogIndex,
+			TreeSize:   p.TreeSize,
+			RootHash:   p.RootHash,
+			Hashes:     p.Hashes,
+			Checkpoint: p.Checkpoint,
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transparency
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/abcxyz/jvs/pkg/audit"
+	"github.com/abcxyz/pkg/testutil"
+)
+
+func TestRekor_Publish(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(`{"jti":"test-jti"}`))
+
+	const created = `{"24296fb24b8ad77a": {
+		"integratedTime": 1700000000,
+		"logID": "c0d23d6ad406973f",
+		"logIndex": 42,
+		"verification": {
+			"signedEntryTimestamp": "MEUCIQ==",
+			"inclusionProof": {
+				"logIndex": 41,
+				"treeSize": 100,
+				"rootHash": "5be1758dd2228acf",
+				"hashes": ["ab", "cd"],
+				"checkpoint": "rekor.sigstore.dev - 2605736670972794746\n100\n"
+			}
+		}
+	}}`
+
+	cases := []struct {
+		name    string
+		digest  []byte
+		status  int
+		body    string
+		want    *audit.Transparency
+		wantErr string
+	}{
+		{
+			name:   "created",
+			digest: digest[:],
+			status: http.StatusCreated,
+			body:   created,
+			want: &audit.Transparency{
+				Digest:               hex.EncodeToString(digest[:]),
+				UUID:                 "24296fb24b8ad77a",
+				LogID:                "c0d23d6ad406973f",
+				LogIndex:             42,
+				IntegratedTime:       time.Unix(1700000000, 0).UTC(),
+				SignedEntryTimestamp: "MEUCIQ==",
+				InclusionProof: &audit.InclusionProof{
+					LogIndex:   41,
+					TreeSize:   100,
+					RootHash:   "5be1758dd2228acf",
+					Hashes:     []string{"ab", "cd"},
+					Checkpoint: "rekor.sigstore.dev - 2605736670972794746\n100\n",
+				},
+			},
+		},
+		{
+			name:    "invalid_digest",
+			digest:  []byte("not a digest"),
+			wantErr: "expected a 32 byte sha256 digest, got 12 bytes",
+		},
+		{
+			name:    "server_error",
+			digest:  digest[:],
+			status:  http.StatusBadRequest,
+			body:    `{"code": 400, "message": "invalid signature"}`,
+			wantErr: "failed to create log entry: status 400",
+		},
+		{
+			name:    "no_entries",
+			digest:  digest[:],
+			status:  http.StatusCreated,
+			body:    `{}`,
+			wantErr: "expected 1 log entry, got 0",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got, want := r.URL.Path, "/api/v1/log/entries"; got != want {
+					t.Errorf("expected path %q to be %q", got, want)
+				}
+
+				var entry hashedRekord
+				if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+					t.Errorf("failed to decode log entry: %v", err)
+				}
+				verifyHashedRekord(t, &entry, tc.digest, &key.PublicKey)
+
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			t.Cleanup(srv.Close)
+
+			got, err := NewRekor(srv.Client(), srv.URL).Publish(ctx, tc.digest, key)
+			if diff := testutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Fatal(diff)
+			}
+			if tc.want != nil {
+				tc.want.LogURL = srv.URL
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("transparency (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// verifyHashedRekord checks the entry is what Rekor accepts: the signature
+// over the digest verifies with the public key.
+func verifyHashedRekord(tb testing.TB, entry *hashedRekord, digest []byte, publicKey *ecdsa.PublicKey) {
+	tb.Helper()
+
+	if got, want := entry.Kind, "hashedrekord"; got != want {
+		tb.Errorf("expected kind %q to be %q", got, want)
+	}
+	if got, want := entry.Spec.Data.Hash.Algorithm, "sha256"; got != want {
+		tb.Errorf("expected hash algorithm %q to be %q", got, want)
+	}
+	if got, want := entry.Spec.Data.Hash.Value, hex.EncodeToString(digest); got != want {
+		tb.Errorf("expected hash %q to be %q", got, want)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		tb.Fatalf("expected public key %q to be pem", b)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if !publicKey.Equal(pub) {
+		tb.Errorf("expected public key to be the signer's")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.Content)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(publicKey, digest, sig) {
+		tb.Errorf("failed to verify signature")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transparency publishes the digests of issuance audit entries to
+// transparency logs, which makes tampering with the audit log evident. Only
+// digests are published, never the entries themselves.
+package transparency
+
+import (
+	"context"
+	"crypto"
+
+	"github.com/abcxyz/jvs/pkg/audit"
+)
+
+// Log is an append-only transparency log.
+type Log interface {
+	// Publish adds the SHA-256 digest, signed by the signer, to the log. It
+	// returns the record of the log entry, including its inclusion proof.
+	Publish(ctx context.Context, digest []byte, signer crypto.Signer) (*audit.Transparency, error)
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/abcxyz/jvs/pkg/config"
 	"github.com/abcxyz/jvs/pkg/controller"
 	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/jvs/pkg/uiauth"
 	"github.com/abcxyz/pkg/logging"
 	"github.com/abcxyz/pkg/renderer"
 )
@@ -30,6 +31,7 @@ import (
 // Server holds the parsed html templates.
 type Server struct {
 	c      *controller.Controller
+	oidc   *uiauth.OIDC
 	config *config.UIServiceConfig
 }
 
@@ -52,9 +54,20 @@ func NewServer(ctx context.Context, uiCfg *config.UIServiceConfig, p *justificat
 	if err != nil {
 		return nil, fmt.Errorf("failed to create controller: %w", err)
 	}
+	uic.WithConfirmation(uiCfg.ConfirmSubmission)
+
+	var oidc *uiauth.OIDC
+	if uiCfg.AuthMode == config.UIAuthModeOIDC {
+		oidc, err = uiauth.NewOIDC(ctx, uiCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create oidc authenticator: %w", err)
+		}
+		uic.WithAuthenticator(oidc)
+	}
 
 	return &Server{
 		c:      uic,
+		oidc:   oidc,
 		config: uiCfg,
 	}, nil
 }
@@ -74,7 +87,13 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 	// See: https://cloud.google.com/run/docs/issues#ah
 	mux.Handle("/health", s.c.HandleHealth())
 	mux.Handle("/static/", http.StripPrefix("/static/", fileServer))
-	mux.Handle("/popup", s.c.HandlePopup())
+	if s.oidc != nil {
+		mux.Handle(uiauth.LoginPath, s.oidc.HandleLogin())
+		mux.Handle(uiauth.CallbackPath, s.oidc.HandleCallback())
+		mux.Handle("/popup", s.oidc.RequireSession(s.c.HandlePopup()))
+	} else {
+		mux.Handle("/popup", s.c.HandlePopup())
+	}
 
 	// Middleware
 	root := logging.HTTPInterceptor(logger, s.config.ProjectID)(mux)
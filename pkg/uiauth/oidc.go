@@ -0,0 +1,381 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package uiauth authenticates users of the JVS UI without relying on
+// Identity-Aware Proxy, by performing an OIDC authorization code flow and
+// tracking the signed-in user in a signed session cookie.
+package uiauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/controller"
+	"github.com/abcxyz/jvs/pkg/idtoken"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// SessionCookieName is the name of the cookie holding the signed-in user.
+	SessionCookieName = "jvs_session"
+
+	// stateCookieName is the name of the short-lived cookie holding the state
+	// and nonce of an in-flight login.
+	stateCookieName = "jvs_oidc_state"
+
+	// stateTTL is how long a user has to complete a login with the provider.
+	stateTTL = 10 * time.Minute
+
+	// keysRefreshInterval is the interval between refreshes of the
+	// provider's JWKS.
+	keysRefreshInterval = 15 * time.Minute
+
+	// LoginPath and CallbackPath are the paths the handlers are expected to be
+	// mounted at.
+	LoginPath    = "/auth/login"
+	CallbackPath = "/auth/callback"
+)
+
+var _ controller.Authenticator = (*OIDC)(nil)
+
+// OIDC authenticates users with an OIDC provider.
+type OIDC struct {
+	oauth      *oauth2.Config
+	verifier   *idtoken.Verifier
+	sessionKey []byte
+	sessionTTL time.Duration
+	secure     bool
+
+	// nowFunc is used to override the current time in tests.
+	nowFunc func() time.Time
+}
+
+// discoveryDocument is the subset of the OIDC provider metadata that is used.
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// session is the payload of both the session and state cookies.
+type session struct {
+	Email     string `json:"email,omitempty"`
+	State     string `json:"state,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+	ReturnTo  string `json:"return_to,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewOIDC discovers the provider configured in cfg and returns an
+// authenticator for it.
+func NewOIDC(ctx context.Context, cfg *config.UIServiceConfig) (*OIDC, error) {
+	sessionKey, err := cfg.SessionKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := discover(ctx, cfg.OIDCIssuer)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := jvspb.NewJWKSProvider(ctx, doc.JWKSURI, jvspb.WithJWKSRefreshInterval(keysRefreshInterval))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oidc jwks provider: %w", err)
+	}
+
+	return &OIDC{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  doc.AuthorizationEndpoint,
+				TokenURL: doc.TokenEndpoint,
+			},
+			Scopes: []string{"openid", "email"},
+		},
+		verifier:   idtoken.NewVerifier(keys, doc.Issuer, cfg.OIDCClientID),
+		sessionKey: sessionKey,
+		sessionTTL: cfg.SessionTTL,
+		secure:     strings.HasPrefix(cfg.OIDCRedirectURL, "https://"),
+		nowFunc:    time.Now,
+	}, nil
+}
+
+// discover fetches the provider metadata of the given issuer.
+func discover(ctx context.Context, issuer string) (*discoveryDocument, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build oidc discovery request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch oidc discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch oidc discovery document: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode oidc discovery document: %w", err)
+	}
+
+	if got, want := doc.Issuer, issuer; got != want {
+		return nil, fmt.Errorf("oidc discovery issuer %q does not match configured issuer %q", got, want)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is missing endpoints")
+	}
+	return &doc, nil
+}
+
+// Email implements [controller.Authenticator]. It returns the email of the
+// signed-in user from the session cookie.
+func (o *OIDC) Email(r *http.Request) (string, error) {
+	s, err := o.readCookie(r, SessionCookieName)
+	if err != nil {
+		return "", fmt.Errorf("not signed in: %w", err)
+	}
+	if s.Email == "" {
+		return "", fmt.Errorf("not signed in: session has no email")
+	}
+	return s.Email, nil
+}
+
+// RequireSession redirects GET requests without a valid session to the login
+// page, and rejects any other requests without a valid session.
+func (o *OIDC) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := o.Email(r); err != nil {
+			if r.Method != http.MethodGet {
+				http.Error(w, "not signed in", http.StatusUnauthorized)
+				return
+			}
+
+			q := url.Values{"return_to": {r.URL.RequestURI()}}
+			http.Redirect(w, r, LoginPath+"?"+q.Encode(), http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleLogin starts a login by redirecting to the provider.
+func (o *OIDC) HandleLogin() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state, err := randomString()
+		if err != nil {
+			http.Error(w, "failed to start login", http.StatusInternalServerError)
+			return
+		}
+		nonce, err := randomString()
+		if err != nil {
+			http.Error(w, "failed to start login", http.StatusInternalServerError)
+			return
+		}
+
+		if err := o.writeCookie(w, stateCookieName, &session{
+			State:     state,
+			Nonce:     nonce,
+			ReturnTo:  safeReturnTo(r.URL.Query().Get("return_to")),
+			ExpiresAt: o.nowFunc().Add(stateTTL).Unix(),
+		}, stateTTL); err != nil {
+			http.Error(w, "failed to start login", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, o.oauth.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce)), http.StatusFound)
+	})
+}
+
+// HandleCallback completes a login by exchanging the authorization code for an
+// ID token and starting a session for its email.
+func (o *OIDC) HandleCallback() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx)
+
+		pending, err := o.readCookie(r, stateCookieName)
+		if err != nil {
+			http.Error(w, "login expired, please try again", http.StatusBadRequest)
+			return
+		}
+		o.clearCookie(w, stateCookieName)
+
+		q := r.URL.Query()
+		if e := q.Get("error"); e != "" {
+			http.Error(w, fmt.Sprintf("login failed: %s", e), http.StatusUnauthorized)
+			return
+		}
+		if !hmac.Equal([]byte(q.Get("state")), []byte(pending.State)) {
+			http.Error(w, "login state mismatch, please try again", http.StatusBadRequest)
+			return
+		}
+
+		email, err := o.exchange(ctx, q.Get("code"), pending.Nonce)
+		if err != nil {
+			logger.WarnContext(ctx, "failed to complete oidc login", "error", err)
+			http.Error(w, "login failed", http.StatusUnauthorized)
+			return
+		}
+
+		if err := o.writeCookie(w, SessionCookieName, &session{
+			Email:     email,
+			ExpiresAt: o.nowFunc().Add(o.sessionTTL).Unix(),
+		}, o.sessionTTL); err != nil {
+			http.Error(w, "failed to start session", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, safeReturnTo(pending.ReturnTo), http.StatusFound)
+	})
+}
+
+// exchange redeems the authorization code and returns the verified email of
+// the ID token.
+func (o *OIDC) exchange(ctx context.Context, code, nonce string) (string, error) {
+	tok, err := o.oauth.Exchange(ctx, code)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	raw, ok := tok.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return "", fmt.Errorf("token response has no id_token")
+	}
+
+	idToken, err := o.verifier.Verify(ctx, raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify id token: %w", err)
+	}
+
+	if got, _ := idToken.Get("nonce"); got != nonce {
+		return "", fmt.Errorf("id token nonce mismatch")
+	}
+	email, err := idtoken.VerifiedEmail(idToken)
+	if err != nil {
+		return "", fmt.Errorf("invalid id token: %w", err)
+	}
+	return email, nil
+}
+
+// writeCookie signs the session and sets it as a cookie.
+func (o *OIDC) writeCookie(w http.ResponseWriter, name string, s *session, ttl time.Duration) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    payload + "." + o.sign(name, payload),
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   o.secure,
+		// Lax is required so the cookies are sent on the redirect back from the
+		// provider.
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// readCookie returns the session from the named cookie if its signature is
+// valid and it has not expired.
+func (o *OIDC) readCookie(r *http.Request, name string) (*session, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return nil, fmt.Errorf("missing cookie: %w", err)
+	}
+
+	payload, sig, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed cookie")
+	}
+	if !hmac.Equal([]byte(sig), []byte(o.sign(name, payload))) {
+		return nil, fmt.Errorf("invalid cookie signature")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cookie: %w", err)
+	}
+	var s session
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("malformed cookie: %w", err)
+	}
+
+	if o.nowFunc().Unix() >= s.ExpiresAt {
+		return nil, fmt.Errorf("session expired")
+	}
+	return &s, nil
+}
+
+// clearCookie removes the named cookie.
+func (o *OIDC) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   o.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// sign returns the signature of the payload. The cookie name is included so a
+// state cookie can never be replayed as a session cookie.
+func (o *OIDC) sign(name, payload string) string {
+	mac := hmac.New(sha256.New, o.sessionKey)
+	mac.Write([]byte(name + "." + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// safeReturnTo only allows redirects to paths on this server, to prevent open
+// redirects.
+func safeReturnTo(s string) string {
+	if !strings.HasPrefix(s, "/") || strings.HasPrefix(s, "//") || strings.HasPrefix(s, "/\\") {
+		return "/popup"
+	}
+	return s
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,401 @@
+// Copyright 2023 The Authors (see AUTHORS file)
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uiauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/abcxyz/jvs/pkg/config"
+)
+
+const testClientID = "test-client"
+
+// testProvider is a fake OIDC provider. The email and email_verified claims of
+// the issued ID tokens are controlled by the fields.
+type testProvider struct {
+	srv           *httptest.Server
+	key           jwk.Key
+	email         string
+	emailVerified bool
+
+	// nonce is the nonce of the last authorization request.
+	nonce string
+}
+
+func newTestProvider(tb testing.TB) *testProvider {
+	tb.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	key, err := jwk.FromRaw(privateKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := key.Set(jwk.KeyIDKey, "test-key"); err != nil {
+		tb.Fatal(err)
+	}
+	if err := key.Set(jwk.AlgorithmKey, jwa.ES256); err != nil {
+		tb.Fatal(err)
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	p := &testProvider{
+		key:           key,
+		email:         "user@example.com",
+		emailVerified: true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.srv.URL,
+			"authorization_endpoint": p.srv.URL + "/authorize",
+			"token_endpoint":         p.srv.URL + "/token",
+			"jwks_uri":               p.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []jwk.Key{publicKey}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		token, err := jwt.NewBuilder().
+			Issuer(p.srv.URL).
+			Audience([]string{testClientID}).
+			Subject("1234").
+			IssuedAt(now).
+			Expiration(now.Add(5*time.Minute)).
+			Claim("email", p.email).
+			Claim("email_verified", p.emailVerified).
+			Claim("nonce", p.nonce).
+			Build()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		b, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, p.key))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+			"id_token":     string(b),
+		})
+	})
+
+	p.srv = httptest.NewServer(mux)
+	tb.Cleanup(p.srv.Close)
+	return p
+}
+
+func testOIDC(tb testing.TB, p *testProvider) *OIDC {
+	tb.Helper()
+
+	o, err := NewOIDC(context.Background(), &config.UIServiceConfig{
+		AuthMode:         config.UIAuthModeOIDC,
+		OIDCIssuer:       p.srv.URL,
+		OIDCClientID:     testClientID,
+		OIDCClientSecret: "secret",
+		OIDCRedirectURL:  "https://jvs-ui.example.com" + CallbackPath,
+		SessionKey:       base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))),
+		SessionTTL:       time.Hour,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return o
+}
+
+// login runs the login and callback handlers and returns the callback response.
+func login(tb testing.TB, o *OIDC, p *testProvider, code string, tamperState bool) *httptest.ResponseRecorder {
+	tb.Helper()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, LoginPath+"?return_to="+url.QueryEscape("/popup?origin=foo"), nil)
+	o.HandleLogin().ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusFound; got != want {
+		tb.Fatalf("expected login status %d to be %d", got, want)
+	}
+
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p.nonce = authURL.Query().Get("nonce")
+	state := authURL.Query().Get("state")
+	if tamperState {
+		state = "not-the-state"
+	}
+
+	cb := httptest.NewRequest(http.MethodGet, CallbackPath+"?"+url.Values{
+		"code":  {code},
+		"state": {state},
+	}.Encode(), nil)
+	for _, c := range w.Result().Cookies() {
+		cb.AddCookie(c)
+	}
+
+	cw := httptest.NewRecorder()
+	o.HandleCallback().ServeHTTP(cw, cb)
+	return cw
+}
+
+func TestOIDC_Login(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name          string
+		code          string
+		tamperState   bool
+		emailVerified bool
+		wantCode      int
+		wantEmail     string
+	}{
+		{
+			name:          "success",
+			code:          "good-code",
+			emailVerified: true,
+			wantCode:      http.StatusFound,
+			wantEmail:     "user@example.com",
+		},
+		{
+			name:          "bad_code",
+			code:          "bad-code",
+			emailVerified: true,
+			wantCode:      http.StatusUnauthorized,
+		},
+		{
+			name:          "state_mismatch",
+			code:          "good-code",
+			tamperState:   true,
+			emailVerified: true,
+			wantCode:      http.StatusBadRequest,
+		},
+		{
+			name:          "email_not_verified",
+			code:          "good-code",
+			emailVerified: false,
+			wantCode:      http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := newTestProvider(t)
+			p.emailVerified = tc.emailVerified
+			o := testOIDC(t, p)
+
+			w := login(t, o, p, tc.code, tc.tamperState)
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Fatalf("expected callback status %d to be %d: %s", got, want, w.Body.String())
+			}
+			if tc.wantEmail == "" {
+				return
+			}
+
+			if got, want := w.Header().Get("Location"), "/popup?origin=foo"; got != want {
+				t.Errorf("expected redirect %q to be %q", got, want)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/popup", nil)
+			for _, c := range w.Result().Cookies() {
+				r.AddCookie(c)
+			}
+			got, err := o.Email(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tc.wantEmail; got != want {
+				t.Errorf("expected email %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOIDC_Email(t *testing.T) {
+	t.Parallel()
+
+	p := newTestProvider(t)
+	o := testOIDC(t, p)
+
+	validCookie := func(tb testing.TB, s *session) *http.Cookie {
+		tb.Helper()
+
+		w := httptest.NewRecorder()
+		if err := o.writeCookie(w, SessionCookieName, s, time.Hour); err != nil {
+			tb.Fatal(err)
+		}
+		return w.Result().Cookies()[0]
+	}
+
+	cases := []struct {
+		name    string
+		cookie  *http.Cookie
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			cookie: validCookie(t, &session{Email: "user@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()}),
+		},
+		{
+			name:    "missing",
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			cookie:  validCookie(t, &session{Email: "user@example.com", ExpiresAt: time.Now().Add(-time.Hour).Unix()}),
+			wantErr: true,
+		},
+		{
+			name: "tampered",
+			cookie: func() *http.Cookie {
+				c := validCookie(t, &session{Email: "user@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+				_, sig, _ := strings.Cut(c.Value, ".")
+				payload := base64.RawURLEncoding.EncodeToString([]byte(`{"email":"admin@example.com","exp":9999999999}`))
+				c.Value = payload + "." + sig
+				return c
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "state_cookie_replayed",
+			cookie: func() *http.Cookie {
+				w := httptest.NewRecorder()
+				if err := o.writeCookie(w, stateCookieName, &session{Email: "user@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()}, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+				c := w.Result().Cookies()[0]
+				c.Name = SessionCookieName
+				return c
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/popup", nil)
+			if tc.cookie != nil {
+				r.AddCookie(tc.cookie)
+			}
+
+			_, err := o.Email(r)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("expected error %t to be %t: %v", got, want, err)
+			}
+		})
+	}
+}
+
+func TestOIDC_RequireSession(t *testing.T) {
+	t.Parallel()
+
+	p := newTestProvider(t)
+	o := testOIDC(t, p)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	cases := []struct {
+		name         string
+		method       string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "get_redirects",
+			method:       http.MethodGet,
+			wantCode:     http.StatusFound,
+			wantLocation: LoginPath + "?return_to=%2Fpopup%3Forigin%3Dfoo",
+		},
+		{
+			name:     "post_rejected",
+			method:   http.MethodPost,
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "/popup?origin=foo", nil)
+			o.RequireSession(next).ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.wantCode; got != want {
+				t.Errorf("expected status %d to be %d", got, want)
+			}
+			if got, want := w.Header().Get("Location"), tc.wantLocation; got != want {
+				t.Errorf("expected location %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in  string
+		exp string
+	}{
+		{in: "/popup?origin=foo", exp: "/popup?origin=foo"},
+		{in: "", exp: "/popup"},
+		{in: "https://evil.example.com", exp: "/popup"},
+		{in: "//evil.example.com", exp: "/popup"},
+		{in: "/\\evil.example.com", exp: "/popup"},
+	}
+
+	for _, tc := range cases {
+		if got, want := safeReturnTo(tc.in), tc.exp; got != want {
+			t.Errorf("safeReturnTo(%q): expected %q to be %q", tc.in, got, want)
+		}
+	}
+}
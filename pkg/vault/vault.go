@@ -0,0 +1,438 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vault manages signing keys in the transit secrets engine of
+// HashiCorp Vault.
+//
+// Keys are ecdsa-p256 transit keys, which must be created in Vault before JVS
+// uses them. The version name is "[KEY]/versions/[N]", where N is the transit
+// key version. Creating a version rotates the key. Versions below the
+// min_decryption_version of the key are disabled, and versions below its
+// min_available_version are destroyed by trimming the key. The primary version
+// is the min_encryption_version of the key, so Vault refuses to sign with
+// older versions.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+)
+
+const (
+	// keyType is the only transit key type JVS signs with.
+	keyType = "ecdsa-p256"
+
+	// versionsSeparator separates the key name and the version number in
+	// version names.
+	versionsSeparator = "/versions/"
+
+	// signaturePrefix prefixes signatures returned by the transit engine.
+	signaturePrefix = "vault:v"
+
+	// maxResponseSize is the maximum size of a Vault response body.
+	maxResponseSize = 1 << 20
+)
+
+// KeyManager manages the versions of keys in the Vault transit engine. It
+// implements [jvscrypto.KeyManager]. Key names are the names of the transit
+// keys.
+type KeyManager struct {
+	config *config.VaultConfig
+	client *http.Client
+}
+
+var _ jvscrypto.KeyManager = (*KeyManager)(nil)
+
+// NewKeyManager creates a new key manager for the Vault server.
+func NewKeyManager(cfg *config.VaultConfig) *KeyManager {
+	return &KeyManager{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// transitKey is a key read from the transit engine.
+type transitKey struct {
+	Type                 string                    `json:"type"`
+	Keys                 map[string]*transitKeyVer `json:"keys"`
+	LatestVersion        int                       `json:"latest_version"`
+	MinAvailableVersion  int                       `json:"min_available_version"`
+	MinDecryptionVersion int                       `json:"min_decryption_version"`
+	MinEncryptionVersion int                       `json:"min_encryption_version"`
+}
+
+// transitKeyVer is a version of an asymmetric transit key.
+type transitKeyVer struct {
+	CreationTime time.Time `json:"creation_time"`
+	PublicKey    string    `json:"public_key"`
+}
+
+// ListVersions returns the versions of the key, oldest first.
+func (m *KeyManager) ListVersions(ctx context.Context, key string) ([]*kmspb.CryptoKeyVersion, error) {
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	vers := make([]*kmspb.CryptoKeyVersion, 0, len(tk.Keys))
+	for n := range tk.Keys {
+		num, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, fmt.Errorf("key %s has invalid version %q: %w", key, n, err)
+		}
+		vers = append(vers, tk.version(key, num))
+	}
+	sort.Slice(vers, func(i, j int) bool {
+		return vers[i].GetCreateTime().AsTime().Before(vers[j].GetCreateTime().AsTime())
+	})
+	return vers, nil
+}
+
+// GetVersion returns the version with the name.
+func (m *KeyManager) GetVersion(ctx context.Context, version string) (*kmspb.CryptoKeyVersion, error) {
+	key, num, err := parseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tk.Keys[strconv.Itoa(num)]; !ok {
+		return nil, fmt.Errorf("version %s not found", version)
+	}
+	return tk.version(key, num), nil
+}
+
+// GetPrimary returns the name of the primary version of the key, or "" if it
+// has none.
+func (m *KeyManager) GetPrimary(ctx context.Context, key string) (string, error) {
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if tk.MinEncryptionVersion == 0 {
+		return "", nil
+	}
+	return versionName(key, tk.MinEncryptionVersion), nil
+}
+
+// SetPrimary sets the min_encryption_version of the key to the version.
+func (m *KeyManager) SetPrimary(ctx context.Context, key, version string) error {
+	verKey, num, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+	if verKey != key {
+		return fmt.Errorf("version %s is not a version of key %s", version, key)
+	}
+
+	if err := m.do(ctx, http.MethodPost, "keys/"+url.PathEscape(key)+"/config", map[string]any{
+		"min_encryption_version": num,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to set primary of key %s: %w", key, err)
+	}
+	return nil
+}
+
+// CreateVersion rotates the key, and returns the new version.
+func (m *KeyManager) CreateVersion(ctx context.Context, key string) (*kmspb.CryptoKeyVersion, error) {
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "creating new key version")
+
+	if err := m.do(ctx, http.MethodPost, "keys/"+url.PathEscape(key)+"/rotate", map[string]any{}, nil); err != nil {
+		return nil, fmt.Errorf("key creation failed: %w", err)
+	}
+
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return tk.version(key, tk.LatestVersion), nil
+}
+
+// DisableVersion raises the min_decryption_version of the key above the
+// version. Older versions are disabled too.
+func (m *KeyManager) DisableVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "disabling key version", "version_name", ver.GetName())
+
+	key, num, err := parseVersion(ver.GetName())
+	if err != nil {
+		return err
+	}
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if num < tk.MinDecryptionVersion {
+		return nil
+	}
+
+	if err := m.do(ctx, http.MethodPost, "keys/"+url.PathEscape(key)+"/config", map[string]any{
+		"min_decryption_version": num + 1,
+	}, nil); err != nil {
+		return fmt.Errorf("key disable failed: %w", err)
+	}
+	return nil
+}
+
+// DestroyVersion trims the key up to and including the version. Older
+// versions are destroyed too, and the version must be disabled first.
+func (m *KeyManager) DestroyVersion(ctx context.Context, ver *kmspb.CryptoKeyVersion) error {
+	logger := logging.FromContext(ctx)
+	logger.InfoContext(ctx, "destroying key version", "version_name", ver.GetName())
+
+	key, num, err := parseVersion(ver.GetName())
+	if err != nil {
+		return err
+	}
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if num < tk.MinAvailableVersion {
+		return nil
+	}
+
+	if err := m.do(ctx, http.MethodPost, "keys/"+url.PathEscape(key)+"/trim", map[string]any{
+		"min_available_version": num + 1,
+	}, nil); err != nil {
+		return fmt.Errorf("key destroy failed: %w", err)
+	}
+	return nil
+}
+
+// PublicKey returns the public key of the version.
+func (m *KeyManager) PublicKey(ctx context.Context, version string) (crypto.PublicKey, error) {
+	return m.publicKey(ctx, version)
+}
+
+// Signer returns a signer that signs with the version in the transit engine.
+func (m *KeyManager) Signer(ctx context.Context, version string) (crypto.Signer, error) {
+	key, num, err := parseVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	pub, err := m.publicKey(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	return &signer{
+		ctx: context.WithoutCancel(ctx),
+		m:   m,
+		key: key,
+		num: num,
+		pub: pub,
+	}, nil
+}
+
+// signer signs digests with a version of a transit key.
+type signer struct {
+	// ctx is the context the signer was created with, without its
+	// cancellation, since [crypto.Signer] doesn't take one and signers are
+	// cached beyond the request that created them.
+	ctx context.Context //nolint:containedctx // Sign has no context.
+
+	m   *KeyManager
+	key string
+	num int
+	pub *ecdsa.PublicKey
+}
+
+// Public returns the public key of the signer.
+func (s *signer) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign signs the SHA-256 digest, and returns the ASN.1 DER encoded signature,
+// like [ecdsa.PrivateKey.Sign].
+func (s *signer) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	if err := s.m.do(s.ctx, http.MethodPost, "sign/"+url.PathEscape(s.key)+"/sha2-256", map[string]any{
+		"input":                base64.StdEncoding.EncodeToString(digest),
+		"prehashed":            true,
+		"key_version":          s.num,
+		"marshaling_algorithm": "asn1",
+	}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	// Signatures are in the format "vault:v[N]:[BASE64]".
+	_, sig, ok := strings.Cut(strings.TrimPrefix(resp.Signature, signaturePrefix), ":")
+	if !ok || !strings.HasPrefix(resp.Signature, signaturePrefix) {
+		return nil, fmt.Errorf("unexpected signature format %q", resp.Signature)
+	}
+	der, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	return der, nil
+}
+
+// publicKey reads the public key of the version.
+func (m *KeyManager) publicKey(ctx context.Context, version string) (*ecdsa.PublicKey, error) {
+	key, num, err := parseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	tk, err := m.readKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	ver, ok := tk.Keys[strconv.Itoa(num)]
+	if !ok {
+		return nil, fmt.Errorf("version %s not found", version)
+	}
+
+	block, _ := pem.Decode([]byte(ver.PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("public key of version %s is not PEM encoded", version)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key of version %s: %w", version, err)
+	}
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key of version %s is %T, not ECDSA", version, pub)
+	}
+	return ecPub, nil
+}
+
+// readKey reads the key from the transit engine, and checks that it can sign
+// JVS tokens.
+func (m *KeyManager) readKey(ctx context.Context, key string) (*transitKey, error) {
+	var tk transitKey
+	if err := m.do(ctx, http.MethodGet, "keys/"+url.PathEscape(key), nil, &tk); err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", key, err)
+	}
+	if tk.Type != keyType {
+		return nil, fmt.Errorf("key %s is of type %q, not %q", key, tk.Type, keyType)
+	}
+	return &tk, nil
+}
+
+// do sends a request to the transit engine, and decodes the data of the
+// response into out, if it is not nil.
+func (m *KeyManager) do(ctx context.Context, method, path string, in, out any) error {
+	u, err := url.JoinPath(m.config.Address, "v1", m.config.TransitMount)
+	if err != nil {
+		return fmt.Errorf("failed to build vault url: %w", err)
+	}
+	u += "/" + path
+
+	var reqBody io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", m.config.Token)
+	if m.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", m.config.Namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxResponseSize)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(body)
+		return fmt.Errorf("%s %s: status %d: %s", method, req.URL.Path, resp.StatusCode, bytes.TrimSpace(b))
+	}
+	if out == nil {
+		return nil
+	}
+
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("failed to parse response data: %w", err)
+	}
+	return nil
+}
+
+// version converts the version of the transit key to a key version.
+func (tk *transitKey) version(key string, num int) *kmspb.CryptoKeyVersion {
+	state := kmspb.CryptoKeyVersion_ENABLED
+	if num < tk.MinDecryptionVersion {
+		state = kmspb.CryptoKeyVersion_DISABLED
+	}
+
+	ver := &kmspb.CryptoKeyVersion{
+		Name:  versionName(key, num),
+		State: state,
+	}
+	if v, ok := tk.Keys[strconv.Itoa(num)]; ok {
+		ver.CreateTime = timestamppb.New(v.CreationTime)
+	}
+	return ver
+}
+
+// versionName returns the name of the version of the key.
+func versionName(key string, num int) string {
+	return key + versionsSeparator + strconv.Itoa(num)
+}
+
+// parseVersion splits the version name into the key name and version number.
+func parseVersion(version string) (string, int, error) {
+	i := strings.LastIndex(version, versionsSeparator)
+	if i > 0 {
+		if num, err := strconv.Atoi(version[i+len(versionsSeparator):]); err == nil && num > 0 {
+			return version[:i], num, nil
+		}
+	}
+	return "", 0, fmt.Errorf("version %q must be in the format [KEY]%s[N]", version, versionsSeparator)
+}
@@ -0,0 +1,573 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	jvspb "github.com/abcxyz/jvs/apis/v0"
+	"github.com/abcxyz/jvs/pkg/config"
+	"github.com/abcxyz/jvs/pkg/justification"
+	"github.com/abcxyz/jvs/pkg/jvscrypto"
+	"github.com/abcxyz/pkg/logging"
+	"github.com/abcxyz/pkg/renderer"
+	pkgtestutil "github.com/abcxyz/pkg/testutil"
+)
+
+const testToken = "s.test-token"
+
+func TestKeyManager(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	created := time.Unix(1_700_000_000, 0).UTC()
+	transit := newFakeTransit(t)
+	transit.now = func() time.Time { return created }
+	transit.createKey(t, "jvs-signing", "ecdsa-p256")
+	m := testKeyManager(t, transit)
+
+	vers, err := m.ListVersions(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &kmspb.CryptoKeyVersion{
+		Name:       "jvs-signing/versions/1",
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		CreateTime: timestamppb.New(created),
+	}
+	if diff := cmp.Diff([]*kmspb.CryptoKeyVersion{first}, vers, protocmp.Transform()); diff != "" {
+		t.Errorf("versions (-want, +got):\n%s", diff)
+	}
+
+	primary, err := m.GetPrimary(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primary != "" {
+		t.Errorf("expected no primary, got %q", primary)
+	}
+
+	transit.now = func() time.Time { return created.Add(time.Hour) }
+	ver, err := m.CreateVersion(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &kmspb.CryptoKeyVersion{
+		Name:       "jvs-signing/versions/2",
+		State:      kmspb.CryptoKeyVersion_ENABLED,
+		CreateTime: timestamppb.New(created.Add(time.Hour)),
+	}
+	if diff := cmp.Diff(want, ver, protocmp.Transform()); diff != "" {
+		t.Errorf("version (-want, +got):\n%s", diff)
+	}
+
+	if diff := pkgtestutil.DiffErrString(m.SetPrimary(ctx, "other", ver.GetName()),
+		"version jvs-signing/versions/2 is not a version of key other"); diff != "" {
+		t.Error(diff)
+	}
+	if err := m.SetPrimary(ctx, "jvs-signing", ver.GetName()); err != nil {
+		t.Fatal(err)
+	}
+	if err := jvscrypto.CheckPrimaryVersion(ctx, m, "jvs-signing"); err != nil {
+		t.Errorf("expected the primary to be ready: %s", err)
+	}
+
+	signer, err := m.Signer(ctx, ver.GetName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := m.PublicKey(ctx, ver.GetName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], sig) {
+		t.Errorf("expected the signature to verify with the public key")
+	}
+
+	// Vault refuses to sign with versions older than the primary.
+	oldSigner, err := m.Signer(ctx, first.GetName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oldSigner.Sign(rand.Reader, digest[:], crypto.SHA256); err == nil {
+		t.Errorf("expected signing with a version older than the primary to fail")
+	}
+
+	if err := m.DisableVersion(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.GetVersion(ctx, first.GetName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got.GetState(), kmspb.CryptoKeyVersion_DISABLED; got != want {
+		t.Errorf("expected state %s to be %s", got, want)
+	}
+
+	if err := m.DestroyVersion(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if vers, err = m.ListVersions(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*kmspb.CryptoKeyVersion{want}, vers, protocmp.Transform()); diff != "" {
+		t.Errorf("versions (-want, +got):\n%s", diff)
+	}
+	if _, err := m.PublicKey(ctx, first.GetName()); err == nil {
+		t.Errorf("expected the public key to be destroyed")
+	}
+}
+
+func TestKeyManager_Errors(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	transit := newFakeTransit(t)
+	transit.createKey(t, "jvs-signing", "ecdsa-p256")
+	transit.createKey(t, "aes", "aes256-gcm96")
+
+	cases := []struct {
+		name    string
+		token   string
+		version string
+		wantErr string
+	}{
+		{
+			name:    "wrong_token",
+			token:   "s.wrong",
+			version: "jvs-signing/versions/1",
+			wantErr: "status 403",
+		},
+		{
+			name:    "missing_key",
+			token:   testToken,
+			version: "missing/versions/1",
+			wantErr: "failed to read key missing",
+		},
+		{
+			name:    "wrong_key_type",
+			token:   testToken,
+			version: "aes/versions/1",
+			wantErr: `key aes is of type "aes256-gcm96", not "ecdsa-p256"`,
+		},
+		{
+			name:    "missing_version",
+			token:   testToken,
+			version: "jvs-signing/versions/2",
+			wantErr: "version jvs-signing/versions/2 not found",
+		},
+		{
+			name:    "invalid_version",
+			token:   testToken,
+			version: "jvs-signing/versions/latest",
+			wantErr: "must be in the format [KEY]/versions/[N]",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := NewKeyManager(&config.VaultConfig{
+				Address:      transit.server.URL,
+				Token:        tc.token,
+				TransitMount: "transit",
+				Timeout:      10 * time.Second,
+			})
+			_, err := m.PublicKey(ctx, tc.version)
+			if diff := pkgtestutil.DiffErrString(err, tc.wantErr); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Rotation(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	transit := newFakeTransit(t)
+	transit.now = func() time.Time { return time.Now().Add(-45 * time.Minute) }
+	transit.createKey(t, "jvs-signing", "ecdsa-p256")
+	m := testKeyManager(t, transit)
+
+	handler := jvscrypto.NewRotationHandler(ctx, nil, &config.CertRotationConfig{
+		KeyTTL:           time.Hour,
+		GracePeriod:      30 * time.Minute,
+		PropagationDelay: 10 * time.Minute,
+		DisabledPeriod:   time.Hour,
+	}).WithKeyManager(m)
+
+	// The first rotation promotes the version the key was created with. It was
+	// created in the past, so it has reached its rotation age.
+	if err := handler.RotateKey(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	primary, err := m.GetPrimary(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := primary, "jvs-signing/versions/1"; got != want {
+		t.Errorf("expected primary %q to be %q", got, want)
+	}
+
+	// The second rotation creates a new version, without promoting it until
+	// the propagation delay has passed.
+	transit.now = time.Now
+	if err := handler.RotateKey(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	vers, err := m.ListVersions(ctx, "jvs-signing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(vers), 2; got != want {
+		t.Fatalf("expected %d versions to be %d", got, want)
+	}
+	if primary, err = m.GetPrimary(ctx, "jvs-signing"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := primary, "jvs-signing/versions/1"; got != want {
+		t.Errorf("expected primary %q to be %q", got, want)
+	}
+}
+
+func TestKeyManager_Tokens(t *testing.T) {
+	t.Parallel()
+
+	ctx := logging.WithLogger(context.Background(), logging.TestLogger(t))
+
+	transit := newFakeTransit(t)
+	transit.createKey(t, "jvs-signing", "ecdsa-p256")
+	m := testKeyManager(t, transit)
+	if err := m.SetPrimary(ctx, "jvs-signing", "jvs-signing/versions/1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// No KMS client, signing and serving public keys must not call KMS.
+	processor := justification.NewProcessor(nil, &config.JustificationConfig{
+		Signer:             config.SignerVault,
+		KeyName:            "jvs-signing",
+		SignerCacheTimeout: 5 * time.Minute,
+		Issuer:             "test-iss",
+		DefaultTTL:         15 * time.Minute,
+		MaxTTL:             1 * time.Hour,
+		MaxAnnotationSize:  100,
+	}).WithKeyManager(m)
+	if err := processor.Ready(ctx); err != nil {
+		t.Fatalf("expected the processor to be ready: %s", err)
+	}
+
+	b, err := processor.CreateToken(ctx, "me@example.com", &jvspb.CreateJustificationRequest{
+		Justifications: []*jvspb.Justification{
+			{Category: "explanation", Value: "debugging"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := renderer.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyServer := jvscrypto.NewKeyServer(ctx, nil, &config.PublicKeyConfig{
+		Signer:       config.SignerVault,
+		KeyNames:     []string{"jvs-signing"},
+		CacheTimeout: 5 * time.Minute,
+	}, h).WithKeyManager(m)
+	if err := keyServer.Ready(ctx); err != nil {
+		t.Fatalf("expected the key server to be ready: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	keyServer.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks", nil))
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected status %d to be %d: %s", got, want, w.Body.String())
+	}
+	set, err := jwk.Parse(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Parse(b, jwt.WithKeySet(set, jws.WithInferAlgorithmFromKey(true)))
+	if err != nil {
+		t.Fatalf("failed to verify token with the jwks: %s", err)
+	}
+	if got, want := token.Subject(), "me@example.com"; got != want {
+		t.Errorf("subject: expected %q to be %q", got, want)
+	}
+}
+
+// testKeyManager returns a key manager for the fake transit engine.
+func testKeyManager(tb testing.TB, transit *fakeTransit) *KeyManager {
+	tb.Helper()
+
+	return NewKeyManager(&config.VaultConfig{
+		Address:      transit.server.URL,
+		Token:        testToken,
+		Namespace:    "team",
+		TransitMount: "transit",
+		Timeout:      10 * time.Second,
+	})
+}
+
+// fakeKey is a key in the fake transit engine.
+type fakeKey struct {
+	typ      string
+	versions map[int]*fakeKeyVersion
+
+	latest, minAvailable, minDecryption, minEncryption int
+}
+
+// fakeKeyVersion is a version of a key in the fake transit engine.
+type fakeKeyVersion struct {
+	key     *ecdsa.PrivateKey
+	created time.Time
+}
+
+// fakeTransit is an in-memory transit engine mounted at "transit". It supports
+// the endpoints the key manager uses, and enforces the constraints Vault puts
+// on the key versions.
+type fakeTransit struct {
+	server *httptest.Server
+
+	mu   sync.Mutex
+	now  func() time.Time
+	keys map[string]*fakeKey
+}
+
+func newFakeTransit(tb testing.TB) *fakeTransit {
+	tb.Helper()
+
+	f := &fakeTransit{
+		now:  time.Now,
+		keys: make(map[string]*fakeKey),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/transit/keys/{name}", f.handle(f.readKey))
+	mux.HandleFunc("POST /v1/transit/keys/{name}/rotate", f.handle(f.rotate))
+	mux.HandleFunc("POST /v1/transit/keys/{name}/config", f.handle(f.config))
+	mux.HandleFunc("POST /v1/transit/keys/{name}/trim", f.handle(f.trim))
+	mux.HandleFunc("POST /v1/transit/sign/{name}/{hash}", f.handle(f.sign))
+
+	f.server = httptest.NewServer(mux)
+	tb.Cleanup(f.server.Close)
+	return f
+}
+
+// createKey creates a key with one version.
+func (f *fakeTransit) createKey(tb testing.TB, name, typ string) {
+	tb.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	k := &fakeKey{
+		typ:           typ,
+		versions:      make(map[int]*fakeKeyVersion),
+		minDecryption: 1,
+	}
+	if err := f.addVersion(k); err != nil {
+		tb.Fatal(err)
+	}
+	f.keys[name] = k
+}
+
+// handle authenticates the request, and responds with the data or error
+// returned by the handler.
+func (f *fakeTransit) handle(fn func(k *fakeKey, r *http.Request, body map[string]any) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != testToken {
+			writeJSON(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+
+		var body map[string]any
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"errors": []string{err.Error()}})
+				return
+			}
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		k, ok := f.keys[r.PathValue("name")]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		data, err := fn(k, r, body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"errors": []string{err.Error()}})
+			return
+		}
+		if data == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"data": data})
+	}
+}
+
+func (f *fakeTransit) readKey(k *fakeKey, _ *http.Request, _ map[string]any) (any, error) {
+	keys := make(map[string]any, len(k.versions))
+	for n, v := range k.versions {
+		der, err := x509.MarshalPKIXPublicKey(&v.key.PublicKey)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // Want passthrough
+		}
+		keys[strconv.Itoa(n)] = map[string]any{
+			"creation_time": v.created.Format(time.RFC3339Nano),
+			"public_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		}
+	}
+	return map[string]any{
+		"type":                   k.typ,
+		"keys":                   keys,
+		"latest_version":         k.latest,
+		"min_available_version":  k.minAvailable,
+		"min_decryption_version": k.minDecryption,
+		"min_encryption_version": k.minEncryption,
+	}, nil
+}
+
+func (f *fakeTransit) rotate(k *fakeKey, _ *http.Request, _ map[string]any) (any, error) {
+	return nil, f.addVersion(k)
+}
+
+func (f *fakeTransit) config(k *fakeKey, _ *http.Request, body map[string]any) (any, error) {
+	minDecryption, minEncryption := k.minDecryption, k.minEncryption
+	if v, ok := body["min_decryption_version"].(float64); ok {
+		minDecryption = int(v)
+	}
+	if v, ok := body["min_encryption_version"].(float64); ok {
+		minEncryption = int(v)
+	}
+	if minDecryption > k.latest || minEncryption > k.latest {
+		return nil, fmt.Errorf("cannot set a version greater than the latest version")
+	}
+	if minEncryption != 0 && minEncryption < minDecryption {
+		return nil, fmt.Errorf("min encryption version should not be less than min decryption version")
+	}
+	k.minDecryption, k.minEncryption = minDecryption, minEncryption
+	return nil, nil
+}
+
+func (f *fakeTransit) trim(k *fakeKey, _ *http.Request, body map[string]any) (any, error) {
+	v, _ := body["min_available_version"].(float64)
+	minAvailable := int(v)
+	if minAvailable > k.minDecryption || (k.minEncryption != 0 && minAvailable > k.minEncryption) {
+		return nil, fmt.Errorf("min available version should not be greater than min decryption or encryption version")
+	}
+	for n := range k.versions {
+		if n < minAvailable {
+			delete(k.versions, n)
+		}
+	}
+	k.minAvailable = minAvailable
+	return nil, nil
+}
+
+func (f *fakeTransit) sign(k *fakeKey, r *http.Request, body map[string]any) (any, error) {
+	if got, want := r.PathValue("hash"), "sha2-256"; got != want {
+		return nil, fmt.Errorf("unsupported hash %q", got)
+	}
+	if prehashed, _ := body["prehashed"].(bool); !prehashed {
+		return nil, fmt.Errorf("expected prehashed input")
+	}
+	if got, want := body["marshaling_algorithm"], "asn1"; got != want {
+		return nil, fmt.Errorf("unsupported marshaling algorithm %q", got)
+	}
+	v, _ := body["key_version"].(float64)
+	n := int(v)
+	if n < k.minEncryption || n < k.minDecryption {
+		return nil, fmt.Errorf("requested version for signing is less than the minimum encryption key version")
+	}
+	ver, ok := k.versions[n]
+	if !ok {
+		return nil, fmt.Errorf("version %d not found", n)
+	}
+
+	input, _ := body["input"].(string)
+	digest, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Want passthrough
+	}
+	sig, err := ecdsa.SignASN1(rand.Reader, ver.key, digest)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Want passthrough
+	}
+	return map[string]any{
+		"signature":   fmt.Sprintf("vault:v%d:%s", n, base64.StdEncoding.EncodeToString(sig)),
+		"key_version": n,
+	}, nil
+}
+
+// addVersion generates a new version of the key. The caller must hold the
+// lock.
+func (f *fakeTransit) addVersion(k *fakeKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err //nolint:wrapcheck // Want passthrough
+	}
+	k.latest++
+	k.versions[k.latest] = &fakeKeyVersion{
+		key:     key,
+		created: f.now().UTC(),
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}